@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/internal/order"
+)
+
+// violation adalah invarian yang dilanggar. repair bernilai nil jika pelanggaran tidak
+// dapat diperbaiki otomatis.
+type violation struct {
+	Check  string
+	Detail string
+	repair func(ctx context.Context) error
+}
+
+// cancelAll membatalkan reservasi ids
+func cancelAll(l *ledger, ids []string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		for _, id := range ids {
+			if err := l.reservations[id].cancel(ctx); err != nil {
+				return fmt.Errorf("failed to cancel %s reservation %s: %w", l.name, id, err)
+			}
+		}
+		return nil
+	}
+}
+
+// auditOrders memeriksa reservasi setiap order yang sudah selesai. Order BOOKED harus
+// memiliki tepat satu reservasi aktif untuk setiap item, sedangkan order FAILED dan
+// CANCELLED tidak boleh memiliki reservasi aktif. Order yang masih berjalan dilewati.
+// Reservasi aktif yang ordernya tidak ada juga dilaporkan. Reservasi HELD tidak diperiksa
+// di sini, baik milik order yang ada maupun tidak, dan diserahkan ke auditHolds.
+func auditOrders(orders []order.Order, ledgers []*ledger) []violation {
+	var violations []violation
+	for _, l := range ledgers {
+		activeByOrder := l.activeByOrder()
+
+		known := make(map[string]bool, len(orders))
+		for i := range orders {
+			o := &orders[i]
+			known[o.ID] = true
+			active := activeByOrder[o.ID]
+
+			switch o.Status {
+			case order.StatusBooked:
+				violations = append(violations, auditBooked(o, l, active)...)
+
+			case order.StatusFailed, order.StatusCancelled:
+				if len(active) > 0 {
+					violations = append(violations, violation{
+						Check:  "failed_order_reservation_active",
+						Detail: fmt.Sprintf("order %s is %s but %s reservations %s are active", o.ID, o.Status, l.name, strings.Join(active, ", ")),
+						repair: cancelAll(l, active),
+					})
+				}
+			}
+		}
+
+		for orderID, active := range activeByOrder {
+			if !known[orderID] {
+				violations = append(violations, violation{
+					Check:  "orphan_reservation",
+					Detail: fmt.Sprintf("%s reservations %s are active but order %s does not exist", l.name, strings.Join(active, ", "), orderID),
+					repair: cancelAll(l, active),
+				})
+			}
+		}
+	}
+
+	return violations
+}
+
+// auditBooked memeriksa reservasi l milik order BOOKED o. Reservasi aktif yang tidak
+// dirujuk item mana pun dibatalkan saat repair, sedangkan item yang kehilangan
+// reservasinya hanya dapat dilaporkan.
+func auditBooked(o *order.Order, l *ledger, active []string) []violation {
+	items := l.items(o)
+
+	var violations []violation
+	referenced := make(map[string]bool, len(items))
+	for i, id := range items {
+		if id == "" {
+			continue
+		}
+		referenced[id] = true
+
+		if r, ok := l.reservations[id]; !ok || !r.Active || r.OrderID != o.ID {
+			violations = append(violations, violation{
+				Check:  "booked_order_reservation_missing",
+				Detail: fmt.Sprintf("order %s is BOOKED but %s item %d has no active reservation %s", o.ID, l.name, i, id),
+			})
+		}
+	}
+
+	if len(active) == len(items) {
+		return violations
+	}
+
+	v := violation{
+		Check:  "booked_order_reservation_count",
+		Detail: fmt.Sprintf("order %s is BOOKED with %d %s items but has %d active reservations (%s)", o.ID, len(items), l.name, len(active), strings.Join(active, ", ")),
+	}
+	// Kelebihan reservasi hanya dibatalkan jika setiap item sudah merujuk reservasinya
+	var unreferenced []string
+	for _, id := range active {
+		if !referenced[id] {
+			unreferenced = append(unreferenced, id)
+		}
+	}
+	if len(referenced) == len(items) && len(violations) == 0 && len(unreferenced) > 0 {
+		v.repair = cancelAll(l, unreferenced)
+	}
+	return append(violations, v)
+}
+
+// auditHolds melaporkan reservasi HELD yang sudah melewati ExpiresAt pada now tetapi
+// belum dilepas. Pelanggaran tidak diperbaiki otomatis karena pelepasan hold adalah tugas
+// layanannya, sama seperti auditor twophase. Hold yang masih berlaku tidak diperiksa.
+func auditHolds(l *ledger, now time.Time) []violation {
+	ids := make([]string, 0, len(l.reservations))
+	for id, r := range l.reservations {
+		if r.Status == statusHeld && !r.held(now) {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+
+	violations := make([]violation, 0, len(ids))
+	for _, id := range ids {
+		r := l.reservations[id]
+		violations = append(violations, violation{
+			Check:  "expired_hold_not_released",
+			Detail: fmt.Sprintf("%s reservation %s of order %s is HELD but expired at %s", l.name, id, r.OrderID, r.ExpiresAt.Format(time.RFC3339)),
+		})
+	}
+	return violations
+}
+
+// auditOverlaps memeriksa bahwa tidak ada dua reservasi aktif yang menempati slot yang
+// sama. Reservasi yang bertabrakan dilaporkan sekali beserta seluruh slotnya. Tabrakan
+// tidak diperbaiki otomatis karena reservasi yang harus dilepas bergantung pada ordernya.
+func auditOverlaps(l *ledger) []violation {
+	bySlot := make(map[string][]string)
+	for _, r := range l.reservations {
+		if !r.Active {
+			continue
+		}
+		for _, slot := range r.Slots {
+			bySlot[slot] = append(bySlot[slot], r.ID)
+		}
+	}
+
+	slotsByConflict := make(map[string][]string)
+	for slot, ids := range bySlot {
+		if len(ids) < 2 {
+			continue
+		}
+		slices.Sort(ids)
+		key := strings.Join(ids, ", ")
+		slotsByConflict[key] = append(slotsByConflict[key], slot)
+	}
+
+	conflicts := make([]string, 0, len(slotsByConflict))
+	for key := range slotsByConflict {
+		conflicts = append(conflicts, key)
+	}
+	slices.Sort(conflicts)
+
+	violations := make([]violation, 0, len(conflicts))
+	for _, key := range conflicts {
+		slots := slotsByConflict[key]
+		slices.Sort(slots)
+		violations = append(violations, violation{
+			Check:  "reservations_overlap",
+			Detail: fmt.Sprintf("%s reservations %s overlap on %s", l.name, key, strings.Join(slots, ", ")),
+		})
+	}
+	return violations
+}
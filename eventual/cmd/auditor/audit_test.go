@@ -0,0 +1,72 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/internal/order"
+)
+
+func TestAuditHeldReservations(t *testing.T) {
+	now := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+	later := now.Add(time.Minute)
+	earlier := now.Add(-time.Minute)
+
+	tests := []struct {
+		name        string
+		orders      []order.Order
+		reservation reservation
+		want        []string
+	}{
+		{
+			name:        "live hold of a held order",
+			orders:      []order.Order{{ID: "order-1", Status: order.StatusHeld}},
+			reservation: reservation{Status: statusHeld, Active: true, ExpiresAt: &later},
+		},
+		{
+			name:        "live hold without an order",
+			reservation: reservation{Status: statusHeld, Active: true, ExpiresAt: &later},
+		},
+		{
+			name:        "expired hold is reported",
+			orders:      []order.Order{{ID: "order-1", Status: order.StatusHoldExpired}},
+			reservation: reservation{Status: statusHeld, Active: true, ExpiresAt: &earlier},
+			want:        []string{"expired_hold_not_released"},
+		},
+		{
+			name:        "released hold",
+			orders:      []order.Order{{ID: "order-1", Status: order.StatusHoldExpired}},
+			reservation: reservation{Status: statusExpired, Active: false, ExpiresAt: &earlier},
+		},
+		{
+			name:        "reserved seat without an order is orphaned",
+			reservation: reservation{Status: "RESERVED", Active: true},
+			want:        []string{"orphan_reservation"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := tt.reservation
+			r.ID, r.OrderID = "reservation-1", "order-1"
+			l := &ledger{
+				name:         "car",
+				reservations: map[string]*reservation{r.ID: &r},
+				items:        func(o *order.Order) []string { return nil },
+			}
+
+			violations := append(auditOrders(tt.orders, []*ledger{l}), auditHolds(l, now)...)
+			var got []string
+			for _, v := range violations {
+				got = append(got, v.Check)
+				if v.Check == "expired_hold_not_released" && v.repair != nil {
+					t.Errorf("expired hold must not be repaired")
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("violations = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
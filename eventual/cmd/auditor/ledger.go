@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/internal/car"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/internal/flight"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/internal/hotel"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/internal/order"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/internal/train"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/config"
+)
+
+// Status reservasi. Nilainya sama di setiap layanan, dan reservasi pesawat tidak pernah
+// HELD maupun EXPIRED.
+const (
+	statusHeld      = "HELD"
+	statusCancelled = "CANCELLED"
+	statusExpired   = "EXPIRED"
+)
+
+// reservation adalah reservasi satu layanan beserta slot yang ditempatinya
+type reservation struct {
+	ID      string
+	OrderID string
+	Status  string
+	// Active bernilai true selama reservasi belum CANCELLED atau EXPIRED
+	Active bool
+	// ExpiresAt adalah batas waktu reservasi HELD
+	ExpiresAt *time.Time
+	// Slots adalah kamar/mobil per tanggal, kursi kereta per segmen, atau kursi pesawat
+	// yang ditempati reservasi. Dua reservasi aktif tidak boleh berbagi slot.
+	Slots []string
+	// cancel membatalkan reservasi ini
+	cancel func(ctx context.Context) error
+}
+
+// held bernilai true untuk reservasi HELD yang belum melewati ExpiresAt pada now
+func (r *reservation) held(now time.Time) bool {
+	return r.Status == statusHeld && (r.ExpiresAt == nil || r.ExpiresAt.After(now))
+}
+
+// isActive bernilai true untuk status reservasi yang masih menempati slotnya
+func isActive(status string) bool {
+	return status != statusCancelled && status != statusExpired
+}
+
+// ledger adalah seluruh reservasi satu layanan
+type ledger struct {
+	name         string
+	reservations map[string]*reservation
+	// items mengembalikan ReservationID setiap item layanan ini pada order
+	items func(o *order.Order) []string
+}
+
+// activeByOrder mengelompokkan ID reservasi aktif berdasarkan order. Reservasi HELD
+// dilewati karena diperiksa auditHolds.
+func (l *ledger) activeByOrder() map[string][]string {
+	byOrder := make(map[string][]string)
+	for _, r := range l.reservations {
+		if r.Active && r.Status != statusHeld {
+			byOrder[r.OrderID] = append(byOrder[r.OrderID], r.ID)
+		}
+	}
+	return byOrder
+}
+
+// loadLedgers membaca reservasi hotel, mobil, kereta dan pesawat
+func loadLedgers(ctx context.Context, client *firestore.Client) ([]*ledger, error) {
+	loaders := []func(context.Context, *firestore.Client) (*ledger, error){loadHotel, loadCar, loadTrain, loadFlight}
+
+	ledgers := make([]*ledger, 0, len(loaders))
+	for _, load := range loaders {
+		l, err := load(ctx, client)
+		if err != nil {
+			return nil, err
+		}
+		ledgers = append(ledgers, l)
+	}
+	return ledgers, nil
+}
+
+func loadHotel(ctx context.Context, client *firestore.Client) (*ledger, error) {
+	repo := hotel.NewFirestoreRepository(client)
+	l := &ledger{
+		name:         "hotel",
+		reservations: make(map[string]*reservation),
+		items: func(o *order.Order) []string {
+			return reservationIDs(o.HotelRooms, func(i order.HotelRoomItem) string { return i.ReservationID })
+		},
+	}
+
+	hotelReservations, err := readAll[hotel.HotelReservation](ctx, client.Collection("hotel_reservations").Query)
+	if err != nil {
+		return nil, err
+	}
+	for _, hr := range hotelReservations {
+		// Reservasi tipe kamar yang belum mendapat kamar tidak menempati kamar mana pun
+		slots, err := dailySlots(hr.HotelRoomID, hr.HotelRoomStartDate, hr.HotelRoomEndDate)
+		if err != nil {
+			return nil, fmt.Errorf("hotel reservation %s: %w", hr.ID, err)
+		}
+
+		l.reservations[hr.ID] = &reservation{
+			ID:        hr.ID,
+			OrderID:   hr.OrderID,
+			Status:    string(hr.Status),
+			Active:    isActive(string(hr.Status)),
+			ExpiresAt: hr.ExpiresAt,
+			Slots:     slots,
+			// CancelHotelReservation juga mengembalikan unit tipe kamar
+			cancel: func(ctx context.Context) error {
+				_, err := repo.CancelHotelReservation(ctx, hr.ID)
+				return err
+			},
+		}
+	}
+	return l, nil
+}
+
+func loadCar(ctx context.Context, client *firestore.Client) (*ledger, error) {
+	repo := car.NewFirestoreRepository(client)
+	l := &ledger{
+		name:         "car",
+		reservations: make(map[string]*reservation),
+		items: func(o *order.Order) []string {
+			return reservationIDs(o.Cars, func(i order.CarItem) string { return i.ReservationID })
+		},
+	}
+
+	carReservations, err := readAll[car.CarReservation](ctx, client.Collection("car_reservations").Query)
+	if err != nil {
+		return nil, err
+	}
+	for _, cr := range carReservations {
+		slots, err := dailySlots(cr.CarID, cr.StartDate, cr.EndDate)
+		if err != nil {
+			return nil, fmt.Errorf("car reservation %s: %w", cr.ID, err)
+		}
+
+		l.reservations[cr.ID] = &reservation{
+			ID:        cr.ID,
+			OrderID:   cr.OrderID,
+			Status:    string(cr.Status),
+			Active:    isActive(string(cr.Status)),
+			ExpiresAt: cr.ExpiresAt,
+			Slots:     slots,
+			cancel: func(ctx context.Context) error {
+				cr.Status = car.CarReservationStatusCancelled
+				return repo.UpdateCarReservation(ctx, &cr)
+			},
+		}
+	}
+	return l, nil
+}
+
+func loadTrain(ctx context.Context, client *firestore.Client) (*ledger, error) {
+	repo := train.NewFirestoreRepository(client)
+	l := &ledger{
+		name:         "train",
+		reservations: make(map[string]*reservation),
+		items: func(o *order.Order) []string {
+			return reservationIDs(o.TrainSeats, func(i order.TrainSeatItem) string { return i.ReservationID })
+		},
+	}
+
+	trainReservations, err := readAll[train.TrainReservation](ctx, client.Collection("train_reservations").Query)
+	if err != nil {
+		return nil, err
+	}
+	for _, tr := range trainReservations {
+		// Kursi yang sama boleh dipesan untuk segmen yang berbeda
+		var slots []string
+		for segment := tr.FromSegment; segment < tr.ToSegment; segment++ {
+			slots = append(slots, fmt.Sprintf("%s-%s-%d", tr.JourneyID, tr.SeatID, segment))
+		}
+
+		l.reservations[tr.ID] = &reservation{
+			ID:        tr.ID,
+			OrderID:   tr.OrderID,
+			Status:    string(tr.Status),
+			Active:    isActive(string(tr.Status)),
+			ExpiresAt: tr.ExpiresAt,
+			Slots:     slots,
+			cancel: func(ctx context.Context) error {
+				tr.Status = train.TrainReservationStatusCancelled
+				return repo.UpdateTrainReservation(ctx, &tr)
+			},
+		}
+	}
+	return l, nil
+}
+
+func loadFlight(ctx context.Context, client *firestore.Client) (*ledger, error) {
+	repo := flight.NewFirestoreRepository(client)
+	l := &ledger{
+		name:         "flight",
+		reservations: make(map[string]*reservation),
+		items: func(o *order.Order) []string {
+			return reservationIDs(o.Flights, func(i order.FlightItem) string { return i.ReservationID })
+		},
+	}
+
+	flightReservations, err := readAll[flight.FlightReservation](ctx, client.Collection("flight_reservations").Query)
+	if err != nil {
+		return nil, err
+	}
+	for _, fr := range flightReservations {
+		l.reservations[fr.ID] = &reservation{
+			ID:      fr.ID,
+			OrderID: fr.OrderID,
+			Status:  string(fr.Status),
+			Active:  isActive(string(fr.Status)),
+			Slots:   []string{fmt.Sprintf("%s-%s", fr.FlightID, fr.SeatID)},
+			cancel: func(ctx context.Context) error {
+				fr.Status = flight.FlightReservationStatusCancelled
+				return repo.UpdateFlightReservation(ctx, &fr)
+			},
+		}
+	}
+	return l, nil
+}
+
+// reservationIDs mengambil ReservationID setiap item sesuai urutan item di order
+func reservationIDs[T any](items []T, id func(T) string) []string {
+	ids := make([]string, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, id(item))
+	}
+	return ids
+}
+
+// readAll membaca seluruh dokumen query ke T
+func readAll[T any](ctx context.Context, query firestore.Query) ([]T, error) {
+	docs, err := query.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get documents: %w", err)
+	}
+
+	values := make([]T, 0, len(docs))
+	for _, doc := range docs {
+		var value T
+		if err := doc.DataTo(&value); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %s: %w", doc.Ref.Path, err)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// dailySlots membentuk slot "{id}-{tanggal}" untuk setiap tanggal dari start sampai end
+// (inklusif). Slot kosong jika id kosong.
+func dailySlots(id, start, end string) ([]string, error) {
+	if id == "" {
+		return nil, nil
+	}
+
+	startDate, err := time.Parse(config.DateFormat, start)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse start date: %w", err)
+	}
+	endDate, err := time.Parse(config.DateFormat, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse end date: %w", err)
+	}
+
+	var slots []string
+	for date := startDate; !date.After(endDate); date = date.AddDate(0, 0, 1) {
+		slots = append(slots, fmt.Sprintf("%s-%s", id, date.Format(config.DateFormat)))
+	}
+	return slots, nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/joho/godotenv"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/internal/order"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/config"
+)
+
+// Auditor memeriksa apakah order dan reservasi setiap layanan sudah konsisten setelah
+// pengujian. Jalankan setelah beban berhenti dan seluruh saga selesai, karena order yang
+// masih diproses dapat terlihat seperti pelanggaran. Program keluar dengan status 1
+// selama masih ada pelanggaran.
+func main() {
+	repair := flag.Bool("repair", false, "perbaiki pelanggaran yang dapat diperbaiki otomatis")
+	flag.Parse()
+
+	log.Println("Starting auditor...")
+
+	if err := godotenv.Load(); err != nil {
+		log.Printf("Error loading .env file: %v, using system environment variables", err)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	ctx := context.Background()
+	client, err := firestore.NewClient(ctx, cfg.GoogleProjectID)
+	if err != nil {
+		log.Fatalf("Failed to create Firestore client: %v", err)
+	}
+	defer client.Close()
+
+	orders, err := readAll[order.Order](ctx, client.Collection("order_orders").Query)
+	if err != nil {
+		log.Fatalf("Failed to read orders: %v", err)
+	}
+
+	ledgers, err := loadLedgers(ctx, client)
+	if err != nil {
+		log.Fatalf("Failed to read reservations: %v", err)
+	}
+
+	now := time.Now()
+	violations := auditOrders(orders, ledgers)
+	for _, l := range ledgers {
+		violations = append(violations, auditHolds(l, now)...)
+		violations = append(violations, auditOverlaps(l)...)
+	}
+
+	repairable := 0
+	for _, v := range violations {
+		log.Printf("[%s] %s", v.Check, v.Detail)
+		if v.repair != nil {
+			repairable++
+		}
+	}
+	log.Printf("Audited %d orders: %d violations, %d repairable", len(orders), len(violations), repairable)
+
+	if !*repair {
+		if len(violations) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	repaired := 0
+	for _, v := range violations {
+		if v.repair == nil {
+			continue
+		}
+		if err := v.repair(ctx); err != nil {
+			log.Printf("Failed to repair [%s] %s: %v", v.Check, v.Detail, err)
+			continue
+		}
+		repaired++
+	}
+
+	log.Printf("Repaired %d of %d violations", repaired, len(violations))
+	if repaired < len(violations) {
+		log.Printf("Run the auditor again to list the violations that remain")
+		os.Exit(1)
+	}
+}
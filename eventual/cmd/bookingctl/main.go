@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"github.com/joho/godotenv"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/internal/order"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/internal/pricing"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/config"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/messagebus"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/saga"
+)
+
+// command adalah satu perintah bookingctl
+type command struct {
+	usage       string
+	description string
+	run         func(ctx context.Context, a *app, args []string) error
+}
+
+// commands adalah seluruh perintah bookingctl dengan kunci "<grup> <perintah>"
+var commands = map[string]command{
+	"order get": {
+		usage:       "order get <orderID>",
+		description: "tampilkan order beserta status setiap item",
+		run:         orderGet,
+	},
+	"order list": {
+		usage:       "order list [-status STATUS] [-limit N]",
+		description: "tampilkan daftar order",
+		run:         orderList,
+	},
+	"saga retry": {
+		usage:       "saga retry <orderID>",
+		description: "kirim ulang command saga yang belum dibalas",
+		run:         sagaRetry,
+	},
+	"saga compensate": {
+		usage:       "saga compensate <orderID>",
+		description: "gagalkan order yang belum selesai dan kirim command kompensasi",
+		run:         sagaCompensate,
+	},
+	"saga steps": {
+		usage:       "saga steps <orderID>",
+		description: "tampilkan riwayat transisi step saga booking order",
+		run:         sagaSteps,
+	},
+}
+
+// bookingctl membantu operator memeriksa dan memperbaiki saga order tanpa membuka
+// Firestore console. Perintah saga mengirim command melalui RabbitMQ seperti order service.
+func main() {
+	if len(os.Args) < 3 {
+		usage()
+	}
+	cmd, ok := commands[os.Args[1]+" "+os.Args[2]]
+	if !ok {
+		usage()
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Printf("Error loading .env file: %v, using system environment variables", err)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	ctx := context.Background()
+	client, err := firestore.NewClient(ctx, cfg.GoogleProjectID)
+	if err != nil {
+		log.Fatalf("Failed to create Firestore client: %v", err)
+	}
+
+	a := &app{cfg: cfg, client: client, orders: order.NewFirestoreRepository(client)}
+	err = cmd.run(ctx, a, os.Args[3:])
+	a.close()
+	client.Close()
+	if err != nil {
+		log.Fatalf("%s %s: %v", os.Args[1], os.Args[2], err)
+	}
+}
+
+func usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Usage: %s <command> [flags] [args]\n\nCommands:\n", os.Args[0])
+	for _, name := range names {
+		fmt.Fprintf(&b, "  %-40s %s\n", commands[name].usage, commands[name].description)
+	}
+	fmt.Fprint(os.Stderr, b.String())
+	os.Exit(2)
+}
+
+// app berisi dependensi yang dipakai perintah. Koneksi RabbitMQ baru dibuka saat
+// perintah saga membutuhkannya.
+type app struct {
+	cfg    config.Config
+	client *firestore.Client
+	orders order.Repository
+	conn   *messagebus.Connection
+}
+
+// saga membuat order service yang sama dengan order service, tanpa fault injection
+func (a *app) saga() (order.Service, error) {
+	conn, err := messagebus.Dial(a.cfg.RabbitMQURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+	}
+	a.conn = conn
+
+	pricingService := pricing.NewService(pricing.NewFirestoreRepository(a.client), a.cfg.QuoteTTL)
+	policy := order.CancellationPolicy{
+		FreeWindow: a.cfg.FreeCancellationWindow,
+		FeePercent: a.cfg.CancellationFeePercent,
+	}
+	history := saga.NewFirestoreHistory(a.client)
+	return order.NewService(a.orders, pricingService, policy, messagebus.NewRabbitmqPublisher(conn), a.cfg.SagaMode, history, a.cfg.SagaStepTimeout), nil
+}
+
+func (a *app) close() {
+	if a.conn != nil {
+		a.conn.Close()
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/internal/order"
+)
+
+func orderGet(ctx context.Context, a *app, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: order get <orderID>")
+	}
+
+	o, err := a.orders.GetOrderByID(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	return printJSON(o)
+}
+
+func orderList(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("order list", flag.ContinueOnError)
+	status := flags.String("status", "", "status order, misalnya AWAITING_CONFIRMATION atau CANCELLING (default semua)")
+	limit := flags.Int("limit", 50, "jumlah order maksimum")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	orders, err := a.orders.ListOrders(ctx, order.OrderStatus(strings.ToUpper(*status)), *limit)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tUSER\tSTATUS\tHOTEL\tCAR\tTRAIN\tFLIGHT\tPAYMENT\tAGE")
+	for _, o := range orders {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			o.ID, o.UserID, o.Status,
+			o.HotelReservationStatus, o.CarReservationStatus, o.TrainReservationStatus, o.FlightReservationStatus,
+			o.PaymentStatus, age(o.CreatedAt))
+	}
+	return w.Flush()
+}
+
+// printJSON menulis v ke stdout sebagai JSON yang mudah dibaca
+func printJSON(v any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// age adalah lama waktu sejak t, dibulatkan ke detik
+func age(t time.Time) string {
+	return time.Since(t).Round(time.Second).String()
+}
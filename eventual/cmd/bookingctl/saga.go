@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/saga"
+)
+
+func sagaRetry(ctx context.Context, a *app, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: saga retry <orderID>")
+	}
+
+	saga, err := a.saga()
+	if err != nil {
+		return err
+	}
+	o, err := saga.RetrySaga(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Re-published the pending commands of order %s (%s)", o.ID, o.Status)
+	return nil
+}
+
+func sagaCompensate(ctx context.Context, a *app, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: saga compensate <orderID>")
+	}
+
+	saga, err := a.saga()
+	if err != nil {
+		return err
+	}
+	o, err := saga.CompensateSaga(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Order %s is %s, compensation commands published", o.ID, o.Status)
+	return nil
+}
+
+func sagaSteps(ctx context.Context, a *app, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: saga steps <orderID>")
+	}
+
+	transitions, err := saga.NewFirestoreHistory(a.client).List(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "AT\tSAGA\tSTEP\tFROM\tTO\tEVENT\tREASON")
+	for _, t := range transitions {
+		step := t.Step
+		if step == "" {
+			step = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			t.At.Format(time.RFC3339Nano), t.Saga, step, t.From, t.To, t.Event, t.Reason)
+	}
+	return w.Flush()
+}
@@ -1,13 +1,49 @@
 package main
 
 import (
+	"flag"
+	"log"
+	"time"
+
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/cmd/seeder/car"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/cmd/seeder/flight"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/cmd/seeder/hotel"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/cmd/seeder/spec"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/cmd/seeder/train"
 )
 
+// csv-exporter menulis data yang di-seed ke CSV untuk load generator. Jalankan dengan spec
+// dan seed yang sama dengan seeder.
 func main() {
-	car.ExportToCSV("car.csv")
-	hotel.ExportToCSV("hotel.csv")
-	train.ExportToCSV("train.csv")
+	specPath := flag.String("spec", "", "file spec YAML atau JSON (default spec bawaan cmd/seeder/spec/default.yaml)")
+	seed := flag.Int64("seed", 0, "seed pembangkitan acak, menimpa seed di spec")
+	flag.Parse()
+
+	s, err := spec.Load(*specPath)
+	if err != nil {
+		log.Fatalf("Failed to load spec: %v", err)
+	}
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			s.Seed = *seed
+		}
+	})
+
+	data, err := s.Build(time.Now())
+	if err != nil {
+		log.Fatalf("Failed to build seed data: %v", err)
+	}
+
+	if err := car.ExportToCSV("car.csv", data); err != nil {
+		log.Fatalf("Failed to export cars: %v", err)
+	}
+	if err := hotel.ExportToCSV("hotel.csv", data); err != nil {
+		log.Fatalf("Failed to export hotel rooms: %v", err)
+	}
+	if err := train.ExportToCSV("train.csv", data); err != nil {
+		log.Fatalf("Failed to export train seats: %v", err)
+	}
+	if err := flight.ExportToCSV("flight.csv", data); err != nil {
+		log.Fatalf("Failed to export flight seats: %v", err)
+	}
 }
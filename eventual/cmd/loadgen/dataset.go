@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/internal/order"
+)
+
+// dataset berisi item hasil csv-exporter untuk setiap layanan yang diuji. Request ke-i
+// memakai item ke-i dari setiap layanan, sehingga kombinasi ID selalu unik.
+type dataset struct {
+	hotelRooms []string
+	cars       []string
+	trainSeats []order.TrainSeatRequest
+	flights    []order.FlightRequest
+}
+
+// loadDataset membaca CSV layanan yang dipilih dari dataDir
+func loadDataset(dataDir string, services []string) (*dataset, error) {
+	d := &dataset{}
+	for _, service := range services {
+		rows, err := readCSV(filepath.Join(dataDir, service+".csv"))
+		if err != nil {
+			return nil, err
+		}
+
+		switch service {
+		case "hotel":
+			for _, row := range rows {
+				d.hotelRooms = append(d.hotelRooms, row[0])
+			}
+		case "car":
+			for _, row := range rows {
+				d.cars = append(d.cars, row[0])
+			}
+		case "train":
+			for _, row := range rows {
+				if len(row) < 6 {
+					return nil, fmt.Errorf("train.csv: expected 6 columns, got %d", len(row))
+				}
+				d.trainSeats = append(d.trainSeats, order.TrainSeatRequest{
+					JourneyID:          row[0],
+					DepartureDate:      row[1],
+					SeatID:             row[2],
+					OriginStation:      row[4],
+					DestinationStation: row[5],
+				})
+			}
+		case "flight":
+			for _, row := range rows {
+				if len(row) < 3 {
+					return nil, fmt.Errorf("flight.csv: expected 3 columns, got %d", len(row))
+				}
+				d.flights = append(d.flights, order.FlightRequest{
+					FlightID:      row[0],
+					DepartureDate: row[1],
+					SeatID:        row[2],
+				})
+			}
+		default:
+			return nil, fmt.Errorf("unknown service %q", service)
+		}
+
+		if len(rows) == 0 {
+			return nil, fmt.Errorf("%s.csv has no rows", service)
+		}
+	}
+
+	return d, nil
+}
+
+// readCSV membaca seluruh baris CSV tanpa header
+func readCSV(filename string) ([][]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", filename, err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	if _, err := reader.Read(); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read header of %s: %w", filename, err)
+	}
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+	return rows, nil
+}
+
+// size adalah jumlah kombinasi unik, yaitu jumlah item layanan dengan item paling sedikit
+func (d *dataset) size() int {
+	size := -1
+	for _, n := range []int{len(d.hotelRooms), len(d.cars), len(d.trainSeats), len(d.flights)} {
+		if n > 0 && (size < 0 || n < size) {
+			size = n
+		}
+	}
+	return max(size, 0)
+}
+
+// payload membuat isian POST /orders untuk request ke-index. Kamar dan mobil dipesan
+// pada startDate sampai endDate, kereta dan pesawat pada tanggal keberangkatannya.
+func (d *dataset) payload(index, userID int, startDate, endDate string) order.CreateOrderPayload {
+	payload := order.CreateOrderPayload{UserID: strconv.Itoa(userID)}
+	if len(d.hotelRooms) > 0 {
+		payload.HotelRooms = []order.HotelRoomRequest{{
+			HotelRoomID: d.hotelRooms[index],
+			StartDate:   startDate,
+			EndDate:     endDate,
+		}}
+	}
+	if len(d.cars) > 0 {
+		payload.Cars = []order.CarRequest{{
+			CarID:     d.cars[index],
+			StartDate: startDate,
+			EndDate:   endDate,
+		}}
+	}
+	if len(d.trainSeats) > 0 {
+		payload.TrainSeats = []order.TrainSeatRequest{d.trainSeats[index]}
+	}
+	if len(d.flights) > 0 {
+		payload.Flights = []order.FlightRequest{d.flights[index]}
+	}
+	return payload
+}
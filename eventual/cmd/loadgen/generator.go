@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxErrorBody membatasi isi respons gagal yang dicatat
+const maxErrorBody = 512
+
+// generator mengirim POST /orders dengan kombinasi ID yang berbeda untuk setiap request
+type generator struct {
+	client  *http.Client
+	url     string
+	data    *dataset
+	opts    options
+	limit   int
+	next    atomic.Int64
+	results chan<- result
+}
+
+// nextIndex mengambil indeks kombinasi berikutnya, false jika request sudah mencapai batas
+func (g *generator) nextIndex() (int, bool) {
+	index := int(g.next.Add(1) - 1)
+	return index, index < g.limit
+}
+
+// runClosed menjalankan opts.concurrency worker yang masing-masing mengirim request
+// berikutnya setelah respons sebelumnya diterima. Worker dimulai merata selama ramp-up.
+func (g *generator) runClosed(ctx context.Context) {
+	var wg sync.WaitGroup
+	for worker := 0; worker < g.opts.concurrency; worker++ {
+		delay := g.opts.rampUp * time.Duration(worker) / time.Duration(g.opts.concurrency)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return
+			}
+
+			for ctx.Err() == nil {
+				index, ok := g.nextIndex()
+				if !ok {
+					return
+				}
+				g.results <- g.send(index, time.Now())
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// runOpen mengirim request dengan laju tetap opts.rate tanpa menunggu respons. Laju naik
+// linear selama ramp-up. Paling banyak opts.concurrency request berjalan bersamaan; latensi
+// diukur dari jadwal kirim sehingga waktu antre saat batas tercapai ikut terhitung.
+func (g *generator) runOpen(ctx context.Context) {
+	var wg sync.WaitGroup
+	inFlight := make(chan struct{}, g.opts.concurrency)
+	start := time.Now()
+
+loop:
+	for sent := 0; ; sent++ {
+		index, ok := g.nextIndex()
+		if !ok {
+			break
+		}
+
+		scheduledAt := start.Add(g.arrival(sent))
+		select {
+		case <-time.After(time.Until(scheduledAt)):
+		case <-ctx.Done():
+			break loop
+		}
+		select {
+		case inFlight <- struct{}{}:
+		case <-ctx.Done():
+			break loop
+		}
+
+		wg.Add(1)
+		go func(index int, scheduledAt time.Time) {
+			defer wg.Done()
+			defer func() { <-inFlight }()
+			g.results <- g.send(index, scheduledAt)
+		}(index, scheduledAt)
+	}
+	wg.Wait()
+}
+
+// arrival adalah jadwal kirim request ke-n sejak pengujian dimulai pada mode open. Selama
+// ramp-up laju naik linear dari 0 sehingga jumlah request sampai waktu t adalah
+// rate*t^2/(2*rampUp); setelahnya request dikirim dengan laju penuh.
+func (g *generator) arrival(n int) time.Duration {
+	rate := g.opts.rate
+	rampUp := g.opts.rampUp.Seconds()
+	rampUpRequests := rate * rampUp / 2
+
+	var seconds float64
+	if float64(n) < rampUpRequests {
+		seconds = math.Sqrt(2 * float64(n) * rampUp / rate)
+	} else {
+		seconds = rampUp + (float64(n)-rampUpRequests)/rate
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// send mengirim order ke-index dan mengukur latensinya dari startedAt sampai seluruh
+// respons terbaca
+func (g *generator) send(index int, startedAt time.Time) result {
+	userID := g.opts.userStart + index
+	res := result{Index: index, UserID: userID, StartedAt: startedAt}
+
+	body, err := json.Marshal(g.data.payload(index, userID, g.opts.startDate, g.opts.endDate))
+	if err != nil {
+		res.Err = err.Error()
+		return res
+	}
+
+	resp, err := g.client.Post(g.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		res.Latency = time.Since(startedAt)
+		res.Err = err.Error()
+		return res
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	io.Copy(io.Discard, resp.Body)
+	res.Latency = time.Since(startedAt)
+	res.StatusCode = resp.StatusCode
+	if !res.succeeded() {
+		res.Err = strings.TrimSpace(string(respBody))
+	}
+
+	return res
+}
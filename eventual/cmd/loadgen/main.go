@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+)
+
+// options adalah konfigurasi load test dari flag command line
+type options struct {
+	baseURL     string
+	dataDir     string
+	services    []string
+	concurrency int
+	rampUp      time.Duration
+	duration    time.Duration
+	count       int
+	mode        string
+	rate        float64
+	startDate   string
+	endDate     string
+	userStart   int
+	timeout     time.Duration
+	output      string
+	summary     string
+}
+
+func main() {
+	var opts options
+	var services string
+	flag.StringVar(&opts.baseURL, "url", "http://localhost:8080", "base URL order service (EC) atau coordinator (2PC)")
+	flag.StringVar(&opts.dataDir, "data-dir", ".", "direktori hotel.csv, car.csv, train.csv, dan flight.csv hasil csv-exporter")
+	flag.StringVar(&services, "services", "hotel,car,train", "layanan yang dipesan setiap order, dipisah koma (hotel, car, train, flight)")
+	flag.IntVar(&opts.concurrency, "concurrency", 100, "jumlah worker (closed) atau batas request yang berjalan bersamaan (open)")
+	flag.DurationVar(&opts.rampUp, "ramp-up", 0, "waktu sampai seluruh worker berjalan (closed) atau sampai rate penuh (open)")
+	flag.DurationVar(&opts.duration, "duration", 0, "lama pengujian, 0 berarti sampai -count atau kombinasi ID habis")
+	flag.IntVar(&opts.count, "count", 0, "jumlah request, 0 berarti sebanyak kombinasi ID unik")
+	flag.StringVar(&opts.mode, "mode", "closed", "closed: setiap worker mengirim request berikutnya setelah respons diterima; open: request dikirim dengan laju -rate")
+	flag.Float64Var(&opts.rate, "rate", 0, "request per detik pada mode open")
+	flag.StringVar(&opts.startDate, "start-date", "2025-06-28", "tanggal mulai kamar dan mobil")
+	flag.StringVar(&opts.endDate, "end-date", "2025-06-28", "tanggal selesai kamar dan mobil")
+	flag.IntVar(&opts.userStart, "user-start", 1, "user_id request pertama, request berikutnya bertambah satu")
+	flag.DurationVar(&opts.timeout, "timeout", 30*time.Second, "batas waktu setiap request")
+	flag.StringVar(&opts.output, "output", "loadgen-results.csv", "file CSV hasil setiap request")
+	flag.StringVar(&opts.summary, "summary", "loadgen-summary.csv", "file CSV ringkasan throughput dan latensi")
+	flag.Parse()
+
+	opts.services = strings.Split(services, ",")
+	if opts.concurrency <= 0 {
+		log.Fatalf("-concurrency must be positive")
+	}
+	if opts.mode != "closed" && opts.mode != "open" {
+		log.Fatalf("-mode must be closed or open, got %q", opts.mode)
+	}
+	if opts.mode == "open" && opts.rate <= 0 {
+		log.Fatalf("-rate must be positive in open mode")
+	}
+
+	data, err := loadDataset(opts.dataDir, opts.services)
+	if err != nil {
+		log.Fatalf("Failed to load dataset: %v", err)
+	}
+
+	limit := data.size()
+	if opts.count > limit {
+		log.Fatalf("-count %d exceeds the %d unique id combinations in the dataset", opts.count, limit)
+	}
+	if opts.count > 0 {
+		limit = opts.count
+	}
+
+	rec, err := newRecorder(opts.output)
+	if err != nil {
+		log.Fatalf("Failed to create recorder: %v", err)
+	}
+
+	// Pengujian berhenti saat durasi habis atau dihentikan dengan Ctrl+C. Request yang
+	// sedang berjalan tetap ditunggu sampai selesai.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if opts.duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.duration)
+		defer cancel()
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = opts.concurrency
+	transport.MaxIdleConnsPerHost = opts.concurrency
+
+	results := make(chan result, opts.concurrency)
+	g := &generator{
+		client:  &http.Client{Transport: transport, Timeout: opts.timeout},
+		url:     strings.TrimRight(opts.baseURL, "/") + "/orders",
+		data:    data,
+		opts:    opts,
+		limit:   limit,
+		results: results,
+	}
+
+	recorded := make(chan error)
+	go func() {
+		var err error
+		for res := range results {
+			if recErr := rec.record(res); recErr != nil && err == nil {
+				err = recErr
+			}
+		}
+		recorded <- err
+	}()
+
+	log.Printf("Sending up to %d orders to %s in %s mode with concurrency %d...", limit, g.url, opts.mode, opts.concurrency)
+	started := time.Now()
+	if opts.mode == "open" {
+		g.runOpen(ctx)
+	} else {
+		g.runClosed(ctx)
+	}
+	elapsed := time.Since(started)
+
+	close(results)
+	if err := <-recorded; err != nil {
+		log.Fatalf("Failed to record results: %v", err)
+	}
+	if err := rec.close(); err != nil {
+		log.Fatalf("Failed to write %s: %v", opts.output, err)
+	}
+
+	s := rec.summarize(elapsed)
+	if err := s.write(opts.summary); err != nil {
+		log.Fatalf("Failed to write %s: %v", opts.summary, err)
+	}
+
+	log.Printf("Requests: %d (%d succeeded, %d failed) in %s", s.Requests, s.Succeeded, s.Failed, s.Elapsed.Round(time.Millisecond))
+	log.Printf("Throughput: %.2f req/s (%.2f succeeded req/s)", s.throughput(s.Requests), s.throughput(s.Succeeded))
+	log.Printf("Latency: mean %s, p50 %s, p90 %s, p95 %s, p99 %s, max %s", s.Mean, s.P50, s.P90, s.P95, s.P99, s.Max)
+	log.Printf("Results written to %s and %s", opts.output, opts.summary)
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// result adalah hasil satu request dari sisi klien
+type result struct {
+	Index      int
+	UserID     int
+	StartedAt  time.Time
+	Latency    time.Duration
+	StatusCode int
+	Err        string
+}
+
+func (r result) succeeded() bool {
+	return r.Err == "" && r.StatusCode >= 200 && r.StatusCode < 300
+}
+
+// recorder menulis setiap hasil ke CSV dan mengumpulkan latensinya untuk ringkasan
+type recorder struct {
+	file      *os.File
+	writer    *csv.Writer
+	latencies []time.Duration
+	succeeded int
+	failed    int
+}
+
+func newRecorder(filename string) (*recorder, error) {
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", filename, err)
+	}
+
+	writer := csv.NewWriter(file)
+	if err := writer.Write([]string{"Index", "UserID", "StartedAt", "LatencyMs", "StatusCode", "Error"}); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to write header: %w", err)
+	}
+
+	return &recorder{file: file, writer: writer}, nil
+}
+
+func (r *recorder) record(res result) error {
+	r.latencies = append(r.latencies, res.Latency)
+	if res.succeeded() {
+		r.succeeded++
+	} else {
+		r.failed++
+	}
+
+	return r.writer.Write([]string{
+		strconv.Itoa(res.Index),
+		strconv.Itoa(res.UserID),
+		res.StartedAt.Format(time.RFC3339Nano),
+		formatMs(res.Latency),
+		strconv.Itoa(res.StatusCode),
+		res.Err,
+	})
+}
+
+func (r *recorder) close() error {
+	r.writer.Flush()
+	if err := r.writer.Error(); err != nil {
+		r.file.Close()
+		return err
+	}
+	return r.file.Close()
+}
+
+// summary adalah throughput dan persentil latensi seluruh request
+type summary struct {
+	Requests  int
+	Succeeded int
+	Failed    int
+	Elapsed   time.Duration
+	Mean      time.Duration
+	P50       time.Duration
+	P90       time.Duration
+	P95       time.Duration
+	P99       time.Duration
+	Max       time.Duration
+}
+
+func (r *recorder) summarize(elapsed time.Duration) summary {
+	s := summary{
+		Requests:  len(r.latencies),
+		Succeeded: r.succeeded,
+		Failed:    r.failed,
+		Elapsed:   elapsed,
+	}
+	if len(r.latencies) == 0 {
+		return s
+	}
+
+	sorted := slices.Clone(r.latencies)
+	slices.Sort(sorted)
+
+	var total time.Duration
+	for _, latency := range sorted {
+		total += latency
+	}
+	s.Mean = total / time.Duration(len(sorted))
+	s.P50 = percentile(sorted, 50)
+	s.P90 = percentile(sorted, 90)
+	s.P95 = percentile(sorted, 95)
+	s.P99 = percentile(sorted, 99)
+	s.Max = sorted[len(sorted)-1]
+	return s
+}
+
+// percentile memakai metode nearest-rank pada latensi yang sudah terurut
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank, 1)-1]
+}
+
+// throughput adalah jumlah request per detik
+func (s summary) throughput(requests int) float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(requests) / s.Elapsed.Seconds()
+}
+
+// write menulis ringkasan sebagai CSV satu baris
+func (s summary) write(filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", filename, err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write([]string{"Requests", "Succeeded", "Failed", "ElapsedSeconds", "ThroughputRps", "SuccessThroughputRps", "MeanMs", "P50Ms", "P90Ms", "P95Ms", "P99Ms", "MaxMs"})
+	writer.Write([]string{
+		strconv.Itoa(s.Requests),
+		strconv.Itoa(s.Succeeded),
+		strconv.Itoa(s.Failed),
+		strconv.FormatFloat(s.Elapsed.Seconds(), 'f', 3, 64),
+		strconv.FormatFloat(s.throughput(s.Requests), 'f', 2, 64),
+		strconv.FormatFloat(s.throughput(s.Succeeded), 'f', 2, 64),
+		formatMs(s.Mean),
+		formatMs(s.P50),
+		formatMs(s.P90),
+		formatMs(s.P95),
+		formatMs(s.P99),
+		formatMs(s.Max),
+	})
+	writer.Flush()
+	return writer.Error()
+}
+
+func formatMs(d time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 3, 64)
+}
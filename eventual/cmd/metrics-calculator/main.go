@@ -5,13 +5,16 @@ import (
 	"encoding/csv"
 	"log"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"cloud.google.com/go/firestore"
 	"github.com/joho/godotenv"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/internal/order"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/config"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/fault"
 	"google.golang.org/api/iterator"
 )
 
@@ -63,15 +66,38 @@ func main() {
 		orders = append(orders, o)
 	}
 
+	// Kegagalan yang disuntikkan selama pengujian chaos, dikelompokkan per order
+	faults := make(map[string]map[fault.Kind]int)
+
+	faultIter := client.Collection(fault.EventCollection).Documents(ctx)
+	for {
+		doc, err := faultIter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			log.Fatalf("Failed to iterate fault events: %v", err)
+		}
+
+		var e fault.Event
+		if err := doc.DataTo(&e); err != nil {
+			log.Fatalf("Failed to convert document to fault event: %v", err)
+		}
+		if faults[e.CorrelationID] == nil {
+			faults[e.CorrelationID] = make(map[fault.Kind]int)
+		}
+		faults[e.CorrelationID][e.Kind]++
+	}
+
 	// Export to CSV dengan nama file yang dikustomisasi
-	if err := exportOrdersToCSV(orders, outputFilename); err != nil {
+	if err := exportOrdersToCSV(orders, faults, outputFilename); err != nil {
 		log.Fatalf("Failed to export orders to CSV: %v", err)
 	}
 
 	log.Printf("Successfully exported %d orders to %s", len(orders), outputFilename)
 }
 
-func exportOrdersToCSV(orders []order.Order, filename string) error {
+func exportOrdersToCSV(orders []order.Order, faults map[string]map[fault.Kind]int, filename string) error {
 	file, err := os.Create(filename)
 	if err != nil {
 		return err
@@ -86,28 +112,63 @@ func exportOrdersToCSV(orders []order.Order, filename string) error {
 		"ID",
 		"UserID",
 		"Status",
-		"HotelRoomID",
-		"CarID",
-		"TrainSeatID",
-		"HotelStartDate",
-		"HotelEndDate",
-		"CarStartDate",
-		"CarEndDate",
-		"HotelReservationID",
-		"CarReservationID",
-		"TrainReservationID",
+		"SagaMode",
+		"QuoteID",
+		"TotalPrice",
+		"Currency",
+		"CancellationFee",
+		"RefundAmount",
+		"ModificationStatus",
+		"PriceDifference",
+		"HotelRoomIDs",
+		"HotelRoomTypeIDs",
+		"CarIDs",
+		"TrainJourneyIDs",
+		"TrainSeatIDs",
+		"FlightIDs",
+		"FlightSeatIDs",
+		"HotelStartDates",
+		"HotelEndDates",
+		"CarStartDates",
+		"CarEndDates",
+		"TrainDepartureDates",
+		"TrainOriginStations",
+		"TrainDestinationStations",
+		"FlightDepartureDates",
+		"HotelPrices",
+		"CarPrices",
+		"TrainPrices",
+		"FlightPrices",
+		"HotelReservationIDs",
+		"CarReservationIDs",
+		"TrainReservationIDs",
+		"FlightReservationIDs",
 		"HotelReservationStatus",
 		"CarReservationStatus",
 		"TrainReservationStatus",
+		"FlightReservationStatus",
+		"HotelItemStatuses",
+		"CarItemStatuses",
+		"TrainItemStatuses",
+		"FlightItemStatuses",
 		"HotelReservationFailureReason",
 		"CarReservationFailureReason",
 		"TrainReservationFailureReason",
+		"FlightReservationFailureReason",
+		"PaymentID",
+		"PaymentStatus",
+		"PaymentFailureReason",
 		"CarDoneAt",
 		"TrainDoneAt",
+		"FlightDoneAt",
 		"HotelDoneAt",
+		"PaymentDoneAt",
 		"DoneAt",
+		"CancelRequestedAt",
+		"CancelledAt",
 		"CreatedAt",
 		"UpdatedAt",
+		"Faults",
 	}
 
 	if err := writer.Write(headers); err != nil {
@@ -120,28 +181,63 @@ func exportOrdersToCSV(orders []order.Order, filename string) error {
 			o.ID,
 			o.UserID,
 			string(o.Status),
-			o.HotelRoomID,
-			o.CarID,
-			o.TrainSeatID,
-			o.HotelStartDate,
-			o.HotelEndDate,
-			o.CarStartDate,
-			o.CarEndDate,
-			o.HotelReservationID,
-			o.CarReservationID,
-			o.TrainReservationID,
+			o.SagaMode,
+			o.QuoteID,
+			strconv.FormatInt(o.TotalPrice, 10),
+			o.Currency,
+			strconv.FormatInt(o.CancellationFee, 10),
+			strconv.FormatInt(o.RefundAmount, 10),
+			modificationStatus(o.Modification),
+			priceDifference(o.Modification),
+			joinItems(o.HotelRooms, func(i order.HotelRoomItem) string { return i.HotelRoomID }),
+			joinItems(o.HotelRooms, func(i order.HotelRoomItem) string { return i.RoomTypeID }),
+			joinItems(o.Cars, func(i order.CarItem) string { return i.CarID }),
+			joinItems(o.TrainSeats, func(i order.TrainSeatItem) string { return i.JourneyID }),
+			joinItems(o.TrainSeats, func(i order.TrainSeatItem) string { return i.SeatID }),
+			joinItems(o.Flights, func(i order.FlightItem) string { return i.FlightID }),
+			joinItems(o.Flights, func(i order.FlightItem) string { return i.SeatID }),
+			joinItems(o.HotelRooms, func(i order.HotelRoomItem) string { return i.StartDate }),
+			joinItems(o.HotelRooms, func(i order.HotelRoomItem) string { return i.EndDate }),
+			joinItems(o.Cars, func(i order.CarItem) string { return i.StartDate }),
+			joinItems(o.Cars, func(i order.CarItem) string { return i.EndDate }),
+			joinItems(o.TrainSeats, func(i order.TrainSeatItem) string { return i.DepartureDate }),
+			joinItems(o.TrainSeats, func(i order.TrainSeatItem) string { return i.OriginStation }),
+			joinItems(o.TrainSeats, func(i order.TrainSeatItem) string { return i.DestinationStation }),
+			joinItems(o.Flights, func(i order.FlightItem) string { return i.DepartureDate }),
+			joinItems(o.HotelRooms, func(i order.HotelRoomItem) string { return strconv.FormatInt(i.Price, 10) }),
+			joinItems(o.Cars, func(i order.CarItem) string { return strconv.FormatInt(i.Price, 10) }),
+			joinItems(o.TrainSeats, func(i order.TrainSeatItem) string { return strconv.FormatInt(i.Price, 10) }),
+			joinItems(o.Flights, func(i order.FlightItem) string { return strconv.FormatInt(i.Price, 10) }),
+			joinItems(o.HotelRooms, func(i order.HotelRoomItem) string { return i.ReservationID }),
+			joinItems(o.Cars, func(i order.CarItem) string { return i.ReservationID }),
+			joinItems(o.TrainSeats, func(i order.TrainSeatItem) string { return i.ReservationID }),
+			joinItems(o.Flights, func(i order.FlightItem) string { return i.ReservationID }),
 			string(o.HotelReservationStatus),
 			string(o.CarReservationStatus),
 			string(o.TrainReservationStatus),
+			string(o.FlightReservationStatus),
+			joinItems(o.HotelRooms, func(i order.HotelRoomItem) string { return string(i.Status) }),
+			joinItems(o.Cars, func(i order.CarItem) string { return string(i.Status) }),
+			joinItems(o.TrainSeats, func(i order.TrainSeatItem) string { return string(i.Status) }),
+			joinItems(o.Flights, func(i order.FlightItem) string { return string(i.Status) }),
 			o.HotelReservationFailureReason,
 			o.CarReservationFailureReason,
 			o.TrainReservationFailureReason,
+			o.FlightReservationFailureReason,
+			o.PaymentID,
+			string(o.PaymentStatus),
+			o.PaymentFailureReason,
 			strconv.FormatInt(formatTime(o.CarDoneAt), 10),
 			strconv.FormatInt(formatTime(o.TrainDoneAt), 10),
+			strconv.FormatInt(formatTime(o.FlightDoneAt), 10),
 			strconv.FormatInt(formatTime(o.HotelDoneAt), 10),
+			strconv.FormatInt(formatTime(o.PaymentDoneAt), 10),
 			strconv.FormatInt(formatTime(o.DoneAt), 10),
+			strconv.FormatInt(formatTime(o.CancelRequestedAt), 10),
+			strconv.FormatInt(formatTime(o.CancelledAt), 10),
 			strconv.FormatInt(formatTime(o.CreatedAt), 10),
 			strconv.FormatInt(formatTime(o.UpdatedAt), 10),
+			formatFaults(faults[o.ID]),
 		}
 
 		if err := writer.Write(row); err != nil {
@@ -152,6 +248,46 @@ func exportOrdersToCSV(orders []order.Order, filename string) error {
 	return nil
 }
 
+// joinItems menggabungkan satu field dari setiap item dengan pemisah ";"
+// sesuai urutan item di order
+func joinItems[T any](items []T, field func(T) string) string {
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		values = append(values, field(item))
+	}
+	return strings.Join(values, ";")
+}
+
+// modificationStatus mengembalikan status modifikasi terakhir, kosong jika order tidak pernah dimodifikasi
+func modificationStatus(m *order.Modification) string {
+	if m == nil {
+		return ""
+	}
+	return string(m.Status)
+}
+
+func priceDifference(m *order.Modification) string {
+	if m == nil {
+		return ""
+	}
+	return strconv.FormatInt(m.PriceDifference, 10)
+}
+
+// formatFaults menuliskan jumlah kegagalan per jenis, misalnya "drop:1;duplicate:2"
+func formatFaults(counts map[fault.Kind]int) string {
+	kinds := make([]fault.Kind, 0, len(counts))
+	for kind := range counts {
+		kinds = append(kinds, kind)
+	}
+	slices.Sort(kinds)
+
+	values := make([]string, 0, len(kinds))
+	for _, kind := range kinds {
+		values = append(values, string(kind)+":"+strconv.Itoa(counts[kind]))
+	}
+	return strings.Join(values, ";")
+}
+
 func formatTime(t time.Time) int64 {
 	return t.UnixMilli()
 }
@@ -12,11 +12,17 @@ import (
 	"cloud.google.com/go/firestore"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
-	"github.com/rabbitmq/amqp091-go"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/internal/car"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/internal/hotel"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/internal/order"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/internal/pricing"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/internal/train"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/internal/waitlist"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/config"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/event"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/fault"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/messagebus"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/saga"
 )
 
 func main() {
@@ -34,7 +40,7 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
-	conn, err := amqp091.Dial(cfg.RabbitMQURL)
+	conn, err := messagebus.Dial(cfg.RabbitMQURL)
 	if err != nil {
 		log.Fatalf("Failed to connect to RabbitMQ: %v", err)
 	}
@@ -46,20 +52,86 @@ func main() {
 	}
 	defer client.Close()
 
-	publisher := messagebus.NewRabbitmqPublisher(conn)
+	faultConfig, err := fault.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load fault config: %v", err)
+	}
+	injector := fault.NewInjector("order-service", faultConfig, fault.NewFirestoreRecorder(client))
+
+	publisher := fault.NewPublisher(messagebus.NewRabbitmqPublisher(conn), injector)
+
+	pricingRepo := pricing.NewFirestoreRepository(client)
+	pricingService := pricing.NewService(pricingRepo, cfg.QuoteTTL)
+	pricingHandler := pricing.NewHandler(pricingService)
+
+	// Pencarian inventaris dibaca langsung dari koleksi milik tiap participant,
+	// service-nya tidak memproses event di sini
+	hotelHandler := hotel.NewHandler(hotel.NewService(hotel.NewFirestoreRepository(client), publisher, cfg.HoldTTL))
+	carHandler := car.NewHandler(car.NewService(car.NewFirestoreRepository(client), publisher, cfg.HoldTTL))
+	trainHandler := train.NewHandler(train.NewService(train.NewFirestoreRepository(client), publisher, cfg.HoldTTL))
 
 	orderRepo := order.NewFirestoreRepository(client)
-	orderService := order.NewService(orderRepo, publisher)
+	cancellationPolicy := order.CancellationPolicy{
+		FreeWindow: cfg.FreeCancellationWindow,
+		FeePercent: cfg.CancellationFeePercent,
+	}
+	orderService := order.NewService(orderRepo, pricingService, cancellationPolicy, publisher, cfg.SagaMode, saga.NewFirestoreHistory(client), cfg.SagaStepTimeout)
 	orderHandler := order.NewHandler(orderService)
 
-	subscriber := messagebus.NewRabbitmqSubscriber(conn)
-	subscriber.Subscribe(ctx, "", cfg.OrderQueueName, func(e event.Message) {
-		orderService.ProcessSagaEvent(ctx, e)
-	})
+	waitlistService := waitlist.NewService(waitlist.NewFirestoreRepository(client), orderRepo, orderService)
+	waitlistHandler := waitlist.NewHandler(waitlistService)
+
+	subscriber := fault.NewSubscriber(messagebus.NewRabbitmqSubscriber(conn), injector)
+	if err := subscriber.Subscribe(ctx, "", cfg.OrderQueueName, func(e event.Message) {
+		if err := orderService.ProcessSagaEvent(ctx, e); err != nil {
+			log.Printf("Failed to process %s for %s: %v", e.EventName, e.CorrelationID, err)
+		}
+		if err := waitlistService.ProcessReleaseEvent(ctx, e); err != nil {
+			log.Printf("Failed to process waitlist for %s of %s: %v", e.EventName, e.CorrelationID, err)
+		}
+	}); err != nil {
+		log.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	// Sweeper mengompensasi order yang step saga-nya tidak dibalas sebelum batas waktu,
+	// batas waktu step hanya berlaku pada saga orkestrasi
+	if cfg.SagaMode == config.SagaModeOrchestration && cfg.SagaStepTimeout > 0 {
+		go func() {
+			ticker := time.NewTicker(cfg.SagaTimeoutSweepInterval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					if err := orderService.ExpireSagaSteps(ctx); err != nil {
+						log.Printf("Failed to expire saga steps: %v", err)
+					}
+				}
+			}
+		}()
+	}
 
 	// Start HTTP server
 	router := gin.Default()
+	router.POST("/quotes", pricingHandler.CreateQuote)
 	router.POST("/orders", orderHandler.CreateOrder)
+	router.GET("/orders/:id", orderHandler.GetOrder)
+	router.POST("/orders/:id/cancel", orderHandler.CancelOrder)
+	router.PATCH("/orders/:id", orderHandler.ModifyOrder)
+	router.POST("/orders/holds", orderHandler.HoldOrder)
+	router.POST("/orders/:id/confirm", orderHandler.ConfirmHold)
+	router.GET("/hotel-rooms", hotelHandler.SearchHotelRooms)
+	router.GET("/hotel-rooms/:id/calendar", hotelHandler.GetHotelRoomCalendar)
+	router.POST("/hotel-reservations/:id/assign-room", hotelHandler.AssignHotelRoom)
+	router.GET("/cars", carHandler.SearchCars)
+	router.GET("/cars/:id/calendar", carHandler.GetCarCalendar)
+	router.GET("/train-journeys/:id/seats", trainHandler.SearchTrainSeats)
+	router.POST("/waitlist", waitlistHandler.Register)
+	router.GET("/waitlist/:id", waitlistHandler.GetEntry)
+	router.DELETE("/waitlist/:id", waitlistHandler.CancelEntry)
+	fault.NewHandler(injector).RegisterRoutes(router)
 
 	// Create HTTP server with proper shutdown handling
 	srv := &http.Server{
@@ -99,9 +171,8 @@ func main() {
 		log.Printf("HTTP server shutdown error: %v", err)
 	}
 
-	// Note: Subscriber goroutines will be terminated when context is cancelled
-	// and connection is closed. The current implementation doesn't provide
-	// explicit unsubscribe functionality.
+	// Note: Subscriber goroutines close their channels when context is cancelled
+	// and stop re-establishing consumers once the connection is closed.
 
 	log.Println("Order service stopped gracefully")
 }
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"cloud.google.com/go/firestore"
+	"github.com/joho/godotenv"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/internal/payment"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/config"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/event"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/fault"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/messagebus"
+)
+
+func main() {
+	// Create a cancellable context
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	log.Println("Starting payment service")
+	if err := godotenv.Load(); err != nil {
+		log.Printf("Error loading .env file: %v, using system environment variables", err)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	conn, err := messagebus.Dial(cfg.RabbitMQURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to RabbitMQ: %v", err)
+	}
+	defer conn.Close()
+
+	client, err := firestore.NewClient(ctx, cfg.GoogleProjectID)
+	if err != nil {
+		log.Fatalf("Failed to create Firestore client: %v", err)
+	}
+	defer client.Close()
+
+	faultConfig, err := fault.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load fault config: %v", err)
+	}
+	injector := fault.NewInjector("payment-service", faultConfig, fault.NewFirestoreRecorder(client))
+	if cfg.FaultAdminPort != "" {
+		go fault.ServeAdmin(ctx, cfg.FaultAdminPort, injector)
+	}
+
+	publisher := fault.NewPublisher(messagebus.NewRabbitmqPublisher(conn), injector)
+
+	paymentRepo := payment.NewFaultyRepository(payment.NewFirestoreRepository(client), injector)
+	paymentProvider := payment.NewFakeProvider(cfg.FakePaymentDeclineAbove, cfg.FakePaymentLatency)
+	paymentService := payment.NewService(paymentRepo, paymentProvider, publisher)
+
+	subscriber := fault.NewSubscriber(messagebus.NewRabbitmqSubscriber(conn), injector)
+	if err := subscriber.Subscribe(ctx, "", cfg.PaymentQueueName, func(e event.Message) {
+		if err := paymentService.ProcessSagaEvent(ctx, e); err != nil {
+			log.Printf("Failed to process %s for %s: %v", e.EventName, e.CorrelationID, err)
+		}
+		// Pada saga koreografi service juga bereaksi terhadap event partisipan lain
+		if cfg.SagaMode == config.SagaModeChoreography {
+			if err := paymentService.ProcessChoreographyEvent(ctx, e); err != nil {
+				log.Printf("Failed to process %s for %s: %v", e.EventName, e.CorrelationID, err)
+			}
+		}
+	}); err != nil {
+		log.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	log.Println("Payment service started")
+
+	// Wait for interrupt signal to gracefully shutdown the server
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case <-ctx.Done():
+		log.Println("Context done, shutting down")
+	case <-signals:
+		log.Println("Received shutdown signal, shutting down")
+	}
+
+	// Cancel context to stop all operations
+	cancel()
+
+	// Note: Subscriber goroutines close their channels when context is cancelled
+	// and stop re-establishing consumers once the connection is closed.
+
+	log.Println("Payment service stopped gracefully")
+}
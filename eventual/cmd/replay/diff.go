@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// transition adalah pasangan hasil rekaman dan hasil replay satu order
+type transition struct {
+	Recorded outcome
+	Replayed outcome
+}
+
+// diff adalah jumlah order untuk setiap pasangan hasil rekaman dan replay
+type diff struct {
+	Orders      int
+	Matched     int
+	Transitions map[transition]int
+}
+
+func compare(results []result) diff {
+	d := diff{Orders: len(results), Transitions: make(map[transition]int)}
+	for _, res := range results {
+		t := transition{Recorded: res.Recorded.Outcome, Replayed: res.Outcome}
+		d.Transitions[t]++
+		if t.Recorded == t.Replayed {
+			d.Matched++
+		}
+	}
+	return d
+}
+
+// sorted mengembalikan pasangan hasil yang berbeda lebih dulu, lalu yang terbanyak
+func (d diff) sorted() []transition {
+	transitions := make([]transition, 0, len(d.Transitions))
+	for t := range d.Transitions {
+		transitions = append(transitions, t)
+	}
+	sort.Slice(transitions, func(i, j int) bool {
+		a, b := transitions[i], transitions[j]
+		if (a.Recorded == a.Replayed) != (b.Recorded == b.Replayed) {
+			return a.Recorded != a.Replayed
+		}
+		if d.Transitions[a] != d.Transitions[b] {
+			return d.Transitions[a] > d.Transitions[b]
+		}
+		return a.Recorded+a.Replayed < b.Recorded+b.Replayed
+	})
+	return transitions
+}
+
+// writeDiff menulis hasil rekaman dan replay setiap order ke CSV sesuai urutan kirim
+func writeDiff(filename string, results []result) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", filename, err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write([]string{
+		"RecordedID",
+		"UserID",
+		"OffsetMs",
+		"RecordedStatus",
+		"RecordedOutcome",
+		"ReplayedID",
+		"StatusCode",
+		"LatencyMs",
+		"ReplayedStatus",
+		"ReplayedOutcome",
+		"Match",
+		"Error",
+	})
+	for _, res := range results {
+		writer.Write([]string{
+			res.Recorded.ID,
+			res.Recorded.Payload.UserID,
+			formatMs(res.Offset),
+			res.Recorded.Status,
+			string(res.Recorded.Outcome),
+			res.OrderID,
+			strconv.Itoa(res.StatusCode),
+			formatMs(res.Latency),
+			res.Status,
+			string(res.Outcome),
+			strconv.FormatBool(res.Recorded.Outcome == res.Outcome),
+			res.Err,
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func formatMs(d time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 3, 64)
+}
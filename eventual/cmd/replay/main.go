@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+)
+
+// options adalah konfigurasi replay dari flag command line
+type options struct {
+	baseURL      string
+	speed        float64
+	count        int
+	concurrency  int
+	timeout      time.Duration
+	settle       time.Duration
+	pollInterval time.Duration
+	output       string
+}
+
+// Replay mengirim ulang order hasil export metrics-calculator (eventual_*.csv atau
+// twophase_*.csv) ke environment baru dengan jarak waktu antar order yang sama seperti
+// rekaman, lalu membandingkan hasil akhir setiap order dengan rekamannya. Environment
+// tujuan sebaiknya baru direset agar ketersediaan item sama seperti saat rekaman dimulai.
+func main() {
+	var opts options
+	flag.StringVar(&opts.baseURL, "url", "http://localhost:8080", "base URL order service (EC) atau coordinator (2PC)")
+	flag.Float64Var(&opts.speed, "speed", 1, "pengali kecepatan replay, misalnya 2 untuk jarak antar order setengah dari rekaman")
+	flag.IntVar(&opts.count, "count", 0, "jumlah order pertama yang dikirim ulang, 0 berarti seluruh order")
+	flag.IntVar(&opts.concurrency, "concurrency", 1000, "batas request yang berjalan bersamaan")
+	flag.DurationVar(&opts.timeout, "timeout", 30*time.Second, "batas waktu setiap request")
+	flag.DurationVar(&opts.settle, "settle", 5*time.Minute, "batas waktu menunggu seluruh order selesai setelah dikirim")
+	flag.DurationVar(&opts.pollInterval, "poll-interval", 2*time.Second, "jarak pembacaan ulang status order yang belum selesai")
+	flag.StringVar(&opts.output, "output", "replay-diff.csv", "file CSV hasil rekaman dan replay setiap order")
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		log.Fatalf("Usage: %s [flags] <eventual_N.csv|twophase_N.csv>\n"+
+			"twophase_N.csv must be exported by the current metrics-calculator, older 2PC exports have no item columns", os.Args[0])
+	}
+	if opts.speed <= 0 {
+		log.Fatalf("-speed must be positive")
+	}
+	if opts.concurrency <= 0 {
+		log.Fatalf("-concurrency must be positive")
+	}
+
+	rec, err := loadRecording(flag.Arg(0))
+	if err != nil {
+		log.Fatalf("Failed to load recording: %v", err)
+	}
+	if rec.Skipped > 0 {
+		log.Printf("Skipped %d orders without items, export the recording again with the current metrics-calculator to replay them", rec.Skipped)
+	}
+	if rec.DroppedTrainSeats > 0 {
+		log.Printf("Dropped %d train seats of the legacy recording, they are not bound to a dated journey", rec.DroppedTrainSeats)
+	}
+	orders := rec.Orders
+	if opts.count > 0 && opts.count < len(orders) {
+		orders = orders[:opts.count]
+	}
+	if len(orders) == 0 {
+		log.Fatalf("No orders to replay in %s", flag.Arg(0))
+	}
+
+	// Replay berhenti saat dihentikan dengan Ctrl+C. Order yang belum dikirim dicatat
+	// sebagai not_sent dan request yang sedang berjalan tetap ditunggu sampai selesai.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = opts.concurrency
+	transport.MaxIdleConnsPerHost = opts.concurrency
+
+	r := &replayer{
+		client:  &http.Client{Transport: transport, Timeout: opts.timeout},
+		baseURL: strings.TrimRight(opts.baseURL, "/"),
+		opts:    opts,
+	}
+
+	span := time.Duration(orders[len(orders)-1].CreatedAt-orders[0].CreatedAt) * time.Millisecond
+	log.Printf("Replaying %d %s orders recorded over %s to %s at %gx speed...", len(orders), rec.Architecture, span, r.baseURL, opts.speed)
+	started := time.Now()
+	results := r.run(ctx, orders)
+	log.Printf("Sent orders in %s", time.Since(started).Round(time.Millisecond))
+
+	r.settle(ctx, results)
+
+	if err := writeDiff(opts.output, results); err != nil {
+		log.Fatalf("Failed to write %s: %v", opts.output, err)
+	}
+
+	d := compare(results)
+	log.Printf("Outcomes matched the recording for %d of %d orders", d.Matched, d.Orders)
+	for _, t := range d.sorted() {
+		log.Printf("  recorded %s, replayed %s: %d", t.Recorded, t.Replayed, d.Transitions[t])
+	}
+	log.Printf("Results written to %s", opts.output)
+}
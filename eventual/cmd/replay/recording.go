@@ -0,0 +1,294 @@
+package main
+
+import (
+	"cmp"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/internal/order"
+)
+
+// outcome adalah hasil akhir order yang dibandingkan antara rekaman dan replay
+type outcome string
+
+const (
+	outcomeSucceeded outcome = "succeeded"
+	outcomeFailed    outcome = "failed"
+	// outcomePending berarti order belum selesai saat rekaman diambil atau saat -settle habis
+	outcomePending outcome = "pending"
+	// outcomeRejected berarti POST /orders gagal atau dijawab dengan status selain 2xx
+	outcomeRejected outcome = "rejected"
+	// outcomeNotSent berarti replay dihentikan sebelum order dikirim
+	outcomeNotSent outcome = "not_sent"
+)
+
+// finalOutcomes adalah status akhir order EC dan transaksi booking 2PC. Order yang sudah
+// BOOKED lalu dibatalkan atau dimodifikasi tetap dihitung berhasil, status lain berarti
+// order belum selesai.
+var finalOutcomes = map[string]outcome{
+	"BOOKED":      outcomeSucceeded,
+	"CANCELLING":  outcomeSucceeded,
+	"CANCELLED":   outcomeSucceeded,
+	"MODIFYING":   outcomeSucceeded,
+	"FAILED":      outcomeFailed,
+	"committed":   outcomeSucceeded,
+	"cancelled":   outcomeSucceeded,
+	"aborted":     outcomeFailed,
+	"rolled_back": outcomeFailed,
+	"timed_out":   outcomeFailed,
+}
+
+// outcomeOf mengembalikan hasil akhir dari status order EC atau transaksi 2PC
+func outcomeOf(status string) outcome {
+	if o, ok := finalOutcomes[status]; ok {
+		return o
+	}
+	return outcomePending
+}
+
+// recordedOrder adalah satu order hasil rekaman. ID adalah ID order (EC) atau ID
+// transaksi booking (2PC) dan CreatedAt dalam milidetik unix.
+type recordedOrder struct {
+	ID        string
+	CreatedAt int64
+	Status    string
+	Outcome   outcome
+	Payload   order.CreateOrderPayload
+}
+
+// recording adalah seluruh order satu CSV metrics-calculator, terurut berdasarkan CreatedAt
+type recording struct {
+	Architecture string
+	Orders       []recordedOrder
+	// Skipped adalah order tanpa item, yaitu transaksi booking 2PC yang log-nya dibuat
+	// sebelum coordinator menyimpan item order
+	Skipped int
+	// DroppedTrainSeats adalah kursi kereta rekaman lama yang tidak ikut dikirim ulang
+	DroppedTrainSeats int
+}
+
+// loadRecording membaca CSV metrics-calculator EC atau 2PC. Arsitektur ditentukan dari
+// header, sehingga nama file bebas.
+func loadRecording(filename string) (*recording, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", filename, err)
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%s: missing header", filename)
+	}
+
+	columns := make(map[string]int, len(records[0]))
+	for i, column := range records[0] {
+		columns[column] = i
+	}
+
+	rec := &recording{}
+	switch {
+	case has(columns, "Participants"):
+		rec.Architecture = "twophase"
+	case has(columns, "HotelReservationStatus"):
+		rec.Architecture = "eventual"
+	default:
+		return nil, fmt.Errorf("%s: not an eventual or twophase metrics-calculator export", filename)
+	}
+	// Export EC sebelum order berisi banyak item memakai kolom tunggal HotelRoomID,
+	// HotelStartDate dan seterusnya. Export 2PC sebelum replay tersedia tidak memiliki
+	// kolom item sama sekali karena transaction log belum menyimpan item order.
+	legacy := false
+	switch {
+	case has(columns, "UserID") && has(columns, "HotelStartDates"):
+	case rec.Architecture == "eventual" && has(columns, "HotelStartDate"):
+		legacy = true
+	default:
+		return nil, fmt.Errorf("%s: missing user and item columns, export the recording again with the current metrics-calculator", filename)
+	}
+
+	for _, record := range records[1:] {
+		r := row{columns: columns, values: record}
+		// Transaksi cancellation dan modification 2PC bukan order baru
+		if kind := r.get("Kind"); kind != "" && kind != "booking" {
+			continue
+		}
+
+		var payload order.CreateOrderPayload
+		if legacy {
+			var dropped int
+			payload, dropped = r.legacyPayload()
+			rec.DroppedTrainSeats += dropped
+		} else if payload, err = r.payload(); err != nil {
+			return nil, fmt.Errorf("%s: order %s: %w", filename, r.get("ID"), err)
+		}
+		if len(payload.HotelRooms)+len(payload.Cars)+len(payload.TrainSeats)+len(payload.Flights) == 0 {
+			rec.Skipped++
+			continue
+		}
+
+		status := r.get("Status")
+		rec.Orders = append(rec.Orders, recordedOrder{
+			ID:        r.get("ID"),
+			CreatedAt: r.millis("CreatedAt"),
+			Status:    status,
+			Outcome:   outcomeOf(status),
+			Payload:   payload,
+		})
+	}
+
+	slices.SortStableFunc(rec.Orders, func(a, b recordedOrder) int {
+		return cmp.Compare(a.CreatedAt, b.CreatedAt)
+	})
+	return rec, nil
+}
+
+func has(columns map[string]int, column string) bool {
+	_, ok := columns[column]
+	return ok
+}
+
+// payload menyusun ulang body POST /orders dari kolom item. Setiap kolom berisi satu nilai
+// per item dengan pemisah ";" sesuai urutan item di order. QuoteID tidak ikut dikirim
+// karena quote rekaman tidak ada di environment baru, sehingga harga dihitung ulang.
+func (r row) payload() (order.CreateOrderPayload, error) {
+	payload := order.CreateOrderPayload{UserID: r.get("UserID")}
+
+	hotel, err := r.items(r.count("HotelStartDates"), "HotelRoomIDs", "HotelRoomTypeIDs", "HotelStartDates", "HotelEndDates")
+	if err != nil {
+		return payload, err
+	}
+	for _, item := range hotel {
+		payload.HotelRooms = append(payload.HotelRooms, order.HotelRoomRequest{
+			HotelRoomID: item[0],
+			RoomTypeID:  item[1],
+			StartDate:   item[2],
+			EndDate:     item[3],
+		})
+	}
+
+	car, err := r.items(r.count("CarIDs"), "CarIDs", "CarStartDates", "CarEndDates")
+	if err != nil {
+		return payload, err
+	}
+	for _, item := range car {
+		payload.Cars = append(payload.Cars, order.CarRequest{
+			CarID:     item[0],
+			StartDate: item[1],
+			EndDate:   item[2],
+		})
+	}
+
+	train, err := r.items(r.count("TrainSeatIDs"), "TrainJourneyIDs", "TrainDepartureDates", "TrainSeatIDs", "TrainOriginStations", "TrainDestinationStations")
+	if err != nil {
+		return payload, err
+	}
+	for _, item := range train {
+		payload.TrainSeats = append(payload.TrainSeats, order.TrainSeatRequest{
+			JourneyID:          item[0],
+			DepartureDate:      item[1],
+			SeatID:             item[2],
+			OriginStation:      item[3],
+			DestinationStation: item[4],
+		})
+	}
+
+	flight, err := r.items(r.count("FlightSeatIDs"), "FlightIDs", "FlightDepartureDates", "FlightSeatIDs")
+	if err != nil {
+		return payload, err
+	}
+	for _, item := range flight {
+		payload.Flights = append(payload.Flights, order.FlightRequest{
+			FlightID:      item[0],
+			DepartureDate: item[1],
+			SeatID:        item[2],
+		})
+	}
+
+	return payload, nil
+}
+
+// legacyPayload menyusun ulang body POST /orders dari kolom tunggal export EC lama yang
+// berisi paling banyak satu kamar, satu mobil dan satu kursi kereta. Kursi kereta saat itu
+// belum terikat perjalanan bertanggal sehingga tidak dapat dipesan ulang dan hanya
+// dihitung pada dropped.
+func (r row) legacyPayload() (payload order.CreateOrderPayload, dropped int) {
+	payload.UserID = r.get("UserID")
+	if r.get("HotelRoomID") != "" {
+		payload.HotelRooms = append(payload.HotelRooms, order.HotelRoomRequest{
+			HotelRoomID: r.get("HotelRoomID"),
+			StartDate:   r.get("HotelStartDate"),
+			EndDate:     r.get("HotelEndDate"),
+		})
+	}
+	if r.get("CarID") != "" {
+		payload.Cars = append(payload.Cars, order.CarRequest{
+			CarID:     r.get("CarID"),
+			StartDate: r.get("CarStartDate"),
+			EndDate:   r.get("CarEndDate"),
+		})
+	}
+	if r.get("TrainSeatID") != "" {
+		dropped++
+	}
+	return payload, dropped
+}
+
+// count mengembalikan jumlah item pada kolom yang selalu terisi untuk setiap item
+func (r row) count(column string) int {
+	if r.get(column) == "" {
+		return 0
+	}
+	return strings.Count(r.get(column), ";") + 1
+}
+
+// items mengembalikan n item yang masing-masing berisi nilai dari setiap kolom. Kolom
+// boleh berisi nilai kosong, misalnya HotelRoomIDs untuk pesanan tipe kamar.
+func (r row) items(n int, columns ...string) ([][]string, error) {
+	if n == 0 {
+		return nil, nil
+	}
+
+	items := make([][]string, n)
+	for _, column := range columns {
+		values := strings.Split(r.get(column), ";")
+		if len(values) != n {
+			return nil, fmt.Errorf("column %s has %d items, expected %d", column, len(values), n)
+		}
+		for i, value := range values {
+			items[i] = append(items[i], value)
+		}
+	}
+	return items, nil
+}
+
+// row adalah satu baris CSV yang kolomnya diakses berdasarkan nama header
+type row struct {
+	columns map[string]int
+	values  []string
+}
+
+// get mengembalikan isi kolom, kosong jika kolom tidak ada
+func (r row) get(column string) string {
+	i, ok := r.columns[column]
+	if !ok || i >= len(r.values) {
+		return ""
+	}
+	return r.values[i]
+}
+
+// millis membaca waktu unix milidetik. Waktu kosong atau zero time Go (negatif) menjadi 0.
+func (r row) millis(column string) int64 {
+	value, err := strconv.ParseInt(r.get(column), 10, 64)
+	if err != nil || value < 0 {
+		return 0
+	}
+	return value
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/internal/order"
+)
+
+// newRow membentuk row dari pasangan nama kolom dan nilainya
+func newRow(fields map[string]string) row {
+	r := row{columns: make(map[string]int, len(fields))}
+	for column, value := range fields {
+		r.columns[column] = len(r.values)
+		r.values = append(r.values, value)
+	}
+	return r
+}
+
+func TestRowItems(t *testing.T) {
+	tests := []struct {
+		name    string
+		fields  map[string]string
+		n       int
+		columns []string
+		want    [][]string
+		wantErr bool
+	}{
+		{
+			name:    "no items",
+			fields:  map[string]string{"CarIDs": ""},
+			columns: []string{"CarIDs"},
+		},
+		{
+			name:    "items are zipped by position",
+			fields:  map[string]string{"CarIDs": "car-1;car-2", "CarStartDates": "2025-12-01;2025-12-03"},
+			n:       2,
+			columns: []string{"CarIDs", "CarStartDates"},
+			want:    [][]string{{"car-1", "2025-12-01"}, {"car-2", "2025-12-03"}},
+		},
+		{
+			name:    "empty values are kept",
+			fields:  map[string]string{"HotelRoomIDs": ";room-2", "HotelRoomTypeIDs": "deluxe;"},
+			n:       2,
+			columns: []string{"HotelRoomIDs", "HotelRoomTypeIDs"},
+			want:    [][]string{{"", "deluxe"}, {"room-2", ""}},
+		},
+		{
+			name:    "column with fewer items",
+			fields:  map[string]string{"CarIDs": "car-1;car-2", "CarStartDates": "2025-12-01"},
+			n:       2,
+			columns: []string{"CarIDs", "CarStartDates"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newRow(tt.fields).items(tt.n, tt.columns...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("items = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRowPayload(t *testing.T) {
+	tests := []struct {
+		name    string
+		fields  map[string]string
+		want    order.CreateOrderPayload
+		wantErr bool
+	}{
+		{
+			name: "every item kind",
+			fields: map[string]string{
+				"UserID":                   "user-1",
+				"HotelRoomIDs":             ";room-2",
+				"HotelRoomTypeIDs":         "deluxe;",
+				"HotelStartDates":          "2025-12-01;2025-12-02",
+				"HotelEndDates":            "2025-12-02;2025-12-03",
+				"CarIDs":                   "car-1",
+				"CarStartDates":            "2025-12-01",
+				"CarEndDates":              "2025-12-02",
+				"TrainJourneyIDs":          "journey-1",
+				"TrainDepartureDates":      "2025-12-01",
+				"TrainSeatIDs":             "seat-1",
+				"TrainOriginStations":      "GMR",
+				"TrainDestinationStations": "BD",
+				"FlightIDs":                "flight-1",
+				"FlightDepartureDates":     "2025-12-01",
+				"FlightSeatIDs":            "12A",
+			},
+			want: order.CreateOrderPayload{
+				UserID: "user-1",
+				HotelRooms: []order.HotelRoomRequest{
+					{RoomTypeID: "deluxe", StartDate: "2025-12-01", EndDate: "2025-12-02"},
+					{HotelRoomID: "room-2", StartDate: "2025-12-02", EndDate: "2025-12-03"},
+				},
+				Cars:       []order.CarRequest{{CarID: "car-1", StartDate: "2025-12-01", EndDate: "2025-12-02"}},
+				TrainSeats: []order.TrainSeatRequest{{JourneyID: "journey-1", DepartureDate: "2025-12-01", SeatID: "seat-1", OriginStation: "GMR", DestinationStation: "BD"}},
+				Flights:    []order.FlightRequest{{FlightID: "flight-1", DepartureDate: "2025-12-01", SeatID: "12A"}},
+			},
+		},
+		{
+			name:   "order without items",
+			fields: map[string]string{"UserID": "user-1", "HotelStartDates": ""},
+			want:   order.CreateOrderPayload{UserID: "user-1"},
+		},
+		{
+			name: "mismatched item columns",
+			fields: map[string]string{
+				"UserID":          "user-1",
+				"HotelStartDates": "2025-12-01;2025-12-02",
+				"HotelEndDates":   "2025-12-02",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newRow(tt.fields).payload()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("payload = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRowLegacyPayload(t *testing.T) {
+	got, dropped := newRow(map[string]string{
+		"UserID":         "user-1",
+		"HotelRoomID":    "room-1",
+		"HotelStartDate": "2025-12-01",
+		"HotelEndDate":   "2025-12-02",
+		"CarID":          "",
+		"TrainSeatID":    "seat-1",
+	}).legacyPayload()
+
+	want := order.CreateOrderPayload{
+		UserID:     "user-1",
+		HotelRooms: []order.HotelRoomRequest{{HotelRoomID: "room-1", StartDate: "2025-12-01", EndDate: "2025-12-02"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("payload = %+v, want %+v", got, want)
+	}
+	if dropped != 1 {
+		t.Errorf("dropped = %d, want 1", dropped)
+	}
+}
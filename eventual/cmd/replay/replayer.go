@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxResponseBody membatasi isi respons yang dibaca
+const maxResponseBody = 64 << 10
+
+// result adalah hasil replay satu order rekaman
+type result struct {
+	Recorded recordedOrder
+	// Offset adalah jadwal kirim sejak replay dimulai, sudah diskalakan dengan -speed
+	Offset     time.Duration
+	Latency    time.Duration
+	StatusCode int
+	Err        string
+	OrderID    string
+	Status     string
+	Outcome    outcome
+	// statusURL adalah endpoint status order (EC) atau transaksi booking (2PC) hasil replay
+	statusURL string
+}
+
+// orderResponse berisi field respons POST /orders dan endpoint status kedua arsitektur.
+// EC mengembalikan order dengan id, 2PC mengembalikan order_id dan transaction_id.
+type orderResponse struct {
+	ID            string `json:"id"`
+	OrderID       string `json:"order_id"`
+	TransactionID string `json:"transaction_id"`
+	Status        string `json:"status"`
+}
+
+// replayer mengirim ulang order rekaman ke order service (EC) atau coordinator (2PC)
+type replayer struct {
+	client  *http.Client
+	baseURL string
+	opts    options
+}
+
+// run mengirim setiap order dengan jarak waktu yang sama seperti rekaman, dibagi -speed.
+// Paling banyak opts.concurrency request berjalan bersamaan; latensi diukur dari jadwal
+// kirim sehingga waktu antre saat batas tercapai ikut terhitung.
+func (r *replayer) run(ctx context.Context, orders []recordedOrder) []result {
+	results := make([]result, len(orders))
+	for i, o := range orders {
+		results[i] = result{Recorded: o, Offset: r.offset(orders[0], o), Outcome: outcomeNotSent}
+	}
+
+	var wg sync.WaitGroup
+	inFlight := make(chan struct{}, r.opts.concurrency)
+	start := time.Now()
+
+loop:
+	for i := range results {
+		scheduledAt := start.Add(results[i].Offset)
+		select {
+		case <-time.After(time.Until(scheduledAt)):
+		case <-ctx.Done():
+			break loop
+		}
+		select {
+		case inFlight <- struct{}{}:
+		case <-ctx.Done():
+			break loop
+		}
+
+		wg.Add(1)
+		go func(res *result, scheduledAt time.Time) {
+			defer wg.Done()
+			defer func() { <-inFlight }()
+			r.send(res, scheduledAt)
+		}(&results[i], scheduledAt)
+	}
+	wg.Wait()
+	return results
+}
+
+// offset adalah jarak CreatedAt order terhadap order pertama rekaman, dibagi -speed
+func (r *replayer) offset(first, o recordedOrder) time.Duration {
+	elapsed := time.Duration(o.CreatedAt-first.CreatedAt) * time.Millisecond
+	return time.Duration(float64(elapsed) / r.opts.speed)
+}
+
+// send mengirim POST /orders dan mencatat order hasil replay beserta endpoint statusnya
+func (r *replayer) send(res *result, startedAt time.Time) {
+	res.Outcome = outcomeRejected
+
+	body, err := json.Marshal(res.Recorded.Payload)
+	if err != nil {
+		res.Err = err.Error()
+		return
+	}
+
+	resp, err := r.client.Post(r.baseURL+"/orders", "application/json", bytes.NewReader(body))
+	if err != nil {
+		res.Latency = time.Since(startedAt)
+		res.Err = err.Error()
+		return
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	io.Copy(io.Discard, resp.Body)
+	res.Latency = time.Since(startedAt)
+	res.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		res.Err = strings.TrimSpace(string(respBody))
+		return
+	}
+
+	var created orderResponse
+	if err := json.Unmarshal(respBody, &created); err != nil {
+		res.Err = fmt.Sprintf("failed to decode response: %v", err)
+		return
+	}
+	if created.TransactionID != "" {
+		res.OrderID = created.OrderID
+		res.statusURL = r.baseURL + "/transactions/" + created.TransactionID
+	} else {
+		res.OrderID = created.ID
+		res.statusURL = r.baseURL + "/orders/" + created.ID
+	}
+	res.Status = created.Status
+	res.Outcome = outcomeOf(created.Status)
+}
+
+// settle membaca ulang status order yang belum selesai setiap opts.pollInterval sampai
+// seluruhnya selesai atau opts.settle habis
+func (r *replayer) settle(ctx context.Context, results []result) {
+	deadline := time.Now().Add(r.opts.settle)
+	for {
+		var pending []*result
+		for i := range results {
+			if results[i].Outcome == outcomePending && results[i].statusURL != "" {
+				pending = append(pending, &results[i])
+			}
+		}
+		if len(pending) == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			log.Printf("%d orders did not finish within %s", len(pending), r.opts.settle)
+			return
+		}
+
+		log.Printf("Waiting for %d orders to finish...", len(pending))
+		select {
+		case <-time.After(r.opts.pollInterval):
+		case <-ctx.Done():
+			return
+		}
+
+		var wg sync.WaitGroup
+		inFlight := make(chan struct{}, r.opts.concurrency)
+		for _, res := range pending {
+			inFlight <- struct{}{}
+			wg.Add(1)
+			go func(res *result) {
+				defer wg.Done()
+				defer func() { <-inFlight }()
+				if err := r.poll(ctx, res); err != nil {
+					res.Err = err.Error()
+				}
+			}(res)
+		}
+		wg.Wait()
+	}
+}
+
+// poll membaca status terbaru satu order hasil replay
+func (r *replayer) poll(ctx context.Context, res *result) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, res.statusURL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned %d: %s", res.statusURL, resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	var current orderResponse
+	if err := json.Unmarshal(respBody, &current); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", res.statusURL, err)
+	}
+	res.Status = current.Status
+	res.Outcome = outcomeOf(current.Status)
+	res.Err = ""
+	return nil
+}
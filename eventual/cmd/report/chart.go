@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"os"
+	"strings"
+)
+
+// seriesColors adalah warna batang setiap series sesuai urutan
+var seriesColors = []string{"#4e79a7", "#f28e2b", "#59a14f", "#e15759"}
+
+// series adalah satu kelompok batang pada grafik, satu nilai untuk setiap kategori
+type series struct {
+	Name   string
+	Values []float64
+}
+
+// barChart adalah grafik batang berkelompok: setiap kategori berisi satu batang per series
+type barChart struct {
+	Title      string
+	Unit       string
+	Categories []string
+	Series     []series
+}
+
+// write menulis grafik sebagai SVG tanpa dependensi eksternal
+func (c barChart) write(filename string) error {
+	const (
+		width  = 720
+		height = 400
+		left   = 80
+		right  = 20
+		top    = 50
+		bottom = 70
+		ticks  = 5
+	)
+	plotWidth := float64(width - left - right)
+	plotHeight := float64(height - top - bottom)
+
+	var maxValue float64
+	for _, s := range c.Series {
+		for _, value := range s.Values {
+			maxValue = max(maxValue, value)
+		}
+	}
+	maxValue = niceMax(maxValue)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="12">`+"\n", width, height, width, height)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#ffffff"/>`+"\n", width, height)
+	fmt.Fprintf(&b, `<text x="%d" y="28" text-anchor="middle" font-size="16">%s</text>`+"\n", width/2, html.EscapeString(c.Title))
+
+	// Sumbu Y beserta garis bantu
+	for i := 0; i <= ticks; i++ {
+		y := float64(top) + plotHeight - plotHeight*float64(i)/ticks
+		fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#dddddd"/>`+"\n", left, y, width-right, y)
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" text-anchor="end" dominant-baseline="middle">%s</text>`+"\n", left-8, y, formatNumber(maxValue*float64(i)/ticks))
+	}
+	fmt.Fprintf(&b, `<text transform="translate(18 %.1f) rotate(-90)" text-anchor="middle">%s</text>`+"\n", float64(top)+plotHeight/2, html.EscapeString(c.Unit))
+
+	// Batang setiap kategori, 80% lebar kategori dibagi rata ke setiap series
+	if len(c.Categories) > 0 && len(c.Series) > 0 {
+		groupWidth := plotWidth / float64(len(c.Categories))
+		barWidth := groupWidth * 0.8 / float64(len(c.Series))
+		for ci, category := range c.Categories {
+			x := float64(left) + groupWidth*float64(ci) + groupWidth*0.1
+			for si, s := range c.Series {
+				if ci >= len(s.Values) {
+					continue
+				}
+				barHeight := plotHeight * s.Values[ci] / maxValue
+				fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"><title>%s %s: %s %s</title></rect>`+"\n",
+					x+barWidth*float64(si), float64(top)+plotHeight-barHeight, barWidth, barHeight, seriesColors[si%len(seriesColors)],
+					html.EscapeString(s.Name), html.EscapeString(category), formatNumber(s.Values[ci]), html.EscapeString(c.Unit))
+			}
+			fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" text-anchor="middle">%s</text>`+"\n", float64(left)+groupWidth*(float64(ci)+0.5), float64(top)+plotHeight+18, html.EscapeString(category))
+		}
+	}
+	fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%.1f" stroke="#333333"/>`+"\n", left, top, left, float64(top)+plotHeight)
+	fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#333333"/>`+"\n", left, float64(top)+plotHeight, width-right, float64(top)+plotHeight)
+
+	// Legenda di bawah grafik
+	for si, s := range c.Series {
+		x := left + si*120
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="12" height="12" fill="%s"/>`+"\n", x, height-26, seriesColors[si%len(seriesColors)])
+		fmt.Fprintf(&b, `<text x="%d" y="%d">%s</text>`+"\n", x+18, height-16, html.EscapeString(s.Name))
+	}
+	b.WriteString("</svg>\n")
+
+	if err := os.WriteFile(filename, []byte(b.String()), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	return nil
+}
+
+// niceMax membulatkan nilai maksimum ke atas menjadi 1, 2, 2.5, atau 5 kali pangkat 10
+// agar label sumbu Y mudah dibaca
+func niceMax(value float64) float64 {
+	if value <= 0 {
+		return 1
+	}
+	magnitude := math.Pow(10, math.Floor(math.Log10(value)))
+	for _, step := range []float64{1, 2, 2.5, 5} {
+		if step*magnitude >= value {
+			return step * magnitude
+		}
+	}
+	return 10 * magnitude
+}
+
+// formatNumber menampilkan bilangan bulat tanpa desimal dan selain itu dengan dua desimal
+func formatNumber(value float64) string {
+	if value == math.Trunc(value) {
+		return fmt.Sprintf("%.0f", value)
+	}
+	return fmt.Sprintf("%.2f", value)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	outputDir := flag.String("output-dir", "report", "direktori hasil report.md, report.json, dan grafik SVG")
+	flag.Parse()
+
+	// Validasi argument untuk file hasil metrics-calculator
+	files := flag.Args()
+	if len(files) == 0 {
+		log.Fatalf("Usage: %s [-output-dir <dir>] <eventual_N.csv|twophase_N.csv>...", os.Args[0])
+	}
+
+	runs := make([]*run, 0, len(files))
+	for _, file := range files {
+		r, err := loadRun(file)
+		if err != nil {
+			log.Fatalf("Failed to load run: %v", err)
+		}
+		runs = append(runs, r)
+	}
+
+	rep, err := buildReport(runs)
+	if err != nil {
+		log.Fatalf("Failed to build report: %v", err)
+	}
+
+	if err := os.MkdirAll(*outputDir, 0o755); err != nil {
+		log.Fatalf("Failed to create %s: %v", *outputDir, err)
+	}
+	if err := writeJSON(rep, filepath.Join(*outputDir, "report.json")); err != nil {
+		log.Fatalf("Failed to write JSON report: %v", err)
+	}
+	if err := writeCharts(rep, *outputDir); err != nil {
+		log.Fatalf("Failed to write charts: %v", err)
+	}
+	if err := writeMarkdown(rep, files, filepath.Join(*outputDir, "report.md")); err != nil {
+		log.Fatalf("Failed to write Markdown report: %v", err)
+	}
+
+	log.Printf("Successfully compared %d runs across %d concurrency levels in %s", len(runs), len(rep.Levels), *outputDir)
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// stalenessChart adalah nama file grafik staleness untuk satu statistik, misalnya p95
+func stalenessChart(name string) string {
+	return "staleness-" + name + ".svg"
+}
+
+// participantsChart adalah nama file grafik latency participant pada satu tingkat concurrency
+func participantsChart(concurrency int) string {
+	return "participants-" + strconv.Itoa(concurrency) + ".svg"
+}
+
+const successRateChart = "success-rate.svg"
+
+func architectureLabel(name string) string {
+	for _, a := range architectures {
+		if a.Name == name {
+			return a.Label
+		}
+	}
+	return name
+}
+
+func writeJSON(rep report, filename string) error {
+	data, err := json.MarshalIndent(rep, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filename, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	return nil
+}
+
+// writeCharts menulis grafik success rate dan staleness setiap persentil dengan kategori
+// tingkat concurrency, serta grafik p95 latency participant untuk setiap tingkat
+func writeCharts(rep report, outputDir string) error {
+	var levels []string
+	for _, l := range rep.Levels {
+		levels = append(levels, strconv.Itoa(l.Concurrency))
+	}
+
+	// perLevel membuat satu series per arsitektur dengan nilai value di setiap tingkat
+	perLevel := func(value func(*runReport) float64) []series {
+		var result []series
+		for _, a := range architectures {
+			s := series{Name: a.Label}
+			for _, l := range rep.Levels {
+				var v float64
+				if r := l.run(a.Name); r != nil {
+					v = value(r)
+				}
+				s.Values = append(s.Values, v)
+			}
+			result = append(result, s)
+		}
+		return result
+	}
+
+	charts := map[string]barChart{
+		successRateChart: {
+			Title:      "Success rate per concurrency",
+			Unit:       "%",
+			Categories: levels,
+			Series:     perLevel(func(r *runReport) float64 { return r.SuccessRate }),
+		},
+	}
+	for i, m := range (latencyStats{}).percentiles() {
+		charts[stalenessChart(m.Name)] = barChart{
+			Title:      "Staleness " + m.Name + " per concurrency",
+			Unit:       "ms",
+			Categories: levels,
+			Series: perLevel(func(r *runReport) float64 {
+				return r.Staleness.percentiles()[i].Value
+			}),
+		}
+	}
+
+	for _, l := range rep.Levels {
+		participants := l.participants()
+		var participantSeries []series
+		for _, a := range architectures {
+			s := series{Name: a.Label}
+			r := l.run(a.Name)
+			for _, participant := range participants {
+				var v float64
+				if r != nil {
+					v = r.Participants[participant].P95
+				}
+				s.Values = append(s.Values, v)
+			}
+			participantSeries = append(participantSeries, s)
+		}
+		charts[participantsChart(l.Concurrency)] = barChart{
+			Title:      fmt.Sprintf("Latency participant p95 (%d concurrent requests)", l.Concurrency),
+			Unit:       "ms",
+			Categories: participants,
+			Series:     participantSeries,
+		}
+	}
+
+	for filename, chart := range charts {
+		if err := chart.write(filepath.Join(outputDir, filename)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeMarkdown menulis perbandingan EC dan 2PC berdampingan untuk setiap tingkat concurrency
+func writeMarkdown(rep report, files []string, filename string) error {
+	var b strings.Builder
+	b.WriteString("# Perbandingan EC dan 2PC\n\n")
+	b.WriteString("Staleness adalah selisih DoneAt dan CreatedAt setiap transaksi yang sudah selesai, sedangkan latency participant adalah selisih waktu selesai participant dan CreatedAt. Seluruh waktu dalam milidetik.\n\n")
+	b.WriteString("Sumber data:\n\n")
+	for _, file := range files {
+		fmt.Fprintf(&b, "- `%s`\n", file)
+	}
+
+	b.WriteString("\n## Ringkasan\n\n")
+	fmt.Fprintf(&b, "![Success rate](%s)\n\n", successRateChart)
+	for _, m := range (latencyStats{}).percentiles() {
+		fmt.Fprintf(&b, "![Staleness %s](%s)\n\n", m.Name, stalenessChart(m.Name))
+	}
+
+	for _, l := range rep.Levels {
+		fmt.Fprintf(&b, "## %d concurrent requests\n\n", l.Concurrency)
+
+		header := "| Metrik |"
+		separator := "| --- |"
+		for _, a := range architectures {
+			header += " " + a.Label + " |"
+			separator += " ---: |"
+		}
+		b.WriteString(header + "\n" + separator + "\n")
+
+		// cells menulis satu baris tabel dengan nilai setiap arsitektur, "-" jika tidak diuji
+		cells := func(name string, value func(*runReport) string) {
+			line := "| " + name + " |"
+			for _, a := range architectures {
+				cell := "-"
+				if r := l.run(a.Name); r != nil {
+					cell = value(r)
+				}
+				line += " " + cell + " |"
+			}
+			b.WriteString(line + "\n")
+		}
+		cells("Transaksi", func(r *runReport) string { return strconv.Itoa(r.Total) })
+		cells("Berhasil", func(r *runReport) string { return fmt.Sprintf("%d (%.2f%%)", r.Succeeded, r.SuccessRate) })
+		cells("Gagal", func(r *runReport) string { return fmt.Sprintf("%d (%.2f%%)", r.Failed, r.FailureRate) })
+		cells("Belum selesai", func(r *runReport) string { return strconv.Itoa(r.Unfinished) })
+		cells("Status", func(r *runReport) string { return formatStatuses(r.Statuses) })
+		cells("Staleness rata-rata", func(r *runReport) string { return formatNumber(r.Staleness.Mean) })
+		for i, m := range (latencyStats{}).percentiles() {
+			cells("Staleness "+m.Name, func(r *runReport) string {
+				return formatNumber(r.Staleness.percentiles()[i].Value)
+			})
+		}
+
+		b.WriteString("\n### Latency participant\n\n")
+		b.WriteString("| Participant | Arsitektur | n | Rata-rata | p50 | p90 | p95 | p99 | max |\n")
+		b.WriteString("| --- | --- | ---: | ---: | ---: | ---: | ---: | ---: | ---: |\n")
+		for _, participant := range l.participants() {
+			for _, a := range architectures {
+				r := l.run(a.Name)
+				if r == nil {
+					continue
+				}
+				stats, ok := r.Participants[participant]
+				if !ok {
+					continue
+				}
+				fmt.Fprintf(&b, "| %s | %s | %d | %s |", participant, a.Label, stats.Count, formatNumber(stats.Mean))
+				for _, m := range stats.percentiles() {
+					fmt.Fprintf(&b, " %s |", formatNumber(m.Value))
+				}
+				b.WriteString("\n")
+			}
+		}
+		fmt.Fprintf(&b, "\n![Latency participant p95](%s)\n\n", participantsChart(l.Concurrency))
+	}
+
+	if err := os.WriteFile(filename, []byte(b.String()), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	return nil
+}
+
+// formatStatuses menampilkan jumlah transaksi per status, misalnya "BOOKED: 98, FAILED: 2"
+func formatStatuses(statuses map[string]int) string {
+	names := make([]string, 0, len(statuses))
+	for name := range statuses {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s: %d", name, statuses[name]))
+	}
+	return strings.Join(parts, ", ")
+}
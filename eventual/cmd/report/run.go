@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// architectures adalah urutan kolom arsitektur pada laporan beserta labelnya
+var architectures = []struct {
+	Name  string
+	Label string
+}{
+	{Name: "eventual", Label: "EC"},
+	{Name: "twophase", Label: "2PC"},
+}
+
+// transaction adalah satu order (EC) atau satu transaksi booking (2PC). Seluruh waktu
+// dalam milidetik unix, 0 jika belum terjadi.
+type transaction struct {
+	Status    string
+	Finished  bool
+	Succeeded bool
+	CreatedAt int64
+	DoneAt    int64
+	// Participants berisi waktu selesai setiap participant, misalnya hotel atau payment
+	Participants map[string]int64
+}
+
+// run adalah hasil satu pengujian, yaitu satu file CSV metrics-calculator
+type run struct {
+	Architecture string
+	Concurrency  int
+	File         string
+	Transactions []transaction
+}
+
+// loadRun membaca CSV metrics-calculator. Arsitektur dan tingkat concurrency diambil dari
+// nama file, misalnya eventual_100.csv atau twophase_1000.csv.
+func loadRun(filename string) (*run, error) {
+	name := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	architecture, level, ok := strings.Cut(name, "_")
+	if !ok {
+		return nil, fmt.Errorf("%s: file name must be <eventual|twophase>_<concurrency>.csv", filename)
+	}
+	concurrency, err := strconv.Atoi(level)
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid concurrency %q", filename, level)
+	}
+
+	var parse func(row) (transaction, bool)
+	switch architecture {
+	case "eventual":
+		parse = parseEventual
+	case "twophase":
+		parse = parseTwophase
+	default:
+		return nil, fmt.Errorf("%s: unknown architecture %q", filename, architecture)
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", filename, err)
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%s: missing header", filename)
+	}
+
+	// Kolom dicari berdasarkan header sehingga CSV dari versi metrics-calculator yang
+	// lebih lama tetap bisa dibaca
+	columns := make(map[string]int, len(records[0]))
+	for i, column := range records[0] {
+		columns[column] = i
+	}
+
+	r := &run{Architecture: architecture, Concurrency: concurrency, File: filename}
+	for _, record := range records[1:] {
+		if tx, ok := parse(row{columns: columns, values: record}); ok {
+			r.Transactions = append(r.Transactions, tx)
+		}
+	}
+	return r, nil
+}
+
+// parseEventual membaca satu order EC. Order FAILED tidak punya DoneAt, sehingga waktu
+// selesainya diambil dari UpdatedAt.
+func parseEventual(r row) (transaction, bool) {
+	tx := transaction{
+		Status:       r.get("Status"),
+		CreatedAt:    r.millis("CreatedAt"),
+		DoneAt:       r.millis("DoneAt"),
+		Participants: make(map[string]int64),
+	}
+	if tx.DoneAt == 0 && tx.Status == "FAILED" {
+		tx.DoneAt = r.millis("UpdatedAt")
+	}
+
+	for column := range r.columns {
+		participant, ok := strings.CutSuffix(column, "DoneAt")
+		if !ok || participant == "" {
+			continue
+		}
+		if doneAt := r.millis(column); doneAt > 0 {
+			tx.Participants[strings.ToLower(participant)] = doneAt
+		}
+	}
+
+	tx.Finished = tx.DoneAt > 0
+	tx.Succeeded = tx.Finished && tx.Status != "FAILED"
+	return tx, true
+}
+
+// twophaseFinished adalah status akhir transaksi 2PC beserta keberhasilannya. Booking
+// yang sudah dibatalkan tetap dihitung berhasil.
+var twophaseFinished = map[string]bool{
+	"committed":   true,
+	"cancelled":   true,
+	"aborted":     false,
+	"rolled_back": false,
+	"timed_out":   false,
+}
+
+// parseTwophase membaca satu transaksi 2PC. Transaksi cancellation dan modification
+// dilewati. Log lama tidak punya DoneAt, sehingga waktu selesai diambil dari
+// CommitTimestamp lalu UpdatedAt.
+func parseTwophase(r row) (transaction, bool) {
+	if kind := r.get("Kind"); kind != "" && kind != "booking" {
+		return transaction{}, false
+	}
+
+	tx := transaction{
+		Status:       r.get("Status"),
+		CreatedAt:    r.millis("CreatedAt"),
+		DoneAt:       r.millis("DoneAt"),
+		Participants: make(map[string]int64),
+	}
+	succeeded, finished := twophaseFinished[tx.Status]
+	if tx.DoneAt == 0 {
+		tx.DoneAt = r.millis("CommitTimestamp")
+	}
+	if tx.DoneAt == 0 && finished {
+		tx.DoneAt = r.millis("UpdatedAt")
+	}
+
+	// ParticipantsDoneAt berformat hotel:1751138077651;car:1751138077767
+	for _, entry := range strings.Split(r.get("ParticipantsDoneAt"), ";") {
+		participant, value, ok := strings.Cut(entry, ":")
+		if !ok {
+			continue
+		}
+		if doneAt, err := strconv.ParseInt(value, 10, 64); err == nil && doneAt > 0 {
+			tx.Participants[participant] = doneAt
+		}
+	}
+
+	tx.Finished = finished && tx.DoneAt > 0
+	tx.Succeeded = tx.Finished && succeeded
+	return tx, true
+}
+
+// row adalah satu baris CSV yang kolomnya diakses berdasarkan nama header
+type row struct {
+	columns map[string]int
+	values  []string
+}
+
+// get mengembalikan isi kolom, kosong jika kolom tidak ada
+func (r row) get(column string) string {
+	i, ok := r.columns[column]
+	if !ok || i >= len(r.values) {
+		return ""
+	}
+	return r.values[i]
+}
+
+// millis membaca waktu unix milidetik. Waktu kosong atau zero time Go (negatif) menjadi 0.
+func (r row) millis(column string) int64 {
+	value, err := strconv.ParseInt(r.get(column), 10, 64)
+	if err != nil || value < 0 {
+		return 0
+	}
+	return value
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// latencyStats adalah statistik latensi dalam milidetik
+type latencyStats struct {
+	Count int     `json:"count"`
+	Mean  float64 `json:"mean_ms"`
+	P50   float64 `json:"p50_ms"`
+	P90   float64 `json:"p90_ms"`
+	P95   float64 `json:"p95_ms"`
+	P99   float64 `json:"p99_ms"`
+	Max   float64 `json:"max_ms"`
+}
+
+// metric adalah nama dan nilai satu statistik latensi
+type metric struct {
+	Name  string
+	Value float64
+}
+
+// percentiles mengembalikan setiap persentil sesuai urutan kolom laporan
+func (s latencyStats) percentiles() []metric {
+	return []metric{
+		{"p50", s.P50},
+		{"p90", s.P90},
+		{"p95", s.P95},
+		{"p99", s.P99},
+		{"max", s.Max},
+	}
+}
+
+func computeLatency(latencies []int64) latencyStats {
+	s := latencyStats{Count: len(latencies)}
+	if len(latencies) == 0 {
+		return s
+	}
+
+	sorted := slices.Clone(latencies)
+	slices.Sort(sorted)
+
+	var total int64
+	for _, latency := range sorted {
+		total += latency
+	}
+	s.Mean = float64(total) / float64(len(sorted))
+	s.P50 = percentile(sorted, 50)
+	s.P90 = percentile(sorted, 90)
+	s.P95 = percentile(sorted, 95)
+	s.P99 = percentile(sorted, 99)
+	s.Max = float64(sorted[len(sorted)-1])
+	return s
+}
+
+// percentile memakai metode nearest-rank pada latensi yang sudah terurut
+func percentile(sorted []int64, p int) float64 {
+	rank := (p*len(sorted) + 99) / 100
+	return float64(sorted[max(rank, 1)-1])
+}
+
+// runReport adalah ringkasan satu pengujian
+type runReport struct {
+	Architecture string `json:"architecture"`
+	Concurrency  int    `json:"concurrency"`
+	File         string `json:"file"`
+	Total        int    `json:"total"`
+	Succeeded    int    `json:"succeeded"`
+	Failed       int    `json:"failed"`
+	Unfinished   int    `json:"unfinished"`
+	// SuccessRate dan FailureRate dalam persen dari Total
+	SuccessRate float64        `json:"success_rate"`
+	FailureRate float64        `json:"failure_rate"`
+	Statuses    map[string]int `json:"statuses"`
+	// Staleness adalah DoneAt - CreatedAt dari transaksi yang sudah selesai
+	Staleness latencyStats `json:"staleness"`
+	// Participants adalah waktu selesai setiap participant - CreatedAt
+	Participants map[string]latencyStats `json:"participants"`
+}
+
+func summarizeRun(r *run) runReport {
+	report := runReport{
+		Architecture: r.Architecture,
+		Concurrency:  r.Concurrency,
+		File:         r.File,
+		Total:        len(r.Transactions),
+		Statuses:     make(map[string]int),
+		Participants: make(map[string]latencyStats),
+	}
+
+	var staleness []int64
+	participants := make(map[string][]int64)
+	for _, tx := range r.Transactions {
+		report.Statuses[tx.Status]++
+		switch {
+		case tx.Succeeded:
+			report.Succeeded++
+		case tx.Finished:
+			report.Failed++
+		default:
+			report.Unfinished++
+		}
+
+		if tx.CreatedAt == 0 {
+			continue
+		}
+		if tx.Finished {
+			staleness = append(staleness, tx.DoneAt-tx.CreatedAt)
+		}
+		for participant, doneAt := range tx.Participants {
+			participants[participant] = append(participants[participant], doneAt-tx.CreatedAt)
+		}
+	}
+
+	if report.Total > 0 {
+		report.SuccessRate = 100 * float64(report.Succeeded) / float64(report.Total)
+		report.FailureRate = 100 * float64(report.Failed) / float64(report.Total)
+	}
+	report.Staleness = computeLatency(staleness)
+	for participant, latencies := range participants {
+		report.Participants[participant] = computeLatency(latencies)
+	}
+	return report
+}
+
+// level adalah hasil setiap arsitektur pada satu tingkat concurrency
+type level struct {
+	Concurrency int         `json:"concurrency"`
+	Runs        []runReport `json:"runs"`
+}
+
+// run mengembalikan hasil arsitektur pada tingkat ini, nil jika tidak diuji
+func (l level) run(architecture string) *runReport {
+	for i := range l.Runs {
+		if l.Runs[i].Architecture == architecture {
+			return &l.Runs[i]
+		}
+	}
+	return nil
+}
+
+// report adalah perbandingan seluruh pengujian, diurutkan berdasarkan concurrency
+type report struct {
+	Levels []level `json:"levels"`
+}
+
+func buildReport(runs []*run) (report, error) {
+	byConcurrency := make(map[int]*level)
+	for _, r := range runs {
+		l, ok := byConcurrency[r.Concurrency]
+		if !ok {
+			l = &level{Concurrency: r.Concurrency}
+			byConcurrency[r.Concurrency] = l
+		}
+		if existing := l.run(r.Architecture); existing != nil {
+			return report{}, fmt.Errorf("%s and %s are both %s runs with concurrency %d", existing.File, r.File, r.Architecture, r.Concurrency)
+		}
+		l.Runs = append(l.Runs, summarizeRun(r))
+	}
+
+	var rep report
+	for _, l := range byConcurrency {
+		sort.Slice(l.Runs, func(i, j int) bool {
+			return architectureIndex(l.Runs[i].Architecture) < architectureIndex(l.Runs[j].Architecture)
+		})
+		rep.Levels = append(rep.Levels, *l)
+	}
+	sort.Slice(rep.Levels, func(i, j int) bool {
+		return rep.Levels[i].Concurrency < rep.Levels[j].Concurrency
+	})
+	return rep, nil
+}
+
+func architectureIndex(name string) int {
+	for i, a := range architectures {
+		if a.Name == name {
+			return i
+		}
+	}
+	return len(architectures)
+}
+
+// participantOrder adalah urutan participant pada tabel dan grafik. Participant lain
+// ditambahkan setelahnya sesuai abjad.
+var participantOrder = []string{"hotel", "car", "train", "flight", "payment"}
+
+// participants mengembalikan seluruh participant yang muncul pada level
+func (l level) participants() []string {
+	var extra []string
+	seen := make(map[string]bool)
+	for _, r := range l.Runs {
+		for participant := range r.Participants {
+			if !seen[participant] && !slices.Contains(participantOrder, participant) {
+				extra = append(extra, participant)
+			}
+			seen[participant] = true
+		}
+	}
+	sort.Strings(extra)
+
+	var result []string
+	for _, participant := range participantOrder {
+		if seen[participant] {
+			result = append(result, participant)
+		}
+	}
+	return append(result, extra...)
+}
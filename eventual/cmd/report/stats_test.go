@@ -0,0 +1,51 @@
+package main
+
+import "testing"
+
+func TestPercentile(t *testing.T) {
+	tens := []int64{10, 20, 30, 40, 50, 60, 70, 80, 90, 100}
+	hundred := make([]int64, 100)
+	for i := range hundred {
+		hundred[i] = int64(i + 1)
+	}
+
+	tests := []struct {
+		name   string
+		sorted []int64
+		p      int
+		want   float64
+	}{
+		{"single value", []int64{42}, 50, 42},
+		{"single value p99", []int64{42}, 99, 42},
+		{"p0 is the minimum", tens, 0, 10},
+		{"p50 of ten is the fifth", tens, 50, 50},
+		{"p90 of ten is the ninth", tens, 90, 90},
+		{"p95 of ten rounds up to the tenth", tens, 95, 100},
+		{"p99 of ten is the maximum", tens, 99, 100},
+		{"p100 is the maximum", tens, 100, 100},
+		{"p50 of two is the first", []int64{1, 2}, 50, 1},
+		{"p51 of two is the second", []int64{1, 2}, 51, 2},
+		{"p99 of hundred", hundred, 99, 99},
+		{"p1 of hundred", hundred, 1, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := percentile(tt.sorted, tt.p); got != tt.want {
+				t.Errorf("percentile(%d) = %v, want %v", tt.p, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComputeLatency(t *testing.T) {
+	got := computeLatency([]int64{40, 10, 30, 20})
+	want := latencyStats{Count: 4, Mean: 25, P50: 20, P90: 40, P95: 40, P99: 40, Max: 40}
+	if got != want {
+		t.Errorf("computeLatency = %+v, want %+v", got, want)
+	}
+
+	if got := computeLatency(nil); got != (latencyStats{}) {
+		t.Errorf("computeLatency(nil) = %+v, want zero stats", got)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"slices"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"github.com/joho/godotenv"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/config"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/fault"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/messagebus"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/saga"
+)
+
+// groups adalah kelompok data yang dapat direset, sesuai urutan reset. Queue dikosongkan
+// lebih dulu agar pesan yang tertinggal tidak menulis ulang data yang sudah dihapus.
+var groups = []string{"queues", "orders", "reservations", "payments", "quotes", "waitlist", "faults", "availability"}
+
+// steps adalah koleksi Firestore setiap kelompok
+var steps = []step{
+	{group: "orders", collection: "order_orders"},
+	{group: "orders", collection: saga.StepCollection},
+	{group: "reservations", collection: "hotel_reservations"},
+	{group: "reservations", collection: "car_reservations"},
+	{group: "reservations", collection: "train_reservations"},
+	{group: "reservations", collection: "flight_reservations"},
+	{group: "payments", collection: "payment_payments"},
+	{group: "quotes", collection: "pricing_quotes"},
+	{group: "waitlist", collection: "waitlist_entries"},
+	{group: "faults", collection: fault.EventCollection},
+	// Ketersediaan kamar, mobil, kursi kereta dan pesawat dihitung dari reservasi,
+	// hanya unit tipe kamar yang disimpan terpisah
+	{
+		group:      "availability",
+		collection: "hotel_room_type_inventories",
+		update: func(doc *firestore.DocumentSnapshot) []firestore.Update {
+			return []firestore.Update{{Path: "available_units", Value: doc.Data()["total_units"]}}
+		},
+	},
+}
+
+// Reset menghapus data hasil pengujian agar pengujian berikutnya dimulai dari data seed.
+// Data seed (kamar, mobil, kereta, pesawat dan tarif) tidak dihapus. Reset hanya berjalan
+// pada project yang terdaftar di RESET_ALLOWED_PROJECT_IDS.
+func main() {
+	dryRun := flag.Bool("dry-run", false, "hanya hitung data yang akan direset tanpa mengubahnya")
+	only := flag.String("only", "", "kelompok data yang direset, dipisah koma: "+strings.Join(groups, ",")+" (default semua)")
+	flag.Parse()
+
+	selected := groups
+	if *only != "" {
+		selected = strings.Split(*only, ",")
+		for _, group := range selected {
+			if !slices.Contains(groups, group) {
+				log.Fatalf("Unknown group %q, expected one of %s", group, strings.Join(groups, ", "))
+			}
+		}
+	}
+
+	log.Println("Starting reset...")
+
+	if err := godotenv.Load(); err != nil {
+		log.Printf("Error loading .env file: %v, using system environment variables", err)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	if !slices.Contains(cfg.ResetAllowedProjectIDs, cfg.GoogleProjectID) {
+		log.Fatalf("Refusing to reset project %q: add it to RESET_ALLOWED_PROJECT_IDS if it is a test environment", cfg.GoogleProjectID)
+	}
+
+	ctx := context.Background()
+	client, err := firestore.NewClient(ctx, cfg.GoogleProjectID)
+	if err != nil {
+		log.Fatalf("Failed to create Firestore client: %v", err)
+	}
+	defer client.Close()
+
+	failed := false
+	if slices.Contains(selected, "queues") {
+		if err := resetQueues(ctx, cfg, *dryRun); err != nil {
+			log.Printf("Failed to reset queues: %v", err)
+			failed = true
+		}
+	}
+
+	for _, group := range groups {
+		if !slices.Contains(selected, group) {
+			continue
+		}
+
+		for _, s := range steps {
+			if s.group != group {
+				continue
+			}
+
+			if *dryRun {
+				count, err := s.count(ctx, client)
+				if err != nil {
+					log.Printf("Failed to count %s: %v", s.collection, err)
+					failed = true
+					continue
+				}
+				log.Printf("[dry-run] %s: would %s %d documents", s.collection, s.action(), count)
+				continue
+			}
+
+			if _, err := s.run(ctx, client); err != nil {
+				log.Printf("Failed to reset %s: %v", s.collection, err)
+				failed = true
+			}
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+	log.Println("Reset finished")
+}
+
+// resetQueues mengosongkan queue setiap service
+func resetQueues(ctx context.Context, cfg config.Config, dryRun bool) error {
+	conn, err := messagebus.Dial(cfg.RabbitMQURL)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	queues := []string{cfg.OrderQueueName, cfg.HotelQueueName, cfg.CarQueueName, cfg.TrainQueueName, cfg.FlightQueueName, cfg.PaymentQueueName}
+	for _, queue := range queues {
+		if dryRun {
+			messages, err := conn.QueueMessages(ctx, queue)
+			if err != nil {
+				return err
+			}
+			log.Printf("[dry-run] %s: would purge %d messages", queue, messages)
+			continue
+		}
+
+		messages, err := conn.PurgeQueue(ctx, queue)
+		if err != nil {
+			return err
+		}
+		log.Printf("%s: purged %d messages", queue, messages)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
+	"google.golang.org/api/iterator"
+)
+
+// progressEvery adalah jumlah dokumen di antara dua log progres
+const progressEvery = 500
+
+// step mereset satu koleksi Firestore
+type step struct {
+	group      string
+	collection string
+	// filter memilih dokumen yang direset, nil berarti seluruh koleksi
+	filter func(q firestore.Query) firestore.Query
+	// update mengembalikan perubahan untuk dokumen. Jika nil, dokumen dihapus.
+	update func(doc *firestore.DocumentSnapshot) []firestore.Update
+}
+
+func (s step) action() string {
+	if s.update == nil {
+		return "delete"
+	}
+	return "update"
+}
+
+func (s step) query(client *firestore.Client) firestore.Query {
+	q := client.Collection(s.collection).Query
+	if s.filter != nil {
+		q = s.filter(q)
+	}
+	return q
+}
+
+// count menghitung dokumen yang akan direset tanpa membacanya
+func (s step) count(ctx context.Context, client *firestore.Client) (int64, error) {
+	q := s.query(client)
+	results, err := q.NewAggregationQuery().WithCount("all").Get(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	count, ok := results["all"]
+	if !ok {
+		return 0, errors.New("firestore: couldn't get alias for COUNT from results")
+	}
+	return count.(*firestorepb.Value).GetIntegerValue(), nil
+}
+
+// run mereset dokumen step dengan BulkWriter dan mencatat progresnya. Mengembalikan
+// jumlah dokumen yang berhasil direset.
+func (s step) run(ctx context.Context, client *firestore.Client) (int, error) {
+	total, err := s.count(ctx, client)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count %s: %w", s.collection, err)
+	}
+	if total == 0 {
+		log.Printf("%s: nothing to %s", s.collection, s.action())
+		return 0, nil
+	}
+
+	bulkWriter := client.BulkWriter(ctx)
+	var jobs []*firestore.BulkWriterJob
+
+	iter := s.query(client).Documents(ctx)
+	defer iter.Stop()
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			bulkWriter.End()
+			return 0, fmt.Errorf("failed to iterate %s: %w", s.collection, err)
+		}
+
+		var job *firestore.BulkWriterJob
+		if s.update == nil {
+			job, err = bulkWriter.Delete(doc.Ref)
+		} else {
+			job, err = bulkWriter.Update(doc.Ref, s.update(doc))
+		}
+		if err != nil {
+			bulkWriter.End()
+			return 0, fmt.Errorf("failed to %s %s: %w", s.action(), doc.Ref.Path, err)
+		}
+		jobs = append(jobs, job)
+
+		if len(jobs)%progressEvery == 0 {
+			bulkWriter.Flush()
+			log.Printf("%s: %d/%d documents", s.collection, len(jobs), total)
+		}
+	}
+	bulkWriter.End()
+
+	done, failed := 0, 0
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			failed++
+			continue
+		}
+		done++
+	}
+
+	log.Printf("%s: %d/%d documents done", s.collection, done, total)
+	if failed > 0 {
+		return done, fmt.Errorf("failed to %s %d documents of %s", s.action(), failed, s.collection)
+	}
+	return done, nil
+}
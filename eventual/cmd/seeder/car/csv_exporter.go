@@ -6,10 +6,10 @@ import (
 	"log"
 	"os"
 
-	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/utils"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/cmd/seeder/spec"
 )
 
-func ExportToCSV(filename string) error {
+func ExportToCSV(filename string, data *spec.Data) error {
 	log.Println("Starting car CSV export...")
 
 	// Create CSV file
@@ -27,23 +27,13 @@ func ExportToCSV(filename string) error {
 		return fmt.Errorf("failed to write header: %w", err)
 	}
 
-	// Generate the same data as seeder but write to CSV
-	for _, brandData := range carBrands {
-		for _, model := range brandData.models {
-			log.Printf("Exporting %s %s...", brandData.brand, model)
-
-			for unitNumber := 1; unitNumber <= 100; unitNumber++ {
-				carName := fmt.Sprintf("%s %s - %03d", brandData.brand, model, unitNumber)
-				carID := utils.Slugify(carName)
-
-				// Write to CSV
-				if err := writer.Write([]string{carID, carName}); err != nil {
-					return fmt.Errorf("failed to write row: %w", err)
-				}
-			}
+	// Data dibangun dari spec yang sama dengan seeder
+	for _, c := range data.Cars {
+		if err := writer.Write([]string{c.ID, c.Name}); err != nil {
+			return fmt.Errorf("failed to write row: %w", err)
 		}
 	}
 
-	log.Printf("Car CSV export completed. Total cars: %d", len(carBrands)*5*100)
+	log.Printf("Car CSV export completed. Total cars: %d", len(data.Cars))
 	return nil
 }
@@ -4,56 +4,47 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"time"
 
 	"cloud.google.com/go/firestore"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/cmd/seeder/spec"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/internal/car"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/internal/pricing"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/utils"
 )
 
-var carBrands = []struct {
-	brand  string
-	models []string
-}{
-	{"Toyota", []string{"Avanza", "Innova", "Fortuner", "Camry", "Corolla"}},
-	{"Honda", []string{"Brio", "Jazz", "HR-V", "CR-V", "Civic"}},
-	{"Suzuki", []string{"Ertiga", "XL7", "Ignis", "Baleno", "Swift"}},
-	{"Daihatsu", []string{"Ayla", "Calya", "Xenia", "Terios", "Rocky"}},
-	{"Mitsubishi", []string{"Xpander", "Pajero", "L300", "Colt", "Mirage"}},
-	{"Nissan", []string{"Livina", "Grand Livina", "X-Trail", "Serena", "March"}},
-	{"Hyundai", []string{"Brio", "Creta", "Santa Fe", "Stargazer", "Palisade"}},
-	{"Kia", []string{"Picanto", "Rio", "Seltos", "Sportage", "Carnival"}},
-	{"Wuling", []string{"Almaz", "Cortez", "Confero", "Air ev", "Alvez"}},
-	{"MG", []string{"ZS", "HS", "RX5", "5", "3"}},
-}
-
-func Seed(ctx context.Context, client *firestore.Client) error {
+func Seed(ctx context.Context, client *firestore.Client, data *spec.Data, mode utils.WriteMode) error {
 	log.Println("Starting car seeder...")
 
 	collection := client.Collection("cars")
-	bw := client.BulkWriter(ctx)
-
-	for _, brandData := range carBrands {
-		for _, model := range brandData.models {
-			log.Printf("Seeding %s %s...", brandData.brand, model)
-
-			for unitNumber := 1; unitNumber <= 100; unitNumber++ {
-				carName := fmt.Sprintf("%s %s - %03d", brandData.brand, model, unitNumber)
-				carID := utils.Slugify(carName)
-
-				carData := car.Car{
-					ID:   carID,
-					Name: carName,
-				}
-
-				docRef := collection.Doc(carID)
-				bw.Set(docRef, carData)
-			}
-		}
+	rateCollection := client.Collection("pricing_car_rates")
+	seasons := pricing.HolidaySeasons(time.Now().Year())
+
+	var cars, rates []utils.Doc
+	for _, c := range data.Cars {
+		cars = append(cars, utils.Doc{Ref: collection.Doc(c.ID), Data: car.Car{
+			ID:    c.ID,
+			Name:  c.Name,
+			Brand: c.Brand,
+			Model: c.Model,
+		}})
+
+		rates = append(rates, utils.Doc{Ref: rateCollection.Doc(c.ID), Data: pricing.CarRate{
+			CarID:       c.ID,
+			DailyRate:   c.DailyRate,
+			WeekendRate: c.WeekendRate,
+			Seasons:     seasons,
+		}})
 	}
 
-	// Flush all writes
-	bw.Flush()
+	result, err := utils.BulkWrite(ctx, client, cars, mode)
+	if err != nil {
+		return fmt.Errorf("failed to write cars: %w", err)
+	}
+	if _, err := utils.BulkWrite(ctx, client, rates, mode); err != nil {
+		return fmt.Errorf("failed to write car rates: %w", err)
+	}
 
-	log.Printf("Car seeder completed. Total cars: %d", len(carBrands)*5*100)
+	log.Printf("Car seeder completed. Total cars: %d, written: %d, skipped: %d", len(data.Cars), result.Written, result.Skipped)
 	return nil
 }
@@ -0,0 +1,59 @@
+package flight
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/cmd/seeder/spec"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/internal/flight"
+)
+
+func ExportToCSV(filename string, data *spec.Data) error {
+	log.Println("Starting flight CSV export...")
+
+	// Create CSV file
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create CSV file: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	// Write header
+	if err := writer.Write([]string{"FlightID", "DepartureDate", "SeatID", "FlightNumber", "Origin", "Destination"}); err != nil {
+		return fmt.Errorf("failed to write header: %w", err)
+	}
+
+	// Data dibangun dari spec yang sama dengan seeder, satu baris per kursi per penerbangan
+	scheduledFlights := data.Flights
+	seats := 0
+	for _, scheduledFlight := range scheduledFlights {
+		log.Printf("Exporting %s on %s...", scheduledFlight.FlightNumber, scheduledFlight.DepartureDate)
+
+		for row := 1; row <= scheduledFlight.Rows; row++ {
+			for seatNumber := 1; seatNumber <= scheduledFlight.SeatsPerRow; seatNumber++ {
+				record := []string{
+					scheduledFlight.ID,
+					scheduledFlight.DepartureDate,
+					flight.SeatID(row, seatNumber),
+					scheduledFlight.FlightNumber,
+					scheduledFlight.Origin,
+					scheduledFlight.Destination,
+				}
+
+				// Write to CSV
+				if err := writer.Write(record); err != nil {
+					return fmt.Errorf("failed to write row: %w", err)
+				}
+				seats++
+			}
+		}
+	}
+
+	log.Printf("Flight CSV export completed. Total seats: %d", seats)
+	return nil
+}
@@ -0,0 +1,65 @@
+package flight
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"cloud.google.com/go/firestore"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/cmd/seeder/spec"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/internal/flight"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/internal/pricing"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/utils"
+)
+
+// fareClassRows mengembalikan susunan kelas tarif per baris untuk disimpan di penerbangan
+func fareClassRows(classes []pricing.FlightClassFare) []flight.FareClassRows {
+	rows := make([]flight.FareClassRows, 0, len(classes))
+	for _, fare := range classes {
+		rows = append(rows, flight.FareClassRows{
+			Class:    flight.FareClass(fare.Class),
+			FirstRow: fare.FirstRow,
+			LastRow:  fare.LastRow,
+		})
+	}
+	return rows
+}
+
+func Seed(ctx context.Context, client *firestore.Client, data *spec.Data, mode utils.WriteMode) error {
+	log.Println("Starting flight seeder...")
+
+	collection := client.Collection("flights")
+	fareCollection := client.Collection("pricing_flight_fares")
+
+	var flights, fares []utils.Doc
+	for _, scheduledFlight := range data.Flights {
+		flights = append(flights, utils.Doc{Ref: collection.Doc(scheduledFlight.ID), Data: flight.Flight{
+			ID:            scheduledFlight.ID,
+			FlightNumber:  scheduledFlight.FlightNumber,
+			Airline:       scheduledFlight.Airline,
+			Origin:        scheduledFlight.Origin,
+			Destination:   scheduledFlight.Destination,
+			DepartureDate: scheduledFlight.DepartureDate,
+			DepartureTime: scheduledFlight.DepartureTime,
+			Rows:          scheduledFlight.Rows,
+			SeatsPerRow:   scheduledFlight.SeatsPerRow,
+			FareClasses:   fareClassRows(scheduledFlight.Classes),
+		}})
+
+		fares = append(fares, utils.Doc{Ref: fareCollection.Doc(scheduledFlight.ID), Data: pricing.FlightFare{
+			FlightID: scheduledFlight.ID,
+			Classes:  scheduledFlight.Classes,
+		}})
+	}
+
+	result, err := utils.BulkWrite(ctx, client, flights, mode)
+	if err != nil {
+		return fmt.Errorf("failed to write flights: %w", err)
+	}
+	if _, err := utils.BulkWrite(ctx, client, fares, mode); err != nil {
+		return fmt.Errorf("failed to write flight fares: %w", err)
+	}
+
+	log.Printf("Flight seeder completed. Total flights: %d, written: %d, skipped: %d", len(flights), result.Written, result.Skipped)
+	return nil
+}
@@ -6,10 +6,10 @@ import (
 	"log"
 	"os"
 
-	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/utils"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/cmd/seeder/spec"
 )
 
-func ExportToCSV(filename string) error {
+func ExportToCSV(filename string, data *spec.Data) error {
 	log.Println("Starting hotel room CSV export...")
 
 	// Create CSV file
@@ -23,28 +23,24 @@ func ExportToCSV(filename string) error {
 	defer writer.Flush()
 
 	// Write header
-	if err := writer.Write([]string{"ID", "HotelName", "RoomName"}); err != nil {
+	if err := writer.Write([]string{"ID", "HotelName", "RoomName", "RoomTypeID"}); err != nil {
 		return fmt.Errorf("failed to write header: %w", err)
 	}
 
-	// Generate the same data as seeder but write to CSV
-	for _, hotelName := range hotelBrands {
-		log.Printf("Exporting %s...", hotelName)
+	// Data dibangun dari spec yang sama dengan seeder
+	for _, h := range data.Hotels {
+		log.Printf("Exporting %s...", h.Name)
 
-		// 5 floors, 20 units per floor
-		for floor := 1; floor <= 5; floor++ {
-			for unitNumber := 1; unitNumber <= 20; unitNumber++ {
-				roomName := fmt.Sprintf("%d%02d", floor, unitNumber)
-				roomID := utils.Slugify(fmt.Sprintf("%s-%s", hotelName, roomName))
-
-				// Write to CSV
-				if err := writer.Write([]string{roomID, hotelName, roomName}); err != nil {
+		for _, roomType := range h.RoomTypes {
+			for _, room := range roomType.Rooms {
+				if err := writer.Write([]string{room.ID, h.Name, room.Name, roomType.ID}); err != nil {
 					return fmt.Errorf("failed to write row: %w", err)
 				}
 			}
 		}
 	}
 
-	log.Printf("Hotel room CSV export completed. Total rooms: %d", len(hotelBrands)*5*20)
+	rooms, _ := data.Rooms()
+	log.Printf("Hotel room CSV export completed. Total rooms: %d", rooms)
 	return nil
 }
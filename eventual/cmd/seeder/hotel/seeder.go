@@ -4,99 +4,71 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"time"
 
 	"cloud.google.com/go/firestore"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/cmd/seeder/spec"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/internal/hotel"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/internal/pricing"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/utils"
 )
 
-var hotelBrands = []string{
-	"Marriott Jakarta",
-	"Ritz-Carlton Jakarta",
-	"Mandarin Oriental Jakarta",
-	"Four Seasons Jakarta",
-	"Grand Hyatt Jakarta",
-	"InterContinental Jakarta",
-	"Sheraton Jakarta",
-	"Pullman Jakarta",
-	"Novotel Jakarta",
-	"Ibis Jakarta",
-	"Marriott Bandung",
-	"Ritz-Carlton Bandung",
-	"Marriott Surabaya",
-	"Ritz-Carlton Surabaya",
-	"Marriott Medan",
-	"Hotel Indonesia Kempinski Jakarta",
-	"Shangri-La Hotel Jakarta",
-	"W Jakarta",
-	"Conrad Jakarta",
-	"Westin Jakarta",
-	"Le Meridien Jakarta",
-	"Aloft Jakarta",
-	"Element Jakarta",
-	"Courtyard Jakarta",
-	"Residence Inn Jakarta",
-	"Fairfield Jakarta",
-	"Springhill Suites Jakarta",
-	"TownePlace Suites Jakarta",
-	"Protea Hotel Jakarta",
-	"AC Hotel Jakarta",
-	"Moxy Jakarta",
-	"Gaylord Hotels Jakarta",
-	"Delta Hotels Jakarta",
-	"St. Regis Jakarta",
-	"Luxury Collection Jakarta",
-	"Tribute Portfolio Jakarta",
-	"Design Hotels Jakarta",
-	"Autograph Collection Jakarta",
-	"Marriott Executive Apartments Jakarta",
-	"Marriott Vacation Club Jakarta",
-	"Ritz-Carlton Reserve Jakarta",
-	"Edition Hotels Jakarta",
-	"Bulgari Hotels Jakarta",
-	"Park Hyatt Jakarta",
-	"Andaz Jakarta",
-	"Hyatt Regency Jakarta",
-	"Hyatt Place Jakarta",
-	"Hyatt House Jakarta",
-	"Grand Hyatt Bandung",
-	"Hyatt Regency Bandung",
-	"Grand Hyatt Surabaya",
-	"Hyatt Regency Surabaya",
-	"Grand Hyatt Medan",
-	"Hyatt Regency Medan",
-}
-
-func Seed(ctx context.Context, client *firestore.Client) error {
+func Seed(ctx context.Context, client *firestore.Client, data *spec.Data, mode utils.WriteMode) error {
 	log.Println("Starting hotel room seeder...")
 
 	collection := client.Collection("hotel_rooms")
-	bw := client.BulkWriter(ctx)
+	roomTypeCollection := client.Collection("hotel_room_types")
+	rateCollection := client.Collection("pricing_room_rates")
+	seasons := pricing.HolidaySeasons(time.Now().Year())
 
-	for _, hotelName := range hotelBrands {
-		log.Printf("Seeding %s...", hotelName)
+	var rooms, roomTypes, rates []utils.Doc
+	for _, h := range data.Hotels {
+		for _, roomType := range h.RoomTypes {
+			roomTypes = append(roomTypes, utils.Doc{Ref: roomTypeCollection.Doc(roomType.ID), Data: hotel.RoomType{
+				ID:         roomType.ID,
+				HotelName:  h.Name,
+				Name:       roomType.Name,
+				City:       h.City,
+				TotalUnits: len(roomType.Rooms),
+			}})
 
-		// 5 floors, 20 units per floor
-		for floor := 1; floor <= 5; floor++ {
-			for unitNumber := 1; unitNumber <= 20; unitNumber++ {
-				roomName := fmt.Sprintf("%d%02d", floor, unitNumber)
-				roomID := utils.Slugify(fmt.Sprintf("%s-%s", hotelName, roomName))
+			rates = append(rates, utils.Doc{Ref: rateCollection.Doc(roomType.ID), Data: pricing.RoomRate{
+				RoomTypeID:  roomType.ID,
+				NightlyRate: roomType.NightlyRate,
+				WeekendRate: roomType.WeekendRate,
+				Seasons:     seasons,
+			}})
 
-				hotelRoom := hotel.HotelRoom{
-					ID:        roomID,
-					HotelName: hotelName,
-					RoomName:  roomName,
-				}
+			for _, room := range roomType.Rooms {
+				rooms = append(rooms, utils.Doc{Ref: collection.Doc(room.ID), Data: hotel.HotelRoom{
+					ID:         room.ID,
+					HotelName:  h.Name,
+					RoomName:   room.Name,
+					City:       h.City,
+					RoomTypeID: roomType.ID,
+				}})
 
-				docRef := collection.Doc(roomID)
-				bw.Set(docRef, hotelRoom)
+				rates = append(rates, utils.Doc{Ref: rateCollection.Doc(room.ID), Data: pricing.RoomRate{
+					HotelRoomID: room.ID,
+					NightlyRate: room.NightlyRate,
+					WeekendRate: room.WeekendRate,
+					Seasons:     seasons,
+				}})
 			}
 		}
 	}
 
-	// Flush all writes
-	bw.Flush()
+	result, err := utils.BulkWrite(ctx, client, rooms, mode)
+	if err != nil {
+		return fmt.Errorf("failed to write hotel rooms: %w", err)
+	}
+	if _, err := utils.BulkWrite(ctx, client, roomTypes, mode); err != nil {
+		return fmt.Errorf("failed to write room types: %w", err)
+	}
+	if _, err := utils.BulkWrite(ctx, client, rates, mode); err != nil {
+		return fmt.Errorf("failed to write room rates: %w", err)
+	}
 
-	log.Printf("Hotel room seeder completed. Total rooms: %d", len(hotelBrands)*5*20)
+	log.Printf("Hotel room seeder completed. Total rooms: %d, room types: %d, written: %d, skipped: %d", len(rooms), len(roomTypes), result.Written, result.Skipped)
 	return nil
 }
@@ -2,20 +2,55 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
 	"os"
+	"time"
 
 	"cloud.google.com/go/firestore"
 	"github.com/joho/godotenv"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/cmd/seeder/car"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/cmd/seeder/flight"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/cmd/seeder/hotel"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/cmd/seeder/spec"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/cmd/seeder/train"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/config"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/utils"
 )
 
 func main() {
+	specPath := flag.String("spec", "", "file spec YAML atau JSON (default spec bawaan cmd/seeder/spec/default.yaml)")
+	seed := flag.Int64("seed", 0, "seed pembangkitan acak, menimpa seed di spec")
+	upsert := flag.Bool("upsert", false, "perbarui dokumen yang sudah ada sesuai spec, bukan hanya membuat dokumen baru")
+	dryRun := flag.Bool("dry-run", false, "hanya tampilkan ringkasan data tanpa menulis ke database")
+	flag.Parse()
+
 	log.Println("Starting database seeder...")
 
+	s, err := spec.Load(*specPath)
+	if err != nil {
+		log.Fatalf("Failed to load spec: %v", err)
+	}
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			s.Seed = *seed
+		}
+	})
+
+	data, err := s.Build(time.Now())
+	if err != nil {
+		log.Fatalf("Failed to build seed data: %v", err)
+	}
+	log.Printf("Seed data: %s", data.Summary())
+	if *dryRun {
+		return
+	}
+
+	mode := utils.WriteModeCreate
+	if *upsert {
+		mode = utils.WriteModeUpsert
+	}
+
 	if err := godotenv.Load(); err != nil {
 		log.Printf("Error loading .env file: %v, using system environment variables", err)
 	}
@@ -34,24 +69,31 @@ func main() {
 
 	// Run car seeder
 	log.Println("Seeding car data...")
-	if err := car.Seed(ctx, client); err != nil {
+	if err := car.Seed(ctx, client, data, mode); err != nil {
 		log.Printf("Error seeding car data: %v", err)
 		os.Exit(1)
 	}
 
 	// Run hotel seeder
 	log.Println("Seeding hotel room data...")
-	if err := hotel.Seed(ctx, client); err != nil {
+	if err := hotel.Seed(ctx, client, data, mode); err != nil {
 		log.Printf("Error seeding hotel room data: %v", err)
 		os.Exit(1)
 	}
 
 	// Run train seeder
 	log.Println("Seeding train data...")
-	if err := train.Seed(ctx, client); err != nil {
+	if err := train.Seed(ctx, client, data, mode); err != nil {
 		log.Printf("Error seeding train data: %v", err)
 		os.Exit(1)
 	}
 
-	log.Println("Database seeding completed successfully!")
+	// Run flight seeder
+	log.Println("Seeding flight data...")
+	if err := flight.Seed(ctx, client, data, mode); err != nil {
+		log.Printf("Error seeding flight data: %v", err)
+		os.Exit(1)
+	}
+
+	log.Printf("Database seeding completed successfully! %s", data.Summary())
 }
@@ -0,0 +1,348 @@
+// Package spec membaca spesifikasi data seed dari file YAML atau JSON. Seeder dan
+// csv-exporter membangun data dari spec yang sama sehingga CSV selalu sesuai dengan isi
+// database.
+package spec
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/internal/pricing"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultSpec adalah spec yang dipakai jika tidak ada file spec, isinya sama dengan data
+// seed sebelum seeder dapat dikonfigurasi
+//
+//go:embed default.yaml
+var defaultSpec []byte
+
+// Spec adalah seluruh data yang di-seed
+type Spec struct {
+	// Seed menentukan hasil pembangkitan acak. Spec dan seed yang sama selalu menghasilkan
+	// data yang sama, juga pada seeder twophase.
+	Seed int64 `yaml:"seed"`
+	// RateJitterPercent mengacak tarif setiap hotel, model mobil, kereta, dan penerbangan
+	// sebesar ±RateJitterPercent persen. 0 berarti tarif tidak diacak.
+	RateJitterPercent int `yaml:"rate_jitter_percent"`
+	// Availability adalah jendela tanggal untuk inventaris yang tidak menentukan jendelanya
+	// sendiri
+	Availability Window `yaml:"availability"`
+
+	Cars    Cars    `yaml:"cars"`
+	Hotels  Hotels  `yaml:"hotels"`
+	Trains  Trains  `yaml:"trains"`
+	Flights Flights `yaml:"flights"`
+}
+
+// Window adalah rentang tanggal inventaris. Kereta dan pesawat dijadwalkan setiap tanggal,
+// sedangkan mobil dan kamar hanya memiliki dokumen ketersediaan per tanggal pada twophase.
+type Window struct {
+	// StartDate adalah tanggal pertama dengan format YYYY-MM-DD. Jika kosong, tanggal pertama
+	// adalah hari ini ditambah StartOffsetDays.
+	StartDate       string `yaml:"start_date"`
+	StartOffsetDays int    `yaml:"start_offset_days"`
+	Days            int    `yaml:"days"`
+}
+
+// Cars adalah armada mobil. Setiap model memiliki Units unit dengan nama
+// "${brand} ${model} - ${nomor unit}".
+type Cars struct {
+	Availability  *Window `yaml:"availability"`
+	UnitsPerModel int     `yaml:"units_per_model"`
+	// DailyRate adalah tarif model pertama setiap brand, model berikutnya lebih mahal
+	// ModelSurcharge
+	DailyRate      int64 `yaml:"daily_rate"`
+	ModelSurcharge int64 `yaml:"model_surcharge"`
+	// WeekendRatePercent adalah tarif hari Sabtu dan Minggu dalam persen dari tarif biasa
+	WeekendRatePercent int64   `yaml:"weekend_rate_percent"`
+	Fleets             []Fleet `yaml:"fleets"`
+}
+
+// Fleet adalah model mobil satu brand
+type Fleet struct {
+	Brand  string   `yaml:"brand"`
+	Models []string `yaml:"models"`
+	// Units menimpa UnitsPerModel untuk brand ini
+	Units int `yaml:"units"`
+}
+
+// Hotels adalah hotel beserta susunan kamarnya. Setiap lantai memiliki RoomsPerFloor kamar
+// dengan nama "${lantai}${nomor kamar 2 digit}".
+type Hotels struct {
+	Availability  *Window `yaml:"availability"`
+	RoomsPerFloor int     `yaml:"rooms_per_floor"`
+	// NightlyRate adalah tarif kamar lantai 1, setiap lantai di atasnya lebih mahal
+	// FloorSurcharge
+	NightlyRate    int64 `yaml:"nightly_rate"`
+	FloorSurcharge int64 `yaml:"floor_surcharge"`
+	// WeekendRatePercent adalah tarif malam Jumat dan Sabtu dalam persen dari tarif biasa
+	WeekendRatePercent int64 `yaml:"weekend_rate_percent"`
+	// RoomTypes membagi lantai setiap hotel menjadi tipe kamar yang dijual
+	RoomTypes  []RoomType      `yaml:"room_types"`
+	Properties []Property      `yaml:"properties"`
+	Generate   GeneratedHotels `yaml:"generate"`
+}
+
+// RoomType adalah tipe kamar yang menempati lantai FirstFloor sampai LastFloor
+type RoomType struct {
+	Name       string `yaml:"name"`
+	FirstFloor int    `yaml:"first_floor"`
+	LastFloor  int    `yaml:"last_floor"`
+}
+
+// Property adalah satu hotel
+type Property struct {
+	Name string `yaml:"name"`
+	City string `yaml:"city"`
+	// RoomsPerFloor menimpa jumlah kamar per lantai untuk hotel ini
+	RoomsPerFloor int `yaml:"rooms_per_floor"`
+}
+
+// GeneratedHotels membangkitkan Count hotel tambahan bernama "${brand} ${kota}" dari
+// pasangan brand dan kota yang dipilih acak berdasarkan Seed
+type GeneratedHotels struct {
+	Count  int      `yaml:"count"`
+	Brands []string `yaml:"brands"`
+	Cities []string `yaml:"cities"`
+}
+
+// Trains adalah jadwal kereta. Setiap rute berangkat satu kali setiap tanggal di jendela
+// ketersediaannya.
+type Trains struct {
+	Availability  *Window      `yaml:"availability"`
+	Coaches       int          `yaml:"coaches"`
+	SeatsPerCoach int          `yaml:"seats_per_coach"`
+	Classes       []CoachClass `yaml:"classes"`
+	Routes        []TrainRoute `yaml:"routes"`
+}
+
+// CoachClass adalah kelas kursi gerbong FirstCoach sampai LastCoach beserta tarif per segmen
+type CoachClass struct {
+	Class       pricing.SeatClass `yaml:"class"`
+	FirstCoach  int               `yaml:"first_coach"`
+	LastCoach   int               `yaml:"last_coach"`
+	SegmentFare int64             `yaml:"segment_fare"`
+}
+
+// TrainRoute adalah urutan stasiun yang dilewati satu kereta
+type TrainRoute struct {
+	Name     string   `yaml:"name"`
+	Stations []string `yaml:"stations"`
+}
+
+// Flights adalah jadwal penerbangan. Setiap rute berangkat satu kali setiap tanggal di
+// jendela ketersediaannya.
+type Flights struct {
+	Availability *Window       `yaml:"availability"`
+	Rows         int           `yaml:"rows"`
+	SeatsPerRow  int           `yaml:"seats_per_row"`
+	Classes      []RowClass    `yaml:"classes"`
+	Routes       []FlightRoute `yaml:"routes"`
+}
+
+// RowClass adalah kelas tarif baris FirstRow sampai LastRow beserta tarif per kursi
+type RowClass struct {
+	Class    pricing.FareClass `yaml:"class"`
+	FirstRow int               `yaml:"first_row"`
+	LastRow  int               `yaml:"last_row"`
+	Fare     int64             `yaml:"fare"`
+}
+
+// FlightRoute adalah satu nomor penerbangan harian
+type FlightRoute struct {
+	FlightNumber  string `yaml:"flight_number"`
+	Airline       string `yaml:"airline"`
+	Origin        string `yaml:"origin"`
+	Destination   string `yaml:"destination"`
+	DepartureTime string `yaml:"departure_time"`
+}
+
+// Load membaca spec dari path. Path kosong memuat spec default.
+func Load(path string) (*Spec, error) {
+	data := defaultSpec
+	if path != "" {
+		var err error
+		data, err = os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read spec: %w", err)
+		}
+	}
+
+	// JSON juga YAML yang valid, sehingga keduanya dibaca dengan decoder yang sama
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	var s Spec
+	if err := decoder.Decode(&s); err != nil {
+		return nil, fmt.Errorf("failed to parse spec: %w", err)
+	}
+	if err := s.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid spec: %w", err)
+	}
+	return &s, nil
+}
+
+// Validate memeriksa bahwa spec dapat dibangun menjadi data seed
+func (s *Spec) Validate() error {
+	if s.RateJitterPercent < 0 || s.RateJitterPercent >= 100 {
+		return fmt.Errorf("rate_jitter_percent must be between 0 and 99, got %d", s.RateJitterPercent)
+	}
+	if err := s.Availability.validate(); err != nil {
+		return fmt.Errorf("availability: %w", err)
+	}
+
+	return errors.Join(s.Cars.validate(), s.Hotels.validate(), s.Trains.validate(), s.Flights.validate())
+}
+
+func (w *Window) validate() error {
+	if w == nil {
+		return nil
+	}
+	if w.StartDate != "" {
+		if _, err := time.Parse(config.DateFormat, w.StartDate); err != nil {
+			return fmt.Errorf("invalid start_date %q", w.StartDate)
+		}
+	}
+	if w.Days < 1 {
+		return fmt.Errorf("days must be at least 1, got %d", w.Days)
+	}
+	return nil
+}
+
+// dates mengembalikan setiap tanggal di jendela
+func (w Window) dates(today time.Time) []string {
+	start := today.AddDate(0, 0, w.StartOffsetDays)
+	if w.StartDate != "" {
+		start, _ = time.Parse(config.DateFormat, w.StartDate)
+	}
+
+	dates := make([]string, 0, w.Days)
+	for day := 0; day < w.Days; day++ {
+		dates = append(dates, start.AddDate(0, 0, day).Format(config.DateFormat))
+	}
+	return dates
+}
+
+func (c Cars) validate() error {
+	if err := c.Availability.validate(); err != nil {
+		return fmt.Errorf("cars.availability: %w", err)
+	}
+	for _, fleet := range c.Fleets {
+		if fleet.Brand == "" || len(fleet.Models) == 0 {
+			return errors.New("cars.fleets: every fleet needs a brand and at least one model")
+		}
+		if units := c.units(fleet); units < 1 || units > 999 {
+			return fmt.Errorf("cars.fleets: %s must have between 1 and 999 units per model, got %d", fleet.Brand, units)
+		}
+	}
+	return nil
+}
+
+func (c Cars) units(fleet Fleet) int {
+	if fleet.Units != 0 {
+		return fleet.Units
+	}
+	return c.UnitsPerModel
+}
+
+func (h Hotels) validate() error {
+	if err := h.Availability.validate(); err != nil {
+		return fmt.Errorf("hotels.availability: %w", err)
+	}
+	if len(h.Properties)+h.Generate.Count > 0 && len(h.RoomTypes) == 0 {
+		return errors.New("hotels.room_types must not be empty")
+	}
+	for _, roomType := range h.RoomTypes {
+		if roomType.Name == "" || roomType.FirstFloor < 1 || roomType.LastFloor < roomType.FirstFloor {
+			return fmt.Errorf("hotels.room_types: %q needs a name and floors first_floor <= last_floor starting at 1", roomType.Name)
+		}
+	}
+	for _, property := range h.Properties {
+		if property.Name == "" || property.City == "" {
+			return fmt.Errorf("hotels.properties: %q needs a name and a city", property.Name)
+		}
+		if rooms := h.roomsPerFloor(property); rooms < 1 || rooms > 99 {
+			return fmt.Errorf("hotels.properties: %s must have between 1 and 99 rooms per floor, got %d", property.Name, rooms)
+		}
+	}
+	if h.Generate.Count < 0 {
+		return fmt.Errorf("hotels.generate.count must not be negative, got %d", h.Generate.Count)
+	}
+	if h.Generate.Count > 0 && (len(h.Generate.Brands) == 0 || len(h.Generate.Cities) == 0) {
+		return errors.New("hotels.generate needs brands and cities")
+	}
+	if h.Generate.Count > 0 && (h.RoomsPerFloor < 1 || h.RoomsPerFloor > 99) {
+		return fmt.Errorf("hotels.rooms_per_floor must be between 1 and 99, got %d", h.RoomsPerFloor)
+	}
+	return nil
+}
+
+func (h Hotels) roomsPerFloor(property Property) int {
+	if property.RoomsPerFloor != 0 {
+		return property.RoomsPerFloor
+	}
+	return h.RoomsPerFloor
+}
+
+func (t Trains) validate() error {
+	if err := t.Availability.validate(); err != nil {
+		return fmt.Errorf("trains.availability: %w", err)
+	}
+	if len(t.Routes) == 0 {
+		return nil
+	}
+	if t.Coaches < 1 || t.SeatsPerCoach < 1 {
+		return fmt.Errorf("trains need at least 1 coach and 1 seat per coach, got %d and %d", t.Coaches, t.SeatsPerCoach)
+	}
+	for _, class := range t.Classes {
+		switch class.Class {
+		case pricing.SeatClassExecutive, pricing.SeatClassBusiness, pricing.SeatClassEconomy:
+		default:
+			return fmt.Errorf("trains.classes: unknown class %q", class.Class)
+		}
+		if class.FirstCoach < 1 || class.LastCoach < class.FirstCoach || class.LastCoach > t.Coaches {
+			return fmt.Errorf("trains.classes: %s coaches %d-%d are outside 1-%d", class.Class, class.FirstCoach, class.LastCoach, t.Coaches)
+		}
+	}
+	for _, route := range t.Routes {
+		if route.Name == "" || len(route.Stations) < 2 {
+			return fmt.Errorf("trains.routes: %q needs a name and at least 2 stations", route.Name)
+		}
+	}
+	return nil
+}
+
+func (f Flights) validate() error {
+	if err := f.Availability.validate(); err != nil {
+		return fmt.Errorf("flights.availability: %w", err)
+	}
+	if len(f.Routes) == 0 {
+		return nil
+	}
+	// Kursi diberi huruf A sampai Z
+	if f.Rows < 1 || f.SeatsPerRow < 1 || f.SeatsPerRow > 26 {
+		return fmt.Errorf("flights need at least 1 row and between 1 and 26 seats per row, got %d and %d", f.Rows, f.SeatsPerRow)
+	}
+	for _, class := range f.Classes {
+		switch class.Class {
+		case pricing.FareClassFirst, pricing.FareClassBusiness, pricing.FareClassEconomy:
+		default:
+			return fmt.Errorf("flights.classes: unknown class %q", class.Class)
+		}
+		if class.FirstRow < 1 || class.LastRow < class.FirstRow || class.LastRow > f.Rows {
+			return fmt.Errorf("flights.classes: %s rows %d-%d are outside 1-%d", class.Class, class.FirstRow, class.LastRow, f.Rows)
+		}
+	}
+	for _, route := range f.Routes {
+		if route.FlightNumber == "" || route.Origin == "" || route.Destination == "" {
+			return fmt.Errorf("flights.routes: %q needs a flight number, origin and destination", route.FlightNumber)
+		}
+	}
+	return nil
+}
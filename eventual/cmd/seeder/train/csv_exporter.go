@@ -6,10 +6,11 @@ import (
 	"log"
 	"os"
 
-	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/utils"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/cmd/seeder/spec"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/internal/train"
 )
 
-func ExportToCSV(filename string) error {
+func ExportToCSV(filename string, data *spec.Data) error {
 	log.Println("Starting train CSV export...")
 
 	// Create CSV file
@@ -23,26 +24,36 @@ func ExportToCSV(filename string) error {
 	defer writer.Flush()
 
 	// Write header
-	if err := writer.Write([]string{"ID", "SeatID", "TrainName"}); err != nil {
+	if err := writer.Write([]string{"JourneyID", "DepartureDate", "SeatID", "TrainName", "OriginStation", "DestinationStation"}); err != nil {
 		return fmt.Errorf("failed to write header: %w", err)
 	}
 
-	// Generate the same data as seeder but write to CSV
-	for _, trainName := range trainNames {
-		log.Printf("Exporting %s...", trainName)
-
-		// 500 seats per train (10 trains × 500 seats = 5000 total)
-		for seatNumber := 1; seatNumber <= 500; seatNumber++ {
-			seatID := fmt.Sprintf("%d", seatNumber)
-			seatDocID := utils.Slugify(fmt.Sprintf("%s-%s", trainName, seatID))
-
-			// Write to CSV
-			if err := writer.Write([]string{seatDocID, seatID, trainName}); err != nil {
-				return fmt.Errorf("failed to write row: %w", err)
+	// Data dibangun dari spec yang sama dengan seeder, satu baris per kursi per perjalanan
+	trainJourneys := data.Journeys
+	seats := 0
+	for _, trainJourney := range trainJourneys {
+		log.Printf("Exporting %s on %s...", trainJourney.TrainName, trainJourney.DepartureDate)
+
+		for coach := 1; coach <= trainJourney.Coaches; coach++ {
+			for seatNumber := 1; seatNumber <= trainJourney.SeatsPerCoach; seatNumber++ {
+				row := []string{
+					trainJourney.ID,
+					trainJourney.DepartureDate,
+					train.SeatID(coach, seatNumber),
+					trainJourney.TrainName,
+					trainJourney.Stations[0],
+					trainJourney.Stations[len(trainJourney.Stations)-1],
+				}
+
+				// Write to CSV
+				if err := writer.Write(row); err != nil {
+					return fmt.Errorf("failed to write row: %w", err)
+				}
+				seats++
 			}
 		}
 	}
 
-	log.Printf("Train CSV export completed. Total seats: %d", len(trainNames)*500)
+	log.Printf("Train CSV export completed. Total seats: %d", seats)
 	return nil
 }
@@ -6,51 +6,44 @@ import (
 	"log"
 
 	"cloud.google.com/go/firestore"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/cmd/seeder/spec"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/internal/pricing"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/internal/train"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/utils"
 )
 
-var trainNames = []string{
-	"Argo Bromo Anggrek",
-	"Argo Lawu",
-	"Argo Parahyangan",
-	"Bima",
-	"Gajayana",
-	"Harina",
-	"Kertajaya",
-	"Lodaya",
-	"Malabar",
-	"Matarmaja",
-}
-
-func Seed(ctx context.Context, client *firestore.Client) error {
-	log.Println("Starting train seeder...")
-
-	collection := client.Collection("train_seats")
-	bw := client.BulkWriter(ctx)
-
-	for _, trainName := range trainNames {
-		log.Printf("Seeding %s...", trainName)
-
-		// 500 seats per train (10 trains × 500 seats = 5000 total)
-		for seatNumber := 1; seatNumber <= 500; seatNumber++ {
-			seatID := fmt.Sprintf("%d", seatNumber)
-			seatDocID := utils.Slugify(fmt.Sprintf("%s-%s", trainName, seatID))
-
-			trainSeat := train.TrainSeat{
-				ID:        seatDocID,
-				SeatID:    seatID,
-				TrainName: trainName,
-			}
-
-			docRef := collection.Doc(seatDocID)
-			bw.Set(docRef, trainSeat)
-		}
+func Seed(ctx context.Context, client *firestore.Client, data *spec.Data, mode utils.WriteMode) error {
+	log.Println("Starting train journey seeder...")
+
+	collection := client.Collection("train_journeys")
+	fareCollection := client.Collection("pricing_journey_fares")
+
+	var journeys, fares []utils.Doc
+	for _, journey := range data.Journeys {
+		journeys = append(journeys, utils.Doc{Ref: collection.Doc(journey.ID), Data: train.TrainJourney{
+			ID:            journey.ID,
+			TrainName:     journey.TrainName,
+			DepartureDate: journey.DepartureDate,
+			Stations:      journey.Stations,
+			Coaches:       journey.Coaches,
+			SeatsPerCoach: journey.SeatsPerCoach,
+		}})
+
+		fares = append(fares, utils.Doc{Ref: fareCollection.Doc(journey.ID), Data: pricing.JourneyFare{
+			JourneyID: journey.ID,
+			Stations:  journey.Stations,
+			Classes:   journey.Classes,
+		}})
 	}
 
-	// Flush all writes
-	bw.Flush()
+	result, err := utils.BulkWrite(ctx, client, journeys, mode)
+	if err != nil {
+		return fmt.Errorf("failed to write train journeys: %w", err)
+	}
+	if _, err := utils.BulkWrite(ctx, client, fares, mode); err != nil {
+		return fmt.Errorf("failed to write journey fares: %w", err)
+	}
 
-	log.Printf("Train seeder completed. Total seats: %d", len(trainNames)*500)
+	log.Printf("Train journey seeder completed. Total journeys: %d, written: %d, skipped: %d", len(journeys), result.Written, result.Skipped)
 	return nil
 }
@@ -6,13 +6,14 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"cloud.google.com/go/firestore"
 	"github.com/joho/godotenv"
-	"github.com/rabbitmq/amqp091-go"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/internal/train"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/config"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/event"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/fault"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/messagebus"
 )
 
@@ -31,7 +32,7 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
-	conn, err := amqp091.Dial(cfg.RabbitMQURL)
+	conn, err := messagebus.Dial(cfg.RabbitMQURL)
 	if err != nil {
 		log.Fatalf("Failed to connect to RabbitMQ: %v", err)
 	}
@@ -43,15 +44,51 @@ func main() {
 	}
 	defer client.Close()
 
-	publisher := messagebus.NewRabbitmqPublisher(conn)
+	faultConfig, err := fault.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load fault config: %v", err)
+	}
+	injector := fault.NewInjector("train-service", faultConfig, fault.NewFirestoreRecorder(client))
+	if cfg.FaultAdminPort != "" {
+		go fault.ServeAdmin(ctx, cfg.FaultAdminPort, injector)
+	}
 
-	trainRepo := train.NewFirestoreRepository(client)
-	trainService := train.NewService(trainRepo, publisher)
+	publisher := fault.NewPublisher(messagebus.NewRabbitmqPublisher(conn), injector)
+
+	trainRepo := train.NewFaultyRepository(train.NewFirestoreRepository(client), injector)
+	trainService := train.NewService(trainRepo, publisher, cfg.HoldTTL)
+
+	subscriber := fault.NewSubscriber(messagebus.NewRabbitmqSubscriber(conn), injector)
+	if err := subscriber.Subscribe(ctx, "", cfg.TrainQueueName, func(e event.Message) {
+		if err := trainService.ProcessSagaEvent(ctx, e); err != nil {
+			log.Printf("Failed to process %s for %s: %v", e.EventName, e.CorrelationID, err)
+		}
+		// Pada saga koreografi service juga bereaksi terhadap event partisipan lain
+		if cfg.SagaMode == config.SagaModeChoreography {
+			if err := trainService.ProcessChoreographyEvent(ctx, e); err != nil {
+				log.Printf("Failed to process %s for %s: %v", e.EventName, e.CorrelationID, err)
+			}
+		}
+	}); err != nil {
+		log.Fatalf("Failed to subscribe: %v", err)
+	}
 
-	subscriber := messagebus.NewRabbitmqSubscriber(conn)
-	subscriber.Subscribe(ctx, "", cfg.TrainQueueName, func(e event.Message) {
-		trainService.ProcessSagaEvent(ctx, e)
-	})
+	// Sweeper melepas hold yang kedaluwarsa
+	go func() {
+		ticker := time.NewTicker(cfg.HoldSweepInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := trainService.ReleaseExpiredHolds(ctx); err != nil {
+					log.Printf("Failed to release expired holds: %v", err)
+				}
+			}
+		}
+	}()
 
 	log.Println("Train service started")
 
@@ -69,9 +106,8 @@ func main() {
 	// Cancel context to stop all operations
 	cancel()
 
-	// Note: Subscriber goroutines will be terminated when context is cancelled
-	// and connection is closed. The current implementation doesn't provide
-	// explicit unsubscribe functionality.
+	// Note: Subscriber goroutines close their channels when context is cancelled
+	// and stop re-establishing consumers once the connection is closed.
 
 	log.Println("Train service stopped gracefully")
 }
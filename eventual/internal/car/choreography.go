@@ -0,0 +1,49 @@
+package car
+
+import (
+	"context"
+	"log"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/event"
+)
+
+// ProcessChoreographyEvent menjalankan bagian car service pada saga booking koreografi.
+// Mobil direservasi setelah kamar berhasil direservasi dan dibatalkan saat partisipan
+// setelahnya gagal.
+func (s *service) ProcessChoreographyEvent(ctx context.Context, msg event.Message) error {
+	switch msg.EventName {
+	case event.RoomReserved:
+		payload, err := mapToPayload[event.RoomReservedPayload](msg)
+		if err != nil {
+			return s.publishErrorEvent(ctx, msg, err)
+		}
+		// Event tanpa order tidak dapat diteruskan ke partisipan berikutnya. Event dibuang
+		// tanpa mengirim event gagal agar partisipan sebelumnya tidak mengompensasi order
+		// yang masih berjalan. Order koreografi tidak dapat di-retry setelah PENDING, jadi
+		// order tersebut tertahan sampai dikompensasi dengan bookingctl saga compensate.
+		if payload.Order == nil {
+			log.Printf("Dropping %s of order %s without order payload", msg.EventName, msg.CorrelationID)
+			return nil
+		}
+		if len(payload.Order.Cars) == 0 {
+			return s.publisher.Publish(ctx, string(event.CarReserved), event.Message{
+				EventName:     event.CarReserved,
+				CorrelationID: msg.CorrelationID,
+				Payload:       event.CarReservedPayload{Order: payload.Order},
+			})
+		}
+		return s.handleReserveCar(ctx, event.Message{
+			EventName:     event.CommandReserveCar,
+			CorrelationID: msg.CorrelationID,
+			Payload:       event.ReserveCarPayload{Cars: payload.Order.Cars, Order: payload.Order},
+		})
+	case event.SeatReservationFailed, event.FlightReservationFailed, event.PaymentFailed, event.PaymentCaptureFailed:
+		return s.handleCancelCar(ctx, event.Message{
+			EventName:     event.CommandCancelCar,
+			CorrelationID: msg.CorrelationID,
+			Payload:       event.CancelCarPayload{OrderID: msg.CorrelationID},
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package car
+
+import (
+	"context"
+	"time"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/fault"
+)
+
+// faultyRepository menyuntikkan kegagalan sebelum dan sesudah setiap penulisan
+// reservasi. Pembacaan diteruskan tanpa perubahan.
+type faultyRepository struct {
+	Repository
+	injector *fault.Injector
+}
+
+func NewFaultyRepository(repo Repository, injector *fault.Injector) Repository {
+	return &faultyRepository{Repository: repo, injector: injector}
+}
+
+func (r *faultyRepository) CreateCarReservations(ctx context.Context, carReservations []*CarReservation) error {
+	orderID := ""
+	if len(carReservations) > 0 {
+		orderID = carReservations[0].OrderID
+	}
+	return r.injector.Do("CreateCarReservations", orderID, func() error {
+		return r.Repository.CreateCarReservations(ctx, carReservations)
+	})
+}
+
+func (r *faultyRepository) ReplaceCarReservation(ctx context.Context, replacedID string, carReservation *CarReservation) error {
+	return r.injector.Do("ReplaceCarReservation", carReservation.OrderID, func() error {
+		return r.Repository.ReplaceCarReservation(ctx, replacedID, carReservation)
+	})
+}
+
+func (r *faultyRepository) UpdateCarReservation(ctx context.Context, carReservation *CarReservation) error {
+	return r.injector.Do("UpdateCarReservation", carReservation.OrderID, func() error {
+		return r.Repository.UpdateCarReservation(ctx, carReservation)
+	})
+}
+
+func (r *faultyRepository) ConfirmCarHolds(ctx context.Context, orderID string, now time.Time) ([]*CarReservation, error) {
+	return fault.Call(r.injector, "ConfirmCarHolds", orderID, func() ([]*CarReservation, error) {
+		return r.Repository.ConfirmCarHolds(ctx, orderID, now)
+	})
+}
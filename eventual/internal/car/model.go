@@ -1,15 +1,23 @@
 package car
 
+import "time"
+
 type CarReservationStatus string
 
 const (
 	CarReservationStatusCancelled CarReservationStatus = "CANCELLED"
 	CarReservationStatusReserved  CarReservationStatus = "RESERVED"
+	// HELD adalah reservasi sementara yang dilepas sweeper menjadi EXPIRED jika
+	// tidak dikonfirmasi sebelum ExpiresAt
+	CarReservationStatusHeld    CarReservationStatus = "HELD"
+	CarReservationStatusExpired CarReservationStatus = "EXPIRED"
 )
 
 type Car struct {
-	ID   string `firestore:"id" json:"id"`
-	Name string `firestore:"name" json:"name"`
+	ID    string `firestore:"id" json:"id"`
+	Name  string `firestore:"name" json:"name"`
+	Brand string `firestore:"brand" json:"brand"`
+	Model string `firestore:"model" json:"model"`
 }
 
 type CarReservation struct {
@@ -18,6 +26,52 @@ type CarReservation struct {
 	CarName   string               `firestore:"car_name" json:"car_name"`
 	StartDate string               `firestore:"start_date" json:"start_date"`
 	EndDate   string               `firestore:"end_date" json:"end_date"`
+	Price     int64                `firestore:"price" json:"price"`
 	OrderID   string               `firestore:"order_id" json:"order_id"`
 	Status    CarReservationStatus `firestore:"status" json:"status"`
+	// ExpiresAt hanya diisi selama reservasi berstatus HELD
+	ExpiresAt *time.Time `firestore:"expires_at,omitempty" json:"expires_at,omitempty"`
+}
+
+// defaultPageSize dipakai jika pencarian tidak menentukan page_size
+const defaultPageSize = 20
+
+// SearchCarsQuery adalah parameter pencarian mobil. Brand dan Model bersifat opsional.
+type SearchCarsQuery struct {
+	Brand     string `form:"brand"`
+	Model     string `form:"model"`
+	StartDate string `form:"start_date" binding:"required"`
+	EndDate   string `form:"end_date" binding:"required"`
+	PageSize  int    `form:"page_size" binding:"omitempty,min=1,max=100"`
+	// PageToken adalah NextPageToken dari halaman sebelumnya
+	PageToken string `form:"page_token"`
+}
+
+// CarPage adalah satu halaman hasil pencarian mobil.
+// NextPageToken kosong pada halaman terakhir.
+type CarPage struct {
+	Items         []*Car `json:"items"`
+	NextPageToken string `json:"next_page_token,omitempty"`
+}
+
+// maxCalendarDays membatasi rentang kalender sekitar dua bulan
+const maxCalendarDays = 62
+
+// CalendarQuery adalah rentang tanggal kalender, keduanya inklusif
+type CalendarQuery struct {
+	From string `form:"from" binding:"required"`
+	To   string `form:"to" binding:"required"`
+}
+
+// CalendarDay adalah ketersediaan mobil pada satu tanggal. Status berisi status
+// reservasi yang memblokir tanggal tersebut.
+type CalendarDay struct {
+	Date      string               `json:"date"`
+	Available bool                 `json:"available"`
+	Status    CarReservationStatus `json:"status,omitempty"`
+}
+
+type CarCalendar struct {
+	CarID string        `json:"car_id"`
+	Days  []CalendarDay `json:"days"`
 }
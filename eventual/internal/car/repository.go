@@ -3,6 +3,7 @@ package car
 import (
 	"context"
 	"errors"
+	"time"
 
 	"cloud.google.com/go/firestore"
 	"cloud.google.com/go/firestore/apiv1/firestorepb"
@@ -11,17 +12,36 @@ import (
 )
 
 var (
-	ErrCarNotFound            = errors.New("car not found")
-	ErrCarReservationNotFound = errors.New("car reservation not found")
+	ErrCarNotFound             = errors.New("car not found")
+	ErrCarReservationNotFound  = errors.New("car reservation not found")
+	ErrCarReservationNotActive = errors.New("car reservation is not active")
+	ErrCarHoldNotFound         = errors.New("car hold not found")
+	ErrCarHoldExpired          = errors.New("car hold has expired")
+	ErrInvalidDateRange        = errors.New("invalid date range")
 )
 
+// CarNotAvailableError menandakan mobil pada item ke-Index sudah direservasi
+type CarNotAvailableError struct {
+	Index int
+}
+
+func (e *CarNotAvailableError) Error() string {
+	return "car is not available"
+}
+
 type Repository interface {
 	GetCarByID(ctx context.Context, id string) (*Car, error)
-	CreateCarReservation(ctx context.Context, carReservation *CarReservation) error
+	ListCars(ctx context.Context, brand, model, startAfter string, limit int) ([]*Car, error)
+	CreateCarReservations(ctx context.Context, carReservations []*CarReservation) error
+	ReplaceCarReservation(ctx context.Context, replacedID string, carReservation *CarReservation) error
 	GetCarReservationByID(ctx context.Context, id string) (*CarReservation, error)
-	GetCarReservationByOrderID(ctx context.Context, orderID string) (*CarReservation, error)
+	GetCarReservationsByOrderID(ctx context.Context, orderID string) ([]*CarReservation, error)
 	UpdateCarReservation(ctx context.Context, carReservation *CarReservation) error
 	IsCarAvailable(ctx context.Context, carID string, startDate, endDate string) (bool, error)
+	GetOverlappingCarReservations(ctx context.Context, carID string, startDate, endDate string) ([]*CarReservation, error)
+	ConfirmCarHolds(ctx context.Context, orderID string, now time.Time) ([]*CarReservation, error)
+	GetExpiredCarHolds(ctx context.Context, now time.Time) ([]*CarReservation, error)
+	ExpireCarHold(ctx context.Context, id string, now time.Time) (bool, error)
 }
 
 const (
@@ -54,9 +74,113 @@ func (r *firestoreRepository) GetCarByID(ctx context.Context, id string) (*Car,
 	return &car, nil
 }
 
-func (r *firestoreRepository) CreateCarReservation(ctx context.Context, carReservation *CarReservation) error {
-	_, err := r.client.Collection(carReservationCollection).Doc(carReservation.ID).Set(ctx, carReservation)
-	return err
+// ListCars mengembalikan paling banyak limit mobil yang diurutkan berdasarkan ID,
+// dimulai setelah mobil startAfter. Filter brand dan model diabaikan jika kosong.
+func (r *firestoreRepository) ListCars(ctx context.Context, brand, model, startAfter string, limit int) ([]*Car, error) {
+	query := r.client.Collection(carCollection).Query
+	if brand != "" {
+		query = query.Where("brand", "==", brand)
+	}
+	if model != "" {
+		query = query.Where("model", "==", model)
+	}
+	query = query.OrderBy("id", firestore.Asc)
+	if startAfter != "" {
+		query = query.StartAfter(startAfter)
+	}
+
+	docs, err := query.Limit(limit).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	cars := make([]*Car, 0, len(docs))
+	for _, doc := range docs {
+		var car Car
+		if err := doc.DataTo(&car); err != nil {
+			return nil, err
+		}
+		cars = append(cars, &car)
+	}
+
+	return cars, nil
+}
+
+// CreateCarReservations membuat seluruh reservasi dalam satu transaksi.
+// Jika salah satu mobil tidak tersedia, tidak ada reservasi yang dibuat.
+func (r *firestoreRepository) CreateCarReservations(ctx context.Context, carReservations []*CarReservation) error {
+	// Item dalam order yang sama juga tidak boleh saling bentrok
+	for i, a := range carReservations {
+		for _, b := range carReservations[:i] {
+			if a.CarID == b.CarID && a.StartDate <= b.EndDate && a.EndDate >= b.StartDate {
+				return &CarNotAvailableError{Index: i}
+			}
+		}
+	}
+
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		for i, carReservation := range carReservations {
+			query := r.overlappingReservations(carReservation.CarID, carReservation.StartDate, carReservation.EndDate)
+			docs, err := tx.Documents(query.Limit(1)).GetAll()
+			if err != nil {
+				return err
+			}
+			if len(docs) > 0 {
+				return &CarNotAvailableError{Index: i}
+			}
+		}
+
+		for _, carReservation := range carReservations {
+			if err := tx.Create(r.client.Collection(carReservationCollection).Doc(carReservation.ID), carReservation); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// ReplaceCarReservation membuat carReservation dan membatalkan reservasi replacedID
+// dalam satu transaksi. Reservasi lama tidak dihitung bentrok dengan reservasi baru.
+func (r *firestoreRepository) ReplaceCarReservation(ctx context.Context, replacedID string, carReservation *CarReservation) error {
+	replacedRef := r.client.Collection(carReservationCollection).Doc(replacedID)
+
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(replacedRef)
+		if status.Code(err) == codes.NotFound {
+			return ErrCarReservationNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		var replaced CarReservation
+		if err := doc.DataTo(&replaced); err != nil {
+			return err
+		}
+		if replaced.OrderID != carReservation.OrderID || replaced.Status != CarReservationStatusReserved {
+			return ErrCarReservationNotActive
+		}
+
+		query := r.overlappingReservations(carReservation.CarID, carReservation.StartDate, carReservation.EndDate)
+		docs, err := tx.Documents(query.Limit(2)).GetAll()
+		if err != nil {
+			return err
+		}
+		for _, doc := range docs {
+			if doc.Ref.ID != replacedID {
+				return &CarNotAvailableError{Index: 0}
+			}
+		}
+
+		if err := tx.Create(r.client.Collection(carReservationCollection).Doc(carReservation.ID), carReservation); err != nil {
+			return err
+		}
+
+		return tx.Update(replacedRef, []firestore.Update{
+			{Path: "status", Value: CarReservationStatusCancelled},
+		})
+	})
 }
 
 func (r *firestoreRepository) GetCarReservationByID(ctx context.Context, id string) (*CarReservation, error) {
@@ -76,23 +200,23 @@ func (r *firestoreRepository) GetCarReservationByID(ctx context.Context, id stri
 	return &carReservation, nil
 }
 
-func (r *firestoreRepository) GetCarReservationByOrderID(ctx context.Context, orderID string) (*CarReservation, error) {
+func (r *firestoreRepository) GetCarReservationsByOrderID(ctx context.Context, orderID string) ([]*CarReservation, error) {
 	query := r.client.Collection(carReservationCollection).Where("order_id", "==", orderID)
 	docs, err := query.Documents(ctx).GetAll()
 	if err != nil {
 		return nil, err
 	}
 
-	if len(docs) == 0 {
-		return nil, ErrCarReservationNotFound
+	carReservations := make([]*CarReservation, 0, len(docs))
+	for _, doc := range docs {
+		var carReservation CarReservation
+		if err := doc.DataTo(&carReservation); err != nil {
+			return nil, err
+		}
+		carReservations = append(carReservations, &carReservation)
 	}
 
-	var carReservation CarReservation
-	if err := docs[0].DataTo(&carReservation); err != nil {
-		return nil, err
-	}
-
-	return &carReservation, nil
+	return carReservations, nil
 }
 
 func (r *firestoreRepository) UpdateCarReservation(ctx context.Context, carReservation *CarReservation) error {
@@ -100,12 +224,17 @@ func (r *firestoreRepository) UpdateCarReservation(ctx context.Context, carReser
 	return err
 }
 
-func (r *firestoreRepository) IsCarAvailable(ctx context.Context, carID string, startDate, endDate string) (bool, error) {
-	query := r.client.Collection(carReservationCollection).
+// overlappingReservations adalah query reservasi aktif yang beririsan dengan rentang tanggal
+func (r *firestoreRepository) overlappingReservations(carID string, startDate, endDate string) firestore.Query {
+	return r.client.Collection(carReservationCollection).
 		Where("car_id", "==", carID).
 		Where("start_date", "<=", endDate).
 		Where("end_date", ">=", startDate).
-		Where("status", "!=", CarReservationStatusCancelled)
+		Where("status", "not-in", []CarReservationStatus{CarReservationStatusCancelled, CarReservationStatusExpired})
+}
+
+func (r *firestoreRepository) IsCarAvailable(ctx context.Context, carID string, startDate, endDate string) (bool, error) {
+	query := r.overlappingReservations(carID, startDate, endDate)
 
 	aggregationQuery := query.NewAggregationQuery().WithCount("all")
 	results, err := aggregationQuery.Get(ctx)
@@ -122,3 +251,136 @@ func (r *firestoreRepository) IsCarAvailable(ctx context.Context, carID string,
 
 	return countValue.GetIntegerValue() == 0, nil
 }
+
+// GetOverlappingCarReservations mengembalikan reservasi aktif mobil yang beririsan dengan rentang tanggal
+func (r *firestoreRepository) GetOverlappingCarReservations(ctx context.Context, carID string, startDate, endDate string) ([]*CarReservation, error) {
+	docs, err := r.overlappingReservations(carID, startDate, endDate).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	carReservations := make([]*CarReservation, 0, len(docs))
+	for _, doc := range docs {
+		var carReservation CarReservation
+		if err := doc.DataTo(&carReservation); err != nil {
+			return nil, err
+		}
+		carReservations = append(carReservations, &carReservation)
+	}
+
+	return carReservations, nil
+}
+
+// ConfirmCarHolds mengubah seluruh hold milik orderID menjadi RESERVED dalam satu transaksi.
+// Jika salah satu hold sudah kedaluwarsa, tidak ada hold yang dikonfirmasi. Hold yang
+// sudah dikonfirmasi sebelumnya dikembalikan lagi agar command yang terkirim ulang tetap dibalas.
+func (r *firestoreRepository) ConfirmCarHolds(ctx context.Context, orderID string, now time.Time) ([]*CarReservation, error) {
+	var confirmed []*CarReservation
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		confirmed = nil
+		query := r.client.Collection(carReservationCollection).Where("order_id", "==", orderID)
+		docs, err := tx.Documents(query).GetAll()
+		if err != nil {
+			return err
+		}
+
+		var held, reserved []*CarReservation
+		for _, doc := range docs {
+			var carReservation CarReservation
+			if err := doc.DataTo(&carReservation); err != nil {
+				return err
+			}
+
+			switch carReservation.Status {
+			case CarReservationStatusHeld:
+				if carReservation.ExpiresAt == nil || !now.Before(*carReservation.ExpiresAt) {
+					return ErrCarHoldExpired
+				}
+				held = append(held, &carReservation)
+			case CarReservationStatusReserved:
+				reserved = append(reserved, &carReservation)
+			}
+		}
+		if len(held) == 0 {
+			if len(reserved) == 0 {
+				return ErrCarHoldNotFound
+			}
+			confirmed = reserved
+			return nil
+		}
+
+		for _, carReservation := range held {
+			if err := tx.Update(r.client.Collection(carReservationCollection).Doc(carReservation.ID), []firestore.Update{
+				{Path: "status", Value: CarReservationStatusReserved},
+				{Path: "expires_at", Value: firestore.Delete},
+			}); err != nil {
+				return err
+			}
+			carReservation.Status = CarReservationStatusReserved
+			carReservation.ExpiresAt = nil
+		}
+		confirmed = held
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return confirmed, nil
+}
+
+// GetExpiredCarHolds mengembalikan hold yang sudah melewati ExpiresAt pada waktu now
+func (r *firestoreRepository) GetExpiredCarHolds(ctx context.Context, now time.Time) ([]*CarReservation, error) {
+	query := r.client.Collection(carReservationCollection).
+		Where("status", "==", CarReservationStatusHeld).
+		Where("expires_at", "<=", now)
+	docs, err := query.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	carReservations := make([]*CarReservation, 0, len(docs))
+	for _, doc := range docs {
+		var carReservation CarReservation
+		if err := doc.DataTo(&carReservation); err != nil {
+			return nil, err
+		}
+		carReservations = append(carReservations, &carReservation)
+	}
+
+	return carReservations, nil
+}
+
+// ExpireCarHold mengubah hold id menjadi EXPIRED jika masih HELD dan sudah kedaluwarsa.
+// Nilai kembalian false berarti hold sudah dikonfirmasi atau dilepas lebih dulu.
+func (r *firestoreRepository) ExpireCarHold(ctx context.Context, id string, now time.Time) (bool, error) {
+	ref := r.client.Collection(carReservationCollection).Doc(id)
+
+	var expired bool
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		expired = false
+		doc, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return ErrCarReservationNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		var carReservation CarReservation
+		if err := doc.DataTo(&carReservation); err != nil {
+			return err
+		}
+		if carReservation.Status != CarReservationStatusHeld || carReservation.ExpiresAt == nil || now.Before(*carReservation.ExpiresAt) {
+			return nil
+		}
+
+		expired = true
+		return tx.Update(ref, []firestore.Update{
+			{Path: "status", Value: CarReservationStatusExpired},
+		})
+	})
+
+	return expired, err
+}
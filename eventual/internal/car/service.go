@@ -4,24 +4,41 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
+	"time"
 
 	"github.com/oklog/ulid/v2"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/config"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/event"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/messagebus"
 )
 
 type Service interface {
 	ProcessSagaEvent(ctx context.Context, msg event.Message) error
+
+	// ProcessChoreographyEvent memproses event partisipan lain pada saga booking
+	// koreografi (SAGA_MODE=choreography)
+	ProcessChoreographyEvent(ctx context.Context, msg event.Message) error
+
+	// ReleaseExpiredHolds dipanggil sweeper secara berkala untuk melepas hold yang kedaluwarsa
+	ReleaseExpiredHolds(ctx context.Context) error
+
+	// SearchCars mencari mobil yang kosong pada seluruh hari dari StartDate sampai EndDate
+	SearchCars(ctx context.Context, query SearchCarsQuery) (*CarPage, error)
+
+	// GetCarCalendar mengembalikan ketersediaan mobil per tanggal dari From sampai To
+	GetCarCalendar(ctx context.Context, carID string, query CalendarQuery) (*CarCalendar, error)
 }
 
 type service struct {
 	repo      Repository
 	publisher messagebus.Publisher
+	holdTTL   time.Duration
 }
 
-func NewService(repo Repository, publisher messagebus.Publisher) Service {
-	return &service{repo: repo, publisher: publisher}
+func NewService(repo Repository, publisher messagebus.Publisher, holdTTL time.Duration) Service {
+	return &service{repo: repo, publisher: publisher, holdTTL: holdTTL}
 }
 
 func (s *service) ProcessSagaEvent(ctx context.Context, msg event.Message) error {
@@ -31,17 +48,33 @@ func (s *service) ProcessSagaEvent(ctx context.Context, msg event.Message) error
 		return s.handleReserveCar(ctx, msg)
 	case event.CommandCancelCar:
 		return s.handleCancelCar(ctx, msg)
+	case event.CommandModifyCar:
+		return s.handleModifyCar(ctx, msg)
+	case event.CommandHoldCar:
+		return s.handleHoldCar(ctx, msg)
+	case event.CommandConfirmCar:
+		return s.handleConfirmCar(ctx, msg)
 	}
 
 	return nil
 }
 
 func (s *service) publishErrorEvent(ctx context.Context, msg event.Message, err error) error {
-	s.publisher.Publish(ctx, string(event.CarReservationFailed), event.Message{
+	return s.publishItemErrorEvent(ctx, msg, nil, err)
+}
+
+// publishItemErrorEvent mengirim event gagal beserta indeks item penyebabnya
+func (s *service) publishItemErrorEvent(ctx context.Context, msg event.Message, failedItem *int, err error) error {
+	if pubErr := s.publisher.Publish(ctx, string(event.CarReservationFailed), event.Message{
 		EventName:     event.CarReservationFailed,
 		CorrelationID: msg.CorrelationID,
-		Payload:       event.CarReservationFailedPayload{FailureReason: err.Error()},
-	})
+		Payload: event.CarReservationFailedPayload{
+			FailedItem:    failedItem,
+			FailureReason: err.Error(),
+		},
+	}); pubErr != nil {
+		return errors.Join(err, pubErr)
+	}
 
 	return err
 }
@@ -52,42 +85,96 @@ func (s *service) handleReserveCar(ctx context.Context, msg event.Message) error
 		return s.publishErrorEvent(ctx, msg, err)
 	}
 
-	isAvailable, err := s.repo.IsCarAvailable(ctx, payload.CarID, payload.StartDate, payload.EndDate)
+	if len(payload.Cars) == 0 {
+		return s.publishErrorEvent(ctx, msg, errors.New("no cars requested"))
+	}
+
+	carReservations, failedItem, err := s.buildCarReservations(ctx, msg.CorrelationID, payload.Cars)
 	if err != nil {
+		return s.publishItemErrorEvent(ctx, msg, &failedItem, err)
+	}
+	for _, carReservation := range carReservations {
+		carReservation.Status = CarReservationStatusReserved
+	}
+
+	if err := s.repo.CreateCarReservations(ctx, carReservations); err != nil {
+		var notAvailableErr *CarNotAvailableError
+		if errors.As(err, &notAvailableErr) {
+			return s.publishItemErrorEvent(ctx, msg, &notAvailableErr.Index, err)
+		}
 		return s.publishErrorEvent(ctx, msg, err)
 	}
-	if !isAvailable {
-		return s.publishErrorEvent(ctx, msg, errors.New("car is not available"))
+
+	reservationIDs := make([]string, 0, len(carReservations))
+	for _, carReservation := range carReservations {
+		reservationIDs = append(reservationIDs, carReservation.ID)
 	}
 
-	car, err := s.repo.GetCarByID(ctx, payload.CarID)
+	return s.publisher.Publish(ctx, string(event.CarReserved), event.Message{
+		EventName:     event.CarReserved,
+		CorrelationID: msg.CorrelationID,
+		Payload: event.CarReservedPayload{
+			CarReservationIDs: reservationIDs,
+			Order:             payload.Order,
+		},
+	})
+}
+
+// publishModificationErrorEvent mengirim event gagal modifikasi, reservasi lama tetap aktif
+func (s *service) publishModificationErrorEvent(ctx context.Context, msg event.Message, err error) error {
+	if pubErr := s.publisher.Publish(ctx, string(event.CarModificationFailed), event.Message{
+		EventName:     event.CarModificationFailed,
+		CorrelationID: msg.CorrelationID,
+		Payload:       event.CarModificationFailedPayload{FailureReason: err.Error()},
+	}); pubErr != nil {
+		return errors.Join(err, pubErr)
+	}
+
+	return err
+}
+
+func (s *service) handleModifyCar(ctx context.Context, msg event.Message) error {
+	payload, err := mapToPayload[event.ModifyCarPayload](msg)
 	if err != nil {
-		return s.publishErrorEvent(ctx, msg, err)
+		return s.publishModificationErrorEvent(ctx, msg, err)
+	}
+
+	car, err := s.repo.GetCarByID(ctx, payload.Car.CarID)
+	if err != nil {
+		return s.publishModificationErrorEvent(ctx, msg, err)
 	}
 
 	carReservation := &CarReservation{
 		ID:        ulid.Make().String(),
 		CarID:     car.ID,
 		CarName:   car.Name,
-		StartDate: payload.StartDate,
-		EndDate:   payload.EndDate,
+		StartDate: payload.Car.StartDate,
+		EndDate:   payload.Car.EndDate,
+		Price:     payload.Car.Price,
 		OrderID:   msg.CorrelationID,
 		Status:    CarReservationStatusReserved,
 	}
-
-	if err := s.repo.CreateCarReservation(ctx, carReservation); err != nil {
-		return s.publishErrorEvent(ctx, msg, err)
+	if err := s.repo.ReplaceCarReservation(ctx, payload.ReservationID, carReservation); err != nil {
+		return s.publishModificationErrorEvent(ctx, msg, err)
 	}
 
-	s.publisher.Publish(ctx, string(event.CarReserved), event.Message{
-		EventName:     event.CarReserved,
+	if err := s.publisher.Publish(ctx, string(event.CarModified), event.Message{
+		EventName:     event.CarModified,
 		CorrelationID: msg.CorrelationID,
-		Payload: event.CarReservedPayload{
-			CarReservationID: carReservation.ID,
+		Payload: event.CarModifiedPayload{
+			ReplacedReservationID: payload.ReservationID,
+			CarReservationID:      carReservation.ID,
 		},
-	})
+	}); err != nil {
+		return err
+	}
 
-	return nil
+	// Reservasi lama sudah dibatalkan saat diganti
+	replaced, err := s.repo.GetCarReservationByID(ctx, payload.ReservationID)
+	if err != nil {
+		return err
+	}
+	return s.publishReleasedEvent(ctx, msg.CorrelationID, []*CarReservation{replaced})
 }
 
 func mapToPayload[T any](msg event.Message) (T, error) {
@@ -108,18 +195,322 @@ func (s *service) handleCancelCar(ctx context.Context, msg event.Message) error
 		return s.publishErrorEvent(ctx, msg, err)
 	}
 
-	carReservation, err := s.repo.GetCarReservationByOrderID(ctx, payload.OrderID)
-	if errors.Is(err, ErrCarReservationNotFound) {
+	carReservations, err := s.repo.GetCarReservationsByOrderID(ctx, payload.OrderID)
+	if err != nil {
+		return s.publishErrorEvent(ctx, msg, err)
+	}
+
+	reservationIDs := make([]string, 0, len(carReservations))
+	var released []*CarReservation
+	for _, carReservation := range carReservations {
+		reservationIDs = append(reservationIDs, carReservation.ID)
+		if carReservation.Status == CarReservationStatusCancelled || carReservation.Status == CarReservationStatusExpired {
+			continue
+		}
+
+		carReservation.Status = CarReservationStatusCancelled
+		if err := s.repo.UpdateCarReservation(ctx, carReservation); err != nil {
+			return s.publishErrorEvent(ctx, msg, err)
+		}
+		released = append(released, carReservation)
+	}
+
+	if err := s.publisher.Publish(ctx, string(event.CarReservationCancelled), event.Message{
+		EventName:     event.CarReservationCancelled,
+		CorrelationID: msg.CorrelationID,
+		Payload:       event.CarReservationCancelledPayload{CarReservationIDs: reservationIDs},
+	}); err != nil {
+		return err
+	}
+
+	return s.publishReleasedEvent(ctx, msg.CorrelationID, released)
+}
+
+// publishReleasedEvent mengumumkan mobil yang kembali tersedia agar waitlist dapat memesannya
+func (s *service) publishReleasedEvent(ctx context.Context, orderID string, carReservations []*CarReservation) error {
+	if len(carReservations) == 0 {
 		return nil
 	}
+
+	cars := make([]event.CarItem, 0, len(carReservations))
+	for _, carReservation := range carReservations {
+		cars = append(cars, event.CarItem{
+			CarID:     carReservation.CarID,
+			StartDate: carReservation.StartDate,
+			EndDate:   carReservation.EndDate,
+			Price:     carReservation.Price,
+		})
+	}
+
+	return s.publisher.Publish(ctx, string(event.CarReleased), event.Message{
+		EventName:     event.CarReleased,
+		CorrelationID: orderID,
+		Payload:       event.CarReleasedPayload{Cars: cars},
+	})
+}
+
+// buildCarReservations membuat reservasi tanpa status untuk setiap mobil. Jika mobil
+// tidak ditemukan, indeks mobil tersebut dikembalikan bersama error.
+func (s *service) buildCarReservations(ctx context.Context, orderID string, items []event.CarItem) ([]*CarReservation, int, error) {
+	carReservations := make([]*CarReservation, 0, len(items))
+	for i, item := range items {
+		car, err := s.repo.GetCarByID(ctx, item.CarID)
+		if err != nil {
+			return nil, i, err
+		}
+
+		carReservations = append(carReservations, &CarReservation{
+			ID:        ulid.Make().String(),
+			CarID:     car.ID,
+			CarName:   car.Name,
+			StartDate: item.StartDate,
+			EndDate:   item.EndDate,
+			Price:     item.Price,
+			OrderID:   orderID,
+		})
+	}
+
+	return carReservations, 0, nil
+}
+
+// publishHoldErrorEvent mengirim event gagal hold beserta indeks item penyebabnya
+func (s *service) publishHoldErrorEvent(ctx context.Context, msg event.Message, failedItem *int, err error) error {
+	if pubErr := s.publisher.Publish(ctx, string(event.CarHoldFailed), event.Message{
+		EventName:     event.CarHoldFailed,
+		CorrelationID: msg.CorrelationID,
+		Payload: event.CarHoldFailedPayload{
+			FailedItem:    failedItem,
+			FailureReason: err.Error(),
+		},
+	}); pubErr != nil {
+		return errors.Join(err, pubErr)
+	}
+
+	return err
+}
+
+func (s *service) handleHoldCar(ctx context.Context, msg event.Message) error {
+	payload, err := mapToPayload[event.HoldCarPayload](msg)
 	if err != nil {
-		return s.publishErrorEvent(ctx, msg, err)
+		return s.publishHoldErrorEvent(ctx, msg, nil, err)
 	}
 
-	carReservation.Status = CarReservationStatusCancelled
-	if err := s.repo.UpdateCarReservation(ctx, carReservation); err != nil {
-		return s.publishErrorEvent(ctx, msg, err)
+	if len(payload.Cars) == 0 {
+		return s.publishHoldErrorEvent(ctx, msg, nil, errors.New("no cars requested"))
 	}
 
-	return nil
+	carReservations, failedItem, err := s.buildCarReservations(ctx, msg.CorrelationID, payload.Cars)
+	if err != nil {
+		return s.publishHoldErrorEvent(ctx, msg, &failedItem, err)
+	}
+
+	ttl := s.holdTTL
+	if payload.TTLSeconds > 0 {
+		ttl = time.Duration(payload.TTLSeconds) * time.Second
+	}
+	expiresAt := time.Now().Add(ttl)
+	for _, carReservation := range carReservations {
+		carReservation.Status = CarReservationStatusHeld
+		carReservation.ExpiresAt = &expiresAt
+	}
+
+	if err := s.repo.CreateCarReservations(ctx, carReservations); err != nil {
+		var notAvailableErr *CarNotAvailableError
+		if errors.As(err, &notAvailableErr) {
+			return s.publishHoldErrorEvent(ctx, msg, &notAvailableErr.Index, err)
+		}
+		return s.publishHoldErrorEvent(ctx, msg, nil, err)
+	}
+
+	reservationIDs := make([]string, 0, len(carReservations))
+	for _, carReservation := range carReservations {
+		reservationIDs = append(reservationIDs, carReservation.ID)
+	}
+
+	return s.publisher.Publish(ctx, string(event.CarHeld), event.Message{
+		EventName:     event.CarHeld,
+		CorrelationID: msg.CorrelationID,
+		Payload: event.CarHeldPayload{
+			CarReservationIDs: reservationIDs,
+			ExpiresAt:         expiresAt,
+		},
+	})
+}
+
+// publishConfirmationErrorEvent mengirim event gagal konfirmasi, hold yang belum kedaluwarsa tetap ditahan
+func (s *service) publishConfirmationErrorEvent(ctx context.Context, msg event.Message, err error) error {
+	if pubErr := s.publisher.Publish(ctx, string(event.CarHoldConfirmationFailed), event.Message{
+		EventName:     event.CarHoldConfirmationFailed,
+		CorrelationID: msg.CorrelationID,
+		Payload:       event.CarHoldConfirmationFailedPayload{FailureReason: err.Error()},
+	}); pubErr != nil {
+		return errors.Join(err, pubErr)
+	}
+
+	return err
+}
+
+func (s *service) handleConfirmCar(ctx context.Context, msg event.Message) error {
+	payload, err := mapToPayload[event.ConfirmCarPayload](msg)
+	if err != nil {
+		return s.publishConfirmationErrorEvent(ctx, msg, err)
+	}
+
+	carReservations, err := s.repo.ConfirmCarHolds(ctx, payload.OrderID, time.Now())
+	if err != nil {
+		return s.publishConfirmationErrorEvent(ctx, msg, err)
+	}
+
+	reservationIDs := make([]string, 0, len(carReservations))
+	for _, carReservation := range carReservations {
+		reservationIDs = append(reservationIDs, carReservation.ID)
+	}
+
+	return s.publisher.Publish(ctx, string(event.CarHoldConfirmed), event.Message{
+		EventName:     event.CarHoldConfirmed,
+		CorrelationID: msg.CorrelationID,
+		Payload:       event.CarHoldConfirmedPayload{CarReservationIDs: reservationIDs},
+	})
+}
+
+func (s *service) ReleaseExpiredHolds(ctx context.Context) error {
+	now := time.Now()
+	carReservations, err := s.repo.GetExpiredCarHolds(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	// Hold dikelompokkan per order agar setiap order menerima satu event
+	var orderIDs []string
+	expiredHolds := make(map[string][]*CarReservation)
+	var errs []error
+	for _, carReservation := range carReservations {
+		expired, err := s.repo.ExpireCarHold(ctx, carReservation.ID, now)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !expired {
+			continue
+		}
+
+		if _, ok := expiredHolds[carReservation.OrderID]; !ok {
+			orderIDs = append(orderIDs, carReservation.OrderID)
+		}
+		expiredHolds[carReservation.OrderID] = append(expiredHolds[carReservation.OrderID], carReservation)
+	}
+
+	for _, orderID := range orderIDs {
+		expiredIDs := make([]string, 0, len(expiredHolds[orderID]))
+		for _, carReservation := range expiredHolds[orderID] {
+			expiredIDs = append(expiredIDs, carReservation.ID)
+		}
+
+		if err := s.publisher.Publish(ctx, string(event.CarHoldExpired), event.Message{
+			EventName:     event.CarHoldExpired,
+			CorrelationID: orderID,
+			Payload:       event.CarHoldExpiredPayload{CarReservationIDs: expiredIDs},
+		}); err != nil {
+			errs = append(errs, err)
+		}
+		if err := s.publishReleasedEvent(ctx, orderID, expiredHolds[orderID]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(orderIDs) > 0 {
+		log.Printf("Released expired car holds of %d orders", len(orderIDs))
+	}
+
+	return errors.Join(errs...)
+}
+
+// SearchCars membaca mobil per halaman dan mengecek ketersediaannya terhadap
+// reservasi aktif sampai satu halaman hasil terisi
+func (s *service) SearchCars(ctx context.Context, query SearchCarsQuery) (*CarPage, error) {
+	startDate, err := time.Parse(config.DateFormat, query.StartDate)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDateRange, err)
+	}
+	endDate, err := time.Parse(config.DateFormat, query.EndDate)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDateRange, err)
+	}
+	if endDate.Before(startDate) {
+		return nil, fmt.Errorf("%w: end date is before start date", ErrInvalidDateRange)
+	}
+
+	pageSize := query.PageSize
+	if pageSize == 0 {
+		pageSize = defaultPageSize
+	}
+
+	page := &CarPage{Items: make([]*Car, 0, pageSize)}
+	startAfter := query.PageToken
+	for {
+		cars, err := s.repo.ListCars(ctx, query.Brand, query.Model, startAfter, pageSize)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, car := range cars {
+			available, err := s.repo.IsCarAvailable(ctx, car.ID, startDate.Format(config.DateFormat), endDate.Format(config.DateFormat))
+			if err != nil {
+				return nil, err
+			}
+			if !available {
+				continue
+			}
+
+			page.Items = append(page.Items, car)
+			if len(page.Items) == pageSize {
+				page.NextPageToken = car.ID
+				return page, nil
+			}
+		}
+
+		if len(cars) < pageSize {
+			return page, nil
+		}
+		startAfter = cars[len(cars)-1].ID
+	}
+}
+
+// GetCarCalendar menandai setiap tanggal yang beririsan dengan reservasi aktif.
+// Hold yang belum dilepas sweeper tetap memblokir tanggalnya.
+func (s *service) GetCarCalendar(ctx context.Context, carID string, query CalendarQuery) (*CarCalendar, error) {
+	from, err := time.Parse(config.DateFormat, query.From)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDateRange, err)
+	}
+	to, err := time.Parse(config.DateFormat, query.To)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDateRange, err)
+	}
+	if to.Before(from) || to.After(from.AddDate(0, 0, maxCalendarDays-1)) {
+		return nil, fmt.Errorf("%w: must span 1 to %d days", ErrInvalidDateRange, maxCalendarDays)
+	}
+
+	if _, err := s.repo.GetCarByID(ctx, carID); err != nil {
+		return nil, err
+	}
+
+	carReservations, err := s.repo.GetOverlappingCarReservations(ctx, carID, from.Format(config.DateFormat), to.Format(config.DateFormat))
+	if err != nil {
+		return nil, err
+	}
+
+	calendar := &CarCalendar{CarID: carID}
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		day := CalendarDay{Date: date.Format(config.DateFormat), Available: true}
+		for _, carReservation := range carReservations {
+			if day.Date >= carReservation.StartDate && day.Date <= carReservation.EndDate {
+				day.Available = false
+				day.Status = carReservation.Status
+				break
+			}
+		}
+		calendar.Days = append(calendar.Days, day)
+	}
+
+	return calendar, nil
 }
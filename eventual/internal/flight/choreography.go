@@ -0,0 +1,46 @@
+package flight
+
+import (
+	"context"
+	"log"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/event"
+)
+
+// ProcessChoreographyEvent menjalankan bagian flight service pada saga booking koreografi.
+// Kursi pesawat direservasi setelah kursi kereta berhasil direservasi dan dibatalkan
+// saat pembayaran gagal.
+func (s *service) ProcessChoreographyEvent(ctx context.Context, msg event.Message) error {
+	switch msg.EventName {
+	case event.SeatReserved:
+		payload, err := mapToPayload[event.SeatReservedPayload](msg)
+		if err != nil {
+			return s.publishErrorEvent(ctx, msg, err)
+		}
+		// Event tanpa order dibuang seperti di car service
+		if payload.Order == nil {
+			log.Printf("Dropping %s of order %s without order payload", msg.EventName, msg.CorrelationID)
+			return nil
+		}
+		if len(payload.Order.Flights) == 0 {
+			return s.publisher.Publish(ctx, string(event.FlightReserved), event.Message{
+				EventName:     event.FlightReserved,
+				CorrelationID: msg.CorrelationID,
+				Payload:       event.FlightReservedPayload{Order: payload.Order},
+			})
+		}
+		return s.handleReserveFlight(ctx, event.Message{
+			EventName:     event.CommandReserveFlight,
+			CorrelationID: msg.CorrelationID,
+			Payload:       event.ReserveFlightPayload{Flights: payload.Order.Flights, Order: payload.Order},
+		})
+	case event.PaymentFailed, event.PaymentCaptureFailed:
+		return s.handleCancelFlight(ctx, event.Message{
+			EventName:     event.CommandCancelFlight,
+			CorrelationID: msg.CorrelationID,
+			Payload:       event.CancelFlightPayload{OrderID: msg.CorrelationID},
+		})
+	}
+
+	return nil
+}
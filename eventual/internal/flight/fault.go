@@ -0,0 +1,34 @@
+package flight
+
+import (
+	"context"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/fault"
+)
+
+// faultyRepository menyuntikkan kegagalan sebelum dan sesudah setiap penulisan
+// reservasi. Pembacaan diteruskan tanpa perubahan.
+type faultyRepository struct {
+	Repository
+	injector *fault.Injector
+}
+
+func NewFaultyRepository(repo Repository, injector *fault.Injector) Repository {
+	return &faultyRepository{Repository: repo, injector: injector}
+}
+
+func (r *faultyRepository) CreateFlightReservations(ctx context.Context, flightReservations []*FlightReservation) error {
+	orderID := ""
+	if len(flightReservations) > 0 {
+		orderID = flightReservations[0].OrderID
+	}
+	return r.injector.Do("CreateFlightReservations", orderID, func() error {
+		return r.Repository.CreateFlightReservations(ctx, flightReservations)
+	})
+}
+
+func (r *faultyRepository) UpdateFlightReservation(ctx context.Context, flightReservation *FlightReservation) error {
+	return r.injector.Do("UpdateFlightReservation", flightReservation.OrderID, func() error {
+		return r.Repository.UpdateFlightReservation(ctx, flightReservation)
+	})
+}
@@ -0,0 +1,87 @@
+package flight
+
+import "fmt"
+
+type FlightReservationStatus string
+
+const (
+	FlightReservationStatusCancelled FlightReservationStatus = "CANCELLED"
+	FlightReservationStatusReserved  FlightReservationStatus = "RESERVED"
+)
+
+type FareClass string
+
+const (
+	FareClassFirst    FareClass = "FIRST"
+	FareClassBusiness FareClass = "BUSINESS"
+	FareClassEconomy  FareClass = "ECONOMY"
+)
+
+// FareClassRows adalah kelas tarif yang menempati baris FirstRow sampai LastRow
+type FareClassRows struct {
+	Class    FareClass `firestore:"class" json:"class"`
+	FirstRow int       `firestore:"first_row" json:"first_row"`
+	LastRow  int       `firestore:"last_row" json:"last_row"`
+}
+
+// Flight adalah satu penerbangan pada tanggal keberangkatan tertentu. Kursi tersusun
+// atas Rows baris dengan SeatsPerRow kursi, dan kelas tarifnya ditentukan oleh baris.
+type Flight struct {
+	ID            string          `firestore:"id" json:"id"`
+	FlightNumber  string          `firestore:"flight_number" json:"flight_number"`
+	Airline       string          `firestore:"airline" json:"airline"`
+	Origin        string          `firestore:"origin" json:"origin"`
+	Destination   string          `firestore:"destination" json:"destination"`
+	DepartureDate string          `firestore:"departure_date" json:"departure_date"`
+	DepartureTime string          `firestore:"departure_time" json:"departure_time"`
+	Rows          int             `firestore:"rows" json:"rows"`
+	SeatsPerRow   int             `firestore:"seats_per_row" json:"seats_per_row"`
+	FareClasses   []FareClassRows `firestore:"fare_classes" json:"fare_classes"`
+}
+
+type FlightReservation struct {
+	ID            string                  `firestore:"id" json:"id"`
+	FlightID      string                  `firestore:"flight_id" json:"flight_id"`
+	FlightNumber  string                  `firestore:"flight_number" json:"flight_number"`
+	DepartureDate string                  `firestore:"departure_date" json:"departure_date"`
+	SeatID        string                  `firestore:"seat_id" json:"seat_id"`
+	FareClass     FareClass               `firestore:"fare_class" json:"fare_class"`
+	Price         int64                   `firestore:"price" json:"price"`
+	OrderID       string                  `firestore:"order_id" json:"order_id"`
+	Status        FlightReservationStatus `firestore:"status" json:"status"`
+}
+
+// SeatID membentuk ID kursi pesawat dengan format "{baris}{huruf}", mis. "12A"
+func SeatID(row, number int) string {
+	return fmt.Sprintf("%d%c", row, 'A'+number-1)
+}
+
+// parseSeatID mengembalikan baris dan nomor kursi dari ID kursi
+func parseSeatID(seatID string) (row, number int, ok bool) {
+	var letter rune
+	if _, err := fmt.Sscanf(seatID, "%d%c", &row, &letter); err != nil {
+		return 0, 0, false
+	}
+	number = int(letter-'A') + 1
+	if SeatID(row, number) != seatID {
+		return 0, 0, false
+	}
+	return row, number, true
+}
+
+// FareClassOf mengembalikan kelas tarif kursi. ok bernilai false jika kursi tidak ada
+// pada susunan kursi penerbangan ini.
+func (f *Flight) FareClassOf(seatID string) (FareClass, bool) {
+	row, number, ok := parseSeatID(seatID)
+	if !ok || row < 1 || row > f.Rows || number < 1 || number > f.SeatsPerRow {
+		return "", false
+	}
+
+	for _, fareClass := range f.FareClasses {
+		if row >= fareClass.FirstRow && row <= fareClass.LastRow {
+			return fareClass.Class, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,127 @@
+package flight
+
+import (
+	"context"
+	"errors"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	ErrFlightNotFound = errors.New("flight not found")
+)
+
+// FlightSeatNotAvailableError menandakan kursi pada item ke-Index sudah direservasi
+type FlightSeatNotAvailableError struct {
+	Index int
+}
+
+func (e *FlightSeatNotAvailableError) Error() string {
+	return "flight seat is not available"
+}
+
+type Repository interface {
+	GetFlightByID(ctx context.Context, id string) (*Flight, error)
+	CreateFlightReservations(ctx context.Context, flightReservations []*FlightReservation) error
+	GetFlightReservationsByOrderID(ctx context.Context, orderID string) ([]*FlightReservation, error)
+	UpdateFlightReservation(ctx context.Context, flightReservation *FlightReservation) error
+}
+
+const (
+	flightCollection            = "flights"
+	flightReservationCollection = "flight_reservations"
+)
+
+type firestoreRepository struct {
+	client *firestore.Client
+}
+
+func NewFirestoreRepository(client *firestore.Client) Repository {
+	return &firestoreRepository{client: client}
+}
+
+func (r *firestoreRepository) GetFlightByID(ctx context.Context, id string) (*Flight, error) {
+	doc, err := r.client.Collection(flightCollection).Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, ErrFlightNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var flight Flight
+	if err := doc.DataTo(&flight); err != nil {
+		return nil, err
+	}
+
+	return &flight, nil
+}
+
+// CreateFlightReservations membuat seluruh reservasi dalam satu transaksi.
+// Jika salah satu kursi tidak tersedia, tidak ada reservasi yang dibuat.
+func (r *firestoreRepository) CreateFlightReservations(ctx context.Context, flightReservations []*FlightReservation) error {
+	// Item dalam order yang sama juga tidak boleh memesan kursi yang sama
+	for i, a := range flightReservations {
+		for _, b := range flightReservations[:i] {
+			if a.FlightID == b.FlightID && a.SeatID == b.SeatID {
+				return &FlightSeatNotAvailableError{Index: i}
+			}
+		}
+	}
+
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		for i, flightReservation := range flightReservations {
+			query := r.activeReservations(flightReservation.FlightID, flightReservation.SeatID)
+			docs, err := tx.Documents(query.Limit(1)).GetAll()
+			if err != nil {
+				return err
+			}
+			if len(docs) > 0 {
+				return &FlightSeatNotAvailableError{Index: i}
+			}
+		}
+
+		for _, flightReservation := range flightReservations {
+			if err := tx.Create(r.client.Collection(flightReservationCollection).Doc(flightReservation.ID), flightReservation); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r *firestoreRepository) GetFlightReservationsByOrderID(ctx context.Context, orderID string) ([]*FlightReservation, error) {
+	query := r.client.Collection(flightReservationCollection).Where("order_id", "==", orderID)
+	docs, err := query.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	flightReservations := make([]*FlightReservation, 0, len(docs))
+	for _, doc := range docs {
+		var flightReservation FlightReservation
+		if err := doc.DataTo(&flightReservation); err != nil {
+			return nil, err
+		}
+		flightReservations = append(flightReservations, &flightReservation)
+	}
+
+	return flightReservations, nil
+}
+
+func (r *firestoreRepository) UpdateFlightReservation(ctx context.Context, flightReservation *FlightReservation) error {
+	_, err := r.client.Collection(flightReservationCollection).Doc(flightReservation.ID).Set(ctx, flightReservation)
+	return err
+}
+
+// activeReservations adalah query reservasi aktif pada kursi yang sama. Setiap
+// penerbangan hanya berangkat pada satu tanggal, sehingga kursi cukup dicek per penerbangan.
+func (r *firestoreRepository) activeReservations(flightID, seatID string) firestore.Query {
+	return r.client.Collection(flightReservationCollection).
+		Where("flight_id", "==", flightID).
+		Where("seat_id", "==", seatID).
+		Where("status", "==", FlightReservationStatusReserved)
+}
@@ -0,0 +1,182 @@
+package flight
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/event"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/messagebus"
+)
+
+type Service interface {
+	ProcessSagaEvent(ctx context.Context, msg event.Message) error
+
+	// ProcessChoreographyEvent memproses event partisipan lain pada saga booking
+	// koreografi (SAGA_MODE=choreography)
+	ProcessChoreographyEvent(ctx context.Context, msg event.Message) error
+}
+
+type service struct {
+	repo      Repository
+	publisher messagebus.Publisher
+}
+
+func NewService(repo Repository, publisher messagebus.Publisher) Service {
+	return &service{repo: repo, publisher: publisher}
+}
+
+func (s *service) ProcessSagaEvent(ctx context.Context, msg event.Message) error {
+	log.Println("Received saga event", msg.EventName)
+	switch msg.EventName {
+	case event.CommandReserveFlight:
+		return s.handleReserveFlight(ctx, msg)
+	case event.CommandCancelFlight:
+		return s.handleCancelFlight(ctx, msg)
+	}
+
+	return nil
+}
+
+func (s *service) publishErrorEvent(ctx context.Context, msg event.Message, err error) error {
+	return s.publishItemErrorEvent(ctx, msg, nil, err)
+}
+
+// publishItemErrorEvent mengirim event gagal beserta indeks item penyebabnya
+func (s *service) publishItemErrorEvent(ctx context.Context, msg event.Message, failedItem *int, err error) error {
+	if pubErr := s.publisher.Publish(ctx, string(event.FlightReservationFailed), event.Message{
+		EventName:     event.FlightReservationFailed,
+		CorrelationID: msg.CorrelationID,
+		Payload: event.FlightReservationFailedPayload{
+			FailedItem:    failedItem,
+			FailureReason: err.Error(),
+		},
+	}); pubErr != nil {
+		return errors.Join(err, pubErr)
+	}
+
+	return err
+}
+
+func (s *service) handleReserveFlight(ctx context.Context, msg event.Message) error {
+	payload, err := mapToPayload[event.ReserveFlightPayload](msg)
+	if err != nil {
+		return s.publishErrorEvent(ctx, msg, err)
+	}
+
+	if len(payload.Flights) == 0 {
+		return s.publishErrorEvent(ctx, msg, errors.New("no flight seats requested"))
+	}
+
+	flightReservations, failedItem, err := s.buildFlightReservations(ctx, msg.CorrelationID, payload.Flights)
+	if err != nil {
+		return s.publishItemErrorEvent(ctx, msg, &failedItem, err)
+	}
+
+	if err := s.repo.CreateFlightReservations(ctx, flightReservations); err != nil {
+		var notAvailableErr *FlightSeatNotAvailableError
+		if errors.As(err, &notAvailableErr) {
+			return s.publishItemErrorEvent(ctx, msg, &notAvailableErr.Index, err)
+		}
+		return s.publishErrorEvent(ctx, msg, err)
+	}
+
+	reservationIDs := make([]string, 0, len(flightReservations))
+	for _, flightReservation := range flightReservations {
+		reservationIDs = append(reservationIDs, flightReservation.ID)
+	}
+
+	return s.publisher.Publish(ctx, string(event.FlightReserved), event.Message{
+		EventName:     event.FlightReserved,
+		CorrelationID: msg.CorrelationID,
+		Payload: event.FlightReservedPayload{
+			FlightReservationIDs: reservationIDs,
+			Order:                payload.Order,
+		},
+	})
+}
+
+// buildFlightReservations membuat reservasi RESERVED untuk setiap kursi. Jika kursi
+// tidak valid, indeks kursi tersebut dikembalikan bersama error.
+func (s *service) buildFlightReservations(ctx context.Context, orderID string, items []event.FlightItem) ([]*FlightReservation, int, error) {
+	flights := make(map[string]*Flight)
+	flightReservations := make([]*FlightReservation, 0, len(items))
+	for i, item := range items {
+		flight, ok := flights[item.FlightID]
+		if !ok {
+			var err error
+			flight, err = s.repo.GetFlightByID(ctx, item.FlightID)
+			if err != nil {
+				return nil, i, err
+			}
+			flights[item.FlightID] = flight
+		}
+
+		if flight.DepartureDate != item.DepartureDate {
+			return nil, i, errors.New("flight does not depart on the requested date")
+		}
+		fareClass, ok := flight.FareClassOf(item.SeatID)
+		if !ok {
+			return nil, i, errors.New("flight seat does not exist on this flight")
+		}
+
+		flightReservations = append(flightReservations, &FlightReservation{
+			ID:            ulid.Make().String(),
+			FlightID:      flight.ID,
+			FlightNumber:  flight.FlightNumber,
+			DepartureDate: flight.DepartureDate,
+			SeatID:        item.SeatID,
+			FareClass:     fareClass,
+			Price:         item.Price,
+			OrderID:       orderID,
+			Status:        FlightReservationStatusReserved,
+		})
+	}
+
+	return flightReservations, 0, nil
+}
+
+func (s *service) handleCancelFlight(ctx context.Context, msg event.Message) error {
+	payload, err := mapToPayload[event.CancelFlightPayload](msg)
+	if err != nil {
+		return s.publishErrorEvent(ctx, msg, err)
+	}
+
+	flightReservations, err := s.repo.GetFlightReservationsByOrderID(ctx, payload.OrderID)
+	if err != nil {
+		return s.publishErrorEvent(ctx, msg, err)
+	}
+
+	reservationIDs := make([]string, 0, len(flightReservations))
+	for _, flightReservation := range flightReservations {
+		reservationIDs = append(reservationIDs, flightReservation.ID)
+		if flightReservation.Status == FlightReservationStatusCancelled {
+			continue
+		}
+
+		flightReservation.Status = FlightReservationStatusCancelled
+		if err := s.repo.UpdateFlightReservation(ctx, flightReservation); err != nil {
+			return s.publishErrorEvent(ctx, msg, err)
+		}
+	}
+
+	return s.publisher.Publish(ctx, string(event.FlightReservationCancelled), event.Message{
+		EventName:     event.FlightReservationCancelled,
+		CorrelationID: msg.CorrelationID,
+		Payload:       event.FlightReservationCancelledPayload{FlightReservationIDs: reservationIDs},
+	})
+}
+
+func mapToPayload[T any](msg event.Message) (T, error) {
+	var payload T
+	marshalledPayload, err := json.Marshal(msg.Payload)
+	if err != nil {
+		return payload, err
+	}
+	if err := json.Unmarshal(marshalledPayload, &payload); err != nil {
+		return payload, err
+	}
+	return payload, nil
+}
@@ -0,0 +1,41 @@
+package hotel
+
+import (
+	"context"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/event"
+)
+
+// ProcessChoreographyEvent menjalankan bagian hotel service pada saga booking koreografi.
+// Hotel adalah partisipan pertama: kamar direservasi saat OrderCreated diterima dan
+// dibatalkan saat partisipan setelahnya gagal.
+func (s *service) ProcessChoreographyEvent(ctx context.Context, msg event.Message) error {
+	switch msg.EventName {
+	case event.OrderCreated:
+		order, err := mapToPayload[event.OrderCreatedPayload](msg)
+		if err != nil {
+			return s.publishErrorEvent(ctx, msg, err)
+		}
+		// Order tanpa kamar tetap diteruskan agar car service dapat melanjutkan saga
+		if len(order.Rooms) == 0 {
+			return s.publisher.Publish(ctx, string(event.RoomReserved), event.Message{
+				EventName:     event.RoomReserved,
+				CorrelationID: msg.CorrelationID,
+				Payload:       event.RoomReservedPayload{Order: &order},
+			})
+		}
+		return s.handleReserveRoom(ctx, event.Message{
+			EventName:     event.CommandReserveRoom,
+			CorrelationID: msg.CorrelationID,
+			Payload:       event.ReserveRoomPayload{Rooms: order.Rooms, Order: &order},
+		})
+	case event.CarReservationFailed, event.SeatReservationFailed, event.FlightReservationFailed, event.PaymentFailed, event.PaymentCaptureFailed:
+		return s.handleCancelRoom(ctx, event.Message{
+			EventName:     event.CommandCancelRoom,
+			CorrelationID: msg.CorrelationID,
+			Payload:       event.CancelRoomPayload{OrderID: msg.CorrelationID},
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package hotel
+
+import (
+	"context"
+	"time"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/fault"
+)
+
+// faultyRepository menyuntikkan kegagalan sebelum dan sesudah setiap penulisan
+// reservasi. Pembacaan diteruskan tanpa perubahan.
+type faultyRepository struct {
+	Repository
+	injector *fault.Injector
+}
+
+func NewFaultyRepository(repo Repository, injector *fault.Injector) Repository {
+	return &faultyRepository{Repository: repo, injector: injector}
+}
+
+func (r *faultyRepository) CreateHotelReservations(ctx context.Context, hotelReservations []*HotelReservation) error {
+	orderID := ""
+	if len(hotelReservations) > 0 {
+		orderID = hotelReservations[0].OrderID
+	}
+	return r.injector.Do("CreateHotelReservations", orderID, func() error {
+		return r.Repository.CreateHotelReservations(ctx, hotelReservations)
+	})
+}
+
+func (r *faultyRepository) ReplaceHotelReservation(ctx context.Context, replacedID string, hotelReservation *HotelReservation) error {
+	return r.injector.Do("ReplaceHotelReservation", hotelReservation.OrderID, func() error {
+		return r.Repository.ReplaceHotelReservation(ctx, replacedID, hotelReservation)
+	})
+}
+
+func (r *faultyRepository) ConfirmHotelHolds(ctx context.Context, orderID string, now time.Time) ([]*HotelReservation, error) {
+	return fault.Call(r.injector, "ConfirmHotelHolds", orderID, func() ([]*HotelReservation, error) {
+		return r.Repository.ConfirmHotelHolds(ctx, orderID, now)
+	})
+}
+
+// CancelHotelReservation hanya menerima ID reservasi sehingga kegagalannya tidak
+// dikaitkan ke order
+func (r *faultyRepository) CancelHotelReservation(ctx context.Context, id string) (bool, error) {
+	return fault.Call(r.injector, "CancelHotelReservation", "", func() (bool, error) {
+		return r.Repository.CancelHotelReservation(ctx, id)
+	})
+}
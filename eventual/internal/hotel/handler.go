@@ -0,0 +1,78 @@
+package hotel
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) SearchHotelRooms(ctx *gin.Context) {
+	var query SearchHotelRoomsQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := h.service.SearchHotelRooms(ctx, query)
+	if errors.Is(err, ErrInvalidDateRange) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, page)
+}
+
+func (h *Handler) GetHotelRoomCalendar(ctx *gin.Context) {
+	var query CalendarQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	calendar, err := h.service.GetHotelRoomCalendar(ctx, ctx.Param("id"), query)
+	if errors.Is(err, ErrInvalidDateRange) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, ErrHotelRoomNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, calendar)
+}
+
+func (h *Handler) AssignHotelRoom(ctx *gin.Context) {
+	hotelReservation, err := h.service.AssignHotelRoom(ctx, ctx.Param("id"))
+	if errors.Is(err, ErrHotelReservationNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, ErrHotelReservationNotActive) || errors.Is(err, ErrNoRoomToAssign) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, hotelReservation)
+}
@@ -1,25 +1,103 @@
 package hotel
 
+import "time"
+
 type HotelRoomReservationStatus string
 
 const (
 	HotelRoomReservationStatusCancelled HotelRoomReservationStatus = "CANCELLED"
 	HotelRoomReservationStatusReserved  HotelRoomReservationStatus = "RESERVED"
+	// HELD adalah reservasi sementara yang dilepas sweeper menjadi EXPIRED jika
+	// tidak dikonfirmasi sebelum ExpiresAt
+	HotelRoomReservationStatusHeld    HotelRoomReservationStatus = "HELD"
+	HotelRoomReservationStatusExpired HotelRoomReservationStatus = "EXPIRED"
 )
 
 type HotelRoom struct {
 	ID        string `firestore:"id" json:"id"`
 	HotelName string `firestore:"hotel_name" json:"hotel_name"`
 	RoomName  string `firestore:"room_name" json:"room_name"`
+	City      string `firestore:"city" json:"city"`
+	// RoomTypeID kosong untuk kamar yang tidak dijual sebagai bagian dari tipe kamar
+	RoomTypeID string `firestore:"room_type_id,omitempty" json:"room_type_id,omitempty"`
+}
+
+// RoomType adalah kategori kamar yang dijual, misalnya "Deluxe King" di satu hotel.
+// TotalUnits adalah jumlah kamar fisik bertipe ini.
+type RoomType struct {
+	ID         string `firestore:"id" json:"id"`
+	HotelName  string `firestore:"hotel_name" json:"hotel_name"`
+	Name       string `firestore:"name" json:"name"`
+	City       string `firestore:"city" json:"city"`
+	TotalUnits int    `firestore:"total_units" json:"total_units"`
+}
+
+// RoomTypeInventory adalah jumlah unit tipe kamar yang masih dapat dijual pada satu
+// tanggal. Dokumen dibuat dari RoomType.TotalUnits saat tanggal tersebut pertama kali dipesan.
+type RoomTypeInventory struct {
+	RoomTypeID     string `firestore:"room_type_id" json:"room_type_id"`
+	Date           string `firestore:"date" json:"date"`
+	TotalUnits     int    `firestore:"total_units" json:"total_units"`
+	AvailableUnits int    `firestore:"available_units" json:"available_units"`
 }
 
+// HotelReservation memesan satu kamar fisik atau satu unit tipe kamar. Reservasi tipe
+// kamar belum memiliki HotelRoomID sampai nomor kamar ditetapkan lewat AssignHotelRoom.
 type HotelReservation struct {
 	ID                 string                     `firestore:"id" json:"id"`
 	HotelRoomID        string                     `firestore:"hotel_room_id" json:"hotel_room_id"`
 	HotelRoomName      string                     `firestore:"hotel_room_name" json:"hotel_room_name"`
+	RoomTypeID         string                     `firestore:"room_type_id,omitempty" json:"room_type_id,omitempty"`
+	RoomTypeName       string                     `firestore:"room_type_name,omitempty" json:"room_type_name,omitempty"`
 	HotelName          string                     `firestore:"hotel_name" json:"hotel_name"`
 	HotelRoomStartDate string                     `firestore:"hotel_room_start_date" json:"hotel_room_start_date"`
 	HotelRoomEndDate   string                     `firestore:"hotel_room_end_date" json:"hotel_room_end_date"`
+	Price              int64                      `firestore:"price" json:"price"`
 	OrderID            string                     `firestore:"order_id" json:"order_id"`
 	Status             HotelRoomReservationStatus `firestore:"status" json:"status"`
+	// ExpiresAt hanya diisi selama reservasi berstatus HELD
+	ExpiresAt *time.Time `firestore:"expires_at,omitempty" json:"expires_at,omitempty"`
+}
+
+// defaultPageSize dipakai jika pencarian tidak menentukan page_size
+const defaultPageSize = 20
+
+// SearchHotelRoomsQuery adalah parameter pencarian kamar. City dan HotelName bersifat opsional.
+type SearchHotelRoomsQuery struct {
+	City      string `form:"city"`
+	HotelName string `form:"hotel_name"`
+	StartDate string `form:"start_date" binding:"required"`
+	EndDate   string `form:"end_date" binding:"required"`
+	PageSize  int    `form:"page_size" binding:"omitempty,min=1,max=100"`
+	// PageToken adalah NextPageToken dari halaman sebelumnya
+	PageToken string `form:"page_token"`
+}
+
+// HotelRoomPage adalah satu halaman hasil pencarian kamar.
+// NextPageToken kosong pada halaman terakhir.
+type HotelRoomPage struct {
+	Items         []*HotelRoom `json:"items"`
+	NextPageToken string       `json:"next_page_token,omitempty"`
+}
+
+// maxCalendarDays membatasi rentang kalender sekitar dua bulan
+const maxCalendarDays = 62
+
+// CalendarQuery adalah rentang tanggal kalender, keduanya inklusif
+type CalendarQuery struct {
+	From string `form:"from" binding:"required"`
+	To   string `form:"to" binding:"required"`
+}
+
+// CalendarDay adalah ketersediaan kamar pada satu tanggal. Status berisi status
+// reservasi yang memblokir tanggal tersebut.
+type CalendarDay struct {
+	Date      string                     `json:"date"`
+	Available bool                       `json:"available"`
+	Status    HotelRoomReservationStatus `json:"status,omitempty"`
+}
+
+type HotelRoomCalendar struct {
+	HotelRoomID string        `json:"hotel_room_id"`
+	Days        []CalendarDay `json:"days"`
 }
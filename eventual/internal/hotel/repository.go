@@ -3,30 +3,59 @@ package hotel
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
 
 	"cloud.google.com/go/firestore"
 	"cloud.google.com/go/firestore/apiv1/firestorepb"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/config"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
 var (
-	ErrHotelRoomNotFound        = errors.New("hotel room not found")
-	ErrHotelReservationNotFound = errors.New("hotel reservation not found")
+	ErrHotelRoomNotFound         = errors.New("hotel room not found")
+	ErrRoomTypeNotFound          = errors.New("room type not found")
+	ErrNoRoomToAssign            = errors.New("no room of this type is free for the whole stay")
+	ErrHotelReservationNotFound  = errors.New("hotel reservation not found")
+	ErrHotelReservationNotActive = errors.New("hotel reservation is not active")
+	ErrHotelHoldNotFound         = errors.New("hotel hold not found")
+	ErrHotelHoldExpired          = errors.New("hotel hold has expired")
+	ErrInvalidDateRange          = errors.New("invalid date range")
 )
 
+// HotelRoomNotAvailableError menandakan kamar pada item ke-Index sudah direservasi
+// atau unit tipe kamarnya sudah habis
+type HotelRoomNotAvailableError struct {
+	Index int
+}
+
+func (e *HotelRoomNotAvailableError) Error() string {
+	return "hotel room is not available"
+}
+
 type Repository interface {
 	GetHotelRoomByID(ctx context.Context, id string) (*HotelRoom, error)
-	CreateHotelReservation(ctx context.Context, hotelReservation *HotelReservation) error
+	GetRoomTypeByID(ctx context.Context, id string) (*RoomType, error)
+	ListHotelRooms(ctx context.Context, city, hotelName, startAfter string, limit int) ([]*HotelRoom, error)
+	CreateHotelReservations(ctx context.Context, hotelReservations []*HotelReservation) error
+	ReplaceHotelReservation(ctx context.Context, replacedID string, hotelReservation *HotelReservation) error
 	GetHotelReservationByID(ctx context.Context, id string) (*HotelReservation, error)
-	GetHotelReservationByOrderID(ctx context.Context, orderID string) (*HotelReservation, error)
-	UpdateHotelReservation(ctx context.Context, hotelReservation *HotelReservation) error
+	GetHotelReservationsByOrderID(ctx context.Context, orderID string) ([]*HotelReservation, error)
+	CancelHotelReservation(ctx context.Context, id string) (bool, error)
+	AssignHotelRoom(ctx context.Context, id string) (*HotelReservation, error)
 	IsHotelRoomAvailable(ctx context.Context, hotelRoomID string, startDate, endDate string) (bool, error)
+	GetOverlappingHotelReservations(ctx context.Context, hotelRoomID string, startDate, endDate string) ([]*HotelReservation, error)
+	ConfirmHotelHolds(ctx context.Context, orderID string, now time.Time) ([]*HotelReservation, error)
+	GetExpiredHotelHolds(ctx context.Context, now time.Time) ([]*HotelReservation, error)
+	ExpireHotelHold(ctx context.Context, id string, now time.Time) (bool, error)
 }
 
 const (
-	hotelRoomCollection        = "hotel_rooms"
-	hotelReservationCollection = "hotel_reservations"
+	hotelRoomCollection         = "hotel_rooms"
+	hotelReservationCollection  = "hotel_reservations"
+	roomTypeCollection          = "hotel_room_types"
+	roomTypeInventoryCollection = "hotel_room_type_inventories"
 )
 
 type firestoreRepository struct {
@@ -54,9 +83,146 @@ func (r *firestoreRepository) GetHotelRoomByID(ctx context.Context, id string) (
 	return &hotelRoom, nil
 }
 
-func (r *firestoreRepository) CreateHotelReservation(ctx context.Context, hotelReservation *HotelReservation) error {
-	_, err := r.client.Collection(hotelReservationCollection).Doc(hotelReservation.ID).Set(ctx, hotelReservation)
-	return err
+func (r *firestoreRepository) GetRoomTypeByID(ctx context.Context, id string) (*RoomType, error) {
+	doc, err := r.client.Collection(roomTypeCollection).Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, ErrRoomTypeNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var roomType RoomType
+	if err := doc.DataTo(&roomType); err != nil {
+		return nil, err
+	}
+
+	return &roomType, nil
+}
+
+// ListHotelRooms mengembalikan paling banyak limit kamar yang diurutkan berdasarkan ID,
+// dimulai setelah kamar startAfter. Filter city dan hotelName diabaikan jika kosong.
+func (r *firestoreRepository) ListHotelRooms(ctx context.Context, city, hotelName, startAfter string, limit int) ([]*HotelRoom, error) {
+	query := r.client.Collection(hotelRoomCollection).Query
+	if city != "" {
+		query = query.Where("city", "==", city)
+	}
+	if hotelName != "" {
+		query = query.Where("hotel_name", "==", hotelName)
+	}
+	query = query.OrderBy("id", firestore.Asc)
+	if startAfter != "" {
+		query = query.StartAfter(startAfter)
+	}
+
+	docs, err := query.Limit(limit).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	hotelRooms := make([]*HotelRoom, 0, len(docs))
+	for _, doc := range docs {
+		var hotelRoom HotelRoom
+		if err := doc.DataTo(&hotelRoom); err != nil {
+			return nil, err
+		}
+		hotelRooms = append(hotelRooms, &hotelRoom)
+	}
+
+	return hotelRooms, nil
+}
+
+// CreateHotelReservations membuat seluruh reservasi dalam satu transaksi.
+// Jika salah satu kamar tidak tersedia, tidak ada reservasi yang dibuat.
+func (r *firestoreRepository) CreateHotelReservations(ctx context.Context, hotelReservations []*HotelReservation) error {
+	// Item dalam order yang sama juga tidak boleh saling bentrok. Reservasi tipe kamar
+	// belum memiliki kamar fisik sehingga hanya dibatasi jumlah unitnya.
+	for i, a := range hotelReservations {
+		for _, b := range hotelReservations[:i] {
+			if a.HotelRoomID != "" && a.HotelRoomID == b.HotelRoomID && a.HotelRoomStartDate <= b.HotelRoomEndDate && a.HotelRoomEndDate >= b.HotelRoomStartDate {
+				return &HotelRoomNotAvailableError{Index: i}
+			}
+		}
+	}
+
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		for i, hotelReservation := range hotelReservations {
+			if hotelReservation.HotelRoomID == "" {
+				continue
+			}
+			query := r.overlappingReservations(hotelReservation.HotelRoomID, hotelReservation.HotelRoomStartDate, hotelReservation.HotelRoomEndDate)
+			docs, err := tx.Documents(query.Limit(1)).GetAll()
+			if err != nil {
+				return err
+			}
+			if len(docs) > 0 {
+				return &HotelRoomNotAvailableError{Index: i}
+			}
+		}
+
+		if err := r.adjustRoomTypeUnits(tx, hotelReservations, nil); err != nil {
+			return err
+		}
+
+		for _, hotelReservation := range hotelReservations {
+			if err := tx.Create(r.client.Collection(hotelReservationCollection).Doc(hotelReservation.ID), hotelReservation); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// ReplaceHotelReservation membuat hotelReservation dan membatalkan reservasi replacedID
+// dalam satu transaksi. Reservasi lama tidak dihitung bentrok dengan reservasi baru,
+// sehingga tanggal menginap dapat digeser atau diperpanjang pada kamar yang sama.
+// Unit tipe kamar milik reservasi lama dikembalikan sebelum unit reservasi baru diambil.
+func (r *firestoreRepository) ReplaceHotelReservation(ctx context.Context, replacedID string, hotelReservation *HotelReservation) error {
+	replacedRef := r.client.Collection(hotelReservationCollection).Doc(replacedID)
+
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(replacedRef)
+		if status.Code(err) == codes.NotFound {
+			return ErrHotelReservationNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		var replaced HotelReservation
+		if err := doc.DataTo(&replaced); err != nil {
+			return err
+		}
+		if replaced.OrderID != hotelReservation.OrderID || replaced.Status != HotelRoomReservationStatusReserved {
+			return ErrHotelReservationNotActive
+		}
+
+		if hotelReservation.HotelRoomID != "" {
+			query := r.overlappingReservations(hotelReservation.HotelRoomID, hotelReservation.HotelRoomStartDate, hotelReservation.HotelRoomEndDate)
+			docs, err := tx.Documents(query.Limit(2)).GetAll()
+			if err != nil {
+				return err
+			}
+			for _, doc := range docs {
+				if doc.Ref.ID != replacedID {
+					return &HotelRoomNotAvailableError{Index: 0}
+				}
+			}
+		}
+
+		if err := r.adjustRoomTypeUnits(tx, []*HotelReservation{hotelReservation}, []*HotelReservation{&replaced}); err != nil {
+			return err
+		}
+
+		if err := tx.Create(r.client.Collection(hotelReservationCollection).Doc(hotelReservation.ID), hotelReservation); err != nil {
+			return err
+		}
+
+		return tx.Update(replacedRef, []firestore.Update{
+			{Path: "status", Value: HotelRoomReservationStatusCancelled},
+		})
+	})
 }
 
 func (r *firestoreRepository) GetHotelReservationByID(ctx context.Context, id string) (*HotelReservation, error) {
@@ -76,36 +242,315 @@ func (r *firestoreRepository) GetHotelReservationByID(ctx context.Context, id st
 	return &hotelReservation, nil
 }
 
-func (r *firestoreRepository) GetHotelReservationByOrderID(ctx context.Context, orderID string) (*HotelReservation, error) {
+func (r *firestoreRepository) GetHotelReservationsByOrderID(ctx context.Context, orderID string) ([]*HotelReservation, error) {
 	query := r.client.Collection(hotelReservationCollection).Where("order_id", "==", orderID)
 	docs, err := query.Documents(ctx).GetAll()
 	if err != nil {
 		return nil, err
 	}
 
-	if len(docs) == 0 {
-		return nil, ErrHotelReservationNotFound
+	hotelReservations := make([]*HotelReservation, 0, len(docs))
+	for _, doc := range docs {
+		var hotelReservation HotelReservation
+		if err := doc.DataTo(&hotelReservation); err != nil {
+			return nil, err
+		}
+		hotelReservations = append(hotelReservations, &hotelReservation)
 	}
 
-	var hotelReservation HotelReservation
-	if err := docs[0].DataTo(&hotelReservation); err != nil {
+	return hotelReservations, nil
+}
+
+// CancelHotelReservation membatalkan reservasi id dan mengembalikan unit tipe kamarnya.
+// Nilai kembalian false berarti reservasi sudah dibatalkan atau kedaluwarsa sebelumnya.
+func (r *firestoreRepository) CancelHotelReservation(ctx context.Context, id string) (bool, error) {
+	ref := r.client.Collection(hotelReservationCollection).Doc(id)
+
+	var cancelled bool
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		cancelled = false
+		doc, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return ErrHotelReservationNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		var hotelReservation HotelReservation
+		if err := doc.DataTo(&hotelReservation); err != nil {
+			return err
+		}
+		if hotelReservation.Status == HotelRoomReservationStatusCancelled || hotelReservation.Status == HotelRoomReservationStatusExpired {
+			return nil
+		}
+
+		if err := r.adjustRoomTypeUnits(tx, nil, []*HotelReservation{&hotelReservation}); err != nil {
+			return err
+		}
+
+		cancelled = true
+		return tx.Update(ref, []firestore.Update{
+			{Path: "status", Value: HotelRoomReservationStatusCancelled},
+			{Path: "expires_at", Value: firestore.Delete},
+		})
+	})
+
+	return cancelled, err
+}
+
+// AssignHotelRoom menetapkan kamar fisik untuk reservasi tipe kamar yang sudah RESERVED.
+// Kamar yang dipilih adalah kamar bertipe sama yang kosong selama seluruh masa menginap.
+// Reservasi yang sudah memiliki kamar dikembalikan apa adanya.
+func (r *firestoreRepository) AssignHotelRoom(ctx context.Context, id string) (*HotelReservation, error) {
+	ref := r.client.Collection(hotelReservationCollection).Doc(id)
+
+	var assigned *HotelReservation
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		assigned = nil
+		doc, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return ErrHotelReservationNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		var hotelReservation HotelReservation
+		if err := doc.DataTo(&hotelReservation); err != nil {
+			return err
+		}
+		if hotelReservation.HotelRoomID != "" {
+			assigned = &hotelReservation
+			return nil
+		}
+		if hotelReservation.RoomTypeID == "" || hotelReservation.Status != HotelRoomReservationStatusReserved {
+			return ErrHotelReservationNotActive
+		}
+
+		query := r.client.Collection(hotelRoomCollection).Where("room_type_id", "==", hotelReservation.RoomTypeID)
+		roomDocs, err := tx.Documents(query).GetAll()
+		if err != nil {
+			return err
+		}
+		for _, roomDoc := range roomDocs {
+			var hotelRoom HotelRoom
+			if err := roomDoc.DataTo(&hotelRoom); err != nil {
+				return err
+			}
+
+			overlapping := r.overlappingReservations(hotelRoom.ID, hotelReservation.HotelRoomStartDate, hotelReservation.HotelRoomEndDate)
+			docs, err := tx.Documents(overlapping.Limit(1)).GetAll()
+			if err != nil {
+				return err
+			}
+			if len(docs) > 0 {
+				continue
+			}
+
+			hotelReservation.HotelRoomID = hotelRoom.ID
+			hotelReservation.HotelRoomName = hotelRoom.RoomName
+			assigned = &hotelReservation
+			return tx.Update(ref, []firestore.Update{
+				{Path: "hotel_room_id", Value: hotelRoom.ID},
+				{Path: "hotel_room_name", Value: hotelRoom.RoomName},
+			})
+		}
+
+		return ErrNoRoomToAssign
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	return &hotelReservation, nil
+	return assigned, nil
 }
 
-func (r *firestoreRepository) UpdateHotelReservation(ctx context.Context, hotelReservation *HotelReservation) error {
-	_, err := r.client.Collection(hotelReservationCollection).Doc(hotelReservation.ID).Set(ctx, hotelReservation)
-	return err
+// roomTypeDate adalah kunci satu dokumen RoomTypeInventory
+type roomTypeDate struct {
+	roomTypeID string
+	date       string
 }
 
-func (r *firestoreRepository) IsHotelRoomAvailable(ctx context.Context, hotelRoomID string, startDate, endDate string) (bool, error) {
-	query := r.client.Collection(hotelReservationCollection).
+func (k roomTypeDate) docID() string {
+	return k.roomTypeID + "-" + k.date
+}
+
+// stayDates mengembalikan setiap tanggal dari startDate sampai endDate, keduanya inklusif
+func stayDates(startDate, endDate string) ([]string, error) {
+	start, err := time.Parse(config.DateFormat, startDate)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDateRange, err)
+	}
+	end, err := time.Parse(config.DateFormat, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDateRange, err)
+	}
+
+	var dates []string
+	for date := start; !date.After(end); date = date.AddDate(0, 0, 1) {
+		dates = append(dates, date.Format(config.DateFormat))
+	}
+	return dates, nil
+}
+
+// roomTypeUnits adalah perubahan unit tipe kamar per tanggal dari sekumpulan reservasi
+type roomTypeUnits struct {
+	// changed adalah tanggal yang unitnya berubah, sesuai urutan kemunculannya
+	changed []roomTypeDate
+	deltas  map[roomTypeDate]int
+	// takenBy adalah indeks reservasi taken pertama yang mengambil unit pada tanggal tersebut
+	takenBy map[roomTypeDate]int
+}
+
+// netRoomTypeUnits menjumlahkan unit yang diambil reservasi taken dan dikembalikan
+// reservasi released per tanggal. Tanggal yang perubahannya saling meniadakan, misalnya
+// malam yang sama pada modifikasi, tidak termasuk changed.
+func netRoomTypeUnits(taken, released []*HotelReservation) (*roomTypeUnits, error) {
+	var keys []roomTypeDate
+	units := &roomTypeUnits{
+		deltas:  make(map[roomTypeDate]int),
+		takenBy: make(map[roomTypeDate]int),
+	}
+	collect := func(hotelReservation *HotelReservation, delta int, index int) error {
+		if hotelReservation.RoomTypeID == "" {
+			return nil
+		}
+		dates, err := stayDates(hotelReservation.HotelRoomStartDate, hotelReservation.HotelRoomEndDate)
+		if err != nil {
+			return err
+		}
+		for _, date := range dates {
+			key := roomTypeDate{roomTypeID: hotelReservation.RoomTypeID, date: date}
+			if _, ok := units.deltas[key]; !ok {
+				keys = append(keys, key)
+			}
+			units.deltas[key] += delta
+			if _, ok := units.takenBy[key]; !ok && delta < 0 {
+				units.takenBy[key] = index
+			}
+		}
+		return nil
+	}
+	for i, hotelReservation := range released {
+		if err := collect(hotelReservation, 1, i); err != nil {
+			return nil, err
+		}
+	}
+	for i, hotelReservation := range taken {
+		if err := collect(hotelReservation, -1, i); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, key := range keys {
+		if units.deltas[key] != 0 {
+			units.changed = append(units.changed, key)
+		}
+	}
+	return units, nil
+}
+
+// apply menerapkan perubahan unit tanggal key ke inventory. Unit yang dikembalikan tidak
+// pernah melebihi jumlah kamar, misalnya untuk reservasi yang dibuat sebelum dokumen
+// inventori tanggal tersebut ada.
+func (u *roomTypeUnits) apply(inventory *RoomTypeInventory, key roomTypeDate) error {
+	inventory.AvailableUnits = min(inventory.AvailableUnits+u.deltas[key], inventory.TotalUnits)
+	if inventory.AvailableUnits < 0 {
+		return &HotelRoomNotAvailableError{Index: u.takenBy[key]}
+	}
+	return nil
+}
+
+// adjustRoomTypeUnits mengambil satu unit tipe kamar per tanggal untuk setiap reservasi
+// taken dan mengembalikan unit milik reservasi released. Reservasi tanpa RoomTypeID
+// diabaikan. Firestore mewajibkan seluruh pembacaan transaksi dilakukan sebelum penulisan,
+// sehingga fungsi ini dipanggil setelah pembacaan lain dan sebelum penulisan lain.
+func (r *firestoreRepository) adjustRoomTypeUnits(tx *firestore.Transaction, taken, released []*HotelReservation) error {
+	units, err := netRoomTypeUnits(taken, released)
+	if err != nil {
+		return err
+	}
+	changed := units.changed
+	if len(changed) == 0 {
+		return nil
+	}
+
+	var typeRefs []*firestore.DocumentRef
+	seenTypes := make(map[string]bool)
+	for _, key := range changed {
+		if !seenTypes[key.roomTypeID] {
+			seenTypes[key.roomTypeID] = true
+			typeRefs = append(typeRefs, r.client.Collection(roomTypeCollection).Doc(key.roomTypeID))
+		}
+	}
+
+	typeDocs, err := tx.GetAll(typeRefs)
+	if err != nil {
+		return err
+	}
+	totalUnits := make(map[string]int, len(typeDocs))
+	for _, doc := range typeDocs {
+		if !doc.Exists() {
+			return ErrRoomTypeNotFound
+		}
+		var roomType RoomType
+		if err := doc.DataTo(&roomType); err != nil {
+			return err
+		}
+		totalUnits[doc.Ref.ID] = roomType.TotalUnits
+	}
+
+	inventoryRefs := make([]*firestore.DocumentRef, 0, len(changed))
+	for _, key := range changed {
+		inventoryRefs = append(inventoryRefs, r.client.Collection(roomTypeInventoryCollection).Doc(key.docID()))
+	}
+	inventoryDocs, err := tx.GetAll(inventoryRefs)
+	if err != nil {
+		return err
+	}
+
+	inventories := make([]*RoomTypeInventory, 0, len(changed))
+	for i, key := range changed {
+		inventory := &RoomTypeInventory{
+			RoomTypeID:     key.roomTypeID,
+			Date:           key.date,
+			TotalUnits:     totalUnits[key.roomTypeID],
+			AvailableUnits: totalUnits[key.roomTypeID],
+		}
+		if inventoryDocs[i].Exists() {
+			if err := inventoryDocs[i].DataTo(inventory); err != nil {
+				return err
+			}
+		}
+
+		if err := units.apply(inventory, key); err != nil {
+			return err
+		}
+		inventories = append(inventories, inventory)
+	}
+
+	for i, inventory := range inventories {
+		if err := tx.Set(inventoryRefs[i], inventory); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// overlappingReservations adalah query reservasi aktif yang beririsan dengan rentang tanggal.
+// Hold yang belum kedaluwarsa ikut dihitung aktif.
+func (r *firestoreRepository) overlappingReservations(hotelRoomID string, startDate, endDate string) firestore.Query {
+	return r.client.Collection(hotelReservationCollection).
 		Where("hotel_room_id", "==", hotelRoomID).
 		Where("hotel_room_start_date", "<=", endDate).
 		Where("hotel_room_end_date", ">=", startDate).
-		Where("status", "!=", HotelRoomReservationStatusCancelled)
+		Where("status", "not-in", []HotelRoomReservationStatus{HotelRoomReservationStatusCancelled, HotelRoomReservationStatusExpired})
+}
+
+func (r *firestoreRepository) IsHotelRoomAvailable(ctx context.Context, hotelRoomID string, startDate, endDate string) (bool, error) {
+	query := r.overlappingReservations(hotelRoomID, startDate, endDate)
 
 	aggregationQuery := query.NewAggregationQuery().WithCount("all")
 	results, err := aggregationQuery.Get(ctx)
@@ -122,3 +567,140 @@ func (r *firestoreRepository) IsHotelRoomAvailable(ctx context.Context, hotelRoo
 
 	return countValue.GetIntegerValue() == 0, nil
 }
+
+// GetOverlappingHotelReservations mengembalikan reservasi aktif kamar yang beririsan dengan rentang tanggal
+func (r *firestoreRepository) GetOverlappingHotelReservations(ctx context.Context, hotelRoomID string, startDate, endDate string) ([]*HotelReservation, error) {
+	docs, err := r.overlappingReservations(hotelRoomID, startDate, endDate).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	hotelReservations := make([]*HotelReservation, 0, len(docs))
+	for _, doc := range docs {
+		var hotelReservation HotelReservation
+		if err := doc.DataTo(&hotelReservation); err != nil {
+			return nil, err
+		}
+		hotelReservations = append(hotelReservations, &hotelReservation)
+	}
+
+	return hotelReservations, nil
+}
+
+// ConfirmHotelHolds mengubah seluruh hold milik orderID menjadi RESERVED dalam satu transaksi.
+// Jika salah satu hold sudah kedaluwarsa, tidak ada hold yang dikonfirmasi. Hold yang
+// sudah dikonfirmasi sebelumnya dikembalikan lagi agar command yang terkirim ulang tetap dibalas.
+func (r *firestoreRepository) ConfirmHotelHolds(ctx context.Context, orderID string, now time.Time) ([]*HotelReservation, error) {
+	var confirmed []*HotelReservation
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		confirmed = nil
+		query := r.client.Collection(hotelReservationCollection).Where("order_id", "==", orderID)
+		docs, err := tx.Documents(query).GetAll()
+		if err != nil {
+			return err
+		}
+
+		var held, reserved []*HotelReservation
+		for _, doc := range docs {
+			var hotelReservation HotelReservation
+			if err := doc.DataTo(&hotelReservation); err != nil {
+				return err
+			}
+
+			switch hotelReservation.Status {
+			case HotelRoomReservationStatusHeld:
+				if hotelReservation.ExpiresAt == nil || !now.Before(*hotelReservation.ExpiresAt) {
+					return ErrHotelHoldExpired
+				}
+				held = append(held, &hotelReservation)
+			case HotelRoomReservationStatusReserved:
+				reserved = append(reserved, &hotelReservation)
+			}
+		}
+		if len(held) == 0 {
+			if len(reserved) == 0 {
+				return ErrHotelHoldNotFound
+			}
+			confirmed = reserved
+			return nil
+		}
+
+		for _, hotelReservation := range held {
+			if err := tx.Update(r.client.Collection(hotelReservationCollection).Doc(hotelReservation.ID), []firestore.Update{
+				{Path: "status", Value: HotelRoomReservationStatusReserved},
+				{Path: "expires_at", Value: firestore.Delete},
+			}); err != nil {
+				return err
+			}
+			hotelReservation.Status = HotelRoomReservationStatusReserved
+			hotelReservation.ExpiresAt = nil
+		}
+		confirmed = held
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return confirmed, nil
+}
+
+// GetExpiredHotelHolds mengembalikan hold yang sudah melewati ExpiresAt pada waktu now
+func (r *firestoreRepository) GetExpiredHotelHolds(ctx context.Context, now time.Time) ([]*HotelReservation, error) {
+	query := r.client.Collection(hotelReservationCollection).
+		Where("status", "==", HotelRoomReservationStatusHeld).
+		Where("expires_at", "<=", now)
+	docs, err := query.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	hotelReservations := make([]*HotelReservation, 0, len(docs))
+	for _, doc := range docs {
+		var hotelReservation HotelReservation
+		if err := doc.DataTo(&hotelReservation); err != nil {
+			return nil, err
+		}
+		hotelReservations = append(hotelReservations, &hotelReservation)
+	}
+
+	return hotelReservations, nil
+}
+
+// ExpireHotelHold mengubah hold id menjadi EXPIRED jika masih HELD dan sudah kedaluwarsa.
+// Nilai kembalian false berarti hold sudah dikonfirmasi atau dilepas lebih dulu.
+func (r *firestoreRepository) ExpireHotelHold(ctx context.Context, id string, now time.Time) (bool, error) {
+	ref := r.client.Collection(hotelReservationCollection).Doc(id)
+
+	var expired bool
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		expired = false
+		doc, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return ErrHotelReservationNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		var hotelReservation HotelReservation
+		if err := doc.DataTo(&hotelReservation); err != nil {
+			return err
+		}
+		if hotelReservation.Status != HotelRoomReservationStatusHeld || hotelReservation.ExpiresAt == nil || now.Before(*hotelReservation.ExpiresAt) {
+			return nil
+		}
+
+		if err := r.adjustRoomTypeUnits(tx, nil, []*HotelReservation{&hotelReservation}); err != nil {
+			return err
+		}
+
+		expired = true
+		return tx.Update(ref, []firestore.Update{
+			{Path: "status", Value: HotelRoomReservationStatusExpired},
+		})
+	})
+
+	return expired, err
+}
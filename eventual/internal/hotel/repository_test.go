@@ -0,0 +1,121 @@
+package hotel
+
+import (
+	"errors"
+	"maps"
+	"testing"
+)
+
+func TestAdjustRoomTypeUnits(t *testing.T) {
+	stay := func(roomTypeID, startDate, endDate string) *HotelReservation {
+		return &HotelReservation{RoomTypeID: roomTypeID, HotelRoomStartDate: startDate, HotelRoomEndDate: endDate}
+	}
+
+	tests := []struct {
+		name      string
+		taken     []*HotelReservation
+		released  []*HotelReservation
+		available int
+		// want adalah unit tersedia per tanggal yang berubah, dengan 2 unit per tipe kamar
+		want           map[string]int
+		wantFailedItem int
+		wantErr        error
+	}{
+		{
+			name:      "take every night of the stay",
+			taken:     []*HotelReservation{stay("deluxe", "2025-12-01", "2025-12-02")},
+			available: 2,
+			want:      map[string]int{"deluxe-2025-12-01": 1, "deluxe-2025-12-02": 1},
+		},
+		{
+			name:      "two reservations of the same night take two units",
+			taken:     []*HotelReservation{stay("deluxe", "2025-12-01", "2025-12-01"), stay("deluxe", "2025-12-01", "2025-12-01")},
+			available: 2,
+			want:      map[string]int{"deluxe-2025-12-01": 0},
+		},
+		{
+			name:      "release is capped at the total units",
+			released:  []*HotelReservation{stay("deluxe", "2025-12-01", "2025-12-01")},
+			available: 2,
+			want:      map[string]int{"deluxe-2025-12-01": 2},
+		},
+		{
+			name:      "modification on the same nights changes nothing",
+			taken:     []*HotelReservation{stay("deluxe", "2025-12-01", "2025-12-02")},
+			released:  []*HotelReservation{stay("deluxe", "2025-12-01", "2025-12-02")},
+			available: 0,
+			want:      map[string]int{},
+		},
+		{
+			name:      "modification shifted by one night only changes the outer nights",
+			taken:     []*HotelReservation{stay("deluxe", "2025-12-02", "2025-12-03")},
+			released:  []*HotelReservation{stay("deluxe", "2025-12-01", "2025-12-02")},
+			available: 1,
+			want:      map[string]int{"deluxe-2025-12-01": 2, "deluxe-2025-12-03": 0},
+		},
+		{
+			name:      "modification to another room type",
+			taken:     []*HotelReservation{stay("suite", "2025-12-01", "2025-12-01")},
+			released:  []*HotelReservation{stay("deluxe", "2025-12-01", "2025-12-01")},
+			available: 1,
+			want:      map[string]int{"deluxe-2025-12-01": 2, "suite-2025-12-01": 0},
+		},
+		{
+			name:      "physical rooms are skipped",
+			taken:     []*HotelReservation{stay("", "2025-12-01", "2025-12-02")},
+			available: 0,
+			want:      map[string]int{},
+		},
+		{
+			name:           "sold out night fails the first reservation taking it",
+			taken:          []*HotelReservation{stay("", "2025-12-01", "2025-12-01"), stay("deluxe", "2025-12-01", "2025-12-01")},
+			available:      0,
+			wantFailedItem: 1,
+			wantErr:        &HotelRoomNotAvailableError{},
+		},
+		{
+			name:      "invalid date",
+			taken:     []*HotelReservation{stay("deluxe", "2025-12-01", "01-12-2025")},
+			available: 2,
+			wantErr:   ErrInvalidDateRange,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			units, err := netRoomTypeUnits(tt.taken, tt.released)
+			got := make(map[string]int)
+			if err == nil {
+				for _, key := range units.changed {
+					inventory := &RoomTypeInventory{RoomTypeID: key.roomTypeID, Date: key.date, TotalUnits: 2, AvailableUnits: tt.available}
+					if err = units.apply(inventory, key); err != nil {
+						break
+					}
+					got[key.docID()] = inventory.AvailableUnits
+				}
+			}
+
+			var notAvailable *HotelRoomNotAvailableError
+			switch {
+			case tt.wantErr == nil:
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if !maps.Equal(got, tt.want) {
+					t.Errorf("available units = %v, want %v", got, tt.want)
+				}
+			case errors.As(tt.wantErr, &notAvailable):
+				if !errors.As(err, &notAvailable) {
+					t.Fatalf("error = %v, want HotelRoomNotAvailableError", err)
+				}
+				if notAvailable.Index != tt.wantFailedItem {
+					t.Errorf("failed item = %d, want %d", notAvailable.Index, tt.wantFailedItem)
+				}
+			default:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("error = %v, want %v", err, tt.wantErr)
+				}
+			}
+		})
+	}
+}
@@ -4,24 +4,44 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
+	"time"
 
 	"github.com/oklog/ulid/v2"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/config"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/event"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/messagebus"
 )
 
 type Service interface {
 	ProcessSagaEvent(ctx context.Context, msg event.Message) error
+
+	// ProcessChoreographyEvent memproses event partisipan lain pada saga booking
+	// koreografi (SAGA_MODE=choreography)
+	ProcessChoreographyEvent(ctx context.Context, msg event.Message) error
+
+	// ReleaseExpiredHolds dipanggil sweeper secara berkala untuk melepas hold yang kedaluwarsa
+	ReleaseExpiredHolds(ctx context.Context) error
+
+	// SearchHotelRooms mencari kamar yang kosong pada seluruh malam dari StartDate sampai EndDate
+	SearchHotelRooms(ctx context.Context, query SearchHotelRoomsQuery) (*HotelRoomPage, error)
+
+	// GetHotelRoomCalendar mengembalikan ketersediaan kamar per tanggal dari From sampai To
+	GetHotelRoomCalendar(ctx context.Context, hotelRoomID string, query CalendarQuery) (*HotelRoomCalendar, error)
+
+	// AssignHotelRoom menetapkan nomor kamar untuk reservasi tipe kamar
+	AssignHotelRoom(ctx context.Context, reservationID string) (*HotelReservation, error)
 }
 
 type service struct {
 	repo      Repository
 	publisher messagebus.Publisher
+	holdTTL   time.Duration
 }
 
-func NewService(repo Repository, publisher messagebus.Publisher) Service {
-	return &service{repo: repo, publisher: publisher}
+func NewService(repo Repository, publisher messagebus.Publisher, holdTTL time.Duration) Service {
+	return &service{repo: repo, publisher: publisher, holdTTL: holdTTL}
 }
 
 func (s *service) ProcessSagaEvent(ctx context.Context, msg event.Message) error {
@@ -31,17 +51,33 @@ func (s *service) ProcessSagaEvent(ctx context.Context, msg event.Message) error
 		return s.handleReserveRoom(ctx, msg)
 	case event.CommandCancelRoom:
 		return s.handleCancelRoom(ctx, msg)
+	case event.CommandModifyRoom:
+		return s.handleModifyRoom(ctx, msg)
+	case event.CommandHoldRoom:
+		return s.handleHoldRoom(ctx, msg)
+	case event.CommandConfirmRoom:
+		return s.handleConfirmRoom(ctx, msg)
 	}
 
 	return nil
 }
 
 func (s *service) publishErrorEvent(ctx context.Context, msg event.Message, err error) error {
-	s.publisher.Publish(ctx, string(event.RoomReservationFailed), event.Message{
+	return s.publishItemErrorEvent(ctx, msg, nil, err)
+}
+
+// publishItemErrorEvent mengirim event gagal beserta indeks item penyebabnya
+func (s *service) publishItemErrorEvent(ctx context.Context, msg event.Message, failedItem *int, err error) error {
+	if pubErr := s.publisher.Publish(ctx, string(event.RoomReservationFailed), event.Message{
 		EventName:     event.RoomReservationFailed,
 		CorrelationID: msg.CorrelationID,
-		Payload:       event.RoomReservationFailedPayload{FailureReason: err.Error()},
-	})
+		Payload: event.RoomReservationFailedPayload{
+			FailedItem:    failedItem,
+			FailureReason: err.Error(),
+		},
+	}); pubErr != nil {
+		return errors.Join(err, pubErr)
+	}
 
 	return err
 }
@@ -52,43 +88,86 @@ func (s *service) handleReserveRoom(ctx context.Context, msg event.Message) erro
 		return s.publishErrorEvent(ctx, msg, err)
 	}
 
-	isAvailable, err := s.repo.IsHotelRoomAvailable(ctx, payload.RoomID, payload.StartDate, payload.EndDate)
-	if err != nil {
-		return s.publishErrorEvent(ctx, msg, err)
-	}
-	if !isAvailable {
-		return s.publishErrorEvent(ctx, msg, errors.New("hotel room is not available"))
+	if len(payload.Rooms) == 0 {
+		return s.publishErrorEvent(ctx, msg, errors.New("no hotel rooms requested"))
 	}
 
-	hotelRoom, err := s.repo.GetHotelRoomByID(ctx, payload.RoomID)
+	hotelReservations, failedItem, err := s.buildHotelReservations(ctx, msg.CorrelationID, payload.Rooms)
 	if err != nil {
-		return s.publishErrorEvent(ctx, msg, err)
+		return s.publishItemErrorEvent(ctx, msg, &failedItem, err)
 	}
-
-	hotelReservation := &HotelReservation{
-		ID:                 ulid.Make().String(),
-		HotelRoomID:        hotelRoom.ID,
-		HotelRoomName:      hotelRoom.RoomName,
-		HotelName:          hotelRoom.HotelName,
-		HotelRoomStartDate: payload.StartDate,
-		HotelRoomEndDate:   payload.EndDate,
-		OrderID:            msg.CorrelationID,
-		Status:             HotelRoomReservationStatusReserved,
+	for _, hotelReservation := range hotelReservations {
+		hotelReservation.Status = HotelRoomReservationStatusReserved
 	}
 
-	if err := s.repo.CreateHotelReservation(ctx, hotelReservation); err != nil {
+	if err := s.repo.CreateHotelReservations(ctx, hotelReservations); err != nil {
+		var notAvailableErr *HotelRoomNotAvailableError
+		if errors.As(err, &notAvailableErr) {
+			return s.publishItemErrorEvent(ctx, msg, &notAvailableErr.Index, err)
+		}
 		return s.publishErrorEvent(ctx, msg, err)
 	}
 
-	s.publisher.Publish(ctx, string(event.RoomReserved), event.Message{
+	reservationIDs := make([]string, 0, len(hotelReservations))
+	for _, hotelReservation := range hotelReservations {
+		reservationIDs = append(reservationIDs, hotelReservation.ID)
+	}
+
+	return s.publisher.Publish(ctx, string(event.RoomReserved), event.Message{
 		EventName:     event.RoomReserved,
 		CorrelationID: msg.CorrelationID,
 		Payload: event.RoomReservedPayload{
-			RoomReservationID: hotelReservation.ID,
+			RoomReservationIDs: reservationIDs,
+			Order:              payload.Order,
 		},
 	})
+}
 
-	return nil
+// publishModificationErrorEvent mengirim event gagal modifikasi, reservasi lama tetap aktif
+func (s *service) publishModificationErrorEvent(ctx context.Context, msg event.Message, err error) error {
+	if pubErr := s.publisher.Publish(ctx, string(event.RoomModificationFailed), event.Message{
+		EventName:     event.RoomModificationFailed,
+		CorrelationID: msg.CorrelationID,
+		Payload:       event.RoomModificationFailedPayload{FailureReason: err.Error()},
+	}); pubErr != nil {
+		return errors.Join(err, pubErr)
+	}
+
+	return err
+}
+
+func (s *service) handleModifyRoom(ctx context.Context, msg event.Message) error {
+	payload, err := mapToPayload[event.ModifyRoomPayload](msg)
+	if err != nil {
+		return s.publishModificationErrorEvent(ctx, msg, err)
+	}
+
+	hotelReservation, err := s.newHotelReservation(ctx, msg.CorrelationID, payload.Room)
+	if err != nil {
+		return s.publishModificationErrorEvent(ctx, msg, err)
+	}
+	hotelReservation.Status = HotelRoomReservationStatusReserved
+	if err := s.repo.ReplaceHotelReservation(ctx, payload.ReservationID, hotelReservation); err != nil {
+		return s.publishModificationErrorEvent(ctx, msg, err)
+	}
+
+	if err := s.publisher.Publish(ctx, string(event.RoomModified), event.Message{
+		EventName:     event.RoomModified,
+		CorrelationID: msg.CorrelationID,
+		Payload: event.RoomModifiedPayload{
+			ReplacedReservationID: payload.ReservationID,
+			RoomReservationID:     hotelReservation.ID,
+		},
+	}); err != nil {
+		return err
+	}
+
+	// Reservasi lama sudah dibatalkan saat diganti
+	replaced, err := s.repo.GetHotelReservationByID(ctx, payload.ReservationID)
+	if err != nil {
+		return err
+	}
+	return s.publishReleasedEvent(ctx, msg.CorrelationID, []*HotelReservation{replaced})
 }
 
 func mapToPayload[T any](msg event.Message) (T, error) {
@@ -109,18 +188,355 @@ func (s *service) handleCancelRoom(ctx context.Context, msg event.Message) error
 		return s.publishErrorEvent(ctx, msg, err)
 	}
 
-	hotelReservation, err := s.repo.GetHotelReservationByOrderID(ctx, payload.OrderID)
-	if errors.Is(err, ErrHotelReservationNotFound) {
+	hotelReservations, err := s.repo.GetHotelReservationsByOrderID(ctx, payload.OrderID)
+	if err != nil {
+		return s.publishErrorEvent(ctx, msg, err)
+	}
+
+	reservationIDs := make([]string, 0, len(hotelReservations))
+	var released []*HotelReservation
+	for _, hotelReservation := range hotelReservations {
+		reservationIDs = append(reservationIDs, hotelReservation.ID)
+		if hotelReservation.Status == HotelRoomReservationStatusCancelled || hotelReservation.Status == HotelRoomReservationStatusExpired {
+			continue
+		}
+
+		cancelled, err := s.repo.CancelHotelReservation(ctx, hotelReservation.ID)
+		if err != nil {
+			return s.publishErrorEvent(ctx, msg, err)
+		}
+		if cancelled {
+			released = append(released, hotelReservation)
+		}
+	}
+
+	// Balasan tetap dikirim jika reservasi sudah dibatalkan sebelumnya agar command yang
+	// terkirim ulang tetap dibalas
+	if err := s.publisher.Publish(ctx, string(event.RoomReservationCancelled), event.Message{
+		EventName:     event.RoomReservationCancelled,
+		CorrelationID: msg.CorrelationID,
+		Payload:       event.RoomReservationCancelledPayload{RoomReservationIDs: reservationIDs},
+	}); err != nil {
+		return err
+	}
+
+	return s.publishReleasedEvent(ctx, msg.CorrelationID, released)
+}
+
+// publishReleasedEvent mengumumkan kamar yang kembali tersedia agar waitlist dapat memesannya
+func (s *service) publishReleasedEvent(ctx context.Context, orderID string, hotelReservations []*HotelReservation) error {
+	if len(hotelReservations) == 0 {
 		return nil
 	}
+
+	rooms := make([]event.RoomItem, 0, len(hotelReservations))
+	for _, hotelReservation := range hotelReservations {
+		rooms = append(rooms, event.RoomItem{
+			RoomID:     hotelReservation.HotelRoomID,
+			RoomTypeID: hotelReservation.RoomTypeID,
+			StartDate:  hotelReservation.HotelRoomStartDate,
+			EndDate:    hotelReservation.HotelRoomEndDate,
+			Price:      hotelReservation.Price,
+		})
+	}
+
+	return s.publisher.Publish(ctx, string(event.RoomReleased), event.Message{
+		EventName:     event.RoomReleased,
+		CorrelationID: orderID,
+		Payload:       event.RoomReleasedPayload{Rooms: rooms},
+	})
+}
+
+// buildHotelReservations membuat reservasi tanpa status untuk setiap kamar. Jika kamar
+// tidak ditemukan, indeks kamar tersebut dikembalikan bersama error.
+func (s *service) buildHotelReservations(ctx context.Context, orderID string, rooms []event.RoomItem) ([]*HotelReservation, int, error) {
+	hotelReservations := make([]*HotelReservation, 0, len(rooms))
+	for i, room := range rooms {
+		hotelReservation, err := s.newHotelReservation(ctx, orderID, room)
+		if err != nil {
+			return nil, i, err
+		}
+		hotelReservations = append(hotelReservations, hotelReservation)
+	}
+
+	return hotelReservations, 0, nil
+}
+
+// newHotelReservation membuat reservasi tanpa status untuk satu kamar fisik atau satu
+// unit tipe kamar. Kamar fisik yang termasuk suatu tipe kamar juga mengambil unit tipe tersebut.
+func (s *service) newHotelReservation(ctx context.Context, orderID string, room event.RoomItem) (*HotelReservation, error) {
+	hotelReservation := &HotelReservation{
+		ID:                 ulid.Make().String(),
+		HotelRoomStartDate: room.StartDate,
+		HotelRoomEndDate:   room.EndDate,
+		Price:              room.Price,
+		OrderID:            orderID,
+	}
+
+	if room.RoomTypeID != "" {
+		roomType, err := s.repo.GetRoomTypeByID(ctx, room.RoomTypeID)
+		if err != nil {
+			return nil, err
+		}
+		hotelReservation.RoomTypeID = roomType.ID
+		hotelReservation.RoomTypeName = roomType.Name
+		hotelReservation.HotelName = roomType.HotelName
+		return hotelReservation, nil
+	}
+
+	hotelRoom, err := s.repo.GetHotelRoomByID(ctx, room.RoomID)
 	if err != nil {
-		return s.publishErrorEvent(ctx, msg, err)
+		return nil, err
 	}
+	hotelReservation.HotelRoomID = hotelRoom.ID
+	hotelReservation.HotelRoomName = hotelRoom.RoomName
+	hotelReservation.RoomTypeID = hotelRoom.RoomTypeID
+	hotelReservation.HotelName = hotelRoom.HotelName
+	return hotelReservation, nil
+}
 
-	hotelReservation.Status = HotelRoomReservationStatusCancelled
-	if err := s.repo.UpdateHotelReservation(ctx, hotelReservation); err != nil {
-		return s.publishErrorEvent(ctx, msg, err)
+// publishHoldErrorEvent mengirim event gagal hold beserta indeks item penyebabnya
+func (s *service) publishHoldErrorEvent(ctx context.Context, msg event.Message, failedItem *int, err error) error {
+	if pubErr := s.publisher.Publish(ctx, string(event.RoomHoldFailed), event.Message{
+		EventName:     event.RoomHoldFailed,
+		CorrelationID: msg.CorrelationID,
+		Payload: event.RoomHoldFailedPayload{
+			FailedItem:    failedItem,
+			FailureReason: err.Error(),
+		},
+	}); pubErr != nil {
+		return errors.Join(err, pubErr)
 	}
 
-	return nil
+	return err
+}
+
+func (s *service) handleHoldRoom(ctx context.Context, msg event.Message) error {
+	payload, err := mapToPayload[event.HoldRoomPayload](msg)
+	if err != nil {
+		return s.publishHoldErrorEvent(ctx, msg, nil, err)
+	}
+
+	if len(payload.Rooms) == 0 {
+		return s.publishHoldErrorEvent(ctx, msg, nil, errors.New("no hotel rooms requested"))
+	}
+
+	hotelReservations, failedItem, err := s.buildHotelReservations(ctx, msg.CorrelationID, payload.Rooms)
+	if err != nil {
+		return s.publishHoldErrorEvent(ctx, msg, &failedItem, err)
+	}
+
+	ttl := s.holdTTL
+	if payload.TTLSeconds > 0 {
+		ttl = time.Duration(payload.TTLSeconds) * time.Second
+	}
+	expiresAt := time.Now().Add(ttl)
+	for _, hotelReservation := range hotelReservations {
+		hotelReservation.Status = HotelRoomReservationStatusHeld
+		hotelReservation.ExpiresAt = &expiresAt
+	}
+
+	if err := s.repo.CreateHotelReservations(ctx, hotelReservations); err != nil {
+		var notAvailableErr *HotelRoomNotAvailableError
+		if errors.As(err, &notAvailableErr) {
+			return s.publishHoldErrorEvent(ctx, msg, &notAvailableErr.Index, err)
+		}
+		return s.publishHoldErrorEvent(ctx, msg, nil, err)
+	}
+
+	reservationIDs := make([]string, 0, len(hotelReservations))
+	for _, hotelReservation := range hotelReservations {
+		reservationIDs = append(reservationIDs, hotelReservation.ID)
+	}
+
+	return s.publisher.Publish(ctx, string(event.RoomHeld), event.Message{
+		EventName:     event.RoomHeld,
+		CorrelationID: msg.CorrelationID,
+		Payload: event.RoomHeldPayload{
+			RoomReservationIDs: reservationIDs,
+			ExpiresAt:          expiresAt,
+		},
+	})
+}
+
+// publishConfirmationErrorEvent mengirim event gagal konfirmasi, hold yang belum kedaluwarsa tetap ditahan
+func (s *service) publishConfirmationErrorEvent(ctx context.Context, msg event.Message, err error) error {
+	if pubErr := s.publisher.Publish(ctx, string(event.RoomHoldConfirmationFailed), event.Message{
+		EventName:     event.RoomHoldConfirmationFailed,
+		CorrelationID: msg.CorrelationID,
+		Payload:       event.RoomHoldConfirmationFailedPayload{FailureReason: err.Error()},
+	}); pubErr != nil {
+		return errors.Join(err, pubErr)
+	}
+
+	return err
+}
+
+func (s *service) handleConfirmRoom(ctx context.Context, msg event.Message) error {
+	payload, err := mapToPayload[event.ConfirmRoomPayload](msg)
+	if err != nil {
+		return s.publishConfirmationErrorEvent(ctx, msg, err)
+	}
+
+	hotelReservations, err := s.repo.ConfirmHotelHolds(ctx, payload.OrderID, time.Now())
+	if err != nil {
+		return s.publishConfirmationErrorEvent(ctx, msg, err)
+	}
+
+	reservationIDs := make([]string, 0, len(hotelReservations))
+	for _, hotelReservation := range hotelReservations {
+		reservationIDs = append(reservationIDs, hotelReservation.ID)
+	}
+
+	return s.publisher.Publish(ctx, string(event.RoomHoldConfirmed), event.Message{
+		EventName:     event.RoomHoldConfirmed,
+		CorrelationID: msg.CorrelationID,
+		Payload:       event.RoomHoldConfirmedPayload{RoomReservationIDs: reservationIDs},
+	})
+}
+
+func (s *service) ReleaseExpiredHolds(ctx context.Context) error {
+	now := time.Now()
+	hotelReservations, err := s.repo.GetExpiredHotelHolds(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	// Hold dikelompokkan per order agar setiap order menerima satu event
+	var orderIDs []string
+	expiredHolds := make(map[string][]*HotelReservation)
+	var errs []error
+	for _, hotelReservation := range hotelReservations {
+		expired, err := s.repo.ExpireHotelHold(ctx, hotelReservation.ID, now)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !expired {
+			continue
+		}
+
+		if _, ok := expiredHolds[hotelReservation.OrderID]; !ok {
+			orderIDs = append(orderIDs, hotelReservation.OrderID)
+		}
+		expiredHolds[hotelReservation.OrderID] = append(expiredHolds[hotelReservation.OrderID], hotelReservation)
+	}
+
+	for _, orderID := range orderIDs {
+		expiredIDs := make([]string, 0, len(expiredHolds[orderID]))
+		for _, hotelReservation := range expiredHolds[orderID] {
+			expiredIDs = append(expiredIDs, hotelReservation.ID)
+		}
+
+		if err := s.publisher.Publish(ctx, string(event.RoomHoldExpired), event.Message{
+			EventName:     event.RoomHoldExpired,
+			CorrelationID: orderID,
+			Payload:       event.RoomHoldExpiredPayload{RoomReservationIDs: expiredIDs},
+		}); err != nil {
+			errs = append(errs, err)
+		}
+		if err := s.publishReleasedEvent(ctx, orderID, expiredHolds[orderID]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(orderIDs) > 0 {
+		log.Printf("Released expired hotel holds of %d orders", len(orderIDs))
+	}
+
+	return errors.Join(errs...)
+}
+
+// SearchHotelRooms membaca kamar per halaman dan mengecek ketersediaannya terhadap
+// reservasi aktif sampai satu halaman hasil terisi
+func (s *service) SearchHotelRooms(ctx context.Context, query SearchHotelRoomsQuery) (*HotelRoomPage, error) {
+	startDate, err := time.Parse(config.DateFormat, query.StartDate)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDateRange, err)
+	}
+	endDate, err := time.Parse(config.DateFormat, query.EndDate)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDateRange, err)
+	}
+	if endDate.Before(startDate) {
+		return nil, fmt.Errorf("%w: end date is before start date", ErrInvalidDateRange)
+	}
+
+	pageSize := query.PageSize
+	if pageSize == 0 {
+		pageSize = defaultPageSize
+	}
+
+	page := &HotelRoomPage{Items: make([]*HotelRoom, 0, pageSize)}
+	startAfter := query.PageToken
+	for {
+		hotelRooms, err := s.repo.ListHotelRooms(ctx, query.City, query.HotelName, startAfter, pageSize)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, hotelRoom := range hotelRooms {
+			available, err := s.repo.IsHotelRoomAvailable(ctx, hotelRoom.ID, startDate.Format(config.DateFormat), endDate.Format(config.DateFormat))
+			if err != nil {
+				return nil, err
+			}
+			if !available {
+				continue
+			}
+
+			page.Items = append(page.Items, hotelRoom)
+			if len(page.Items) == pageSize {
+				page.NextPageToken = hotelRoom.ID
+				return page, nil
+			}
+		}
+
+		if len(hotelRooms) < pageSize {
+			return page, nil
+		}
+		startAfter = hotelRooms[len(hotelRooms)-1].ID
+	}
+}
+
+// GetHotelRoomCalendar menandai setiap tanggal yang beririsan dengan reservasi aktif.
+// Hold yang belum dilepas sweeper tetap memblokir tanggalnya.
+func (s *service) GetHotelRoomCalendar(ctx context.Context, hotelRoomID string, query CalendarQuery) (*HotelRoomCalendar, error) {
+	from, err := time.Parse(config.DateFormat, query.From)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDateRange, err)
+	}
+	to, err := time.Parse(config.DateFormat, query.To)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDateRange, err)
+	}
+	if to.Before(from) || to.After(from.AddDate(0, 0, maxCalendarDays-1)) {
+		return nil, fmt.Errorf("%w: must span 1 to %d days", ErrInvalidDateRange, maxCalendarDays)
+	}
+
+	if _, err := s.repo.GetHotelRoomByID(ctx, hotelRoomID); err != nil {
+		return nil, err
+	}
+
+	hotelReservations, err := s.repo.GetOverlappingHotelReservations(ctx, hotelRoomID, from.Format(config.DateFormat), to.Format(config.DateFormat))
+	if err != nil {
+		return nil, err
+	}
+
+	calendar := &HotelRoomCalendar{HotelRoomID: hotelRoomID}
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		day := CalendarDay{Date: date.Format(config.DateFormat), Available: true}
+		for _, hotelReservation := range hotelReservations {
+			if day.Date >= hotelReservation.HotelRoomStartDate && day.Date <= hotelReservation.HotelRoomEndDate {
+				day.Available = false
+				day.Status = hotelReservation.Status
+				break
+			}
+		}
+		calendar.Days = append(calendar.Days, day)
+	}
+
+	return calendar, nil
+}
+
+func (s *service) AssignHotelRoom(ctx context.Context, reservationID string) (*HotelReservation, error) {
+	return s.repo.AssignHotelRoom(ctx, reservationID)
 }
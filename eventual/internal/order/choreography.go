@@ -0,0 +1,175 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/event"
+)
+
+// choreography menjalankan saga booking secara koreografi (SAGA_MODE=choreography).
+// Order service hanya memulai saga dengan OrderCreated, setelahnya setiap partisipan
+// bereaksi terhadap event partisipan sebelumnya dan mengompensasi reservasinya sendiri
+// saat partisipan setelahnya gagal. Order service tetap mencatat setiap event ke order
+// agar metrik kedua mode dapat dibandingkan.
+type choreography struct {
+	s *service
+}
+
+func (c *choreography) Start(ctx context.Context, order *Order) error {
+	if err := c.s.repo.UpdateOrder(ctx, order); err != nil {
+		return err
+	}
+
+	msg := orderCreatedEvent(order)
+	if err := c.s.publisher.Publish(ctx, string(msg.EventName), msg); err != nil {
+		// Event mungkin sudah diterima hotel service walaupun publish gagal
+		return errors.Join(err, c.Compensate(ctx, order, "failed to publish order created"))
+	}
+	return nil
+}
+
+// Handle mencatat event partisipan ke order. Order yang sudah selesai tidak berubah
+// lagi karena kompensasi dilakukan partisipan sendiri.
+func (c *choreography) Handle(ctx context.Context, order *Order, msg event.Message) error {
+	if order.Status != StatusAwaitingConfirmation && order.Status != StatusCapturingPayment {
+		return nil
+	}
+
+	// Partisipan tanpa item tetap mengirim event berhasil agar saga berlanjut, event
+	// tersebut tidak dicatat ke sub-transaksi yang tidak diminta
+	var err error
+	switch msg.EventName {
+	case event.RoomReserved:
+		if order.HotelReservationStatus != ReservationStatusNotRequested {
+			err = c.s.roomReserved(order, msg)
+		}
+	case event.CarReserved:
+		if order.CarReservationStatus != ReservationStatusNotRequested {
+			err = c.s.carReserved(order, msg)
+		}
+	case event.SeatReserved:
+		if order.TrainReservationStatus != ReservationStatusNotRequested {
+			err = c.s.seatReserved(order, msg)
+		}
+	case event.FlightReserved:
+		if order.FlightReservationStatus != ReservationStatusNotRequested {
+			err = c.s.flightReserved(order, msg)
+		}
+	case event.PaymentAuthorized:
+		err = c.s.paymentAuthorized(order, msg)
+		order.Status = StatusCapturingPayment
+	case event.PaymentCaptured:
+		err = c.s.paymentCaptured(order, msg)
+		order.Status = StatusBooked
+		order.DoneAt = time.Now()
+	case event.RoomReservationFailed:
+		err = c.fail(order, msg, c.s.roomReservationFailed)
+	case event.CarReservationFailed:
+		err = c.fail(order, msg, c.s.carReservationFailed)
+	case event.SeatReservationFailed:
+		err = c.fail(order, msg, c.s.seatReservationFailed)
+	case event.FlightReservationFailed:
+		err = c.fail(order, msg, c.s.flightReservationFailed)
+	case event.PaymentFailed:
+		err = c.fail(order, msg, c.s.paymentFailed)
+	case event.PaymentCaptureFailed:
+		err = c.fail(order, msg, c.s.paymentCaptureFailed)
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := c.s.repo.UpdateOrder(ctx, order); err != nil {
+		return err
+	}
+
+	switch order.Status {
+	case StatusBooked:
+		final := orderBookedEvent(order)
+		return c.s.publisher.Publish(ctx, string(final.EventName), final)
+	case StatusFailed:
+		final := orderFailedEvent(order)
+		return c.s.publisher.Publish(ctx, string(final.EventName), final)
+	}
+	return nil
+}
+
+// fail mencatat event gagal lalu menggagalkan order
+func (c *choreography) fail(order *Order, msg event.Message, record func(*Order, event.Message) error) error {
+	if err := record(order, msg); err != nil {
+		return err
+	}
+	order.Status = StatusFailed
+	return nil
+}
+
+// Retry tidak didukung karena tidak ada yang mencatat partisipan mana yang sedang
+// memproses order. Order PENDING dimulai ulang oleh RetrySaga.
+func (c *choreography) Retry(ctx context.Context, order *Order) error {
+	return ErrSagaNotRetryable
+}
+
+// Compensate menggagalkan order dan mengirim command pembatalan ke seluruh partisipan
+// yang diminta, karena order service tidak mengetahui partisipan mana yang sudah
+// mereservasi
+func (c *choreography) Compensate(ctx context.Context, order *Order, reason string) error {
+	log.Printf("Compensating order %s: %s", order.ID, reason)
+	order.Status = StatusFailed
+	if err := c.s.repo.UpdateOrder(ctx, order); err != nil {
+		return err
+	}
+
+	var messages []event.Message
+	if order.HotelReservationStatus != ReservationStatusNotRequested {
+		messages = append(messages, cancelRoomCommand(order))
+	}
+	if order.CarReservationStatus != ReservationStatusNotRequested {
+		messages = append(messages, cancelCarCommand(order))
+	}
+	if order.TrainReservationStatus != ReservationStatusNotRequested {
+		messages = append(messages, cancelSeatCommand(order))
+	}
+	if order.FlightReservationStatus != ReservationStatusNotRequested {
+		messages = append(messages, cancelFlightCommand(order))
+	}
+	switch order.PaymentStatus {
+	case PaymentStatusCaptured:
+		messages = append(messages, refundPaymentCommand(order, order.TotalPrice))
+	case PaymentStatusPending, PaymentStatusAuthorized, PaymentStatusCaptureFailed:
+		messages = append(messages, voidPaymentCommand(order))
+	}
+	messages = append(messages, orderFailedEvent(order))
+
+	var errs []error
+	for _, msg := range messages {
+		errs = append(errs, c.s.publisher.Publish(ctx, string(msg.EventName), msg))
+	}
+	return errors.Join(errs...)
+}
+
+// ExpireSteps tidak melakukan apa pun karena batas waktu step hanya berlaku pada saga
+// orkestrasi
+func (c *choreography) ExpireSteps(ctx context.Context) error {
+	return nil
+}
+
+func orderCreatedEvent(order *Order) event.Message {
+	return event.Message{
+		EventName:     event.OrderCreated,
+		CorrelationID: order.ID,
+		Payload: event.OrderCreatedPayload{
+			UserID:   order.UserID,
+			Rooms:    roomItems(order),
+			Cars:     carItems(order),
+			Seats:    seatItems(order),
+			Flights:  flightItems(order),
+			Amount:   order.TotalPrice,
+			Currency: order.Currency,
+		},
+	}
+}
@@ -1,9 +1,11 @@
 package order
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/internal/pricing"
 )
 
 type Handler struct {
@@ -22,6 +24,140 @@ func (h *Handler) CreateOrder(ctx *gin.Context) {
 	}
 
 	order, err := h.service.StartSaga(ctx, payload)
+	if errors.Is(err, ErrEmptyOrder) || pricing.IsRequestError(err) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, pricing.ErrQuoteNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, pricing.ErrQuoteExpired) {
+		ctx.JSON(http.StatusGone, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, pricing.ErrQuoteAlreadyUsed) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, order)
+}
+
+func (h *Handler) GetOrder(ctx *gin.Context) {
+	order, err := h.service.GetOrder(ctx, ctx.Param("id"))
+	if errors.Is(err, ErrOrderNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, order)
+}
+
+func (h *Handler) CancelOrder(ctx *gin.Context) {
+	order, err := h.service.CancelOrder(ctx, ctx.Param("id"))
+	if errors.Is(err, ErrOrderNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, ErrOrderNotCancellable) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, order)
+}
+
+func (h *Handler) ModifyOrder(ctx *gin.Context) {
+	var payload ModifyOrderPayload
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	order, err := h.service.ModifyOrder(ctx, ctx.Param("id"), payload)
+	if errors.Is(err, ErrInvalidModification) || errors.Is(err, ErrFlightNotModifiable) || errors.Is(err, ErrItemNotFound) || pricing.IsRequestError(err) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, ErrOrderNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, ErrOrderNotModifiable) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, order)
+}
+
+func (h *Handler) HoldOrder(ctx *gin.Context) {
+	var payload HoldOrderPayload
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	order, err := h.service.HoldOrder(ctx, payload)
+	if errors.Is(err, ErrEmptyOrder) || errors.Is(err, ErrFlightNotHoldable) || pricing.IsRequestError(err) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, ErrHoldNotSupported) {
+		ctx.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, pricing.ErrQuoteNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, pricing.ErrQuoteExpired) {
+		ctx.JSON(http.StatusGone, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, pricing.ErrQuoteAlreadyUsed) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, order)
+}
+
+func (h *Handler) ConfirmHold(ctx *gin.Context) {
+	order, err := h.service.ConfirmHold(ctx, ctx.Param("id"))
+	if errors.Is(err, ErrOrderNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, ErrOrderNotHeld) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, ErrHoldExpired) {
+		ctx.JSON(http.StatusGone, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -0,0 +1,485 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/config"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/event"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/saga"
+)
+
+// Nama step saga hold dan saga konfirmasi hold, dicatat di riwayat saga
+const (
+	stepHoldHotel    = "hold_hotel"
+	stepHoldCar      = "hold_car"
+	stepHoldTrain    = "hold_train"
+	stepConfirmHotel = "confirm_hotel"
+	stepConfirmCar   = "confirm_car"
+	stepConfirmTrain = "confirm_train"
+)
+
+// HoldOrderPayload menahan item order selama TTLSeconds detik, atau selama HOLD_TTL
+// partisipan jika kosong. Hanya kamar hotel, mobil dan kursi kereta yang dapat ditahan.
+type HoldOrderPayload struct {
+	CreateOrderPayload
+	TTLSeconds int `json:"ttl_seconds" binding:"min=0"`
+}
+
+var (
+	ErrHoldNotSupported  = errors.New("holds require SAGA_MODE=orchestration")
+	ErrFlightNotHoldable = errors.New("flight seats cannot be held, book them with POST /orders")
+	ErrOrderNotHeld      = errors.New("only held orders can be confirmed")
+	ErrHoldExpired       = errors.New("hold has expired")
+)
+
+// HoldOrder membuat order yang itemnya ditahan tanpa dibayar. Order menjadi HELD setelah
+// seluruh partisipan menahan itemnya dan harus dikonfirmasi dengan ConfirmHold sebelum
+// hold kedaluwarsa. Jika salah satu hold gagal, hold lain dilepas dan order FAILED.
+func (s *service) HoldOrder(ctx context.Context, payload HoldOrderPayload) (*Order, error) {
+	// Pada saga koreografi payment service meng-capture setiap otorisasi sendiri, sehingga
+	// otorisasi saat konfirmasi tidak dapat diorkestrasi
+	if s.mode == config.SagaModeChoreography {
+		return nil, ErrHoldNotSupported
+	}
+	if len(payload.Flights) > 0 {
+		return nil, ErrFlightNotHoldable
+	}
+
+	order, err := s.createOrder(ctx, payload.CreateOrderPayload, &Hold{TTLSeconds: payload.TTLSeconds})
+	if err != nil {
+		return nil, err
+	}
+
+	order.Status = StatusHolding
+	if err := s.holds.Start(ctx, order); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// ConfirmHold mengubah hold order HELD menjadi reservasi bersamaan dengan otorisasi
+// pembayaran, lalu meng-capture pembayaran seperti saga booking. Jika konfirmasi salah
+// satu partisipan gagal, misalnya karena hold-nya sudah kedaluwarsa, seluruh item dilepas.
+func (s *service) ConfirmHold(ctx context.Context, orderID string) (*Order, error) {
+	order, err := s.repo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Hold == nil || order.Status != StatusHeld {
+		if order.Status == StatusHoldExpired {
+			return nil, ErrHoldExpired
+		}
+		return nil, ErrOrderNotHeld
+	}
+	if !time.Now().Before(order.Hold.ExpiresAt) {
+		return nil, ErrHoldExpired
+	}
+
+	order.Status = StatusAwaitingConfirmation
+	order.Hold.ConfirmRequestedAt = time.Now()
+	if err := s.confirmations.Start(ctx, order); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// processHoldExpiredEvent mencatat hold yang dilepas sweeper partisipan sebelum order
+// dikonfirmasi. Hold sub-transaksi lain kedaluwarsa pada waktu yang sama sehingga tidak
+// perlu dilepas. Order yang sudah dikonfirmasi diselesaikan saga konfirmasi hold.
+func (s *service) processHoldExpiredEvent(ctx context.Context, order *Order, msg event.Message) error {
+	if order.Status != StatusHeld && order.Status != StatusHoldExpired {
+		return nil
+	}
+
+	switch msg.EventName {
+	case event.RoomHoldExpired:
+		order.HotelReservationStatus = ReservationStatusExpired
+		for i := range order.HotelRooms {
+			order.HotelRooms[i].Status = ReservationStatusExpired
+		}
+	case event.CarHoldExpired:
+		order.CarReservationStatus = ReservationStatusExpired
+		for i := range order.Cars {
+			order.Cars[i].Status = ReservationStatusExpired
+		}
+	case event.SeatHoldExpired:
+		order.TrainReservationStatus = ReservationStatusExpired
+		for i := range order.TrainSeats {
+			order.TrainSeats[i].Status = ReservationStatusExpired
+		}
+	}
+
+	if order.Status == StatusHoldExpired {
+		return s.repo.UpdateOrder(ctx, order)
+	}
+
+	order.Status = StatusHoldExpired
+	order.DoneAt = time.Now()
+	if err := s.repo.UpdateOrder(ctx, order); err != nil {
+		return err
+	}
+	return s.publisher.Publish(ctx, string(event.OrderHoldExpired), event.Message{
+		EventName:     event.OrderHoldExpired,
+		CorrelationID: order.ID,
+		Payload:       event.OrderHoldExpiredPayload{OrderID: order.ID},
+	})
+}
+
+// holdSaga menahan item setiap sub-transaksi secara bersamaan. Hold yang berhasil
+// dilepas dengan command cancel jika hold sub-transaksi lain gagal.
+func (s *service) holdSaga(stepTimeout time.Duration) saga.Definition[*Order] {
+	return saga.Definition[*Order]{
+		Name: "hold",
+		Steps: []saga.Step[*Order]{
+			{
+				Name:             stepHoldHotel,
+				Skip:             func(order *Order) bool { return len(order.HotelRooms) == 0 },
+				Command:          holdRoomCommand,
+				SuccessEvent:     event.RoomHeld,
+				FailureEvent:     event.RoomHoldFailed,
+				OnSuccess:        s.roomHeld,
+				OnFailure:        s.roomHoldFailed,
+				Compensation:     always(cancelRoomCommand),
+				CompensatedEvent: event.RoomReservationCancelled,
+				Timeout:          stepTimeout,
+			},
+			{
+				Name:             stepHoldCar,
+				Skip:             func(order *Order) bool { return len(order.Cars) == 0 },
+				Command:          holdCarCommand,
+				SuccessEvent:     event.CarHeld,
+				FailureEvent:     event.CarHoldFailed,
+				OnSuccess:        s.carHeld,
+				OnFailure:        s.carHoldFailed,
+				Compensation:     always(cancelCarCommand),
+				CompensatedEvent: event.CarReservationCancelled,
+				Timeout:          stepTimeout,
+			},
+			{
+				Name:             stepHoldTrain,
+				Skip:             func(order *Order) bool { return len(order.TrainSeats) == 0 },
+				Command:          holdSeatCommand,
+				SuccessEvent:     event.SeatHeld,
+				FailureEvent:     event.SeatHoldFailed,
+				OnSuccess:        s.seatHeld,
+				OnFailure:        s.seatHoldFailed,
+				Compensation:     always(cancelSeatCommand),
+				CompensatedEvent: event.SeatReservationCancelled,
+				Timeout:          stepTimeout,
+			},
+		},
+		OnCompleted: func(order *Order) event.Message {
+			order.Status = StatusHeld
+			return event.Message{
+				EventName:     event.OrderHeld,
+				CorrelationID: order.ID,
+				Payload:       event.OrderHeldPayload{OrderID: order.ID, ExpiresAt: order.Hold.ExpiresAt},
+			}
+		},
+		OnFailed: failBooking,
+	}
+}
+
+// holdConfirmationSaga mengonfirmasi hold setiap sub-transaksi bersamaan dengan otorisasi
+// pembayaran, lalu meng-capture pembayaran setelah semuanya berhasil. Command cancel
+// melepas item yang masih ditahan maupun yang sudah dikonfirmasi.
+func (s *service) holdConfirmationSaga(stepTimeout time.Duration) saga.Definition[*Order] {
+	return saga.Definition[*Order]{
+		Name: "hold_confirmation",
+		Steps: []saga.Step[*Order]{
+			{
+				Name:             stepConfirmHotel,
+				Skip:             func(order *Order) bool { return len(order.HotelRooms) == 0 },
+				Command:          confirmRoomCommand,
+				SuccessEvent:     event.RoomHoldConfirmed,
+				FailureEvent:     event.RoomHoldConfirmationFailed,
+				OnSuccess:        s.roomHoldConfirmed,
+				OnFailure:        s.roomHoldConfirmationFailed,
+				Compensation:     always(cancelRoomCommand),
+				CompensatedEvent: event.RoomReservationCancelled,
+				Timeout:          stepTimeout,
+			},
+			{
+				Name:             stepConfirmCar,
+				Skip:             func(order *Order) bool { return len(order.Cars) == 0 },
+				Command:          confirmCarCommand,
+				SuccessEvent:     event.CarHoldConfirmed,
+				FailureEvent:     event.CarHoldConfirmationFailed,
+				OnSuccess:        s.carHoldConfirmed,
+				OnFailure:        s.carHoldConfirmationFailed,
+				Compensation:     always(cancelCarCommand),
+				CompensatedEvent: event.CarReservationCancelled,
+				Timeout:          stepTimeout,
+			},
+			{
+				Name:             stepConfirmTrain,
+				Skip:             func(order *Order) bool { return len(order.TrainSeats) == 0 },
+				Command:          confirmSeatCommand,
+				SuccessEvent:     event.SeatHoldConfirmed,
+				FailureEvent:     event.SeatHoldConfirmationFailed,
+				OnSuccess:        s.seatHoldConfirmed,
+				OnFailure:        s.seatHoldConfirmationFailed,
+				Compensation:     always(cancelSeatCommand),
+				CompensatedEvent: event.SeatReservationCancelled,
+				Timeout:          stepTimeout,
+			},
+			s.authorizePaymentStep(stepTimeout),
+			s.capturePaymentStep(stepTimeout, stepConfirmHotel, stepConfirmCar, stepConfirmTrain),
+		},
+		OnCompleted: completeBooking,
+		OnFailed:    failBooking,
+	}
+}
+
+func holdTTLSeconds(order *Order) int {
+	if order.Hold == nil {
+		return 0
+	}
+	return order.Hold.TTLSeconds
+}
+
+func holdRoomCommand(order *Order) event.Message {
+	return event.Message{
+		EventName:     event.CommandHoldRoom,
+		CorrelationID: order.ID,
+		Payload:       event.HoldRoomPayload{Rooms: roomItems(order), TTLSeconds: holdTTLSeconds(order)},
+	}
+}
+
+func holdCarCommand(order *Order) event.Message {
+	return event.Message{
+		EventName:     event.CommandHoldCar,
+		CorrelationID: order.ID,
+		Payload:       event.HoldCarPayload{Cars: carItems(order), TTLSeconds: holdTTLSeconds(order)},
+	}
+}
+
+func holdSeatCommand(order *Order) event.Message {
+	return event.Message{
+		EventName:     event.CommandHoldSeat,
+		CorrelationID: order.ID,
+		Payload:       event.HoldSeatPayload{Seats: seatItems(order), TTLSeconds: holdTTLSeconds(order)},
+	}
+}
+
+// Command konfirmasi hanya berisi OrderID, partisipan mengonfirmasi seluruh hold milik order tersebut
+
+func confirmRoomCommand(order *Order) event.Message {
+	return event.Message{
+		EventName:     event.CommandConfirmRoom,
+		CorrelationID: order.ID,
+		Payload:       event.ConfirmRoomPayload{OrderID: order.ID},
+	}
+}
+
+func confirmCarCommand(order *Order) event.Message {
+	return event.Message{
+		EventName:     event.CommandConfirmCar,
+		CorrelationID: order.ID,
+		Payload:       event.ConfirmCarPayload{OrderID: order.ID},
+	}
+}
+
+func confirmSeatCommand(order *Order) event.Message {
+	return event.Message{
+		EventName:     event.CommandConfirmSeat,
+		CorrelationID: order.ID,
+		Payload:       event.ConfirmSeatPayload{OrderID: order.ID},
+	}
+}
+
+// holdUntil memajukan batas waktu hold order ke expiresAt jika lebih awal
+func holdUntil(order *Order, expiresAt time.Time) {
+	if order.Hold.ExpiresAt.IsZero() || expiresAt.Before(order.Hold.ExpiresAt) {
+		order.Hold.ExpiresAt = expiresAt
+	}
+}
+
+// Balasan step saga hold dicatat ke order. Hold item dalam satu sub-transaksi bersifat
+// atomik seperti reservasinya.
+
+func (s *service) roomHeld(order *Order, msg event.Message) error {
+	var payload event.RoomHeldPayload
+	if err := s.unmarshalPayload(msg.Payload, &payload); err != nil {
+		return err
+	}
+	order.HotelReservationStatus = ReservationStatusHeld
+	for i := range order.HotelRooms {
+		order.HotelRooms[i].Status = ReservationStatusHeld
+		if i < len(payload.RoomReservationIDs) {
+			order.HotelRooms[i].ReservationID = payload.RoomReservationIDs[i]
+		}
+	}
+	holdUntil(order, payload.ExpiresAt)
+	return nil
+}
+
+func (s *service) carHeld(order *Order, msg event.Message) error {
+	var payload event.CarHeldPayload
+	if err := s.unmarshalPayload(msg.Payload, &payload); err != nil {
+		return err
+	}
+	order.CarReservationStatus = ReservationStatusHeld
+	for i := range order.Cars {
+		order.Cars[i].Status = ReservationStatusHeld
+		if i < len(payload.CarReservationIDs) {
+			order.Cars[i].ReservationID = payload.CarReservationIDs[i]
+		}
+	}
+	holdUntil(order, payload.ExpiresAt)
+	return nil
+}
+
+func (s *service) seatHeld(order *Order, msg event.Message) error {
+	var payload event.SeatHeldPayload
+	if err := s.unmarshalPayload(msg.Payload, &payload); err != nil {
+		return err
+	}
+	order.TrainReservationStatus = ReservationStatusHeld
+	for i := range order.TrainSeats {
+		order.TrainSeats[i].Status = ReservationStatusHeld
+		if i < len(payload.SeatReservationIDs) {
+			order.TrainSeats[i].ReservationID = payload.SeatReservationIDs[i]
+		}
+	}
+	holdUntil(order, payload.ExpiresAt)
+	return nil
+}
+
+func (s *service) roomHoldFailed(order *Order, msg event.Message) error {
+	var payload event.RoomHoldFailedPayload
+	if err := s.unmarshalPayload(msg.Payload, &payload); err != nil {
+		return err
+	}
+	order.HotelReservationStatus = ReservationStatusFailed
+	order.HotelReservationFailureReason = payload.FailureReason
+	for i := range order.HotelRooms {
+		order.HotelRooms[i].Status = ReservationStatusFailed
+	}
+	if payload.FailedItem != nil && *payload.FailedItem < len(order.HotelRooms) {
+		order.HotelRooms[*payload.FailedItem].FailureReason = payload.FailureReason
+	}
+	order.HotelDoneAt = time.Now()
+	return nil
+}
+
+func (s *service) carHoldFailed(order *Order, msg event.Message) error {
+	var payload event.CarHoldFailedPayload
+	if err := s.unmarshalPayload(msg.Payload, &payload); err != nil {
+		return err
+	}
+	order.CarReservationStatus = ReservationStatusFailed
+	order.CarReservationFailureReason = payload.FailureReason
+	for i := range order.Cars {
+		order.Cars[i].Status = ReservationStatusFailed
+	}
+	if payload.FailedItem != nil && *payload.FailedItem < len(order.Cars) {
+		order.Cars[*payload.FailedItem].FailureReason = payload.FailureReason
+	}
+	order.CarDoneAt = time.Now()
+	return nil
+}
+
+func (s *service) seatHoldFailed(order *Order, msg event.Message) error {
+	var payload event.SeatHoldFailedPayload
+	if err := s.unmarshalPayload(msg.Payload, &payload); err != nil {
+		return err
+	}
+	order.TrainReservationStatus = ReservationStatusFailed
+	order.TrainReservationFailureReason = payload.FailureReason
+	for i := range order.TrainSeats {
+		order.TrainSeats[i].Status = ReservationStatusFailed
+	}
+	if payload.FailedItem != nil && *payload.FailedItem < len(order.TrainSeats) {
+		order.TrainSeats[*payload.FailedItem].FailureReason = payload.FailureReason
+	}
+	order.TrainDoneAt = time.Now()
+	return nil
+}
+
+// Balasan konfirmasi hold membawa ID reservasi yang sama dengan hold-nya
+
+func (s *service) roomHoldConfirmed(order *Order, msg event.Message) error {
+	var payload event.RoomHoldConfirmedPayload
+	if err := s.unmarshalPayload(msg.Payload, &payload); err != nil {
+		return err
+	}
+	order.HotelReservationStatus = ReservationStatusBooked
+	for i := range order.HotelRooms {
+		order.HotelRooms[i].Status = ReservationStatusBooked
+	}
+	order.HotelDoneAt = time.Now()
+	return nil
+}
+
+func (s *service) carHoldConfirmed(order *Order, msg event.Message) error {
+	var payload event.CarHoldConfirmedPayload
+	if err := s.unmarshalPayload(msg.Payload, &payload); err != nil {
+		return err
+	}
+	order.CarReservationStatus = ReservationStatusBooked
+	for i := range order.Cars {
+		order.Cars[i].Status = ReservationStatusBooked
+	}
+	order.CarDoneAt = time.Now()
+	return nil
+}
+
+func (s *service) seatHoldConfirmed(order *Order, msg event.Message) error {
+	var payload event.SeatHoldConfirmedPayload
+	if err := s.unmarshalPayload(msg.Payload, &payload); err != nil {
+		return err
+	}
+	order.TrainReservationStatus = ReservationStatusBooked
+	for i := range order.TrainSeats {
+		order.TrainSeats[i].Status = ReservationStatusBooked
+	}
+	order.TrainDoneAt = time.Now()
+	return nil
+}
+
+func (s *service) roomHoldConfirmationFailed(order *Order, msg event.Message) error {
+	var payload event.RoomHoldConfirmationFailedPayload
+	if err := s.unmarshalPayload(msg.Payload, &payload); err != nil {
+		return err
+	}
+	order.HotelReservationStatus = ReservationStatusFailed
+	order.HotelReservationFailureReason = payload.FailureReason
+	for i := range order.HotelRooms {
+		order.HotelRooms[i].Status = ReservationStatusFailed
+	}
+	order.HotelDoneAt = time.Now()
+	return nil
+}
+
+func (s *service) carHoldConfirmationFailed(order *Order, msg event.Message) error {
+	var payload event.CarHoldConfirmationFailedPayload
+	if err := s.unmarshalPayload(msg.Payload, &payload); err != nil {
+		return err
+	}
+	order.CarReservationStatus = ReservationStatusFailed
+	order.CarReservationFailureReason = payload.FailureReason
+	for i := range order.Cars {
+		order.Cars[i].Status = ReservationStatusFailed
+	}
+	order.CarDoneAt = time.Now()
+	return nil
+}
+
+func (s *service) seatHoldConfirmationFailed(order *Order, msg event.Message) error {
+	var payload event.SeatHoldConfirmationFailedPayload
+	if err := s.unmarshalPayload(msg.Payload, &payload); err != nil {
+		return err
+	}
+	order.TrainReservationStatus = ReservationStatusFailed
+	order.TrainReservationFailureReason = payload.FailureReason
+	for i := range order.TrainSeats {
+		order.TrainSeats[i].Status = ReservationStatusFailed
+	}
+	order.TrainDoneAt = time.Now()
+	return nil
+}
@@ -1,6 +1,10 @@
 package order
 
-import "time"
+import (
+	"time"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/saga"
+)
 
 // OrderStatus merepresentasikan status dari Saga
 type OrderStatus string
@@ -10,47 +14,253 @@ type ReservationStatus string
 const (
 	StatusPending              OrderStatus = "PENDING"
 	StatusAwaitingConfirmation OrderStatus = "AWAITING_CONFIRMATION"
-	StatusBooked               OrderStatus = "BOOKED"
-	StatusFailed               OrderStatus = "FAILED"
+	// StatusCapturingPayment berarti seluruh reservasi berhasil dan pembayaran sedang di-capture
+	StatusCapturingPayment OrderStatus = "CAPTURING_PAYMENT"
+	StatusBooked           OrderStatus = "BOOKED"
+	StatusFailed           OrderStatus = "FAILED"
+	// StatusCancelling dan StatusCancelled dipakai saat order yang sudah BOOKED dibatalkan customer
+	StatusCancelling OrderStatus = "CANCELLING"
+	StatusCancelled  OrderStatus = "CANCELLED"
+	// StatusModifying berarti satu item order yang sudah BOOKED sedang diganti
+	StatusModifying OrderStatus = "MODIFYING"
+	// StatusHolding, StatusHeld dan StatusHoldExpired dipakai order hold sebelum dikonfirmasi.
+	// Setelah dikonfirmasi order hold melanjutkan status order biasa mulai AWAITING_CONFIRMATION.
+	StatusHolding     OrderStatus = "HOLDING"
+	StatusHeld        OrderStatus = "HELD"
+	StatusHoldExpired OrderStatus = "HOLD_EXPIRED"
 )
 
 const (
 	ReservationStatusPending ReservationStatus = "PENDING"
 	ReservationStatusBooked  ReservationStatus = "BOOKED"
 	ReservationStatusFailed  ReservationStatus = "FAILED"
+	// ReservationStatusCancelled dipakai untuk reservasi yang dilepas karena order dibatalkan customer
+	ReservationStatusCancelled ReservationStatus = "CANCELLED"
+	// ReservationStatusNotRequested dipakai untuk sub-transaksi yang tidak ada di order
+	ReservationStatusNotRequested ReservationStatus = "NOT_REQUESTED"
+	// ReservationStatusHeld dan ReservationStatusExpired dipakai untuk item order hold
+	ReservationStatusHeld    ReservationStatus = "HELD"
+	ReservationStatusExpired ReservationStatus = "EXPIRED"
+)
+
+// PaymentStatus adalah status pembayaran order menurut Order Service
+type PaymentStatus string
+
+const (
+	PaymentStatusPending       PaymentStatus = "PENDING"
+	PaymentStatusAuthorized    PaymentStatus = "AUTHORIZED"
+	PaymentStatusCaptured      PaymentStatus = "CAPTURED"
+	PaymentStatusFailed        PaymentStatus = "FAILED"
+	PaymentStatusCaptureFailed PaymentStatus = "CAPTURE_FAILED"
+	PaymentStatusRefunded      PaymentStatus = "REFUNDED"
+)
+
+// ModificationStatus adalah status penggantian satu item order
+type ModificationStatus string
+
+const (
+	ModificationStatusPending   ModificationStatus = "PENDING"
+	ModificationStatusSucceeded ModificationStatus = "SUCCEEDED"
+	ModificationStatusFailed    ModificationStatus = "FAILED"
+)
+
+// ModificationStep adalah langkah saga modifikasi yang sedang menunggu balasan
+type ModificationStep string
+
+const (
+	// ModificationStepCharging menarik selisih harga positif sebelum item diganti
+	ModificationStepCharging ModificationStep = "CHARGING"
+	// ModificationStepModifying menunggu partisipan pemilik item mengganti reservasinya
+	ModificationStepModifying ModificationStep = "MODIFYING"
+	// ModificationStepRefunding mengembalikan selisih harga negatif setelah item diganti
+	ModificationStepRefunding ModificationStep = "REFUNDING"
+	// ModificationStepReverting mengembalikan selisih harga yang sudah ditarik karena item
+	// gagal diganti
+	ModificationStepReverting ModificationStep = "REVERTING"
 )
 
+// HotelRoomItem adalah satu kamar dalam order beserta status reservasinya. Untuk
+// pesanan tipe kamar, HotelRoomID kosong dan nomor kamar dicatat di reservasi hotel.
+type HotelRoomItem struct {
+	HotelRoomID   string            `firestore:"hotel_room_id,omitempty" json:"hotel_room_id,omitempty"`
+	RoomTypeID    string            `firestore:"room_type_id,omitempty" json:"room_type_id,omitempty"`
+	StartDate     string            `firestore:"start_date" json:"start_date"`
+	EndDate       string            `firestore:"end_date" json:"end_date"`
+	Price         int64             `firestore:"price" json:"price"`
+	ReservationID string            `firestore:"reservation_id,omitempty" json:"reservation_id,omitempty"`
+	Status        ReservationStatus `firestore:"status" json:"status"`
+	FailureReason string            `firestore:"failure_reason,omitempty" json:"failure_reason,omitempty"`
+}
+
+// CarItem adalah satu mobil dalam order beserta status reservasinya
+type CarItem struct {
+	CarID         string            `firestore:"car_id" json:"car_id"`
+	StartDate     string            `firestore:"start_date" json:"start_date"`
+	EndDate       string            `firestore:"end_date" json:"end_date"`
+	Price         int64             `firestore:"price" json:"price"`
+	ReservationID string            `firestore:"reservation_id,omitempty" json:"reservation_id,omitempty"`
+	Status        ReservationStatus `firestore:"status" json:"status"`
+	FailureReason string            `firestore:"failure_reason,omitempty" json:"failure_reason,omitempty"`
+}
+
+// TrainSeatItem adalah satu kursi kereta dalam order beserta status reservasinya
+type TrainSeatItem struct {
+	JourneyID          string            `firestore:"journey_id" json:"journey_id"`
+	DepartureDate      string            `firestore:"departure_date" json:"departure_date"`
+	SeatID             string            `firestore:"seat_id" json:"seat_id"`
+	OriginStation      string            `firestore:"origin_station" json:"origin_station"`
+	DestinationStation string            `firestore:"destination_station" json:"destination_station"`
+	Price              int64             `firestore:"price" json:"price"`
+	ReservationID      string            `firestore:"reservation_id,omitempty" json:"reservation_id,omitempty"`
+	Status             ReservationStatus `firestore:"status" json:"status"`
+	FailureReason      string            `firestore:"failure_reason,omitempty" json:"failure_reason,omitempty"`
+}
+
+// FlightItem adalah satu kursi pesawat dalam order beserta status reservasinya
+type FlightItem struct {
+	FlightID      string            `firestore:"flight_id" json:"flight_id"`
+	DepartureDate string            `firestore:"departure_date" json:"departure_date"`
+	SeatID        string            `firestore:"seat_id" json:"seat_id"`
+	Price         int64             `firestore:"price" json:"price"`
+	ReservationID string            `firestore:"reservation_id,omitempty" json:"reservation_id,omitempty"`
+	Status        ReservationStatus `firestore:"status" json:"status"`
+	FailureReason string            `firestore:"failure_reason,omitempty" json:"failure_reason,omitempty"`
+}
+
+// Modification adalah penggantian item ke-Index pada satu sub-transaksi order yang sudah
+// BOOKED. Hanya satu dari HotelRoom, Car dan TrainSeat yang terisi, yaitu item penggantinya.
+type Modification struct {
+	ID        string         `firestore:"id,omitempty" json:"id,omitempty"`
+	Index     int            `firestore:"index" json:"index"`
+	HotelRoom *HotelRoomItem `firestore:"hotel_room,omitempty" json:"hotel_room,omitempty"`
+	Car       *CarItem       `firestore:"car,omitempty" json:"car,omitempty"`
+	TrainSeat *TrainSeatItem `firestore:"train_seat,omitempty" json:"train_seat,omitempty"`
+
+	// PriceDifference adalah selisih harga item pengganti terhadap item yang diganti.
+	// Total harga order baru berubah setelah selisihnya ditarik atau dikembalikan.
+	PriceDifference int64              `firestore:"price_difference" json:"price_difference"`
+	Status          ModificationStatus `firestore:"status" json:"status"`
+	// Step kosong pada modifikasi yang dibuat sebelum selisih harga ditagih, sama dengan
+	// ModificationStepModifying
+	Step          ModificationStep `firestore:"step,omitempty" json:"step,omitempty"`
+	FailureReason string           `firestore:"failure_reason,omitempty" json:"failure_reason,omitempty"`
+	RequestedAt   time.Time        `firestore:"requested_at" json:"requested_at"`
+	DoneAt        time.Time        `firestore:"done_at,omitempty" json:"done_at,omitempty"`
+}
+
+// Hold adalah batas waktu item order hold ditahan sebelum dikonfirmasi customer
+type Hold struct {
+	// TTLSeconds adalah lama hold yang diminta, 0 berarti HOLD_TTL partisipan
+	TTLSeconds int `firestore:"ttl_seconds,omitempty" json:"ttl_seconds,omitempty"`
+	// ExpiresAt adalah batas waktu hold paling awal dari seluruh sub-transaksi
+	ExpiresAt          time.Time `firestore:"expires_at,omitempty" json:"expires_at,omitempty"`
+	ConfirmRequestedAt time.Time `firestore:"confirm_requested_at,omitempty" json:"confirm_requested_at,omitempty"`
+}
+
 // Order adalah representasi data order di Firestore
 type Order struct {
-	ID             string      `firestore:"id" json:"id"`
-	UserID         string      `firestore:"user_id" json:"user_id"`
-	Status         OrderStatus `firestore:"status" json:"status"`
-	HotelRoomID    string      `firestore:"hotel_room_id" json:"hotel_room_id"`
-	CarID          string      `firestore:"car_id" json:"car_id"`
-	TrainSeatID    string      `firestore:"train_seat_id" json:"train_seat_id"`
-	HotelStartDate string      `firestore:"hotel_start_date" json:"hotel_start_date"`
-	HotelEndDate   string      `firestore:"hotel_end_date" json:"hotel_end_date"`
-	CarStartDate   string      `firestore:"car_start_date" json:"car_start_date"`
-	CarEndDate     string      `firestore:"car_end_date" json:"car_end_date"`
-
-	// Reservation ID dari sub-transaksi
-	HotelReservationID string `firestore:"hotel_reservation_id,omitempty" json:"hotel_reservation_id,omitempty"`
-	CarReservationID   string `firestore:"car_reservation_id,omitempty" json:"car_reservation_id,omitempty"`
-	TrainReservationID string `firestore:"train_reservation_id,omitempty" json:"train_reservation_id,omitempty"`
+	ID     string      `firestore:"id" json:"id"`
+	UserID string      `firestore:"user_id" json:"user_id"`
+	Status OrderStatus `firestore:"status" json:"status"`
+
+	// Item yang dipesan untuk setiap sub-transaksi
+	HotelRooms []HotelRoomItem `firestore:"hotel_rooms" json:"hotel_rooms"`
+	Cars       []CarItem       `firestore:"cars" json:"cars"`
+	TrainSeats []TrainSeatItem `firestore:"train_seats" json:"train_seats"`
+	Flights    []FlightItem    `firestore:"flights" json:"flights"`
+
+	// Harga yang dikunci saat order dibuat, berasal dari quote jika QuoteID terisi
+	QuoteID    string `firestore:"quote_id,omitempty" json:"quote_id,omitempty"`
+	TotalPrice int64  `firestore:"total_price" json:"total_price"`
+	Currency   string `firestore:"currency" json:"currency"`
 
 	// Status untuk setiap sub-transaksi
-	HotelReservationStatus        ReservationStatus `firestore:"hotel_reservation_status" json:"hotel_reservation_status"`
-	CarReservationStatus          ReservationStatus `firestore:"car_reservation_status" json:"car_reservation_status"`
-	TrainReservationStatus        ReservationStatus `firestore:"train_reservation_status" json:"train_reservation_status"`
-	HotelReservationFailureReason string            `firestore:"hotel_reservation_failure_reason,omitempty" json:"hotel_reservation_failure_reason,omitempty"`
-	CarReservationFailureReason   string            `firestore:"car_reservation_failure_reason,omitempty" json:"car_reservation_failure_reason,omitempty"`
-	TrainReservationFailureReason string            `firestore:"train_reservation_failure_reason,omitempty" json:"train_reservation_failure_reason,omitempty"`
-
-	CarDoneAt   time.Time `firestore:"car_done_at,omitempty" json:"car_done_at,omitempty"`
-	TrainDoneAt time.Time `firestore:"train_done_at,omitempty" json:"train_done_at,omitempty"`
-	HotelDoneAt time.Time `firestore:"hotel_done_at,omitempty" json:"hotel_done_at,omitempty"`
-	DoneAt      time.Time `firestore:"done_at,omitempty" json:"done_at,omitempty"`
+	HotelReservationStatus         ReservationStatus `firestore:"hotel_reservation_status" json:"hotel_reservation_status"`
+	CarReservationStatus           ReservationStatus `firestore:"car_reservation_status" json:"car_reservation_status"`
+	TrainReservationStatus         ReservationStatus `firestore:"train_reservation_status" json:"train_reservation_status"`
+	FlightReservationStatus        ReservationStatus `firestore:"flight_reservation_status" json:"flight_reservation_status"`
+	HotelReservationFailureReason  string            `firestore:"hotel_reservation_failure_reason,omitempty" json:"hotel_reservation_failure_reason,omitempty"`
+	CarReservationFailureReason    string            `firestore:"car_reservation_failure_reason,omitempty" json:"car_reservation_failure_reason,omitempty"`
+	TrainReservationFailureReason  string            `firestore:"train_reservation_failure_reason,omitempty" json:"train_reservation_failure_reason,omitempty"`
+	FlightReservationFailureReason string            `firestore:"flight_reservation_failure_reason,omitempty" json:"flight_reservation_failure_reason,omitempty"`
+
+	// Pembayaran diotorisasi bersamaan dengan reservasi dan di-capture setelah seluruh reservasi berhasil
+	PaymentStatus        PaymentStatus `firestore:"payment_status" json:"payment_status"`
+	PaymentID            string        `firestore:"payment_id,omitempty" json:"payment_id,omitempty"`
+	PaymentFailureReason string        `firestore:"payment_failure_reason,omitempty" json:"payment_failure_reason,omitempty"`
+
+	// Biaya dan nominal refund yang ditetapkan saat order dibatalkan customer
+	CancellationFee int64 `firestore:"cancellation_fee,omitempty" json:"cancellation_fee,omitempty"`
+	RefundAmount    int64 `firestore:"refund_amount,omitempty" json:"refund_amount,omitempty"`
+
+	CarDoneAt     time.Time `firestore:"car_done_at,omitempty" json:"car_done_at,omitempty"`
+	TrainDoneAt   time.Time `firestore:"train_done_at,omitempty" json:"train_done_at,omitempty"`
+	FlightDoneAt  time.Time `firestore:"flight_done_at,omitempty" json:"flight_done_at,omitempty"`
+	HotelDoneAt   time.Time `firestore:"hotel_done_at,omitempty" json:"hotel_done_at,omitempty"`
+	PaymentDoneAt time.Time `firestore:"payment_done_at,omitempty" json:"payment_done_at,omitempty"`
+	DoneAt        time.Time `firestore:"done_at,omitempty" json:"done_at,omitempty"`
+
+	// Modification adalah modifikasi terakhir yang diminta customer
+	Modification *Modification `firestore:"modification,omitempty" json:"modification,omitempty"`
+
+	// Hold terisi untuk order yang itemnya ditahan lebih dulu dan baru dibayar setelah
+	// dikonfirmasi customer
+	Hold *Hold `firestore:"hold,omitempty" json:"hold,omitempty"`
+
+	// Saga adalah state saga booking order, riwayat transisinya disimpan di saga.StepCollection
+	Saga saga.State `firestore:"saga" json:"saga"`
+	// SagaMode adalah mode saga booking saat order dibuat (config.SagaMode), dipakai untuk
+	// membandingkan hasil pengujian kedua mode
+	SagaMode string `firestore:"saga_mode,omitempty" json:"saga_mode,omitempty"`
+
+	CancelRequestedAt time.Time `firestore:"cancel_requested_at,omitempty" json:"cancel_requested_at,omitempty"`
+	CancelledAt       time.Time `firestore:"cancelled_at,omitempty" json:"cancelled_at,omitempty"`
 
 	CreatedAt time.Time `firestore:"created_at" json:"created_at"`
 	UpdatedAt time.Time `firestore:"updated_at" json:"updated_at"`
 }
+
+func (o *Order) SagaID() string {
+	return o.ID
+}
+
+func (o *Order) SagaState() *saga.State {
+	return &o.Saga
+}
+
+// legStatus mengembalikan status awal sub-transaksi berdasarkan ada tidaknya item
+func legStatus(itemCount int) ReservationStatus {
+	if itemCount == 0 {
+		return ReservationStatusNotRequested
+	}
+	return ReservationStatusPending
+}
+
+// RequestedLegStatuses mengembalikan status dari sub-transaksi yang ada di order saja.
+// Order yang dibuat sebelum ada penerbangan tidak memiliki status penerbangan.
+func (o *Order) RequestedLegStatuses() []ReservationStatus {
+	var statuses []ReservationStatus
+	for _, status := range []ReservationStatus{o.HotelReservationStatus, o.CarReservationStatus, o.TrainReservationStatus, o.FlightReservationStatus} {
+		if status != ReservationStatusNotRequested && status != "" {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
+
+// CancellationPolicy menentukan biaya pembatalan order yang sudah BOOKED
+type CancellationPolicy struct {
+	// FreeWindow adalah lama waktu sejak order BOOKED di mana pembatalan tidak dikenai biaya,
+	// termasuk tepat pada akhir FreeWindow
+	FreeWindow time.Duration
+	// FeePercent adalah biaya pembatalan setelah FreeWindow, dalam persen dari total harga
+	FeePercent int64
+}
+
+// Fee menghitung biaya pembatalan order jika dibatalkan pada waktu now
+func (p CancellationPolicy) Fee(order *Order, now time.Time) int64 {
+	if !now.After(order.DoneAt.Add(p.FreeWindow)) {
+		return 0
+	}
+	return order.TotalPrice * p.FeePercent / 100
+}
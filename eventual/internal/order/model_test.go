@@ -0,0 +1,38 @@
+package order
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCancellationPolicyFee(t *testing.T) {
+	policy := CancellationPolicy{FreeWindow: 24 * time.Hour, FeePercent: 10}
+	bookedAt := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want int64
+	}{
+		{name: "right after booking", now: bookedAt, want: 0},
+		{name: "inside free window", now: bookedAt.Add(23 * time.Hour), want: 0},
+		{name: "window boundary", now: bookedAt.Add(24 * time.Hour), want: 0},
+		{name: "right after free window", now: bookedAt.Add(24*time.Hour + time.Second), want: 1500},
+		{name: "after free window", now: bookedAt.Add(48 * time.Hour), want: 1500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := &Order{TotalPrice: 15000, DoneAt: bookedAt}
+			if got := policy.Fee(order, tt.now); got != tt.want {
+				t.Errorf("Fee() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("fee rounds down", func(t *testing.T) {
+		order := &Order{TotalPrice: 999, DoneAt: bookedAt}
+		if got := policy.Fee(order, bookedAt.Add(25*time.Hour)); got != 99 {
+			t.Errorf("Fee() = %d, want 99", got)
+		}
+	})
+}
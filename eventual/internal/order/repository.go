@@ -2,16 +2,27 @@ package order
 
 import (
 	"context"
+	"errors"
+	"slices"
 	"time"
 
 	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+var ErrOrderNotFound = errors.New("order not found")
+
 // Repository mendefinisikan interface untuk persistensi data Order
 type Repository interface {
 	CreateOrder(ctx context.Context, order *Order) error
 	GetOrderByID(ctx context.Context, id string) (*Order, error)
 	UpdateOrder(ctx context.Context, order *Order) error
+	// ListOrders mengembalikan paling banyak limit order berstatus status, atau order dengan
+	// status apa pun jika status kosong. Order yang terbaca diurutkan dari yang terbaru.
+	ListOrders(ctx context.Context, status OrderStatus, limit int) ([]*Order, error)
+	// ListExpiredSagas mengembalikan order yang saga booking-nya punya step melewati batas waktu pada now
+	ListExpiredSagas(ctx context.Context, now time.Time) ([]*Order, error)
 }
 
 const (
@@ -34,6 +45,9 @@ func (r *firestoreRepository) CreateOrder(ctx context.Context, order *Order) err
 
 func (r *firestoreRepository) GetOrderByID(ctx context.Context, id string) (*Order, error) {
 	doc, err := r.client.Collection(collectionName).Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, ErrOrderNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -55,3 +69,47 @@ func (r *firestoreRepository) UpdateOrder(ctx context.Context, order *Order) err
 	_, err = r.client.Collection(collectionName).Doc(order.ID).Set(ctx, order)
 	return err
 }
+
+// ListOrders tidak memakai OrderBy agar filter status tidak membutuhkan composite index,
+// sehingga limit tidak selalu memilih order terbaru
+func (r *firestoreRepository) ListOrders(ctx context.Context, status OrderStatus, limit int) ([]*Order, error) {
+	query := r.client.Collection(collectionName).Query
+	if status != "" {
+		query = query.Where("status", "==", status)
+	}
+
+	docs, err := query.Limit(limit).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	orders := make([]*Order, 0, len(docs))
+	for _, doc := range docs {
+		var order Order
+		if err := doc.DataTo(&order); err != nil {
+			return nil, err
+		}
+		orders = append(orders, &order)
+	}
+	slices.SortFunc(orders, func(a, b *Order) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	return orders, nil
+}
+
+func (r *firestoreRepository) ListExpiredSagas(ctx context.Context, now time.Time) ([]*Order, error) {
+	docs, err := r.client.Collection(collectionName).Where("saga.deadline", "<=", now).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	orders := make([]*Order, 0, len(docs))
+	for _, doc := range docs {
+		var order Order
+		if err := doc.DataTo(&order); err != nil {
+			return nil, err
+		}
+		orders = append(orders, &order)
+	}
+	return orders, nil
+}
@@ -0,0 +1,547 @@
+package order
+
+import (
+	"context"
+	"time"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/event"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/saga"
+)
+
+// Nama step saga booking, dicatat di riwayat saga
+const (
+	stepReserveHotel     = "reserve_hotel"
+	stepReserveCar       = "reserve_car"
+	stepReserveTrain     = "reserve_train"
+	stepReserveFlight    = "reserve_flight"
+	stepAuthorizePayment = "authorize_payment"
+	stepCapturePayment   = "capture_payment"
+)
+
+// bookingSaga mendefinisikan saga booking. Setiap sub-transaksi direservasi bersamaan
+// dengan otorisasi pembayaran, lalu pembayaran di-capture setelah semuanya berhasil.
+// Sub-transaksi baru cukup ditambahkan sebagai step reservasi dan syarat capture.
+func (s *service) bookingSaga(stepTimeout time.Duration) saga.Definition[*Order] {
+	return saga.Definition[*Order]{
+		Name: "booking",
+		Steps: []saga.Step[*Order]{
+			{
+				Name:             stepReserveHotel,
+				Skip:             func(order *Order) bool { return len(order.HotelRooms) == 0 },
+				Command:          reserveRoomCommand,
+				SuccessEvent:     event.RoomReserved,
+				FailureEvent:     event.RoomReservationFailed,
+				OnSuccess:        s.roomReserved,
+				OnFailure:        s.roomReservationFailed,
+				Compensation:     always(cancelRoomCommand),
+				CompensatedEvent: event.RoomReservationCancelled,
+				Timeout:          stepTimeout,
+			},
+			{
+				Name:             stepReserveCar,
+				Skip:             func(order *Order) bool { return len(order.Cars) == 0 },
+				Command:          reserveCarCommand,
+				SuccessEvent:     event.CarReserved,
+				FailureEvent:     event.CarReservationFailed,
+				OnSuccess:        s.carReserved,
+				OnFailure:        s.carReservationFailed,
+				Compensation:     always(cancelCarCommand),
+				CompensatedEvent: event.CarReservationCancelled,
+				Timeout:          stepTimeout,
+			},
+			{
+				Name:             stepReserveTrain,
+				Skip:             func(order *Order) bool { return len(order.TrainSeats) == 0 },
+				Command:          reserveSeatCommand,
+				SuccessEvent:     event.SeatReserved,
+				FailureEvent:     event.SeatReservationFailed,
+				OnSuccess:        s.seatReserved,
+				OnFailure:        s.seatReservationFailed,
+				Compensation:     always(cancelSeatCommand),
+				CompensatedEvent: event.SeatReservationCancelled,
+				Timeout:          stepTimeout,
+			},
+			{
+				Name:             stepReserveFlight,
+				Skip:             func(order *Order) bool { return len(order.Flights) == 0 },
+				Command:          reserveFlightCommand,
+				SuccessEvent:     event.FlightReserved,
+				FailureEvent:     event.FlightReservationFailed,
+				OnSuccess:        s.flightReserved,
+				OnFailure:        s.flightReservationFailed,
+				Compensation:     always(cancelFlightCommand),
+				CompensatedEvent: event.FlightReservationCancelled,
+				Timeout:          stepTimeout,
+			},
+			s.authorizePaymentStep(stepTimeout),
+			s.capturePaymentStep(stepTimeout, stepReserveHotel, stepReserveCar, stepReserveTrain, stepReserveFlight),
+		},
+		OnCompleted: completeBooking,
+		OnFailed:    failBooking,
+	}
+}
+
+// authorizePaymentStep mengotorisasi pembayaran sebesar total harga yang dikunci. Void
+// tetap dikirim saat otorisasi belum dibalas agar otorisasi yang datang terlambat ikut
+// dibatalkan, void tidak dibalas payment service. Pembayaran yang sudah di-capture
+// di-refund oleh kompensasi step capture.
+func (s *service) authorizePaymentStep(stepTimeout time.Duration) saga.Step[*Order] {
+	return saga.Step[*Order]{
+		Name:         stepAuthorizePayment,
+		Command:      authorizePaymentCommand,
+		SuccessEvent: event.PaymentAuthorized,
+		FailureEvent: event.PaymentFailed,
+		OnSuccess:    s.paymentAuthorized,
+		OnFailure:    s.paymentFailed,
+		Compensation: func(order *Order) (event.Message, bool) {
+			switch order.PaymentStatus {
+			case PaymentStatusPending, PaymentStatusAuthorized, PaymentStatusCaptureFailed:
+				return voidPaymentCommand(order), true
+			}
+			return event.Message{}, false
+		},
+		Timeout: stepTimeout,
+	}
+}
+
+// capturePaymentStep meng-capture pembayaran setelah otorisasi dan seluruh step legs berhasil
+func (s *service) capturePaymentStep(stepTimeout time.Duration, legs ...string) saga.Step[*Order] {
+	return saga.Step[*Order]{
+		Name:     stepCapturePayment,
+		Requires: append(legs, stepAuthorizePayment),
+		OnStart: func(order *Order) {
+			order.Status = StatusCapturingPayment
+		},
+		Command:      capturePaymentCommand,
+		SuccessEvent: event.PaymentCaptured,
+		FailureEvent: event.PaymentCaptureFailed,
+		OnSuccess:    s.paymentCaptured,
+		OnFailure:    s.paymentCaptureFailed,
+		// Pembayaran yang sudah di-capture di-refund, selain itu cukup di-void
+		Compensation: func(order *Order) (event.Message, bool) {
+			if order.PaymentStatus != PaymentStatusCaptured {
+				return event.Message{}, false
+			}
+			return refundPaymentCommand(order, order.TotalPrice), true
+		},
+		CompensatedEvent: event.PaymentRefunded,
+		Timeout:          stepTimeout,
+	}
+}
+
+func completeBooking(order *Order) event.Message {
+	order.Status = StatusBooked
+	order.DoneAt = time.Now()
+	return orderBookedEvent(order)
+}
+
+func failBooking(order *Order, reason string) event.Message {
+	order.Status = StatusFailed
+	return orderFailedEvent(order)
+}
+
+func orderBookedEvent(order *Order) event.Message {
+	return event.Message{
+		EventName:     event.OrderBooked,
+		CorrelationID: order.ID,
+		Payload:       event.OrderBookedPayload{OrderID: order.ID},
+	}
+}
+
+func orderFailedEvent(order *Order) event.Message {
+	return event.Message{
+		EventName:     event.OrderFailed,
+		CorrelationID: order.ID,
+		Payload:       event.OrderFailedPayload{OrderID: order.ID},
+	}
+}
+
+// always dipakai untuk step yang selalu dikompensasi dengan command yang sama
+func always(command func(order *Order) event.Message) func(order *Order) (event.Message, bool) {
+	return func(order *Order) (event.Message, bool) {
+		return command(order), true
+	}
+}
+
+// sagaStore menyimpan state saga bersama order-nya. match memilih order yang sedang
+// menjalankan saga milik store, karena seluruh saga disimpan di field Saga yang sama.
+type sagaStore struct {
+	repo  Repository
+	match func(order *Order) bool
+}
+
+func (s sagaStore) Save(ctx context.Context, order *Order) error {
+	return s.repo.UpdateOrder(ctx, order)
+}
+
+func (s sagaStore) Expired(ctx context.Context, now time.Time) ([]*Order, error) {
+	orders, err := s.repo.ListExpiredSagas(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	matched := orders[:0]
+	for _, order := range orders {
+		if s.match(order) {
+			matched = append(matched, order)
+		}
+	}
+	return matched, nil
+}
+
+// isBooking, isHolding dan isConfirmingHold melaporkan saga yang sedang dijalankan order:
+// saga booking, saga hold sebelum dikonfirmasi, atau saga konfirmasi hold
+
+func isBooking(order *Order) bool {
+	return order.Hold == nil
+}
+
+func isHolding(order *Order) bool {
+	return order.Hold != nil && order.Hold.ConfirmRequestedAt.IsZero()
+}
+
+func isConfirmingHold(order *Order) bool {
+	return order.Hold != nil && !order.Hold.ConfirmRequestedAt.IsZero()
+}
+
+// Command reservasi berisi seluruh item sub-transaksi. Reservasi item dalam satu
+// sub-transaksi bersifat atomik.
+
+func reserveRoomCommand(order *Order) event.Message {
+	return event.Message{
+		EventName:     event.CommandReserveRoom,
+		CorrelationID: order.ID,
+		Payload:       event.ReserveRoomPayload{Rooms: roomItems(order)},
+	}
+}
+
+func roomItems(order *Order) []event.RoomItem {
+	rooms := make([]event.RoomItem, 0, len(order.HotelRooms))
+	for _, room := range order.HotelRooms {
+		rooms = append(rooms, event.RoomItem{
+			RoomID:     room.HotelRoomID,
+			RoomTypeID: room.RoomTypeID,
+			StartDate:  room.StartDate,
+			EndDate:    room.EndDate,
+			Price:      room.Price,
+		})
+	}
+	return rooms
+}
+
+func reserveCarCommand(order *Order) event.Message {
+	return event.Message{
+		EventName:     event.CommandReserveCar,
+		CorrelationID: order.ID,
+		Payload:       event.ReserveCarPayload{Cars: carItems(order)},
+	}
+}
+
+func carItems(order *Order) []event.CarItem {
+	cars := make([]event.CarItem, 0, len(order.Cars))
+	for _, car := range order.Cars {
+		cars = append(cars, event.CarItem{
+			CarID:     car.CarID,
+			StartDate: car.StartDate,
+			EndDate:   car.EndDate,
+			Price:     car.Price,
+		})
+	}
+	return cars
+}
+
+func reserveSeatCommand(order *Order) event.Message {
+	return event.Message{
+		EventName:     event.CommandReserveSeat,
+		CorrelationID: order.ID,
+		Payload:       event.ReserveSeatPayload{Seats: seatItems(order)},
+	}
+}
+
+func seatItems(order *Order) []event.SeatItem {
+	seats := make([]event.SeatItem, 0, len(order.TrainSeats))
+	for _, seat := range order.TrainSeats {
+		seats = append(seats, event.SeatItem{
+			JourneyID:          seat.JourneyID,
+			DepartureDate:      seat.DepartureDate,
+			SeatID:             seat.SeatID,
+			OriginStation:      seat.OriginStation,
+			DestinationStation: seat.DestinationStation,
+			Price:              seat.Price,
+		})
+	}
+	return seats
+}
+
+func reserveFlightCommand(order *Order) event.Message {
+	return event.Message{
+		EventName:     event.CommandReserveFlight,
+		CorrelationID: order.ID,
+		Payload:       event.ReserveFlightPayload{Flights: flightItems(order)},
+	}
+}
+
+func flightItems(order *Order) []event.FlightItem {
+	flights := make([]event.FlightItem, 0, len(order.Flights))
+	for _, flight := range order.Flights {
+		flights = append(flights, event.FlightItem{
+			FlightID:      flight.FlightID,
+			DepartureDate: flight.DepartureDate,
+			SeatID:        flight.SeatID,
+			Price:         flight.Price,
+		})
+	}
+	return flights
+}
+
+func authorizePaymentCommand(order *Order) event.Message {
+	return event.Message{
+		EventName:     event.CommandAuthorizePayment,
+		CorrelationID: order.ID,
+		Payload: event.AuthorizePaymentPayload{
+			UserID:   order.UserID,
+			Amount:   order.TotalPrice,
+			Currency: order.Currency,
+		},
+	}
+}
+
+func capturePaymentCommand(order *Order) event.Message {
+	return event.Message{
+		EventName:     event.CommandCapturePayment,
+		CorrelationID: order.ID,
+		Payload:       event.CapturePaymentPayload{OrderID: order.ID},
+	}
+}
+
+// Command pembatalan hanya berisi OrderID, partisipan membatalkan seluruh reservasi
+// milik order tersebut. Dipakai untuk kompensasi dan pembatalan order yang sudah BOOKED.
+
+func cancelRoomCommand(order *Order) event.Message {
+	return event.Message{
+		EventName:     event.CommandCancelRoom,
+		CorrelationID: order.ID,
+		Payload:       event.CancelRoomPayload{OrderID: order.ID},
+	}
+}
+
+func cancelCarCommand(order *Order) event.Message {
+	return event.Message{
+		EventName:     event.CommandCancelCar,
+		CorrelationID: order.ID,
+		Payload:       event.CancelCarPayload{OrderID: order.ID},
+	}
+}
+
+func cancelSeatCommand(order *Order) event.Message {
+	return event.Message{
+		EventName:     event.CommandCancelSeat,
+		CorrelationID: order.ID,
+		Payload:       event.CancelSeatPayload{OrderID: order.ID},
+	}
+}
+
+func cancelFlightCommand(order *Order) event.Message {
+	return event.Message{
+		EventName:     event.CommandCancelFlight,
+		CorrelationID: order.ID,
+		Payload:       event.CancelFlightPayload{OrderID: order.ID},
+	}
+}
+
+func voidPaymentCommand(order *Order) event.Message {
+	return event.Message{
+		EventName:     event.CommandVoidPayment,
+		CorrelationID: order.ID,
+		Payload:       event.VoidPaymentPayload{OrderID: order.ID},
+	}
+}
+
+func refundPaymentCommand(order *Order, amount int64) event.Message {
+	return event.Message{
+		EventName:     event.CommandRefundPayment,
+		CorrelationID: order.ID,
+		Payload:       event.RefundPaymentPayload{OrderID: order.ID, Amount: amount},
+	}
+}
+
+// Balasan step saga booking dicatat ke order. Reservasi item dalam satu sub-transaksi
+// bersifat atomik, sehingga jika gagal seluruh item ditandai FAILED dan alasan dicatat
+// pada item penyebabnya.
+
+func (s *service) roomReserved(order *Order, msg event.Message) error {
+	var payload event.RoomReservedPayload
+	if err := s.unmarshalPayload(msg.Payload, &payload); err != nil {
+		return err
+	}
+	order.HotelReservationStatus = ReservationStatusBooked
+	for i := range order.HotelRooms {
+		order.HotelRooms[i].Status = ReservationStatusBooked
+		if i < len(payload.RoomReservationIDs) {
+			order.HotelRooms[i].ReservationID = payload.RoomReservationIDs[i]
+		}
+	}
+	order.HotelDoneAt = time.Now()
+	return nil
+}
+
+func (s *service) carReserved(order *Order, msg event.Message) error {
+	var payload event.CarReservedPayload
+	if err := s.unmarshalPayload(msg.Payload, &payload); err != nil {
+		return err
+	}
+	order.CarReservationStatus = ReservationStatusBooked
+	for i := range order.Cars {
+		order.Cars[i].Status = ReservationStatusBooked
+		if i < len(payload.CarReservationIDs) {
+			order.Cars[i].ReservationID = payload.CarReservationIDs[i]
+		}
+	}
+	order.CarDoneAt = time.Now()
+	return nil
+}
+
+func (s *service) seatReserved(order *Order, msg event.Message) error {
+	var payload event.SeatReservedPayload
+	if err := s.unmarshalPayload(msg.Payload, &payload); err != nil {
+		return err
+	}
+	order.TrainReservationStatus = ReservationStatusBooked
+	for i := range order.TrainSeats {
+		order.TrainSeats[i].Status = ReservationStatusBooked
+		if i < len(payload.SeatReservationIDs) {
+			order.TrainSeats[i].ReservationID = payload.SeatReservationIDs[i]
+		}
+	}
+	order.TrainDoneAt = time.Now()
+	return nil
+}
+
+func (s *service) flightReserved(order *Order, msg event.Message) error {
+	var payload event.FlightReservedPayload
+	if err := s.unmarshalPayload(msg.Payload, &payload); err != nil {
+		return err
+	}
+	order.FlightReservationStatus = ReservationStatusBooked
+	for i := range order.Flights {
+		order.Flights[i].Status = ReservationStatusBooked
+		if i < len(payload.FlightReservationIDs) {
+			order.Flights[i].ReservationID = payload.FlightReservationIDs[i]
+		}
+	}
+	order.FlightDoneAt = time.Now()
+	return nil
+}
+
+func (s *service) roomReservationFailed(order *Order, msg event.Message) error {
+	var payload event.RoomReservationFailedPayload
+	if err := s.unmarshalPayload(msg.Payload, &payload); err != nil {
+		return err
+	}
+	order.HotelReservationStatus = ReservationStatusFailed
+	order.HotelReservationFailureReason = payload.FailureReason
+	for i := range order.HotelRooms {
+		order.HotelRooms[i].Status = ReservationStatusFailed
+	}
+	if payload.FailedItem != nil && *payload.FailedItem < len(order.HotelRooms) {
+		order.HotelRooms[*payload.FailedItem].FailureReason = payload.FailureReason
+	}
+	order.HotelDoneAt = time.Now()
+	return nil
+}
+
+func (s *service) carReservationFailed(order *Order, msg event.Message) error {
+	var payload event.CarReservationFailedPayload
+	if err := s.unmarshalPayload(msg.Payload, &payload); err != nil {
+		return err
+	}
+	order.CarReservationStatus = ReservationStatusFailed
+	order.CarReservationFailureReason = payload.FailureReason
+	for i := range order.Cars {
+		order.Cars[i].Status = ReservationStatusFailed
+	}
+	if payload.FailedItem != nil && *payload.FailedItem < len(order.Cars) {
+		order.Cars[*payload.FailedItem].FailureReason = payload.FailureReason
+	}
+	order.CarDoneAt = time.Now()
+	return nil
+}
+
+func (s *service) seatReservationFailed(order *Order, msg event.Message) error {
+	var payload event.SeatReservationFailedPayload
+	if err := s.unmarshalPayload(msg.Payload, &payload); err != nil {
+		return err
+	}
+	order.TrainReservationStatus = ReservationStatusFailed
+	order.TrainReservationFailureReason = payload.FailureReason
+	for i := range order.TrainSeats {
+		order.TrainSeats[i].Status = ReservationStatusFailed
+	}
+	if payload.FailedItem != nil && *payload.FailedItem < len(order.TrainSeats) {
+		order.TrainSeats[*payload.FailedItem].FailureReason = payload.FailureReason
+	}
+	order.TrainDoneAt = time.Now()
+	return nil
+}
+
+func (s *service) flightReservationFailed(order *Order, msg event.Message) error {
+	var payload event.FlightReservationFailedPayload
+	if err := s.unmarshalPayload(msg.Payload, &payload); err != nil {
+		return err
+	}
+	order.FlightReservationStatus = ReservationStatusFailed
+	order.FlightReservationFailureReason = payload.FailureReason
+	for i := range order.Flights {
+		order.Flights[i].Status = ReservationStatusFailed
+	}
+	if payload.FailedItem != nil && *payload.FailedItem < len(order.Flights) {
+		order.Flights[*payload.FailedItem].FailureReason = payload.FailureReason
+	}
+	order.FlightDoneAt = time.Now()
+	return nil
+}
+
+func (s *service) paymentAuthorized(order *Order, msg event.Message) error {
+	var payload event.PaymentAuthorizedPayload
+	if err := s.unmarshalPayload(msg.Payload, &payload); err != nil {
+		return err
+	}
+	// Balasan otorisasi yang datang terlambat tidak boleh menimpa status capture
+	if order.PaymentStatus == PaymentStatusPending {
+		order.PaymentStatus = PaymentStatusAuthorized
+		order.PaymentID = payload.PaymentID
+	}
+	return nil
+}
+
+func (s *service) paymentFailed(order *Order, msg event.Message) error {
+	var payload event.PaymentFailedPayload
+	if err := s.unmarshalPayload(msg.Payload, &payload); err != nil {
+		return err
+	}
+	order.PaymentStatus = PaymentStatusFailed
+	order.PaymentFailureReason = payload.FailureReason
+	order.PaymentDoneAt = time.Now()
+	return nil
+}
+
+func (s *service) paymentCaptured(order *Order, msg event.Message) error {
+	var payload event.PaymentCapturedPayload
+	if err := s.unmarshalPayload(msg.Payload, &payload); err != nil {
+		return err
+	}
+	order.PaymentStatus = PaymentStatusCaptured
+	order.PaymentID = payload.PaymentID
+	order.PaymentDoneAt = time.Now()
+	return nil
+}
+
+func (s *service) paymentCaptureFailed(order *Order, msg event.Message) error {
+	var payload event.PaymentCaptureFailedPayload
+	if err := s.unmarshalPayload(msg.Payload, &payload); err != nil {
+		return err
+	}
+	order.PaymentStatus = PaymentStatusCaptureFailed
+	order.PaymentFailureReason = payload.FailureReason
+	order.PaymentDoneAt = time.Now()
+	return nil
+}
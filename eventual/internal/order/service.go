@@ -3,93 +3,318 @@ package order
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/oklog/ulid/v2"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/internal/pricing"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/config"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/event"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/messagebus"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/saga"
 )
 
+// HotelRoomRequest memesan satu kamar fisik (HotelRoomID) atau satu unit tipe kamar
+// (RoomTypeID). Nomor kamar untuk pesanan tipe kamar ditetapkan belakangan oleh hotel.
+type HotelRoomRequest struct {
+	HotelRoomID string `json:"hotel_room_id,omitempty" binding:"required_without=RoomTypeID,excluded_with=RoomTypeID"`
+	RoomTypeID  string `json:"room_type_id,omitempty" binding:"required_without=HotelRoomID"`
+	StartDate   string `json:"start_date" binding:"required"`
+	EndDate     string `json:"end_date" binding:"required"`
+}
+
+type CarRequest struct {
+	CarID     string `json:"car_id" binding:"required"`
+	StartDate string `json:"start_date" binding:"required"`
+	EndDate   string `json:"end_date" binding:"required"`
+}
+
+type TrainSeatRequest struct {
+	JourneyID          string `json:"journey_id" binding:"required"`
+	DepartureDate      string `json:"departure_date" binding:"required"`
+	SeatID             string `json:"seat_id" binding:"required"`
+	OriginStation      string `json:"origin_station" binding:"required"`
+	DestinationStation string `json:"destination_station" binding:"required"`
+}
+
+type FlightRequest struct {
+	FlightID      string `json:"flight_id" binding:"required"`
+	DepartureDate string `json:"departure_date" binding:"required"`
+	SeatID        string `json:"seat_id" binding:"required"`
+}
+
+// CreateOrderPayload berisi daftar item untuk setiap sub-transaksi. Setiap sub-transaksi
+// bersifat opsional, tetapi order minimal berisi satu item. Seluruh item dipesan
+// secara atomik, satu item gagal berarti seluruh order gagal. Jika QuoteID diisi,
+// item harus sama dengan item di quote dan harga quote yang dipakai.
 type CreateOrderPayload struct {
-	HotelRoomID        string `json:"hotel_room_id" binding:"required"`
-	HotelRoomStartDate string `json:"hotel_room_start_date" binding:"required"`
-	HotelRoomEndDate   string `json:"hotel_room_end_date" binding:"required"`
-	CarID              string `json:"car_id" binding:"required"`
-	CarStartDate       string `json:"car_start_date" binding:"required"`
-	CarEndDate         string `json:"car_end_date" binding:"required"`
-	TrainSeatID        string `json:"train_seat_id" binding:"required"`
-	UserID             string `json:"user_id" binding:"required"`
+	HotelRooms []HotelRoomRequest `json:"hotel_rooms" binding:"omitempty,dive"`
+	Cars       []CarRequest       `json:"cars" binding:"omitempty,dive"`
+	TrainSeats []TrainSeatRequest `json:"train_seats" binding:"omitempty,dive"`
+	Flights    []FlightRequest    `json:"flights" binding:"omitempty,dive"`
+	UserID     string             `json:"user_id" binding:"required"`
+	QuoteID    string             `json:"quote_id"`
+}
+
+// ModifyOrderPayload mengganti item ke-Index pada satu sub-transaksi order yang sudah
+// BOOKED. Tepat satu dari HotelRoom, Car dan TrainSeat harus diisi. Item pengganti
+// diberi harga dengan tarif saat ini. Flight hanya ada agar modifikasi kursi pesawat
+// ditolak dengan ErrFlightNotModifiable.
+type ModifyOrderPayload struct {
+	Index     int               `json:"index" binding:"min=0"`
+	HotelRoom *HotelRoomRequest `json:"hotel_room"`
+	Car       *CarRequest       `json:"car"`
+	TrainSeat *TrainSeatRequest `json:"train_seat"`
+	Flight    *FlightRequest    `json:"flight"`
 }
 
+var (
+	ErrEmptyOrder          = errors.New("order must contain at least one hotel room, car, train seat or flight seat")
+	ErrOrderNotCancellable = errors.New("only booked orders can be cancelled")
+	ErrOrderNotModifiable  = errors.New("only booked orders can be modified")
+	ErrInvalidModification = errors.New("modification must replace exactly one hotel room, car or train seat")
+	ErrFlightNotModifiable = errors.New("flight seats cannot be modified, cancel the order and book again")
+	ErrItemNotFound        = errors.New("order has no item at the given index")
+	ErrSagaNotRetryable    = errors.New("only pending, holding, awaiting confirmation, capturing payment, cancelling or modifying orders can be retried")
+	ErrSagaNotCompensable  = errors.New("only unfinished or failed bookings can be compensated")
+	ErrSagaModeMismatch    = errors.New("order was booked in another saga mode, use an order service running that mode")
+)
+
 // Service mendefinisikan logika bisnis untuk Order Service
 type Service interface {
 	// StartSaga dipanggil oleh HTTP handler untuk memulai proses booking
 	StartSaga(ctx context.Context, payload CreateOrderPayload) (*Order, error)
 
+	// GetOrder dipanggil oleh HTTP handler untuk membaca status order
+	GetOrder(ctx context.Context, orderID string) (*Order, error)
+
+	// CancelOrder dipanggil oleh HTTP handler untuk memulai saga pembatalan order yang sudah BOOKED
+	CancelOrder(ctx context.Context, orderID string) (*Order, error)
+
+	// ModifyOrder dipanggil oleh HTTP handler untuk memulai saga penggantian satu item order yang sudah BOOKED
+	ModifyOrder(ctx context.Context, orderID string, payload ModifyOrderPayload) (*Order, error)
+
+	// HoldOrder dipanggil oleh HTTP handler untuk membuat order yang itemnya ditahan lebih dulu
+	HoldOrder(ctx context.Context, payload HoldOrderPayload) (*Order, error)
+
+	// ConfirmHold dipanggil oleh HTTP handler untuk mengonfirmasi dan membayar order HELD
+	ConfirmHold(ctx context.Context, orderID string) (*Order, error)
+
 	// ProcessSagaEvent dipanggil oleh event handler saat menerima balasan dari service lain
 	ProcessSagaEvent(ctx context.Context, msg event.Message) error
+
+	// RetrySaga dipanggil operator untuk mengirim ulang command yang belum dibalas
+	RetrySaga(ctx context.Context, orderID string) (*Order, error)
+
+	// CompensateSaga dipanggil operator untuk menggagalkan order dan mengirim command kompensasi
+	CompensateSaga(ctx context.Context, orderID string) (*Order, error)
+
+	// ExpireSagaSteps dipanggil berkala untuk mengompensasi step saga yang melewati batas waktu
+	ExpireSagaSteps(ctx context.Context) error
 }
 
 type service struct {
 	repo      Repository
+	pricing   pricing.Service
+	policy    CancellationPolicy
 	publisher messagebus.Publisher
+	mode      string
+	booking   bookingFlow
+	// holds dan confirmations menjalankan order hold sebelum dan sesudah dikonfirmasi
+	holds         *saga.Orchestrator[*Order]
+	confirmations *saga.Orchestrator[*Order]
 }
 
-func NewService(repo Repository, publisher messagebus.Publisher) Service {
-	return &service{repo: repo, publisher: publisher}
+// bookingFlow menjalankan saga booking, diimplementasikan saga.Orchestrator untuk mode
+// orchestration dan choreography untuk mode choreography
+type bookingFlow interface {
+	Start(ctx context.Context, order *Order) error
+	Handle(ctx context.Context, order *Order, msg event.Message) error
+	Retry(ctx context.Context, order *Order) error
+	Compensate(ctx context.Context, order *Order, reason string) error
+	ExpireSteps(ctx context.Context) error
 }
 
-func (s *service) parseDate(hotelStartDateStr, hotelEndDateStr, carStartDateStr, carEndDateStr string) (time.Time, time.Time, time.Time, time.Time, error) {
-	hotelStartDate, err := time.Parse(config.DateFormat, hotelStartDateStr)
-	if err != nil {
-		return time.Time{}, time.Time{}, time.Time{}, time.Time{}, err
+// NewService membuat order service dengan saga booking sesuai mode (config.SagaMode). Pada
+// mode orchestration transisi saga booking dicatat ke history dan step yang tidak dibalas
+// dalam stepTimeout dikompensasi, 0 berarti tanpa batas waktu. Order hold selalu
+// dijalankan secara orkestrasi.
+func NewService(repo Repository, pricing pricing.Service, policy CancellationPolicy, publisher messagebus.Publisher, mode string, history saga.History, stepTimeout time.Duration) Service {
+	s := &service{repo: repo, pricing: pricing, policy: policy, publisher: publisher, mode: mode}
+	if mode == config.SagaModeChoreography {
+		s.booking = &choreography{s: s}
+	} else {
+		s.booking = saga.NewOrchestrator(s.bookingSaga(stepTimeout), sagaStore{repo: repo, match: isBooking}, history, publisher)
 	}
-	hotelEndDate, err := time.Parse(config.DateFormat, hotelEndDateStr)
-	if err != nil {
-		return time.Time{}, time.Time{}, time.Time{}, time.Time{}, err
+	s.holds = saga.NewOrchestrator(s.holdSaga(stepTimeout), sagaStore{repo: repo, match: isHolding}, history, publisher)
+	s.confirmations = saga.NewOrchestrator(s.holdConfirmationSaga(stepTimeout), sagaStore{repo: repo, match: isConfirmingHold}, history, publisher)
+	return s
+}
+
+// flow mengembalikan saga yang sedang dijalankan order
+func (s *service) flow(order *Order) bookingFlow {
+	switch {
+	case isBooking(order):
+		return s.booking
+	case isHolding(order):
+		return s.holds
+	default:
+		return s.confirmations
 	}
-	carStartDate, err := time.Parse(config.DateFormat, carStartDateStr)
-	if err != nil {
-		return time.Time{}, time.Time{}, time.Time{}, time.Time{}, err
+}
+
+// checkSagaMode memastikan saga booking order dijalankan dengan mode saat order dibuat,
+// karena state saga orchestration dan choreography tidak dapat saling dilanjutkan. Order
+// hold selalu diorkestrasi dan order lama tanpa SagaMode dianggap sesuai.
+func (s *service) checkSagaMode(order *Order) error {
+	if isBooking(order) && order.SagaMode != "" && order.SagaMode != s.mode {
+		return fmt.Errorf("%w: order %s is %s, service is %s", ErrSagaModeMismatch, order.ID, order.SagaMode, s.mode)
 	}
-	carEndDate, err := time.Parse(config.DateFormat, carEndDateStr)
+	return nil
+}
+
+// normalizeDate memvalidasi tanggal dan mengembalikannya dalam format config.DateFormat
+func normalizeDate(date string) (string, error) {
+	parsed, err := time.Parse(config.DateFormat, date)
 	if err != nil {
-		return time.Time{}, time.Time{}, time.Time{}, time.Time{}, err
+		return "", err
+	}
+	return parsed.Format(config.DateFormat), nil
+}
+
+// buildItems mengubah payload menjadi item order dengan status PENDING
+func buildItems(payload CreateOrderPayload) ([]HotelRoomItem, []CarItem, []TrainSeatItem, []FlightItem, error) {
+	hotelRooms := make([]HotelRoomItem, 0, len(payload.HotelRooms))
+	for _, room := range payload.HotelRooms {
+		startDate, err := normalizeDate(room.StartDate)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		endDate, err := normalizeDate(room.EndDate)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		hotelRooms = append(hotelRooms, HotelRoomItem{
+			HotelRoomID: room.HotelRoomID,
+			RoomTypeID:  room.RoomTypeID,
+			StartDate:   startDate,
+			EndDate:     endDate,
+			Status:      ReservationStatusPending,
+		})
+	}
+
+	cars := make([]CarItem, 0, len(payload.Cars))
+	for _, car := range payload.Cars {
+		startDate, err := normalizeDate(car.StartDate)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		endDate, err := normalizeDate(car.EndDate)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		cars = append(cars, CarItem{
+			CarID:     car.CarID,
+			StartDate: startDate,
+			EndDate:   endDate,
+			Status:    ReservationStatusPending,
+		})
+	}
+
+	trainSeats := make([]TrainSeatItem, 0, len(payload.TrainSeats))
+	for _, seat := range payload.TrainSeats {
+		departureDate, err := normalizeDate(seat.DepartureDate)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		trainSeats = append(trainSeats, TrainSeatItem{
+			JourneyID:          seat.JourneyID,
+			DepartureDate:      departureDate,
+			SeatID:             seat.SeatID,
+			OriginStation:      seat.OriginStation,
+			DestinationStation: seat.DestinationStation,
+			Status:             ReservationStatusPending,
+		})
 	}
 
-	return hotelStartDate, hotelEndDate, carStartDate, carEndDate, nil
+	flights := make([]FlightItem, 0, len(payload.Flights))
+	for _, flight := range payload.Flights {
+		departureDate, err := normalizeDate(flight.DepartureDate)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		flights = append(flights, FlightItem{
+			FlightID:      flight.FlightID,
+			DepartureDate: departureDate,
+			SeatID:        flight.SeatID,
+			Status:        ReservationStatusPending,
+		})
+	}
+
+	return hotelRooms, cars, trainSeats, flights, nil
 }
 
 func (s *service) StartSaga(ctx context.Context, payload CreateOrderPayload) (*Order, error) {
-	hotelStartDate, hotelEndDate, carStartDate, carEndDate, err := s.parseDate(
-		payload.HotelRoomStartDate,
-		payload.HotelRoomEndDate,
-		payload.CarStartDate,
-		payload.CarEndDate,
-	)
+	order, err := s.createOrder(ctx, payload, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ubah status order menjadi AWAITING_CONFIRMATION lalu mulai saga booking, yang
+	// mengirim command ke setiap partisipan dan otorisasi pembayaran dengan
+	// CorrelationID order.ID. Jika command gagal dikirim, order dikompensasi.
+	order.Status = StatusAwaitingConfirmation
+	if err := s.booking.Start(ctx, order); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// createOrder mengunci harga item payload lalu menyimpannya sebagai order PENDING
+func (s *service) createOrder(ctx context.Context, payload CreateOrderPayload, hold *Hold) (*Order, error) {
+	if len(payload.HotelRooms) == 0 && len(payload.Cars) == 0 && len(payload.TrainSeats) == 0 && len(payload.Flights) == 0 {
+		return nil, ErrEmptyOrder
+	}
+
+	hotelRooms, cars, trainSeats, flights, err := buildItems(payload)
 	if err != nil {
 		return nil, err
 	}
 
-	// 1. Buat Order baru dengan status PENDING
+	// 1. Kunci harga order, dari quote jika ada atau dari tarif saat ini
+	orderID := ulid.Make().String()
+	quote, err := s.priceOrder(ctx, orderID, payload.QuoteID, hotelRooms, cars, trainSeats, flights)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Buat Order baru dengan status PENDING
 	order := &Order{
-		ID:     ulid.Make().String(),
+		ID:     orderID,
 		UserID: payload.UserID,
 		Status: StatusPending,
 
-		HotelRoomID:    payload.HotelRoomID,
-		CarID:          payload.CarID,
-		TrainSeatID:    payload.TrainSeatID,
-		HotelStartDate: hotelStartDate.Format(config.DateFormat),
-		HotelEndDate:   hotelEndDate.Format(config.DateFormat),
-		CarStartDate:   carStartDate.Format(config.DateFormat),
-		CarEndDate:     carEndDate.Format(config.DateFormat),
+		HotelRooms: hotelRooms,
+		Cars:       cars,
+		TrainSeats: trainSeats,
+		Flights:    flights,
+
+		QuoteID:    payload.QuoteID,
+		TotalPrice: quote.TotalPrice,
+		Currency:   quote.Currency,
 
-		HotelReservationStatus: ReservationStatusPending,
-		CarReservationStatus:   ReservationStatusPending,
-		TrainReservationStatus: ReservationStatusPending,
+		HotelReservationStatus:  legStatus(len(hotelRooms)),
+		CarReservationStatus:    legStatus(len(cars)),
+		TrainReservationStatus:  legStatus(len(trainSeats)),
+		FlightReservationStatus: legStatus(len(flights)),
+		PaymentStatus:           PaymentStatusPending,
+
+		Hold:     hold,
+		SagaMode: s.mode,
 
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
@@ -98,165 +323,674 @@ func (s *service) StartSaga(ctx context.Context, payload CreateOrderPayload) (*O
 		return nil, err
 	}
 
-	// 2. Publish command untuk setiap layanan partisipan
-	//    Gunakan CorrelationID yang sama dengan order.ID
-	s.publisher.Publish(ctx, string(event.CommandReserveRoom), event.Message{
-		EventName:     event.CommandReserveRoom,
-		CorrelationID: order.ID,
-		Payload: event.ReserveRoomPayload{
-			RoomID:    payload.HotelRoomID,
-			StartDate: payload.HotelRoomStartDate,
-			EndDate:   payload.HotelRoomEndDate,
-		},
-	})
+	return order, nil
+}
 
-	s.publisher.Publish(ctx, string(event.CommandReserveCar), event.Message{
-		EventName:     event.CommandReserveCar,
-		CorrelationID: order.ID,
-		Payload: event.ReserveCarPayload{
-			CarID:     payload.CarID,
-			StartDate: payload.CarStartDate,
-			EndDate:   payload.CarEndDate,
-		},
-	})
+// priceOrder menghitung harga setiap item dan mengisinya ke item order. Jika quoteID
+// diisi, quote dikunci untuk orderID sehingga tidak dapat dipakai order lain.
+func (s *service) priceOrder(ctx context.Context, orderID, quoteID string, hotelRooms []HotelRoomItem, cars []CarItem, trainSeats []TrainSeatItem, flights []FlightItem) (*pricing.Quote, error) {
+	var req pricing.QuoteRequest
+	for _, room := range hotelRooms {
+		req.HotelRooms = append(req.HotelRooms, pricing.RoomQuoteRequest{
+			HotelRoomID: room.HotelRoomID,
+			RoomTypeID:  room.RoomTypeID,
+			StartDate:   room.StartDate,
+			EndDate:     room.EndDate,
+		})
+	}
+	for _, car := range cars {
+		req.Cars = append(req.Cars, pricing.CarQuoteRequest{
+			CarID:     car.CarID,
+			StartDate: car.StartDate,
+			EndDate:   car.EndDate,
+		})
+	}
+	for _, seat := range trainSeats {
+		req.TrainSeats = append(req.TrainSeats, pricing.SeatQuoteRequest{
+			JourneyID:          seat.JourneyID,
+			DepartureDate:      seat.DepartureDate,
+			SeatID:             seat.SeatID,
+			OriginStation:      seat.OriginStation,
+			DestinationStation: seat.DestinationStation,
+		})
+	}
+	for _, flight := range flights {
+		req.Flights = append(req.Flights, pricing.FlightQuoteRequest{
+			FlightID:      flight.FlightID,
+			DepartureDate: flight.DepartureDate,
+			SeatID:        flight.SeatID,
+		})
+	}
 
-	s.publisher.Publish(ctx, string(event.CommandReserveSeat), event.Message{
-		EventName:     event.CommandReserveSeat,
-		CorrelationID: order.ID,
-		Payload: event.ReserveSeatPayload{
-			SeatID: payload.TrainSeatID,
-		},
-	})
+	var quote *pricing.Quote
+	var err error
+	if quoteID != "" {
+		quote, err = s.pricing.RedeemQuote(ctx, quoteID, orderID, req)
+	} else {
+		quote, err = s.pricing.Price(ctx, req)
+	}
+	if err != nil {
+		return nil, err
+	}
 
-	// 3. Update status order menjadi AWAITING_CONFIRMATION
-	order.Status = StatusAwaitingConfirmation
-	s.repo.UpdateOrder(ctx, order)
+	// Urutan line pada quote sama dengan urutan item pada order
+	for i := range hotelRooms {
+		hotelRooms[i].Price = quote.HotelRooms[i].Price
+	}
+	for i := range cars {
+		cars[i].Price = quote.Cars[i].Price
+	}
+	for i := range trainSeats {
+		trainSeats[i].Price = quote.TrainSeats[i].Price
+	}
+	for i := range flights {
+		flights[i].Price = quote.Flights[i].Price
+	}
 
-	return order, nil
+	return quote, nil
 }
 
 func (s *service) ProcessSagaEvent(ctx context.Context, msg event.Message) error {
 	log.Println("Received saga event", msg.EventName)
+	// Event pelepasan item diproses oleh waitlist, bukan oleh saga order
+	switch msg.EventName {
+	case event.RoomReleased, event.CarReleased, event.SeatReleased:
+		return nil
+	}
+
 	// 1. Ambil order dari DB menggunakan msg.CorrelationID
 	order, err := s.repo.GetOrderByID(ctx, msg.CorrelationID)
 	if err != nil {
 		return err
 	}
 
-	// 2. State machine untuk memproses event berdasarkan EventName
+	// 2. Balasan pembatalan diproses saga pembatalan saat order dibatalkan customer,
+	//    selain itu balasan tersebut adalah balasan kompensasi saga booking
+	switch msg.EventName {
+	case event.RoomReservationCancelled, event.CarReservationCancelled, event.SeatReservationCancelled, event.FlightReservationCancelled, event.PaymentRefunded:
+		if order.Status == StatusCancelling {
+			return s.processCancellationEvent(ctx, order, msg)
+		}
+	case event.RoomModified, event.RoomModificationFailed, event.CarModified, event.CarModificationFailed, event.SeatModified, event.SeatModificationFailed,
+		event.PaymentAdjusted, event.PaymentAdjustmentFailed:
+		if order.Status != StatusModifying {
+			return nil
+		}
+		return s.processModificationEvent(ctx, order, msg)
+	case event.RoomHoldExpired, event.CarHoldExpired, event.SeatHoldExpired:
+		return s.processHoldExpiredEvent(ctx, order, msg)
+	}
+	if order.Status == StatusCancelling || order.Status == StatusCancelled || order.Status == StatusModifying {
+		return nil
+	}
+
+	// 3. Sisanya adalah balasan step saga booking atau saga order hold
+	return s.flow(order).Handle(ctx, order, msg)
+}
+
+// unmarshalPayload adalah helper function untuk unmarshal JSON payload
+func (s *service) unmarshalPayload(payload any, target any) error {
+	// Convert payload to JSON bytes first
+	jsonBytes, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	// Then unmarshal to target struct
+	return json.Unmarshal(jsonBytes, target)
+}
+
+// RetrySaga mengirim ulang command saga yang belum dibalas, misalnya karena pesannya
+// hilang. Participant tidak mendeduplikasi command, sehingga command reservasi yang
+// ternyata sudah diproses akan dibalas gagal dan order dikompensasi.
+func (s *service) RetrySaga(ctx context.Context, orderID string) (*Order, error) {
+	order, err := s.repo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkSagaMode(order); err != nil {
+		return nil, err
+	}
+
+	switch order.Status {
+	case StatusPending, StatusHolding, StatusAwaitingConfirmation, StatusCapturingPayment:
+		// Order PENDING belum sempat memulai saga booking atau saga hold saat dibuat. Pada
+		// mode choreography saga dimulai ulang dengan mengirim ulang OrderCreated.
+		if order.Status == StatusPending {
+			order.Status = StatusAwaitingConfirmation
+			if order.Hold != nil {
+				order.Status = StatusHolding
+			}
+			err = s.flow(order).Start(ctx, order)
+		} else {
+			err = s.flow(order).Retry(ctx, order)
+		}
+		if errors.Is(err, saga.ErrNotRunning) {
+			return nil, ErrSagaNotRetryable
+		}
+		if err != nil {
+			return nil, err
+		}
+	case StatusCancelling:
+		if err := s.publishCancelCommands(ctx, order, true); err != nil {
+			return nil, err
+		}
+	case StatusModifying:
+		msg := modificationCommand(order)
+		if err := s.publisher.Publish(ctx, string(msg.EventName), msg); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrSagaNotRetryable
+	}
+
+	return order, nil
+}
+
+// CompensateSaga menggagalkan order yang belum selesai dan mengirim command kompensasi ke
+// seluruh participant. Order FAILED dikompensasi ulang, misalnya jika command kompensasi
+// sebelumnya hilang. Order yang sudah BOOKED dibatalkan dengan CancelOrder.
+func (s *service) CompensateSaga(ctx context.Context, orderID string) (*Order, error) {
+	order, err := s.repo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkSagaMode(order); err != nil {
+		return nil, err
+	}
+
+	switch order.Status {
+	case StatusPending, StatusHolding, StatusAwaitingConfirmation, StatusCapturingPayment, StatusFailed:
+	default:
+		return nil, ErrSagaNotCompensable
+	}
+
+	if err := s.flow(order).Compensate(ctx, order, "compensated by operator"); err != nil {
+		if errors.Is(err, saga.ErrCompleted) {
+			return nil, ErrSagaNotCompensable
+		}
+		return nil, err
+	}
+	return order, nil
+}
+
+// ExpireSagaSteps mengompensasi order yang step saga booking-nya tidak dibalas sebelum
+// batas waktunya
+func (s *service) ExpireSagaSteps(ctx context.Context) error {
+	return errors.Join(s.booking.ExpireSteps(ctx), s.holds.ExpireSteps(ctx), s.confirmations.ExpireSteps(ctx))
+}
+
+func (s *service) GetOrder(ctx context.Context, orderID string) (*Order, error) {
+	return s.repo.GetOrderByID(ctx, orderID)
+}
+
+func (s *service) CancelOrder(ctx context.Context, orderID string) (*Order, error) {
+	order, err := s.repo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != StatusBooked {
+		return nil, ErrOrderNotCancellable
+	}
+
+	// 1. Tetapkan biaya pembatalan sesuai kebijakan dan ubah status menjadi CANCELLING
+	now := time.Now()
+	order.Status = StatusCancelling
+	order.CancellationFee = s.policy.Fee(order, now)
+	order.RefundAmount = order.TotalPrice - order.CancellationFee
+	order.CancelRequestedAt = now
+	if err := s.repo.UpdateOrder(ctx, order); err != nil {
+		return nil, err
+	}
+
+	// 2. Lepaskan seluruh reservasi dan refund pembayaran dikurangi biaya pembatalan
+	if err := s.publishCancelCommands(ctx, order, false); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// publishCancelCommands mengirim command pembatalan ke setiap sub-transaksi order dan
+// refund pembayaran. Jika pendingOnly, sub-transaksi yang sudah CANCELLED dan pembayaran
+// yang sudah di-refund tidak dikirimi command lagi.
+func (s *service) publishCancelCommands(ctx context.Context, order *Order, pendingOnly bool) error {
+	requested := func(status ReservationStatus) bool {
+		return status != ReservationStatusNotRequested && (!pendingOnly || status != ReservationStatusCancelled)
+	}
+
+	var messages []event.Message
+	if requested(order.HotelReservationStatus) {
+		messages = append(messages, cancelRoomCommand(order))
+	}
+	if requested(order.CarReservationStatus) {
+		messages = append(messages, cancelCarCommand(order))
+	}
+	if requested(order.TrainReservationStatus) {
+		messages = append(messages, cancelSeatCommand(order))
+	}
+	if len(order.Flights) > 0 && (!pendingOnly || order.FlightReservationStatus != ReservationStatusCancelled) {
+		messages = append(messages, cancelFlightCommand(order))
+	}
+	if !pendingOnly || order.PaymentStatus != PaymentStatusRefunded {
+		messages = append(messages, refundPaymentCommand(order, order.RefundAmount))
+	}
+
+	var errs []error
+	for _, msg := range messages {
+		errs = append(errs, s.publisher.Publish(ctx, string(msg.EventName), msg))
+	}
+	return errors.Join(errs...)
+}
+
+// processCancellationEvent mencatat balasan saga pembatalan. Order menjadi CANCELLED
+// setelah seluruh reservasi dilepas dan pembayaran di-refund.
+func (s *service) processCancellationEvent(ctx context.Context, order *Order, msg event.Message) error {
 	switch msg.EventName {
-	case event.RoomReserved:
-		var payload event.RoomReservedPayload
+	case event.RoomReservationCancelled:
+		order.HotelReservationStatus = ReservationStatusCancelled
+		for i := range order.HotelRooms {
+			order.HotelRooms[i].Status = ReservationStatusCancelled
+		}
+	case event.CarReservationCancelled:
+		order.CarReservationStatus = ReservationStatusCancelled
+		for i := range order.Cars {
+			order.Cars[i].Status = ReservationStatusCancelled
+		}
+	case event.SeatReservationCancelled:
+		order.TrainReservationStatus = ReservationStatusCancelled
+		for i := range order.TrainSeats {
+			order.TrainSeats[i].Status = ReservationStatusCancelled
+		}
+	case event.FlightReservationCancelled:
+		order.FlightReservationStatus = ReservationStatusCancelled
+		for i := range order.Flights {
+			order.Flights[i].Status = ReservationStatusCancelled
+		}
+	case event.PaymentRefunded:
+		var payload event.PaymentRefundedPayload
 		if err := s.unmarshalPayload(msg.Payload, &payload); err != nil {
 			return err
 		}
-		order.HotelReservationStatus = ReservationStatusBooked
-		order.HotelReservationID = payload.RoomReservationID
-		order.HotelDoneAt = time.Now()
+		order.PaymentStatus = PaymentStatusRefunded
+		order.RefundAmount = payload.Amount
+	}
+
+	for _, status := range order.RequestedLegStatuses() {
+		if status != ReservationStatusCancelled {
+			return s.repo.UpdateOrder(ctx, order)
+		}
+	}
+	if order.PaymentStatus != PaymentStatusRefunded {
+		return s.repo.UpdateOrder(ctx, order)
+	}
+
+	order.Status = StatusCancelled
+	order.CancelledAt = time.Now()
+	if err := s.repo.UpdateOrder(ctx, order); err != nil {
+		return err
+	}
+
+	return s.publisher.Publish(ctx, string(event.OrderCancelled), event.Message{
+		EventName:     event.OrderCancelled,
+		CorrelationID: order.ID,
+		Payload:       event.OrderCancelledPayload{OrderID: order.ID},
+	})
+}
+
+func (s *service) ModifyOrder(ctx context.Context, orderID string, payload ModifyOrderPayload) (*Order, error) {
+	if payload.Flight != nil {
+		return nil, ErrFlightNotModifiable
+	}
+
+	order, err := s.repo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != StatusBooked {
+		return nil, ErrOrderNotModifiable
+	}
+
+	// 1. Pastikan tepat satu item yang diganti dan item tersebut ada di order
+	var requested, booked int
+	if payload.HotelRoom != nil {
+		requested, booked = requested+1, len(order.HotelRooms)
+	}
+	if payload.Car != nil {
+		requested, booked = requested+1, len(order.Cars)
+	}
+	if payload.TrainSeat != nil {
+		requested, booked = requested+1, len(order.TrainSeats)
+	}
+	if requested != 1 {
+		return nil, ErrInvalidModification
+	}
+	if payload.Index >= booked {
+		return nil, ErrItemNotFound
+	}
+
+	// 2. Beri harga item pengganti dengan tarif saat ini
+	createPayload := CreateOrderPayload{}
+	if payload.HotelRoom != nil {
+		createPayload.HotelRooms = []HotelRoomRequest{*payload.HotelRoom}
+	}
+	if payload.Car != nil {
+		createPayload.Cars = []CarRequest{*payload.Car}
+	}
+	if payload.TrainSeat != nil {
+		createPayload.TrainSeats = []TrainSeatRequest{*payload.TrainSeat}
+	}
+	hotelRooms, cars, trainSeats, _, err := buildItems(createPayload)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.priceOrder(ctx, order.ID, "", hotelRooms, cars, trainSeats, nil); err != nil {
+		return nil, err
+	}
+
+	// 3. Siapkan command untuk partisipan pemilik item. Partisipan mereservasi item
+	//    pengganti lebih dulu dan baru melepas item lama jika reservasi berhasil.
+	modification := &Modification{
+		ID:          ulid.Make().String(),
+		Index:       payload.Index,
+		Status:      ModificationStatusPending,
+		Step:        ModificationStepModifying,
+		RequestedAt: time.Now(),
+	}
+	switch {
+	case len(hotelRooms) > 0:
+		room := hotelRooms[0]
+		modification.HotelRoom = &room
+		modification.PriceDifference = room.Price - order.HotelRooms[payload.Index].Price
+	case len(cars) > 0:
+		car := cars[0]
+		modification.Car = &car
+		modification.PriceDifference = car.Price - order.Cars[payload.Index].Price
+	default:
+		seat := trainSeats[0]
+		modification.TrainSeat = &seat
+		modification.PriceDifference = seat.Price - order.TrainSeats[payload.Index].Price
+	}
+
+	// 4. Selisih harga positif ditarik lebih dulu, item baru diganti setelah pembayaran
+	//    berhasil
+	if modification.PriceDifference > 0 {
+		modification.Step = ModificationStepCharging
+	}
+
+	// 5. Ubah status menjadi MODIFYING lalu kirim command
+	order.Status = StatusModifying
+	order.Modification = modification
+	if err := s.repo.UpdateOrder(ctx, order); err != nil {
+		return nil, err
+	}
+
+	msg := modificationCommand(order)
+	if err := s.publisher.Publish(ctx, string(msg.EventName), msg); err != nil {
+		// Command tidak terkirim, order tetap seperti sebelum modifikasi
+		order.Status = StatusBooked
+		modification.Status = ModificationStatusFailed
+		modification.FailureReason = err.Error()
+		modification.DoneAt = time.Now()
+		if updateErr := s.repo.UpdateOrder(ctx, order); updateErr != nil {
+			return nil, errors.Join(err, updateErr)
+		}
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// modificationCommand menyusun command langkah saga modifikasi yang sedang ditunggu
+func modificationCommand(order *Order) event.Message {
+	switch order.Modification.Step {
+	case ModificationStepCharging, ModificationStepRefunding, ModificationStepReverting:
+		return adjustPaymentCommand(order)
+	default:
+		return modifyCommand(order)
+	}
+}
+
+// adjustmentID adalah ID penyesuaian pembayaran langkah step modifikasi. Setiap langkah
+// memakai ID sendiri sehingga balasan langkah sebelumnya diabaikan.
+func adjustmentID(modification *Modification, step ModificationStep) string {
+	return modification.ID + "-" + strings.ToLower(string(step))
+}
+
+// adjustPaymentCommand menyusun command penyesuaian pembayaran untuk langkah modifikasi.
+// Penarikan yang dikembalikan saat REVERTING di-refund dari otorisasi penarikannya.
+func adjustPaymentCommand(order *Order) event.Message {
+	modification := order.Modification
+	payload := event.AdjustPaymentPayload{
+		OrderID:      order.ID,
+		AdjustmentID: adjustmentID(modification, modification.Step),
+		Amount:       modification.PriceDifference,
+	}
+	if modification.Step == ModificationStepReverting {
+		payload.Amount = -modification.PriceDifference
+		payload.RevertsID = adjustmentID(modification, ModificationStepCharging)
+	}
+	return event.Message{
+		EventName:     event.CommandAdjustPayment,
+		CorrelationID: order.ID,
+		Payload:       payload,
+	}
+}
+
+// modifyCommand menyusun command untuk order.Modification. Command berisi reservasi item
+// yang diganti dan item penggantinya.
+func modifyCommand(order *Order) event.Message {
+	modification := order.Modification
+	switch {
+	case modification.HotelRoom != nil:
+		room := modification.HotelRoom
+		return event.Message{
+			EventName:     event.CommandModifyRoom,
+			CorrelationID: order.ID,
+			Payload: event.ModifyRoomPayload{
+				ReservationID: order.HotelRooms[modification.Index].ReservationID,
+				Room: event.RoomItem{
+					RoomID:     room.HotelRoomID,
+					RoomTypeID: room.RoomTypeID,
+					StartDate:  room.StartDate,
+					EndDate:    room.EndDate,
+					Price:      room.Price,
+				},
+			},
+		}
+	case modification.Car != nil:
+		car := modification.Car
+		return event.Message{
+			EventName:     event.CommandModifyCar,
+			CorrelationID: order.ID,
+			Payload: event.ModifyCarPayload{
+				ReservationID: order.Cars[modification.Index].ReservationID,
+				Car: event.CarItem{
+					CarID:     car.CarID,
+					StartDate: car.StartDate,
+					EndDate:   car.EndDate,
+					Price:     car.Price,
+				},
+			},
+		}
+	default:
+		seat := modification.TrainSeat
+		return event.Message{
+			EventName:     event.CommandModifySeat,
+			CorrelationID: order.ID,
+			Payload: event.ModifySeatPayload{
+				ReservationID: order.TrainSeats[modification.Index].ReservationID,
+				Seat: event.SeatItem{
+					JourneyID:          seat.JourneyID,
+					DepartureDate:      seat.DepartureDate,
+					SeatID:             seat.SeatID,
+					OriginStation:      seat.OriginStation,
+					DestinationStation: seat.DestinationStation,
+					Price:              seat.Price,
+				},
+			},
+		}
+	}
+}
 
-	case event.CarReserved:
-		var payload event.CarReservedPayload
+// processModificationEvent memproses balasan partisipan pemilik item pada saga
+// modifikasi. Jika berhasil, item lama diganti item pengganti. Selisih harga negatif
+// dikembalikan setelah item diganti, sedangkan selisih harga positif yang sudah ditarik
+// dikembalikan jika item gagal diganti. Balasan yang tidak ditunggu diabaikan.
+func (s *service) processModificationEvent(ctx context.Context, order *Order, msg event.Message) error {
+	modification := order.Modification
+	if msg.EventName == event.PaymentAdjusted || msg.EventName == event.PaymentAdjustmentFailed {
+		return s.processModificationPayment(ctx, order, msg)
+	}
+	if modification.Step != ModificationStepModifying && modification.Step != "" {
+		return nil
+	}
+
+	succeeded := true
+	switch msg.EventName {
+	case event.RoomModified:
+		var payload event.RoomModifiedPayload
+		if err := s.unmarshalPayload(msg.Payload, &payload); err != nil {
+			return err
+		}
+		room := *modification.HotelRoom
+		room.ReservationID = payload.RoomReservationID
+		room.Status = ReservationStatusBooked
+		order.HotelRooms[modification.Index] = room
+	case event.CarModified:
+		var payload event.CarModifiedPayload
 		if err := s.unmarshalPayload(msg.Payload, &payload); err != nil {
 			return err
 		}
-		order.CarReservationStatus = ReservationStatusBooked
-		order.CarReservationID = payload.CarReservationID
-		order.CarDoneAt = time.Now()
-	case event.SeatReserved:
-		var payload event.SeatReservedPayload
+		car := *modification.Car
+		car.ReservationID = payload.CarReservationID
+		car.Status = ReservationStatusBooked
+		order.Cars[modification.Index] = car
+	case event.SeatModified:
+		var payload event.SeatModifiedPayload
 		if err := s.unmarshalPayload(msg.Payload, &payload); err != nil {
 			return err
 		}
-		order.TrainReservationStatus = ReservationStatusBooked
-		order.TrainReservationID = payload.SeatReservationID
-		order.TrainDoneAt = time.Now()
-	case event.RoomReservationFailed:
-		var payload event.RoomReservationFailedPayload
+		seat := *modification.TrainSeat
+		seat.ReservationID = payload.SeatReservationID
+		seat.Status = ReservationStatusBooked
+		order.TrainSeats[modification.Index] = seat
+	case event.RoomModificationFailed:
+		var payload event.RoomModificationFailedPayload
 		if err := s.unmarshalPayload(msg.Payload, &payload); err != nil {
 			return err
 		}
-		order.HotelReservationStatus = ReservationStatusFailed
-		order.HotelReservationFailureReason = payload.FailureReason
-		order.HotelDoneAt = time.Now()
-	case event.CarReservationFailed:
-		var payload event.CarReservationFailedPayload
+		succeeded = false
+		modification.FailureReason = payload.FailureReason
+	case event.CarModificationFailed:
+		var payload event.CarModificationFailedPayload
 		if err := s.unmarshalPayload(msg.Payload, &payload); err != nil {
 			return err
 		}
-		order.CarReservationStatus = ReservationStatusFailed
-		order.CarReservationFailureReason = payload.FailureReason
-		order.CarDoneAt = time.Now()
-	case event.SeatReservationFailed:
-		var payload event.SeatReservationFailedPayload
+		succeeded = false
+		modification.FailureReason = payload.FailureReason
+	case event.SeatModificationFailed:
+		var payload event.SeatModificationFailedPayload
 		if err := s.unmarshalPayload(msg.Payload, &payload); err != nil {
 			return err
 		}
-		order.TrainReservationStatus = ReservationStatusFailed
-		order.TrainReservationFailureReason = payload.FailureReason
-		order.TrainDoneAt = time.Now()
+		succeeded = false
+		modification.FailureReason = payload.FailureReason
 	}
 
-	// 3. Cek apakah ada yang pending
-	if order.HotelReservationStatus == ReservationStatusPending || order.CarReservationStatus == ReservationStatusPending || order.TrainReservationStatus == ReservationStatusPending {
-		return s.repo.UpdateOrder(ctx, order)
+	switch {
+	case succeeded && modification.PriceDifference < 0:
+		return s.adjustModificationPayment(ctx, order, ModificationStepRefunding)
+	case !succeeded && modification.PriceDifference > 0:
+		return s.adjustModificationPayment(ctx, order, ModificationStepReverting)
 	}
+	return s.finishModification(ctx, order, succeeded)
+}
 
-	// 4. Cek apakah semua reservasi sudah berhasil
-	if order.HotelReservationStatus == ReservationStatusBooked && order.CarReservationStatus == ReservationStatusBooked && order.TrainReservationStatus == ReservationStatusBooked {
-		order.Status = StatusBooked
-		order.DoneAt = time.Now()
-		s.repo.UpdateOrder(ctx, order)
-		s.publisher.Publish(ctx, string(event.OrderBooked), event.Message{
-			EventName:     event.OrderBooked,
-			CorrelationID: order.ID,
-			Payload:       event.OrderBookedPayload{OrderID: order.ID},
-		})
-		return nil
+// processModificationPayment memproses balasan penyesuaian pembayaran saga modifikasi.
+// Penarikan yang gagal menggagalkan modifikasi tanpa mengubah item. Pengembalian yang
+// gagal membiarkan order MODIFYING agar dapat dikirim ulang dengan retry saga.
+func (s *service) processModificationPayment(ctx context.Context, order *Order, msg event.Message) error {
+	modification := order.Modification
+	if msg.EventName == event.PaymentAdjustmentFailed {
+		var payload event.PaymentAdjustmentFailedPayload
+		if err := s.unmarshalPayload(msg.Payload, &payload); err != nil {
+			return err
+		}
+		if payload.AdjustmentID != adjustmentID(modification, modification.Step) {
+			return nil
+		}
+		if modification.Step != ModificationStepCharging {
+			log.Printf("Failed to refund the price difference of order %s, retry the saga: %s", order.ID, payload.FailureReason)
+			return nil
+		}
+		modification.FailureReason = payload.FailureReason
+		return s.finishModification(ctx, order, false)
 	}
 
-	return s.startCompensation(ctx, order)
+	var payload event.PaymentAdjustedPayload
+	if err := s.unmarshalPayload(msg.Payload, &payload); err != nil {
+		return err
+	}
+	if payload.AdjustmentID != adjustmentID(modification, modification.Step) {
+		return nil
+	}
+	switch modification.Step {
+	case ModificationStepCharging:
+		modification.Step = ModificationStepModifying
+		if err := s.repo.UpdateOrder(ctx, order); err != nil {
+			return err
+		}
+		msg := modifyCommand(order)
+		return s.publisher.Publish(ctx, string(msg.EventName), msg)
+	case ModificationStepRefunding:
+		return s.finishModification(ctx, order, true)
+	default:
+		return s.finishModification(ctx, order, false)
+	}
 }
 
-// unmarshalPayload adalah helper function untuk unmarshal JSON payload
-func (s *service) unmarshalPayload(payload any, target any) error {
-	// Convert payload to JSON bytes first
-	jsonBytes, err := json.Marshal(payload)
-	if err != nil {
+// adjustModificationPayment menyimpan langkah step lalu mengirim command penyesuaian
+// pembayarannya
+func (s *service) adjustModificationPayment(ctx context.Context, order *Order, step ModificationStep) error {
+	order.Modification.Step = step
+	if err := s.repo.UpdateOrder(ctx, order); err != nil {
 		return err
 	}
-	// Then unmarshal to target struct
-	return json.Unmarshal(jsonBytes, target)
+	msg := adjustPaymentCommand(order)
+	return s.publisher.Publish(ctx, string(msg.EventName), msg)
 }
 
-func (s *service) startCompensation(ctx context.Context, order *Order) error {
-	order.Status = StatusFailed
-	s.repo.UpdateOrder(ctx, order)
-
-	// Kirim command kompensasi untuk command yang sudah dikirim
-	// Menggunakan OrderID saja karena relasi one-to-one
-	s.publisher.Publish(ctx, string(event.CommandCancelRoom), event.Message{
-		EventName:     event.CommandCancelRoom,
-		CorrelationID: order.ID,
-		Payload:       event.CancelRoomPayload{OrderID: order.ID},
-	})
-
-	s.publisher.Publish(ctx, string(event.CommandCancelCar), event.Message{
-		EventName:     event.CommandCancelCar,
-		CorrelationID: order.ID,
-		Payload:       event.CancelCarPayload{OrderID: order.ID},
-	})
-	s.publisher.Publish(ctx, string(event.CommandCancelSeat), event.Message{
-		EventName:     event.CommandCancelSeat,
-		CorrelationID: order.ID,
-		Payload:       event.CancelSeatPayload{OrderID: order.ID},
-	})
+// finishModification mengembalikan order ke BOOKED. Total harga order hanya berubah jika
+// modifikasi berhasil, yaitu setelah item diganti dan selisih harganya disesuaikan.
+func (s *service) finishModification(ctx context.Context, order *Order, succeeded bool) error {
+	modification := order.Modification
+	order.Status = StatusBooked
+	modification.DoneAt = time.Now()
+	if !succeeded {
+		modification.Status = ModificationStatusFailed
+		if err := s.repo.UpdateOrder(ctx, order); err != nil {
+			return err
+		}
+		return s.publisher.Publish(ctx, string(event.OrderModificationFailed), event.Message{
+			EventName:     event.OrderModificationFailed,
+			CorrelationID: order.ID,
+			Payload: event.OrderModificationFailedPayload{
+				OrderID:       order.ID,
+				FailureReason: modification.FailureReason,
+			},
+		})
+	}
 
-	// Publish event final ORDER_FAILED
-	s.publisher.Publish(ctx, string(event.OrderFailed), event.Message{
-		EventName:     event.OrderFailed,
+	modification.Status = ModificationStatusSucceeded
+	order.TotalPrice += modification.PriceDifference
+	if err := s.repo.UpdateOrder(ctx, order); err != nil {
+		return err
+	}
+	return s.publisher.Publish(ctx, string(event.OrderModified), event.Message{
+		EventName:     event.OrderModified,
 		CorrelationID: order.ID,
-		Payload:       event.OrderFailedPayload{OrderID: order.ID},
+		Payload:       event.OrderModifiedPayload{OrderID: order.ID},
 	})
-
-	return nil
 }
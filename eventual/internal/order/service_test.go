@@ -0,0 +1,72 @@
+package order
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/event"
+)
+
+func TestModificationCommand(t *testing.T) {
+	tests := []struct {
+		name            string
+		step            ModificationStep
+		priceDifference int64
+		wantEvent       event.EventName
+		wantPayload     any
+	}{
+		{
+			name:            "charge a positive difference before modifying",
+			step:            ModificationStepCharging,
+			priceDifference: 500,
+			wantEvent:       event.CommandAdjustPayment,
+			wantPayload:     event.AdjustPaymentPayload{OrderID: "order-1", AdjustmentID: "mod-1-charging", Amount: 500},
+		},
+		{
+			name:            "refund a negative difference after modifying",
+			step:            ModificationStepRefunding,
+			priceDifference: -500,
+			wantEvent:       event.CommandAdjustPayment,
+			wantPayload:     event.AdjustPaymentPayload{OrderID: "order-1", AdjustmentID: "mod-1-refunding", Amount: -500},
+		},
+		{
+			name:            "revert the charge of a failed modification",
+			step:            ModificationStepReverting,
+			priceDifference: 500,
+			wantEvent:       event.CommandAdjustPayment,
+			wantPayload:     event.AdjustPaymentPayload{OrderID: "order-1", AdjustmentID: "mod-1-reverting", Amount: -500, RevertsID: "mod-1-charging"},
+		},
+		{
+			name:      "modify the item",
+			step:      ModificationStepModifying,
+			wantEvent: event.CommandModifyCar,
+		},
+		{
+			name:      "modification created before price differences were charged",
+			wantEvent: event.CommandModifyCar,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := &Order{
+				ID:   "order-1",
+				Cars: []CarItem{{CarID: "car-1", ReservationID: "reservation-1"}},
+				Modification: &Modification{
+					ID:              "mod-1",
+					Car:             &CarItem{CarID: "car-2"},
+					PriceDifference: tt.priceDifference,
+					Step:            tt.step,
+				},
+			}
+
+			msg := modificationCommand(order)
+			if msg.EventName != tt.wantEvent {
+				t.Fatalf("event = %s, want %s", msg.EventName, tt.wantEvent)
+			}
+			if tt.wantPayload != nil && !reflect.DeepEqual(msg.Payload, tt.wantPayload) {
+				t.Errorf("payload = %+v, want %+v", msg.Payload, tt.wantPayload)
+			}
+		})
+	}
+}
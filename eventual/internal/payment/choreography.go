@@ -0,0 +1,49 @@
+package payment
+
+import (
+	"context"
+	"log"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/event"
+)
+
+// ProcessChoreographyEvent menjalankan bagian payment service pada saga booking
+// koreografi. Payment adalah partisipan terakhir, sehingga pembayaran yang berhasil
+// diotorisasi langsung di-capture dan otorisasinya di-void jika capture gagal.
+func (s *service) ProcessChoreographyEvent(ctx context.Context, msg event.Message) error {
+	switch msg.EventName {
+	case event.FlightReserved:
+		payload, err := mapToPayload[event.FlightReservedPayload](msg)
+		if err != nil {
+			return s.publishErrorEvent(ctx, msg, err)
+		}
+		// Event tanpa order dibuang seperti di car service
+		if payload.Order == nil {
+			log.Printf("Dropping %s of order %s without order payload", msg.EventName, msg.CorrelationID)
+			return nil
+		}
+		return s.handleAuthorizePayment(ctx, event.Message{
+			EventName:     event.CommandAuthorizePayment,
+			CorrelationID: msg.CorrelationID,
+			Payload: event.AuthorizePaymentPayload{
+				UserID:   payload.Order.UserID,
+				Amount:   payload.Order.Amount,
+				Currency: payload.Order.Currency,
+			},
+		})
+	case event.PaymentAuthorized:
+		return s.handleCapturePayment(ctx, event.Message{
+			EventName:     event.CommandCapturePayment,
+			CorrelationID: msg.CorrelationID,
+			Payload:       event.CapturePaymentPayload{OrderID: msg.CorrelationID},
+		})
+	case event.PaymentCaptureFailed:
+		return s.handleReleasePayment(ctx, event.Message{
+			EventName:     event.CommandVoidPayment,
+			CorrelationID: msg.CorrelationID,
+			Payload:       event.VoidPaymentPayload{OrderID: msg.CorrelationID},
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,30 @@
+package payment
+
+import (
+	"context"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/fault"
+)
+
+// faultyRepository menyuntikkan kegagalan sebelum dan sesudah setiap penulisan
+// reservasi. Pembacaan diteruskan tanpa perubahan.
+type faultyRepository struct {
+	Repository
+	injector *fault.Injector
+}
+
+func NewFaultyRepository(repo Repository, injector *fault.Injector) Repository {
+	return &faultyRepository{Repository: repo, injector: injector}
+}
+
+func (r *faultyRepository) CreatePayment(ctx context.Context, payment *Payment) error {
+	return r.injector.Do("CreatePayment", payment.OrderID, func() error {
+		return r.Repository.CreatePayment(ctx, payment)
+	})
+}
+
+func (r *faultyRepository) UpdatePayment(ctx context.Context, payment *Payment) error {
+	return r.injector.Do("UpdatePayment", payment.OrderID, func() error {
+		return r.Repository.UpdatePayment(ctx, payment)
+	})
+}
@@ -0,0 +1,41 @@
+package payment
+
+import "time"
+
+type PaymentStatus string
+
+const (
+	PaymentStatusAuthorized PaymentStatus = "AUTHORIZED"
+	PaymentStatusCaptured   PaymentStatus = "CAPTURED"
+	PaymentStatusVoided     PaymentStatus = "VOIDED"
+	PaymentStatusRefunded   PaymentStatus = "REFUNDED"
+	PaymentStatusFailed     PaymentStatus = "FAILED"
+)
+
+// Payment adalah pembayaran untuk satu order, disimpan dengan ID dokumen OrderID
+// sehingga satu order hanya memiliki satu pembayaran
+type Payment struct {
+	ID              string        `firestore:"id" json:"id"`
+	OrderID         string        `firestore:"order_id" json:"order_id"`
+	UserID          string        `firestore:"user_id" json:"user_id"`
+	Amount          int64         `firestore:"amount" json:"amount"`
+	Currency        string        `firestore:"currency" json:"currency"`
+	AuthorizationID string        `firestore:"authorization_id,omitempty" json:"authorization_id,omitempty"`
+	Status          PaymentStatus `firestore:"status" json:"status"`
+	FailureReason   string        `firestore:"failure_reason,omitempty" json:"failure_reason,omitempty"`
+	RefundedAmount  int64         `firestore:"refunded_amount,omitempty" json:"refunded_amount,omitempty"`
+	// Adjustments adalah selisih harga modifikasi order yang sudah ditarik atau
+	// dikembalikan. Amount sudah termasuk seluruh penyesuaian.
+	Adjustments []Adjustment `firestore:"adjustments,omitempty" json:"adjustments,omitempty"`
+	CreatedAt   time.Time    `firestore:"created_at" json:"created_at"`
+	UpdatedAt   time.Time    `firestore:"updated_at" json:"updated_at"`
+}
+
+// Adjustment adalah satu penyesuaian pembayaran. Penarikan diotorisasi dan di-capture
+// dengan AuthorizationID tersendiri.
+type Adjustment struct {
+	ID              string    `firestore:"id" json:"id"`
+	Amount          int64     `firestore:"amount" json:"amount"`
+	AuthorizationID string    `firestore:"authorization_id,omitempty" json:"authorization_id,omitempty"`
+	CreatedAt       time.Time `firestore:"created_at" json:"created_at"`
+}
@@ -0,0 +1,91 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+var ErrPaymentDeclined = errors.New("payment declined by provider")
+
+// Provider adalah payment gateway yang dipakai payment service. Dana ditahan saat
+// Authorize, ditarik saat Capture, dilepas saat Void, dan dikembalikan saat Refund.
+type Provider interface {
+	Authorize(ctx context.Context, userID string, amount int64, currency string) (authorizationID string, err error)
+	Capture(ctx context.Context, authorizationID string, amount int64) error
+	Void(ctx context.Context, authorizationID string) error
+	Refund(ctx context.Context, authorizationID string, amount int64) error
+}
+
+const fakeAuthorizationPrefix = "fake_auth_"
+
+// fakeProvider adalah provider lokal untuk pengujian. Provider ini tidak menyimpan
+// state, status pembayaran dijaga oleh payment service.
+type fakeProvider struct {
+	declineAbove int64
+	latency      time.Duration
+}
+
+// NewFakeProvider membuat provider lokal yang menolak otorisasi dengan nominal di atas
+// declineAbove (0 berarti tidak pernah menolak) dan menunggu latency di setiap panggilan
+func NewFakeProvider(declineAbove int64, latency time.Duration) Provider {
+	return &fakeProvider{declineAbove: declineAbove, latency: latency}
+}
+
+func (p *fakeProvider) wait(ctx context.Context) error {
+	if p.latency <= 0 {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(p.latency):
+		return nil
+	}
+}
+
+func (p *fakeProvider) Authorize(ctx context.Context, userID string, amount int64, currency string) (string, error) {
+	if err := p.wait(ctx); err != nil {
+		return "", err
+	}
+	if amount <= 0 {
+		return "", errors.New("amount must be positive")
+	}
+	if p.declineAbove > 0 && amount > p.declineAbove {
+		return "", ErrPaymentDeclined
+	}
+
+	return fakeAuthorizationPrefix + ulid.Make().String(), nil
+}
+
+func (p *fakeProvider) Capture(ctx context.Context, authorizationID string, amount int64) error {
+	if err := p.wait(ctx); err != nil {
+		return err
+	}
+	return p.checkAuthorization(authorizationID)
+}
+
+func (p *fakeProvider) Void(ctx context.Context, authorizationID string) error {
+	if err := p.wait(ctx); err != nil {
+		return err
+	}
+	return p.checkAuthorization(authorizationID)
+}
+
+func (p *fakeProvider) Refund(ctx context.Context, authorizationID string, amount int64) error {
+	if err := p.wait(ctx); err != nil {
+		return err
+	}
+	return p.checkAuthorization(authorizationID)
+}
+
+func (p *fakeProvider) checkAuthorization(authorizationID string) error {
+	if !strings.HasPrefix(authorizationID, fakeAuthorizationPrefix) {
+		return errors.New("unknown authorization")
+	}
+	return nil
+}
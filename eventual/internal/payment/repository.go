@@ -0,0 +1,67 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	ErrPaymentNotFound      = errors.New("payment not found")
+	ErrPaymentAlreadyExists = errors.New("payment already exists for this order")
+)
+
+type Repository interface {
+	GetPaymentByOrderID(ctx context.Context, orderID string) (*Payment, error)
+	CreatePayment(ctx context.Context, payment *Payment) error
+	UpdatePayment(ctx context.Context, payment *Payment) error
+}
+
+const (
+	paymentCollection = "payment_payments"
+)
+
+type firestoreRepository struct {
+	client *firestore.Client
+}
+
+func NewFirestoreRepository(client *firestore.Client) Repository {
+	return &firestoreRepository{client: client}
+}
+
+func (r *firestoreRepository) GetPaymentByOrderID(ctx context.Context, orderID string) (*Payment, error) {
+	doc, err := r.client.Collection(paymentCollection).Doc(orderID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, ErrPaymentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var payment Payment
+	if err := doc.DataTo(&payment); err != nil {
+		return nil, err
+	}
+
+	return &payment, nil
+}
+
+// CreatePayment gagal dengan ErrPaymentAlreadyExists jika order sudah memiliki
+// pembayaran, termasuk pembayaran yang dibatalkan sebelum sempat diotorisasi
+func (r *firestoreRepository) CreatePayment(ctx context.Context, payment *Payment) error {
+	_, err := r.client.Collection(paymentCollection).Doc(payment.OrderID).Create(ctx, payment)
+	if status.Code(err) == codes.AlreadyExists {
+		return ErrPaymentAlreadyExists
+	}
+	return err
+}
+
+func (r *firestoreRepository) UpdatePayment(ctx context.Context, payment *Payment) error {
+	payment.UpdatedAt = time.Now()
+	_, err := r.client.Collection(paymentCollection).Doc(payment.OrderID).Set(ctx, payment)
+	return err
+}
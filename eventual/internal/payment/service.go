@@ -0,0 +1,351 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/event"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/messagebus"
+)
+
+type Service interface {
+	ProcessSagaEvent(ctx context.Context, msg event.Message) error
+
+	// ProcessChoreographyEvent memproses event partisipan lain pada saga booking
+	// koreografi (SAGA_MODE=choreography)
+	ProcessChoreographyEvent(ctx context.Context, msg event.Message) error
+}
+
+type service struct {
+	repo      Repository
+	provider  Provider
+	publisher messagebus.Publisher
+}
+
+func NewService(repo Repository, provider Provider, publisher messagebus.Publisher) Service {
+	return &service{repo: repo, provider: provider, publisher: publisher}
+}
+
+func (s *service) ProcessSagaEvent(ctx context.Context, msg event.Message) error {
+	log.Println("Received saga event", msg.EventName)
+	switch msg.EventName {
+	case event.CommandAuthorizePayment:
+		return s.handleAuthorizePayment(ctx, msg)
+	case event.CommandCapturePayment:
+		return s.handleCapturePayment(ctx, msg)
+	case event.CommandVoidPayment, event.CommandRefundPayment:
+		return s.handleReleasePayment(ctx, msg)
+	case event.CommandAdjustPayment:
+		return s.handleAdjustPayment(ctx, msg)
+	}
+
+	return nil
+}
+
+func (s *service) publishErrorEvent(ctx context.Context, msg event.Message, err error) error {
+	if pubErr := s.publisher.Publish(ctx, string(event.PaymentFailed), event.Message{
+		EventName:     event.PaymentFailed,
+		CorrelationID: msg.CorrelationID,
+		Payload: event.PaymentFailedPayload{
+			FailureReason: err.Error(),
+		},
+	}); pubErr != nil {
+		return errors.Join(err, pubErr)
+	}
+
+	return err
+}
+
+func (s *service) publishCaptureErrorEvent(ctx context.Context, msg event.Message, err error) error {
+	if pubErr := s.publisher.Publish(ctx, string(event.PaymentCaptureFailed), event.Message{
+		EventName:     event.PaymentCaptureFailed,
+		CorrelationID: msg.CorrelationID,
+		Payload: event.PaymentCaptureFailedPayload{
+			FailureReason: err.Error(),
+		},
+	}); pubErr != nil {
+		return errors.Join(err, pubErr)
+	}
+
+	return err
+}
+
+func (s *service) publishAuthorized(ctx context.Context, msg event.Message, payment *Payment) error {
+	return s.publisher.Publish(ctx, string(event.PaymentAuthorized), event.Message{
+		EventName:     event.PaymentAuthorized,
+		CorrelationID: msg.CorrelationID,
+		Payload:       event.PaymentAuthorizedPayload{PaymentID: payment.ID},
+	})
+}
+
+func (s *service) handleAuthorizePayment(ctx context.Context, msg event.Message) error {
+	payload, err := mapToPayload[event.AuthorizePaymentPayload](msg)
+	if err != nil {
+		return s.publishErrorEvent(ctx, msg, err)
+	}
+
+	// Command yang terkirim ulang tidak boleh mengotorisasi dua kali
+	existing, err := s.repo.GetPaymentByOrderID(ctx, msg.CorrelationID)
+	if err != nil && !errors.Is(err, ErrPaymentNotFound) {
+		return s.publishErrorEvent(ctx, msg, err)
+	}
+	if existing != nil {
+		return s.replyExisting(ctx, msg, existing)
+	}
+
+	payment := &Payment{
+		ID:        ulid.Make().String(),
+		OrderID:   msg.CorrelationID,
+		UserID:    payload.UserID,
+		Amount:    payload.Amount,
+		Currency:  payload.Currency,
+		Status:    PaymentStatusAuthorized,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	authorizationID, authErr := s.provider.Authorize(ctx, payload.UserID, payload.Amount, payload.Currency)
+	if authErr != nil {
+		payment.Status = PaymentStatusFailed
+		payment.FailureReason = authErr.Error()
+		if err := s.repo.CreatePayment(ctx, payment); err != nil && !errors.Is(err, ErrPaymentAlreadyExists) {
+			return s.publishErrorEvent(ctx, msg, errors.Join(authErr, err))
+		}
+		return s.publishErrorEvent(ctx, msg, authErr)
+	}
+
+	payment.AuthorizationID = authorizationID
+	if err := s.repo.CreatePayment(ctx, payment); err != nil {
+		// Order sudah dibatalkan sebelum otorisasi tercatat, lepaskan kembali dananya
+		if voidErr := s.provider.Void(ctx, authorizationID); voidErr != nil {
+			err = errors.Join(err, voidErr)
+		}
+		return s.publishErrorEvent(ctx, msg, err)
+	}
+
+	return s.publishAuthorized(ctx, msg, payment)
+}
+
+// replyExisting membalas command otorisasi berdasarkan pembayaran yang sudah tercatat
+func (s *service) replyExisting(ctx context.Context, msg event.Message, payment *Payment) error {
+	switch payment.Status {
+	case PaymentStatusAuthorized, PaymentStatusCaptured:
+		return s.publishAuthorized(ctx, msg, payment)
+	case PaymentStatusFailed:
+		return s.publishErrorEvent(ctx, msg, errors.New(payment.FailureReason))
+	default:
+		return s.publishErrorEvent(ctx, msg, fmt.Errorf("payment is already %s", payment.Status))
+	}
+}
+
+func (s *service) handleCapturePayment(ctx context.Context, msg event.Message) error {
+	payload, err := mapToPayload[event.CapturePaymentPayload](msg)
+	if err != nil {
+		return s.publishCaptureErrorEvent(ctx, msg, err)
+	}
+
+	payment, err := s.repo.GetPaymentByOrderID(ctx, payload.OrderID)
+	if err != nil {
+		return s.publishCaptureErrorEvent(ctx, msg, err)
+	}
+
+	switch payment.Status {
+	case PaymentStatusCaptured:
+		// Capture sudah pernah dilakukan, cukup kirim ulang balasannya
+	case PaymentStatusAuthorized:
+		if err := s.provider.Capture(ctx, payment.AuthorizationID, payment.Amount); err != nil {
+			return s.publishCaptureErrorEvent(ctx, msg, err)
+		}
+		payment.Status = PaymentStatusCaptured
+		if err := s.repo.UpdatePayment(ctx, payment); err != nil {
+			return s.publishCaptureErrorEvent(ctx, msg, err)
+		}
+	default:
+		return s.publishCaptureErrorEvent(ctx, msg, fmt.Errorf("cannot capture %s payment", payment.Status))
+	}
+
+	return s.publisher.Publish(ctx, string(event.PaymentCaptured), event.Message{
+		EventName:     event.PaymentCaptured,
+		CorrelationID: msg.CorrelationID,
+		Payload:       event.PaymentCapturedPayload{PaymentID: payment.ID},
+	})
+}
+
+// handleReleasePayment menangani void dan refund. Pembayaran yang sudah di-capture
+// di-refund, yang baru diotorisasi di-void. Jika otorisasi belum tercatat, pembayaran
+// dicatat sebagai VOIDED agar otorisasi yang datang terlambat ditolak. Command refund
+// dibalas dengan PaymentRefunded, sedangkan void tidak dibalas.
+func (s *service) handleReleasePayment(ctx context.Context, msg event.Message) error {
+	// RefundPaymentPayload mencakup seluruh field VoidPaymentPayload
+	payload, err := mapToPayload[event.RefundPaymentPayload](msg)
+	if err != nil {
+		return err
+	}
+
+	payment, err := s.repo.GetPaymentByOrderID(ctx, payload.OrderID)
+	if errors.Is(err, ErrPaymentNotFound) {
+		err = s.repo.CreatePayment(ctx, &Payment{
+			ID:        ulid.Make().String(),
+			OrderID:   payload.OrderID,
+			Status:    PaymentStatusVoided,
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		})
+		if !errors.Is(err, ErrPaymentAlreadyExists) {
+			return err
+		}
+		// Otorisasi tercatat bersamaan, ulangi dengan pembayaran yang ada
+		payment, err = s.repo.GetPaymentByOrderID(ctx, payload.OrderID)
+	}
+	if err != nil {
+		return err
+	}
+
+	switch payment.Status {
+	case PaymentStatusAuthorized:
+		if err := s.provider.Void(ctx, payment.AuthorizationID); err != nil {
+			return err
+		}
+		payment.Status = PaymentStatusVoided
+	case PaymentStatusCaptured:
+		amount := payment.Amount
+		if msg.EventName == event.CommandRefundPayment {
+			amount = min(max(payload.Amount, 0), payment.Amount)
+		}
+		if amount > 0 {
+			if err := s.provider.Refund(ctx, payment.AuthorizationID, amount); err != nil {
+				return err
+			}
+		}
+		payment.Status = PaymentStatusRefunded
+		payment.RefundedAmount = amount
+	case PaymentStatusRefunded:
+		// Refund sudah pernah dilakukan, cukup kirim ulang balasannya
+		return s.publishRefunded(ctx, msg, payment)
+	default:
+		return nil
+	}
+
+	if err := s.repo.UpdatePayment(ctx, payment); err != nil {
+		return err
+	}
+
+	return s.publishRefunded(ctx, msg, payment)
+}
+
+func (s *service) publishRefunded(ctx context.Context, msg event.Message, payment *Payment) error {
+	if msg.EventName != event.CommandRefundPayment || payment.Status != PaymentStatusRefunded {
+		return nil
+	}
+
+	return s.publisher.Publish(ctx, string(event.PaymentRefunded), event.Message{
+		EventName:     event.PaymentRefunded,
+		CorrelationID: msg.CorrelationID,
+		Payload: event.PaymentRefundedPayload{
+			PaymentID: payment.ID,
+			Amount:    payment.RefundedAmount,
+		},
+	})
+}
+
+// handleAdjustPayment menarik atau mengembalikan selisih harga modifikasi order yang
+// pembayarannya sudah di-capture. Penarikan diotorisasi lalu langsung di-capture, dan
+// pengembalian penarikan di-refund dari otorisasi penarikan tersebut. Command yang
+// terkirim ulang untuk AdjustmentID yang sudah tercatat cukup dibalas ulang.
+func (s *service) handleAdjustPayment(ctx context.Context, msg event.Message) error {
+	payload, err := mapToPayload[event.AdjustPaymentPayload](msg)
+	if err != nil {
+		return s.publishAdjustmentErrorEvent(ctx, msg, "", err)
+	}
+
+	payment, err := s.repo.GetPaymentByOrderID(ctx, payload.OrderID)
+	if err != nil {
+		return s.publishAdjustmentErrorEvent(ctx, msg, payload.AdjustmentID, err)
+	}
+	for _, adjustment := range payment.Adjustments {
+		if adjustment.ID == payload.AdjustmentID {
+			return s.publishAdjusted(ctx, msg, payment, adjustment)
+		}
+	}
+	if payment.Status != PaymentStatusCaptured {
+		return s.publishAdjustmentErrorEvent(ctx, msg, payload.AdjustmentID, fmt.Errorf("cannot adjust %s payment", payment.Status))
+	}
+
+	adjustment := Adjustment{ID: payload.AdjustmentID, Amount: payload.Amount, CreatedAt: time.Now()}
+	switch {
+	case payload.Amount > 0:
+		authorizationID, err := s.provider.Authorize(ctx, payment.UserID, payload.Amount, payment.Currency)
+		if err != nil {
+			return s.publishAdjustmentErrorEvent(ctx, msg, payload.AdjustmentID, err)
+		}
+		if err := s.provider.Capture(ctx, authorizationID, payload.Amount); err != nil {
+			if voidErr := s.provider.Void(ctx, authorizationID); voidErr != nil {
+				err = errors.Join(err, voidErr)
+			}
+			return s.publishAdjustmentErrorEvent(ctx, msg, payload.AdjustmentID, err)
+		}
+		adjustment.AuthorizationID = authorizationID
+	case payload.Amount < 0:
+		authorizationID := payment.AuthorizationID
+		for _, reverted := range payment.Adjustments {
+			if reverted.ID == payload.RevertsID {
+				authorizationID = reverted.AuthorizationID
+			}
+		}
+		if err := s.provider.Refund(ctx, authorizationID, -payload.Amount); err != nil {
+			return s.publishAdjustmentErrorEvent(ctx, msg, payload.AdjustmentID, err)
+		}
+	}
+
+	payment.Amount += payload.Amount
+	payment.Adjustments = append(payment.Adjustments, adjustment)
+	if err := s.repo.UpdatePayment(ctx, payment); err != nil {
+		return s.publishAdjustmentErrorEvent(ctx, msg, payload.AdjustmentID, err)
+	}
+
+	return s.publishAdjusted(ctx, msg, payment, adjustment)
+}
+
+func (s *service) publishAdjusted(ctx context.Context, msg event.Message, payment *Payment, adjustment Adjustment) error {
+	return s.publisher.Publish(ctx, string(event.PaymentAdjusted), event.Message{
+		EventName:     event.PaymentAdjusted,
+		CorrelationID: msg.CorrelationID,
+		Payload: event.PaymentAdjustedPayload{
+			PaymentID:    payment.ID,
+			AdjustmentID: adjustment.ID,
+			Amount:       adjustment.Amount,
+		},
+	})
+}
+
+func (s *service) publishAdjustmentErrorEvent(ctx context.Context, msg event.Message, adjustmentID string, err error) error {
+	if pubErr := s.publisher.Publish(ctx, string(event.PaymentAdjustmentFailed), event.Message{
+		EventName:     event.PaymentAdjustmentFailed,
+		CorrelationID: msg.CorrelationID,
+		Payload: event.PaymentAdjustmentFailedPayload{
+			AdjustmentID:  adjustmentID,
+			FailureReason: err.Error(),
+		},
+	}); pubErr != nil {
+		return errors.Join(err, pubErr)
+	}
+
+	return err
+}
+
+func mapToPayload[T any](msg event.Message) (T, error) {
+	var payload T
+	marshalledPayload, err := json.Marshal(msg.Payload)
+	if err != nil {
+		return payload, err
+	}
+	if err := json.Unmarshal(marshalledPayload, &payload); err != nil {
+		return payload, err
+	}
+	return payload, nil
+}
@@ -0,0 +1,285 @@
+package pricing
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/config"
+)
+
+// Currency adalah mata uang seluruh harga, nominal disimpan dalam satuan rupiah
+const Currency = "IDR"
+
+type SeatClass string
+
+const (
+	SeatClassExecutive SeatClass = "EXECUTIVE"
+	SeatClassBusiness  SeatClass = "BUSINESS"
+	SeatClassEconomy   SeatClass = "ECONOMY"
+)
+
+// Season adalah periode dengan tambahan tarif dalam persen, mis. libur akhir tahun.
+// StartDate dan EndDate bersifat inklusif.
+type Season struct {
+	Name             string `firestore:"name" json:"name"`
+	StartDate        string `firestore:"start_date" json:"start_date"`
+	EndDate          string `firestore:"end_date" json:"end_date"`
+	SurchargePercent int64  `firestore:"surcharge_percent" json:"surcharge_percent"`
+}
+
+// RoomRate adalah tarif per malam untuk satu kamar atau satu tipe kamar. Malam Jumat
+// dan Sabtu menggunakan WeekendRate.
+type RoomRate struct {
+	HotelRoomID string   `firestore:"hotel_room_id,omitempty" json:"hotel_room_id,omitempty"`
+	RoomTypeID  string   `firestore:"room_type_id,omitempty" json:"room_type_id,omitempty"`
+	NightlyRate int64    `firestore:"nightly_rate" json:"nightly_rate"`
+	WeekendRate int64    `firestore:"weekend_rate" json:"weekend_rate"`
+	Seasons     []Season `firestore:"seasons" json:"seasons"`
+}
+
+// CarRate adalah tarif sewa harian untuk satu mobil. Hari Sabtu dan Minggu
+// menggunakan WeekendRate.
+type CarRate struct {
+	CarID       string   `firestore:"car_id" json:"car_id"`
+	DailyRate   int64    `firestore:"daily_rate" json:"daily_rate"`
+	WeekendRate int64    `firestore:"weekend_rate" json:"weekend_rate"`
+	Seasons     []Season `firestore:"seasons" json:"seasons"`
+}
+
+// ClassFare adalah tarif per segmen untuk kelas yang menempati gerbong
+// FirstCoach sampai LastCoach
+type ClassFare struct {
+	Class       SeatClass `firestore:"class" json:"class"`
+	FirstCoach  int       `firestore:"first_coach" json:"first_coach"`
+	LastCoach   int       `firestore:"last_coach" json:"last_coach"`
+	SegmentFare int64     `firestore:"segment_fare" json:"segment_fare"`
+}
+
+// JourneyFare berisi tarif kursi per kelas untuk satu perjalanan kereta. Harga kursi
+// adalah SegmentFare dikali jumlah segmen antara stasiun naik dan stasiun turun.
+type JourneyFare struct {
+	JourneyID string      `firestore:"journey_id" json:"journey_id"`
+	Stations  []string    `firestore:"stations" json:"stations"`
+	Classes   []ClassFare `firestore:"classes" json:"classes"`
+}
+
+type FareClass string
+
+const (
+	FareClassFirst    FareClass = "FIRST"
+	FareClassBusiness FareClass = "BUSINESS"
+	FareClassEconomy  FareClass = "ECONOMY"
+)
+
+// FlightClassFare adalah tarif kursi untuk kelas yang menempati baris FirstRow sampai LastRow
+type FlightClassFare struct {
+	Class    FareClass `firestore:"class" json:"class"`
+	FirstRow int       `firestore:"first_row" json:"first_row"`
+	LastRow  int       `firestore:"last_row" json:"last_row"`
+	Fare     int64     `firestore:"fare" json:"fare"`
+}
+
+// FlightFare berisi tarif kursi per kelas untuk satu penerbangan
+type FlightFare struct {
+	FlightID string            `firestore:"flight_id" json:"flight_id"`
+	Classes  []FlightClassFare `firestore:"classes" json:"classes"`
+}
+
+// RoomLine adalah harga satu kamar dalam quote
+type RoomLine struct {
+	HotelRoomID string `firestore:"hotel_room_id,omitempty" json:"hotel_room_id,omitempty"`
+	RoomTypeID  string `firestore:"room_type_id,omitempty" json:"room_type_id,omitempty"`
+	StartDate   string `firestore:"start_date" json:"start_date"`
+	EndDate     string `firestore:"end_date" json:"end_date"`
+	Nights      int    `firestore:"nights" json:"nights"`
+	Price       int64  `firestore:"price" json:"price"`
+}
+
+// CarLine adalah harga satu mobil dalam quote
+type CarLine struct {
+	CarID     string `firestore:"car_id" json:"car_id"`
+	StartDate string `firestore:"start_date" json:"start_date"`
+	EndDate   string `firestore:"end_date" json:"end_date"`
+	Days      int    `firestore:"days" json:"days"`
+	Price     int64  `firestore:"price" json:"price"`
+}
+
+// SeatLine adalah harga satu kursi kereta dalam quote
+type SeatLine struct {
+	JourneyID          string    `firestore:"journey_id" json:"journey_id"`
+	DepartureDate      string    `firestore:"departure_date" json:"departure_date"`
+	SeatID             string    `firestore:"seat_id" json:"seat_id"`
+	OriginStation      string    `firestore:"origin_station" json:"origin_station"`
+	DestinationStation string    `firestore:"destination_station" json:"destination_station"`
+	Class              SeatClass `firestore:"class" json:"class"`
+	Segments           int       `firestore:"segments" json:"segments"`
+	Price              int64     `firestore:"price" json:"price"`
+}
+
+// FlightLine adalah harga satu kursi pesawat dalam quote
+type FlightLine struct {
+	FlightID      string    `firestore:"flight_id" json:"flight_id"`
+	DepartureDate string    `firestore:"departure_date" json:"departure_date"`
+	SeatID        string    `firestore:"seat_id" json:"seat_id"`
+	Class         FareClass `firestore:"class" json:"class"`
+	Price         int64     `firestore:"price" json:"price"`
+}
+
+// Quote adalah harga calon booking yang berlaku sampai ExpiresAt. Quote hanya
+// dapat dipakai oleh satu order, OrderID terisi setelah quote dipakai.
+type Quote struct {
+	ID         string       `firestore:"id" json:"quote_id"`
+	HotelRooms []RoomLine   `firestore:"hotel_rooms" json:"hotel_rooms"`
+	Cars       []CarLine    `firestore:"cars" json:"cars"`
+	TrainSeats []SeatLine   `firestore:"train_seats" json:"train_seats"`
+	Flights    []FlightLine `firestore:"flights" json:"flights"`
+	TotalPrice int64        `firestore:"total_price" json:"total_price"`
+	Currency   string       `firestore:"currency" json:"currency"`
+	OrderID    string       `firestore:"order_id,omitempty" json:"order_id,omitempty"`
+	CreatedAt  time.Time    `firestore:"created_at" json:"created_at"`
+	ExpiresAt  time.Time    `firestore:"expires_at" json:"expires_at"`
+}
+
+// HolidaySeasons mengembalikan musim liburan pada tahun year, termasuk libur
+// akhir tahun sebelumnya yang berlanjut ke awal tahun year
+func HolidaySeasons(year int) []Season {
+	return []Season{
+		{Name: "Libur Natal dan Tahun Baru", StartDate: fmt.Sprintf("%d-12-20", year-1), EndDate: fmt.Sprintf("%d-01-05", year), SurchargePercent: 35},
+		{Name: "Libur Sekolah", StartDate: fmt.Sprintf("%d-06-20", year), EndDate: fmt.Sprintf("%d-07-15", year), SurchargePercent: 20},
+		{Name: "Libur Natal dan Tahun Baru", StartDate: fmt.Sprintf("%d-12-20", year), EndDate: fmt.Sprintf("%d-01-05", year+1), SurchargePercent: 35},
+	}
+}
+
+// dailyPrice menghitung tarif satu tanggal. Tarif musim liburan ditambahkan di atas
+// tarif hari biasa maupun akhir pekan.
+func dailyPrice(rate, weekendRate int64, weekend bool, seasons []Season, date string) int64 {
+	price := rate
+	if weekend {
+		price = weekendRate
+	}
+
+	for _, season := range seasons {
+		if date >= season.StartDate && date <= season.EndDate {
+			return price + price*season.SurchargePercent/100
+		}
+	}
+
+	return price
+}
+
+// Price menghitung harga kamar untuk setiap malam dari start sampai end (inklusif),
+// sama dengan rentang tanggal yang dikunci reservasi
+func (r *RoomRate) Price(start, end time.Time) (nights int, price int64) {
+	for date := start; !date.After(end); date = date.AddDate(0, 0, 1) {
+		weekend := date.Weekday() == time.Friday || date.Weekday() == time.Saturday
+		price += dailyPrice(r.NightlyRate, r.WeekendRate, weekend, r.Seasons, date.Format(config.DateFormat))
+		nights++
+	}
+	return nights, price
+}
+
+// Price menghitung harga sewa mobil untuk setiap hari dari start sampai end (inklusif)
+func (r *CarRate) Price(start, end time.Time) (days int, price int64) {
+	for date := start; !date.After(end); date = date.AddDate(0, 0, 1) {
+		weekend := date.Weekday() == time.Saturday || date.Weekday() == time.Sunday
+		price += dailyPrice(r.DailyRate, r.WeekendRate, weekend, r.Seasons, date.Format(config.DateFormat))
+		days++
+	}
+	return days, price
+}
+
+// ClassOf mengembalikan tarif kelas untuk kursi dengan format "{gerbong}-{nomor}"
+func (f *JourneyFare) ClassOf(seatID string) (ClassFare, bool) {
+	var coach, number int
+	if _, err := fmt.Sscanf(seatID, "%d-%d", &coach, &number); err != nil {
+		return ClassFare{}, false
+	}
+
+	for _, class := range f.Classes {
+		if coach >= class.FirstCoach && coach <= class.LastCoach {
+			return class, true
+		}
+	}
+
+	return ClassFare{}, false
+}
+
+// ClassOf mengembalikan tarif kelas untuk kursi dengan format "{baris}{huruf}"
+func (f *FlightFare) ClassOf(seatID string) (FlightClassFare, bool) {
+	var row int
+	var letter rune
+	if _, err := fmt.Sscanf(seatID, "%d%c", &row, &letter); err != nil {
+		return FlightClassFare{}, false
+	}
+
+	for _, class := range f.Classes {
+		if row >= class.FirstRow && row <= class.LastRow {
+			return class, true
+		}
+	}
+
+	return FlightClassFare{}, false
+}
+
+// Segments mengembalikan jumlah segmen antara stasiun naik dan stasiun turun.
+// ok bernilai false jika stasiun tidak ada di rute atau urutannya terbalik.
+func (f *JourneyFare) Segments(origin, destination string) (int, bool) {
+	from, to := -1, -1
+	for i, station := range f.Stations {
+		if station == origin && from == -1 {
+			from = i
+		}
+		if station == destination {
+			to = i
+		}
+	}
+
+	if from == -1 || to == -1 || from >= to {
+		return 0, false
+	}
+
+	return to - from, true
+}
+
+// Expired mengecek apakah quote sudah tidak berlaku pada waktu now
+func (q *Quote) Expired(now time.Time) bool {
+	return !now.Before(q.ExpiresAt)
+}
+
+// Matches mengecek apakah item pada req sama persis dengan item yang diberi harga di quote
+func (q *Quote) Matches(req QuoteRequest) bool {
+	if len(q.HotelRooms) != len(req.HotelRooms) || len(q.Cars) != len(req.Cars) || len(q.TrainSeats) != len(req.TrainSeats) || len(q.Flights) != len(req.Flights) {
+		return false
+	}
+
+	for i, room := range req.HotelRooms {
+		line := q.HotelRooms[i]
+		if line.HotelRoomID != room.HotelRoomID || line.RoomTypeID != room.RoomTypeID || line.StartDate != room.StartDate || line.EndDate != room.EndDate {
+			return false
+		}
+	}
+
+	for i, car := range req.Cars {
+		line := q.Cars[i]
+		if line.CarID != car.CarID || line.StartDate != car.StartDate || line.EndDate != car.EndDate {
+			return false
+		}
+	}
+
+	for i, seat := range req.TrainSeats {
+		line := q.TrainSeats[i]
+		if line.JourneyID != seat.JourneyID || line.DepartureDate != seat.DepartureDate || line.SeatID != seat.SeatID ||
+			line.OriginStation != seat.OriginStation || line.DestinationStation != seat.DestinationStation {
+			return false
+		}
+	}
+
+	for i, flight := range req.Flights {
+		line := q.Flights[i]
+		if line.FlightID != flight.FlightID || line.DepartureDate != flight.DepartureDate || line.SeatID != flight.SeatID {
+			return false
+		}
+	}
+
+	return true
+}
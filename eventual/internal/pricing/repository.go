@@ -0,0 +1,142 @@
+package pricing
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	ErrQuoteNotFound    = errors.New("quote not found")
+	ErrQuoteExpired     = errors.New("quote has expired")
+	ErrQuoteAlreadyUsed = errors.New("quote has already been used by another order")
+)
+
+type Repository interface {
+	GetRoomRates(ctx context.Context, hotelRoomIDs []string) (map[string]*RoomRate, error)
+	GetCarRates(ctx context.Context, carIDs []string) (map[string]*CarRate, error)
+	GetJourneyFares(ctx context.Context, journeyIDs []string) (map[string]*JourneyFare, error)
+	GetFlightFares(ctx context.Context, flightIDs []string) (map[string]*FlightFare, error)
+	CreateQuote(ctx context.Context, quote *Quote) error
+	GetQuoteByID(ctx context.Context, id string) (*Quote, error)
+	RedeemQuote(ctx context.Context, id, orderID string, now time.Time) error
+}
+
+const (
+	roomRateCollection    = "pricing_room_rates"
+	carRateCollection     = "pricing_car_rates"
+	journeyFareCollection = "pricing_journey_fares"
+	flightFareCollection  = "pricing_flight_fares"
+	quoteCollection       = "pricing_quotes"
+)
+
+type firestoreRepository struct {
+	client *firestore.Client
+}
+
+func NewFirestoreRepository(client *firestore.Client) Repository {
+	return &firestoreRepository{client: client}
+}
+
+func (r *firestoreRepository) GetRoomRates(ctx context.Context, hotelRoomIDs []string) (map[string]*RoomRate, error) {
+	return getAll[RoomRate](ctx, r.client, roomRateCollection, hotelRoomIDs)
+}
+
+func (r *firestoreRepository) GetCarRates(ctx context.Context, carIDs []string) (map[string]*CarRate, error) {
+	return getAll[CarRate](ctx, r.client, carRateCollection, carIDs)
+}
+
+func (r *firestoreRepository) GetJourneyFares(ctx context.Context, journeyIDs []string) (map[string]*JourneyFare, error) {
+	return getAll[JourneyFare](ctx, r.client, journeyFareCollection, journeyIDs)
+}
+
+func (r *firestoreRepository) GetFlightFares(ctx context.Context, flightIDs []string) (map[string]*FlightFare, error) {
+	return getAll[FlightFare](ctx, r.client, flightFareCollection, flightIDs)
+}
+
+// getAll membaca dokumen-dokumen dengan ID tertentu dalam satu request.
+// Dokumen yang tidak ada tidak dimasukkan ke dalam map.
+func getAll[T any](ctx context.Context, client *firestore.Client, collection string, ids []string) (map[string]*T, error) {
+	refs := make([]*firestore.DocumentRef, 0, len(ids))
+	for _, id := range ids {
+		refs = append(refs, client.Collection(collection).Doc(id))
+	}
+
+	docs, err := client.GetAll(ctx, refs)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]*T, len(docs))
+	for _, doc := range docs {
+		if !doc.Exists() {
+			continue
+		}
+
+		var data T
+		if err := doc.DataTo(&data); err != nil {
+			return nil, err
+		}
+		result[doc.Ref.ID] = &data
+	}
+
+	return result, nil
+}
+
+func (r *firestoreRepository) CreateQuote(ctx context.Context, quote *Quote) error {
+	_, err := r.client.Collection(quoteCollection).Doc(quote.ID).Create(ctx, quote)
+	return err
+}
+
+func (r *firestoreRepository) GetQuoteByID(ctx context.Context, id string) (*Quote, error) {
+	doc, err := r.client.Collection(quoteCollection).Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, ErrQuoteNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var quote Quote
+	if err := doc.DataTo(&quote); err != nil {
+		return nil, err
+	}
+
+	return &quote, nil
+}
+
+// RedeemQuote menandai quote sudah dipakai oleh orderID dalam satu transaksi,
+// sehingga dua order tidak dapat memakai quote yang sama
+func (r *firestoreRepository) RedeemQuote(ctx context.Context, id, orderID string, now time.Time) error {
+	ref := r.client.Collection(quoteCollection).Doc(id)
+
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return ErrQuoteNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		var quote Quote
+		if err := doc.DataTo(&quote); err != nil {
+			return err
+		}
+
+		if quote.OrderID != "" && quote.OrderID != orderID {
+			return ErrQuoteAlreadyUsed
+		}
+		if quote.Expired(now) {
+			return ErrQuoteExpired
+		}
+
+		return tx.Update(ref, []firestore.Update{
+			{Path: "order_id", Value: orderID},
+		})
+	})
+}
@@ -0,0 +1,320 @@
+package pricing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/config"
+)
+
+// RoomQuoteRequest memberi harga satu kamar fisik atau satu tipe kamar
+type RoomQuoteRequest struct {
+	HotelRoomID string `json:"hotel_room_id" binding:"required_without=RoomTypeID,excluded_with=RoomTypeID"`
+	RoomTypeID  string `json:"room_type_id" binding:"required_without=HotelRoomID"`
+	StartDate   string `json:"start_date" binding:"required"`
+	EndDate     string `json:"end_date" binding:"required"`
+}
+
+// rateID adalah ID tarif yang dipakai, tarif tipe kamar disimpan dengan ID tipe kamar
+func (r RoomQuoteRequest) rateID() string {
+	if r.RoomTypeID != "" {
+		return r.RoomTypeID
+	}
+	return r.HotelRoomID
+}
+
+type CarQuoteRequest struct {
+	CarID     string `json:"car_id" binding:"required"`
+	StartDate string `json:"start_date" binding:"required"`
+	EndDate   string `json:"end_date" binding:"required"`
+}
+
+type SeatQuoteRequest struct {
+	JourneyID          string `json:"journey_id" binding:"required"`
+	DepartureDate      string `json:"departure_date" binding:"required"`
+	SeatID             string `json:"seat_id" binding:"required"`
+	OriginStation      string `json:"origin_station" binding:"required"`
+	DestinationStation string `json:"destination_station" binding:"required"`
+}
+
+type FlightQuoteRequest struct {
+	FlightID      string `json:"flight_id" binding:"required"`
+	DepartureDate string `json:"departure_date" binding:"required"`
+	SeatID        string `json:"seat_id" binding:"required"`
+}
+
+// QuoteRequest berisi item yang akan diberi harga, dengan bentuk yang sama seperti
+// item pada POST /orders
+type QuoteRequest struct {
+	HotelRooms []RoomQuoteRequest   `json:"hotel_rooms" binding:"omitempty,dive"`
+	Cars       []CarQuoteRequest    `json:"cars" binding:"omitempty,dive"`
+	TrainSeats []SeatQuoteRequest   `json:"train_seats" binding:"omitempty,dive"`
+	Flights    []FlightQuoteRequest `json:"flights" binding:"omitempty,dive"`
+}
+
+var (
+	ErrEmptyQuote         = errors.New("quote must contain at least one hotel room, car, train seat or flight seat")
+	ErrInvalidDateRange   = errors.New("end date must not be before start date")
+	ErrRateNotFound       = errors.New("rate not found")
+	ErrSeatClassNotFound  = errors.New("seat class not found")
+	ErrStationsNotOnRoute = errors.New("origin and destination stations are not on the journey route")
+	ErrQuoteMismatch      = errors.New("order items do not match the quote")
+)
+
+// IsRequestError mengecek apakah err disebabkan oleh isi request, bukan kegagalan sistem
+func IsRequestError(err error) bool {
+	for _, target := range []error{ErrEmptyQuote, ErrInvalidDateRange, ErrRateNotFound, ErrSeatClassNotFound, ErrStationsNotOnRoute, ErrQuoteMismatch} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	var parseErr *time.ParseError
+	return errors.As(err, &parseErr)
+}
+
+type Service interface {
+	// CreateQuote menghitung harga req dan menyimpannya sebagai quote yang berlaku selama TTL
+	CreateQuote(ctx context.Context, req QuoteRequest) (*Quote, error)
+
+	// Price menghitung harga req dengan tarif saat ini tanpa menyimpan quote
+	Price(ctx context.Context, req QuoteRequest) (*Quote, error)
+
+	// RedeemQuote mengunci quote untuk orderID. Item pada req harus sama dengan item di quote.
+	RedeemQuote(ctx context.Context, quoteID, orderID string, req QuoteRequest) (*Quote, error)
+}
+
+type service struct {
+	repo Repository
+	ttl  time.Duration
+}
+
+func NewService(repo Repository, ttl time.Duration) Service {
+	return &service{repo: repo, ttl: ttl}
+}
+
+func (s *service) CreateQuote(ctx context.Context, req QuoteRequest) (*Quote, error) {
+	quote, err := s.Price(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	quote.ID = ulid.Make().String()
+	quote.CreatedAt = time.Now()
+	quote.ExpiresAt = quote.CreatedAt.Add(s.ttl)
+	if err := s.repo.CreateQuote(ctx, quote); err != nil {
+		return nil, err
+	}
+
+	return quote, nil
+}
+
+func (s *service) RedeemQuote(ctx context.Context, quoteID, orderID string, req QuoteRequest) (*Quote, error) {
+	quote, err := s.repo.GetQuoteByID(ctx, quoteID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !quote.Matches(req) {
+		return nil, ErrQuoteMismatch
+	}
+
+	if err := s.repo.RedeemQuote(ctx, quoteID, orderID, time.Now()); err != nil {
+		return nil, err
+	}
+	quote.OrderID = orderID
+
+	return quote, nil
+}
+
+func (s *service) Price(ctx context.Context, req QuoteRequest) (*Quote, error) {
+	if len(req.HotelRooms) == 0 && len(req.Cars) == 0 && len(req.TrainSeats) == 0 && len(req.Flights) == 0 {
+		return nil, ErrEmptyQuote
+	}
+
+	quote := &Quote{
+		HotelRooms: make([]RoomLine, 0, len(req.HotelRooms)),
+		Cars:       make([]CarLine, 0, len(req.Cars)),
+		TrainSeats: make([]SeatLine, 0, len(req.TrainSeats)),
+		Flights:    make([]FlightLine, 0, len(req.Flights)),
+		Currency:   Currency,
+	}
+
+	if len(req.HotelRooms) > 0 {
+		ids := make([]string, 0, len(req.HotelRooms))
+		for _, room := range req.HotelRooms {
+			ids = append(ids, room.rateID())
+		}
+		rates, err := s.repo.GetRoomRates(ctx, uniqueIDs(ids))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, room := range req.HotelRooms {
+			start, end, err := parseDateRange(room.StartDate, room.EndDate)
+			if err != nil {
+				return nil, err
+			}
+			rate, ok := rates[room.rateID()]
+			if !ok {
+				return nil, fmt.Errorf("%w: hotel room %s", ErrRateNotFound, room.rateID())
+			}
+
+			nights, price := rate.Price(start, end)
+			quote.HotelRooms = append(quote.HotelRooms, RoomLine{
+				HotelRoomID: room.HotelRoomID,
+				RoomTypeID:  room.RoomTypeID,
+				StartDate:   start.Format(config.DateFormat),
+				EndDate:     end.Format(config.DateFormat),
+				Nights:      nights,
+				Price:       price,
+			})
+			quote.TotalPrice += price
+		}
+	}
+
+	if len(req.Cars) > 0 {
+		ids := make([]string, 0, len(req.Cars))
+		for _, car := range req.Cars {
+			ids = append(ids, car.CarID)
+		}
+		rates, err := s.repo.GetCarRates(ctx, uniqueIDs(ids))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, car := range req.Cars {
+			start, end, err := parseDateRange(car.StartDate, car.EndDate)
+			if err != nil {
+				return nil, err
+			}
+			rate, ok := rates[car.CarID]
+			if !ok {
+				return nil, fmt.Errorf("%w: car %s", ErrRateNotFound, car.CarID)
+			}
+
+			days, price := rate.Price(start, end)
+			quote.Cars = append(quote.Cars, CarLine{
+				CarID:     car.CarID,
+				StartDate: start.Format(config.DateFormat),
+				EndDate:   end.Format(config.DateFormat),
+				Days:      days,
+				Price:     price,
+			})
+			quote.TotalPrice += price
+		}
+	}
+
+	if len(req.TrainSeats) > 0 {
+		ids := make([]string, 0, len(req.TrainSeats))
+		for _, seat := range req.TrainSeats {
+			ids = append(ids, seat.JourneyID)
+		}
+		fares, err := s.repo.GetJourneyFares(ctx, uniqueIDs(ids))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, seat := range req.TrainSeats {
+			departureDate, err := time.Parse(config.DateFormat, seat.DepartureDate)
+			if err != nil {
+				return nil, err
+			}
+			fare, ok := fares[seat.JourneyID]
+			if !ok {
+				return nil, fmt.Errorf("%w: train journey %s", ErrRateNotFound, seat.JourneyID)
+			}
+			class, ok := fare.ClassOf(seat.SeatID)
+			if !ok {
+				return nil, fmt.Errorf("%w: seat %s", ErrSeatClassNotFound, seat.SeatID)
+			}
+			segments, ok := fare.Segments(seat.OriginStation, seat.DestinationStation)
+			if !ok {
+				return nil, ErrStationsNotOnRoute
+			}
+
+			price := class.SegmentFare * int64(segments)
+			quote.TrainSeats = append(quote.TrainSeats, SeatLine{
+				JourneyID:          seat.JourneyID,
+				DepartureDate:      departureDate.Format(config.DateFormat),
+				SeatID:             seat.SeatID,
+				OriginStation:      seat.OriginStation,
+				DestinationStation: seat.DestinationStation,
+				Class:              class.Class,
+				Segments:           segments,
+				Price:              price,
+			})
+			quote.TotalPrice += price
+		}
+	}
+
+	if len(req.Flights) > 0 {
+		ids := make([]string, 0, len(req.Flights))
+		for _, flight := range req.Flights {
+			ids = append(ids, flight.FlightID)
+		}
+		fares, err := s.repo.GetFlightFares(ctx, uniqueIDs(ids))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, flight := range req.Flights {
+			departureDate, err := time.Parse(config.DateFormat, flight.DepartureDate)
+			if err != nil {
+				return nil, err
+			}
+			fare, ok := fares[flight.FlightID]
+			if !ok {
+				return nil, fmt.Errorf("%w: flight %s", ErrRateNotFound, flight.FlightID)
+			}
+			class, ok := fare.ClassOf(flight.SeatID)
+			if !ok {
+				return nil, fmt.Errorf("%w: seat %s", ErrSeatClassNotFound, flight.SeatID)
+			}
+
+			quote.Flights = append(quote.Flights, FlightLine{
+				FlightID:      flight.FlightID,
+				DepartureDate: departureDate.Format(config.DateFormat),
+				SeatID:        flight.SeatID,
+				Class:         class.Class,
+				Price:         class.Fare,
+			})
+			quote.TotalPrice += class.Fare
+		}
+	}
+
+	return quote, nil
+}
+
+// parseDateRange memvalidasi rentang tanggal dalam format config.DateFormat
+func parseDateRange(startDate, endDate string) (time.Time, time.Time, error) {
+	start, err := time.Parse(config.DateFormat, startDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	end, err := time.Parse(config.DateFormat, endDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if end.Before(start) {
+		return time.Time{}, time.Time{}, ErrInvalidDateRange
+	}
+	return start, end, nil
+}
+
+// uniqueIDs menghapus ID duplikat agar setiap dokumen hanya dibaca sekali
+func uniqueIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	result := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	return result
+}
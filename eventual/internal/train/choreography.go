@@ -0,0 +1,46 @@
+package train
+
+import (
+	"context"
+	"log"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/event"
+)
+
+// ProcessChoreographyEvent menjalankan bagian train service pada saga booking koreografi.
+// Kursi direservasi setelah mobil berhasil direservasi dan dibatalkan saat partisipan
+// setelahnya gagal.
+func (s *service) ProcessChoreographyEvent(ctx context.Context, msg event.Message) error {
+	switch msg.EventName {
+	case event.CarReserved:
+		payload, err := mapToPayload[event.CarReservedPayload](msg)
+		if err != nil {
+			return s.publishErrorEvent(ctx, msg, err)
+		}
+		// Event tanpa order dibuang seperti di car service
+		if payload.Order == nil {
+			log.Printf("Dropping %s of order %s without order payload", msg.EventName, msg.CorrelationID)
+			return nil
+		}
+		if len(payload.Order.Seats) == 0 {
+			return s.publisher.Publish(ctx, string(event.SeatReserved), event.Message{
+				EventName:     event.SeatReserved,
+				CorrelationID: msg.CorrelationID,
+				Payload:       event.SeatReservedPayload{Order: payload.Order},
+			})
+		}
+		return s.handleReserveSeat(ctx, event.Message{
+			EventName:     event.CommandReserveSeat,
+			CorrelationID: msg.CorrelationID,
+			Payload:       event.ReserveSeatPayload{Seats: payload.Order.Seats, Order: payload.Order},
+		})
+	case event.FlightReservationFailed, event.PaymentFailed, event.PaymentCaptureFailed:
+		return s.handleCancelSeat(ctx, event.Message{
+			EventName:     event.CommandCancelSeat,
+			CorrelationID: msg.CorrelationID,
+			Payload:       event.CancelSeatPayload{OrderID: msg.CorrelationID},
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package train
+
+import (
+	"context"
+	"time"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/fault"
+)
+
+// faultyRepository menyuntikkan kegagalan sebelum dan sesudah setiap penulisan
+// reservasi. Pembacaan diteruskan tanpa perubahan.
+type faultyRepository struct {
+	Repository
+	injector *fault.Injector
+}
+
+func NewFaultyRepository(repo Repository, injector *fault.Injector) Repository {
+	return &faultyRepository{Repository: repo, injector: injector}
+}
+
+func (r *faultyRepository) CreateTrainReservations(ctx context.Context, trainReservations []*TrainReservation) error {
+	orderID := ""
+	if len(trainReservations) > 0 {
+		orderID = trainReservations[0].OrderID
+	}
+	return r.injector.Do("CreateTrainReservations", orderID, func() error {
+		return r.Repository.CreateTrainReservations(ctx, trainReservations)
+	})
+}
+
+func (r *faultyRepository) ReplaceTrainReservation(ctx context.Context, replacedID string, trainReservation *TrainReservation) error {
+	return r.injector.Do("ReplaceTrainReservation", trainReservation.OrderID, func() error {
+		return r.Repository.ReplaceTrainReservation(ctx, replacedID, trainReservation)
+	})
+}
+
+func (r *faultyRepository) UpdateTrainReservation(ctx context.Context, trainReservation *TrainReservation) error {
+	return r.injector.Do("UpdateTrainReservation", trainReservation.OrderID, func() error {
+		return r.Repository.UpdateTrainReservation(ctx, trainReservation)
+	})
+}
+
+func (r *faultyRepository) ConfirmTrainHolds(ctx context.Context, orderID string, now time.Time) ([]*TrainReservation, error) {
+	return fault.Call(r.injector, "ConfirmTrainHolds", orderID, func() ([]*TrainReservation, error) {
+		return r.Repository.ConfirmTrainHolds(ctx, orderID, now)
+	})
+}
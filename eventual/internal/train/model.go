@@ -1,22 +1,112 @@
 package train
 
+import (
+	"fmt"
+	"time"
+)
+
 type TrainReservationStatus string
 
 const (
 	TrainReservationStatusCancelled TrainReservationStatus = "CANCELLED"
 	TrainReservationStatusReserved  TrainReservationStatus = "RESERVED"
+	// HELD adalah reservasi sementara yang dilepas sweeper menjadi EXPIRED jika
+	// tidak dikonfirmasi sebelum ExpiresAt
+	TrainReservationStatusHeld    TrainReservationStatus = "HELD"
+	TrainReservationStatusExpired TrainReservationStatus = "EXPIRED"
 )
 
-type TrainSeat struct {
-	ID        string `firestore:"id" json:"id"`
-	SeatID    string `firestore:"seat_id" json:"seat_id"`
-	TrainName string `firestore:"train_name" json:"train_name"`
+// TrainJourney adalah satu perjalanan kereta pada tanggal keberangkatan tertentu.
+// Stations berisi urutan stasiun yang dilewati, segmen ke-i adalah
+// perjalanan dari Stations[i] ke Stations[i+1].
+type TrainJourney struct {
+	ID            string   `firestore:"id" json:"id"`
+	TrainName     string   `firestore:"train_name" json:"train_name"`
+	DepartureDate string   `firestore:"departure_date" json:"departure_date"`
+	Stations      []string `firestore:"stations" json:"stations"`
+	Coaches       int      `firestore:"coaches" json:"coaches"`
+	SeatsPerCoach int      `firestore:"seats_per_coach" json:"seats_per_coach"`
 }
 
 type TrainReservation struct {
-	ID        string                 `firestore:"id" json:"id"`
-	SeatID    string                 `firestore:"seat_id" json:"seat_id"`
-	TrainName string                 `firestore:"train_name" json:"train_name"`
-	OrderID   string                 `firestore:"order_id" json:"order_id"`
-	Status    TrainReservationStatus `firestore:"status" json:"status"`
+	ID                 string `firestore:"id" json:"id"`
+	JourneyID          string `firestore:"journey_id" json:"journey_id"`
+	DepartureDate      string `firestore:"departure_date" json:"departure_date"`
+	SeatID             string `firestore:"seat_id" json:"seat_id"`
+	TrainName          string `firestore:"train_name" json:"train_name"`
+	OriginStation      string `firestore:"origin_station" json:"origin_station"`
+	DestinationStation string `firestore:"destination_station" json:"destination_station"`
+	// Segmen yang dikunci adalah [FromSegment, ToSegment)
+	FromSegment int                    `firestore:"from_segment" json:"from_segment"`
+	ToSegment   int                    `firestore:"to_segment" json:"to_segment"`
+	Price       int64                  `firestore:"price" json:"price"`
+	OrderID     string                 `firestore:"order_id" json:"order_id"`
+	Status      TrainReservationStatus `firestore:"status" json:"status"`
+	// ExpiresAt hanya diisi selama reservasi berstatus HELD
+	ExpiresAt *time.Time `firestore:"expires_at,omitempty" json:"expires_at,omitempty"`
+}
+
+// SeatID membentuk ID kursi dalam satu perjalanan dengan format "{gerbong}-{nomor}"
+func SeatID(coach, number int) string {
+	return fmt.Sprintf("%d-%d", coach, number)
+}
+
+// HasSeat mengecek apakah seatID termasuk dalam susunan gerbong perjalanan ini
+func (j *TrainJourney) HasSeat(seatID string) bool {
+	var coach, number int
+	if _, err := fmt.Sscanf(seatID, "%d-%d", &coach, &number); err != nil {
+		return false
+	}
+	if SeatID(coach, number) != seatID {
+		return false
+	}
+
+	return coach >= 1 && coach <= j.Coaches && number >= 1 && number <= j.SeatsPerCoach
+}
+
+// Segments mengembalikan rentang segmen [from, to) antara stasiun naik dan
+// stasiun turun. ok bernilai false jika stasiun tidak ada di rute atau urutannya terbalik.
+func (j *TrainJourney) Segments(origin, destination string) (from, to int, ok bool) {
+	from, to = -1, -1
+	for i, station := range j.Stations {
+		if station == origin && from == -1 {
+			from = i
+		}
+		if station == destination {
+			to = i
+		}
+	}
+
+	if from == -1 || to == -1 || from >= to {
+		return 0, 0, false
+	}
+
+	return from, to, true
+}
+
+// defaultPageSize dipakai jika pencarian tidak menentukan page_size
+const defaultPageSize = 20
+
+// SearchTrainSeatsQuery adalah parameter pencarian kursi. Jika stasiun tidak diisi,
+// pencarian mencakup seluruh rute perjalanan.
+type SearchTrainSeatsQuery struct {
+	OriginStation      string `form:"origin_station"`
+	DestinationStation string `form:"destination_station"`
+	PageSize           int    `form:"page_size" binding:"omitempty,min=1,max=100"`
+	// PageToken adalah NextPageToken dari halaman sebelumnya
+	PageToken string `form:"page_token"`
+}
+
+type TrainSeat struct {
+	SeatID        string `json:"seat_id"`
+	JourneyID     string `json:"journey_id"`
+	TrainName     string `json:"train_name"`
+	DepartureDate string `json:"departure_date"`
+}
+
+// TrainSeatPage adalah satu halaman hasil pencarian kursi.
+// NextPageToken kosong pada halaman terakhir.
+type TrainSeatPage struct {
+	Items         []*TrainSeat `json:"items"`
+	NextPageToken string       `json:"next_page_token,omitempty"`
 }
@@ -3,6 +3,7 @@ package train
 import (
 	"context"
 	"errors"
+	"time"
 
 	"cloud.google.com/go/firestore"
 	"cloud.google.com/go/firestore/apiv1/firestorepb"
@@ -11,21 +12,39 @@ import (
 )
 
 var (
-	ErrTrainSeatNotFound        = errors.New("train seat not found")
-	ErrTrainReservationNotFound = errors.New("train reservation not found")
+	ErrTrainJourneyNotFound      = errors.New("train journey not found")
+	ErrTrainReservationNotFound  = errors.New("train reservation not found")
+	ErrTrainReservationNotActive = errors.New("train reservation is not active")
+	ErrTrainHoldNotFound         = errors.New("train hold not found")
+	ErrTrainHoldExpired          = errors.New("train hold has expired")
+	ErrStationsNotOnRoute        = errors.New("train journey does not serve the requested stations")
 )
 
+// TrainSeatNotAvailableError menandakan kursi pada item ke-Index sudah direservasi
+type TrainSeatNotAvailableError struct {
+	Index int
+}
+
+func (e *TrainSeatNotAvailableError) Error() string {
+	return "train seat is not available"
+}
+
 type Repository interface {
-	GetTrainSeatByID(ctx context.Context, id string) (*TrainSeat, error)
-	CreateTrainReservation(ctx context.Context, trainReservation *TrainReservation) error
+	GetTrainJourneyByID(ctx context.Context, id string) (*TrainJourney, error)
+	GetReservedTrainSeatIDs(ctx context.Context, journeyID string, fromSegment, toSegment int) (map[string]bool, error)
+	CreateTrainReservations(ctx context.Context, trainReservations []*TrainReservation) error
+	ReplaceTrainReservation(ctx context.Context, replacedID string, trainReservation *TrainReservation) error
 	GetTrainReservationByID(ctx context.Context, id string) (*TrainReservation, error)
-	GetTrainReservationByOrderID(ctx context.Context, orderID string) (*TrainReservation, error)
+	GetTrainReservationsByOrderID(ctx context.Context, orderID string) ([]*TrainReservation, error)
 	UpdateTrainReservation(ctx context.Context, trainReservation *TrainReservation) error
-	IsTrainSeatAvailable(ctx context.Context, seatID string) (bool, error)
+	IsTrainSeatAvailable(ctx context.Context, journeyID, departureDate, seatID string, fromSegment, toSegment int) (bool, error)
+	ConfirmTrainHolds(ctx context.Context, orderID string, now time.Time) ([]*TrainReservation, error)
+	GetExpiredTrainHolds(ctx context.Context, now time.Time) ([]*TrainReservation, error)
+	ExpireTrainHold(ctx context.Context, id string, now time.Time) (bool, error)
 }
 
 const (
-	trainSeatCollection        = "train_seats"
+	trainJourneyCollection     = "train_journeys"
 	trainReservationCollection = "train_reservations"
 )
 
@@ -37,26 +56,98 @@ func NewFirestoreRepository(client *firestore.Client) Repository {
 	return &firestoreRepository{client: client}
 }
 
-func (r *firestoreRepository) GetTrainSeatByID(ctx context.Context, id string) (*TrainSeat, error) {
-	doc, err := r.client.Collection(trainSeatCollection).Doc(id).Get(ctx)
+func (r *firestoreRepository) GetTrainJourneyByID(ctx context.Context, id string) (*TrainJourney, error) {
+	doc, err := r.client.Collection(trainJourneyCollection).Doc(id).Get(ctx)
 	if status.Code(err) == codes.NotFound {
-		return nil, ErrTrainSeatNotFound
+		return nil, ErrTrainJourneyNotFound
 	}
 	if err != nil {
 		return nil, err
 	}
 
-	var trainSeat TrainSeat
-	if err := doc.DataTo(&trainSeat); err != nil {
+	var trainJourney TrainJourney
+	if err := doc.DataTo(&trainJourney); err != nil {
 		return nil, err
 	}
 
-	return &trainSeat, nil
+	return &trainJourney, nil
 }
 
-func (r *firestoreRepository) CreateTrainReservation(ctx context.Context, trainReservation *TrainReservation) error {
-	_, err := r.client.Collection(trainReservationCollection).Doc(trainReservation.ID).Set(ctx, trainReservation)
-	return err
+// CreateTrainReservations membuat seluruh reservasi dalam satu transaksi.
+// Jika salah satu kursi tidak tersedia, tidak ada reservasi yang dibuat.
+func (r *firestoreRepository) CreateTrainReservations(ctx context.Context, trainReservations []*TrainReservation) error {
+	// Item dalam order yang sama juga tidak boleh saling bentrok
+	for i, a := range trainReservations {
+		for _, b := range trainReservations[:i] {
+			if a.JourneyID == b.JourneyID && a.SeatID == b.SeatID && a.FromSegment < b.ToSegment && a.ToSegment > b.FromSegment {
+				return &TrainSeatNotAvailableError{Index: i}
+			}
+		}
+	}
+
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		for i, trainReservation := range trainReservations {
+			query := r.overlappingReservations(trainReservation.JourneyID, trainReservation.DepartureDate, trainReservation.SeatID, trainReservation.FromSegment, trainReservation.ToSegment)
+			docs, err := tx.Documents(query.Limit(1)).GetAll()
+			if err != nil {
+				return err
+			}
+			if len(docs) > 0 {
+				return &TrainSeatNotAvailableError{Index: i}
+			}
+		}
+
+		for _, trainReservation := range trainReservations {
+			if err := tx.Create(r.client.Collection(trainReservationCollection).Doc(trainReservation.ID), trainReservation); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// ReplaceTrainReservation membuat trainReservation dan membatalkan reservasi replacedID
+// dalam satu transaksi. Reservasi lama tidak dihitung bentrok dengan reservasi baru.
+func (r *firestoreRepository) ReplaceTrainReservation(ctx context.Context, replacedID string, trainReservation *TrainReservation) error {
+	replacedRef := r.client.Collection(trainReservationCollection).Doc(replacedID)
+
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(replacedRef)
+		if status.Code(err) == codes.NotFound {
+			return ErrTrainReservationNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		var replaced TrainReservation
+		if err := doc.DataTo(&replaced); err != nil {
+			return err
+		}
+		if replaced.OrderID != trainReservation.OrderID || replaced.Status != TrainReservationStatusReserved {
+			return ErrTrainReservationNotActive
+		}
+
+		query := r.overlappingReservations(trainReservation.JourneyID, trainReservation.DepartureDate, trainReservation.SeatID, trainReservation.FromSegment, trainReservation.ToSegment)
+		docs, err := tx.Documents(query.Limit(2)).GetAll()
+		if err != nil {
+			return err
+		}
+		for _, doc := range docs {
+			if doc.Ref.ID != replacedID {
+				return &TrainSeatNotAvailableError{Index: 0}
+			}
+		}
+
+		if err := tx.Create(r.client.Collection(trainReservationCollection).Doc(trainReservation.ID), trainReservation); err != nil {
+			return err
+		}
+
+		return tx.Update(replacedRef, []firestore.Update{
+			{Path: "status", Value: TrainReservationStatusCancelled},
+		})
+	})
 }
 
 func (r *firestoreRepository) GetTrainReservationByID(ctx context.Context, id string) (*TrainReservation, error) {
@@ -76,23 +167,23 @@ func (r *firestoreRepository) GetTrainReservationByID(ctx context.Context, id st
 	return &trainReservation, nil
 }
 
-func (r *firestoreRepository) GetTrainReservationByOrderID(ctx context.Context, orderID string) (*TrainReservation, error) {
+func (r *firestoreRepository) GetTrainReservationsByOrderID(ctx context.Context, orderID string) ([]*TrainReservation, error) {
 	query := r.client.Collection(trainReservationCollection).Where("order_id", "==", orderID)
 	docs, err := query.Documents(ctx).GetAll()
 	if err != nil {
 		return nil, err
 	}
 
-	if len(docs) == 0 {
-		return nil, ErrTrainReservationNotFound
-	}
-
-	var trainReservation TrainReservation
-	if err := docs[0].DataTo(&trainReservation); err != nil {
-		return nil, err
+	trainReservations := make([]*TrainReservation, 0, len(docs))
+	for _, doc := range docs {
+		var trainReservation TrainReservation
+		if err := doc.DataTo(&trainReservation); err != nil {
+			return nil, err
+		}
+		trainReservations = append(trainReservations, &trainReservation)
 	}
 
-	return &trainReservation, nil
+	return trainReservations, nil
 }
 
 func (r *firestoreRepository) UpdateTrainReservation(ctx context.Context, trainReservation *TrainReservation) error {
@@ -100,10 +191,22 @@ func (r *firestoreRepository) UpdateTrainReservation(ctx context.Context, trainR
 	return err
 }
 
-func (r *firestoreRepository) IsTrainSeatAvailable(ctx context.Context, seatID string) (bool, error) {
-	query := r.client.Collection(trainReservationCollection).
+// overlappingReservations adalah query reservasi aktif pada kursi yang sama
+// yang rentang segmennya beririsan dengan [fromSegment, toSegment)
+func (r *firestoreRepository) overlappingReservations(journeyID, departureDate, seatID string, fromSegment, toSegment int) firestore.Query {
+	return r.client.Collection(trainReservationCollection).
+		Where("journey_id", "==", journeyID).
+		Where("departure_date", "==", departureDate).
 		Where("seat_id", "==", seatID).
-		Where("status", "!=", TrainReservationStatusCancelled)
+		Where("from_segment", "<", toSegment).
+		Where("to_segment", ">", fromSegment).
+		Where("status", "not-in", []TrainReservationStatus{TrainReservationStatusCancelled, TrainReservationStatusExpired})
+}
+
+// IsTrainSeatAvailable mengecek apakah kursi kosong pada segmen [fromSegment, toSegment).
+// Reservasi lain hanya bentrok jika rentang segmennya beririsan.
+func (r *firestoreRepository) IsTrainSeatAvailable(ctx context.Context, journeyID, departureDate, seatID string, fromSegment, toSegment int) (bool, error) {
+	query := r.overlappingReservations(journeyID, departureDate, seatID, fromSegment, toSegment)
 
 	aggregationQuery := query.NewAggregationQuery().WithCount("all")
 	results, err := aggregationQuery.Get(ctx)
@@ -120,3 +223,142 @@ func (r *firestoreRepository) IsTrainSeatAvailable(ctx context.Context, seatID s
 
 	return countValue.GetIntegerValue() == 0, nil
 }
+
+// GetReservedTrainSeatIDs mengembalikan kursi perjalanan yang memiliki reservasi aktif
+// pada salah satu segmen [fromSegment, toSegment)
+func (r *firestoreRepository) GetReservedTrainSeatIDs(ctx context.Context, journeyID string, fromSegment, toSegment int) (map[string]bool, error) {
+	docs, err := r.client.Collection(trainReservationCollection).
+		Where("journey_id", "==", journeyID).
+		Where("from_segment", "<", toSegment).
+		Where("to_segment", ">", fromSegment).
+		Where("status", "not-in", []TrainReservationStatus{TrainReservationStatusCancelled, TrainReservationStatusExpired}).
+		Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	seatIDs := make(map[string]bool, len(docs))
+	for _, doc := range docs {
+		var trainReservation TrainReservation
+		if err := doc.DataTo(&trainReservation); err != nil {
+			return nil, err
+		}
+		seatIDs[trainReservation.SeatID] = true
+	}
+
+	return seatIDs, nil
+}
+
+// ConfirmTrainHolds mengubah seluruh hold milik orderID menjadi RESERVED dalam satu transaksi.
+// Jika salah satu hold sudah kedaluwarsa, tidak ada hold yang dikonfirmasi. Hold yang
+// sudah dikonfirmasi sebelumnya dikembalikan lagi agar command yang terkirim ulang tetap dibalas.
+func (r *firestoreRepository) ConfirmTrainHolds(ctx context.Context, orderID string, now time.Time) ([]*TrainReservation, error) {
+	var confirmed []*TrainReservation
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		confirmed = nil
+		query := r.client.Collection(trainReservationCollection).Where("order_id", "==", orderID)
+		docs, err := tx.Documents(query).GetAll()
+		if err != nil {
+			return err
+		}
+
+		var held, reserved []*TrainReservation
+		for _, doc := range docs {
+			var trainReservation TrainReservation
+			if err := doc.DataTo(&trainReservation); err != nil {
+				return err
+			}
+
+			switch trainReservation.Status {
+			case TrainReservationStatusHeld:
+				if trainReservation.ExpiresAt == nil || !now.Before(*trainReservation.ExpiresAt) {
+					return ErrTrainHoldExpired
+				}
+				held = append(held, &trainReservation)
+			case TrainReservationStatusReserved:
+				reserved = append(reserved, &trainReservation)
+			}
+		}
+		if len(held) == 0 {
+			if len(reserved) == 0 {
+				return ErrTrainHoldNotFound
+			}
+			confirmed = reserved
+			return nil
+		}
+
+		for _, trainReservation := range held {
+			if err := tx.Update(r.client.Collection(trainReservationCollection).Doc(trainReservation.ID), []firestore.Update{
+				{Path: "status", Value: TrainReservationStatusReserved},
+				{Path: "expires_at", Value: firestore.Delete},
+			}); err != nil {
+				return err
+			}
+			trainReservation.Status = TrainReservationStatusReserved
+			trainReservation.ExpiresAt = nil
+		}
+		confirmed = held
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return confirmed, nil
+}
+
+// GetExpiredTrainHolds mengembalikan hold yang sudah melewati ExpiresAt pada waktu now
+func (r *firestoreRepository) GetExpiredTrainHolds(ctx context.Context, now time.Time) ([]*TrainReservation, error) {
+	query := r.client.Collection(trainReservationCollection).
+		Where("status", "==", TrainReservationStatusHeld).
+		Where("expires_at", "<=", now)
+	docs, err := query.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	trainReservations := make([]*TrainReservation, 0, len(docs))
+	for _, doc := range docs {
+		var trainReservation TrainReservation
+		if err := doc.DataTo(&trainReservation); err != nil {
+			return nil, err
+		}
+		trainReservations = append(trainReservations, &trainReservation)
+	}
+
+	return trainReservations, nil
+}
+
+// ExpireTrainHold mengubah hold id menjadi EXPIRED jika masih HELD dan sudah kedaluwarsa.
+// Nilai kembalian false berarti hold sudah dikonfirmasi atau dilepas lebih dulu.
+func (r *firestoreRepository) ExpireTrainHold(ctx context.Context, id string, now time.Time) (bool, error) {
+	ref := r.client.Collection(trainReservationCollection).Doc(id)
+
+	var expired bool
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		expired = false
+		doc, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return ErrTrainReservationNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		var trainReservation TrainReservation
+		if err := doc.DataTo(&trainReservation); err != nil {
+			return err
+		}
+		if trainReservation.Status != TrainReservationStatusHeld || trainReservation.ExpiresAt == nil || now.Before(*trainReservation.ExpiresAt) {
+			return nil
+		}
+
+		expired = true
+		return tx.Update(ref, []firestore.Update{
+			{Path: "status", Value: TrainReservationStatusExpired},
+		})
+	})
+
+	return expired, err
+}
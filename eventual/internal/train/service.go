@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"log"
+	"time"
 
 	"github.com/oklog/ulid/v2"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/event"
@@ -13,15 +14,26 @@ import (
 
 type Service interface {
 	ProcessSagaEvent(ctx context.Context, msg event.Message) error
+
+	// ProcessChoreographyEvent memproses event partisipan lain pada saga booking
+	// koreografi (SAGA_MODE=choreography)
+	ProcessChoreographyEvent(ctx context.Context, msg event.Message) error
+
+	// ReleaseExpiredHolds dipanggil sweeper secara berkala untuk melepas hold yang kedaluwarsa
+	ReleaseExpiredHolds(ctx context.Context) error
+
+	// SearchTrainSeats mencari kursi perjalanan yang kosong di seluruh segmen antara dua stasiun
+	SearchTrainSeats(ctx context.Context, journeyID string, query SearchTrainSeatsQuery) (*TrainSeatPage, error)
 }
 
 type service struct {
 	repo      Repository
 	publisher messagebus.Publisher
+	holdTTL   time.Duration
 }
 
-func NewService(repo Repository, publisher messagebus.Publisher) Service {
-	return &service{repo: repo, publisher: publisher}
+func NewService(repo Repository, publisher messagebus.Publisher, holdTTL time.Duration) Service {
+	return &service{repo: repo, publisher: publisher, holdTTL: holdTTL}
 }
 
 func (s *service) ProcessSagaEvent(ctx context.Context, msg event.Message) error {
@@ -31,17 +43,33 @@ func (s *service) ProcessSagaEvent(ctx context.Context, msg event.Message) error
 		return s.handleReserveSeat(ctx, msg)
 	case event.CommandCancelSeat:
 		return s.handleCancelSeat(ctx, msg)
+	case event.CommandModifySeat:
+		return s.handleModifySeat(ctx, msg)
+	case event.CommandHoldSeat:
+		return s.handleHoldSeat(ctx, msg)
+	case event.CommandConfirmSeat:
+		return s.handleConfirmSeat(ctx, msg)
 	}
 
 	return nil
 }
 
 func (s *service) publishErrorEvent(ctx context.Context, msg event.Message, err error) error {
-	s.publisher.Publish(ctx, string(event.SeatReservationFailed), event.Message{
+	return s.publishItemErrorEvent(ctx, msg, nil, err)
+}
+
+// publishItemErrorEvent mengirim event gagal beserta indeks item penyebabnya
+func (s *service) publishItemErrorEvent(ctx context.Context, msg event.Message, failedItem *int, err error) error {
+	if pubErr := s.publisher.Publish(ctx, string(event.SeatReservationFailed), event.Message{
 		EventName:     event.SeatReservationFailed,
 		CorrelationID: msg.CorrelationID,
-		Payload:       event.SeatReservationFailedPayload{FailureReason: err.Error()},
-	})
+		Payload: event.SeatReservationFailedPayload{
+			FailedItem:    failedItem,
+			FailureReason: err.Error(),
+		},
+	}); pubErr != nil {
+		return errors.Join(err, pubErr)
+	}
 
 	return err
 }
@@ -52,40 +80,113 @@ func (s *service) handleReserveSeat(ctx context.Context, msg event.Message) erro
 		return s.publishErrorEvent(ctx, msg, err)
 	}
 
-	isAvailable, err := s.repo.IsTrainSeatAvailable(ctx, payload.SeatID)
-	if err != nil {
-		return s.publishErrorEvent(ctx, msg, err)
-	}
-	if !isAvailable {
-		return s.publishErrorEvent(ctx, msg, errors.New("train seat is not available"))
+	if len(payload.Seats) == 0 {
+		return s.publishErrorEvent(ctx, msg, errors.New("no train seats requested"))
 	}
 
-	trainSeat, err := s.repo.GetTrainSeatByID(ctx, payload.SeatID)
+	trainReservations, failedItem, err := s.buildTrainReservations(ctx, msg.CorrelationID, payload.Seats)
 	if err != nil {
-		return s.publishErrorEvent(ctx, msg, err)
+		return s.publishItemErrorEvent(ctx, msg, &failedItem, err)
 	}
-
-	trainReservation := &TrainReservation{
-		ID:        ulid.Make().String(),
-		SeatID:    trainSeat.SeatID,
-		TrainName: trainSeat.TrainName,
-		OrderID:   msg.CorrelationID,
-		Status:    TrainReservationStatusReserved,
+	for _, trainReservation := range trainReservations {
+		trainReservation.Status = TrainReservationStatusReserved
 	}
 
-	if err := s.repo.CreateTrainReservation(ctx, trainReservation); err != nil {
+	if err := s.repo.CreateTrainReservations(ctx, trainReservations); err != nil {
+		var notAvailableErr *TrainSeatNotAvailableError
+		if errors.As(err, &notAvailableErr) {
+			return s.publishItemErrorEvent(ctx, msg, &notAvailableErr.Index, err)
+		}
 		return s.publishErrorEvent(ctx, msg, err)
 	}
 
-	s.publisher.Publish(ctx, string(event.SeatReserved), event.Message{
+	reservationIDs := make([]string, 0, len(trainReservations))
+	for _, trainReservation := range trainReservations {
+		reservationIDs = append(reservationIDs, trainReservation.ID)
+	}
+
+	return s.publisher.Publish(ctx, string(event.SeatReserved), event.Message{
 		EventName:     event.SeatReserved,
 		CorrelationID: msg.CorrelationID,
 		Payload: event.SeatReservedPayload{
-			SeatReservationID: trainReservation.ID,
+			SeatReservationIDs: reservationIDs,
+			Order:              payload.Order,
 		},
 	})
+}
 
-	return nil
+// publishModificationErrorEvent mengirim event gagal modifikasi, reservasi lama tetap aktif
+func (s *service) publishModificationErrorEvent(ctx context.Context, msg event.Message, err error) error {
+	if pubErr := s.publisher.Publish(ctx, string(event.SeatModificationFailed), event.Message{
+		EventName:     event.SeatModificationFailed,
+		CorrelationID: msg.CorrelationID,
+		Payload:       event.SeatModificationFailedPayload{FailureReason: err.Error()},
+	}); pubErr != nil {
+		return errors.Join(err, pubErr)
+	}
+
+	return err
+}
+
+func (s *service) handleModifySeat(ctx context.Context, msg event.Message) error {
+	payload, err := mapToPayload[event.ModifySeatPayload](msg)
+	if err != nil {
+		return s.publishModificationErrorEvent(ctx, msg, err)
+	}
+
+	seat := payload.Seat
+	trainJourney, err := s.repo.GetTrainJourneyByID(ctx, seat.JourneyID)
+	if err != nil {
+		return s.publishModificationErrorEvent(ctx, msg, err)
+	}
+
+	if trainJourney.DepartureDate != seat.DepartureDate {
+		return s.publishModificationErrorEvent(ctx, msg, errors.New("train journey does not depart on the requested date"))
+	}
+	if !trainJourney.HasSeat(seat.SeatID) {
+		return s.publishModificationErrorEvent(ctx, msg, errors.New("train seat does not exist on this journey"))
+	}
+
+	fromSegment, toSegment, ok := trainJourney.Segments(seat.OriginStation, seat.DestinationStation)
+	if !ok {
+		return s.publishModificationErrorEvent(ctx, msg, ErrStationsNotOnRoute)
+	}
+
+	trainReservation := &TrainReservation{
+		ID:                 ulid.Make().String(),
+		JourneyID:          trainJourney.ID,
+		DepartureDate:      trainJourney.DepartureDate,
+		SeatID:             seat.SeatID,
+		TrainName:          trainJourney.TrainName,
+		OriginStation:      seat.OriginStation,
+		DestinationStation: seat.DestinationStation,
+		FromSegment:        fromSegment,
+		ToSegment:          toSegment,
+		Price:              seat.Price,
+		OrderID:            msg.CorrelationID,
+		Status:             TrainReservationStatusReserved,
+	}
+	if err := s.repo.ReplaceTrainReservation(ctx, payload.ReservationID, trainReservation); err != nil {
+		return s.publishModificationErrorEvent(ctx, msg, err)
+	}
+
+	if err := s.publisher.Publish(ctx, string(event.SeatModified), event.Message{
+		EventName:     event.SeatModified,
+		CorrelationID: msg.CorrelationID,
+		Payload: event.SeatModifiedPayload{
+			ReplacedReservationID: payload.ReservationID,
+			SeatReservationID:     trainReservation.ID,
+		},
+	}); err != nil {
+		return err
+	}
+
+	// Reservasi lama sudah dibatalkan saat diganti
+	replaced, err := s.repo.GetTrainReservationByID(ctx, payload.ReservationID)
+	if err != nil {
+		return err
+	}
+	return s.publishReleasedEvent(ctx, msg.CorrelationID, []*TrainReservation{replaced})
 }
 
 func mapToPayload[T any](msg event.Message) (T, error) {
@@ -106,18 +207,315 @@ func (s *service) handleCancelSeat(ctx context.Context, msg event.Message) error
 		return s.publishErrorEvent(ctx, msg, err)
 	}
 
-	trainReservation, err := s.repo.GetTrainReservationByOrderID(ctx, payload.OrderID)
-	if errors.Is(err, ErrTrainReservationNotFound) {
+	trainReservations, err := s.repo.GetTrainReservationsByOrderID(ctx, payload.OrderID)
+	if err != nil {
+		return s.publishErrorEvent(ctx, msg, err)
+	}
+
+	reservationIDs := make([]string, 0, len(trainReservations))
+	var released []*TrainReservation
+	for _, trainReservation := range trainReservations {
+		reservationIDs = append(reservationIDs, trainReservation.ID)
+		if trainReservation.Status == TrainReservationStatusCancelled || trainReservation.Status == TrainReservationStatusExpired {
+			continue
+		}
+
+		trainReservation.Status = TrainReservationStatusCancelled
+		if err := s.repo.UpdateTrainReservation(ctx, trainReservation); err != nil {
+			return s.publishErrorEvent(ctx, msg, err)
+		}
+		released = append(released, trainReservation)
+	}
+
+	if err := s.publisher.Publish(ctx, string(event.SeatReservationCancelled), event.Message{
+		EventName:     event.SeatReservationCancelled,
+		CorrelationID: msg.CorrelationID,
+		Payload:       event.SeatReservationCancelledPayload{SeatReservationIDs: reservationIDs},
+	}); err != nil {
+		return err
+	}
+
+	return s.publishReleasedEvent(ctx, msg.CorrelationID, released)
+}
+
+// publishReleasedEvent mengumumkan kursi yang kembali tersedia agar waitlist dapat memesannya
+func (s *service) publishReleasedEvent(ctx context.Context, orderID string, trainReservations []*TrainReservation) error {
+	if len(trainReservations) == 0 {
 		return nil
 	}
+
+	seats := make([]event.SeatItem, 0, len(trainReservations))
+	for _, trainReservation := range trainReservations {
+		seats = append(seats, event.SeatItem{
+			JourneyID:          trainReservation.JourneyID,
+			DepartureDate:      trainReservation.DepartureDate,
+			SeatID:             trainReservation.SeatID,
+			OriginStation:      trainReservation.OriginStation,
+			DestinationStation: trainReservation.DestinationStation,
+			Price:              trainReservation.Price,
+		})
+	}
+
+	return s.publisher.Publish(ctx, string(event.SeatReleased), event.Message{
+		EventName:     event.SeatReleased,
+		CorrelationID: orderID,
+		Payload:       event.SeatReleasedPayload{Seats: seats},
+	})
+}
+
+// buildTrainReservations membuat reservasi tanpa status untuk setiap kursi. Jika kursi
+// tidak valid, indeks kursi tersebut dikembalikan bersama error.
+func (s *service) buildTrainReservations(ctx context.Context, orderID string, seats []event.SeatItem) ([]*TrainReservation, int, error) {
+	trainJourneys := make(map[string]*TrainJourney)
+	trainReservations := make([]*TrainReservation, 0, len(seats))
+	for i, seat := range seats {
+		trainJourney, ok := trainJourneys[seat.JourneyID]
+		if !ok {
+			var err error
+			trainJourney, err = s.repo.GetTrainJourneyByID(ctx, seat.JourneyID)
+			if err != nil {
+				return nil, i, err
+			}
+			trainJourneys[seat.JourneyID] = trainJourney
+		}
+
+		if trainJourney.DepartureDate != seat.DepartureDate {
+			return nil, i, errors.New("train journey does not depart on the requested date")
+		}
+		if !trainJourney.HasSeat(seat.SeatID) {
+			return nil, i, errors.New("train seat does not exist on this journey")
+		}
+
+		fromSegment, toSegment, ok := trainJourney.Segments(seat.OriginStation, seat.DestinationStation)
+		if !ok {
+			return nil, i, ErrStationsNotOnRoute
+		}
+
+		trainReservations = append(trainReservations, &TrainReservation{
+			ID:                 ulid.Make().String(),
+			JourneyID:          trainJourney.ID,
+			DepartureDate:      trainJourney.DepartureDate,
+			SeatID:             seat.SeatID,
+			TrainName:          trainJourney.TrainName,
+			OriginStation:      seat.OriginStation,
+			DestinationStation: seat.DestinationStation,
+			FromSegment:        fromSegment,
+			ToSegment:          toSegment,
+			Price:              seat.Price,
+			OrderID:            orderID,
+		})
+	}
+
+	return trainReservations, 0, nil
+}
+
+// publishHoldErrorEvent mengirim event gagal hold beserta indeks item penyebabnya
+func (s *service) publishHoldErrorEvent(ctx context.Context, msg event.Message, failedItem *int, err error) error {
+	if pubErr := s.publisher.Publish(ctx, string(event.SeatHoldFailed), event.Message{
+		EventName:     event.SeatHoldFailed,
+		CorrelationID: msg.CorrelationID,
+		Payload: event.SeatHoldFailedPayload{
+			FailedItem:    failedItem,
+			FailureReason: err.Error(),
+		},
+	}); pubErr != nil {
+		return errors.Join(err, pubErr)
+	}
+
+	return err
+}
+
+func (s *service) handleHoldSeat(ctx context.Context, msg event.Message) error {
+	payload, err := mapToPayload[event.HoldSeatPayload](msg)
 	if err != nil {
-		return s.publishErrorEvent(ctx, msg, err)
+		return s.publishHoldErrorEvent(ctx, msg, nil, err)
 	}
 
-	trainReservation.Status = TrainReservationStatusCancelled
-	if err := s.repo.UpdateTrainReservation(ctx, trainReservation); err != nil {
-		return s.publishErrorEvent(ctx, msg, err)
+	if len(payload.Seats) == 0 {
+		return s.publishHoldErrorEvent(ctx, msg, nil, errors.New("no train seats requested"))
 	}
 
-	return nil
+	trainReservations, failedItem, err := s.buildTrainReservations(ctx, msg.CorrelationID, payload.Seats)
+	if err != nil {
+		return s.publishHoldErrorEvent(ctx, msg, &failedItem, err)
+	}
+
+	ttl := s.holdTTL
+	if payload.TTLSeconds > 0 {
+		ttl = time.Duration(payload.TTLSeconds) * time.Second
+	}
+	expiresAt := time.Now().Add(ttl)
+	for _, trainReservation := range trainReservations {
+		trainReservation.Status = TrainReservationStatusHeld
+		trainReservation.ExpiresAt = &expiresAt
+	}
+
+	if err := s.repo.CreateTrainReservations(ctx, trainReservations); err != nil {
+		var notAvailableErr *TrainSeatNotAvailableError
+		if errors.As(err, &notAvailableErr) {
+			return s.publishHoldErrorEvent(ctx, msg, &notAvailableErr.Index, err)
+		}
+		return s.publishHoldErrorEvent(ctx, msg, nil, err)
+	}
+
+	reservationIDs := make([]string, 0, len(trainReservations))
+	for _, trainReservation := range trainReservations {
+		reservationIDs = append(reservationIDs, trainReservation.ID)
+	}
+
+	return s.publisher.Publish(ctx, string(event.SeatHeld), event.Message{
+		EventName:     event.SeatHeld,
+		CorrelationID: msg.CorrelationID,
+		Payload: event.SeatHeldPayload{
+			SeatReservationIDs: reservationIDs,
+			ExpiresAt:          expiresAt,
+		},
+	})
+}
+
+// publishConfirmationErrorEvent mengirim event gagal konfirmasi, hold yang belum kedaluwarsa tetap ditahan
+func (s *service) publishConfirmationErrorEvent(ctx context.Context, msg event.Message, err error) error {
+	if pubErr := s.publisher.Publish(ctx, string(event.SeatHoldConfirmationFailed), event.Message{
+		EventName:     event.SeatHoldConfirmationFailed,
+		CorrelationID: msg.CorrelationID,
+		Payload:       event.SeatHoldConfirmationFailedPayload{FailureReason: err.Error()},
+	}); pubErr != nil {
+		return errors.Join(err, pubErr)
+	}
+
+	return err
+}
+
+func (s *service) handleConfirmSeat(ctx context.Context, msg event.Message) error {
+	payload, err := mapToPayload[event.ConfirmSeatPayload](msg)
+	if err != nil {
+		return s.publishConfirmationErrorEvent(ctx, msg, err)
+	}
+
+	trainReservations, err := s.repo.ConfirmTrainHolds(ctx, payload.OrderID, time.Now())
+	if err != nil {
+		return s.publishConfirmationErrorEvent(ctx, msg, err)
+	}
+
+	reservationIDs := make([]string, 0, len(trainReservations))
+	for _, trainReservation := range trainReservations {
+		reservationIDs = append(reservationIDs, trainReservation.ID)
+	}
+
+	return s.publisher.Publish(ctx, string(event.SeatHoldConfirmed), event.Message{
+		EventName:     event.SeatHoldConfirmed,
+		CorrelationID: msg.CorrelationID,
+		Payload:       event.SeatHoldConfirmedPayload{SeatReservationIDs: reservationIDs},
+	})
+}
+
+func (s *service) ReleaseExpiredHolds(ctx context.Context) error {
+	now := time.Now()
+	trainReservations, err := s.repo.GetExpiredTrainHolds(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	// Hold dikelompokkan per order agar setiap order menerima satu event
+	var orderIDs []string
+	expiredHolds := make(map[string][]*TrainReservation)
+	var errs []error
+	for _, trainReservation := range trainReservations {
+		expired, err := s.repo.ExpireTrainHold(ctx, trainReservation.ID, now)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !expired {
+			continue
+		}
+
+		if _, ok := expiredHolds[trainReservation.OrderID]; !ok {
+			orderIDs = append(orderIDs, trainReservation.OrderID)
+		}
+		expiredHolds[trainReservation.OrderID] = append(expiredHolds[trainReservation.OrderID], trainReservation)
+	}
+
+	for _, orderID := range orderIDs {
+		expiredIDs := make([]string, 0, len(expiredHolds[orderID]))
+		for _, trainReservation := range expiredHolds[orderID] {
+			expiredIDs = append(expiredIDs, trainReservation.ID)
+		}
+
+		if err := s.publisher.Publish(ctx, string(event.SeatHoldExpired), event.Message{
+			EventName:     event.SeatHoldExpired,
+			CorrelationID: orderID,
+			Payload:       event.SeatHoldExpiredPayload{SeatReservationIDs: expiredIDs},
+		}); err != nil {
+			errs = append(errs, err)
+		}
+		if err := s.publishReleasedEvent(ctx, orderID, expiredHolds[orderID]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(orderIDs) > 0 {
+		log.Printf("Released expired train holds of %d orders", len(orderIDs))
+	}
+
+	return errors.Join(errs...)
+}
+
+// SearchTrainSeats menelusuri kursi sesuai urutan gerbong dan nomor, dimulai setelah
+// kursi PageToken, dan melewati kursi yang memiliki reservasi aktif pada segmen yang diminta
+func (s *service) SearchTrainSeats(ctx context.Context, journeyID string, query SearchTrainSeatsQuery) (*TrainSeatPage, error) {
+	trainJourney, err := s.repo.GetTrainJourneyByID(ctx, journeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	origin, destination := query.OriginStation, query.DestinationStation
+	if origin == "" && len(trainJourney.Stations) > 0 {
+		origin = trainJourney.Stations[0]
+	}
+	if destination == "" && len(trainJourney.Stations) > 0 {
+		destination = trainJourney.Stations[len(trainJourney.Stations)-1]
+	}
+
+	fromSegment, toSegment, ok := trainJourney.Segments(origin, destination)
+	if !ok {
+		return nil, ErrStationsNotOnRoute
+	}
+
+	reservedSeatIDs, err := s.repo.GetReservedTrainSeatIDs(ctx, journeyID, fromSegment, toSegment)
+	if err != nil {
+		return nil, err
+	}
+
+	pageSize := query.PageSize
+	if pageSize == 0 {
+		pageSize = defaultPageSize
+	}
+
+	page := &TrainSeatPage{Items: make([]*TrainSeat, 0, pageSize)}
+	started := query.PageToken == ""
+	for coach := 1; coach <= trainJourney.Coaches; coach++ {
+		for number := 1; number <= trainJourney.SeatsPerCoach; number++ {
+			seatID := SeatID(coach, number)
+			if !started {
+				started = seatID == query.PageToken
+				continue
+			}
+			if reservedSeatIDs[seatID] {
+				continue
+			}
+
+			if len(page.Items) == pageSize {
+				page.NextPageToken = page.Items[len(page.Items)-1].SeatID
+				return page, nil
+			}
+			page.Items = append(page.Items, &TrainSeat{
+				SeatID:        seatID,
+				JourneyID:     trainJourney.ID,
+				TrainName:     trainJourney.TrainName,
+				DepartureDate: trainJourney.DepartureDate,
+			})
+		}
+	}
+
+	return page, nil
 }
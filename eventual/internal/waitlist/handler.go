@@ -0,0 +1,70 @@
+package waitlist
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) Register(ctx *gin.Context) {
+	var payload RegisterPayload
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entry, err := h.service.Register(ctx, payload)
+	var parseErr *time.ParseError
+	if errors.Is(err, ErrInvalidEntry) || errors.Is(err, ErrInvalidDateRange) || errors.As(err, &parseErr) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, entry)
+}
+
+func (h *Handler) GetEntry(ctx *gin.Context) {
+	entry, err := h.service.GetEntry(ctx, ctx.Param("id"))
+	if errors.Is(err, ErrEntryNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, entry)
+}
+
+func (h *Handler) CancelEntry(ctx *gin.Context) {
+	entry, err := h.service.CancelEntry(ctx, ctx.Param("id"))
+	if errors.Is(err, ErrEntryNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, ErrEntryNotCancellable) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, entry)
+}
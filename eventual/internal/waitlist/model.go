@@ -0,0 +1,50 @@
+package waitlist
+
+import (
+	"time"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/internal/order"
+)
+
+type EntryStatus string
+
+const (
+	// StatusWaiting berarti customer menunggu item kembali tersedia
+	StatusWaiting EntryStatus = "WAITING"
+	// StatusBooking berarti order sudah dibuat untuk customer dan saga-nya sedang berjalan
+	StatusBooking   EntryStatus = "BOOKING"
+	StatusFulfilled EntryStatus = "FULFILLED"
+	StatusCancelled EntryStatus = "CANCELLED"
+)
+
+// Entry adalah pendaftaran satu customer untuk satu kamar, mobil atau kursi kereta.
+// ItemKey mengidentifikasi item tanpa tanggal agar entry dapat dicari saat item dilepas.
+type Entry struct {
+	ID        string                  `firestore:"id" json:"id"`
+	UserID    string                  `firestore:"user_id" json:"user_id"`
+	ItemKey   string                  `firestore:"item_key" json:"-"`
+	HotelRoom *order.HotelRoomRequest `firestore:"hotel_room,omitempty" json:"hotel_room,omitempty"`
+	Car       *order.CarRequest       `firestore:"car,omitempty" json:"car,omitempty"`
+	TrainSeat *order.TrainSeatRequest `firestore:"train_seat,omitempty" json:"train_seat,omitempty"`
+	Status    EntryStatus             `firestore:"status" json:"status"`
+	// OrderID adalah order yang dibuat saat item dilepas, kosong selama masih menunggu
+	OrderID   string    `firestore:"order_id,omitempty" json:"order_id,omitempty"`
+	CreatedAt time.Time `firestore:"created_at" json:"created_at"`
+	UpdatedAt time.Time `firestore:"updated_at" json:"updated_at"`
+}
+
+func hotelRoomKey(hotelRoomID string) string {
+	return "hotel_room:" + hotelRoomID
+}
+
+func roomTypeKey(roomTypeID string) string {
+	return "room_type:" + roomTypeID
+}
+
+func carKey(carID string) string {
+	return "car:" + carID
+}
+
+func trainSeatKey(journeyID, seatID string) string {
+	return "train_seat:" + journeyID + ":" + seatID
+}
@@ -0,0 +1,79 @@
+package waitlist
+
+import (
+	"context"
+	"errors"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var ErrEntryNotFound = errors.New("waitlist entry not found")
+
+type Repository interface {
+	CreateEntry(ctx context.Context, entry *Entry) error
+	GetEntryByID(ctx context.Context, id string) (*Entry, error)
+	UpdateEntry(ctx context.Context, entry *Entry) error
+	// GetPendingEntries mengembalikan entry WAITING dan BOOKING untuk satu item, yang paling lama mendaftar lebih dulu
+	GetPendingEntries(ctx context.Context, itemKey string) ([]*Entry, error)
+}
+
+const (
+	entryCollection = "waitlist_entries"
+)
+
+type firestoreRepository struct {
+	client *firestore.Client
+}
+
+func NewFirestoreRepository(client *firestore.Client) Repository {
+	return &firestoreRepository{client: client}
+}
+
+func (r *firestoreRepository) CreateEntry(ctx context.Context, entry *Entry) error {
+	_, err := r.client.Collection(entryCollection).Doc(entry.ID).Create(ctx, entry)
+	return err
+}
+
+func (r *firestoreRepository) GetEntryByID(ctx context.Context, id string) (*Entry, error) {
+	doc, err := r.client.Collection(entryCollection).Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, ErrEntryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entry Entry
+	if err := doc.DataTo(&entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *firestoreRepository) UpdateEntry(ctx context.Context, entry *Entry) error {
+	_, err := r.client.Collection(entryCollection).Doc(entry.ID).Set(ctx, entry)
+	return err
+}
+
+func (r *firestoreRepository) GetPendingEntries(ctx context.Context, itemKey string) ([]*Entry, error) {
+	query := r.client.Collection(entryCollection).
+		Where("item_key", "==", itemKey).
+		Where("status", "in", []string{string(StatusWaiting), string(StatusBooking)}).
+		OrderBy("created_at", firestore.Asc)
+	docs, err := query.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*Entry, 0, len(docs))
+	for _, doc := range docs {
+		var entry Entry
+		if err := doc.DataTo(&entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, &entry)
+	}
+
+	return entries, nil
+}
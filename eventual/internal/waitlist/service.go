@@ -0,0 +1,287 @@
+package waitlist
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/internal/order"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/config"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/event"
+)
+
+// RegisterPayload mendaftarkan customer untuk tepat satu dari HotelRoom, Car dan TrainSeat
+type RegisterPayload struct {
+	UserID    string                  `json:"user_id" binding:"required"`
+	HotelRoom *order.HotelRoomRequest `json:"hotel_room"`
+	Car       *order.CarRequest       `json:"car"`
+	TrainSeat *order.TrainSeatRequest `json:"train_seat"`
+}
+
+var (
+	ErrInvalidEntry        = errors.New("waitlist entry must contain exactly one hotel room, car or train seat")
+	ErrInvalidDateRange    = errors.New("end date must not be before start date")
+	ErrEntryNotCancellable = errors.New("only waiting entries can be cancelled")
+)
+
+type Service interface {
+	// Register dipanggil oleh HTTP handler untuk mendaftarkan customer ke waitlist
+	Register(ctx context.Context, payload RegisterPayload) (*Entry, error)
+
+	GetEntry(ctx context.Context, id string) (*Entry, error)
+
+	// CancelEntry dipanggil oleh HTTP handler untuk keluar dari waitlist
+	CancelEntry(ctx context.Context, id string) (*Entry, error)
+
+	// ProcessReleaseEvent dipanggil oleh event handler saat participant melepas item.
+	// Event lain diabaikan.
+	ProcessReleaseEvent(ctx context.Context, msg event.Message) error
+}
+
+type service struct {
+	repo   Repository
+	orders order.Repository
+	saga   order.Service
+}
+
+func NewService(repo Repository, orders order.Repository, saga order.Service) Service {
+	return &service{repo: repo, orders: orders, saga: saga}
+}
+
+func normalizeDateRange(startDate, endDate string) (string, string, error) {
+	start, err := time.Parse(config.DateFormat, startDate)
+	if err != nil {
+		return "", "", err
+	}
+	end, err := time.Parse(config.DateFormat, endDate)
+	if err != nil {
+		return "", "", err
+	}
+	if end.Before(start) {
+		return "", "", ErrInvalidDateRange
+	}
+	return start.Format(config.DateFormat), end.Format(config.DateFormat), nil
+}
+
+func (s *service) Register(ctx context.Context, payload RegisterPayload) (*Entry, error) {
+	requested := 0
+	for _, ok := range []bool{payload.HotelRoom != nil, payload.Car != nil, payload.TrainSeat != nil} {
+		if ok {
+			requested++
+		}
+	}
+	if requested != 1 {
+		return nil, ErrInvalidEntry
+	}
+
+	now := time.Now()
+	entry := &Entry{
+		ID:        ulid.Make().String(),
+		UserID:    payload.UserID,
+		Status:    StatusWaiting,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	var err error
+	switch {
+	case payload.HotelRoom != nil:
+		hotelRoom := *payload.HotelRoom
+		if hotelRoom.StartDate, hotelRoom.EndDate, err = normalizeDateRange(hotelRoom.StartDate, hotelRoom.EndDate); err != nil {
+			return nil, err
+		}
+		entry.HotelRoom = &hotelRoom
+		entry.ItemKey = hotelRoomKey(hotelRoom.HotelRoomID)
+		if hotelRoom.RoomTypeID != "" {
+			entry.ItemKey = roomTypeKey(hotelRoom.RoomTypeID)
+		}
+	case payload.Car != nil:
+		car := *payload.Car
+		if car.StartDate, car.EndDate, err = normalizeDateRange(car.StartDate, car.EndDate); err != nil {
+			return nil, err
+		}
+		entry.Car = &car
+		entry.ItemKey = carKey(car.CarID)
+	case payload.TrainSeat != nil:
+		trainSeat := *payload.TrainSeat
+		if trainSeat.DepartureDate, _, err = normalizeDateRange(trainSeat.DepartureDate, trainSeat.DepartureDate); err != nil {
+			return nil, err
+		}
+		entry.TrainSeat = &trainSeat
+		entry.ItemKey = trainSeatKey(trainSeat.JourneyID, trainSeat.SeatID)
+	}
+
+	if err := s.repo.CreateEntry(ctx, entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (s *service) GetEntry(ctx context.Context, id string) (*Entry, error) {
+	entry, err := s.repo.GetEntryByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Status == StatusBooking {
+		if err := s.reconcile(ctx, entry); err != nil {
+			return nil, err
+		}
+	}
+	return entry, nil
+}
+
+func (s *service) CancelEntry(ctx context.Context, id string) (*Entry, error) {
+	entry, err := s.repo.GetEntryByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Status != StatusWaiting {
+		return nil, ErrEntryNotCancellable
+	}
+
+	entry.Status = StatusCancelled
+	entry.UpdatedAt = time.Now()
+	if err := s.repo.UpdateEntry(ctx, entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (s *service) ProcessReleaseEvent(ctx context.Context, msg event.Message) error {
+	var errs []error
+	switch msg.EventName {
+	case event.RoomReleased:
+		var payload event.RoomReleasedPayload
+		if err := unmarshalPayload(msg.Payload, &payload); err != nil {
+			return err
+		}
+		// Kamar fisik yang termasuk suatu tipe kamar juga mengembalikan satu unit tipe kamar tersebut
+		for _, room := range payload.Rooms {
+			matches := func(entry *Entry) bool {
+				return overlaps(entry.HotelRoom.StartDate, entry.HotelRoom.EndDate, room.StartDate, room.EndDate)
+			}
+			if room.RoomID != "" {
+				errs = append(errs, s.bookFirstWaiting(ctx, hotelRoomKey(room.RoomID), matches))
+			}
+			if room.RoomTypeID != "" {
+				errs = append(errs, s.bookFirstWaiting(ctx, roomTypeKey(room.RoomTypeID), matches))
+			}
+		}
+	case event.CarReleased:
+		var payload event.CarReleasedPayload
+		if err := unmarshalPayload(msg.Payload, &payload); err != nil {
+			return err
+		}
+		for _, car := range payload.Cars {
+			errs = append(errs, s.bookFirstWaiting(ctx, carKey(car.CarID), func(entry *Entry) bool {
+				return overlaps(entry.Car.StartDate, entry.Car.EndDate, car.StartDate, car.EndDate)
+			}))
+		}
+	case event.SeatReleased:
+		var payload event.SeatReleasedPayload
+		if err := unmarshalPayload(msg.Payload, &payload); err != nil {
+			return err
+		}
+		// Perjalanan hanya berangkat pada satu tanggal, sehingga kursi yang dilepas
+		// cocok dengan semua entry untuk kursi tersebut
+		for _, seat := range payload.Seats {
+			errs = append(errs, s.bookFirstWaiting(ctx, trainSeatKey(seat.JourneyID, seat.SeatID), func(entry *Entry) bool {
+				return true
+			}))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// bookFirstWaiting memulai order untuk entry WAITING paling awal yang cocok dengan item
+// yang dilepas. Selama masih ada entry BOOKING yang cocok dan order-nya belum selesai,
+// item dianggap sudah diberikan ke entry tersebut.
+func (s *service) bookFirstWaiting(ctx context.Context, itemKey string, matches func(entry *Entry) bool) error {
+	entries, err := s.repo.GetPendingEntries(ctx, itemKey)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if !matches(entry) {
+			continue
+		}
+		if entry.Status == StatusBooking {
+			if err := s.reconcile(ctx, entry); err != nil {
+				return err
+			}
+			if entry.Status == StatusBooking {
+				return nil
+			}
+		}
+		if entry.Status != StatusWaiting {
+			continue
+		}
+
+		return s.startOrder(ctx, entry)
+	}
+
+	return nil
+}
+
+func (s *service) startOrder(ctx context.Context, entry *Entry) error {
+	payload := order.CreateOrderPayload{UserID: entry.UserID}
+	switch {
+	case entry.HotelRoom != nil:
+		payload.HotelRooms = []order.HotelRoomRequest{*entry.HotelRoom}
+	case entry.Car != nil:
+		payload.Cars = []order.CarRequest{*entry.Car}
+	case entry.TrainSeat != nil:
+		payload.TrainSeats = []order.TrainSeatRequest{*entry.TrainSeat}
+	}
+
+	createdOrder, err := s.saga.StartSaga(ctx, payload)
+	if err != nil {
+		return err
+	}
+	log.Printf("Started order %s for waitlist entry %s", createdOrder.ID, entry.ID)
+
+	entry.Status = StatusBooking
+	entry.OrderID = createdOrder.ID
+	entry.UpdatedAt = time.Now()
+	return s.repo.UpdateEntry(ctx, entry)
+}
+
+// reconcile menyesuaikan entry BOOKING dengan status order-nya. Order yang gagal,
+// mis. karena item sudah dipesan customer lain, mengembalikan entry ke antrean.
+func (s *service) reconcile(ctx context.Context, entry *Entry) error {
+	bookingOrder, err := s.orders.GetOrderByID(ctx, entry.OrderID)
+	if err != nil {
+		return err
+	}
+
+	switch bookingOrder.Status {
+	case order.StatusBooked, order.StatusCancelling, order.StatusCancelled, order.StatusModifying:
+		entry.Status = StatusFulfilled
+	case order.StatusFailed:
+		entry.Status = StatusWaiting
+		entry.OrderID = ""
+	default:
+		return nil
+	}
+
+	entry.UpdatedAt = time.Now()
+	return s.repo.UpdateEntry(ctx, entry)
+}
+
+// overlaps mengecek apakah dua rentang tanggal inklusif beririsan
+func overlaps(startDate, endDate, otherStartDate, otherEndDate string) bool {
+	return startDate <= otherEndDate && otherStartDate <= endDate
+}
+
+func unmarshalPayload(payload any, target any) error {
+	marshalledPayload, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(marshalledPayload, target)
+}
@@ -1,20 +1,70 @@
 package config
 
-import "github.com/caarlos0/env/v11"
+import (
+	"fmt"
+	"time"
+
+	"github.com/caarlos0/env/v11"
+)
 
 const (
 	DateFormat = "2006-01-02"
 )
 
+// Mode saga booking, lihat SagaMode
+const (
+	SagaModeOrchestration = "orchestration"
+	SagaModeChoreography  = "choreography"
+)
+
 type Config struct {
 	RabbitMQURL     string `env:"RABBITMQ_URL,required"`
 	GoogleProjectID string `env:"GOOGLE_PROJECT_ID,required"`
 	Port            string `env:"PORT" envDefault:"8080"`
 
-	OrderQueueName string `env:"ORDER_QUEUE_NAME" envDefault:"order_service_queue"`
-	HotelQueueName string `env:"HOTEL_QUEUE_NAME" envDefault:"hotel_service_queue"`
-	CarQueueName   string `env:"CAR_QUEUE_NAME" envDefault:"car_service_queue"`
-	TrainQueueName string `env:"TRAIN_QUEUE_NAME" envDefault:"train_service_queue"`
+	OrderQueueName   string `env:"ORDER_QUEUE_NAME" envDefault:"order_service_queue"`
+	HotelQueueName   string `env:"HOTEL_QUEUE_NAME" envDefault:"hotel_service_queue"`
+	CarQueueName     string `env:"CAR_QUEUE_NAME" envDefault:"car_service_queue"`
+	TrainQueueName   string `env:"TRAIN_QUEUE_NAME" envDefault:"train_service_queue"`
+	FlightQueueName  string `env:"FLIGHT_QUEUE_NAME" envDefault:"flight_service_queue"`
+	PaymentQueueName string `env:"PAYMENT_QUEUE_NAME" envDefault:"payment_service_queue"`
+
+	// QuoteTTL adalah lama quote harga berlaku sejak dibuat
+	QuoteTTL time.Duration `env:"QUOTE_TTL" envDefault:"15m"`
+
+	// HoldTTL adalah lama hold berlaku jika command hold tidak menentukan TTL.
+	// Sweeper di setiap partisipan melepas hold yang kedaluwarsa setiap HoldSweepInterval.
+	HoldTTL           time.Duration `env:"HOLD_TTL" envDefault:"10m"`
+	HoldSweepInterval time.Duration `env:"HOLD_SWEEP_INTERVAL" envDefault:"1m"`
+
+	// Kebijakan pembatalan order. Pembatalan dalam FreeCancellationWindow sejak order
+	// BOOKED tidak dikenai biaya, setelahnya dikenai CancellationFeePercent dari total harga.
+	FreeCancellationWindow time.Duration `env:"FREE_CANCELLATION_WINDOW" envDefault:"24h"`
+	CancellationFeePercent int64         `env:"CANCELLATION_FEE_PERCENT" envDefault:"10"`
+
+	// SagaStepTimeout adalah batas waktu order service menunggu balasan setiap step saga
+	// booking, 0 berarti tanpa batas waktu. Sweeper mengompensasi order yang step-nya
+	// melewati batas waktu setiap SagaTimeoutSweepInterval.
+	SagaStepTimeout          time.Duration `env:"SAGA_STEP_TIMEOUT" envDefault:"0s"`
+	SagaTimeoutSweepInterval time.Duration `env:"SAGA_TIMEOUT_SWEEP_INTERVAL" envDefault:"30s"`
+
+	// SagaMode memilih cara saga booking dijalankan. Pada orchestration order service
+	// mengirim command ke setiap partisipan, pada choreography setiap partisipan bereaksi
+	// terhadap event partisipan sebelumnya. Seluruh service harus memakai mode yang sama.
+	SagaMode string `env:"SAGA_MODE" envDefault:"orchestration"`
+
+	// Konfigurasi fake payment provider. Otorisasi dengan nominal di atas
+	// FakePaymentDeclineAbove ditolak, 0 berarti tidak pernah ditolak.
+	FakePaymentDeclineAbove int64         `env:"FAKE_PAYMENT_DECLINE_ABOVE" envDefault:"0"`
+	FakePaymentLatency      time.Duration `env:"FAKE_PAYMENT_LATENCY" envDefault:"0s"`
+
+	// FaultAdminPort adalah port endpoint admin fault injection pada partisipan. Kosong
+	// berarti endpoint tidak dijalankan, order service memasangnya di server HTTP-nya.
+	FaultAdminPort string `env:"FAULT_ADMIN_PORT"`
+
+	// ResetAllowedProjectIDs adalah project Google Cloud lingkungan uji yang boleh
+	// dikosongkan oleh cmd/reset
+	ResetAllowedProjectIDs []string `env:"RESET_ALLOWED_PROJECT_IDS" envSeparator:","`
 }
 
 func LoadConfig() (Config, error) {
@@ -22,5 +72,8 @@ func LoadConfig() (Config, error) {
 	if err := env.Parse(&cfg); err != nil {
 		return Config{}, err
 	}
+	if cfg.SagaMode != SagaModeOrchestration && cfg.SagaMode != SagaModeChoreography {
+		return Config{}, fmt.Errorf("invalid SAGA_MODE %q, expected %s or %s", cfg.SagaMode, SagaModeOrchestration, SagaModeChoreography)
+	}
 	return cfg, nil
 }
@@ -1,5 +1,7 @@
 package event
 
+import "time"
+
 // EventName mendefinisikan tipe untuk nama event yang valid
 type EventName string
 
@@ -8,23 +10,111 @@ const (
 	CommandReserveRoom EventName = "booking.command.reserve.room"
 	CommandReserveCar  EventName = "booking.command.reserve.car"
 	CommandReserveSeat EventName = "booking.command.reserve.seat"
+	// Kursi pesawat belum mendukung modifikasi dan hold
+	CommandReserveFlight EventName = "booking.command.reserve.flight"
+
+	// Commands modifikasi dari Order Service ke Partisipan, mengganti satu reservasi
+	CommandModifyRoom EventName = "booking.command.modify.room"
+	CommandModifyCar  EventName = "booking.command.modify.car"
+	CommandModifySeat EventName = "booking.command.modify.seat"
+	// CommandAdjustPayment menarik atau mengembalikan selisih harga modifikasi order
+	CommandAdjustPayment EventName = "booking.command.adjust.payment"
+
+	// Commands hold ke Partisipan. Hold mereservasi item sampai batas waktu tertentu dan
+	// confirm mengubah hold menjadi reservasi. Hold dilepas lebih awal dengan command cancel.
+	CommandHoldRoom    EventName = "booking.command.hold.room"
+	CommandHoldCar     EventName = "booking.command.hold.car"
+	CommandHoldSeat    EventName = "booking.command.hold.seat"
+	CommandConfirmRoom EventName = "booking.command.confirm.room"
+	CommandConfirmCar  EventName = "booking.command.confirm.car"
+	CommandConfirmSeat EventName = "booking.command.confirm.seat"
+
+	// Commands pembayaran dari Order Service ke Payment Service
+	CommandAuthorizePayment EventName = "booking.command.authorize.payment"
+	CommandCapturePayment   EventName = "booking.command.capture.payment"
 
 	// Events dari Partisipan ke Order Service
-	RoomReserved          EventName = "booking.event.room.reserved"
-	RoomReservationFailed EventName = "booking.event.room.failed"
-	CarReserved           EventName = "booking.event.car.reserved"
-	CarReservationFailed  EventName = "booking.event.car.failed"
-	SeatReserved          EventName = "booking.event.seat.reserved"
-	SeatReservationFailed EventName = "booking.event.seat.failed"
+	RoomReserved            EventName = "booking.event.room.reserved"
+	RoomReservationFailed   EventName = "booking.event.room.failed"
+	CarReserved             EventName = "booking.event.car.reserved"
+	CarReservationFailed    EventName = "booking.event.car.failed"
+	SeatReserved            EventName = "booking.event.seat.reserved"
+	SeatReservationFailed   EventName = "booking.event.seat.failed"
+	FlightReserved          EventName = "booking.event.flight.reserved"
+	FlightReservationFailed EventName = "booking.event.flight.failed"
+	PaymentAuthorized       EventName = "booking.event.payment.authorized"
+	PaymentFailed           EventName = "booking.event.payment.failed"
+	PaymentCaptured         EventName = "booking.event.payment.captured"
+	PaymentCaptureFailed    EventName = "booking.event.payment.capture_failed"
+
+	// Balasan command pembatalan dari Partisipan ke Order Service
+	RoomReservationCancelled   EventName = "booking.event.room.cancelled"
+	CarReservationCancelled    EventName = "booking.event.car.cancelled"
+	SeatReservationCancelled   EventName = "booking.event.seat.cancelled"
+	FlightReservationCancelled EventName = "booking.event.flight.cancelled"
+	PaymentRefunded            EventName = "booking.event.payment.refunded"
+
+	// Balasan command modifikasi dari Partisipan ke Order Service
+	RoomModified           EventName = "booking.event.room.modified"
+	RoomModificationFailed EventName = "booking.event.room.modification_failed"
+	CarModified            EventName = "booking.event.car.modified"
+	CarModificationFailed  EventName = "booking.event.car.modification_failed"
+	SeatModified           EventName = "booking.event.seat.modified"
+	SeatModificationFailed EventName = "booking.event.seat.modification_failed"
+	// Balasan command penyesuaian pembayaran
+	PaymentAdjusted         EventName = "booking.event.payment.adjusted"
+	PaymentAdjustmentFailed EventName = "booking.event.payment.adjustment_failed"
+
+	// Balasan command hold dari Partisipan. HoldExpired dikirim sweeper saat hold
+	// kedaluwarsa sebelum dikonfirmasi.
+	RoomHeld                   EventName = "booking.event.room.held"
+	RoomHoldFailed             EventName = "booking.event.room.hold_failed"
+	RoomHoldConfirmed          EventName = "booking.event.room.hold_confirmed"
+	RoomHoldConfirmationFailed EventName = "booking.event.room.hold_confirmation_failed"
+	RoomHoldExpired            EventName = "booking.event.room.hold_expired"
+	CarHeld                    EventName = "booking.event.car.held"
+	CarHoldFailed              EventName = "booking.event.car.hold_failed"
+	CarHoldConfirmed           EventName = "booking.event.car.hold_confirmed"
+	CarHoldConfirmationFailed  EventName = "booking.event.car.hold_confirmation_failed"
+	CarHoldExpired             EventName = "booking.event.car.hold_expired"
+	SeatHeld                   EventName = "booking.event.seat.held"
+	SeatHoldFailed             EventName = "booking.event.seat.hold_failed"
+	SeatHoldConfirmed          EventName = "booking.event.seat.hold_confirmed"
+	SeatHoldConfirmationFailed EventName = "booking.event.seat.hold_confirmation_failed"
+	SeatHoldExpired            EventName = "booking.event.seat.hold_expired"
+
+	// Events dari Partisipan saat reservasi dilepas (dibatalkan, dikompensasi, diganti,
+	// atau hold kedaluwarsa) sehingga item kembali tersedia untuk waitlist
+	RoomReleased EventName = "booking.event.room.released"
+	CarReleased  EventName = "booking.event.car.released"
+	SeatReleased EventName = "booking.event.seat.released"
 
 	// Commands Kompensasi dari Order Service
-	CommandCancelRoom EventName = "booking.command.cancel.room"
-	CommandCancelCar  EventName = "booking.command.cancel.car"
-	CommandCancelSeat EventName = "booking.command.cancel.seat"
+	CommandCancelRoom   EventName = "booking.command.cancel.room"
+	CommandCancelCar    EventName = "booking.command.cancel.car"
+	CommandCancelSeat   EventName = "booking.command.cancel.seat"
+	CommandCancelFlight EventName = "booking.command.cancel.flight"
+	// Void untuk pembayaran yang baru diotorisasi, refund untuk yang sudah di-capture
+	CommandVoidPayment   EventName = "booking.command.void.payment"
+	CommandRefundPayment EventName = "booking.command.refund.payment"
+
+	// OrderCreated memulai saga booking koreografi (SAGA_MODE=choreography). Hotel service
+	// bereaksi terhadap OrderCreated, car terhadap RoomReserved, train terhadap CarReserved,
+	// flight terhadap SeatReserved, dan payment terhadap FlightReserved.
+	OrderCreated EventName = "booking.event.order.created"
 
 	// Event Final
 	OrderBooked EventName = "booking.event.order.booked"
 	OrderFailed EventName = "booking.event.order.failed"
+	// OrderCancelled dikirim setelah order yang sudah BOOKED dibatalkan customer
+	OrderCancelled EventName = "booking.event.order.cancelled"
+	// OrderModified dan OrderModificationFailed dikirim setelah saga modifikasi selesai
+	OrderModified           EventName = "booking.event.order.modified"
+	OrderModificationFailed EventName = "booking.event.order.modification_failed"
+	// OrderHeld dikirim setelah seluruh item order hold berhasil ditahan, OrderHoldExpired
+	// setelah hold kedaluwarsa sebelum dikonfirmasi
+	OrderHeld        EventName = "booking.event.order.held"
+	OrderHoldExpired EventName = "booking.event.order.hold_expired"
 )
 
 // Message adalah struktur dasar untuk setiap pesan di RabbitMQ
@@ -34,21 +124,85 @@ type Message struct {
 	Payload       any       `json:"payload"`
 }
 
-// ReserveRoomPayload adalah contoh payload untuk command reserve room
+// RoomItem adalah satu kamar yang dipesan dalam satu order. Kamar dipesan per kamar
+// fisik (RoomID) atau per tipe kamar (RoomTypeID), tepat salah satu yang diisi.
+type RoomItem struct {
+	RoomID     string `json:"hotel_room_id,omitempty"`
+	RoomTypeID string `json:"room_type_id,omitempty"`
+	StartDate  string `json:"start_date"`
+	EndDate    string `json:"end_date"`
+	// Price adalah harga yang sudah dikunci order untuk item ini
+	Price int64 `json:"price"`
+}
+
+// ReserveRoomPayload berisi seluruh kamar dalam satu order. Semua kamar
+// direservasi bersamaan, jika salah satu gagal maka tidak ada yang direservasi.
 type ReserveRoomPayload struct {
-	RoomID    string `json:"hotel_room_id"`
-	StartDate string `json:"start_date"`
-	EndDate   string `json:"end_date"`
+	Rooms []RoomItem `json:"rooms"`
+
+	// Order diteruskan ke event balasan pada saga koreografi, kosong pada saga orkestrasi
+	Order *OrderCreatedPayload `json:"order,omitempty"`
 }
 
-type ReserveCarPayload struct {
+type CarItem struct {
 	CarID     string `json:"car_id"`
 	StartDate string `json:"start_date"`
 	EndDate   string `json:"end_date"`
+	Price     int64  `json:"price"`
+}
+
+type ReserveCarPayload struct {
+	Cars  []CarItem            `json:"cars"`
+	Order *OrderCreatedPayload `json:"order,omitempty"`
+}
+
+type SeatItem struct {
+	JourneyID          string `json:"journey_id"`
+	DepartureDate      string `json:"departure_date"`
+	SeatID             string `json:"seat_id"`
+	OriginStation      string `json:"origin_station"`
+	DestinationStation string `json:"destination_station"`
+	Price              int64  `json:"price"`
 }
 
 type ReserveSeatPayload struct {
-	SeatID string `json:"seat_id"`
+	Seats []SeatItem           `json:"seats"`
+	Order *OrderCreatedPayload `json:"order,omitempty"`
+}
+
+// FlightItem adalah satu kursi pesawat pada penerbangan dengan tanggal keberangkatan tertentu
+type FlightItem struct {
+	FlightID      string `json:"flight_id"`
+	DepartureDate string `json:"departure_date"`
+	SeatID        string `json:"seat_id"`
+	Price         int64  `json:"price"`
+}
+
+type ReserveFlightPayload struct {
+	Flights []FlightItem         `json:"flights"`
+	Order   *OrderCreatedPayload `json:"order,omitempty"`
+}
+
+// AuthorizePaymentPayload berisi total harga order yang dikunci saat order dibuat
+type AuthorizePaymentPayload struct {
+	UserID   string `json:"user_id"`
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+type CapturePaymentPayload struct {
+	OrderID string `json:"order_id"`
+}
+
+type VoidPaymentPayload struct {
+	OrderID string `json:"order_id"`
+}
+
+// RefundPaymentPayload berisi nominal yang dikembalikan, bisa lebih kecil dari
+// total harga jika pembatalan dikenai biaya
+type RefundPaymentPayload struct {
+	OrderID string `json:"order_id"`
+	Amount  int64  `json:"amount"`
 }
 
 type CancelRoomPayload struct {
@@ -63,6 +217,23 @@ type CancelSeatPayload struct {
 	OrderID string `json:"order_id"`
 }
 
+type CancelFlightPayload struct {
+	OrderID string `json:"order_id"`
+}
+
+// OrderCreatedPayload berisi seluruh item dan total harga order. Setiap partisipan
+// saga koreografi meneruskannya ke event balasannya agar partisipan berikutnya dapat
+// mereservasi item miliknya tanpa membaca order.
+type OrderCreatedPayload struct {
+	UserID   string       `json:"user_id"`
+	Rooms    []RoomItem   `json:"rooms,omitempty"`
+	Cars     []CarItem    `json:"cars,omitempty"`
+	Seats    []SeatItem   `json:"seats,omitempty"`
+	Flights  []FlightItem `json:"flights,omitempty"`
+	Amount   int64        `json:"amount"`
+	Currency string       `json:"currency"`
+}
+
 type OrderBookedPayload struct {
 	OrderID string `json:"order_id"`
 }
@@ -71,15 +242,134 @@ type OrderFailedPayload struct {
 	OrderID string `json:"order_id"`
 }
 
+type OrderCancelledPayload struct {
+	OrderID string `json:"order_id"`
+}
+
+type OrderModifiedPayload struct {
+	OrderID string `json:"order_id"`
+}
+
+type OrderModificationFailedPayload struct {
+	OrderID       string `json:"order_id"`
+	FailureReason string `json:"failure_reason"`
+}
+
+type OrderHeldPayload struct {
+	OrderID   string    `json:"order_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type OrderHoldExpiredPayload struct {
+	OrderID string `json:"order_id"`
+}
+
+// ModifyRoomPayload mengganti reservasi ReservationID dengan Room. Kamar baru direservasi
+// lebih dulu, reservasi lama baru dilepas jika kamar baru berhasil direservasi.
+// Malam yang sudah dipegang reservasi lama boleh dipakai ulang oleh kamar baru.
+type ModifyRoomPayload struct {
+	ReservationID string   `json:"reservation_id"`
+	Room          RoomItem `json:"room"`
+}
+
+type ModifyCarPayload struct {
+	ReservationID string  `json:"reservation_id"`
+	Car           CarItem `json:"car"`
+}
+
+type ModifySeatPayload struct {
+	ReservationID string   `json:"reservation_id"`
+	Seat          SeatItem `json:"seat"`
+}
+
+// RoomModifiedPayload berisi ID reservasi lama yang dilepas dan ID reservasi penggantinya
+type RoomModifiedPayload struct {
+	ReplacedReservationID string `json:"replaced_reservation_id"`
+	RoomReservationID     string `json:"room_reservation_id"`
+}
+
+type CarModifiedPayload struct {
+	ReplacedReservationID string `json:"replaced_reservation_id"`
+	CarReservationID      string `json:"car_reservation_id"`
+}
+
+type SeatModifiedPayload struct {
+	ReplacedReservationID string `json:"replaced_reservation_id"`
+	SeatReservationID     string `json:"seat_reservation_id"`
+}
+
+// RoomModificationFailedPayload dikirim jika kamar pengganti gagal direservasi. Reservasi
+// lama tetap aktif.
+type RoomModificationFailedPayload struct {
+	FailureReason string `json:"failure_reason"`
+}
+
+type CarModificationFailedPayload struct {
+	FailureReason string `json:"failure_reason"`
+}
+
+type SeatModificationFailedPayload struct {
+	FailureReason string `json:"failure_reason"`
+}
+
+// AdjustPaymentPayload menyesuaikan pembayaran order yang sudah di-capture sebesar Amount.
+// Amount positif ditarik dari customer, negatif dikembalikan. RevertsID adalah
+// AdjustmentID penarikan yang dikembalikan, kosong jika yang dikembalikan adalah bagian
+// dari pembayaran awal.
+type AdjustPaymentPayload struct {
+	OrderID      string `json:"order_id"`
+	AdjustmentID string `json:"adjustment_id"`
+	Amount       int64  `json:"amount"`
+	RevertsID    string `json:"reverts_id,omitempty"`
+}
+
+type PaymentAdjustedPayload struct {
+	PaymentID    string `json:"payment_id"`
+	AdjustmentID string `json:"adjustment_id"`
+	Amount       int64  `json:"amount"`
+}
+
+type PaymentAdjustmentFailedPayload struct {
+	AdjustmentID  string `json:"adjustment_id"`
+	FailureReason string `json:"failure_reason"`
+}
+
+// RoomReservedPayload berisi ID reservasi dengan urutan yang sama dengan ReserveRoomPayload.Rooms
+// dan meneruskan order dari ReserveRoomPayload
 type RoomReservedPayload struct {
-	RoomReservationID string `json:"room_reservation_id"`
+	RoomReservationIDs []string             `json:"room_reservation_ids"`
+	Order              *OrderCreatedPayload `json:"order,omitempty"`
 }
 
 type CarReservedPayload struct {
-	CarReservationID string `json:"car_reservation_id"`
+	CarReservationIDs []string             `json:"car_reservation_ids"`
+	Order             *OrderCreatedPayload `json:"order,omitempty"`
 }
 type SeatReservedPayload struct {
-	SeatReservationID string `json:"seat_reservation_id"`
+	SeatReservationIDs []string             `json:"seat_reservation_ids"`
+	Order              *OrderCreatedPayload `json:"order,omitempty"`
+}
+
+type FlightReservedPayload struct {
+	FlightReservationIDs []string             `json:"flight_reservation_ids"`
+	Order                *OrderCreatedPayload `json:"order,omitempty"`
+}
+
+type PaymentAuthorizedPayload struct {
+	PaymentID string `json:"payment_id"`
+}
+
+type PaymentCapturedPayload struct {
+	PaymentID string `json:"payment_id"`
+}
+
+// PaymentFailedPayload dikirim jika otorisasi ditolak provider atau pembayaran sudah dibatalkan
+type PaymentFailedPayload struct {
+	FailureReason string `json:"failure_reason"`
+}
+
+type PaymentCaptureFailedPayload struct {
+	FailureReason string `json:"failure_reason"`
 }
 
 type RoomBookingConfirmedPayload struct {
@@ -90,25 +380,157 @@ type CarBookingConfirmedPayload struct {
 	CarReservationID string `json:"car_reservation_id"`
 }
 
+type RoomReservationCancelledPayload struct {
+	RoomReservationIDs []string `json:"room_reservation_ids"`
+}
+
 type CarReservationCancelledPayload struct {
-	CarReservationID string `json:"car_reservation_id"`
+	CarReservationIDs []string `json:"car_reservation_ids"`
 }
 
 type SeatReservationCancelledPayload struct {
-	SeatReservationID string `json:"seat_reservation_id"`
+	SeatReservationIDs []string `json:"seat_reservation_ids"`
+}
+
+type FlightReservationCancelledPayload struct {
+	FlightReservationIDs []string `json:"flight_reservation_ids"`
+}
+
+type PaymentRefundedPayload struct {
+	PaymentID string `json:"payment_id"`
+	Amount    int64  `json:"amount"`
 }
 
+// RoomReservationFailedPayload dikirim jika reservasi kamar gagal. FailedItem berisi
+// indeks item penyebab kegagalan, kosong jika kegagalan tidak terkait item tertentu.
 type RoomReservationFailedPayload struct {
-	RoomReservationID string `json:"room_reservation_id"`
-	FailureReason     string `json:"failure_reason"`
+	FailedItem    *int   `json:"failed_item,omitempty"`
+	FailureReason string `json:"failure_reason"`
 }
 
 type CarReservationFailedPayload struct {
-	CarReservationID string `json:"car_reservation_id"`
-	FailureReason    string `json:"failure_reason"`
+	FailedItem    *int   `json:"failed_item,omitempty"`
+	FailureReason string `json:"failure_reason"`
 }
 
 type SeatReservationFailedPayload struct {
-	SeatReservationID string `json:"seat_reservation_id"`
-	FailureReason     string `json:"failure_reason"`
+	FailedItem    *int   `json:"failed_item,omitempty"`
+	FailureReason string `json:"failure_reason"`
+}
+
+type FlightReservationFailedPayload struct {
+	FailedItem    *int   `json:"failed_item,omitempty"`
+	FailureReason string `json:"failure_reason"`
+}
+
+// HoldRoomPayload menahan seluruh kamar sampai TTLSeconds detik, atau sampai HOLD_TTL
+// layanan jika kosong. Sama seperti reservasi, jika satu kamar gagal maka tidak ada
+// kamar yang ditahan.
+type HoldRoomPayload struct {
+	Rooms      []RoomItem `json:"rooms"`
+	TTLSeconds int        `json:"ttl_seconds,omitempty"`
+}
+
+type HoldCarPayload struct {
+	Cars       []CarItem `json:"cars"`
+	TTLSeconds int       `json:"ttl_seconds,omitempty"`
+}
+
+type HoldSeatPayload struct {
+	Seats      []SeatItem `json:"seats"`
+	TTLSeconds int        `json:"ttl_seconds,omitempty"`
+}
+
+// RoomHeldPayload berisi ID reservasi dengan urutan yang sama dengan HoldRoomPayload.Rooms
+type RoomHeldPayload struct {
+	RoomReservationIDs []string  `json:"room_reservation_ids"`
+	ExpiresAt          time.Time `json:"expires_at"`
+}
+
+type CarHeldPayload struct {
+	CarReservationIDs []string  `json:"car_reservation_ids"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+type SeatHeldPayload struct {
+	SeatReservationIDs []string  `json:"seat_reservation_ids"`
+	ExpiresAt          time.Time `json:"expires_at"`
+}
+
+type RoomHoldFailedPayload struct {
+	FailedItem    *int   `json:"failed_item,omitempty"`
+	FailureReason string `json:"failure_reason"`
+}
+
+type CarHoldFailedPayload struct {
+	FailedItem    *int   `json:"failed_item,omitempty"`
+	FailureReason string `json:"failure_reason"`
+}
+
+type SeatHoldFailedPayload struct {
+	FailedItem    *int   `json:"failed_item,omitempty"`
+	FailureReason string `json:"failure_reason"`
+}
+
+// ConfirmRoomPayload mengubah seluruh hold kamar milik OrderID menjadi reservasi
+type ConfirmRoomPayload struct {
+	OrderID string `json:"order_id"`
+}
+
+type ConfirmCarPayload struct {
+	OrderID string `json:"order_id"`
+}
+
+type ConfirmSeatPayload struct {
+	OrderID string `json:"order_id"`
+}
+
+type RoomHoldConfirmedPayload struct {
+	RoomReservationIDs []string `json:"room_reservation_ids"`
+}
+
+type CarHoldConfirmedPayload struct {
+	CarReservationIDs []string `json:"car_reservation_ids"`
+}
+
+type SeatHoldConfirmedPayload struct {
+	SeatReservationIDs []string `json:"seat_reservation_ids"`
+}
+
+// RoomHoldConfirmationFailedPayload dikirim jika hold tidak ditemukan atau sudah kedaluwarsa
+type RoomHoldConfirmationFailedPayload struct {
+	FailureReason string `json:"failure_reason"`
+}
+
+type CarHoldConfirmationFailedPayload struct {
+	FailureReason string `json:"failure_reason"`
+}
+
+type SeatHoldConfirmationFailedPayload struct {
+	FailureReason string `json:"failure_reason"`
+}
+
+type RoomHoldExpiredPayload struct {
+	RoomReservationIDs []string `json:"room_reservation_ids"`
+}
+
+type CarHoldExpiredPayload struct {
+	CarReservationIDs []string `json:"car_reservation_ids"`
+}
+
+type SeatHoldExpiredPayload struct {
+	SeatReservationIDs []string `json:"seat_reservation_ids"`
+}
+
+// RoomReleasedPayload berisi kamar dan rentang tanggal yang kembali tersedia
+type RoomReleasedPayload struct {
+	Rooms []RoomItem `json:"rooms"`
+}
+
+type CarReleasedPayload struct {
+	Cars []CarItem `json:"cars"`
+}
+
+type SeatReleasedPayload struct {
+	Seats []SeatItem `json:"seats"`
 }
@@ -0,0 +1,263 @@
+// Package fault menyuntikkan kegagalan ke message bus dan repository partisipan untuk
+// pengujian chaos. Setiap kegagalan yang disuntikkan dihitung dan dicatat ke Firestore
+// agar terlihat di metrik per order.
+package fault
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caarlos0/env/v11"
+)
+
+// ErrInjected dikembalikan oleh operasi yang digagalkan injector
+var ErrInjected = errors.New("injected fault")
+
+// Kind adalah jenis kegagalan yang disuntikkan
+type Kind string
+
+const (
+	// Kegagalan message bus, berlaku untuk publish maupun consume
+	KindDrop      Kind = "drop"
+	KindDelay     Kind = "delay"
+	KindDuplicate Kind = "duplicate"
+	KindReorder   Kind = "reorder"
+
+	// Kegagalan repository partisipan. Kegagalan setelah commit terjadi ketika penulisan
+	// sudah tersimpan tetapi pemanggil tetap menerima error.
+	KindFailBeforeCommit Kind = "fail_before_commit"
+	KindFailAfterCommit  Kind = "fail_after_commit"
+)
+
+// Config adalah peluang (0 sampai 1) setiap jenis kegagalan. Nilai awalnya dibaca dari
+// environment dan dapat diubah saat berjalan melalui endpoint admin.
+type Config struct {
+	DropRate      float64 `env:"FAULT_DROP_RATE" json:"drop_rate"`
+	DelayRate     float64 `env:"FAULT_DELAY_RATE" json:"delay_rate"`
+	DuplicateRate float64 `env:"FAULT_DUPLICATE_RATE" json:"duplicate_rate"`
+	// Pesan yang diacak urutannya dikirim di latar belakang setelah Delay sehingga
+	// didahului pesan sesudahnya
+	ReorderRate float64 `env:"FAULT_REORDER_RATE" json:"reorder_rate"`
+	// Delay adalah lama penundaan untuk kegagalan delay dan reorder
+	Delay time.Duration `env:"FAULT_DELAY" envDefault:"2s" json:"-"`
+
+	FailBeforeCommitRate float64 `env:"FAULT_FAIL_BEFORE_COMMIT_RATE" json:"fail_before_commit_rate"`
+	FailAfterCommitRate  float64 `env:"FAULT_FAIL_AFTER_COMMIT_RATE" json:"fail_after_commit_rate"`
+
+	// Targets membatasi kegagalan pada nama event atau operasi repository yang memuat
+	// salah satu nilainya. Kosong berarti semua.
+	Targets []string `env:"FAULT_TARGETS" envSeparator:"," json:"targets"`
+}
+
+// LoadConfig membaca Config dari environment
+func LoadConfig() (Config, error) {
+	cfg := Config{}
+	if err := env.Parse(&cfg); err != nil {
+		return Config{}, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// Validate memastikan setiap peluang berada di antara 0 dan 1
+func (c Config) Validate() error {
+	for _, kind := range []Kind{KindDrop, KindDelay, KindDuplicate, KindReorder, KindFailBeforeCommit, KindFailAfterCommit} {
+		if rate := c.rate(kind); rate < 0 || rate > 1 {
+			return fmt.Errorf("%s rate must be between 0 and 1, got %v", kind, rate)
+		}
+	}
+	if c.Delay < 0 {
+		return fmt.Errorf("delay must not be negative, got %s", c.Delay)
+	}
+	return nil
+}
+
+func (c Config) rate(kind Kind) float64 {
+	switch kind {
+	case KindDrop:
+		return c.DropRate
+	case KindDelay:
+		return c.DelayRate
+	case KindDuplicate:
+		return c.DuplicateRate
+	case KindReorder:
+		return c.ReorderRate
+	case KindFailBeforeCommit:
+		return c.FailBeforeCommitRate
+	case KindFailAfterCommit:
+		return c.FailAfterCommitRate
+	}
+	return 0
+}
+
+func (c Config) targets(target string) bool {
+	if len(c.Targets) == 0 {
+		return true
+	}
+	for _, t := range c.Targets {
+		if t != "" && strings.Contains(target, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// configJSON menulis Delay sebagai durasi seperti "2s", bukan nanodetik
+type configJSON struct {
+	*configAlias
+	Delay string `json:"delay"`
+}
+
+type configAlias Config
+
+func (c Config) MarshalJSON() ([]byte, error) {
+	return json.Marshal(configJSON{configAlias: (*configAlias)(&c), Delay: c.Delay.String()})
+}
+
+// UnmarshalJSON hanya mengubah field yang ada di JSON sehingga endpoint admin dapat
+// mengubah sebagian konfigurasi
+func (c *Config) UnmarshalJSON(data []byte) error {
+	aux := configJSON{configAlias: (*configAlias)(c)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	if aux.Delay != "" {
+		delay, err := time.ParseDuration(aux.Delay)
+		if err != nil {
+			return fmt.Errorf("invalid delay: %w", err)
+		}
+		c.Delay = delay
+	}
+	return nil
+}
+
+// Injector memutuskan kapan kegagalan disuntikkan dan menghitung setiap kegagalan yang
+// terjadi. Injector nil tidak pernah menyuntikkan kegagalan.
+type Injector struct {
+	service  string
+	recorder Recorder
+
+	mu       sync.Mutex
+	config   Config
+	injected map[Kind]int64
+}
+
+// NewInjector membuat injector untuk service. recorder boleh nil jika kegagalan cukup
+// dihitung tanpa dicatat.
+func NewInjector(service string, config Config, recorder Recorder) *Injector {
+	return &Injector{
+		service:  service,
+		recorder: recorder,
+		config:   config,
+		injected: make(map[Kind]int64),
+	}
+}
+
+// Config mengembalikan konfigurasi yang sedang berlaku
+func (i *Injector) Config() Config {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.config
+}
+
+// SetConfig mengganti konfigurasi yang berlaku
+func (i *Injector) SetConfig(config Config) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.config = config
+	return nil
+}
+
+// Injected mengembalikan jumlah kegagalan yang sudah disuntikkan per jenis
+func (i *Injector) Injected() map[Kind]int64 {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	injected := make(map[Kind]int64, len(i.injected))
+	for kind, count := range i.injected {
+		injected[kind] = count
+	}
+	return injected
+}
+
+// inject mengundi kegagalan kind untuk target. Kegagalan yang terjadi dihitung dan
+// dicatat dengan correlationID (OrderID) agar dapat dikaitkan ke ordernya.
+func (i *Injector) inject(kind Kind, target, correlationID string) bool {
+	if i == nil {
+		return false
+	}
+
+	i.mu.Lock()
+	rate := i.config.rate(kind)
+	if rate <= 0 || !i.config.targets(target) || rand.Float64() >= rate {
+		i.mu.Unlock()
+		return false
+	}
+	i.injected[kind]++
+	i.mu.Unlock()
+
+	log.Printf("Injected %s fault on %s for %s", kind, target, correlationID)
+	if i.recorder != nil {
+		e := &Event{
+			Service:       i.service,
+			Kind:          kind,
+			Target:        target,
+			CorrelationID: correlationID,
+			CreatedAt:     time.Now(),
+		}
+		go func() {
+			if err := i.recorder.Record(context.Background(), e); err != nil {
+				log.Printf("Failed to record %s fault: %v", kind, err)
+			}
+		}()
+	}
+	return true
+}
+
+// delay mengembalikan lama penundaan yang berlaku
+func (i *Injector) delay() time.Duration {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.config.Delay
+}
+
+// Do menjalankan penulisan op milik correlationID dan dapat menggagalkannya sebelum
+// atau sesudah penulisan tersimpan
+func (i *Injector) Do(op, correlationID string, write func() error) error {
+	_, err := Call(i, op, correlationID, func() (struct{}, error) {
+		return struct{}{}, write()
+	})
+	return err
+}
+
+// Call seperti Do untuk penulisan yang mengembalikan nilai. Nilai tetap dikembalikan
+// bersama error jika kegagalan terjadi setelah commit.
+func Call[T any](i *Injector, op, correlationID string, write func() (T, error)) (T, error) {
+	if i.inject(KindFailBeforeCommit, op, correlationID) {
+		var zero T
+		return zero, fmt.Errorf("%s before commit: %w", op, ErrInjected)
+	}
+
+	value, err := write()
+	if err != nil {
+		return value, err
+	}
+
+	if i.inject(KindFailAfterCommit, op, correlationID) {
+		return value, fmt.Errorf("%s after commit: %w", op, ErrInjected)
+	}
+	return value, nil
+}
@@ -0,0 +1,89 @@
+package fault
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler adalah endpoint admin untuk membaca dan mengubah konfigurasi injector
+type Handler struct {
+	injector *Injector
+}
+
+func NewHandler(injector *Injector) *Handler {
+	return &Handler{injector: injector}
+}
+
+// RegisterRoutes memasang GET, PUT dan DELETE /admin/faults
+func (h *Handler) RegisterRoutes(r gin.IRoutes) {
+	r.GET("/admin/faults", h.GetFaults)
+	r.PUT("/admin/faults", h.UpdateFaults)
+	r.DELETE("/admin/faults", h.ResetFaults)
+}
+
+// GetFaults mengembalikan konfigurasi yang berlaku dan jumlah kegagalan per jenis
+func (h *Handler) GetFaults(ctx *gin.Context) {
+	h.respond(ctx)
+}
+
+// UpdateFaults mengubah field konfigurasi yang ada di body, field lain tidak berubah
+func (h *Handler) UpdateFaults(ctx *gin.Context) {
+	config := h.injector.Config()
+	if err := ctx.ShouldBindJSON(&config); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.injector.SetConfig(config); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.respond(ctx)
+}
+
+// ResetFaults mematikan seluruh kegagalan. Jumlah kegagalan yang sudah terjadi tetap.
+func (h *Handler) ResetFaults(ctx *gin.Context) {
+	config := Config{Delay: h.injector.Config().Delay}
+	if err := h.injector.SetConfig(config); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.respond(ctx)
+}
+
+func (h *Handler) respond(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{
+		"service":  h.injector.service,
+		"config":   h.injector.Config(),
+		"injected": h.injector.Injected(),
+	})
+}
+
+// ServeAdmin menjalankan endpoint admin di port sampai ctx selesai. Dipakai partisipan
+// yang tidak memiliki server HTTP sendiri.
+func ServeAdmin(ctx context.Context, port string, injector *Injector) {
+	router := gin.Default()
+	NewHandler(injector).RegisterRoutes(router)
+
+	srv := &http.Server{
+		Addr:    ":" + port,
+		Handler: router,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Fault admin server shutdown error: %v", err)
+		}
+	}()
+
+	log.Println("Fault admin endpoint started at port", port)
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Printf("Failed to start fault admin server: %v", err)
+	}
+}
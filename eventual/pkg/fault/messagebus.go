@@ -0,0 +1,96 @@
+package fault
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/event"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/messagebus"
+)
+
+type faultyPublisher struct {
+	next     messagebus.Publisher
+	injector *Injector
+}
+
+// NewPublisher membungkus next sehingga pesan yang dipublish dapat hilang, tertunda,
+// terkirim dua kali, atau tertukar urutannya
+func NewPublisher(next messagebus.Publisher, injector *Injector) messagebus.Publisher {
+	return &faultyPublisher{next: next, injector: injector}
+}
+
+func (p *faultyPublisher) Publish(ctx context.Context, routingKey string, e event.Message) error {
+	target := string(e.EventName)
+	if p.injector.inject(KindDrop, target, e.CorrelationID) {
+		return nil
+	}
+
+	if p.injector.inject(KindReorder, target, e.CorrelationID) {
+		delay := p.injector.delay()
+		ctx := context.WithoutCancel(ctx)
+		go func() {
+			time.Sleep(delay)
+			if err := p.next.Publish(ctx, routingKey, e); err != nil {
+				log.Printf("Failed to publish reordered %s for %s: %v", e.EventName, e.CorrelationID, err)
+			}
+		}()
+		return nil
+	}
+
+	if p.injector.inject(KindDelay, target, e.CorrelationID) {
+		select {
+		case <-time.After(p.injector.delay()):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if err := p.next.Publish(ctx, routingKey, e); err != nil {
+		return err
+	}
+
+	if p.injector.inject(KindDuplicate, target, e.CorrelationID) {
+		return p.next.Publish(ctx, routingKey, e)
+	}
+	return nil
+}
+
+type faultySubscriber struct {
+	next     messagebus.Subscriber
+	injector *Injector
+}
+
+// NewSubscriber membungkus next sehingga pesan yang diterima dapat hilang, tertunda,
+// diproses dua kali, atau diproses setelah pesan sesudahnya
+func NewSubscriber(next messagebus.Subscriber, injector *Injector) messagebus.Subscriber {
+	return &faultySubscriber{next: next, injector: injector}
+}
+
+func (s *faultySubscriber) Subscribe(ctx context.Context, routingKey, queueName string, handler func(e event.Message)) error {
+	return s.next.Subscribe(ctx, routingKey, queueName, func(e event.Message) {
+		target := string(e.EventName)
+		if s.injector.inject(KindDrop, target, e.CorrelationID) {
+			return
+		}
+
+		if s.injector.inject(KindReorder, target, e.CorrelationID) {
+			delay := s.injector.delay()
+			go func() {
+				time.Sleep(delay)
+				handler(e)
+			}()
+			return
+		}
+
+		if s.injector.inject(KindDelay, target, e.CorrelationID) {
+			time.Sleep(s.injector.delay())
+		}
+
+		handler(e)
+
+		if s.injector.inject(KindDuplicate, target, e.CorrelationID) {
+			handler(e)
+		}
+	})
+}
@@ -0,0 +1,45 @@
+package fault
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/oklog/ulid/v2"
+)
+
+// EventCollection menyimpan setiap kegagalan yang disuntikkan. Metrics calculator
+// menjumlahkannya per order.
+const EventCollection = "fault_events"
+
+// Event adalah satu kegagalan yang disuntikkan
+type Event struct {
+	ID      string `firestore:"id"`
+	Service string `firestore:"service"`
+	Kind    Kind   `firestore:"kind"`
+	// Target adalah nama event atau operasi repository yang digagalkan
+	Target        string    `firestore:"target"`
+	CorrelationID string    `firestore:"correlation_id"`
+	CreatedAt     time.Time `firestore:"created_at"`
+}
+
+// Recorder mencatat kegagalan yang disuntikkan
+type Recorder interface {
+	Record(ctx context.Context, e *Event) error
+}
+
+type firestoreRecorder struct {
+	client *firestore.Client
+}
+
+func NewFirestoreRecorder(client *firestore.Client) Recorder {
+	return &firestoreRecorder{client: client}
+}
+
+func (r *firestoreRecorder) Record(ctx context.Context, e *Event) error {
+	if e.ID == "" {
+		e.ID = ulid.Make().String()
+	}
+	_, err := r.client.Collection(EventCollection).Doc(e.ID).Set(ctx, e)
+	return err
+}
@@ -0,0 +1,198 @@
+package messagebus
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	reconnectInitialDelay = 500 * time.Millisecond
+	reconnectMaxDelay     = 30 * time.Second
+)
+
+var ErrConnectionClosed = errors.New("messagebus: connection closed")
+
+// Connection membungkus koneksi AMQP dan melakukan reconnect otomatis
+// dengan exponential backoff ketika koneksi ke broker terputus.
+type Connection struct {
+	url string
+
+	mu    sync.RWMutex
+	conn  *amqp091.Connection
+	ready chan struct{}
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// Dial membuka koneksi awal ke broker. Error hanya dikembalikan jika
+// koneksi pertama gagal, setelah itu reconnect ditangani di background.
+func Dial(url string) (*Connection, error) {
+	conn, err := amqp091.Dial(url)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Connection{
+		url:    url,
+		conn:   conn,
+		ready:  make(chan struct{}),
+		closed: make(chan struct{}),
+	}
+	close(c.ready)
+
+	go c.watch(conn)
+
+	return c, nil
+}
+
+// Close menutup koneksi dan menghentikan proses reconnect.
+func (c *Connection) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.closed)
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.conn != nil {
+			err = c.conn.Close()
+		}
+	})
+	return err
+}
+
+// current mengembalikan koneksi aktif. Jika koneksi sedang terputus,
+// current menunggu hingga reconnect berhasil atau ctx selesai.
+func (c *Connection) current(ctx context.Context) (*amqp091.Connection, error) {
+	for {
+		c.mu.RLock()
+		conn, ready := c.conn, c.ready
+		c.mu.RUnlock()
+
+		select {
+		case <-c.closed:
+			return nil, ErrConnectionClosed
+		default:
+		}
+
+		if conn != nil && !conn.IsClosed() {
+			return conn, nil
+		}
+
+		wait := ready
+		if conn != nil {
+			// Koneksi sudah putus tapi watch belum memulai reconnect
+			wait = nil
+		}
+
+		select {
+		case <-wait:
+		case <-time.After(reconnectInitialDelay):
+		case <-c.closed:
+			return nil, ErrConnectionClosed
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// channel membuka channel baru pada koneksi aktif.
+func (c *Connection) channel(ctx context.Context) (*amqp091.Channel, error) {
+	conn, err := c.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return conn.Channel()
+}
+
+// QueueMessages mengembalikan jumlah pesan yang menunggu di queue name.
+func (c *Connection) QueueMessages(ctx context.Context, name string) (int, error) {
+	ch, err := c.channel(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer ch.Close()
+
+	queue, err := ch.QueueDeclarePassive(name, true, false, false, false, nil)
+	if err != nil {
+		return 0, err
+	}
+	return queue.Messages, nil
+}
+
+// PurgeQueue menghapus seluruh pesan yang menunggu di queue name dan mengembalikan
+// jumlah pesan yang dihapus. Pesan yang sedang diproses consumer tidak ikut dihapus.
+func (c *Connection) PurgeQueue(ctx context.Context, name string) (int, error) {
+	ch, err := c.channel(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer ch.Close()
+
+	return ch.QueuePurge(name, false)
+}
+
+func (c *Connection) watch(conn *amqp091.Connection) {
+	for {
+		select {
+		case <-c.closed:
+			return
+		case amqpErr, ok := <-conn.NotifyClose(make(chan *amqp091.Error, 1)):
+			if !ok && amqpErr == nil {
+				// Koneksi ditutup secara normal (mis. lewat Close)
+				select {
+				case <-c.closed:
+					return
+				default:
+				}
+			}
+			log.Printf("RabbitMQ connection lost: %v, reconnecting", amqpErr)
+		}
+
+		c.mu.Lock()
+		c.conn = nil
+		c.ready = make(chan struct{})
+		c.mu.Unlock()
+
+		newConn, ok := c.reconnect()
+		if !ok {
+			return
+		}
+
+		c.mu.Lock()
+		c.conn = newConn
+		close(c.ready)
+		c.mu.Unlock()
+
+		log.Println("RabbitMQ connection re-established")
+		conn = newConn
+	}
+}
+
+func (c *Connection) reconnect() (*amqp091.Connection, bool) {
+	delay := reconnectInitialDelay
+	for {
+		select {
+		case <-c.closed:
+			return nil, false
+		case <-time.After(delay):
+		}
+
+		conn, err := amqp091.Dial(c.url)
+		if err == nil {
+			return conn, true
+		}
+
+		log.Printf("Failed to reconnect to RabbitMQ: %v, retrying in %s", err, delay)
+		delay *= 2
+		if delay > reconnectMaxDelay {
+			delay = reconnectMaxDelay
+		}
+	}
+}
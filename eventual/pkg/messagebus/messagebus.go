@@ -3,12 +3,27 @@ package messagebus
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"time"
 
+	"github.com/oklog/ulid/v2"
 	"github.com/rabbitmq/amqp091-go"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/event"
 )
 
+const (
+	publisherPoolSize    = 16
+	publishMaxAttempts   = 3
+	defaultPublishWindow = 10 * time.Second
+
+	// dedupeWindow adalah jumlah message ID terakhir yang diingat setiap subscriber
+	dedupeWindow = 10000
+)
+
+var ErrPublishNotAcked = errors.New("messagebus: message was not acknowledged by broker")
+
 type Publisher interface {
 	Publish(ctx context.Context, routingKey string, e event.Message) error
 }
@@ -18,67 +33,246 @@ type Subscriber interface {
 }
 
 type rabbitmqPublisher struct {
-	conn *amqp091.Connection
+	conn *Connection
+	pool chan *amqp091.Channel
 }
 
-func NewRabbitmqPublisher(conn *amqp091.Connection) Publisher {
-	return &rabbitmqPublisher{conn: conn}
+func NewRabbitmqPublisher(conn *Connection) Publisher {
+	return &rabbitmqPublisher{
+		conn: conn,
+		pool: make(chan *amqp091.Channel, publisherPoolSize),
+	}
 }
 
-func (p *rabbitmqPublisher) Publish(ctx context.Context, routingKey string, e event.Message) error {
-	ch, err := p.conn.Channel()
-	if err != nil {
-		return err
+// acquire mengambil channel dari pool atau membuka channel baru dalam
+// mode publisher confirms. Channel yang sudah tertutup dibuang.
+func (p *rabbitmqPublisher) acquire(ctx context.Context) (*amqp091.Channel, error) {
+	for {
+		select {
+		case ch := <-p.pool:
+			if ch.IsClosed() {
+				continue
+			}
+			return ch, nil
+		default:
+		}
+
+		ch, err := p.conn.channel(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := ch.Confirm(false); err != nil {
+			ch.Close()
+			return nil, err
+		}
+
+		return ch, nil
 	}
-	defer ch.Close()
+}
 
+func (p *rabbitmqPublisher) release(ch *amqp091.Channel) {
+	if ch.IsClosed() {
+		return
+	}
+
+	select {
+	case p.pool <- ch:
+	default:
+		ch.Close()
+	}
+}
+
+func (p *rabbitmqPublisher) Publish(ctx context.Context, routingKey string, e event.Message) error {
 	ev, err := json.Marshal(e)
 	if err != nil {
 		return err
 	}
 
+	// Publish yang diulang memakai MessageId yang sama. Ack broker yang terlambat dapat
+	// membuat pesan terkirim dua kali, salinannya dibuang subscriber berdasarkan ID ini.
 	msg := amqp091.Publishing{
-		ContentType: "application/json",
-		Body:        ev,
+		ContentType:  "application/json",
+		DeliveryMode: amqp091.Persistent,
+		MessageId:    ulid.Make().String(),
+		Body:         ev,
 	}
 
-	if err := ch.PublishWithContext(ctx, "amq.topic", routingKey, false, false, msg); err != nil {
-		return err
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultPublishWindow)
+		defer cancel()
 	}
 
-	return nil
+	var lastErr error
+	for attempt := 1; attempt <= publishMaxAttempts; attempt++ {
+		acked, err := p.publishOnce(ctx, routingKey, msg)
+		if err == nil && acked {
+			return nil
+		}
+		if err == nil {
+			lastErr = ErrPublishNotAcked
+		} else {
+			lastErr = err
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+		log.Printf("Publish %s attempt %d failed: %v", routingKey, attempt, lastErr)
+	}
+
+	return fmt.Errorf("failed to publish %s: %w", routingKey, lastErr)
+}
+
+func (p *rabbitmqPublisher) publishOnce(ctx context.Context, routingKey string, msg amqp091.Publishing) (bool, error) {
+	ch, err := p.acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	confirmation, err := ch.PublishWithDeferredConfirmWithContext(ctx, "amq.topic", routingKey, false, false, msg)
+	if err != nil {
+		ch.Close()
+		return false, err
+	}
+
+	acked, err := confirmation.WaitContext(ctx)
+	if err != nil {
+		// Status confirm tidak diketahui, channel tidak dikembalikan ke pool
+		ch.Close()
+		return false, err
+	}
+
+	p.release(ch)
+	return acked, nil
 }
 
 type rabbitmqSubscriber struct {
-	conn *amqp091.Connection
+	conn *Connection
 }
 
-func NewRabbitmqSubscriber(conn *amqp091.Connection) Subscriber {
+func NewRabbitmqSubscriber(conn *Connection) Subscriber {
 	return &rabbitmqSubscriber{conn: conn}
 }
 
+// Subscribe mulai mengonsumsi queueName. Consumer dipasang ulang secara
+// otomatis setiap kali koneksi atau channel terputus sampai ctx selesai.
 func (p *rabbitmqSubscriber) Subscribe(ctx context.Context, routingKey, queueName string, handler func(e event.Message)) error {
-	ch, err := p.conn.Channel()
-	if err != nil {
-		log.Printf("Failed to create channel: %v", err)
-		return err
-	}
-	msgs, err := ch.Consume(queueName, "", true, false, false, false, nil)
+	msgs, err := p.consume(ctx, queueName)
 	if err != nil {
 		log.Printf("Failed to consume messages: %v", err)
 		return err
 	}
 
 	go func() {
-		for d := range msgs {
-			var e event.Message
-			if err := json.Unmarshal(d.Body, &e); err != nil {
-				log.Printf("Failed to unmarshal message: %v", err)
-				continue
+		recent := newRecentIDs(dedupeWindow)
+		for {
+			for d := range msgs {
+				if recent.seen(d.MessageId) {
+					log.Printf("Dropping duplicate message %s on %s", d.MessageId, queueName)
+					continue
+				}
+
+				var e event.Message
+				if err := json.Unmarshal(d.Body, &e); err != nil {
+					log.Printf("Failed to unmarshal message: %v", err)
+					continue
+				}
+				handler(e)
+			}
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			log.Printf("Consumer for %s stopped, re-establishing", queueName)
+			msgs = p.reconsume(ctx, queueName)
+			if msgs == nil {
+				return
 			}
-			handler(e)
 		}
 	}()
 
 	return nil
 }
+
+func (p *rabbitmqSubscriber) consume(ctx context.Context, queueName string) (<-chan amqp091.Delivery, error) {
+	ch, err := p.conn.channel(ctx)
+	if err != nil {
+		log.Printf("Failed to create channel: %v", err)
+		return nil, err
+	}
+
+	msgs, err := ch.Consume(queueName, "", true, false, false, false, nil)
+	if err != nil {
+		ch.Close()
+		return nil, err
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			ch.Close()
+		case <-ch.NotifyClose(make(chan *amqp091.Error, 1)):
+		}
+	}()
+
+	return msgs, nil
+}
+
+func (p *rabbitmqSubscriber) reconsume(ctx context.Context, queueName string) <-chan amqp091.Delivery {
+	delay := reconnectInitialDelay
+	for {
+		msgs, err := p.consume(ctx, queueName)
+		if err == nil {
+			log.Printf("Consumer for %s re-established", queueName)
+			return msgs
+		}
+		if errors.Is(err, ErrConnectionClosed) || ctx.Err() != nil {
+			return nil
+		}
+
+		log.Printf("Failed to re-establish consumer for %s: %v", queueName, err)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if delay > reconnectMaxDelay {
+			delay = reconnectMaxDelay
+		}
+	}
+}
+
+// recentIDs mengingat message ID terakhir yang diterima subscriber. Pesan ganda hanya
+// dibuang selama ID-nya masih di dalam jendela dan diterima proses yang sama.
+type recentIDs struct {
+	ids  map[string]struct{}
+	ring []string
+	next int
+}
+
+func newRecentIDs(size int) *recentIDs {
+	return &recentIDs{ids: make(map[string]struct{}, size), ring: make([]string, size)}
+}
+
+// seen mencatat id dan mengembalikan true jika id sudah pernah diterima. Pesan tanpa
+// MessageId, misalnya dari publisher lama, tidak pernah dianggap ganda.
+func (r *recentIDs) seen(id string) bool {
+	if id == "" {
+		return false
+	}
+	if _, ok := r.ids[id]; ok {
+		return true
+	}
+
+	if oldest := r.ring[r.next]; oldest != "" {
+		delete(r.ids, oldest)
+	}
+	r.ring[r.next] = id
+	r.ids[id] = struct{}{}
+	r.next = (r.next + 1) % len(r.ring)
+	return false
+}
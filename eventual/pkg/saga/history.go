@@ -0,0 +1,88 @@
+package saga
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/oklog/ulid/v2"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/event"
+)
+
+// StepCollection menyimpan riwayat transisi setiap instance saga
+const StepCollection = "saga_steps"
+
+// Transition adalah satu perubahan status saga atau step. Step kosong berarti
+// perubahan status saga itu sendiri.
+type Transition struct {
+	ID     string          `firestore:"id" json:"id"`
+	SagaID string          `firestore:"saga_id" json:"saga_id"`
+	Saga   string          `firestore:"saga" json:"saga"`
+	Step   string          `firestore:"step,omitempty" json:"step,omitempty"`
+	From   string          `firestore:"from" json:"from"`
+	To     string          `firestore:"to" json:"to"`
+	Event  event.EventName `firestore:"event,omitempty" json:"event,omitempty"`
+	Reason string          `firestore:"reason,omitempty" json:"reason,omitempty"`
+	At     time.Time       `firestore:"at" json:"at"`
+}
+
+// History mencatat dan membaca riwayat transisi saga
+type History interface {
+	Record(ctx context.Context, transitions []Transition) error
+	// List mengembalikan riwayat satu instance saga, yang paling lama lebih dulu
+	List(ctx context.Context, sagaID string) ([]Transition, error)
+}
+
+type firestoreHistory struct {
+	client *firestore.Client
+}
+
+func NewFirestoreHistory(client *firestore.Client) History {
+	return &firestoreHistory{client: client}
+}
+
+// Record menulis seluruh transisi satu operasi sekaligus
+func (h *firestoreHistory) Record(ctx context.Context, transitions []Transition) error {
+	if len(transitions) == 0 {
+		return nil
+	}
+	return h.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		for i := range transitions {
+			if transitions[i].ID == "" {
+				transitions[i].ID = ulid.Make().String()
+			}
+			if err := tx.Create(h.client.Collection(StepCollection).Doc(transitions[i].ID), transitions[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// List tidak memakai OrderBy agar filter saga_id tidak membutuhkan composite index
+func (h *firestoreHistory) List(ctx context.Context, sagaID string) ([]Transition, error) {
+	docs, err := h.client.Collection(StepCollection).Where("saga_id", "==", sagaID).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	transitions := make([]Transition, 0, len(docs))
+	for _, doc := range docs {
+		var transition Transition
+		if err := doc.DataTo(&transition); err != nil {
+			return nil, err
+		}
+		transitions = append(transitions, transition)
+	}
+	// Transisi dalam satu operasi memiliki waktu yang sama, ID berupa ULID sehingga
+	// urutannya mengikuti urutan pencatatan
+	slices.SortFunc(transitions, func(a, b Transition) int {
+		if c := a.At.Compare(b.At); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return transitions, nil
+}
@@ -0,0 +1,400 @@
+package saga
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/event"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/messagebus"
+)
+
+var (
+	ErrNotRunning = errors.New("saga is not running")
+	ErrCompleted  = errors.New("saga has already completed")
+)
+
+type replyKind int
+
+const (
+	replySuccess replyKind = iota
+	replyFailure
+	replyCompensated
+)
+
+// reply adalah step dan jenis balasan dari satu event
+type reply[T Instance] struct {
+	step *Step[T]
+	kind replyKind
+}
+
+// Orchestrator menjalankan instance saga sesuai Definition. Setiap operasi mengubah
+// state, menyimpannya bersama data instance, mencatat transisinya ke History, lalu
+// mengirim command. Command baru dikirim setelah state tersimpan.
+type Orchestrator[T Instance] struct {
+	def       Definition[T]
+	store     Store[T]
+	history   History
+	publisher messagebus.Publisher
+	replies   map[event.EventName]reply[T]
+}
+
+func NewOrchestrator[T Instance](def Definition[T], store Store[T], history History, publisher messagebus.Publisher) *Orchestrator[T] {
+	o := &Orchestrator[T]{
+		def:       def,
+		store:     store,
+		history:   history,
+		publisher: publisher,
+		replies:   make(map[event.EventName]reply[T]),
+	}
+	for i := range def.Steps {
+		step := &o.def.Steps[i]
+		o.addReply(step.SuccessEvent, reply[T]{step: step, kind: replySuccess})
+		o.addReply(step.FailureEvent, reply[T]{step: step, kind: replyFailure})
+		o.addReply(step.CompensatedEvent, reply[T]{step: step, kind: replyCompensated})
+	}
+	return o
+}
+
+func (o *Orchestrator[T]) addReply(name event.EventName, r reply[T]) {
+	if name == "" {
+		return
+	}
+	if existing, ok := o.replies[name]; ok {
+		panic(fmt.Sprintf("saga %s: event %s is handled by both step %s and %s", o.def.Name, name, existing.step.Name, r.step.Name))
+	}
+	o.replies[name] = r
+}
+
+// Handles melaporkan apakah event merupakan balasan salah satu step saga
+func (o *Orchestrator[T]) Handles(name event.EventName) bool {
+	_, ok := o.replies[name]
+	return ok
+}
+
+// Start memulai saga untuk data yang sudah dibuat. Jika command gagal dikirim, saga
+// langsung dikompensasi.
+func (o *Orchestrator[T]) Start(ctx context.Context, data T) error {
+	r := o.newRun(data)
+	r.state.Steps = make(map[string]StepState, len(o.def.Steps))
+	r.setStatus(StatusRunning, "", "")
+	for i := range o.def.Steps {
+		step := &o.def.Steps[i]
+		if step.Skip != nil && step.Skip(data) {
+			r.setStep(step, StepSkipped, "", "")
+			continue
+		}
+		r.setStep(step, StepPending, "", "")
+	}
+	r.advance()
+
+	if err := r.save(ctx); err != nil {
+		return err
+	}
+	if err := r.publish(ctx); err != nil {
+		// Sebagian command mungkin sudah terkirim, batalkan semuanya
+		c := o.newRun(data)
+		c.fail(fmt.Sprintf("failed to publish commands: %v", err))
+		if compErr := c.commit(ctx); compErr != nil {
+			return errors.Join(err, compErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// Handle memproses balasan step. Balasan yang datang setelah saga gagal tetap dicatat,
+// dan step yang ternyata berhasil dikompensasi ulang karena command kompensasinya bisa
+// jadi diproses participant lebih dulu. Balasan lain yang tidak ditunggu diabaikan.
+func (o *Orchestrator[T]) Handle(ctx context.Context, data T, msg event.Message) error {
+	rp, ok := o.replies[msg.EventName]
+	if !ok {
+		return nil
+	}
+	r := o.newRun(data)
+	current, ok := r.state.Steps[rp.step.Name]
+	if !ok {
+		return nil
+	}
+
+	switch rp.kind {
+	case replySuccess:
+		switch {
+		case r.state.Status == StatusRunning && current.Status == StepStarted:
+			if err := callReply(rp.step.OnSuccess, data, msg); err != nil {
+				return err
+			}
+			r.setStep(rp.step, StepSucceeded, msg.EventName, "")
+			r.advance()
+		case r.state.Status == StatusFailed && slices.Contains([]StepStatus{StepStarted, StepTimedOut, StepCompensating, StepCompensated}, current.Status):
+			if err := callReply(rp.step.OnSuccess, data, msg); err != nil {
+				return err
+			}
+			r.setStep(rp.step, StepSucceeded, msg.EventName, "reply arrived after the saga failed")
+			r.compensate(rp.step)
+		default:
+			return nil
+		}
+	case replyFailure:
+		switch {
+		case r.state.Status == StatusRunning && current.Status == StepStarted:
+			if err := callReply(rp.step.OnFailure, data, msg); err != nil {
+				return err
+			}
+			r.setStep(rp.step, StepFailed, msg.EventName, "")
+			r.fail(fmt.Sprintf("step %s failed", rp.step.Name))
+		case r.state.Status == StatusFailed && slices.Contains([]StepStatus{StepStarted, StepTimedOut, StepCompensating}, current.Status):
+			// Step yang gagal tidak perlu dikompensasi
+			if err := callReply(rp.step.OnFailure, data, msg); err != nil {
+				return err
+			}
+			r.setStep(rp.step, StepFailed, msg.EventName, "reply arrived after the saga failed")
+		default:
+			return nil
+		}
+	case replyCompensated:
+		if current.Status != StepCompensating {
+			return nil
+		}
+		r.setStep(rp.step, StepCompensated, msg.EventName, "")
+	}
+
+	return r.commit(ctx)
+}
+
+// Retry mengirim ulang command step yang belum dibalas dan memperpanjang batas waktunya
+func (o *Orchestrator[T]) Retry(ctx context.Context, data T) error {
+	r := o.newRun(data)
+	if r.state.Status != StatusRunning {
+		return ErrNotRunning
+	}
+	for i := range o.def.Steps {
+		step := &o.def.Steps[i]
+		if r.state.Steps[step.Name].Status == StepStarted {
+			r.start(step, "retried")
+		}
+	}
+	return r.commit(ctx)
+}
+
+// Compensate menggagalkan saga yang masih berjalan dan mengirim command kompensasi.
+// Saga yang sudah gagal dikompensasi ulang, misalnya jika command kompensasinya hilang.
+func (o *Orchestrator[T]) Compensate(ctx context.Context, data T, reason string) error {
+	r := o.newRun(data)
+	if r.state.Status == StatusCompleted {
+		return ErrCompleted
+	}
+	r.fail(reason)
+	return r.commit(ctx)
+}
+
+// ExpireSteps menandai step yang melewati batas waktu sebagai TIMED_OUT dan
+// mengompensasi saganya
+func (o *Orchestrator[T]) ExpireSteps(ctx context.Context) error {
+	now := time.Now()
+	expired, err := o.store.Expired(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, data := range expired {
+		r := o.newRun(data)
+		if r.state.Status != StatusRunning {
+			continue
+		}
+		var timedOut []string
+		for i := range o.def.Steps {
+			step := &o.def.Steps[i]
+			current := r.state.Steps[step.Name]
+			if current.Status == StepStarted && current.Deadline != nil && !current.Deadline.After(now) {
+				r.setStep(step, StepTimedOut, "", fmt.Sprintf("no reply within %s", step.Timeout))
+				timedOut = append(timedOut, step.Name)
+			}
+		}
+		if len(timedOut) == 0 {
+			continue
+		}
+		r.fail(fmt.Sprintf("step %s timed out", strings.Join(timedOut, ", ")))
+		if err := r.commit(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", data.SagaID(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func callReply[T any](f func(T, event.Message) error, data T, msg event.Message) error {
+	if f == nil {
+		return nil
+	}
+	return f(data, msg)
+}
+
+// run mengumpulkan transisi dan command selama satu operasi pada satu instance
+type run[T Instance] struct {
+	o           *Orchestrator[T]
+	data        T
+	state       *State
+	now         time.Time
+	transitions []Transition
+	messages    []event.Message
+}
+
+func (o *Orchestrator[T]) newRun(data T) *run[T] {
+	return &run[T]{o: o, data: data, state: data.SagaState(), now: time.Now()}
+}
+
+func (r *run[T]) record(step string, from, to string, name event.EventName, reason string) {
+	r.transitions = append(r.transitions, Transition{
+		ID:     ulid.Make().String(),
+		SagaID: r.data.SagaID(),
+		Saga:   r.o.def.Name,
+		Step:   step,
+		From:   from,
+		To:     to,
+		Event:  name,
+		Reason: reason,
+		At:     r.now,
+	})
+}
+
+func (r *run[T]) setStatus(to Status, name event.EventName, reason string) {
+	if r.state.Status == to {
+		return
+	}
+	r.record("", string(r.state.Status), string(to), name, reason)
+	r.state.Status = to
+}
+
+func (r *run[T]) setStep(step *Step[T], to StepStatus, name event.EventName, reason string) {
+	current := r.state.Steps[step.Name]
+	r.record(step.Name, string(current.Status), string(to), name, reason)
+	current.Status = to
+	current.Deadline = nil
+	current.UpdatedAt = r.now
+	r.state.Steps[step.Name] = current
+}
+
+// start mengirim command step dan memasang batas waktunya
+func (r *run[T]) start(step *Step[T], reason string) {
+	r.setStep(step, StepStarted, "", reason)
+	if step.Timeout > 0 {
+		deadline := r.now.Add(step.Timeout)
+		current := r.state.Steps[step.Name]
+		current.Deadline = &deadline
+		r.state.Steps[step.Name] = current
+	}
+	if step.OnStart != nil {
+		step.OnStart(r.data)
+	}
+	r.messages = append(r.messages, step.Command(r.data))
+}
+
+// advance memulai step yang seluruh syaratnya sudah berhasil, lalu menyelesaikan saga
+// jika seluruh step sudah berhasil atau dilewati
+func (r *run[T]) advance() {
+	done := true
+	for i := range r.o.def.Steps {
+		step := &r.o.def.Steps[i]
+		status := r.state.Steps[step.Name].Status
+		if status == StepPending && r.ready(step) {
+			r.start(step, "")
+			status = StepStarted
+		}
+		if status != StepSucceeded && status != StepSkipped {
+			done = false
+		}
+	}
+	if !done {
+		return
+	}
+
+	r.setStatus(StatusCompleted, "", "")
+	if r.o.def.OnCompleted != nil {
+		r.messages = append(r.messages, r.o.def.OnCompleted(r.data))
+	}
+}
+
+func (r *run[T]) ready(step *Step[T]) bool {
+	for _, name := range step.Requires {
+		status := r.state.Steps[name].Status
+		if status != StepSucceeded && status != StepSkipped {
+			return false
+		}
+	}
+	return true
+}
+
+// fail menggagalkan saga dan mengompensasi setiap step yang sudah dimulai dan tidak gagal
+func (r *run[T]) fail(reason string) {
+	r.setStatus(StatusFailed, "", reason)
+	for i := range r.o.def.Steps {
+		step := &r.o.def.Steps[i]
+		switch r.state.Steps[step.Name].Status {
+		case StepStarted, StepSucceeded, StepTimedOut, StepCompensating, StepCompensated:
+			r.compensate(step)
+		}
+	}
+	if r.o.def.OnFailed != nil {
+		r.messages = append(r.messages, r.o.def.OnFailed(r.data, reason))
+	}
+}
+
+func (r *run[T]) compensate(step *Step[T]) {
+	if step.Compensation == nil {
+		return
+	}
+	msg, ok := step.Compensation(r.data)
+	if !ok {
+		return
+	}
+	if step.CompensatedEvent == "" {
+		r.setStep(step, StepCompensated, "", "")
+	} else {
+		r.setStep(step, StepCompensating, "", "")
+	}
+	r.messages = append(r.messages, msg)
+}
+
+// save menyimpan state beserta data instance lalu mencatat transisinya. Riwayat yang
+// gagal dicatat tidak menghentikan saga.
+func (r *run[T]) save(ctx context.Context) error {
+	r.state.Deadline = nil
+	if r.state.Status == StatusRunning {
+		for _, step := range r.state.Steps {
+			if step.Status == StepStarted && step.Deadline != nil && (r.state.Deadline == nil || step.Deadline.Before(*r.state.Deadline)) {
+				r.state.Deadline = step.Deadline
+			}
+		}
+	}
+
+	if err := r.o.store.Save(ctx, r.data); err != nil {
+		return err
+	}
+	if err := r.o.history.Record(ctx, r.transitions); err != nil {
+		log.Printf("Failed to record %d saga steps of %s: %v", len(r.transitions), r.data.SagaID(), err)
+	}
+	return nil
+}
+
+// publish mengirim seluruh command. Semua command tetap dicoba meskipun salah satunya
+// gagal terkirim.
+func (r *run[T]) publish(ctx context.Context) error {
+	var errs []error
+	for _, msg := range r.messages {
+		errs = append(errs, r.o.publisher.Publish(ctx, string(msg.EventName), msg))
+	}
+	return errors.Join(errs...)
+}
+
+func (r *run[T]) commit(ctx context.Context) error {
+	if err := r.save(ctx); err != nil {
+		return err
+	}
+	return r.publish(ctx)
+}
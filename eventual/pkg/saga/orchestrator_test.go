@@ -0,0 +1,281 @@
+package saga
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/event"
+)
+
+// testOrder adalah instance saga untuk test
+type testOrder struct {
+	id    string
+	noCar bool
+	state State
+}
+
+func (o *testOrder) SagaID() string    { return o.id }
+func (o *testOrder) SagaState() *State { return &o.state }
+
+type fakeStore struct {
+	saves int
+}
+
+func (s *fakeStore) Save(ctx context.Context, data *testOrder) error {
+	s.saves++
+	return nil
+}
+
+func (s *fakeStore) Expired(ctx context.Context, now time.Time) ([]*testOrder, error) {
+	return nil, nil
+}
+
+type fakeHistory struct {
+	transitions []Transition
+}
+
+func (h *fakeHistory) Record(ctx context.Context, transitions []Transition) error {
+	h.transitions = append(h.transitions, transitions...)
+	return nil
+}
+
+func (h *fakeHistory) List(ctx context.Context, sagaID string) ([]Transition, error) {
+	return h.transitions, nil
+}
+
+type fakePublisher struct {
+	published []event.EventName
+}
+
+func (p *fakePublisher) Publish(ctx context.Context, routingKey string, e event.Message) error {
+	p.published = append(p.published, e.EventName)
+	return nil
+}
+
+func message(name event.EventName) func(*testOrder) event.Message {
+	return func(o *testOrder) event.Message {
+		return event.Message{EventName: name, CorrelationID: o.id}
+	}
+}
+
+func compensation(name event.EventName) func(*testOrder) (event.Message, bool) {
+	return func(o *testOrder) (event.Message, bool) {
+		return event.Message{EventName: name, CorrelationID: o.id}, true
+	}
+}
+
+// testDefinition memesan hotel dan mobil bersamaan, lalu membayar setelah keduanya
+// berhasil. Pembayaran dikompensasi tanpa balasan.
+func testDefinition() Definition[*testOrder] {
+	return Definition[*testOrder]{
+		Name: "test",
+		Steps: []Step[*testOrder]{
+			{
+				Name:             "hotel",
+				Command:          message("hotel.reserve"),
+				SuccessEvent:     "hotel.reserved",
+				FailureEvent:     "hotel.failed",
+				Compensation:     compensation("hotel.cancel"),
+				CompensatedEvent: "hotel.cancelled",
+				Timeout:          time.Minute,
+			},
+			{
+				Name:             "car",
+				Skip:             func(o *testOrder) bool { return o.noCar },
+				Command:          message("car.reserve"),
+				SuccessEvent:     "car.reserved",
+				FailureEvent:     "car.failed",
+				Compensation:     compensation("car.cancel"),
+				CompensatedEvent: "car.cancelled",
+				Timeout:          time.Minute,
+			},
+			{
+				Name:         "payment",
+				Requires:     []string{"hotel", "car"},
+				Command:      message("payment.capture"),
+				SuccessEvent: "payment.captured",
+				FailureEvent: "payment.failed",
+				Compensation: compensation("payment.refund"),
+			},
+		},
+		OnCompleted: message("order.booked"),
+		OnFailed: func(o *testOrder, reason string) event.Message {
+			return event.Message{EventName: "order.failed", CorrelationID: o.id}
+		},
+	}
+}
+
+func TestOrchestratorHandle(t *testing.T) {
+	tests := []struct {
+		name  string
+		noCar bool
+		// replies adalah event balasan yang diproses setelah saga dimulai. Nilai
+		// "compensate" mengompensasi saga secara manual.
+		replies []event.EventName
+		// published adalah seluruh command dan event final yang dikirim sejak Start
+		published []event.EventName
+		status    Status
+		steps     map[string]StepStatus
+		// saves adalah jumlah state disimpan, termasuk saat Start
+		saves int
+	}{
+		{
+			name:      "happy path",
+			replies:   []event.EventName{"hotel.reserved", "car.reserved", "payment.captured"},
+			published: []event.EventName{"hotel.reserve", "car.reserve", "payment.capture", "order.booked"},
+			status:    StatusCompleted,
+			steps:     map[string]StepStatus{"hotel": StepSucceeded, "car": StepSucceeded, "payment": StepSucceeded},
+			saves:     4,
+		},
+		{
+			name:      "payment waits for every required step",
+			replies:   []event.EventName{"car.reserved"},
+			published: []event.EventName{"hotel.reserve", "car.reserve"},
+			status:    StatusRunning,
+			steps:     map[string]StepStatus{"hotel": StepStarted, "car": StepSucceeded, "payment": StepPending},
+			saves:     2,
+		},
+		{
+			name:      "skipped step counts as done",
+			noCar:     true,
+			replies:   []event.EventName{"hotel.reserved", "payment.captured"},
+			published: []event.EventName{"hotel.reserve", "payment.capture", "order.booked"},
+			status:    StatusCompleted,
+			steps:     map[string]StepStatus{"hotel": StepSucceeded, "car": StepSkipped, "payment": StepSucceeded},
+			saves:     3,
+		},
+		{
+			name:      "failed payment compensates in step order",
+			replies:   []event.EventName{"hotel.reserved", "car.reserved", "payment.failed"},
+			published: []event.EventName{"hotel.reserve", "car.reserve", "payment.capture", "hotel.cancel", "car.cancel", "order.failed"},
+			status:    StatusFailed,
+			steps:     map[string]StepStatus{"hotel": StepCompensating, "car": StepCompensating, "payment": StepFailed},
+			saves:     4,
+		},
+		{
+			name:      "compensation replies complete the compensation",
+			replies:   []event.EventName{"hotel.reserved", "car.reserved", "payment.failed", "car.cancelled", "hotel.cancelled"},
+			published: []event.EventName{"hotel.reserve", "car.reserve", "payment.capture", "hotel.cancel", "car.cancel", "order.failed"},
+			status:    StatusFailed,
+			steps:     map[string]StepStatus{"hotel": StepCompensated, "car": StepCompensated, "payment": StepFailed},
+			saves:     6,
+		},
+		{
+			name:      "failed step is not compensated and pending step is not started",
+			replies:   []event.EventName{"hotel.failed"},
+			published: []event.EventName{"hotel.reserve", "car.reserve", "car.cancel", "order.failed"},
+			status:    StatusFailed,
+			steps:     map[string]StepStatus{"hotel": StepFailed, "car": StepCompensating, "payment": StepPending},
+			saves:     2,
+		},
+		{
+			name:      "late success after the saga failed is compensated again",
+			replies:   []event.EventName{"hotel.failed", "car.reserved"},
+			published: []event.EventName{"hotel.reserve", "car.reserve", "car.cancel", "order.failed", "car.cancel"},
+			status:    StatusFailed,
+			steps:     map[string]StepStatus{"hotel": StepFailed, "car": StepCompensating, "payment": StepPending},
+			saves:     3,
+		},
+		{
+			name:      "late success after the compensation finished is compensated again",
+			replies:   []event.EventName{"hotel.failed", "car.cancelled", "car.reserved"},
+			published: []event.EventName{"hotel.reserve", "car.reserve", "car.cancel", "order.failed", "car.cancel"},
+			status:    StatusFailed,
+			steps:     map[string]StepStatus{"hotel": StepFailed, "car": StepCompensating, "payment": StepPending},
+			saves:     4,
+		},
+		{
+			name:      "late failure after the saga failed is recorded",
+			replies:   []event.EventName{"hotel.failed", "car.failed"},
+			published: []event.EventName{"hotel.reserve", "car.reserve", "car.cancel", "order.failed"},
+			status:    StatusFailed,
+			steps:     map[string]StepStatus{"hotel": StepFailed, "car": StepFailed, "payment": StepPending},
+			saves:     3,
+		},
+		{
+			name:      "late success after manual compensation is compensated again",
+			replies:   []event.EventName{"hotel.reserved", "compensate", "car.reserved"},
+			published: []event.EventName{"hotel.reserve", "car.reserve", "hotel.cancel", "car.cancel", "order.failed", "car.cancel"},
+			status:    StatusFailed,
+			steps:     map[string]StepStatus{"hotel": StepCompensating, "car": StepCompensating, "payment": StepPending},
+			saves:     4,
+		},
+		{
+			name:      "duplicate and unknown replies are ignored",
+			replies:   []event.EventName{"hotel.reserved", "hotel.reserved", "hotel.cancelled", "unknown.event"},
+			published: []event.EventName{"hotel.reserve", "car.reserve"},
+			status:    StatusRunning,
+			steps:     map[string]StepStatus{"hotel": StepSucceeded, "car": StepStarted, "payment": StepPending},
+			saves:     2,
+		},
+		{
+			name:      "replies after completion are ignored",
+			replies:   []event.EventName{"hotel.reserved", "car.reserved", "payment.captured", "car.failed", "hotel.reserved"},
+			published: []event.EventName{"hotel.reserve", "car.reserve", "payment.capture", "order.booked"},
+			status:    StatusCompleted,
+			steps:     map[string]StepStatus{"hotel": StepSucceeded, "car": StepSucceeded, "payment": StepSucceeded},
+			saves:     4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := &fakeStore{}
+			history := &fakeHistory{}
+			publisher := &fakePublisher{}
+			o := NewOrchestrator(testDefinition(), store, history, publisher)
+
+			order := &testOrder{id: "order-1", noCar: tt.noCar}
+			if err := o.Start(ctx, order); err != nil {
+				t.Fatalf("Start: %v", err)
+			}
+			for _, name := range tt.replies {
+				var err error
+				if name == "compensate" {
+					err = o.Compensate(ctx, order, "cancelled by operator")
+				} else {
+					err = o.Handle(ctx, order, event.Message{EventName: name, CorrelationID: order.id})
+				}
+				if err != nil {
+					t.Fatalf("%s: %v", name, err)
+				}
+			}
+
+			if !slices.Equal(publisher.published, tt.published) {
+				t.Errorf("published = %v, want %v", publisher.published, tt.published)
+			}
+			if order.state.Status != tt.status {
+				t.Errorf("status = %s, want %s", order.state.Status, tt.status)
+			}
+			steps := make(map[string]StepStatus, len(order.state.Steps))
+			for name, step := range order.state.Steps {
+				steps[name] = step.Status
+			}
+			if !maps.Equal(steps, tt.steps) {
+				t.Errorf("steps = %v, want %v", steps, tt.steps)
+			}
+			if store.saves != tt.saves {
+				t.Errorf("saves = %d, want %d", store.saves, tt.saves)
+			}
+			if len(history.transitions) == 0 || history.transitions[0].From != "" || history.transitions[0].To != string(StatusRunning) {
+				t.Errorf("first transition = %+v, want saga started", history.transitions)
+			}
+		})
+	}
+}
+
+func TestOrchestratorDuplicateReplyPanics(t *testing.T) {
+	def := testDefinition()
+	def.Steps[1].SuccessEvent = def.Steps[0].SuccessEvent
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("NewOrchestrator did not panic on an event handled by two steps")
+		}
+	}()
+	NewOrchestrator(def, &fakeStore{}, &fakeHistory{}, &fakePublisher{})
+}
@@ -0,0 +1,109 @@
+package saga
+
+import (
+	"context"
+	"time"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/eventual/pkg/event"
+)
+
+// Status adalah status satu instance saga
+type Status string
+
+const (
+	StatusRunning   Status = "RUNNING"
+	StatusCompleted Status = "COMPLETED"
+	// StatusFailed berarti salah satu step gagal atau timeout dan command kompensasi sudah dikirim
+	StatusFailed Status = "FAILED"
+)
+
+// StepStatus adalah status satu step dalam instance saga
+type StepStatus string
+
+const (
+	// StepPending berarti step menunggu step yang menjadi syaratnya berhasil
+	StepPending StepStatus = "PENDING"
+	// StepSkipped berarti step tidak dibutuhkan instance, misalnya sub-transaksi tanpa item
+	StepSkipped StepStatus = "SKIPPED"
+	// StepStarted berarti command sudah dikirim dan balasannya ditunggu
+	StepStarted   StepStatus = "STARTED"
+	StepSucceeded StepStatus = "SUCCEEDED"
+	StepFailed    StepStatus = "FAILED"
+	// StepTimedOut berarti balasan tidak datang sebelum batas waktu step
+	StepTimedOut StepStatus = "TIMED_OUT"
+	// StepCompensating berarti command kompensasi sudah dikirim dan balasannya ditunggu
+	StepCompensating StepStatus = "COMPENSATING"
+	StepCompensated  StepStatus = "COMPENSATED"
+)
+
+// Step mendefinisikan satu langkah saga: command yang dikirim, event balasan yang
+// ditunggu, dan command kompensasinya
+type Step[T any] struct {
+	Name string
+	// Requires adalah nama step yang harus berhasil (atau dilewati) sebelum step ini
+	// dimulai. Step tanpa Requires dimulai bersamaan saat saga dimulai.
+	Requires []string
+	// Skip melewati step yang tidak dibutuhkan instance, nil berarti step selalu dijalankan
+	Skip func(data T) bool
+	// OnStart dipanggil saat step dimulai, sebelum state disimpan
+	OnStart func(data T)
+	// Command menyusun command yang dikirim saat step dimulai atau di-retry
+	Command func(data T) event.Message
+
+	SuccessEvent event.EventName
+	FailureEvent event.EventName
+	// OnSuccess dan OnFailure mencatat isi balasan ke data instance
+	OnSuccess func(data T, msg event.Message) error
+	OnFailure func(data T, msg event.Message) error
+
+	// Compensation menyusun command kompensasi untuk step yang sudah dimulai dan tidak
+	// gagal. ok bernilai false jika step tidak perlu dikompensasi.
+	Compensation func(data T) (msg event.Message, ok bool)
+	// CompensatedEvent adalah balasan command kompensasi. Kosong berarti command
+	// kompensasi tidak dibalas sehingga step langsung COMPENSATED.
+	CompensatedEvent event.EventName
+
+	// Timeout adalah batas waktu menunggu balasan command, 0 berarti tanpa batas
+	Timeout time.Duration
+}
+
+// Definition mendefinisikan saga secara deklaratif. Step dimulai dan dikompensasi
+// sesuai urutan Steps.
+type Definition[T any] struct {
+	Name  string
+	Steps []Step[T]
+	// OnCompleted dipanggil setelah seluruh step berhasil dan mengembalikan event final
+	OnCompleted func(data T) event.Message
+	// OnFailed dipanggil saat saga gagal atau dikompensasi ulang dan mengembalikan event
+	// final yang dikirim setelah command kompensasi
+	OnFailed func(data T, reason string) event.Message
+}
+
+// State adalah state instance saga yang disimpan bersama data instance
+type State struct {
+	Status Status               `firestore:"status" json:"status"`
+	Steps  map[string]StepState `firestore:"steps" json:"steps"`
+	// Deadline adalah batas waktu paling awal dari step yang sedang berjalan. Kosong jika
+	// tidak ada step dengan batas waktu, sehingga instance tidak dibaca sweeper.
+	Deadline *time.Time `firestore:"deadline,omitempty" json:"deadline,omitempty"`
+}
+
+// StepState adalah state satu step
+type StepState struct {
+	Status    StepStatus `firestore:"status" json:"status"`
+	Deadline  *time.Time `firestore:"deadline,omitempty" json:"deadline,omitempty"`
+	UpdatedAt time.Time  `firestore:"updated_at" json:"updated_at"`
+}
+
+// Instance adalah data yang dijalankan saga, misalnya order
+type Instance interface {
+	SagaID() string
+	SagaState() *State
+}
+
+// Store menyimpan instance saga beserta state-nya
+type Store[T Instance] interface {
+	Save(ctx context.Context, data T) error
+	// Expired mengembalikan instance yang punya step melewati batas waktu pada now
+	Expired(ctx context.Context, now time.Time) ([]T, error)
+}
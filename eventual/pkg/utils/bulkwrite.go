@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// WriteMode menentukan cara BulkWrite memperlakukan dokumen yang sudah ada
+type WriteMode string
+
+const (
+	// WriteModeCreate hanya membuat dokumen baru, dokumen yang sudah ada dilewati
+	WriteModeCreate WriteMode = "create"
+	// WriteModeUpsert membuat dokumen baru dan memperbarui dokumen yang sudah ada
+	WriteModeUpsert WriteMode = "upsert"
+)
+
+// Doc adalah dokumen yang ditulis BulkWrite
+type Doc struct {
+	Ref  *firestore.DocumentRef
+	Data any
+}
+
+// BulkWriteResult adalah jumlah dokumen yang ditulis dan dilewati BulkWrite
+type BulkWriteResult struct {
+	Written int
+	Skipped int
+}
+
+// BulkWrite menulis docs dengan BulkWriter sesuai mode, sehingga aman dijalankan
+// berulang kali. Pada WriteModeUpsert, fields membatasi field yang diperbarui pada dokumen
+// yang sudah ada agar field lain seperti status ketersediaan tidak berubah. Tanpa fields,
+// dokumen yang sudah ada ditimpa seluruhnya.
+func BulkWrite(ctx context.Context, client *firestore.Client, docs []Doc, mode WriteMode, fields ...string) (BulkWriteResult, error) {
+	if mode == WriteModeUpsert && len(fields) == 0 {
+		written, _, err := bulkWrite(ctx, client, docs, func(bw *firestore.BulkWriter, doc Doc) (*firestore.BulkWriterJob, error) {
+			return bw.Set(doc.Ref, doc.Data)
+		})
+		return BulkWriteResult{Written: written}, err
+	}
+
+	created, existing, err := bulkWrite(ctx, client, docs, func(bw *firestore.BulkWriter, doc Doc) (*firestore.BulkWriterJob, error) {
+		return bw.Create(doc.Ref, doc.Data)
+	})
+	if err != nil || mode == WriteModeCreate || len(existing) == 0 {
+		return BulkWriteResult{Written: created, Skipped: len(existing)}, err
+	}
+
+	paths := make([]firestore.FieldPath, 0, len(fields))
+	for _, field := range fields {
+		paths = append(paths, firestore.FieldPath{field})
+	}
+	updated, _, err := bulkWrite(ctx, client, existing, func(bw *firestore.BulkWriter, doc Doc) (*firestore.BulkWriterJob, error) {
+		return bw.Set(doc.Ref, doc.Data, firestore.Merge(paths...))
+	})
+	return BulkWriteResult{Written: created + updated}, err
+}
+
+// bulkWrite menjalankan write untuk setiap dokumen dan memisahkan dokumen yang sudah ada
+func bulkWrite(ctx context.Context, client *firestore.Client, docs []Doc, write func(*firestore.BulkWriter, Doc) (*firestore.BulkWriterJob, error)) (int, []Doc, error) {
+	bw := client.BulkWriter(ctx)
+	jobs := make([]*firestore.BulkWriterJob, 0, len(docs))
+	for _, doc := range docs {
+		job, err := write(bw, doc)
+		if err != nil {
+			bw.End()
+			return 0, nil, fmt.Errorf("failed to write %s: %w", doc.Ref.Path, err)
+		}
+		jobs = append(jobs, job)
+	}
+	bw.End()
+
+	written, failed := 0, 0
+	var existing []Doc
+	var firstErr error
+	for i, job := range jobs {
+		_, err := job.Results()
+		switch {
+		case err == nil:
+			written++
+		case status.Code(err) == codes.AlreadyExists:
+			existing = append(existing, docs[i])
+		default:
+			failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	if failed > 0 {
+		return written, existing, fmt.Errorf("failed to write %d of %d documents: %w", failed, len(docs), firstErr)
+	}
+	return written, existing, nil
+}
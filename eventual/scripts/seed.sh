@@ -1,7 +1,8 @@
 #!/bin/bash
 
 # Database Seeder Script
-# Pastikan GOOGLE_PROJECT_ID sudah diset di environment
+# Pastikan GOOGLE_PROJECT_ID sudah diset di environment. Argumen diteruskan ke seeder,
+# mis. ./scripts/seed.sh -spec my-spec.yaml -upsert
 
 echo "🚀 Starting Database Seeder..."
 echo ""
@@ -22,17 +23,17 @@ cd "$(dirname "$0")/.."
 
 # Run the seeder
 echo "🌱 Running database seeder..."
-go run cmd/seeder/main.go
+output=$(mktemp)
+trap 'rm -f "$output"' EXIT
+go run ./cmd/seeder "$@" 2>&1 | tee "$output"
 
-if [ $? -eq 0 ]; then
+if [ "${PIPESTATUS[0]}" -eq 0 ]; then
     echo ""
     echo "✅ Database seeding completed successfully!"
     echo ""
+    # Jumlah data dihitung seeder dari spec
     echo "📊 Summary:"
-    echo "   - Cars: 5,000 units"
-    echo "   - Hotel Rooms: 1,500 rooms"
-    echo "   - Train Seats: 5,000 seats"
-    echo "   - Total: 11,500 records"
+    grep -o "Seed data: .*" "$output" | sed 's/^Seed data: /   - /; s/, /\n   - /g'
 else
     echo ""
     echo "❌ Database seeding failed!"
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/coordinator"
+)
+
+// violation is a broken invariant. repair is nil when it cannot be fixed automatically.
+type violation struct {
+	Check  string
+	Detail string
+	repair func(ctx context.Context) error
+}
+
+// auditBookings checks every finished booking transaction against the participants.
+// Committed bookings must have one active reservation per item in a committed participant
+// transaction, while aborted, rolled back, timed out and cancelled bookings must not hold
+// any active reservation. Transactions still in progress are skipped.
+func auditBookings(logs []coordinator.TransactionLog, participants []*participant) []violation {
+	byName := make(map[string]*participant, len(participants))
+	for _, p := range participants {
+		byName[p.name] = p
+	}
+
+	// Reservations created by a transaction, also those a participant transaction no
+	// longer lists
+	byTransaction := make(map[string]map[string][]string, len(participants))
+	for _, p := range participants {
+		byTransaction[p.name] = make(map[string][]string)
+		for _, r := range p.reservations {
+			if r.Active {
+				byTransaction[p.name][r.TransactionID] = append(byTransaction[p.name][r.TransactionID], r.ID)
+			}
+		}
+	}
+
+	var violations []violation
+	for _, log := range logs {
+		if log.Kind != coordinator.KindBooking && log.Kind != "" {
+			continue
+		}
+
+		switch log.Status {
+		case coordinator.StatusCommitted:
+			for _, lp := range log.Participants {
+				p, ok := byName[lp.ServiceName]
+				if !ok {
+					continue
+				}
+				violations = append(violations, auditCommitted(log.ID, len(lp.Items), p)...)
+			}
+
+		case coordinator.StatusAborted, coordinator.StatusRolledBack, coordinator.StatusTimedOut:
+			for _, p := range participants {
+				violations = append(violations, auditAborted(log.ID, string(log.Status), p, byTransaction[p.name][log.ID])...)
+			}
+
+		case coordinator.StatusCancelled:
+			for _, lp := range log.Participants {
+				p, ok := byName[lp.ServiceName]
+				if !ok {
+					continue
+				}
+				active := p.activeReservations(p.transactions[log.ID].ReservationIDs)
+				if len(active) > 0 {
+					violations = append(violations, violation{
+						Check:  "cancelled_booking_reservations_active",
+						Detail: fmt.Sprintf("transaction %s is cancelled but %s reservations %s are active", log.ID, p.name, strings.Join(active, ", ")),
+					})
+				}
+			}
+		}
+	}
+
+	return violations
+}
+
+// auditCommitted checks the participant side of a committed booking with items items.
+// Logs written before items were tracked expect one reservation per reservation ID.
+func auditCommitted(transactionID string, items int, p *participant) []violation {
+	t, ok := p.transactions[transactionID]
+	if !ok {
+		return []violation{{
+			Check:  "committed_booking_transaction_missing",
+			Detail: fmt.Sprintf("transaction %s is committed but %s has no transaction", transactionID, p.name),
+		}}
+	}
+
+	var violations []violation
+	if t.Status != statusCommitted {
+		v := violation{
+			Check:  "committed_booking_not_committed",
+			Detail: fmt.Sprintf("transaction %s is committed but is %s in %s", transactionID, t.Status, p.name),
+		}
+		// The coordinator decided to commit, so a prepared participant only missed the commit
+		if t.Status == statusPrepared {
+			v.repair = func(ctx context.Context) error {
+				return p.commit(ctx, transactionID)
+			}
+		}
+		violations = append(violations, v)
+	}
+
+	if items == 0 {
+		items = len(t.ReservationIDs)
+	}
+	if active := p.activeReservations(t.ReservationIDs); len(active) != items {
+		violations = append(violations, violation{
+			Check:  "committed_booking_reservation_count",
+			Detail: fmt.Sprintf("transaction %s has %d active %s reservations for %d items (reservations: %s)", transactionID, len(active), p.name, items, strings.Join(t.ReservationIDs, ", ")),
+		})
+	}
+
+	return violations
+}
+
+// auditAborted checks that a booking that ended in status holds nothing in the participant
+func auditAborted(transactionID, status string, p *participant, active []string) []violation {
+	t, ok := p.transactions[transactionID]
+	if ok && (t.Status == statusPrepared || t.Status == statusCommitted) {
+		v := violation{
+			Check:  "aborted_booking_still_live",
+			Detail: fmt.Sprintf("transaction %s is %s but is %s in %s (reservations: %s)", transactionID, status, t.Status, p.name, strings.Join(t.ReservationIDs, ", ")),
+		}
+		// Aborting a prepared transaction releases its reservations and availability as well
+		if t.Status == statusPrepared {
+			v.repair = func(ctx context.Context) error {
+				return p.abort(ctx, transactionID)
+			}
+		}
+		return []violation{v}
+	}
+
+	if len(active) > 0 {
+		return []violation{{
+			Check:  "aborted_booking_reservations_active",
+			Detail: fmt.Sprintf("transaction %s is %s but %s reservations %s are active", transactionID, status, p.name, strings.Join(active, ", ")),
+		}}
+	}
+	return nil
+}
+
+// auditAvailability checks that every unavailable document of the participant is taken by
+// an active reservation of a prepared, committed or held transaction. The repair marks
+// the document available again.
+func auditAvailability(client *firestore.Client, p *participant) []violation {
+	taken := make(map[string]bool)
+	for _, r := range p.reservations {
+		if r.Active && p.transactions[r.TransactionID].live() {
+			for _, slot := range r.Slots {
+				taken[slot] = true
+			}
+		}
+	}
+
+	var violations []violation
+	for _, id := range p.unavailable {
+		if taken[id] {
+			continue
+		}
+
+		ref := client.Collection(p.availabilityCollection).Doc(id)
+		violations = append(violations, violation{
+			Check:  "unavailable_without_transaction",
+			Detail: fmt.Sprintf("%s %s is unavailable without a live transaction", p.availabilityCollection, id),
+			repair: func(ctx context.Context) error {
+				_, err := ref.Update(ctx, []firestore.Update{
+					{Path: "available", Value: true},
+				})
+				return err
+			},
+		})
+	}
+	return violations
+}
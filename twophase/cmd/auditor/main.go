@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+
+	"cloud.google.com/go/firestore"
+	"github.com/joho/godotenv"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/coordinator"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/config"
+)
+
+// The auditor checks that a run ended consistent across the coordinator and the
+// participants. It should run once the load has stopped, as transactions that are still
+// in flight can look like violations. It exits with status 1 while violations remain.
+func main() {
+	repair := flag.Bool("repair", false, "fix the violations that can be fixed automatically")
+	flag.Parse()
+
+	log.Println("Starting auditor...")
+
+	if err := godotenv.Load(); err != nil {
+		log.Printf("Error loading .env file: %v, using system environment variables", err)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	ctx := context.Background()
+	client, err := firestore.NewClient(ctx, cfg.GoogleProjectID)
+	if err != nil {
+		log.Fatalf("Failed to create Firestore client: %v", err)
+	}
+	defer client.Close()
+
+	transactionLogs, err := readAll[coordinator.TransactionLog](ctx, client.Collection("twophase_transactions").Query)
+	if err != nil {
+		log.Fatalf("Failed to read transaction logs: %v", err)
+	}
+
+	participants, err := loadParticipants(ctx, client)
+	if err != nil {
+		log.Fatalf("Failed to read participants: %v", err)
+	}
+
+	violations := auditBookings(transactionLogs, participants)
+	for _, p := range participants {
+		violations = append(violations, auditAvailability(client, p)...)
+	}
+
+	repairable := 0
+	for _, v := range violations {
+		log.Printf("[%s] %s", v.Check, v.Detail)
+		if v.repair != nil {
+			repairable++
+		}
+	}
+	log.Printf("Audited %d transaction logs: %d violations, %d repairable", len(transactionLogs), len(violations), repairable)
+
+	if !*repair {
+		if len(violations) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	repaired := 0
+	for _, v := range violations {
+		if v.repair == nil {
+			continue
+		}
+		if err := v.repair(ctx); err != nil {
+			log.Printf("Failed to repair [%s] %s: %v", v.Check, v.Detail, err)
+			continue
+		}
+		repaired++
+	}
+
+	log.Printf("Repaired %d of %d violations", repaired, len(violations))
+	if repaired < len(violations) {
+		log.Printf("Run the auditor again to list the violations that remain")
+		os.Exit(1)
+	}
+}
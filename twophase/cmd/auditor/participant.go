@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/car"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/flight"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/hotel"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/train"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/config"
+)
+
+// Participant transaction statuses. They are the same in every participant.
+const (
+	statusPrepared  = "PREPARED"
+	statusCommitted = "COMMITTED"
+	statusHeld      = "HELD"
+)
+
+// participantTransaction is a two-phase transaction as recorded by a participant
+type participantTransaction struct {
+	ID             string
+	Status         string
+	ReservationIDs []string
+}
+
+// live reports whether the transaction may keep inventory unavailable
+func (t participantTransaction) live() bool {
+	return t.Status == statusPrepared || t.Status == statusCommitted || t.Status == statusHeld
+}
+
+// reservation is a reservation of a participant with the availability documents it makes
+// unavailable
+type reservation struct {
+	ID            string
+	TransactionID string
+	Status        string
+	Active        bool
+	Slots         []string
+}
+
+// participant is everything the auditor reads from one participant service
+type participant struct {
+	name                   string
+	availabilityCollection string
+	transactions           map[string]participantTransaction
+	reservations           map[string]reservation
+	// unavailable holds the IDs of the availability documents marked unavailable
+	unavailable []string
+
+	// commit and abort finish a prepared booking transaction of the participant
+	commit func(ctx context.Context, transactionID string) error
+	abort  func(ctx context.Context, transactionID string) error
+}
+
+func newParticipant(name, availabilityCollection string) *participant {
+	return &participant{
+		name:                   name,
+		availabilityCollection: availabilityCollection,
+		transactions:           make(map[string]participantTransaction),
+		reservations:           make(map[string]reservation),
+	}
+}
+
+// activeReservations returns the IDs among reservationIDs of reservations that are not cancelled
+func (p *participant) activeReservations(reservationIDs []string) []string {
+	var active []string
+	for _, id := range reservationIDs {
+		if p.reservations[id].Active {
+			active = append(active, id)
+		}
+	}
+	return active
+}
+
+// loadParticipants reads the hotel, car, train and flight participants. Payment holds no
+// inventory and is not audited.
+func loadParticipants(ctx context.Context, client *firestore.Client) ([]*participant, error) {
+	loaders := []func(context.Context, *firestore.Client) (*participant, error){loadHotel, loadCar, loadTrain, loadFlight}
+
+	participants := make([]*participant, 0, len(loaders))
+	for _, load := range loaders {
+		p, err := load(ctx, client)
+		if err != nil {
+			return nil, err
+		}
+		participants = append(participants, p)
+	}
+	return participants, nil
+}
+
+func loadHotel(ctx context.Context, client *firestore.Client) (*participant, error) {
+	p := newParticipant("hotel", hotel.HotelRoomAvailabilityCollection)
+	repo := hotel.NewRepository(client)
+	p.commit, p.abort = repo.CommitRoomReservation, repo.AbortRoomReservation
+
+	transactions, err := readAll[hotel.TwoPhaseTransaction](ctx, client.Collection(hotel.HotelRoomTransactionCollection).Query)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range transactions {
+		p.transactions[t.Id] = participantTransaction{ID: t.Id, Status: string(t.Status), ReservationIDs: t.ReservationIDs}
+	}
+
+	reservations, err := readAll[hotel.HotelReservation](ctx, client.Collection(hotel.HotelRoomReservationCollection).Query)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range reservations {
+		slots, err := dailySlots(r.HotelRoomID, r.HotelRoomStartDate, r.HotelRoomEndDate)
+		if err != nil {
+			return nil, fmt.Errorf("hotel reservation %s: %w", r.ID, err)
+		}
+		p.reservations[r.ID] = reservation{
+			ID:            r.ID,
+			TransactionID: r.TransactionID,
+			Status:        string(r.Status),
+			Active:        r.Status != hotel.HotelRoomReservationStatusCancelled,
+			Slots:         slots,
+		}
+	}
+
+	return p, p.loadUnavailable(ctx, client)
+}
+
+func loadCar(ctx context.Context, client *firestore.Client) (*participant, error) {
+	p := newParticipant("car", car.CarAvailabilityCollection)
+	repo := car.NewRepository(client)
+	p.commit, p.abort = repo.CommitCarReservation, repo.AbortCarReservation
+
+	transactions, err := readAll[car.TwoPhaseTransaction](ctx, client.Collection(car.CarTransactionCollection).Query)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range transactions {
+		p.transactions[t.Id] = participantTransaction{ID: t.Id, Status: string(t.Status), ReservationIDs: t.ReservationIDs}
+	}
+
+	reservations, err := readAll[car.CarReservation](ctx, client.Collection(car.CarReservationCollection).Query)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range reservations {
+		slots, err := dailySlots(r.CarID, r.CarStartDate, r.CarEndDate)
+		if err != nil {
+			return nil, fmt.Errorf("car reservation %s: %w", r.ID, err)
+		}
+		p.reservations[r.ID] = reservation{
+			ID:            r.ID,
+			TransactionID: r.TransactionID,
+			Status:        string(r.Status),
+			Active:        r.Status != car.CarReservationStatusCancelled,
+			Slots:         slots,
+		}
+	}
+
+	return p, p.loadUnavailable(ctx, client)
+}
+
+func loadTrain(ctx context.Context, client *firestore.Client) (*participant, error) {
+	p := newParticipant("train", train.TrainSeatTicketCollection)
+	repo := train.NewRepository(client)
+	p.commit, p.abort = repo.CommitSeatReservation, repo.AbortSeatReservation
+
+	transactions, err := readAll[train.TwoPhaseTransaction](ctx, client.Collection(train.TrainTransactionCollection).Query)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range transactions {
+		p.transactions[t.Id] = participantTransaction{ID: t.Id, Status: string(t.Status), ReservationIDs: t.ReservationIDs}
+	}
+
+	reservations, err := readAll[train.TrainSeatReservation](ctx, client.Collection(train.TrainSeatReservationCollection).Query)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range reservations {
+		// Seat tickets are keyed by journey, seat and segment
+		var slots []string
+		for segment := r.FromSegment; segment < r.ToSegment; segment++ {
+			slots = append(slots, fmt.Sprintf("%s-%s-%d", r.JourneyID, r.SeatID, segment))
+		}
+		p.reservations[r.ID] = reservation{
+			ID:            r.ID,
+			TransactionID: r.TransactionID,
+			Status:        string(r.Status),
+			Active:        r.Status != train.TrainSeatReservationStatusCancelled,
+			Slots:         slots,
+		}
+	}
+
+	return p, p.loadUnavailable(ctx, client)
+}
+
+func loadFlight(ctx context.Context, client *firestore.Client) (*participant, error) {
+	p := newParticipant("flight", flight.FlightSeatCollection)
+	repo := flight.NewRepository(client)
+	p.commit, p.abort = repo.CommitFlightReservation, repo.AbortFlightReservation
+
+	transactions, err := readAll[flight.TwoPhaseTransaction](ctx, client.Collection(flight.FlightTransactionCollection).Query)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range transactions {
+		p.transactions[t.Id] = participantTransaction{ID: t.Id, Status: string(t.Status), ReservationIDs: t.ReservationIDs}
+	}
+
+	reservations, err := readAll[flight.FlightReservation](ctx, client.Collection(flight.FlightReservationCollection).Query)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range reservations {
+		p.reservations[r.ID] = reservation{
+			ID:            r.ID,
+			TransactionID: r.TransactionID,
+			Status:        string(r.Status),
+			Active:        r.Status != flight.FlightReservationStatusCancelled,
+			Slots:         []string{fmt.Sprintf("%s-%s", r.FlightID, r.SeatID)},
+		}
+	}
+
+	return p, p.loadUnavailable(ctx, client)
+}
+
+// loadUnavailable reads the IDs of the availability documents marked unavailable
+func (p *participant) loadUnavailable(ctx context.Context, client *firestore.Client) error {
+	docs, err := client.Collection(p.availabilityCollection).Where("available", "==", false).Documents(ctx).GetAll()
+	if err != nil {
+		return fmt.Errorf("failed to get %s: %w", p.availabilityCollection, err)
+	}
+	for _, doc := range docs {
+		p.unavailable = append(p.unavailable, doc.Ref.ID)
+	}
+	return nil
+}
+
+// readAll reads every document of query into T
+func readAll[T any](ctx context.Context, query firestore.Query) ([]T, error) {
+	docs, err := query.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get documents: %w", err)
+	}
+
+	values := make([]T, 0, len(docs))
+	for _, doc := range docs {
+		var value T
+		if err := doc.DataTo(&value); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %s: %w", doc.Ref.Path, err)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// dailySlots returns the availability document IDs "{id}-{date}" of every date from start
+// to end (inclusive). Nothing is returned when id is empty, i.e. for room type
+// reservations without an assigned room.
+func dailySlots(id, start, end string) ([]string, error) {
+	if id == "" {
+		return nil, nil
+	}
+
+	startDate, err := time.Parse(config.DateFormat, start)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse start date: %w", err)
+	}
+	endDate, err := time.Parse(config.DateFormat, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse end date: %w", err)
+	}
+
+	var slots []string
+	for date := startDate; !date.After(endDate); date = date.AddDate(0, 0, 1) {
+		slots = append(slots, fmt.Sprintf("%s-%s", id, date.Format(config.DateFormat)))
+	}
+	return slots, nil
+}
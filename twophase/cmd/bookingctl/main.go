@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"github.com/joho/godotenv"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/coordinator"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/pricing"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/config"
+)
+
+// command is a single bookingctl command
+type command struct {
+	usage       string
+	description string
+	run         func(ctx context.Context, a *app, args []string) error
+}
+
+// commands holds every bookingctl command keyed by its space separated name
+var commands = map[string]command{
+	"tx get": {
+		usage:       "tx get <txID>",
+		description: "show a coordinator transaction and its participants",
+		run:         txGet,
+	},
+	"tx list": {
+		usage:       "tx list [-status STATUS] [-order ID] [-limit N]",
+		description: "list coordinator transactions",
+		run:         txList,
+	},
+	"tx commit": {
+		usage:       "tx commit <txID>",
+		description: "commit a prepared transaction in every participant",
+		run:         txCommit,
+	},
+	"tx abort": {
+		usage:       "tx abort <txID>",
+		description: "abort a transaction that has not committed",
+		run:         txAbort,
+	},
+	"participant tx list": {
+		usage:       "participant tx list [-service NAME]",
+		description: "list the PREPARED transactions of the participants",
+		run:         participantTxList,
+	},
+}
+
+// bookingctl lets an operator inspect and unblock two-phase commit transactions
+// without the Firestore console. The commit and abort commands send the same requests
+// to the participants as the coordinator, using the coordinator environment variables.
+func main() {
+	name, cmd, args, ok := lookup(os.Args[1:])
+	if !ok {
+		usage()
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Printf("Error loading .env file: %v, using system environment variables", err)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	ctx := context.Background()
+	client, err := firestore.NewClient(ctx, cfg.GoogleProjectID)
+	if err != nil {
+		log.Fatalf("Failed to create Firestore client: %v", err)
+	}
+
+	repo := coordinator.NewRepository(client)
+	pricingService := pricing.NewService(pricing.NewRepository(client), pricing.DefaultQuoteTTL)
+	a := &app{
+		client:      client,
+		repo:        repo,
+		coordinator: coordinator.NewService(repo, pricingService, coordinator.ConfigFromEnv()),
+	}
+
+	err = cmd.run(ctx, a, args)
+	client.Close()
+	if err != nil {
+		log.Fatalf("%s: %v", name, err)
+	}
+}
+
+// lookup finds the command named by the leading words of args, preferring the longest name
+func lookup(args []string) (string, command, []string, bool) {
+	for n := len(args); n > 0; n-- {
+		name := strings.Join(args[:n], " ")
+		if cmd, ok := commands[name]; ok {
+			return name, cmd, args[n:], true
+		}
+	}
+	return "", command{}, nil, false
+}
+
+func usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Usage: %s <command> [flags] [args]\n\nCommands:\n", os.Args[0])
+	for _, name := range names {
+		fmt.Fprintf(&b, "  %-50s %s\n", commands[name].usage, commands[name].description)
+	}
+	fmt.Fprint(os.Stderr, b.String())
+	os.Exit(2)
+}
+
+// app holds the dependencies shared by the commands
+type app struct {
+	client      *firestore.Client
+	repo        *coordinator.Repository
+	coordinator *coordinator.Service
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/car"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/flight"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/hotel"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/payment"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/train"
+)
+
+// preparedTransaction is the part of a participant TwoPhaseTransaction that is listed
+type preparedTransaction struct {
+	ID                   string
+	BookingTransactionID string
+	// Documents are the reservation IDs, or the payment ID for the payment service
+	Documents []string
+	CreatedAt time.Time
+}
+
+// preparedLoaders reads the PREPARED transactions of each participant, in the order
+// the coordinator prepares them
+var preparedLoaders = []struct {
+	service string
+	load    func(ctx context.Context, a *app) ([]preparedTransaction, error)
+}{
+	{"hotel", func(ctx context.Context, a *app) ([]preparedTransaction, error) {
+		txs, err := hotel.NewRepository(a.client).GetPreparedTransactions(ctx)
+		return mapPrepared(txs, err, func(tx *hotel.TwoPhaseTransaction) preparedTransaction {
+			return preparedTransaction{tx.Id, tx.BookingTransactionID, tx.ReservationIDs, tx.CreatedAt}
+		})
+	}},
+	{"car", func(ctx context.Context, a *app) ([]preparedTransaction, error) {
+		txs, err := car.NewRepository(a.client).GetPreparedTransactions(ctx)
+		return mapPrepared(txs, err, func(tx *car.TwoPhaseTransaction) preparedTransaction {
+			return preparedTransaction{tx.Id, tx.BookingTransactionID, tx.ReservationIDs, tx.CreatedAt}
+		})
+	}},
+	{"train", func(ctx context.Context, a *app) ([]preparedTransaction, error) {
+		txs, err := train.NewRepository(a.client).GetPreparedTransactions(ctx)
+		return mapPrepared(txs, err, func(tx *train.TwoPhaseTransaction) preparedTransaction {
+			return preparedTransaction{tx.Id, tx.BookingTransactionID, tx.ReservationIDs, tx.CreatedAt}
+		})
+	}},
+	{"flight", func(ctx context.Context, a *app) ([]preparedTransaction, error) {
+		txs, err := flight.NewRepository(a.client).GetPreparedTransactions(ctx)
+		return mapPrepared(txs, err, func(tx *flight.TwoPhaseTransaction) preparedTransaction {
+			return preparedTransaction{tx.Id, tx.BookingTransactionID, tx.ReservationIDs, tx.CreatedAt}
+		})
+	}},
+	{"payment", func(ctx context.Context, a *app) ([]preparedTransaction, error) {
+		txs, err := payment.NewRepository(a.client).GetPreparedTransactions(ctx)
+		return mapPrepared(txs, err, func(tx *payment.TwoPhaseTransaction) preparedTransaction {
+			var documents []string
+			if tx.PaymentID != "" {
+				documents = []string{tx.PaymentID}
+			}
+			return preparedTransaction{tx.Id, tx.BookingTransactionID, documents, tx.CreatedAt}
+		})
+	}},
+}
+
+func mapPrepared[T any](txs []*T, err error, f func(*T) preparedTransaction) ([]preparedTransaction, error) {
+	if err != nil {
+		return nil, err
+	}
+	prepared := make([]preparedTransaction, 0, len(txs))
+	for _, tx := range txs {
+		prepared = append(prepared, f(tx))
+	}
+	return prepared, nil
+}
+
+func participantTxList(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("participant tx list", flag.ContinueOnError)
+	service := flags.String("service", "", "only list this participant: hotel, car, train, flight or payment (default all)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	found := false
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SERVICE\tTX\tBOOKING_TX\tDOCUMENTS\tAGE")
+	for _, loader := range preparedLoaders {
+		if *service != "" && *service != loader.service {
+			continue
+		}
+		found = true
+
+		txs, err := loader.load(ctx, a)
+		if err != nil {
+			return fmt.Errorf("%s: %w", loader.service, err)
+		}
+		for _, tx := range txs {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+				loader.service, tx.ID, tx.BookingTransactionID, strings.Join(tx.Documents, ","), age(tx.CreatedAt))
+		}
+	}
+	if !found {
+		return fmt.Errorf("unknown service %q", *service)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/coordinator"
+)
+
+func txGet(ctx context.Context, a *app, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: tx get <txID>")
+	}
+
+	status, err := a.coordinator.GetTransactionStatus(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	return printJSON(status)
+}
+
+func txList(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("tx list", flag.ContinueOnError)
+	status := flags.String("status", "", "transaction status, e.g. prepared or timed_out (default all)")
+	orderID := flags.String("order", "", "only list the transactions of this order")
+	limit := flags.Int("limit", 50, "maximum number of transactions")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var logs []*coordinator.TransactionLog
+	var err error
+	if *orderID != "" {
+		logs, err = a.repo.GetTransactionLogsByOrderID(ctx, *orderID)
+	} else {
+		logs, err = a.repo.ListTransactionLogs(ctx, coordinator.TransactionStatus(strings.ToLower(*status)), *limit)
+	}
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tORDER\tKIND\tSTATUS\tPARTICIPANTS\tAGE\tREASON")
+	for _, l := range logs {
+		// GetTransactionLogsByOrderID does not filter by status
+		if *status != "" && !strings.EqualFold(string(l.Status), *status) {
+			continue
+		}
+		kind := l.Kind
+		if kind == "" {
+			kind = coordinator.KindBooking
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			l.ID, l.OrderID, kind, l.Status, participants(l.Participants), age(l.CreatedAt), l.FailureReason)
+	}
+	return w.Flush()
+}
+
+func txCommit(ctx context.Context, a *app, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: tx commit <txID>")
+	}
+
+	if err := a.coordinator.CommitTransaction(ctx, args[0]); err != nil {
+		return err
+	}
+
+	log.Printf("Transaction %s committed", args[0])
+	return nil
+}
+
+func txAbort(ctx context.Context, a *app, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: tx abort <txID>")
+	}
+
+	if err := a.coordinator.AbortTransaction(ctx, args[0]); err != nil {
+		return err
+	}
+
+	// Participants that did not answer the abort request keep their status
+	l, err := a.repo.GetTransactionLog(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	log.Printf("Transaction %s aborted, participants: %s", l.ID, participants(l.Participants))
+	return nil
+}
+
+// participants formats the status of every participant, e.g. "hotel=prepared car=failed"
+func participants(ps []coordinator.Participant) string {
+	statuses := make([]string, 0, len(ps))
+	for _, p := range ps {
+		statuses = append(statuses, p.ServiceName+"="+p.Status)
+	}
+	return strings.Join(statuses, " ")
+}
+
+// printJSON writes v to stdout as indented JSON
+func printJSON(v any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// age is the time since t, rounded to the second
+func age(t time.Time) string {
+	return time.Since(t).Round(time.Second).String()
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/car"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/config"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/fault"
 )
 
 const (
@@ -41,13 +42,37 @@ func main() {
 	}
 	defer client.Close()
 
-	carRepo := car.NewRepository(client)
-	carService := car.NewService(carRepo)
+	faultConfig, err := fault.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load fault config: %v", err)
+	}
+	injector := fault.NewInjector("car-service", faultConfig, fault.NewRecorder(client))
+
+	carRepo := car.NewRepository(client).WithFaults(injector)
+	carService := car.NewService(carRepo, cfg.HoldTTL)
 	carHandler := car.NewHandler(carService)
 
+	// Start sweeper releasing expired holds
+	go func() {
+		ticker := time.NewTicker(cfg.HoldSweepInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := carService.ReleaseExpiredHolds(ctx); err != nil {
+					log.Printf("Failed to release expired holds: %v", err)
+				}
+			}
+		}
+	}()
+
 	// Start HTTP server
 	router := gin.Default()
 	carHandler.RegisterRoutes(router)
+	fault.NewHandler(injector).RegisterRoutes(router)
 
 	// Create HTTP server with proper shutdown handling
 	srv := &http.Server{
@@ -13,6 +13,8 @@ import (
 	"github.com/joho/godotenv"
 
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/coordinator"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/pricing"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/fault"
 )
 
 func main() {
@@ -32,41 +34,29 @@ func main() {
 	// Initialize repository
 	repo := coordinator.NewRepository(client)
 
-	// Initialize configuration
-	config := coordinator.DefaultConfig()
+	// Initialize configuration from the environment
+	config := coordinator.ConfigFromEnv()
 
-	// Override with environment variables if provided
-	if timeout := os.Getenv("TRANSACTION_TIMEOUT"); timeout != "" {
-		if duration, err := time.ParseDuration(timeout); err == nil {
-			config.TransactionTimeout = duration
-		}
-	}
-
-	if maxRetries := os.Getenv("MAX_RETRIES"); maxRetries != "" {
-		if retries, err := time.ParseDuration(maxRetries); err == nil {
-			config.MaxRetries = int(retries)
-		}
+	// Faults are injected into the requests sent to the participants
+	faultConfig, err := fault.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load fault config: %v", err)
 	}
-
-	if retryDelay := os.Getenv("RETRY_DELAY"); retryDelay != "" {
-		if delay, err := time.ParseDuration(retryDelay); err == nil {
-			config.RetryDelay = delay
+	injector := fault.NewInjector("coordinator", faultConfig, fault.NewRecorder(client))
+	config.Transport = fault.NewTransport(nil, injector)
+
+	// Initialize pricing
+	quoteTTL := pricing.DefaultQuoteTTL
+	if ttl := os.Getenv("QUOTE_TTL"); ttl != "" {
+		if duration, err := time.ParseDuration(ttl); err == nil {
+			quoteTTL = duration
 		}
 	}
-
-	// Override service URLs if provided
-	if hotelURL := os.Getenv("HOTEL_SERVICE_URL"); hotelURL != "" {
-		config.Services["hotel"] = hotelURL
-	}
-	if carURL := os.Getenv("CAR_SERVICE_URL"); carURL != "" {
-		config.Services["car"] = carURL
-	}
-	if trainURL := os.Getenv("TRAIN_SERVICE_URL"); trainURL != "" {
-		config.Services["train"] = trainURL
-	}
+	pricingService := pricing.NewService(pricing.NewRepository(client), quoteTTL)
+	pricingHandler := pricing.NewHandler(pricingService)
 
 	// Initialize service
-	service := coordinator.NewService(repo, config)
+	service := coordinator.NewService(repo, pricingService, config)
 
 	// Initialize handler
 	handler := coordinator.NewHandler(service)
@@ -78,7 +68,7 @@ func main() {
 	// Add CORS middleware
 	r.Use(func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
 
 		if c.Request.Method == "OPTIONS" {
@@ -91,6 +81,8 @@ func main() {
 
 	// Register routes
 	handler.RegisterRoutes(r)
+	pricingHandler.RegisterRoutes(r)
+	fault.NewHandler(injector).RegisterRoutes(r)
 
 	// Start cleanup goroutine
 	go func() {
@@ -107,6 +99,21 @@ func main() {
 		}
 	}()
 
+	// Start waitlist goroutine that books released inventory for waiting users
+	go func() {
+		ticker := time.NewTicker(config.WaitlistInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := service.ProcessInventoryReleases(ctx); err != nil {
+					log.Printf("Failed to process inventory releases: %v", err)
+				}
+			}
+		}
+	}()
+
 	// Get port from environment or use default
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -14,6 +14,7 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/hotel"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/config"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/fault"
 )
 
 const (
@@ -41,13 +42,37 @@ func main() {
 	}
 	defer client.Close()
 
-	hotelRepo := hotel.NewRepository(client)
-	hotelService := hotel.NewService(hotelRepo)
+	faultConfig, err := fault.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load fault config: %v", err)
+	}
+	injector := fault.NewInjector("hotel-service", faultConfig, fault.NewRecorder(client))
+
+	hotelRepo := hotel.NewRepository(client).WithFaults(injector)
+	hotelService := hotel.NewService(hotelRepo, cfg.HoldTTL)
 	hotelHandler := hotel.NewHandler(hotelService)
 
+	// Start sweeper releasing expired holds
+	go func() {
+		ticker := time.NewTicker(cfg.HoldSweepInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := hotelService.ReleaseExpiredHolds(ctx); err != nil {
+					log.Printf("Failed to release expired holds: %v", err)
+				}
+			}
+		}
+	}()
+
 	// Start HTTP server
 	router := gin.Default()
 	hotelHandler.RegisterRoutes(router)
+	fault.NewHandler(injector).RegisterRoutes(router)
 
 	// Create HTTP server with proper shutdown handling
 	srv := &http.Server{
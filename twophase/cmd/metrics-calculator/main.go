@@ -5,13 +5,16 @@ import (
 	"encoding/csv"
 	"log"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"cloud.google.com/go/firestore"
 	"github.com/joho/godotenv"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/coordinator"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/config"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/fault"
 	"google.golang.org/api/iterator"
 )
 
@@ -63,15 +66,38 @@ func main() {
 		transactionLogs = append(transactionLogs, tl)
 	}
 
+	// Faults injected during chaos testing, grouped by transaction
+	faults := make(map[string]map[fault.Kind]int)
+
+	faultIter := client.Collection(fault.EventCollection).Documents(ctx)
+	for {
+		doc, err := faultIter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			log.Fatalf("Failed to iterate fault events: %v", err)
+		}
+
+		var e fault.Event
+		if err := doc.DataTo(&e); err != nil {
+			log.Fatalf("Failed to convert document to fault event: %v", err)
+		}
+		if faults[e.TransactionID] == nil {
+			faults[e.TransactionID] = make(map[fault.Kind]int)
+		}
+		faults[e.TransactionID][e.Kind]++
+	}
+
 	// Export to CSV dengan nama file yang dikustomisasi
-	if err := exportTransactionLogsToCSV(transactionLogs, outputFilename); err != nil {
+	if err := exportTransactionLogsToCSV(transactionLogs, faults, outputFilename); err != nil {
 		log.Fatalf("Failed to export transaction logs to CSV: %v", err)
 	}
 
 	log.Printf("Successfully exported %d transaction logs to %s", len(transactionLogs), outputFilename)
 }
 
-func exportTransactionLogsToCSV(transactionLogs []coordinator.TransactionLog, filename string) error {
+func exportTransactionLogsToCSV(transactionLogs []coordinator.TransactionLog, faults map[string]map[fault.Kind]int, filename string) error {
 	file, err := os.Create(filename)
 	if err != nil {
 		return err
@@ -85,8 +111,10 @@ func exportTransactionLogsToCSV(transactionLogs []coordinator.TransactionLog, fi
 	headers := []string{
 		"ID",
 		"OrderID",
+		"UserID",
 		"Status",
 		"Participants",
+		"ParticipantItems",
 		"ParticipantsDoneAt",
 		"DoneAt",
 		"CreatedAt",
@@ -97,6 +125,31 @@ func exportTransactionLogsToCSV(transactionLogs []coordinator.TransactionLog, fi
 		"LastRetryAt",
 		"FailureReason",
 		"CommitTimestamp",
+		"QuoteID",
+		"TotalPrice",
+		"Currency",
+		"Kind",
+		"BookingTransactionID",
+		"CancellationFee",
+		"RefundAmount",
+		"ItemIndex",
+		"PriceDifference",
+		"HotelRoomIDs",
+		"HotelRoomTypeIDs",
+		"CarIDs",
+		"TrainJourneyIDs",
+		"TrainSeatIDs",
+		"FlightIDs",
+		"FlightSeatIDs",
+		"HotelStartDates",
+		"HotelEndDates",
+		"CarStartDates",
+		"CarEndDates",
+		"TrainDepartureDates",
+		"TrainOriginStations",
+		"TrainDestinationStations",
+		"FlightDepartureDates",
+		"Faults",
 	}
 
 	if err := writer.Write(headers); err != nil {
@@ -107,6 +160,7 @@ func exportTransactionLogsToCSV(transactionLogs []coordinator.TransactionLog, fi
 	for _, tl := range transactionLogs {
 		// Convert participants to string representation
 		participantsStr := ""
+		participantItemsStr := ""
 		participantsDoneAtStr := ""
 		for i, participant := range tl.Participants {
 			if i > 0 {
@@ -115,13 +169,23 @@ func exportTransactionLogsToCSV(transactionLogs []coordinator.TransactionLog, fi
 			}
 			participantsStr += participant.ServiceName + ":" + participant.Status
 			participantsDoneAtStr += participant.ServiceName + ":" + formatTimePtr(participant.DoneAt)
+
+			// Item status per participant, e.g. hotel[0]:committed
+			for j, item := range participant.Items {
+				if participantItemsStr != "" {
+					participantItemsStr += ";"
+				}
+				participantItemsStr += participant.ServiceName + "[" + strconv.Itoa(j) + "]:" + item.Status
+			}
 		}
 
 		row := []string{
 			tl.ID,
 			tl.OrderID,
+			tl.UserID,
 			string(tl.Status),
 			participantsStr,
+			participantItemsStr,
 			participantsDoneAtStr,
 			formatTimePtr(tl.DoneAt),
 			strconv.FormatInt(formatTime(tl.CreatedAt), 10),
@@ -132,6 +196,31 @@ func exportTransactionLogsToCSV(transactionLogs []coordinator.TransactionLog, fi
 			formatTimePtr(tl.LastRetryAt),
 			tl.FailureReason,
 			formatTimePtr(tl.CommitTimestamp),
+			tl.QuoteID,
+			strconv.FormatInt(tl.TotalPrice, 10),
+			tl.Currency,
+			string(tl.Kind),
+			tl.BookingTransactionID,
+			strconv.FormatInt(tl.CancellationFee, 10),
+			strconv.FormatInt(tl.RefundAmount, 10),
+			strconv.Itoa(tl.ItemIndex),
+			strconv.FormatInt(tl.PriceDifference, 10),
+			joinItems(tl.HotelRooms, func(i coordinator.HotelRoomItem) string { return i.HotelRoomID }),
+			joinItems(tl.HotelRooms, func(i coordinator.HotelRoomItem) string { return i.RoomTypeID }),
+			joinItems(tl.Cars, func(i coordinator.CarItem) string { return i.CarID }),
+			joinItems(tl.TrainSeats, func(i coordinator.TrainSeatItem) string { return i.JourneyID }),
+			joinItems(tl.TrainSeats, func(i coordinator.TrainSeatItem) string { return i.SeatID }),
+			joinItems(tl.Flights, func(i coordinator.FlightItem) string { return i.FlightID }),
+			joinItems(tl.Flights, func(i coordinator.FlightItem) string { return i.SeatID }),
+			joinItems(tl.HotelRooms, func(i coordinator.HotelRoomItem) string { return i.StartDate }),
+			joinItems(tl.HotelRooms, func(i coordinator.HotelRoomItem) string { return i.EndDate }),
+			joinItems(tl.Cars, func(i coordinator.CarItem) string { return i.StartDate }),
+			joinItems(tl.Cars, func(i coordinator.CarItem) string { return i.EndDate }),
+			joinItems(tl.TrainSeats, func(i coordinator.TrainSeatItem) string { return i.DepartureDate }),
+			joinItems(tl.TrainSeats, func(i coordinator.TrainSeatItem) string { return i.OriginStation }),
+			joinItems(tl.TrainSeats, func(i coordinator.TrainSeatItem) string { return i.DestinationStation }),
+			joinItems(tl.Flights, func(i coordinator.FlightItem) string { return i.DepartureDate }),
+			formatFaults(faults[tl.ID]),
 		}
 
 		if err := writer.Write(row); err != nil {
@@ -142,6 +231,34 @@ func exportTransactionLogsToCSV(transactionLogs []coordinator.TransactionLog, fi
 	return nil
 }
 
+// joinItems joins one field of every booked item with ";", in the order of the request.
+// The columns match the item columns of the eventual metrics-calculator.
+func joinItems[T any](items []T, field func(T) string) string {
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		values = append(values, field(item))
+	}
+	return strings.Join(values, ";")
+}
+
+// formatFaults writes the number of faults per kind, e.g. "http_500:1;http_timeout:2"
+func formatFaults(counts map[fault.Kind]int) string {
+	kinds := make([]string, 0, len(counts))
+	for kind := range counts {
+		kinds = append(kinds, string(kind))
+	}
+	sort.Strings(kinds)
+
+	faultsStr := ""
+	for i, kind := range kinds {
+		if i > 0 {
+			faultsStr += ";"
+		}
+		faultsStr += kind + ":" + strconv.Itoa(counts[fault.Kind(kind)])
+	}
+	return faultsStr
+}
+
 func formatTime(t time.Time) int64 {
 	return t.UnixMilli()
 }
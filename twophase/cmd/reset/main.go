@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"slices"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"github.com/joho/godotenv"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/car"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/flight"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/hotel"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/payment"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/pricing"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/train"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/api"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/config"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/fault"
+)
+
+// groups are the kinds of data that can be reset, in reset order
+var groups = []string{"transactions", "reservations", "payments", "quotes", "waitlist", "outbox", "faults", "availability"}
+
+// steps are the Firestore collections of every group
+var steps = []step{
+	// Orders only exist as coordinator transaction logs
+	{group: "transactions", collection: "twophase_transactions"},
+	{group: "transactions", collection: hotel.HotelRoomTransactionCollection},
+	{group: "transactions", collection: car.CarTransactionCollection},
+	{group: "transactions", collection: train.TrainTransactionCollection},
+	{group: "transactions", collection: flight.FlightTransactionCollection},
+	{group: "transactions", collection: payment.PaymentTransactionCollection},
+	{group: "reservations", collection: hotel.HotelRoomReservationCollection},
+	{group: "reservations", collection: car.CarReservationCollection},
+	{group: "reservations", collection: train.TrainSeatReservationCollection},
+	{group: "reservations", collection: flight.FlightReservationCollection},
+	{group: "payments", collection: payment.PaymentCollection},
+	{group: "quotes", collection: pricing.QuoteCollection},
+	{group: "waitlist", collection: "twophase_waitlist_entries"},
+	{group: "outbox", collection: api.InventoryReleaseCollection},
+	{group: "faults", collection: fault.EventCollection},
+	{group: "availability", collection: hotel.HotelRoomAvailabilityCollection, filter: unavailable, update: markAvailable},
+	{group: "availability", collection: car.CarAvailabilityCollection, filter: unavailable, update: markAvailable},
+	{group: "availability", collection: train.TrainSeatTicketCollection, filter: unavailable, update: markAvailable},
+	{group: "availability", collection: flight.FlightSeatCollection, filter: unavailable, update: markAvailable},
+	{
+		group:      "availability",
+		collection: hotel.HotelRoomTypeAvailabilityCollection,
+		update: func(doc *firestore.DocumentSnapshot) []firestore.Update {
+			return []firestore.Update{{Path: "available_units", Value: doc.Data()["total_units"]}}
+		},
+	},
+}
+
+func unavailable(q firestore.Query) firestore.Query {
+	return q.Where("available", "==", false)
+}
+
+func markAvailable(doc *firestore.DocumentSnapshot) []firestore.Update {
+	return []firestore.Update{{Path: "available", Value: true}}
+}
+
+// The reset removes the data written by a test run so the next run starts from the
+// seeded data. Seeded data (availability documents, journeys, flights and rates) is kept
+// and marked available again. It only runs against projects listed in
+// RESET_ALLOWED_PROJECT_IDS.
+func main() {
+	dryRun := flag.Bool("dry-run", false, "only count the data that would be reset")
+	only := flag.String("only", "", "comma separated groups to reset: "+strings.Join(groups, ",")+" (default all)")
+	flag.Parse()
+
+	selected := groups
+	if *only != "" {
+		selected = strings.Split(*only, ",")
+		for _, group := range selected {
+			if !slices.Contains(groups, group) {
+				log.Fatalf("Unknown group %q, expected one of %s", group, strings.Join(groups, ", "))
+			}
+		}
+	}
+
+	log.Println("Starting reset...")
+
+	if err := godotenv.Load(); err != nil {
+		log.Printf("Error loading .env file: %v, using system environment variables", err)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	if !slices.Contains(cfg.ResetAllowedProjectIDs, cfg.GoogleProjectID) {
+		log.Fatalf("Refusing to reset project %q: add it to RESET_ALLOWED_PROJECT_IDS if it is a test environment", cfg.GoogleProjectID)
+	}
+
+	ctx := context.Background()
+	client, err := firestore.NewClient(ctx, cfg.GoogleProjectID)
+	if err != nil {
+		log.Fatalf("Failed to create Firestore client: %v", err)
+	}
+	defer client.Close()
+
+	failed := false
+	for _, group := range groups {
+		if !slices.Contains(selected, group) {
+			continue
+		}
+
+		for _, s := range steps {
+			if s.group != group {
+				continue
+			}
+
+			if *dryRun {
+				count, err := s.count(ctx, client)
+				if err != nil {
+					log.Printf("Failed to count %s: %v", s.collection, err)
+					failed = true
+					continue
+				}
+				log.Printf("[dry-run] %s: would %s %d documents", s.collection, s.action(), count)
+				continue
+			}
+
+			if _, err := s.run(ctx, client); err != nil {
+				log.Printf("Failed to reset %s: %v", s.collection, err)
+				failed = true
+			}
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+	log.Println("Reset finished")
+}
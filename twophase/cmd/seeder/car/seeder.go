@@ -6,55 +6,48 @@ import (
 	"log"
 	"time"
 
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/cmd/seeder/spec"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/car"
-	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/config"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/pricing"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/utils"
 )
 
-var carBrands = []struct {
-	brand  string
-	models []string
-}{
-	{"Toyota", []string{"Avanza", "Innova", "Fortuner", "Camry", "Corolla"}},
-	{"Honda", []string{"Brio", "Jazz", "HR-V", "CR-V", "Civic"}},
-	{"Suzuki", []string{"Ertiga", "XL7", "Ignis", "Baleno", "Swift"}},
-	{"Daihatsu", []string{"Ayla", "Calya", "Xenia", "Terios", "Rocky"}},
-	{"Mitsubishi", []string{"Xpander", "Pajero", "L300", "Colt", "Mirage"}},
-	{"Nissan", []string{"Livina", "Grand Livina", "X-Trail", "Serena", "March"}},
-	{"Hyundai", []string{"Brio", "Creta", "Santa Fe", "Stargazer", "Palisade"}},
-	{"Kia", []string{"Picanto", "Rio", "Seltos", "Sportage", "Carnival"}},
-	{"Wuling", []string{"Almaz", "Cortez", "Confero", "Air ev", "Alvez"}},
-	{"MG", []string{"ZS", "HS", "RX5", "5", "3"}},
-}
-
-func Seed(ctx context.Context, repo *car.Repository) error {
+func Seed(ctx context.Context, repo *car.Repository, pricingRepo *pricing.Repository, data *spec.Data, mode utils.WriteMode) error {
 	log.Println("Starting car seeder...")
 
 	var carAvailabilities []car.CarAvailability
-
-	for _, brandData := range carBrands {
-		for _, model := range brandData.models {
-			log.Printf("Seeding %s %s...", brandData.brand, model)
-
-			for unitNumber := 1; unitNumber <= 100; unitNumber++ {
-				carName := fmt.Sprintf("%s %s - %03d", brandData.brand, model, unitNumber)
-				carID := utils.Slugify(carName)
-
-				date := time.Now().AddDate(0, 0, -1).Format(config.DateFormat)
-				carAvailabilities = append(carAvailabilities, car.CarAvailability{
-					CarID:     carID,
-					CarName:   carName,
-					Date:      date,
-					Available: true,
-				})
-			}
+	var carRates []pricing.CarRate
+	seasons := pricing.HolidaySeasons(time.Now().Year())
+
+	for _, c := range data.Cars {
+		for _, date := range data.CarDates {
+			carAvailabilities = append(carAvailabilities, car.CarAvailability{
+				CarID:     c.ID,
+				CarName:   c.Name,
+				Brand:     c.Brand,
+				Model:     c.Model,
+				Date:      date,
+				Available: true,
+			})
 		}
+
+		carRates = append(carRates, pricing.CarRate{
+			CarID:       c.ID,
+			DailyRate:   c.DailyRate,
+			WeekendRate: c.WeekendRate,
+			Seasons:     seasons,
+		})
 	}
 
-	if err := repo.BulkWriteCarAvailability(ctx, carAvailabilities); err != nil {
+	result, err := repo.BulkWriteCarAvailability(ctx, carAvailabilities, mode)
+	if err != nil {
 		return fmt.Errorf("failed to bulk write car availability: %w", err)
 	}
 
-	log.Printf("Car seeder completed. Total cars: %d", len(carAvailabilities))
+	if _, err := pricingRepo.BulkWriteCarRates(ctx, carRates, mode); err != nil {
+		return fmt.Errorf("failed to bulk write car rates: %w", err)
+	}
+
+	log.Printf("Car seeder completed. Total cars: %d, availabilities written: %d, skipped: %d", len(data.Cars), result.Written, result.Skipped)
 	return nil
 }
@@ -0,0 +1,69 @@
+package flight
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/cmd/seeder/spec"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/flight"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/pricing"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/utils"
+)
+
+func Seed(ctx context.Context, repo *flight.Repository, pricingRepo *pricing.Repository, data *spec.Data, mode utils.WriteMode) error {
+	log.Println("Starting flight seeder...")
+
+	var flights []flight.Flight
+	var flightFares []pricing.FlightFare
+	var flightSeats []flight.FlightSeat
+
+	for _, scheduledFlight := range data.Flights {
+		log.Printf("Seeding %s on %s...", scheduledFlight.FlightNumber, scheduledFlight.DepartureDate)
+
+		flights = append(flights, flight.Flight{
+			ID:            scheduledFlight.ID,
+			FlightNumber:  scheduledFlight.FlightNumber,
+			Airline:       scheduledFlight.Airline,
+			Origin:        scheduledFlight.Origin,
+			Destination:   scheduledFlight.Destination,
+			DepartureDate: scheduledFlight.DepartureDate,
+			DepartureTime: scheduledFlight.DepartureTime,
+			Rows:          scheduledFlight.Rows,
+			SeatsPerRow:   scheduledFlight.SeatsPerRow,
+		})
+
+		flightFares = append(flightFares, pricing.FlightFare{
+			FlightID: scheduledFlight.ID,
+			Classes:  scheduledFlight.Classes,
+		})
+
+		for row := 1; row <= scheduledFlight.Rows; row++ {
+			for seatNumber := 1; seatNumber <= scheduledFlight.SeatsPerRow; seatNumber++ {
+				flightSeats = append(flightSeats, flight.FlightSeat{
+					FlightID:      scheduledFlight.ID,
+					DepartureDate: scheduledFlight.DepartureDate,
+					SeatID:        flight.SeatID(row, seatNumber),
+					FlightNumber:  scheduledFlight.FlightNumber,
+					Available:     true,
+				})
+			}
+		}
+	}
+
+	if _, err := repo.BulkWriteFlight(ctx, flights, mode); err != nil {
+		return fmt.Errorf("failed to bulk write flights: %w", err)
+	}
+
+	result, err := repo.BulkWriteFlightSeat(ctx, flightSeats, mode)
+	if err != nil {
+		return fmt.Errorf("failed to bulk write flight seats: %w", err)
+	}
+
+	if _, err := pricingRepo.BulkWriteFlightFares(ctx, flightFares, mode); err != nil {
+		return fmt.Errorf("failed to bulk write flight fares: %w", err)
+	}
+
+	log.Printf("Flight seeder completed. Total flights: %d, total seats: %d, written: %d, skipped: %d", len(flights), len(flightSeats), result.Written, result.Skipped)
+	return nil
+}
@@ -6,99 +6,79 @@ import (
 	"log"
 	"time"
 
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/cmd/seeder/spec"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/hotel"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/pricing"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/utils"
 )
 
-var hotelBrands = []string{
-	"Marriott Jakarta",
-	"Ritz-Carlton Jakarta",
-	"Mandarin Oriental Jakarta",
-	"Four Seasons Jakarta",
-	"Grand Hyatt Jakarta",
-	"InterContinental Jakarta",
-	"Sheraton Jakarta",
-	"Pullman Jakarta",
-	"Novotel Jakarta",
-	"Ibis Jakarta",
-	"Marriott Bandung",
-	"Ritz-Carlton Bandung",
-	"Marriott Surabaya",
-	"Ritz-Carlton Surabaya",
-	"Marriott Medan",
-	"Hotel Indonesia Kempinski Jakarta",
-	"Shangri-La Hotel Jakarta",
-	"W Jakarta",
-	"Conrad Jakarta",
-	"Westin Jakarta",
-	"Le Meridien Jakarta",
-	"Aloft Jakarta",
-	"Element Jakarta",
-	"Courtyard Jakarta",
-	"Residence Inn Jakarta",
-	"Fairfield Jakarta",
-	"Springhill Suites Jakarta",
-	"TownePlace Suites Jakarta",
-	"Protea Hotel Jakarta",
-	"AC Hotel Jakarta",
-	"Moxy Jakarta",
-	"Gaylord Hotels Jakarta",
-	"Delta Hotels Jakarta",
-	"St. Regis Jakarta",
-	"Luxury Collection Jakarta",
-	"Tribute Portfolio Jakarta",
-	"Design Hotels Jakarta",
-	"Autograph Collection Jakarta",
-	"Marriott Executive Apartments Jakarta",
-	"Marriott Vacation Club Jakarta",
-	"Ritz-Carlton Reserve Jakarta",
-	"Edition Hotels Jakarta",
-	"Bulgari Hotels Jakarta",
-	"Park Hyatt Jakarta",
-	"Andaz Jakarta",
-	"Hyatt Regency Jakarta",
-	"Hyatt Place Jakarta",
-	"Hyatt House Jakarta",
-	"Grand Hyatt Bandung",
-	"Hyatt Regency Bandung",
-	"Grand Hyatt Surabaya",
-	"Hyatt Regency Surabaya",
-	"Grand Hyatt Medan",
-	"Hyatt Regency Medan",
-}
-
-func Seed(ctx context.Context, repo *hotel.Repository) error {
+func Seed(ctx context.Context, repo *hotel.Repository, pricingRepo *pricing.Repository, data *spec.Data, mode utils.WriteMode) error {
 	log.Println("Starting hotel room availability seeder...")
 
 	hotelRoomAvailabilities := make([]hotel.HotelRoomAvailability, 0)
+	var roomTypeAvailabilities []hotel.RoomTypeAvailability
+	var roomRates []pricing.RoomRate
+	seasons := pricing.HolidaySeasons(time.Now().Year())
 
-	for _, hotelName := range hotelBrands {
-		log.Printf("Seeding %s...", hotelName)
+	for _, h := range data.Hotels {
+		log.Printf("Seeding %s...", h.Name)
+
+		for _, roomType := range h.RoomTypes {
+			for _, date := range data.HotelDates {
+				roomTypeAvailabilities = append(roomTypeAvailabilities, hotel.RoomTypeAvailability{
+					RoomTypeID:     roomType.ID,
+					HotelName:      h.Name,
+					RoomTypeName:   roomType.Name,
+					City:           h.City,
+					Date:           date,
+					TotalUnits:     len(roomType.Rooms),
+					AvailableUnits: len(roomType.Rooms),
+				})
+			}
 
-		// 5 floors, 20 units per floor
-		for floor := 1; floor <= 5; floor++ {
-			for unitNumber := 1; unitNumber <= 20; unitNumber++ {
-				roomName := fmt.Sprintf("%d%02d", floor, unitNumber)
-				roomID := utils.Slugify(fmt.Sprintf("%s-%s", hotelName, roomName))
+			roomRates = append(roomRates, pricing.RoomRate{
+				RoomTypeID:  roomType.ID,
+				NightlyRate: roomType.NightlyRate,
+				WeekendRate: roomType.WeekendRate,
+				Seasons:     seasons,
+			})
 
-				date := time.Now().AddDate(0, 0, -1)
-				hotelRoom := hotel.HotelRoomAvailability{
-					RoomID:    roomID,
-					HotelName: hotelName,
-					RoomName:  roomName,
-					Date:      date.Format(time.DateOnly),
-					Available: true,
+			for _, room := range roomType.Rooms {
+				for _, date := range data.HotelDates {
+					hotelRoomAvailabilities = append(hotelRoomAvailabilities, hotel.HotelRoomAvailability{
+						RoomID:     room.ID,
+						HotelName:  h.Name,
+						RoomName:   room.Name,
+						City:       h.City,
+						Date:       date,
+						Available:  true,
+						RoomTypeID: roomType.ID,
+					})
 				}
 
-				hotelRoomAvailabilities = append(hotelRoomAvailabilities, hotelRoom)
+				roomRates = append(roomRates, pricing.RoomRate{
+					HotelRoomID: room.ID,
+					NightlyRate: room.NightlyRate,
+					WeekendRate: room.WeekendRate,
+					Seasons:     seasons,
+				})
 			}
 		}
 	}
 
-	if err := repo.BulkWriteHotelRoomAvailability(ctx, hotelRoomAvailabilities); err != nil {
+	result, err := repo.BulkWriteHotelRoomAvailability(ctx, hotelRoomAvailabilities, mode)
+	if err != nil {
 		return fmt.Errorf("failed to bulk write hotel room availability: %w", err)
 	}
 
-	log.Printf("Hotel room availability seeder completed. Total rooms: %d", len(hotelRoomAvailabilities))
+	if _, err := repo.BulkWriteRoomTypeAvailability(ctx, roomTypeAvailabilities, mode); err != nil {
+		return fmt.Errorf("failed to bulk write room type availability: %w", err)
+	}
+
+	if _, err := pricingRepo.BulkWriteRoomRates(ctx, roomRates, mode); err != nil {
+		return fmt.Errorf("failed to bulk write room rates: %w", err)
+	}
+
+	log.Printf("Hotel room availability seeder completed. Total room availabilities: %d, room type availabilities: %d, written: %d, skipped: %d", len(hotelRoomAvailabilities), len(roomTypeAvailabilities), result.Written, result.Skipped)
 	return nil
 }
@@ -2,23 +2,61 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
 	"os"
+	"time"
 
 	"cloud.google.com/go/firestore"
 	"github.com/joho/godotenv"
 	carSeeder "github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/cmd/seeder/car"
+	flightSeeder "github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/cmd/seeder/flight"
 	hotelSeeder "github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/cmd/seeder/hotel"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/cmd/seeder/spec"
 	trainSeeder "github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/cmd/seeder/train"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/car"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/flight"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/hotel"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/pricing"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/train"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/config"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/utils"
 )
 
 func main() {
+	specPath := flag.String("spec", "", "YAML or JSON spec file (default: the built-in cmd/seeder/spec/default.yaml)")
+	seed := flag.Int64("seed", 0, "random generation seed, overrides the seed of the spec")
+	upsert := flag.Bool("upsert", false, "update existing documents to match the spec instead of only creating missing ones")
+	dryRun := flag.Bool("dry-run", false, "only log a summary of the data without writing to the database")
+	flag.Parse()
+
 	log.Println("Starting database seeder...")
 
+	s, err := spec.Load(*specPath)
+	if err != nil {
+		log.Fatalf("Failed to load spec: %v", err)
+	}
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			s.Seed = *seed
+		}
+	})
+
+	data, err := s.Build(time.Now())
+	if err != nil {
+		log.Fatalf("Failed to build seed data: %v", err)
+	}
+	log.Printf("Seed data: %s", data.Summary())
+	if *dryRun {
+		return
+	}
+
+	// Existing documents are skipped unless upserting, so seeding can be run again
+	mode := utils.WriteModeCreate
+	if *upsert {
+		mode = utils.WriteModeUpsert
+	}
+
 	if err := godotenv.Load(); err != nil {
 		log.Printf("Error loading .env file: %v, using system environment variables", err)
 	}
@@ -35,10 +73,13 @@ func main() {
 	}
 	defer client.Close()
 
+	// Rates are seeded alongside the items they price
+	pricingRepo := pricing.NewRepository(client)
+
 	// Run car seeder
 	log.Println("Seeding car data...")
 	carRepo := car.NewRepository(client)
-	if err := carSeeder.Seed(ctx, carRepo); err != nil {
+	if err := carSeeder.Seed(ctx, carRepo, pricingRepo, data, mode); err != nil {
 		log.Printf("Error seeding car data: %v", err)
 		os.Exit(1)
 	}
@@ -46,7 +87,7 @@ func main() {
 	// Run hotel seeder
 	log.Println("Seeding hotel room data...")
 	hotelRepo := hotel.NewRepository(client)
-	if err := hotelSeeder.Seed(ctx, hotelRepo); err != nil {
+	if err := hotelSeeder.Seed(ctx, hotelRepo, pricingRepo, data, mode); err != nil {
 		log.Printf("Error seeding hotel room data: %v", err)
 		os.Exit(1)
 	}
@@ -54,10 +95,18 @@ func main() {
 	// Run train seeder
 	log.Println("Seeding train data...")
 	trainRepo := train.NewRepository(client)
-	if err := trainSeeder.Seed(ctx, trainRepo); err != nil {
+	if err := trainSeeder.Seed(ctx, trainRepo, pricingRepo, data, mode); err != nil {
 		log.Printf("Error seeding train data: %v", err)
 		os.Exit(1)
 	}
 
-	log.Println("Database seeding completed successfully!")
+	// Run flight seeder
+	log.Println("Seeding flight data...")
+	flightRepo := flight.NewRepository(client)
+	if err := flightSeeder.Seed(ctx, flightRepo, pricingRepo, data, mode); err != nil {
+		log.Printf("Error seeding flight data: %v", err)
+		os.Exit(1)
+	}
+
+	log.Printf("Database seeding completed successfully! %s", data.Summary())
 }
@@ -0,0 +1,371 @@
+package spec
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/pricing"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/utils"
+)
+
+// Data is the seed data built from a spec
+type Data struct {
+	Cars []Car
+	// CarDates and HotelDates are the availability dates of cars and rooms
+	CarDates   []string
+	Hotels     []Hotel
+	HotelDates []string
+	Journeys   []Journey
+	Flights    []Flight
+}
+
+// Car is a single car
+type Car struct {
+	ID          string
+	Name        string
+	Brand       string
+	Model       string
+	DailyRate   int64
+	WeekendRate int64
+}
+
+// Hotel is a hotel with its room types and rooms
+type Hotel struct {
+	Name      string
+	City      string
+	RoomTypes []HotelRoomType
+}
+
+// HotelRoomType is a room type of a hotel, sold at the rate of its lowest floor
+type HotelRoomType struct {
+	ID          string
+	Name        string
+	NightlyRate int64
+	WeekendRate int64
+	Rooms       []Room
+}
+
+// Room is a single hotel room
+type Room struct {
+	ID          string
+	Name        string
+	NightlyRate int64
+	WeekendRate int64
+}
+
+// Journey is a train journey on one departure date
+type Journey struct {
+	ID            string
+	TrainName     string
+	DepartureDate string
+	Stations      []string
+	Coaches       int
+	SeatsPerCoach int
+	Classes       []pricing.ClassFare
+}
+
+// Flight is a flight on one departure date
+type Flight struct {
+	ID            string
+	FlightNumber  string
+	Airline       string
+	Origin        string
+	Destination   string
+	DepartureDate string
+	DepartureTime string
+	Rows          int
+	SeatsPerRow   int
+	Classes       []pricing.FlightClassFare
+}
+
+// Rooms returns the number of hotel rooms and room types
+func (d *Data) Rooms() (rooms, roomTypes int) {
+	for _, hotel := range d.Hotels {
+		for _, roomType := range hotel.RoomTypes {
+			rooms += len(roomType.Rooms)
+			roomTypes++
+		}
+	}
+	return rooms, roomTypes
+}
+
+// Summary describes the amount of seed data for logging
+func (d *Data) Summary() string {
+	rooms, roomTypes := d.Rooms()
+	trainSeats, flightSeats := 0, 0
+	for _, journey := range d.Journeys {
+		trainSeats += journey.Coaches * journey.SeatsPerCoach
+	}
+	for _, flight := range d.Flights {
+		flightSeats += flight.Rows * flight.SeatsPerRow
+	}
+
+	return fmt.Sprintf("%d cars, %d hotels with %d rooms and %d room types, %d train journeys with %d seats, %d flights with %d seats",
+		len(d.Cars), len(d.Hotels), rooms, roomTypes, len(d.Journeys), trainSeats, len(d.Flights), flightSeats)
+}
+
+// builder builds data from a spec. All random generation draws from one rng in a fixed
+// order, so the result only depends on the spec and the seed. The eventual seeder uses
+// the same math/rand source and draws in the same order.
+type builder struct {
+	spec  *Spec
+	today time.Time
+	rng   *rand.Rand
+	ids   map[string]bool
+}
+
+// Build builds the seed data with date windows relative to today
+func (s *Spec) Build(today time.Time) (*Data, error) {
+	b := &builder{
+		spec:  s,
+		today: today,
+		rng:   rand.New(rand.NewSource(s.Seed)),
+		ids:   make(map[string]bool),
+	}
+
+	properties, err := b.properties()
+	if err != nil {
+		return nil, err
+	}
+
+	data := &Data{
+		CarDates:   b.window(s.Cars.Availability),
+		HotelDates: b.window(s.Hotels.Availability),
+	}
+	for _, property := range properties {
+		hotel, err := b.hotel(property)
+		if err != nil {
+			return nil, err
+		}
+		data.Hotels = append(data.Hotels, hotel)
+	}
+	if data.Cars, err = b.cars(); err != nil {
+		return nil, err
+	}
+	if data.Journeys, err = b.journeys(); err != nil {
+		return nil, err
+	}
+	if data.Flights, err = b.flights(); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (b *builder) window(w *Window) []string {
+	if w == nil {
+		return b.spec.Availability.dates(b.today)
+	}
+	return w.dates(b.today)
+}
+
+// id returns the slug of name and rejects IDs already used by the same kind of data
+func (b *builder) id(kind, name string) (string, error) {
+	id := utils.Slugify(name)
+	key := kind + "/" + id
+	if b.ids[key] {
+		return "", fmt.Errorf("duplicate %s %q", kind, id)
+	}
+	b.ids[key] = true
+	return id, nil
+}
+
+// jitter returns a random rate multiplier within ±RateJitterPercent percent
+func (b *builder) jitter() float64 {
+	if b.spec.RateJitterPercent == 0 {
+		return 1
+	}
+	return 1 + (b.rng.Float64()*2-1)*float64(b.spec.RateJitterPercent)/100
+}
+
+// rate multiplies base by a jitter factor, rounded to thousands of rupiah
+func rate(base int64, factor float64) int64 {
+	if factor == 1 {
+		return base
+	}
+	return int64(math.Round(float64(base)*factor/1000)) * 1000
+}
+
+// properties returns the hotels of the spec followed by the generated hotels
+func (b *builder) properties() ([]Property, error) {
+	hotels := b.spec.Hotels
+	properties := append([]Property(nil), hotels.Properties...)
+	if hotels.Generate.Count == 0 {
+		return properties, nil
+	}
+
+	listed := make(map[string]bool, len(properties))
+	for _, property := range properties {
+		listed[property.Name] = true
+	}
+
+	generated := 0
+	combinations := len(hotels.Generate.Brands) * len(hotels.Generate.Cities)
+	for _, i := range b.rng.Perm(combinations) {
+		if generated == hotels.Generate.Count {
+			break
+		}
+		brand := hotels.Generate.Brands[i/len(hotels.Generate.Cities)]
+		city := hotels.Generate.Cities[i%len(hotels.Generate.Cities)]
+		name := brand + " " + city
+		if listed[name] {
+			continue
+		}
+		properties = append(properties, Property{Name: name, City: city})
+		generated++
+	}
+
+	if generated < hotels.Generate.Count {
+		return nil, fmt.Errorf("hotels.generate: only %d of %d hotels can be generated from the brands and cities", generated, hotels.Generate.Count)
+	}
+	return properties, nil
+}
+
+func (b *builder) hotel(property Property) (Hotel, error) {
+	hotels := b.spec.Hotels
+	roomsPerFloor := hotels.roomsPerFloor(property)
+	factor := b.jitter()
+
+	hotel := Hotel{Name: property.Name, City: property.City}
+	for _, roomType := range hotels.RoomTypes {
+		roomTypeID, err := b.id("room type", fmt.Sprintf("%s-%s", property.Name, roomType.Name))
+		if err != nil {
+			return Hotel{}, err
+		}
+
+		typeRate := rate(hotels.NightlyRate+int64(roomType.FirstFloor-1)*hotels.FloorSurcharge, factor)
+		hotelRoomType := HotelRoomType{
+			ID:          roomTypeID,
+			Name:        roomType.Name,
+			NightlyRate: typeRate,
+			WeekendRate: typeRate * hotels.WeekendRatePercent / 100,
+		}
+
+		for floor := roomType.FirstFloor; floor <= roomType.LastFloor; floor++ {
+			nightlyRate := rate(hotels.NightlyRate+int64(floor-1)*hotels.FloorSurcharge, factor)
+			for unitNumber := 1; unitNumber <= roomsPerFloor; unitNumber++ {
+				roomName := fmt.Sprintf("%d%02d", floor, unitNumber)
+				roomID, err := b.id("room", fmt.Sprintf("%s-%s", property.Name, roomName))
+				if err != nil {
+					return Hotel{}, err
+				}
+
+				hotelRoomType.Rooms = append(hotelRoomType.Rooms, Room{
+					ID:          roomID,
+					Name:        roomName,
+					NightlyRate: nightlyRate,
+					WeekendRate: nightlyRate * hotels.WeekendRatePercent / 100,
+				})
+			}
+		}
+		hotel.RoomTypes = append(hotel.RoomTypes, hotelRoomType)
+	}
+	return hotel, nil
+}
+
+func (b *builder) cars() ([]Car, error) {
+	cars := b.spec.Cars
+
+	var result []Car
+	for _, fleet := range cars.Fleets {
+		for modelIndex, model := range fleet.Models {
+			dailyRate := rate(cars.DailyRate+int64(modelIndex)*cars.ModelSurcharge, b.jitter())
+
+			for unitNumber := 1; unitNumber <= cars.units(fleet); unitNumber++ {
+				carName := fmt.Sprintf("%s %s - %03d", fleet.Brand, model, unitNumber)
+				carID, err := b.id("car", carName)
+				if err != nil {
+					return nil, err
+				}
+
+				result = append(result, Car{
+					ID:          carID,
+					Name:        carName,
+					Brand:       fleet.Brand,
+					Model:       model,
+					DailyRate:   dailyRate,
+					WeekendRate: dailyRate * cars.WeekendRatePercent / 100,
+				})
+			}
+		}
+	}
+	return result, nil
+}
+
+func (b *builder) journeys() ([]Journey, error) {
+	trains := b.spec.Trains
+	dates := b.window(trains.Availability)
+
+	var result []Journey
+	for _, route := range trains.Routes {
+		factor := b.jitter()
+		classes := make([]pricing.ClassFare, 0, len(trains.Classes))
+		for _, class := range trains.Classes {
+			classes = append(classes, pricing.ClassFare{
+				Class:       class.Class,
+				FirstCoach:  class.FirstCoach,
+				LastCoach:   class.LastCoach,
+				SegmentFare: rate(class.SegmentFare, factor),
+			})
+		}
+
+		for _, departureDate := range dates {
+			journeyID, err := b.id("train journey", fmt.Sprintf("%s-%s", route.Name, departureDate))
+			if err != nil {
+				return nil, err
+			}
+
+			result = append(result, Journey{
+				ID:            journeyID,
+				TrainName:     route.Name,
+				DepartureDate: departureDate,
+				Stations:      route.Stations,
+				Coaches:       trains.Coaches,
+				SeatsPerCoach: trains.SeatsPerCoach,
+				Classes:       classes,
+			})
+		}
+	}
+	return result, nil
+}
+
+func (b *builder) flights() ([]Flight, error) {
+	flights := b.spec.Flights
+	dates := b.window(flights.Availability)
+
+	var result []Flight
+	for _, route := range flights.Routes {
+		factor := b.jitter()
+		classes := make([]pricing.FlightClassFare, 0, len(flights.Classes))
+		for _, class := range flights.Classes {
+			classes = append(classes, pricing.FlightClassFare{
+				Class:    class.Class,
+				FirstRow: class.FirstRow,
+				LastRow:  class.LastRow,
+				Fare:     rate(class.Fare, factor),
+			})
+		}
+
+		for _, departureDate := range dates {
+			flightID, err := b.id("flight", fmt.Sprintf("%s-%s", route.FlightNumber, departureDate))
+			if err != nil {
+				return nil, err
+			}
+
+			result = append(result, Flight{
+				ID:            flightID,
+				FlightNumber:  route.FlightNumber,
+				Airline:       route.Airline,
+				Origin:        route.Origin,
+				Destination:   route.Destination,
+				DepartureDate: departureDate,
+				DepartureTime: route.DepartureTime,
+				Rows:          flights.Rows,
+				SeatsPerRow:   flights.SeatsPerRow,
+				Classes:       classes,
+			})
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,346 @@
+// Package spec reads the seed data specification from a YAML or JSON file. The seeders of
+// both architectures build their data from the same spec, so a spec seeds the same
+// hotels, cars, trains and flights whichever architecture is tested.
+package spec
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/pricing"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultSpec is used when no spec file is given. It holds the data that was seeded before
+// the seeder became configurable.
+//
+//go:embed default.yaml
+var defaultSpec []byte
+
+// Spec describes all seeded data
+type Spec struct {
+	// Seed drives random generation. The same spec and seed always build the same data, in
+	// the eventual seeder as well.
+	Seed int64 `yaml:"seed"`
+	// RateJitterPercent randomizes the rates of every hotel, car model, train and flight by
+	// up to ±RateJitterPercent percent. 0 keeps the rates as they are.
+	RateJitterPercent int `yaml:"rate_jitter_percent"`
+	// Availability is the date window of inventory that does not set its own
+	Availability Window `yaml:"availability"`
+
+	Cars    Cars    `yaml:"cars"`
+	Hotels  Hotels  `yaml:"hotels"`
+	Trains  Trains  `yaml:"trains"`
+	Flights Flights `yaml:"flights"`
+}
+
+// Window is a range of inventory dates. Trains and flights depart on every date, cars and
+// rooms get an availability document per date.
+type Window struct {
+	// StartDate is the first date as YYYY-MM-DD. When empty, the first date is today plus
+	// StartOffsetDays.
+	StartDate       string `yaml:"start_date"`
+	StartOffsetDays int    `yaml:"start_offset_days"`
+	Days            int    `yaml:"days"`
+}
+
+// Cars is the car fleet. Every model has Units units named
+// "${brand} ${model} - ${unit number}".
+type Cars struct {
+	Availability  *Window `yaml:"availability"`
+	UnitsPerModel int     `yaml:"units_per_model"`
+	// DailyRate is the rate of the first model of every brand, each following model costs
+	// ModelSurcharge more
+	DailyRate      int64 `yaml:"daily_rate"`
+	ModelSurcharge int64 `yaml:"model_surcharge"`
+	// WeekendRatePercent is the Saturday and Sunday rate as a percentage of the daily rate
+	WeekendRatePercent int64   `yaml:"weekend_rate_percent"`
+	Fleets             []Fleet `yaml:"fleets"`
+}
+
+// Fleet is the models of one brand
+type Fleet struct {
+	Brand  string   `yaml:"brand"`
+	Models []string `yaml:"models"`
+	// Units overrides UnitsPerModel for this brand
+	Units int `yaml:"units"`
+}
+
+// Hotels is the hotels and their room layout. Every floor has RoomsPerFloor rooms named
+// "${floor}${2 digit room number}".
+type Hotels struct {
+	Availability  *Window `yaml:"availability"`
+	RoomsPerFloor int     `yaml:"rooms_per_floor"`
+	// NightlyRate is the rate of a first floor room, each floor above costs FloorSurcharge
+	// more
+	NightlyRate    int64 `yaml:"nightly_rate"`
+	FloorSurcharge int64 `yaml:"floor_surcharge"`
+	// WeekendRatePercent is the Friday and Saturday night rate as a percentage of the
+	// nightly rate
+	WeekendRatePercent int64 `yaml:"weekend_rate_percent"`
+	// RoomTypes splits the floors of every hotel into the room types it sells
+	RoomTypes  []RoomType      `yaml:"room_types"`
+	Properties []Property      `yaml:"properties"`
+	Generate   GeneratedHotels `yaml:"generate"`
+}
+
+// RoomType is a room type taking floors FirstFloor to LastFloor
+type RoomType struct {
+	Name       string `yaml:"name"`
+	FirstFloor int    `yaml:"first_floor"`
+	LastFloor  int    `yaml:"last_floor"`
+}
+
+// Property is a single hotel
+type Property struct {
+	Name string `yaml:"name"`
+	City string `yaml:"city"`
+	// RoomsPerFloor overrides the rooms per floor of this hotel
+	RoomsPerFloor int `yaml:"rooms_per_floor"`
+}
+
+// GeneratedHotels adds Count hotels named "${brand} ${city}" from brand and city pairs
+// picked at random from Seed
+type GeneratedHotels struct {
+	Count  int      `yaml:"count"`
+	Brands []string `yaml:"brands"`
+	Cities []string `yaml:"cities"`
+}
+
+// Trains is the train timetable. Every route departs once on every date of its window.
+type Trains struct {
+	Availability  *Window      `yaml:"availability"`
+	Coaches       int          `yaml:"coaches"`
+	SeatsPerCoach int          `yaml:"seats_per_coach"`
+	Classes       []CoachClass `yaml:"classes"`
+	Routes        []TrainRoute `yaml:"routes"`
+}
+
+// CoachClass is the seat class of coaches FirstCoach to LastCoach and its per-segment fare
+type CoachClass struct {
+	Class       pricing.SeatClass `yaml:"class"`
+	FirstCoach  int               `yaml:"first_coach"`
+	LastCoach   int               `yaml:"last_coach"`
+	SegmentFare int64             `yaml:"segment_fare"`
+}
+
+// TrainRoute is the ordered stations a train calls at
+type TrainRoute struct {
+	Name     string   `yaml:"name"`
+	Stations []string `yaml:"stations"`
+}
+
+// Flights is the flight timetable. Every route departs once on every date of its window.
+type Flights struct {
+	Availability *Window       `yaml:"availability"`
+	Rows         int           `yaml:"rows"`
+	SeatsPerRow  int           `yaml:"seats_per_row"`
+	Classes      []RowClass    `yaml:"classes"`
+	Routes       []FlightRoute `yaml:"routes"`
+}
+
+// RowClass is the fare class of rows FirstRow to LastRow and its seat fare
+type RowClass struct {
+	Class    pricing.FareClass `yaml:"class"`
+	FirstRow int               `yaml:"first_row"`
+	LastRow  int               `yaml:"last_row"`
+	Fare     int64             `yaml:"fare"`
+}
+
+// FlightRoute is a daily flight number
+type FlightRoute struct {
+	FlightNumber  string `yaml:"flight_number"`
+	Airline       string `yaml:"airline"`
+	Origin        string `yaml:"origin"`
+	Destination   string `yaml:"destination"`
+	DepartureTime string `yaml:"departure_time"`
+}
+
+// Load reads the spec at path. An empty path loads the default spec.
+func Load(path string) (*Spec, error) {
+	data := defaultSpec
+	if path != "" {
+		var err error
+		data, err = os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read spec: %w", err)
+		}
+	}
+
+	// JSON is valid YAML, so the same decoder reads both
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	var s Spec
+	if err := decoder.Decode(&s); err != nil {
+		return nil, fmt.Errorf("failed to parse spec: %w", err)
+	}
+	if err := s.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid spec: %w", err)
+	}
+	return &s, nil
+}
+
+// Validate checks that the spec can be built into seed data
+func (s *Spec) Validate() error {
+	if s.RateJitterPercent < 0 || s.RateJitterPercent >= 100 {
+		return fmt.Errorf("rate_jitter_percent must be between 0 and 99, got %d", s.RateJitterPercent)
+	}
+	if err := s.Availability.validate(); err != nil {
+		return fmt.Errorf("availability: %w", err)
+	}
+
+	return errors.Join(s.Cars.validate(), s.Hotels.validate(), s.Trains.validate(), s.Flights.validate())
+}
+
+func (w *Window) validate() error {
+	if w == nil {
+		return nil
+	}
+	if w.StartDate != "" {
+		if _, err := time.Parse(config.DateFormat, w.StartDate); err != nil {
+			return fmt.Errorf("invalid start_date %q", w.StartDate)
+		}
+	}
+	if w.Days < 1 {
+		return fmt.Errorf("days must be at least 1, got %d", w.Days)
+	}
+	return nil
+}
+
+// dates returns every date of the window
+func (w Window) dates(today time.Time) []string {
+	start := today.AddDate(0, 0, w.StartOffsetDays)
+	if w.StartDate != "" {
+		start, _ = time.Parse(config.DateFormat, w.StartDate)
+	}
+
+	dates := make([]string, 0, w.Days)
+	for day := 0; day < w.Days; day++ {
+		dates = append(dates, start.AddDate(0, 0, day).Format(config.DateFormat))
+	}
+	return dates
+}
+
+func (c Cars) validate() error {
+	if err := c.Availability.validate(); err != nil {
+		return fmt.Errorf("cars.availability: %w", err)
+	}
+	for _, fleet := range c.Fleets {
+		if fleet.Brand == "" || len(fleet.Models) == 0 {
+			return errors.New("cars.fleets: every fleet needs a brand and at least one model")
+		}
+		if units := c.units(fleet); units < 1 || units > 999 {
+			return fmt.Errorf("cars.fleets: %s must have between 1 and 999 units per model, got %d", fleet.Brand, units)
+		}
+	}
+	return nil
+}
+
+func (c Cars) units(fleet Fleet) int {
+	if fleet.Units != 0 {
+		return fleet.Units
+	}
+	return c.UnitsPerModel
+}
+
+func (h Hotels) validate() error {
+	if err := h.Availability.validate(); err != nil {
+		return fmt.Errorf("hotels.availability: %w", err)
+	}
+	if len(h.Properties)+h.Generate.Count > 0 && len(h.RoomTypes) == 0 {
+		return errors.New("hotels.room_types must not be empty")
+	}
+	for _, roomType := range h.RoomTypes {
+		if roomType.Name == "" || roomType.FirstFloor < 1 || roomType.LastFloor < roomType.FirstFloor {
+			return fmt.Errorf("hotels.room_types: %q needs a name and floors first_floor <= last_floor starting at 1", roomType.Name)
+		}
+	}
+	for _, property := range h.Properties {
+		if property.Name == "" || property.City == "" {
+			return fmt.Errorf("hotels.properties: %q needs a name and a city", property.Name)
+		}
+		if rooms := h.roomsPerFloor(property); rooms < 1 || rooms > 99 {
+			return fmt.Errorf("hotels.properties: %s must have between 1 and 99 rooms per floor, got %d", property.Name, rooms)
+		}
+	}
+	if h.Generate.Count < 0 {
+		return fmt.Errorf("hotels.generate.count must not be negative, got %d", h.Generate.Count)
+	}
+	if h.Generate.Count > 0 && (len(h.Generate.Brands) == 0 || len(h.Generate.Cities) == 0) {
+		return errors.New("hotels.generate needs brands and cities")
+	}
+	if h.Generate.Count > 0 && (h.RoomsPerFloor < 1 || h.RoomsPerFloor > 99) {
+		return fmt.Errorf("hotels.rooms_per_floor must be between 1 and 99, got %d", h.RoomsPerFloor)
+	}
+	return nil
+}
+
+func (h Hotels) roomsPerFloor(property Property) int {
+	if property.RoomsPerFloor != 0 {
+		return property.RoomsPerFloor
+	}
+	return h.RoomsPerFloor
+}
+
+func (t Trains) validate() error {
+	if err := t.Availability.validate(); err != nil {
+		return fmt.Errorf("trains.availability: %w", err)
+	}
+	if len(t.Routes) == 0 {
+		return nil
+	}
+	if t.Coaches < 1 || t.SeatsPerCoach < 1 {
+		return fmt.Errorf("trains need at least 1 coach and 1 seat per coach, got %d and %d", t.Coaches, t.SeatsPerCoach)
+	}
+	for _, class := range t.Classes {
+		switch class.Class {
+		case pricing.SeatClassExecutive, pricing.SeatClassBusiness, pricing.SeatClassEconomy:
+		default:
+			return fmt.Errorf("trains.classes: unknown class %q", class.Class)
+		}
+		if class.FirstCoach < 1 || class.LastCoach < class.FirstCoach || class.LastCoach > t.Coaches {
+			return fmt.Errorf("trains.classes: %s coaches %d-%d are outside 1-%d", class.Class, class.FirstCoach, class.LastCoach, t.Coaches)
+		}
+	}
+	for _, route := range t.Routes {
+		if route.Name == "" || len(route.Stations) < 2 {
+			return fmt.Errorf("trains.routes: %q needs a name and at least 2 stations", route.Name)
+		}
+	}
+	return nil
+}
+
+func (f Flights) validate() error {
+	if err := f.Availability.validate(); err != nil {
+		return fmt.Errorf("flights.availability: %w", err)
+	}
+	if len(f.Routes) == 0 {
+		return nil
+	}
+	// Seats are lettered A to Z
+	if f.Rows < 1 || f.SeatsPerRow < 1 || f.SeatsPerRow > 26 {
+		return fmt.Errorf("flights need at least 1 row and between 1 and 26 seats per row, got %d and %d", f.Rows, f.SeatsPerRow)
+	}
+	for _, class := range f.Classes {
+		switch class.Class {
+		case pricing.FareClassFirst, pricing.FareClassBusiness, pricing.FareClassEconomy:
+		default:
+			return fmt.Errorf("flights.classes: unknown class %q", class.Class)
+		}
+		if class.FirstRow < 1 || class.LastRow < class.FirstRow || class.LastRow > f.Rows {
+			return fmt.Errorf("flights.classes: %s rows %d-%d are outside 1-%d", class.Class, class.FirstRow, class.LastRow, f.Rows)
+		}
+	}
+	for _, route := range f.Routes {
+		if route.FlightNumber == "" || route.Origin == "" || route.Destination == "" {
+			return fmt.Errorf("flights.routes: %q needs a flight number, origin and destination", route.FlightNumber)
+		}
+	}
+	return nil
+}
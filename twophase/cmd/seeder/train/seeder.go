@@ -5,48 +5,69 @@ import (
 	"fmt"
 	"log"
 
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/cmd/seeder/spec"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/pricing"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/train"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/utils"
 )
 
-var trainNames = []string{
-	"Argo Bromo Anggrek",
-	"Argo Lawu",
-	"Argo Parahyangan",
-	"Bima",
-	"Gajayana",
-	"Harina",
-	"Kertajaya",
-	"Lodaya",
-	"Malabar",
-	"Matarmaja",
-}
-
-func Seed(ctx context.Context, repo *train.Repository) error {
+func Seed(ctx context.Context, repo *train.Repository, pricingRepo *pricing.Repository, data *spec.Data, mode utils.WriteMode) error {
 	log.Println("Starting train seeder...")
 
+	var trainJourneys []train.TrainJourney
+	var journeyFares []pricing.JourneyFare
 	var trainSeats []train.TrainSeatTicket
 
-	for _, trainName := range trainNames {
-		log.Printf("Seeding %s...", trainName)
+	for _, journey := range data.Journeys {
+		log.Printf("Seeding %s on %s...", journey.TrainName, journey.DepartureDate)
 
-		// 500 seats per train
-		for seatNumber := 1; seatNumber <= 500; seatNumber++ {
-			seatID := fmt.Sprintf("%d", seatNumber)
-			seatDocID := utils.Slugify(fmt.Sprintf("%s-%s", trainName, seatID))
+		trainJourneys = append(trainJourneys, train.TrainJourney{
+			ID:            journey.ID,
+			TrainName:     journey.TrainName,
+			DepartureDate: journey.DepartureDate,
+			Stations:      journey.Stations,
+			Coaches:       journey.Coaches,
+			SeatsPerCoach: journey.SeatsPerCoach,
+		})
 
-			trainSeats = append(trainSeats, train.TrainSeatTicket{
-				SeatID:    seatDocID,
-				TrainName: trainName,
-				Available: true,
-			})
+		journeyFares = append(journeyFares, pricing.JourneyFare{
+			JourneyID: journey.ID,
+			Stations:  journey.Stations,
+			Classes:   journey.Classes,
+		})
+
+		// One ticket per seat per segment between consecutive stations
+		for coach := 1; coach <= journey.Coaches; coach++ {
+			for seatNumber := 1; seatNumber <= journey.SeatsPerCoach; seatNumber++ {
+				for segment := 0; segment < len(journey.Stations)-1; segment++ {
+					trainSeats = append(trainSeats, train.TrainSeatTicket{
+						JourneyID:     journey.ID,
+						DepartureDate: journey.DepartureDate,
+						SeatID:        fmt.Sprintf("%d-%d", coach, seatNumber),
+						TrainName:     journey.TrainName,
+						Segment:       segment,
+						FromStation:   journey.Stations[segment],
+						ToStation:     journey.Stations[segment+1],
+						Available:     true,
+					})
+				}
+			}
 		}
 	}
 
-	if err := repo.BulkWriteTrainSeatTicket(ctx, trainSeats); err != nil {
+	if _, err := repo.BulkWriteTrainJourney(ctx, trainJourneys, mode); err != nil {
+		return fmt.Errorf("failed to bulk write train journeys: %w", err)
+	}
+
+	result, err := repo.BulkWriteTrainSeatTicket(ctx, trainSeats, mode)
+	if err != nil {
 		return fmt.Errorf("failed to bulk write train seats: %w", err)
 	}
 
-	log.Printf("Train seeder completed. Total seats: %d", len(trainSeats))
+	if _, err := pricingRepo.BulkWriteJourneyFares(ctx, journeyFares, mode); err != nil {
+		return fmt.Errorf("failed to bulk write journey fares: %w", err)
+	}
+
+	log.Printf("Train seeder completed. Total journeys: %d, total seat segments: %d, written: %d, skipped: %d", len(trainJourneys), len(trainSeats), result.Written, result.Skipped)
 	return nil
 }
@@ -0,0 +1,35 @@
+package car
+
+import (
+	"context"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/fault"
+)
+
+// WithFaults makes the repository fail the prepare, commit and abort of bookings as
+// configured in injector
+func (r *Repository) WithFaults(injector *fault.Injector) *Repository {
+	r.faults = injector
+	return r
+}
+
+// PrepareCarReservation runs prepareCarReservation, failing before or after it when a fault is injected
+func (r *Repository) PrepareCarReservation(ctx context.Context, transactionID string, cars []CarItem) error {
+	return r.faults.Do("PrepareCarReservation", transactionID, func() error {
+		return r.prepareCarReservation(ctx, transactionID, cars)
+	})
+}
+
+// CommitCarReservation runs commitCarReservation, failing before or after it when a fault is injected
+func (r *Repository) CommitCarReservation(ctx context.Context, transactionID string) error {
+	return r.faults.Do("CommitCarReservation", transactionID, func() error {
+		return r.commitCarReservation(ctx, transactionID)
+	})
+}
+
+// AbortCarReservation runs abortCarReservation, failing before or after it when a fault is injected
+func (r *Repository) AbortCarReservation(ctx context.Context, transactionID string) error {
+	return r.faults.Do("AbortCarReservation", transactionID, func() error {
+		return r.abortCarReservation(ctx, transactionID)
+	})
+}
@@ -2,13 +2,18 @@ package car
 
 import (
 	"time"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/api"
 )
 
 type CarReservationStatus string
 
 const (
-	CarReservationStatusCancelled CarReservationStatus = "CANCELLED"
-	CarReservationStatusReserved  CarReservationStatus = "RESERVED"
+	CarReservationStatusCancelled  CarReservationStatus = "CANCELLED"
+	CarReservationStatusReserved   CarReservationStatus = "RESERVED"
+	CarReservationStatusCancelling CarReservationStatus = "CANCELLING"
+	CarReservationStatusModifying  CarReservationStatus = "MODIFYING"
+	CarReservationStatusHeld       CarReservationStatus = "HELD"
 )
 
 type TwoPhaseTransactionStatus string
@@ -17,11 +22,17 @@ const (
 	TwoPhaseTransactionStatusPrepared  TwoPhaseTransactionStatus = "PREPARED"
 	TwoPhaseTransactionStatusCommitted TwoPhaseTransactionStatus = "COMMITTED"
 	TwoPhaseTransactionStatusAborted   TwoPhaseTransactionStatus = "ABORTED"
+	// HELD transactions are holds, which become COMMITTED when confirmed or EXPIRED when
+	// the participant releases them after ExpiresAt
+	TwoPhaseTransactionStatusHeld    TwoPhaseTransactionStatus = "HELD"
+	TwoPhaseTransactionStatusExpired TwoPhaseTransactionStatus = "EXPIRED"
 )
 
 type CarAvailability struct {
 	CarID     string `firestore:"car_id" json:"car_id"`
 	CarName   string `firestore:"car_name" json:"car_name"`
+	Brand     string `firestore:"brand" json:"brand"`
+	Model     string `firestore:"model" json:"model"`
 	Date      string `firestore:"date" json:"date"`
 	Available bool   `firestore:"available" json:"available"`
 }
@@ -32,21 +43,90 @@ type CarReservation struct {
 	CarName       string               `firestore:"car_name" json:"car_name"`
 	CarStartDate  string               `firestore:"car_start_date" json:"car_start_date"`
 	CarEndDate    string               `firestore:"car_end_date" json:"car_end_date"`
+	Price         int64                `firestore:"price" json:"price"`
 	TransactionID string               `firestore:"transaction_id" json:"transaction_id"`
 	Status        CarReservationStatus `firestore:"status" json:"status"`
 }
 
 // TwoPhaseTransaction represents a two-phase commit transaction for hotel
 type TwoPhaseTransaction struct {
-	Id            string                    `firestore:"id"`
-	Status        TwoPhaseTransactionStatus `firestore:"status"` // "prepared", "committed", "aborted"
-	ReservationID string                    `firestore:"reservation_id,omitempty"`
-	CreatedAt     time.Time                 `firestore:"created_at"`
-	UpdatedAt     time.Time                 `firestore:"updated_at"`
+	Id             string                    `firestore:"id"`
+	Status         TwoPhaseTransactionStatus `firestore:"status"` // "prepared", "committed", "aborted"
+	ReservationIDs []string                  `firestore:"reservation_ids,omitempty"`
+	// BookingTransactionID is set on cancellation transactions and refers to the booking being cancelled
+	BookingTransactionID string `firestore:"booking_transaction_id,omitempty"`
+	// ReplacedReservationID is set on modification transactions and refers to the
+	// reservation of the booking that ReservationIDs replaces on commit
+	ReplacedReservationID string `firestore:"replaced_reservation_id,omitempty"`
+	// ExpiresAt is set on holds
+	ExpiresAt *time.Time `firestore:"expires_at,omitempty"`
+	CreatedAt time.Time  `firestore:"created_at"`
+	UpdatedAt time.Time  `firestore:"updated_at"`
+}
+
+// CarItem is a single car rented for a date range
+type CarItem struct {
+	CarID     string `json:"car_id" binding:"required"`
+	StartDate string `json:"start_date" binding:"required"`
+	EndDate   string `json:"end_date" binding:"required"`
+	Price     int64  `json:"price"`
 }
 
 type CarReservationPayload struct {
-	CarID        string `json:"car_id" binding:"required"`
-	CarStartDate string `json:"car_start_date" binding:"required"`
-	CarEndDate   string `json:"car_end_date" binding:"required"`
+	Cars []CarItem `json:"cars" binding:"required,min=1,dive"`
+}
+
+// CarModificationPayload replaces the car at Index of a committed booking with Car
+type CarModificationPayload struct {
+	BookingTransactionID string  `json:"booking_transaction_id" binding:"required"`
+	Index                int     `json:"index" binding:"min=0"`
+	Car                  CarItem `json:"car"`
+}
+
+// CarSearchQuery searches the cars that are available on every day from StartDate to
+// EndDate (inclusive), optionally narrowed down to a brand or a model
+type CarSearchQuery struct {
+	Brand     string `form:"brand"`
+	Model     string `form:"model"`
+	StartDate string `form:"start_date" binding:"required"`
+	EndDate   string `form:"end_date" binding:"required"`
+	api.PageRequest
+}
+
+// AvailableCar is a car found by a search
+type AvailableCar struct {
+	CarID   string `json:"car_id"`
+	CarName string `json:"car_name"`
+	Brand   string `json:"brand"`
+	Model   string `json:"model"`
+}
+
+// BlockingStatus is the status of the reservation that makes a car unavailable on a date
+type BlockingStatus string
+
+const (
+	// PREPARED cars are reserved by a transaction that is not committed yet
+	BlockingStatusPrepared BlockingStatus = "PREPARED"
+	BlockingStatusHeld     BlockingStatus = "HELD"
+	BlockingStatusReserved BlockingStatus = "RESERVED"
+)
+
+// CalendarQuery is the date range of a calendar, both dates inclusive
+type CalendarQuery struct {
+	From string `form:"from" binding:"required"`
+	To   string `form:"to" binding:"required"`
+}
+
+// CalendarDay is the availability of a car on one date. Status is set when a
+// reservation blocks the date.
+type CalendarDay struct {
+	Date      string         `json:"date"`
+	Available bool           `json:"available"`
+	Status    BlockingStatus `json:"status,omitempty"`
+}
+
+// CarCalendar is the availability of a car over a date range
+type CarCalendar struct {
+	CarID string        `json:"car_id"`
+	Days  []CalendarDay `json:"days"`
 }
@@ -8,9 +8,11 @@ import (
 
 	"cloud.google.com/go/firestore"
 	"github.com/oklog/ulid/v2"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/api"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/config"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/fault"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/utils"
+	"google.golang.org/api/iterator"
 )
 
 const (
@@ -20,12 +22,19 @@ const (
 )
 
 var (
-	ErrCarNotAvailable = errors.New("car not available")
+	ErrCarNotAvailable       = errors.New("car not available")
+	ErrBookingNotCancellable = errors.New("booking is not committed or is already being cancelled")
+	ErrBookingNotModifiable  = errors.New("booking is not committed or is already being cancelled or modified")
+	ErrHoldNotActive         = errors.New("hold is not active")
+	ErrHoldExpired           = errors.New("hold has expired")
+	ErrItemNotFound          = errors.New("booking has no car at the given index")
+	ErrInvalidDateRange      = errors.New("invalid date range")
 )
 
 // Repository handles Firestore operations for car service
 type Repository struct {
 	client *firestore.Client
+	faults *fault.Injector
 }
 
 // NewRepository creates a new repository instance
@@ -52,6 +61,36 @@ func (r *Repository) GetTwoPhaseTransaction(ctx context.Context, transactionID s
 	return &transaction, nil
 }
 
+// GetPreparedTransactions retrieves the transactions that are prepared and still wait
+// for the coordinator to commit or abort them
+func (r *Repository) GetPreparedTransactions(ctx context.Context) ([]*TwoPhaseTransaction, error) {
+	query := r.client.Collection(CarTransactionCollection).
+		Where("status", "==", TwoPhaseTransactionStatusPrepared)
+
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+
+	var transactions []*TwoPhaseTransaction
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate transactions: %w", err)
+		}
+
+		var transaction TwoPhaseTransaction
+		if err := doc.DataTo(&transaction); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal transaction: %w", err)
+		}
+
+		transactions = append(transactions, &transaction)
+	}
+
+	return transactions, nil
+}
+
 func (r *Repository) getCarAvailabilityId(carID, date string) string {
 	return fmt.Sprintf("%s-%s", carID, date)
 }
@@ -85,7 +124,7 @@ func (r *Repository) getCarAvailabilityRefs(carID string, checkInDate, checkOutD
 	return carAvailabilityRefs, nil
 }
 
-func (r *Repository) CommitCarReservation(ctx context.Context, transactionID string) error {
+func (r *Repository) commitCarReservation(ctx context.Context, transactionID string) error {
 	transactionRef := r.client.Collection(CarTransactionCollection).Doc(transactionID)
 
 	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
@@ -115,7 +154,13 @@ func (r *Repository) CommitCarReservation(ctx context.Context, transactionID str
 	})
 }
 
-func (r *Repository) AbortCarReservation(ctx context.Context, transactionID string) error {
+func (r *Repository) abortCarReservation(ctx context.Context, transactionID string) error {
+	return r.releaseCarReservations(ctx, transactionID, TwoPhaseTransactionStatusPrepared, TwoPhaseTransactionStatusAborted)
+}
+
+// releaseCarReservations releases the availability held by a transaction in fromStatus,
+// cancels its reservations and moves the transaction to finalStatus
+func (r *Repository) releaseCarReservations(ctx context.Context, transactionID string, fromStatus, finalStatus TwoPhaseTransactionStatus) error {
 	transactionRef := r.client.Collection(CarTransactionCollection).Doc(transactionID)
 
 	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
@@ -129,38 +174,43 @@ func (r *Repository) AbortCarReservation(ctx context.Context, transactionID stri
 			return fmt.Errorf("failed to unmarshal transaction: %w", err)
 		}
 
-		if transaction.Status != TwoPhaseTransactionStatusPrepared {
+		if transaction.Status != fromStatus {
 			// Already committed or aborted
 			return nil
 		}
 
-		reservationRef := r.client.Collection(CarReservationCollection).Doc(transaction.ReservationID)
-		reservationDoc, err := tx.Get(reservationRef)
-		if err != nil {
-			return fmt.Errorf("failed to get reservation: %w", err)
-		}
-
-		var reservation CarReservation
-		if err := reservationDoc.DataTo(&reservation); err != nil {
-			return fmt.Errorf("failed to unmarshal reservation: %w", err)
+		var reservationRefs []*firestore.DocumentRef
+		for _, reservationID := range transaction.ReservationIDs {
+			reservationRefs = append(reservationRefs, r.client.Collection(CarReservationCollection).Doc(reservationID))
 		}
 
-		carAvailabilityRefs, err := r.getCarAvailabilityRefs(reservation.CarID, reservation.CarStartDate, reservation.CarEndDate)
+		reservationDocs, err := tx.GetAll(reservationRefs)
 		if err != nil {
-			return fmt.Errorf("failed to get car availability refs: %w", err)
+			return fmt.Errorf("failed to get reservations: %w", err)
 		}
 
-		for _, ref := range carAvailabilityRefs {
-			doc, err := tx.Get(ref)
-			if err != nil {
-				return fmt.Errorf("failed to get car availability: %w", err)
+		// All reads must happen before the first write in a transaction
+		var carAvailabilityRefs []*firestore.DocumentRef
+		reservations := make([]*CarReservation, 0, len(reservationDocs))
+		for _, reservationDoc := range reservationDocs {
+			var reservation CarReservation
+			if err := reservationDoc.DataTo(&reservation); err != nil {
+				return fmt.Errorf("failed to unmarshal reservation: %w", err)
 			}
+			reservations = append(reservations, &reservation)
 
-			var carAvailability CarAvailability
-			if err := doc.DataTo(&carAvailability); err != nil {
-				return fmt.Errorf("failed to unmarshal car availability: %w", err)
+			refs, err := r.getCarAvailabilityRefs(reservation.CarID, reservation.CarStartDate, reservation.CarEndDate)
+			if err != nil {
+				return fmt.Errorf("failed to get car availability refs: %w", err)
 			}
+			carAvailabilityRefs = append(carAvailabilityRefs, refs...)
+		}
 
+		if _, err := tx.GetAll(carAvailabilityRefs); err != nil {
+			return fmt.Errorf("failed to get car availability: %w", err)
+		}
+
+		for _, ref := range carAvailabilityRefs {
 			if err := tx.Update(ref, []firestore.Update{
 				{Path: "available", Value: true},
 			}); err != nil {
@@ -169,55 +219,219 @@ func (r *Repository) AbortCarReservation(ctx context.Context, transactionID stri
 		}
 
 		if err := tx.Update(transactionRef, []firestore.Update{
-			{Path: "status", Value: TwoPhaseTransactionStatusAborted},
+			{Path: "status", Value: finalStatus},
 			{Path: "updated_at", Value: time.Now()},
 		}); err != nil {
 			return fmt.Errorf("failed to update transaction: %w", err)
 		}
 
-		if err := tx.Update(reservationRef, []firestore.Update{
-			{Path: "status", Value: CarReservationStatusCancelled},
+		for _, reservationRef := range reservationRefs {
+			if err := tx.Update(reservationRef, []firestore.Update{
+				{Path: "status", Value: CarReservationStatusCancelled},
+				{Path: "updated_at", Value: time.Now()},
+			}); err != nil {
+				return fmt.Errorf("failed to update reservation: %w", err)
+			}
+		}
+
+		for _, reservation := range reservations {
+			if err := r.recordRelease(tx, reservation); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// PrepareCarCancellation prepares releasing the cars reserved by a committed booking
+// transaction. The reservations are marked CANCELLING so that no other cancellation can
+// prepare them, while availability is only released on commit.
+func (r *Repository) PrepareCarCancellation(ctx context.Context, transactionID, bookingTransactionID string) error {
+	bookingRef := r.client.Collection(CarTransactionCollection).Doc(bookingTransactionID)
+
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		bookingDoc, err := tx.Get(bookingRef)
+		if err != nil {
+			return fmt.Errorf("failed to get booking transaction: %w", err)
+		}
+
+		var booking TwoPhaseTransaction
+		if err := bookingDoc.DataTo(&booking); err != nil {
+			return fmt.Errorf("failed to unmarshal booking transaction: %w", err)
+		}
+
+		if booking.Status != TwoPhaseTransactionStatusCommitted {
+			return ErrBookingNotCancellable
+		}
+
+		var reservationRefs []*firestore.DocumentRef
+		for _, reservationID := range booking.ReservationIDs {
+			reservationRefs = append(reservationRefs, r.client.Collection(CarReservationCollection).Doc(reservationID))
+		}
+
+		reservationDocs, err := tx.GetAll(reservationRefs)
+		if err != nil {
+			return fmt.Errorf("failed to get reservations: %w", err)
+		}
+
+		for _, reservationDoc := range reservationDocs {
+			var reservation CarReservation
+			if err := reservationDoc.DataTo(&reservation); err != nil {
+				return fmt.Errorf("failed to unmarshal reservation: %w", err)
+			}
+
+			if reservation.Status != CarReservationStatusReserved {
+				return ErrBookingNotCancellable
+			}
+		}
+
+		for _, reservationRef := range reservationRefs {
+			if err := tx.Update(reservationRef, []firestore.Update{
+				{Path: "status", Value: CarReservationStatusCancelling},
+				{Path: "updated_at", Value: time.Now()},
+			}); err != nil {
+				return fmt.Errorf("failed to update reservation: %w", err)
+			}
+		}
+
+		twoPhaseTransaction := &TwoPhaseTransaction{
+			Id:                   transactionID,
+			Status:               TwoPhaseTransactionStatusPrepared,
+			ReservationIDs:       booking.ReservationIDs,
+			BookingTransactionID: bookingTransactionID,
+			CreatedAt:            time.Now(),
+			UpdatedAt:            time.Now(),
+		}
+
+		twoPhaseTransactionRef := r.client.Collection(CarTransactionCollection).Doc(twoPhaseTransaction.Id)
+		if err := tx.Create(twoPhaseTransactionRef, twoPhaseTransaction); err != nil {
+			return fmt.Errorf("failed to create two-phase transaction: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// CommitCarCancellation releases the availability of the cancelled booking
+func (r *Repository) CommitCarCancellation(ctx context.Context, transactionID string) error {
+	return r.releaseCarReservations(ctx, transactionID, TwoPhaseTransactionStatusPrepared, TwoPhaseTransactionStatusCommitted)
+}
+
+// AbortCarCancellation puts the reservations of the booking back to RESERVED
+func (r *Repository) AbortCarCancellation(ctx context.Context, transactionID string) error {
+	transactionRef := r.client.Collection(CarTransactionCollection).Doc(transactionID)
+
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		transactionDoc, err := tx.Get(transactionRef)
+		if err != nil {
+			return fmt.Errorf("failed to get transaction: %w", err)
+		}
+
+		var transaction TwoPhaseTransaction
+		if err := transactionDoc.DataTo(&transaction); err != nil {
+			return fmt.Errorf("failed to unmarshal transaction: %w", err)
+		}
+
+		if transaction.Status != TwoPhaseTransactionStatusPrepared {
+			// Already committed or aborted
+			return nil
+		}
+
+		if err := tx.Update(transactionRef, []firestore.Update{
+			{Path: "status", Value: TwoPhaseTransactionStatusAborted},
 			{Path: "updated_at", Value: time.Now()},
 		}); err != nil {
-			return fmt.Errorf("failed to update reservation: %w", err)
+			return fmt.Errorf("failed to update transaction: %w", err)
+		}
+
+		for _, reservationID := range transaction.ReservationIDs {
+			if err := tx.Update(r.client.Collection(CarReservationCollection).Doc(reservationID), []firestore.Update{
+				{Path: "status", Value: CarReservationStatusReserved},
+				{Path: "updated_at", Value: time.Now()},
+			}); err != nil {
+				return fmt.Errorf("failed to update reservation: %w", err)
+			}
 		}
 
 		return nil
 	})
 }
 
-// PrepareCarReservation prepares a car reservation
-func (r *Repository) PrepareCarReservation(ctx context.Context, transactionID, carID string, checkInDate, checkOutDate string) error {
-	carAvailabilityRefs, err := r.getCarAvailabilityRefs(carID, checkInDate, checkOutDate)
+// PrepareCarModification prepares replacing the car at index of a committed booking
+// transaction. The new car is reserved right away while the replaced reservation is
+// marked MODIFYING, so the booking keeps its original car until the commit. Days
+// already held by the replaced reservation can be reused by the new car.
+func (r *Repository) PrepareCarModification(ctx context.Context, transactionID, bookingTransactionID string, index int, car CarItem) error {
+	bookingRef := r.client.Collection(CarTransactionCollection).Doc(bookingTransactionID)
+
+	newRefs, err := r.getCarAvailabilityRefs(car.CarID, car.StartDate, car.EndDate)
 	if err != nil {
 		return fmt.Errorf("failed to get car availability refs: %w", err)
 	}
-
-	if len(carAvailabilityRefs) == 0 {
+	if len(newRefs) == 0 {
 		return ErrCarNotAvailable
 	}
 
 	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		bookingDoc, err := tx.Get(bookingRef)
+		if err != nil {
+			return fmt.Errorf("failed to get booking transaction: %w", err)
+		}
+
+		var booking TwoPhaseTransaction
+		if err := bookingDoc.DataTo(&booking); err != nil {
+			return fmt.Errorf("failed to unmarshal booking transaction: %w", err)
+		}
+
+		if booking.Status != TwoPhaseTransactionStatusCommitted {
+			return ErrBookingNotModifiable
+		}
+		if index < 0 || index >= len(booking.ReservationIDs) {
+			return ErrItemNotFound
+		}
+
+		oldRef := r.client.Collection(CarReservationCollection).Doc(booking.ReservationIDs[index])
+		oldDoc, err := tx.Get(oldRef)
+		if err != nil {
+			return fmt.Errorf("failed to get reservation: %w", err)
+		}
+
+		var oldReservation CarReservation
+		if err := oldDoc.DataTo(&oldReservation); err != nil {
+			return fmt.Errorf("failed to unmarshal reservation: %w", err)
+		}
+
+		if oldReservation.Status != CarReservationStatusReserved {
+			return ErrBookingNotModifiable
+		}
+
+		oldRefs, err := r.getCarAvailabilityRefs(oldReservation.CarID, oldReservation.CarStartDate, oldReservation.CarEndDate)
+		if err != nil {
+			return fmt.Errorf("failed to get car availability refs: %w", err)
+		}
+
+		docs, err := tx.GetAll(newRefs)
+		if err != nil {
+			return fmt.Errorf("failed to get car availability: %w", err)
+		}
+
 		var carAvailability CarAvailability
-		for _, ref := range carAvailabilityRefs {
-			doc, err := tx.Get(ref)
-			if status.Code(err) == codes.NotFound {
+		for _, doc := range docs {
+			if !doc.Exists() {
 				return ErrCarNotAvailable
 			}
-			if err != nil {
-				return fmt.Errorf("failed to get car availability: %w", err)
-			}
 
 			if err := doc.DataTo(&carAvailability); err != nil {
 				return fmt.Errorf("failed to unmarshal car availability: %w", err)
 			}
 
-			if !carAvailability.Available {
+			if !carAvailability.Available && !containsRef(oldRefs, doc.Ref) {
 				return ErrCarNotAvailable
 			}
+		}
 
-			carAvailability.Available = false
-
+		for _, ref := range excludeRefs(newRefs, oldRefs) {
 			if err := tx.Update(ref, []firestore.Update{
 				{Path: "available", Value: false},
 			}); err != nil {
@@ -225,13 +439,21 @@ func (r *Repository) PrepareCarReservation(ctx context.Context, transactionID, c
 			}
 		}
 
+		if err := tx.Update(oldRef, []firestore.Update{
+			{Path: "status", Value: CarReservationStatusModifying},
+			{Path: "updated_at", Value: time.Now()},
+		}); err != nil {
+			return fmt.Errorf("failed to update reservation: %w", err)
+		}
+
 		carReservation := &CarReservation{
 			ID:            ulid.Make().String(),
 			TransactionID: transactionID,
-			CarID:         carID,
+			CarID:         car.CarID,
 			CarName:       carAvailability.CarName,
-			CarStartDate:  checkInDate,
-			CarEndDate:    checkOutDate,
+			CarStartDate:  car.StartDate,
+			CarEndDate:    car.EndDate,
+			Price:         car.Price,
 			Status:        CarReservationStatusReserved,
 		}
 
@@ -241,11 +463,13 @@ func (r *Repository) PrepareCarReservation(ctx context.Context, transactionID, c
 		}
 
 		twoPhaseTransaction := &TwoPhaseTransaction{
-			Id:            transactionID,
-			Status:        TwoPhaseTransactionStatusPrepared,
-			ReservationID: carReservation.ID,
-			CreatedAt:     time.Now(),
-			UpdatedAt:     time.Now(),
+			Id:                    transactionID,
+			Status:                TwoPhaseTransactionStatusPrepared,
+			ReservationIDs:        []string{carReservation.ID},
+			BookingTransactionID:  bookingTransactionID,
+			ReplacedReservationID: oldReservation.ID,
+			CreatedAt:             time.Now(),
+			UpdatedAt:             time.Now(),
 		}
 
 		twoPhaseTransactionRef := r.client.Collection(CarTransactionCollection).Doc(twoPhaseTransaction.Id)
@@ -257,17 +481,537 @@ func (r *Repository) PrepareCarReservation(ctx context.Context, transactionID, c
 	})
 }
 
-func (r *Repository) BulkWriteCarAvailability(ctx context.Context, carAvailabilities []CarAvailability) error {
+// CommitCarModification releases the days of the replaced reservation that the new
+// car does not use and swaps the reservation in the booking transaction
+func (r *Repository) CommitCarModification(ctx context.Context, transactionID string) error {
+	return r.finishCarModification(ctx, transactionID, TwoPhaseTransactionStatusCommitted)
+}
+
+// AbortCarModification releases the new car and puts the replaced reservation back to RESERVED
+func (r *Repository) AbortCarModification(ctx context.Context, transactionID string) error {
+	return r.finishCarModification(ctx, transactionID, TwoPhaseTransactionStatusAborted)
+}
+
+// finishCarModification moves a prepared modification transaction to finalStatus. On
+// commit the replaced reservation is cancelled, on abort the new one is.
+func (r *Repository) finishCarModification(ctx context.Context, transactionID string, finalStatus TwoPhaseTransactionStatus) error {
+	transactionRef := r.client.Collection(CarTransactionCollection).Doc(transactionID)
+
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		transactionDoc, err := tx.Get(transactionRef)
+		if err != nil {
+			return fmt.Errorf("failed to get transaction: %w", err)
+		}
+
+		var transaction TwoPhaseTransaction
+		if err := transactionDoc.DataTo(&transaction); err != nil {
+			return fmt.Errorf("failed to unmarshal transaction: %w", err)
+		}
+
+		if transaction.Status != TwoPhaseTransactionStatusPrepared {
+			// Already committed or aborted
+			return nil
+		}
+
+		bookingRef := r.client.Collection(CarTransactionCollection).Doc(transaction.BookingTransactionID)
+		bookingDoc, err := tx.Get(bookingRef)
+		if err != nil {
+			return fmt.Errorf("failed to get booking transaction: %w", err)
+		}
+
+		var booking TwoPhaseTransaction
+		if err := bookingDoc.DataTo(&booking); err != nil {
+			return fmt.Errorf("failed to unmarshal booking transaction: %w", err)
+		}
+
+		oldRef := r.client.Collection(CarReservationCollection).Doc(transaction.ReplacedReservationID)
+		newRef := r.client.Collection(CarReservationCollection).Doc(transaction.ReservationIDs[0])
+		reservationDocs, err := tx.GetAll([]*firestore.DocumentRef{oldRef, newRef})
+		if err != nil {
+			return fmt.Errorf("failed to get reservations: %w", err)
+		}
+
+		reservationRefs := make([][]*firestore.DocumentRef, len(reservationDocs))
+		reservations := make([]*CarReservation, len(reservationDocs))
+		for i, reservationDoc := range reservationDocs {
+			var reservation CarReservation
+			if err := reservationDoc.DataTo(&reservation); err != nil {
+				return fmt.Errorf("failed to unmarshal reservation: %w", err)
+			}
+			reservations[i] = &reservation
+
+			reservationRefs[i], err = r.getCarAvailabilityRefs(reservation.CarID, reservation.CarStartDate, reservation.CarEndDate)
+			if err != nil {
+				return fmt.Errorf("failed to get car availability refs: %w", err)
+			}
+		}
+
+		// Only the days held by the released reservation alone become available again
+		releasedRef, keptRef := oldRef, newRef
+		releasedRefs := excludeRefs(reservationRefs[0], reservationRefs[1])
+		released := reservations[0]
+		if finalStatus == TwoPhaseTransactionStatusAborted {
+			releasedRef, keptRef = newRef, oldRef
+			releasedRefs = excludeRefs(reservationRefs[1], reservationRefs[0])
+			released = reservations[1]
+		}
+
+		if _, err := tx.GetAll(releasedRefs); err != nil {
+			return fmt.Errorf("failed to get car availability: %w", err)
+		}
+
+		for _, ref := range releasedRefs {
+			if err := tx.Update(ref, []firestore.Update{
+				{Path: "available", Value: true},
+			}); err != nil {
+				return fmt.Errorf("failed to update car availability: %w", err)
+			}
+		}
+
+		if err := tx.Update(releasedRef, []firestore.Update{
+			{Path: "status", Value: CarReservationStatusCancelled},
+			{Path: "updated_at", Value: time.Now()},
+		}); err != nil {
+			return fmt.Errorf("failed to update reservation: %w", err)
+		}
+
+		if err := tx.Update(keptRef, []firestore.Update{
+			{Path: "status", Value: CarReservationStatusReserved},
+			{Path: "updated_at", Value: time.Now()},
+		}); err != nil {
+			return fmt.Errorf("failed to update reservation: %w", err)
+		}
+
+		if finalStatus == TwoPhaseTransactionStatusCommitted {
+			reservationIDs := make([]string, 0, len(booking.ReservationIDs))
+			for _, reservationID := range booking.ReservationIDs {
+				if reservationID == transaction.ReplacedReservationID {
+					reservationID = newRef.ID
+				}
+				reservationIDs = append(reservationIDs, reservationID)
+			}
+
+			if err := tx.Update(bookingRef, []firestore.Update{
+				{Path: "reservation_ids", Value: reservationIDs},
+				{Path: "updated_at", Value: time.Now()},
+			}); err != nil {
+				return fmt.Errorf("failed to update booking transaction: %w", err)
+			}
+		}
+
+		if err := tx.Update(transactionRef, []firestore.Update{
+			{Path: "status", Value: finalStatus},
+			{Path: "updated_at", Value: time.Now()},
+		}); err != nil {
+			return fmt.Errorf("failed to update transaction: %w", err)
+		}
+
+		if len(releasedRefs) > 0 {
+			if err := r.recordRelease(tx, released); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// recordRelease adds the released reservation to the inventory release outbox
+func (r *Repository) recordRelease(tx *firestore.Transaction, reservation *CarReservation) error {
+	release := &api.InventoryRelease{
+		ID:        ulid.Make().String(),
+		Service:   "car",
+		ItemID:    reservation.CarID,
+		StartDate: reservation.CarStartDate,
+		EndDate:   reservation.CarEndDate,
+		CreatedAt: time.Now(),
+	}
+	if err := tx.Create(r.client.Collection(api.InventoryReleaseCollection).Doc(release.ID), release); err != nil {
+		return fmt.Errorf("failed to record release: %w", err)
+	}
+	return nil
+}
+
+// containsRef reports whether refs contains a reference to the same document as ref
+func containsRef(refs []*firestore.DocumentRef, ref *firestore.DocumentRef) bool {
+	for _, r := range refs {
+		if r.Path == ref.Path {
+			return true
+		}
+	}
+	return false
+}
+
+// excludeRefs returns the references of refs that are not in excluded
+func excludeRefs(refs, excluded []*firestore.DocumentRef) []*firestore.DocumentRef {
+	var result []*firestore.DocumentRef
+	for _, ref := range refs {
+		if !containsRef(excluded, ref) {
+			result = append(result, ref)
+		}
+	}
+	return result
+}
+
+// prepareCarReservation prepares reservations for all cars in a single transaction.
+// If any car is unavailable on any day, nothing is reserved and an *api.ItemError
+// wrapping ErrCarNotAvailable identifies the offending item.
+func (r *Repository) prepareCarReservation(ctx context.Context, transactionID string, cars []CarItem) error {
+	return r.reserveCars(ctx, transactionID, cars, nil)
+}
+
+// HoldCars reserves all cars like PrepareCarReservation, but as a hold that is
+// released by ExpireCarHold unless it is confirmed before expiresAt
+func (r *Repository) HoldCars(ctx context.Context, holdID string, cars []CarItem, expiresAt time.Time) error {
+	return r.reserveCars(ctx, holdID, cars, &expiresAt)
+}
+
+// reserveCars locks the availability of the cars and creates their reservations under
+// transactionID, as a hold when expiresAt is set or as a prepared transaction otherwise
+func (r *Repository) reserveCars(ctx context.Context, transactionID string, cars []CarItem, expiresAt *time.Time) error {
+	transactionStatus, reservationStatus := TwoPhaseTransactionStatusPrepared, CarReservationStatusReserved
+	if expiresAt != nil {
+		transactionStatus, reservationStatus = TwoPhaseTransactionStatusHeld, CarReservationStatusHeld
+	}
+
+	carAvailabilityRefs := make([][]*firestore.DocumentRef, len(cars))
+	seen := make(map[string]bool)
+	for i, car := range cars {
+		refs, err := r.getCarAvailabilityRefs(car.CarID, car.StartDate, car.EndDate)
+		if err != nil {
+			return fmt.Errorf("failed to get car availability refs: %w", err)
+		}
+
+		if len(refs) == 0 {
+			return &api.ItemError{Index: i, Err: ErrCarNotAvailable}
+		}
+
+		// The same car day cannot be booked twice within one order
+		for _, ref := range refs {
+			if seen[ref.ID] {
+				return &api.ItemError{Index: i, Err: ErrCarNotAvailable}
+			}
+			seen[ref.ID] = true
+		}
+
+		carAvailabilityRefs[i] = refs
+	}
+
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		carAvailabilities := make([]CarAvailability, len(cars))
+		for i, refs := range carAvailabilityRefs {
+			docs, err := tx.GetAll(refs)
+			if err != nil {
+				return fmt.Errorf("failed to get car availability: %w", err)
+			}
+
+			for _, doc := range docs {
+				if !doc.Exists() {
+					return &api.ItemError{Index: i, Err: ErrCarNotAvailable}
+				}
+
+				if err := doc.DataTo(&carAvailabilities[i]); err != nil {
+					return fmt.Errorf("failed to unmarshal car availability: %w", err)
+				}
+
+				if !carAvailabilities[i].Available {
+					return &api.ItemError{Index: i, Err: ErrCarNotAvailable}
+				}
+			}
+		}
+
+		reservationIDs := make([]string, 0, len(cars))
+		for i, car := range cars {
+			for _, ref := range carAvailabilityRefs[i] {
+				if err := tx.Update(ref, []firestore.Update{
+					{Path: "available", Value: false},
+				}); err != nil {
+					return fmt.Errorf("failed to update car availability: %w", err)
+				}
+			}
+
+			carReservation := &CarReservation{
+				ID:            ulid.Make().String(),
+				TransactionID: transactionID,
+				CarID:         car.CarID,
+				CarName:       carAvailabilities[i].CarName,
+				CarStartDate:  car.StartDate,
+				CarEndDate:    car.EndDate,
+				Price:         car.Price,
+				Status:        reservationStatus,
+			}
+
+			carReservationRef := r.client.Collection(CarReservationCollection).Doc(carReservation.ID)
+			if err := tx.Create(carReservationRef, carReservation); err != nil {
+				return fmt.Errorf("failed to create car reservation: %w", err)
+			}
+
+			reservationIDs = append(reservationIDs, carReservation.ID)
+		}
+
+		twoPhaseTransaction := &TwoPhaseTransaction{
+			Id:             transactionID,
+			Status:         transactionStatus,
+			ReservationIDs: reservationIDs,
+			ExpiresAt:      expiresAt,
+			CreatedAt:      time.Now(),
+			UpdatedAt:      time.Now(),
+		}
+
+		twoPhaseTransactionRef := r.client.Collection(CarTransactionCollection).Doc(twoPhaseTransaction.Id)
+		if err := tx.Create(twoPhaseTransactionRef, twoPhaseTransaction); err != nil {
+			return fmt.Errorf("failed to create two-phase transaction: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// ConfirmCarHold turns an unexpired hold into a committed booking transaction, so it can
+// later be cancelled or modified like any other booking
+func (r *Repository) ConfirmCarHold(ctx context.Context, holdID string, now time.Time) error {
+	holdRef := r.client.Collection(CarTransactionCollection).Doc(holdID)
+
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		holdDoc, err := tx.Get(holdRef)
+		if err != nil {
+			return fmt.Errorf("failed to get hold: %w", err)
+		}
+
+		var hold TwoPhaseTransaction
+		if err := holdDoc.DataTo(&hold); err != nil {
+			return fmt.Errorf("failed to unmarshal hold: %w", err)
+		}
+
+		if hold.Status == TwoPhaseTransactionStatusCommitted && hold.ExpiresAt != nil {
+			// Already confirmed
+			return nil
+		}
+		if hold.Status != TwoPhaseTransactionStatusHeld {
+			return ErrHoldNotActive
+		}
+		if hold.ExpiresAt == nil || !now.Before(*hold.ExpiresAt) {
+			return ErrHoldExpired
+		}
+
+		for _, reservationID := range hold.ReservationIDs {
+			if err := tx.Update(r.client.Collection(CarReservationCollection).Doc(reservationID), []firestore.Update{
+				{Path: "status", Value: CarReservationStatusReserved},
+				{Path: "updated_at", Value: time.Now()},
+			}); err != nil {
+				return fmt.Errorf("failed to update reservation: %w", err)
+			}
+		}
+
+		if err := tx.Update(holdRef, []firestore.Update{
+			{Path: "status", Value: TwoPhaseTransactionStatusCommitted},
+			{Path: "updated_at", Value: time.Now()},
+		}); err != nil {
+			return fmt.Errorf("failed to update hold: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// ReleaseCarHold releases the cars of a hold before it expires
+func (r *Repository) ReleaseCarHold(ctx context.Context, holdID string) error {
+	return r.releaseCarReservations(ctx, holdID, TwoPhaseTransactionStatusHeld, TwoPhaseTransactionStatusAborted)
+}
+
+// ExpireCarHold releases the cars of an expired hold
+func (r *Repository) ExpireCarHold(ctx context.Context, holdID string) error {
+	return r.releaseCarReservations(ctx, holdID, TwoPhaseTransactionStatusHeld, TwoPhaseTransactionStatusExpired)
+}
+
+// GetExpiredHoldIDs returns the IDs of the holds that expired before now
+func (r *Repository) GetExpiredHoldIDs(ctx context.Context, now time.Time) ([]string, error) {
+	docs, err := r.client.Collection(CarTransactionCollection).
+		Where("status", "==", TwoPhaseTransactionStatusHeld).
+		Where("expires_at", "<=", now).
+		Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expired holds: %w", err)
+	}
+
+	holdIDs := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		holdIDs = append(holdIDs, doc.Ref.ID)
+	}
+
+	return holdIDs, nil
+}
+
+// SearchAvailableCars returns up to limit cars, ordered by ID and starting after the car
+// pageToken, that are available on all dates. The returned token is empty once there are
+// no more cars to search.
+func (r *Repository) SearchAvailableCars(ctx context.Context, brand, model string, dates []string, pageToken string, limit int) ([]AvailableCar, string, error) {
+	// Every car has one availability document per date, so a car is available when all
+	// of its documents for the dates are
+	query := r.client.Collection(CarAvailabilityCollection).
+		Where("date", "in", dates).
+		Where("available", "==", true)
+	if brand != "" {
+		query = query.Where("brand", "==", brand)
+	}
+	if model != "" {
+		query = query.Where("model", "==", model)
+	}
+	query = query.OrderBy("car_id", firestore.Asc)
+	if pageToken != "" {
+		query = query.StartAfter(pageToken)
+	}
+
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+
+	cars := make([]AvailableCar, 0, limit)
+	var current *CarAvailability
+	availableDays := 0
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to search car availability: %w", err)
+		}
+
+		var availability CarAvailability
+		if err := doc.DataTo(&availability); err != nil {
+			return nil, "", fmt.Errorf("failed to unmarshal car availability: %w", err)
+		}
+
+		if current != nil && availability.CarID == current.CarID {
+			availableDays++
+			continue
+		}
+
+		if current != nil && availableDays == len(dates) {
+			cars = append(cars, availableCar(current))
+			if len(cars) == limit {
+				return cars, current.CarID, nil
+			}
+		}
+		current = &availability
+		availableDays = 1
+	}
+
+	if current != nil && availableDays == len(dates) {
+		cars = append(cars, availableCar(current))
+	}
+
+	return cars, "", nil
+}
+
+func availableCar(availability *CarAvailability) AvailableCar {
+	return AvailableCar{
+		CarID:   availability.CarID,
+		CarName: availability.CarName,
+		Brand:   availability.Brand,
+		Model:   availability.Model,
+	}
+}
+
+// GetCarCalendar returns the availability of the car on each of the dates, which must be
+// in ascending order. Dates without an availability document are unavailable without a status.
+func (r *Repository) GetCarCalendar(ctx context.Context, carID string, dates []string) ([]CalendarDay, error) {
+	refs := make([]*firestore.DocumentRef, 0, len(dates))
+	for _, date := range dates {
+		refs = append(refs, r.client.Collection(CarAvailabilityCollection).Doc(r.getCarAvailabilityId(carID, date)))
+	}
+
+	docs, err := r.client.GetAll(ctx, refs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get car availability: %w", err)
+	}
+
+	days := make([]CalendarDay, len(dates))
+	blocked := false
+	for i, doc := range docs {
+		days[i].Date = dates[i]
+		if !doc.Exists() {
+			continue
+		}
+
+		var availability CarAvailability
+		if err := doc.DataTo(&availability); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal car availability: %w", err)
+		}
+
+		days[i].Available = availability.Available
+		blocked = blocked || !availability.Available
+	}
+	if !blocked {
+		return days, nil
+	}
+
+	// Label the unavailable dates with the status of the reservation blocking them
+	reservationDocs, err := r.client.Collection(CarReservationCollection).
+		Where("car_id", "==", carID).
+		Where("car_start_date", "<=", dates[len(dates)-1]).
+		Where("car_end_date", ">=", dates[0]).
+		Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get car reservations: %w", err)
+	}
+
+	var reservations []CarReservation
+	var transactionRefs []*firestore.DocumentRef
+	for _, doc := range reservationDocs {
+		var reservation CarReservation
+		if err := doc.DataTo(&reservation); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal reservation: %w", err)
+		}
+		if reservation.Status == CarReservationStatusCancelled {
+			continue
+		}
+
+		reservations = append(reservations, reservation)
+		transactionRefs = append(transactionRefs, r.client.Collection(CarTransactionCollection).Doc(reservation.TransactionID))
+	}
+	if len(reservations) == 0 {
+		return days, nil
+	}
+
+	transactionDocs, err := r.client.GetAll(ctx, transactionRefs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transactions: %w", err)
+	}
+
+	for i, reservation := range reservations {
+		status := BlockingStatusReserved
+		if reservation.Status == CarReservationStatusHeld {
+			status = BlockingStatusHeld
+		} else if transactionDocs[i].Exists() {
+			var transaction TwoPhaseTransaction
+			if err := transactionDocs[i].DataTo(&transaction); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal transaction: %w", err)
+			}
+			if transaction.Status == TwoPhaseTransactionStatusPrepared {
+				status = BlockingStatusPrepared
+			}
+		}
+
+		for j := range days {
+			if !days[j].Available && days[j].Date >= reservation.CarStartDate && days[j].Date <= reservation.CarEndDate {
+				days[j].Status = status
+			}
+		}
+	}
+
+	return days, nil
+}
+
+// BulkWriteCarAvailability seeds car availabilities. An upsert keeps the available flag of
+// existing documents.
+func (r *Repository) BulkWriteCarAvailability(ctx context.Context, carAvailabilities []CarAvailability, mode utils.WriteMode) (utils.BulkWriteResult, error) {
 	collection := r.client.Collection(CarAvailabilityCollection)
-	bw := r.client.BulkWriter(ctx)
 
+	docs := make([]utils.Doc, 0, len(carAvailabilities))
 	for _, carAvailability := range carAvailabilities {
-		docRef := collection.Doc(r.getCarAvailabilityId(carAvailability.CarID, carAvailability.Date))
-		bw.Set(docRef, carAvailability)
+		docs = append(docs, utils.Doc{Ref: collection.Doc(r.getCarAvailabilityId(carAvailability.CarID, carAvailability.Date)), Data: carAvailability})
 	}
 
-	// Flush all writes
-	bw.Flush()
-
-	return nil
+	return utils.BulkWrite(ctx, r.client, docs, mode, "car_id", "car_name", "brand", "model", "date")
 }
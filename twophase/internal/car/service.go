@@ -2,7 +2,9 @@ package car
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"time"
 
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/api"
@@ -11,13 +13,15 @@ import (
 
 // Service handles car business logic with two-phase commit
 type Service struct {
-	repo *Repository
+	repo    *Repository
+	holdTTL time.Duration
 }
 
-// NewService creates a new car service
-func NewService(repo *Repository) *Service {
+// NewService creates a new car service. Holds last holdTTL unless the request sets its own TTL.
+func NewService(repo *Repository, holdTTL time.Duration) *Service {
 	return &Service{
-		repo: repo,
+		repo:    repo,
+		holdTTL: holdTTL,
 	}
 }
 
@@ -33,33 +37,27 @@ func (s *Service) Prepare(ctx context.Context, req *api.PrepareRequest[CarReserv
 		}, nil
 	}
 
-	startDate, err := time.Parse(config.DateFormat, req.Payload.CarStartDate)
-	if err != nil {
-		return &api.PrepareResponse{
-			Success: false,
-			Message: fmt.Sprintf("Failed to parse start date: %v", err),
-		}, nil
-	}
-
-	endDate, err := time.Parse(config.DateFormat, req.Payload.CarEndDate)
-	if err != nil {
+	cars, itemErr := parseCars(req.Payload.Cars)
+	if itemErr != nil {
 		return &api.PrepareResponse{
-			Success: false,
-			Message: fmt.Sprintf("Failed to parse end date: %v", err),
+			Success:    false,
+			Message:    fmt.Sprintf("Failed to parse item: %v", itemErr.Err),
+			FailedItem: &itemErr.Index,
 		}, nil
 	}
 
-	if err := s.repo.PrepareCarReservation(
-		ctx,
-		req.TransactionID,
-		req.Payload.CarID,
-		startDate.Format(config.DateFormat),
-		endDate.Format(config.DateFormat),
-	); err != nil {
-		return &api.PrepareResponse{
+	if err := s.repo.PrepareCarReservation(ctx, req.TransactionID, cars); err != nil {
+		response := &api.PrepareResponse{
 			Success: false,
 			Message: fmt.Sprintf("Failed to create transaction log: %v", err),
-		}, nil
+		}
+
+		var itemErr *api.ItemError
+		if errors.As(err, &itemErr) {
+			response.FailedItem = &itemErr.Index
+		}
+
+		return response, nil
 	}
 
 	return &api.PrepareResponse{
@@ -97,3 +95,322 @@ func (s *Service) Abort(ctx context.Context, req *api.AbortRequest) (*api.AbortR
 		Message: "Car service aborted successfully",
 	}, nil
 }
+
+// PrepareCancellation handles the prepare phase of a cancellation transaction
+func (s *Service) PrepareCancellation(ctx context.Context, req *api.PrepareRequest[api.CancellationPayload]) (*api.PrepareResponse, error) {
+	// Check if transaction already exists
+	existingTransaction, err := s.repo.GetTwoPhaseTransaction(ctx, req.TransactionID)
+	if err == nil && existingTransaction != nil {
+		return &api.PrepareResponse{
+			Success: existingTransaction.Status == TwoPhaseTransactionStatusPrepared,
+			Message: fmt.Sprintf("Transaction already %s", existingTransaction.Status),
+		}, nil
+	}
+
+	if err := s.repo.PrepareCarCancellation(ctx, req.TransactionID, req.Payload.BookingTransactionID); err != nil {
+		return &api.PrepareResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to prepare cancellation: %v", err),
+		}, nil
+	}
+
+	return &api.PrepareResponse{
+		Success: true,
+		Message: "Car service prepared cancellation successfully",
+	}, nil
+}
+
+// CommitCancellation handles the commit phase of a cancellation transaction
+func (s *Service) CommitCancellation(ctx context.Context, req *api.CommitRequest) (*api.CommitResponse, error) {
+	if err := s.repo.CommitCarCancellation(ctx, req.TransactionID); err != nil {
+		return &api.CommitResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to commit cancellation: %v", err),
+		}, nil
+	}
+
+	return &api.CommitResponse{
+		Success: true,
+		Message: "Car service committed cancellation successfully",
+	}, nil
+}
+
+// AbortCancellation handles the abort phase of a cancellation transaction
+func (s *Service) AbortCancellation(ctx context.Context, req *api.AbortRequest) (*api.AbortResponse, error) {
+	if err := s.repo.AbortCarCancellation(ctx, req.TransactionID); err != nil {
+		return &api.AbortResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to abort cancellation: %v", err),
+		}, nil
+	}
+
+	return &api.AbortResponse{
+		Success: true,
+		Message: "Car service aborted cancellation successfully",
+	}, nil
+}
+
+// PrepareModification handles the prepare phase of a modification transaction
+func (s *Service) PrepareModification(ctx context.Context, req *api.PrepareRequest[CarModificationPayload]) (*api.PrepareResponse, error) {
+	// Check if transaction already exists
+	existingTransaction, err := s.repo.GetTwoPhaseTransaction(ctx, req.TransactionID)
+	if err == nil && existingTransaction != nil {
+		return &api.PrepareResponse{
+			Success: existingTransaction.Status == TwoPhaseTransactionStatusPrepared,
+			Message: fmt.Sprintf("Transaction already %s", existingTransaction.Status),
+		}, nil
+	}
+
+	car := req.Payload.Car
+	startDate, err := time.Parse(config.DateFormat, car.StartDate)
+	if err != nil {
+		return &api.PrepareResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to parse start date: %v", err),
+		}, nil
+	}
+
+	endDate, err := time.Parse(config.DateFormat, car.EndDate)
+	if err != nil {
+		return &api.PrepareResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to parse end date: %v", err),
+		}, nil
+	}
+
+	car.StartDate = startDate.Format(config.DateFormat)
+	car.EndDate = endDate.Format(config.DateFormat)
+	if err := s.repo.PrepareCarModification(ctx, req.TransactionID, req.Payload.BookingTransactionID, req.Payload.Index, car); err != nil {
+		return &api.PrepareResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to prepare modification: %v", err),
+		}, nil
+	}
+
+	return &api.PrepareResponse{
+		Success: true,
+		Message: "Car service prepared modification successfully",
+	}, nil
+}
+
+// CommitModification handles the commit phase of a modification transaction
+func (s *Service) CommitModification(ctx context.Context, req *api.CommitRequest) (*api.CommitResponse, error) {
+	if err := s.repo.CommitCarModification(ctx, req.TransactionID); err != nil {
+		return &api.CommitResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to commit modification: %v", err),
+		}, nil
+	}
+
+	return &api.CommitResponse{
+		Success: true,
+		Message: "Car service committed modification successfully",
+	}, nil
+}
+
+// AbortModification handles the abort phase of a modification transaction
+func (s *Service) AbortModification(ctx context.Context, req *api.AbortRequest) (*api.AbortResponse, error) {
+	if err := s.repo.AbortCarModification(ctx, req.TransactionID); err != nil {
+		return &api.AbortResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to abort modification: %v", err),
+		}, nil
+	}
+
+	return &api.AbortResponse{
+		Success: true,
+		Message: "Car service aborted modification successfully",
+	}, nil
+}
+
+// parseCars normalizes the dates of all cars to config.DateFormat
+func parseCars(items []CarItem) ([]CarItem, *api.ItemError) {
+	cars := make([]CarItem, 0, len(items))
+	for i, car := range items {
+		startDate, err := time.Parse(config.DateFormat, car.StartDate)
+		if err != nil {
+			return nil, &api.ItemError{Index: i, Err: fmt.Errorf("invalid start date: %w", err)}
+		}
+
+		endDate, err := time.Parse(config.DateFormat, car.EndDate)
+		if err != nil {
+			return nil, &api.ItemError{Index: i, Err: fmt.Errorf("invalid end date: %w", err)}
+		}
+
+		cars = append(cars, CarItem{
+			CarID:     car.CarID,
+			StartDate: startDate.Format(config.DateFormat),
+			EndDate:   endDate.Format(config.DateFormat),
+			Price:     car.Price,
+		})
+	}
+
+	return cars, nil
+}
+
+// Hold places a hold on the cars until it is confirmed, released or expires
+func (s *Service) Hold(ctx context.Context, req *api.HoldRequest[CarReservationPayload]) (*api.HoldResponse, error) {
+	// Check if hold already exists
+	existingHold, err := s.repo.GetTwoPhaseTransaction(ctx, req.HoldID)
+	if err == nil && existingHold != nil {
+		return &api.HoldResponse{
+			Success:   existingHold.Status == TwoPhaseTransactionStatusHeld,
+			Message:   fmt.Sprintf("Hold already %s", existingHold.Status),
+			ExpiresAt: existingHold.ExpiresAt,
+		}, nil
+	}
+
+	cars, itemErr := parseCars(req.Payload.Cars)
+	if itemErr != nil {
+		return &api.HoldResponse{
+			Success:    false,
+			Message:    fmt.Sprintf("Failed to parse item: %v", itemErr.Err),
+			FailedItem: &itemErr.Index,
+		}, nil
+	}
+
+	ttl := s.holdTTL
+	if req.TTLSeconds > 0 {
+		ttl = time.Duration(req.TTLSeconds) * time.Second
+	}
+	expiresAt := time.Now().Add(ttl)
+
+	if err := s.repo.HoldCars(ctx, req.HoldID, cars, expiresAt); err != nil {
+		response := &api.HoldResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to hold cars: %v", err),
+		}
+
+		var itemErr *api.ItemError
+		if errors.As(err, &itemErr) {
+			response.FailedItem = &itemErr.Index
+		}
+
+		return response, nil
+	}
+
+	return &api.HoldResponse{
+		Success:   true,
+		Message:   "Car service held cars successfully",
+		ExpiresAt: &expiresAt,
+	}, nil
+}
+
+// ConfirmHold turns a hold into a committed booking transaction
+func (s *Service) ConfirmHold(ctx context.Context, req *api.ConfirmHoldRequest) (*api.CommitResponse, error) {
+	if err := s.repo.ConfirmCarHold(ctx, req.HoldID, time.Now()); err != nil {
+		return &api.CommitResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to confirm hold: %v", err),
+		}, nil
+	}
+
+	return &api.CommitResponse{
+		Success: true,
+		Message: "Car service confirmed hold successfully",
+	}, nil
+}
+
+// ReleaseHold releases a hold before it expires
+func (s *Service) ReleaseHold(ctx context.Context, req *api.ReleaseHoldRequest) (*api.AbortResponse, error) {
+	if err := s.repo.ReleaseCarHold(ctx, req.HoldID); err != nil {
+		return &api.AbortResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to release hold: %v", err),
+		}, nil
+	}
+
+	return &api.AbortResponse{
+		Success: true,
+		Message: "Car service released hold successfully",
+	}, nil
+}
+
+// ReleaseExpiredHolds releases every hold that expired without being confirmed. It is
+// called periodically by the sweeper of the car service.
+func (s *Service) ReleaseExpiredHolds(ctx context.Context) error {
+	holdIDs, err := s.repo.GetExpiredHoldIDs(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, holdID := range holdIDs {
+		if err := s.repo.ExpireCarHold(ctx, holdID); err != nil {
+			errs = append(errs, fmt.Errorf("failed to expire hold %s: %w", holdID, err))
+		}
+	}
+	if len(holdIDs) > 0 {
+		log.Printf("Released %d expired holds", len(holdIDs))
+	}
+
+	return errors.Join(errs...)
+}
+
+const (
+	// maxSearchDays bounds the date range of a search, as Firestore accepts at most
+	// 30 values in an "in" filter
+	maxSearchDays = 30
+	// maxCalendarDays bounds the date range of a calendar to about two months
+	maxCalendarDays = 62
+)
+
+// dateRange returns every date from startDate to endDate (inclusive), which must span
+// 1 to maxDays days
+func dateRange(startDate, endDate string, maxDays int) ([]string, error) {
+	start, err := time.Parse(config.DateFormat, startDate)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid start date: %v", ErrInvalidDateRange, err)
+	}
+
+	end, err := time.Parse(config.DateFormat, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid end date: %v", ErrInvalidDateRange, err)
+	}
+
+	var dates []string
+	for date := start; !date.After(end) && len(dates) <= maxDays; date = date.AddDate(0, 0, 1) {
+		dates = append(dates, date.Format(config.DateFormat))
+	}
+	if len(dates) == 0 || len(dates) > maxDays {
+		return nil, fmt.Errorf("%w: must span 1 to %d days", ErrInvalidDateRange, maxDays)
+	}
+
+	return dates, nil
+}
+
+// SearchCars returns a page of the cars available on every day of the query
+func (s *Service) SearchCars(ctx context.Context, query *CarSearchQuery) (*api.Page[AvailableCar], error) {
+	dates, err := dateRange(query.StartDate, query.EndDate, maxSearchDays)
+	if err != nil {
+		return nil, err
+	}
+
+	cars, nextPageToken, err := s.repo.SearchAvailableCars(ctx, query.Brand, query.Model, dates, query.PageToken, query.Limit())
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.Page[AvailableCar]{
+		Items:         cars,
+		NextPageToken: nextPageToken,
+	}, nil
+}
+
+// GetCarCalendar returns the availability of the car on every day of the query
+func (s *Service) GetCarCalendar(ctx context.Context, carID string, query *CalendarQuery) (*CarCalendar, error) {
+	dates, err := dateRange(query.From, query.To, maxCalendarDays)
+	if err != nil {
+		return nil, err
+	}
+
+	days, err := s.repo.GetCarCalendar(ctx, carID, dates)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CarCalendar{
+		CarID: carID,
+		Days:  days,
+	}, nil
+}
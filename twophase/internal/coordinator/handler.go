@@ -1,10 +1,12 @@
 package coordinator
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/pricing"
 )
 
 // Handler handles HTTP requests for the coordinator
@@ -23,6 +25,13 @@ func NewHandler(service *Service) *Handler {
 func (h *Handler) RegisterRoutes(r *gin.Engine) {
 	// Order creation endpoint
 	r.POST("/orders", h.CreateOrder)
+	r.POST("/orders/:orderID/cancel", h.CancelOrder)
+	r.PATCH("/orders/:orderID", h.ModifyOrder)
+
+	// Waitlist for items that are not available
+	r.POST("/waitlist", h.JoinWaitlist)
+	r.GET("/waitlist/:entryID", h.GetWaitlistEntry)
+	r.DELETE("/waitlist/:entryID", h.LeaveWaitlist)
 
 	// Transaction status endpoint
 	r.GET("/transactions/:transactionID", h.GetTransactionStatus)
@@ -43,18 +52,28 @@ func (h *Handler) CreateOrder(c *gin.Context) {
 	}
 
 	// Validate required fields
-	if req.UserID == "" || req.HotelRoomID == "" || req.CarID == "" ||
-		req.TrainSeatID == "" {
+	if req.UserID == "" || (len(req.HotelRooms) == 0 && len(req.Cars) == 0 && len(req.TrainSeats) == 0 && len(req.Flights) == 0) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Missing required fields",
-			"message": "user_id, hotel_id, room_id, car_id, train_id, and seat_id are required",
+			"message": "user_id and at least one item in hotel_rooms, cars, train_seats, or flights are required",
 		})
 		return
 	}
 
 	response, err := h.service.CreateOrder(c.Request.Context(), &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		statusCode := http.StatusInternalServerError
+		switch {
+		case pricing.IsRequestError(err):
+			statusCode = http.StatusBadRequest
+		case errors.Is(err, pricing.ErrQuoteNotFound):
+			statusCode = http.StatusNotFound
+		case errors.Is(err, pricing.ErrQuoteExpired):
+			statusCode = http.StatusGone
+		case errors.Is(err, pricing.ErrQuoteAlreadyUsed):
+			statusCode = http.StatusConflict
+		}
+		c.JSON(statusCode, gin.H{
 			"error":   "Failed to create order",
 			"message": err.Error(),
 		})
@@ -64,6 +83,59 @@ func (h *Handler) CreateOrder(c *gin.Context) {
 	c.JSON(http.StatusAccepted, response)
 }
 
+// CancelOrder handles cancellation of a committed order with two-phase commit
+func (h *Handler) CancelOrder(c *gin.Context) {
+	response, err := h.service.CancelOrder(c.Request.Context(), c.Param("orderID"))
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, ErrOrderNotFound):
+			statusCode = http.StatusNotFound
+		case errors.Is(err, ErrOrderNotCancellable):
+			statusCode = http.StatusConflict
+		}
+		c.JSON(statusCode, gin.H{
+			"error":   "Failed to cancel order",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, response)
+}
+
+// ModifyOrder handles replacing one item of a committed order with two-phase commit
+func (h *Handler) ModifyOrder(c *gin.Context) {
+	var req ModifyOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	response, err := h.service.ModifyOrder(c.Request.Context(), c.Param("orderID"), &req)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, ErrInvalidModification), errors.Is(err, ErrFlightNotModifiable), errors.Is(err, ErrItemNotFound), pricing.IsRequestError(err):
+			statusCode = http.StatusBadRequest
+		case errors.Is(err, ErrOrderNotFound):
+			statusCode = http.StatusNotFound
+		case errors.Is(err, ErrOrderNotModifiable):
+			statusCode = http.StatusConflict
+		}
+		c.JSON(statusCode, gin.H{
+			"error":   "Failed to modify order",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, response)
+}
+
 // GetTransactionStatus handles transaction status retrieval
 func (h *Handler) GetTransactionStatus(c *gin.Context) {
 	transactionID := c.Param("transactionID")
@@ -87,6 +159,72 @@ func (h *Handler) GetTransactionStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, status)
 }
 
+// JoinWaitlist handles registering a user for an item that is not available
+func (h *Handler) JoinWaitlist(c *gin.Context) {
+	var req WaitlistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	entry, err := h.service.JoinWaitlist(c.Request.Context(), &req)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, ErrInvalidWaitlistEntry) {
+			statusCode = http.StatusBadRequest
+		}
+		c.JSON(statusCode, gin.H{
+			"error":   "Failed to join waitlist",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, entry)
+}
+
+// GetWaitlistEntry handles waitlist entry retrieval
+func (h *Handler) GetWaitlistEntry(c *gin.Context) {
+	entry, err := h.service.GetWaitlistEntry(c.Request.Context(), c.Param("entryID"))
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, ErrWaitlistEntryNotFound) {
+			statusCode = http.StatusNotFound
+		}
+		c.JSON(statusCode, gin.H{
+			"error":   "Failed to get waitlist entry",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+// LeaveWaitlist handles cancelling a waiting entry
+func (h *Handler) LeaveWaitlist(c *gin.Context) {
+	entry, err := h.service.LeaveWaitlist(c.Request.Context(), c.Param("entryID"))
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, ErrWaitlistEntryNotFound):
+			statusCode = http.StatusNotFound
+		case errors.Is(err, ErrWaitlistEntryNotCancellable):
+			statusCode = http.StatusConflict
+		}
+		c.JSON(statusCode, gin.H{
+			"error":   "Failed to leave waitlist",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
 // HealthCheck handles health check requests
 func (h *Handler) HealthCheck(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
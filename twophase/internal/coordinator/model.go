@@ -1,7 +1,13 @@
 package coordinator
 
 import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
 	"time"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/pricing"
 )
 
 // TransactionStatus represents the status of a two-phase commit transaction
@@ -14,12 +20,27 @@ const (
 	StatusAborted    TransactionStatus = "aborted"
 	StatusRolledBack TransactionStatus = "rolled_back"
 	StatusTimedOut   TransactionStatus = "timed_out"
+	// StatusCancelled marks a committed booking released by a committed cancellation
+	StatusCancelled TransactionStatus = "cancelled"
+)
+
+// TransactionKind tells what a transaction does in the participants
+type TransactionKind string
+
+const (
+	// KindBooking reserves the order items. Logs created before kinds existed have no kind and are bookings.
+	KindBooking TransactionKind = "booking"
+	// KindCancellation releases everything reserved by a committed booking
+	KindCancellation TransactionKind = "cancellation"
+	// KindModification replaces one item of a committed booking in a single participant
+	KindModification TransactionKind = "modification"
 )
 
 // TransactionLog represents a transaction log entry in Firestore
 type TransactionLog struct {
 	ID              string            `firestore:"id"`
 	OrderID         string            `firestore:"order_id"`
+	Kind            TransactionKind   `firestore:"kind,omitempty"`
 	Status          TransactionStatus `firestore:"status"`
 	Participants    []Participant     `firestore:"participants"`
 	DoneAt          *time.Time        `firestore:"done_at,omitempty"`
@@ -31,28 +52,276 @@ type TransactionLog struct {
 	LastRetryAt     *time.Time        `firestore:"last_retry_at,omitempty"`
 	FailureReason   string            `firestore:"failure_reason,omitempty"`
 	CommitTimestamp *time.Time        `firestore:"commit_timestamp,omitempty"`
+	QuoteID         string            `firestore:"quote_id,omitempty"`
+	TotalPrice      int64             `firestore:"total_price"`
+	Currency        string            `firestore:"currency"`
+	// Set on cancellation and modification transactions only
+	BookingTransactionID string `firestore:"booking_transaction_id,omitempty"`
+	CancellationFee      int64  `firestore:"cancellation_fee,omitempty"`
+	RefundAmount         int64  `firestore:"refund_amount,omitempty"`
+	// Set on modification transactions only. TotalPrice is the booking total after the
+	// modification and PriceDifference the change caused by the replacement item.
+	ItemIndex       int   `firestore:"item_index,omitempty"`
+	PriceDifference int64 `firestore:"price_difference,omitempty"`
+	// Set on booking transactions only. The requested items are kept so that a
+	// recorded run can be replayed from the exported transaction logs.
+	UserID     string          `firestore:"user_id,omitempty"`
+	HotelRooms []HotelRoomItem `firestore:"hotel_rooms,omitempty"`
+	Cars       []CarItem       `firestore:"cars,omitempty"`
+	TrainSeats []TrainSeatItem `firestore:"train_seats,omitempty"`
+	Flights    []FlightItem    `firestore:"flights,omitempty"`
+}
+
+// isBooking reports whether the log is a booking transaction
+func (l *TransactionLog) isBooking() bool {
+	return l.Kind == KindBooking || l.Kind == ""
+}
+
+// isCancellation reports whether the log is a cancellation transaction
+func (l *TransactionLog) isCancellation() bool {
+	return l.Kind == KindCancellation
+}
+
+// isModification reports whether the log is a modification transaction
+func (l *TransactionLog) isModification() bool {
+	return l.Kind == KindModification
+}
+
+// phasePath returns the participant endpoint group for the kind of transaction
+func (l *TransactionLog) phasePath() string {
+	switch {
+	case l.isCancellation():
+		return "/twophase/cancel"
+	case l.isModification():
+		return "/twophase/modify"
+	}
+	return "/twophase"
 }
 
 // Participant represents a service participating in the transaction
 type Participant struct {
-	ServiceName string     `firestore:"service_name"`
-	ServiceURL  string     `firestore:"service_url"`
-	Status      string     `firestore:"status"` // "prepared", "committed", "aborted", "failed"
-	DoneAt      *time.Time `firestore:"done_at,omitempty"`
-	Error       string     `firestore:"error,omitempty"`
-	RetryCount  int        `firestore:"retry_count"`
+	ServiceName string            `firestore:"service_name"`
+	ServiceURL  string            `firestore:"service_url"`
+	Status      string            `firestore:"status"` // "prepared", "committed", "aborted", "failed"
+	Items       []ParticipantItem `firestore:"items"`
+	DoneAt      *time.Time        `firestore:"done_at,omitempty"`
+	Error       string            `firestore:"error,omitempty"`
+	RetryCount  int               `firestore:"retry_count"`
 }
 
-// CreateOrderRequest represents the request to create an order
+// ParticipantItem tracks a single line item handled by a participant. Items of a
+// participant are prepared atomically, so they share the participant status unless
+// one of them is the reason the prepare failed.
+type ParticipantItem struct {
+	Item   string `firestore:"item"`
+	Price  int64  `firestore:"price,omitempty"`
+	Status string `firestore:"status"`
+	Error  string `firestore:"error,omitempty"`
+}
+
+// HotelRoomItem is a single room, or a unit of a room type, booked for a date range.
+// Exactly one of HotelRoomID and RoomTypeID is set; rooms of room type bookings are
+// assigned later by the hotel service. Price is set by the coordinator once the order
+// is priced and is ignored when sent by clients.
+type HotelRoomItem struct {
+	HotelRoomID string `json:"hotel_room_id,omitempty" binding:"required_without=RoomTypeID,excluded_with=RoomTypeID"`
+	RoomTypeID  string `json:"room_type_id,omitempty" binding:"required_without=HotelRoomID"`
+	StartDate   string `json:"start_date" binding:"required"`
+	EndDate     string `json:"end_date" binding:"required"`
+	Price       int64  `json:"price"`
+}
+
+// itemID returns the room or the room type booked by the item
+func (i HotelRoomItem) itemID() string {
+	if i.RoomTypeID != "" {
+		return i.RoomTypeID
+	}
+	return i.HotelRoomID
+}
+
+// CarItem is a single car rented for a date range
+type CarItem struct {
+	CarID     string `json:"car_id" binding:"required"`
+	StartDate string `json:"start_date" binding:"required"`
+	EndDate   string `json:"end_date" binding:"required"`
+	Price     int64  `json:"price"`
+}
+
+// TrainSeatItem is a single seat booked between two stations of a dated journey
+type TrainSeatItem struct {
+	JourneyID          string `json:"journey_id" binding:"required"`
+	DepartureDate      string `json:"departure_date" binding:"required"`
+	SeatID             string `json:"seat_id" binding:"required"`
+	OriginStation      string `json:"origin_station" binding:"required"`
+	DestinationStation string `json:"destination_station" binding:"required"`
+	Price              int64  `json:"price"`
+}
+
+// FlightItem is a single seat booked on a dated flight
+type FlightItem struct {
+	FlightID      string `json:"flight_id" binding:"required"`
+	DepartureDate string `json:"departure_date" binding:"required"`
+	SeatID        string `json:"seat_id" binding:"required"`
+	Price         int64  `json:"price"`
+}
+
+// CreateOrderRequest represents the request to create an order. Every leg is optional,
+// but at least one item is required. Every item of every requested leg is reserved
+// within the same distributed transaction. When QuoteID is set, the items must match
+// the quoted items and the quoted prices are used. TotalPrice and Currency are set by
+// the coordinator and authorized by the payment participant.
 type CreateOrderRequest struct {
-	HotelRoomID        string `json:"hotel_room_id" binding:"required"`
-	HotelRoomStartDate string `json:"hotel_room_start_date" binding:"required"`
-	HotelRoomEndDate   string `json:"hotel_room_end_date" binding:"required"`
-	CarID              string `json:"car_id" binding:"required"`
-	CarStartDate       string `json:"car_start_date" binding:"required"`
-	CarEndDate         string `json:"car_end_date" binding:"required"`
-	TrainSeatID        string `json:"train_seat_id" binding:"required"`
-	UserID             string `json:"user_id" binding:"required"`
+	HotelRooms []HotelRoomItem `json:"hotel_rooms" binding:"omitempty,dive"`
+	Cars       []CarItem       `json:"cars" binding:"omitempty,dive"`
+	TrainSeats []TrainSeatItem `json:"train_seats" binding:"omitempty,dive"`
+	Flights    []FlightItem    `json:"flights" binding:"omitempty,dive"`
+	UserID     string          `json:"user_id" binding:"required"`
+	QuoteID    string          `json:"quote_id"`
+	TotalPrice int64           `json:"total_price"`
+	Currency   string          `json:"currency"`
+}
+
+// quoteRequest returns the items of the order in the shape priced by the pricing service
+func (r *CreateOrderRequest) quoteRequest() pricing.QuoteRequest {
+	var req pricing.QuoteRequest
+	for _, room := range r.HotelRooms {
+		req.HotelRooms = append(req.HotelRooms, pricing.RoomQuoteRequest{
+			HotelRoomID: room.HotelRoomID,
+			RoomTypeID:  room.RoomTypeID,
+			StartDate:   room.StartDate,
+			EndDate:     room.EndDate,
+		})
+	}
+	for _, car := range r.Cars {
+		req.Cars = append(req.Cars, pricing.CarQuoteRequest{
+			CarID:     car.CarID,
+			StartDate: car.StartDate,
+			EndDate:   car.EndDate,
+		})
+	}
+	for _, seat := range r.TrainSeats {
+		req.TrainSeats = append(req.TrainSeats, pricing.SeatQuoteRequest{
+			JourneyID:          seat.JourneyID,
+			DepartureDate:      seat.DepartureDate,
+			SeatID:             seat.SeatID,
+			OriginStation:      seat.OriginStation,
+			DestinationStation: seat.DestinationStation,
+		})
+	}
+	for _, flight := range r.Flights {
+		req.Flights = append(req.Flights, pricing.FlightQuoteRequest{
+			FlightID:      flight.FlightID,
+			DepartureDate: flight.DepartureDate,
+			SeatID:        flight.SeatID,
+		})
+	}
+	return req
+}
+
+// applyQuote copies the quoted price of every item and the total onto the request.
+// Quote lines are in the same order as the request items.
+func (r *CreateOrderRequest) applyQuote(quote *pricing.Quote) {
+	r.TotalPrice = quote.TotalPrice
+	r.Currency = quote.Currency
+	for i := range r.HotelRooms {
+		r.HotelRooms[i].Price = quote.HotelRooms[i].Price
+	}
+	for i := range r.Cars {
+		r.Cars[i].Price = quote.Cars[i].Price
+	}
+	for i := range r.TrainSeats {
+		r.TrainSeats[i].Price = quote.TrainSeats[i].Price
+	}
+	for i := range r.Flights {
+		r.Flights[i].Price = quote.Flights[i].Price
+	}
+}
+
+// participantItems returns the line items each participant is responsible for.
+// Participants without items are absent from the map. Payment always has a single
+// item, the order total.
+func (r *CreateOrderRequest) participantItems() map[string][]ParticipantItem {
+	items := make(map[string][]ParticipantItem)
+	for _, room := range r.HotelRooms {
+		items["hotel"] = append(items["hotel"], ParticipantItem{
+			Item:   fmt.Sprintf("%s:%s:%s", room.itemID(), room.StartDate, room.EndDate),
+			Price:  room.Price,
+			Status: "pending",
+		})
+	}
+	for _, car := range r.Cars {
+		items["car"] = append(items["car"], ParticipantItem{
+			Item:   fmt.Sprintf("%s:%s:%s", car.CarID, car.StartDate, car.EndDate),
+			Price:  car.Price,
+			Status: "pending",
+		})
+	}
+	for _, seat := range r.TrainSeats {
+		items["train"] = append(items["train"], ParticipantItem{
+			Item:   fmt.Sprintf("%s:%s:%s-%s", seat.JourneyID, seat.SeatID, seat.OriginStation, seat.DestinationStation),
+			Price:  seat.Price,
+			Status: "pending",
+		})
+	}
+	for _, flight := range r.Flights {
+		items["flight"] = append(items["flight"], ParticipantItem{
+			Item:   fmt.Sprintf("%s:%s", flight.FlightID, flight.SeatID),
+			Price:  flight.Price,
+			Status: "pending",
+		})
+	}
+	items["payment"] = []ParticipantItem{{
+		Item:   fmt.Sprintf("%d %s", r.TotalPrice, r.Currency),
+		Price:  r.TotalPrice,
+		Status: "pending",
+	}}
+	return items
+}
+
+// ModifyOrderRequest replaces the item at Index of one leg of a committed order. Exactly
+// one of HotelRoom, Car and TrainSeat must be set. The replacement is priced at current rates.
+// Flight is accepted only to refuse it: flight seats cannot be modified.
+type ModifyOrderRequest struct {
+	Index     int            `json:"index" binding:"min=0"`
+	HotelRoom *HotelRoomItem `json:"hotel_room"`
+	Car       *CarItem       `json:"car"`
+	TrainSeat *TrainSeatItem `json:"train_seat"`
+	Flight    *FlightItem    `json:"flight"`
+}
+
+// serviceName returns the participant that owns the modified leg, or an empty string
+// when not exactly one leg is set
+func (r *ModifyOrderRequest) serviceName() string {
+	var names []string
+	if r.HotelRoom != nil {
+		names = append(names, "hotel")
+	}
+	if r.Car != nil {
+		names = append(names, "car")
+	}
+	if r.TrainSeat != nil {
+		names = append(names, "train")
+	}
+	if len(names) != 1 {
+		return ""
+	}
+	return names[0]
+}
+
+// order returns the replacement item as a single item order, so that it is priced and
+// described the same way as the items of a booking
+func (r *ModifyOrderRequest) order() *CreateOrderRequest {
+	order := &CreateOrderRequest{}
+	if r.HotelRoom != nil {
+		order.HotelRooms = []HotelRoomItem{*r.HotelRoom}
+	}
+	if r.Car != nil {
+		order.Cars = []CarItem{*r.Car}
+	}
+	if r.TrainSeat != nil {
+		order.TrainSeats = []TrainSeatItem{*r.TrainSeat}
+	}
+	return order
 }
 
 // OrderResponse represents the response after order creation
@@ -61,6 +330,51 @@ type OrderResponse struct {
 	TransactionID string            `json:"transaction_id"`
 	Status        TransactionStatus `json:"status"`
 	Message       string            `json:"message"`
+	QuoteID       string            `json:"quote_id,omitempty"`
+	TotalPrice    int64             `json:"total_price"`
+	Currency      string            `json:"currency"`
+}
+
+// CancelOrderResponse represents the response after a cancellation is started
+type CancelOrderResponse struct {
+	OrderID              string            `json:"order_id"`
+	TransactionID        string            `json:"transaction_id"`
+	BookingTransactionID string            `json:"booking_transaction_id"`
+	Status               TransactionStatus `json:"status"`
+	Message              string            `json:"message"`
+	CancellationFee      int64             `json:"cancellation_fee"`
+	RefundAmount         int64             `json:"refund_amount"`
+	Currency             string            `json:"currency"`
+}
+
+// ModifyOrderResponse represents the response after a modification is started
+type ModifyOrderResponse struct {
+	OrderID              string            `json:"order_id"`
+	TransactionID        string            `json:"transaction_id"`
+	BookingTransactionID string            `json:"booking_transaction_id"`
+	Status               TransactionStatus `json:"status"`
+	Message              string            `json:"message"`
+	PriceDifference      int64             `json:"price_difference"`
+	TotalPrice           int64             `json:"total_price"`
+	Currency             string            `json:"currency"`
+}
+
+// CancellationPayload is the prepare payload of a cancellation transaction
+type CancellationPayload struct {
+	BookingTransactionID string `json:"booking_transaction_id"`
+	RefundAmount         int64  `json:"refund_amount"`
+}
+
+// ModificationPayload is the prepare payload of a modification transaction. Only the
+// item of the modified leg is set. PriceDifference is charged, or refunded when negative,
+// by the payment participant.
+type ModificationPayload struct {
+	BookingTransactionID string         `json:"booking_transaction_id"`
+	Index                int            `json:"index"`
+	PriceDifference      int64          `json:"price_difference"`
+	HotelRoom            *HotelRoomItem `json:"hotel_room,omitempty"`
+	Car                  *CarItem       `json:"car,omitempty"`
+	TrainSeat            *TrainSeatItem `json:"train_seat,omitempty"`
 }
 
 // PrepareRequest represents the prepare phase request
@@ -72,8 +386,9 @@ type PrepareRequest struct {
 
 // PrepareResponse represents the prepare phase response
 type PrepareResponse struct {
-	Success bool   `json:"success"`
-	Message string `json:"message"`
+	Success    bool   `json:"success"`
+	Message    string `json:"message"`
+	FailedItem *int   `json:"failed_item,omitempty"`
 }
 
 // CommitRequest represents the commit phase request
@@ -107,6 +422,7 @@ type AbortResponse struct {
 type TransactionStatusResponse struct {
 	TransactionID string            `json:"transaction_id"`
 	OrderID       string            `json:"order_id"`
+	Kind          TransactionKind   `json:"kind"`
 	Status        TransactionStatus `json:"status"`
 	Participants  []Participant     `json:"participants"`
 	CreatedAt     time.Time         `json:"created_at"`
@@ -114,6 +430,94 @@ type TransactionStatusResponse struct {
 	TimeoutAt     time.Time         `json:"timeout_at"`
 	RetryCount    int               `json:"retry_count"`
 	FailureReason string            `json:"failure_reason,omitempty"`
+	QuoteID       string            `json:"quote_id,omitempty"`
+	TotalPrice    int64             `json:"total_price"`
+	Currency      string            `json:"currency"`
+
+	BookingTransactionID string `json:"booking_transaction_id,omitempty"`
+	CancellationFee      int64  `json:"cancellation_fee,omitempty"`
+	RefundAmount         int64  `json:"refund_amount,omitempty"`
+	ItemIndex            int    `json:"item_index,omitempty"`
+	PriceDifference      int64  `json:"price_difference,omitempty"`
+}
+
+// WaitlistStatus represents the status of a waitlist entry
+type WaitlistStatus string
+
+const (
+	WaitlistStatusWaiting WaitlistStatus = "waiting"
+	// WaitlistStatusBooking means an order was started for the entry and its booking
+	// transaction has not finished yet
+	WaitlistStatusBooking   WaitlistStatus = "booking"
+	WaitlistStatusFulfilled WaitlistStatus = "fulfilled"
+	WaitlistStatusCancelled WaitlistStatus = "cancelled"
+)
+
+// WaitlistEntry is a user waiting for a single room, car or train seat to become
+// available. ItemKey identifies the item regardless of dates, so that the entry can be
+// found when a participant releases it.
+type WaitlistEntry struct {
+	ID            string         `firestore:"id" json:"id"`
+	UserID        string         `firestore:"user_id" json:"user_id"`
+	ItemKey       string         `firestore:"item_key" json:"-"`
+	HotelRoom     *HotelRoomItem `firestore:"hotel_room,omitempty" json:"hotel_room,omitempty"`
+	Car           *CarItem       `firestore:"car,omitempty" json:"car,omitempty"`
+	TrainSeat     *TrainSeatItem `firestore:"train_seat,omitempty" json:"train_seat,omitempty"`
+	Status        WaitlistStatus `firestore:"status" json:"status"`
+	OrderID       string         `firestore:"order_id,omitempty" json:"order_id,omitempty"`
+	TransactionID string         `firestore:"transaction_id,omitempty" json:"transaction_id,omitempty"`
+	CreatedAt     time.Time      `firestore:"created_at" json:"created_at"`
+	UpdatedAt     time.Time      `firestore:"updated_at" json:"updated_at"`
+}
+
+// order returns the item of the entry as a single item order for the user
+func (e *WaitlistEntry) order() *CreateOrderRequest {
+	order := &CreateOrderRequest{UserID: e.UserID}
+	if e.HotelRoom != nil {
+		order.HotelRooms = []HotelRoomItem{*e.HotelRoom}
+	}
+	if e.Car != nil {
+		order.Cars = []CarItem{*e.Car}
+	}
+	if e.TrainSeat != nil {
+		order.TrainSeats = []TrainSeatItem{*e.TrainSeat}
+	}
+	return order
+}
+
+// matches reports whether the entry wants dates released between startDate and
+// endDate. Train journeys depart on a single date, so seats always match.
+func (e *WaitlistEntry) matches(startDate, endDate string) bool {
+	switch {
+	case e.HotelRoom != nil:
+		return e.HotelRoom.StartDate <= endDate && startDate <= e.HotelRoom.EndDate
+	case e.Car != nil:
+		return e.Car.StartDate <= endDate && startDate <= e.Car.EndDate
+	}
+	return true
+}
+
+// waitlistItemKey returns the key of the item a participant reserves. seatID is only
+// used for train seats.
+func waitlistItemKey(serviceName, itemID, seatID string) string {
+	if serviceName == "train" {
+		return fmt.Sprintf("train:%s:%s", itemID, seatID)
+	}
+	return fmt.Sprintf("%s:%s", serviceName, itemID)
+}
+
+// WaitlistRequest registers a user for exactly one of HotelRoom, Car and TrainSeat
+type WaitlistRequest struct {
+	UserID    string         `json:"user_id" binding:"required"`
+	HotelRoom *HotelRoomItem `json:"hotel_room"`
+	Car       *CarItem       `json:"car"`
+	TrainSeat *TrainSeatItem `json:"train_seat"`
+}
+
+// serviceName returns the participant that owns the item, or an empty string when not
+// exactly one item is set
+func (r *WaitlistRequest) serviceName() string {
+	return (&ModifyOrderRequest{HotelRoom: r.HotelRoom, Car: r.Car, TrainSeat: r.TrainSeat}).serviceName()
 }
 
 // Config represents the coordinator configuration
@@ -122,18 +526,94 @@ type Config struct {
 	MaxRetries         int
 	RetryDelay         time.Duration
 	Services           map[string]string // service name -> service URL
+
+	// WaitlistInterval is how often released inventory is matched against the waitlist
+	WaitlistInterval time.Duration
+
+	// Cancellations within FreeCancellationWindow of the commit are free; later ones
+	// are charged CancellationFeePercent of the total price
+	FreeCancellationWindow time.Duration
+	CancellationFeePercent int64
+
+	// Transport sends the requests to the participants, nil uses http.DefaultTransport
+	Transport http.RoundTripper
 }
 
 // DefaultConfig returns default configuration
 func DefaultConfig() *Config {
 	return &Config{
-		TransactionTimeout: 30 * time.Second,
-		MaxRetries:         3,
-		RetryDelay:         2 * time.Second,
+		TransactionTimeout:     30 * time.Second,
+		MaxRetries:             3,
+		RetryDelay:             2 * time.Second,
+		FreeCancellationWindow: 24 * time.Hour,
+		CancellationFeePercent: 10,
+		WaitlistInterval:       5 * time.Second,
 		Services: map[string]string{
-			"hotel": "http://localhost:8081",
-			"car":   "http://localhost:8082",
-			"train": "http://localhost:8083",
+			"hotel":   "http://localhost:8081",
+			"car":     "http://localhost:8082",
+			"train":   "http://localhost:8083",
+			"payment": "http://localhost:8084",
+			"flight":  "http://localhost:8085",
 		},
 	}
 }
+
+// ConfigFromEnv returns the default configuration overridden by the environment
+// variables that are set
+func ConfigFromEnv() *Config {
+	config := DefaultConfig()
+
+	if timeout := os.Getenv("TRANSACTION_TIMEOUT"); timeout != "" {
+		if duration, err := time.ParseDuration(timeout); err == nil {
+			config.TransactionTimeout = duration
+		}
+	}
+
+	if maxRetries := os.Getenv("MAX_RETRIES"); maxRetries != "" {
+		if retries, err := time.ParseDuration(maxRetries); err == nil {
+			config.MaxRetries = int(retries)
+		}
+	}
+
+	if retryDelay := os.Getenv("RETRY_DELAY"); retryDelay != "" {
+		if delay, err := time.ParseDuration(retryDelay); err == nil {
+			config.RetryDelay = delay
+		}
+	}
+
+	// Override service URLs if provided
+	if hotelURL := os.Getenv("HOTEL_SERVICE_URL"); hotelURL != "" {
+		config.Services["hotel"] = hotelURL
+	}
+	if carURL := os.Getenv("CAR_SERVICE_URL"); carURL != "" {
+		config.Services["car"] = carURL
+	}
+	if trainURL := os.Getenv("TRAIN_SERVICE_URL"); trainURL != "" {
+		config.Services["train"] = trainURL
+	}
+	if flightURL := os.Getenv("FLIGHT_SERVICE_URL"); flightURL != "" {
+		config.Services["flight"] = flightURL
+	}
+	if paymentURL := os.Getenv("PAYMENT_SERVICE_URL"); paymentURL != "" {
+		config.Services["payment"] = paymentURL
+	}
+
+	if interval := os.Getenv("WAITLIST_INTERVAL"); interval != "" {
+		if duration, err := time.ParseDuration(interval); err == nil {
+			config.WaitlistInterval = duration
+		}
+	}
+
+	if window := os.Getenv("FREE_CANCELLATION_WINDOW"); window != "" {
+		if duration, err := time.ParseDuration(window); err == nil {
+			config.FreeCancellationWindow = duration
+		}
+	}
+	if feePercent := os.Getenv("CANCELLATION_FEE_PERCENT"); feePercent != "" {
+		if percent, err := strconv.ParseInt(feePercent, 10, 64); err == nil {
+			config.CancellationFeePercent = percent
+		}
+	}
+
+	return config
+}
@@ -3,9 +3,11 @@ package coordinator
 import (
 	"context"
 	"fmt"
+	"sort"
 	"time"
 
 	"cloud.google.com/go/firestore"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/api"
 	"google.golang.org/api/iterator"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -93,6 +95,16 @@ func (r *Repository) UpdateParticipantStatus(ctx context.Context, transactionID,
 		if participant.ServiceName == serviceName {
 			log.Participants[i].Status = status
 			log.Participants[i].Error = errorMsg
+			for j := range log.Participants[i].Items {
+				item := &log.Participants[i].Items[j]
+				if status == "prepared" || status == "committed" {
+					item.Error = ""
+				} else if item.Error != "" {
+					// Keep the status of the item that caused a prepare failure
+					continue
+				}
+				item.Status = status
+			}
 			if status == "failed" {
 				log.Participants[i].RetryCount++
 			}
@@ -114,6 +126,39 @@ func (r *Repository) UpdateParticipantStatus(ctx context.Context, transactionID,
 	return nil
 }
 
+// UpdateParticipantItemError marks a single item of a participant as failed
+func (r *Repository) UpdateParticipantItemError(ctx context.Context, transactionID, serviceName string, itemIndex int, errorMsg string) error {
+	collection := r.client.Collection("twophase_transactions")
+	doc := collection.Doc(transactionID)
+
+	docSnap, err := doc.Get(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get transaction log: %w", err)
+	}
+
+	var log TransactionLog
+	if err := docSnap.DataTo(&log); err != nil {
+		return fmt.Errorf("failed to unmarshal transaction log: %w", err)
+	}
+
+	for i, participant := range log.Participants {
+		if participant.ServiceName == serviceName && itemIndex >= 0 && itemIndex < len(participant.Items) {
+			log.Participants[i].Items[itemIndex].Status = "failed"
+			log.Participants[i].Items[itemIndex].Error = errorMsg
+			break
+		}
+	}
+
+	log.UpdatedAt = time.Now()
+
+	_, err = doc.Set(ctx, log)
+	if err != nil {
+		return fmt.Errorf("failed to update participant item: %w", err)
+	}
+
+	return nil
+}
+
 // GetTimedOutTransactions retrieves transactions that have timed out
 func (r *Repository) GetTimedOutTransactions(ctx context.Context) ([]*TransactionLog, error) {
 	collection := r.client.Collection("twophase_transactions")
@@ -176,6 +221,47 @@ func (r *Repository) GetPendingTransactions(ctx context.Context) ([]*Transaction
 	return logs, nil
 }
 
+// ListTransactionLogs retrieves up to limit transaction logs with the given status, or
+// with any status when status is empty, newest first. The query has no OrderBy so the
+// status filter does not need a composite index, which means the limit does not always
+// keep the newest logs.
+func (r *Repository) ListTransactionLogs(ctx context.Context, status TransactionStatus, limit int) ([]*TransactionLog, error) {
+	query := r.client.Collection("twophase_transactions").Query
+	if status != "" {
+		query = query.Where("status", "==", string(status))
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+
+	var logs []*TransactionLog
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate transaction logs: %w", err)
+		}
+
+		var log TransactionLog
+		if err := doc.DataTo(&log); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal transaction log: %w", err)
+		}
+
+		logs = append(logs, &log)
+	}
+
+	sort.Slice(logs, func(i, j int) bool {
+		return logs[i].CreatedAt.After(logs[j].CreatedAt)
+	})
+
+	return logs, nil
+}
+
 // DeleteTransactionLog deletes a transaction log (for cleanup purposes)
 func (r *Repository) DeleteTransactionLog(ctx context.Context, transactionID string) error {
 	collection := r.client.Collection("twophase_transactions")
@@ -218,3 +304,146 @@ func (r *Repository) GetTransactionLogsByOrderID(ctx context.Context, orderID st
 
 	return logs, nil
 }
+
+// CreateOrderTransactionLog creates a cancellation or modification log of an existing
+// order. check receives every transaction log of the order read in the same Firestore
+// transaction as the create, so two concurrent requests cannot both pass it.
+func (r *Repository) CreateOrderTransactionLog(ctx context.Context, log *TransactionLog, check func(logs []*TransactionLog) error) error {
+	collection := r.client.Collection("twophase_transactions")
+
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		docs, err := tx.Documents(collection.Where("order_id", "==", log.OrderID)).GetAll()
+		if err != nil {
+			return fmt.Errorf("failed to get transaction logs: %w", err)
+		}
+
+		logs := make([]*TransactionLog, 0, len(docs))
+		for _, doc := range docs {
+			var existing TransactionLog
+			if err := doc.DataTo(&existing); err != nil {
+				return fmt.Errorf("failed to unmarshal transaction log: %w", err)
+			}
+			logs = append(logs, &existing)
+		}
+		if err := check(logs); err != nil {
+			return err
+		}
+
+		return tx.Create(collection.Doc(log.ID), log)
+	})
+}
+
+// CreateWaitlistEntry creates a new waitlist entry
+func (r *Repository) CreateWaitlistEntry(ctx context.Context, entry *WaitlistEntry) error {
+	_, err := r.client.Collection("twophase_waitlist_entries").Doc(entry.ID).Create(ctx, entry)
+	if err != nil {
+		return fmt.Errorf("failed to create waitlist entry: %w", err)
+	}
+
+	return nil
+}
+
+// GetWaitlistEntry retrieves a waitlist entry by ID
+func (r *Repository) GetWaitlistEntry(ctx context.Context, entryID string) (*WaitlistEntry, error) {
+	docSnap, err := r.client.Collection("twophase_waitlist_entries").Doc(entryID).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, ErrWaitlistEntryNotFound
+		}
+		return nil, fmt.Errorf("failed to get waitlist entry: %w", err)
+	}
+
+	var entry WaitlistEntry
+	if err := docSnap.DataTo(&entry); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal waitlist entry: %w", err)
+	}
+
+	return &entry, nil
+}
+
+// UpdateWaitlistEntry updates an existing waitlist entry
+func (r *Repository) UpdateWaitlistEntry(ctx context.Context, entry *WaitlistEntry) error {
+	entry.UpdatedAt = time.Now()
+
+	_, err := r.client.Collection("twophase_waitlist_entries").Doc(entry.ID).Set(ctx, entry)
+	if err != nil {
+		return fmt.Errorf("failed to update waitlist entry: %w", err)
+	}
+
+	return nil
+}
+
+// GetPendingWaitlistEntries retrieves the waiting and booking entries of an item, oldest first
+func (r *Repository) GetPendingWaitlistEntries(ctx context.Context, itemKey string) ([]*WaitlistEntry, error) {
+	query := r.client.Collection("twophase_waitlist_entries").
+		Where("item_key", "==", itemKey).
+		Where("status", "in", []string{string(WaitlistStatusWaiting), string(WaitlistStatusBooking)}).
+		OrderBy("created_at", firestore.Asc)
+
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+
+	var entries []*WaitlistEntry
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate waitlist entries: %w", err)
+		}
+
+		var entry WaitlistEntry
+		if err := doc.DataTo(&entry); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal waitlist entry: %w", err)
+		}
+
+		entries = append(entries, &entry)
+	}
+
+	return entries, nil
+}
+
+// GetUnprocessedReleases retrieves up to limit releases written by the participants
+// that the waitlist has not handled yet, oldest first
+func (r *Repository) GetUnprocessedReleases(ctx context.Context, limit int) ([]*api.InventoryRelease, error) {
+	query := r.client.Collection(api.InventoryReleaseCollection).
+		Where("processed", "==", false).
+		OrderBy("created_at", firestore.Asc).
+		Limit(limit)
+
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+
+	var releases []*api.InventoryRelease
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate releases: %w", err)
+		}
+
+		var release api.InventoryRelease
+		if err := doc.DataTo(&release); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal release: %w", err)
+		}
+
+		releases = append(releases, &release)
+	}
+
+	return releases, nil
+}
+
+// MarkReleaseProcessed marks a release as handled by the waitlist
+func (r *Repository) MarkReleaseProcessed(ctx context.Context, releaseID string) error {
+	_, err := r.client.Collection(api.InventoryReleaseCollection).Doc(releaseID).Update(ctx, []firestore.Update{
+		{Path: "processed", Value: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to mark release processed: %w", err)
+	}
+
+	return nil
+}
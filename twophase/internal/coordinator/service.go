@@ -4,28 +4,56 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"net/http"
 	"time"
 
 	"github.com/oklog/ulid/v2"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/internal/pricing"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/api"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/config"
 )
 
+var (
+	ErrOrderNotFound       = errors.New("order not found")
+	ErrOrderNotCancellable = errors.New("only committed orders that are not being cancelled can be cancelled")
+	ErrOrderNotModifiable  = errors.New("only committed orders that are not being cancelled or modified can be modified")
+	ErrInvalidModification = errors.New("modification must replace exactly one hotel room, car or train seat")
+	ErrItemNotFound        = errors.New("order has no item at the given index")
+	ErrFlightNotModifiable = errors.New("flight seats cannot be modified, cancel the order and book again")
+
+	ErrInvalidWaitlistEntry        = errors.New("waitlist entry must contain exactly one hotel room, car or train seat")
+	ErrWaitlistEntryNotFound       = errors.New("waitlist entry not found")
+	ErrWaitlistEntryNotCancellable = errors.New("only waiting entries can be cancelled")
+
+	ErrTransactionNotCommittable = errors.New("only prepared transactions whose participants are all prepared can be committed")
+	ErrTransactionNotAbortable   = errors.New("only initiated, prepared or timed out transactions can be aborted")
+	ErrTransactionCommitFailed   = errors.New("commit phase failed, transaction rolled back")
+)
+
+// participantOrder is the order in which participants are prepared and committed.
+// Payment is last so that funds are only held once every reservation is prepared.
+var participantOrder = []string{"hotel", "car", "train", "flight", "payment"}
+
 // Service handles the two-phase commit coordination logic
 type Service struct {
-	repo   *Repository
-	config *Config
-	client *http.Client
+	repo    *Repository
+	pricing *pricing.Service
+	config  *Config
+	client  *http.Client
 }
 
 // NewService creates a new coordinator service
-func NewService(repo *Repository, config *Config) *Service {
+func NewService(repo *Repository, pricing *pricing.Service, config *Config) *Service {
 	return &Service{
-		repo:   repo,
-		config: config,
+		repo:    repo,
+		pricing: pricing,
+		config:  config,
 		client: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout:   10 * time.Second,
+			Transport: config.Transport,
 		},
 	}
 }
@@ -36,21 +64,48 @@ func (s *Service) CreateOrder(ctx context.Context, req *CreateOrderRequest) (*Or
 	transactionID := ulid.Make().String()
 	orderID := ulid.Make().String()
 
+	// Lock the price of every item, from the quote if one is given
+	quote, err := s.priceOrder(ctx, orderID, req)
+	if err != nil {
+		return nil, err
+	}
+	req.applyQuote(quote)
+
+	// Only enlist the participants that have items to reserve
+	items := req.participantItems()
+	var participants []Participant
+	for _, serviceName := range participantOrder {
+		if len(items[serviceName]) == 0 {
+			continue
+		}
+		participants = append(participants, Participant{
+			ServiceName: serviceName,
+			ServiceURL:  s.config.Services[serviceName],
+			Status:      "pending",
+			Items:       items[serviceName],
+		})
+	}
+
 	// Create transaction log
 	log := &TransactionLog{
-		ID:         transactionID,
-		OrderID:    orderID,
-		Status:     StatusInitiated,
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
-		TimeoutAt:  time.Now().Add(s.config.TransactionTimeout),
-		RetryCount: 0,
-		MaxRetries: s.config.MaxRetries,
-		Participants: []Participant{
-			{ServiceName: "hotel", ServiceURL: s.config.Services["hotel"], Status: "pending"},
-			{ServiceName: "car", ServiceURL: s.config.Services["car"], Status: "pending"},
-			{ServiceName: "train", ServiceURL: s.config.Services["train"], Status: "pending"},
-		},
+		ID:           transactionID,
+		OrderID:      orderID,
+		Kind:         KindBooking,
+		Status:       StatusInitiated,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+		TimeoutAt:    time.Now().Add(s.config.TransactionTimeout),
+		RetryCount:   0,
+		MaxRetries:   s.config.MaxRetries,
+		Participants: participants,
+		QuoteID:      req.QuoteID,
+		TotalPrice:   quote.TotalPrice,
+		Currency:     quote.Currency,
+		UserID:       req.UserID,
+		HotelRooms:   req.HotelRooms,
+		Cars:         req.Cars,
+		TrainSeats:   req.TrainSeats,
+		Flights:      req.Flights,
 	}
 
 	// Save transaction log
@@ -66,13 +121,268 @@ func (s *Service) CreateOrder(ctx context.Context, req *CreateOrderRequest) (*Or
 		TransactionID: transactionID,
 		Status:        StatusInitiated,
 		Message:       "Transaction initiated successfully",
+		QuoteID:       req.QuoteID,
+		TotalPrice:    quote.TotalPrice,
+		Currency:      quote.Currency,
+	}, nil
+}
+
+// cancellationFee applies the cancellation policy to a booking cancelled at now. The free
+// window includes its end, and bookings without a commit timestamp are always charged.
+func cancellationFee(booking *TransactionLog, config *Config, now time.Time) int64 {
+	if booking.CommitTimestamp != nil && !now.After(booking.CommitTimestamp.Add(config.FreeCancellationWindow)) {
+		return 0
+	}
+	return booking.TotalPrice * config.CancellationFeePercent / 100
+}
+
+// CancelOrder starts a cancellation transaction that releases everything reserved by
+// the committed booking of orderID in all its participants and refunds the payment
+// minus the cancellation fee
+func (s *Service) CancelOrder(ctx context.Context, orderID string) (*CancelOrderResponse, error) {
+	booking, err := s.committedBooking(ctx, orderID, ErrOrderNotCancellable)
+	if err != nil {
+		return nil, err
+	}
+
+	fee := cancellationFee(booking, s.config, time.Now())
+	refundAmount := booking.TotalPrice - fee
+
+	var participants []Participant
+	for _, participant := range booking.Participants {
+		items := make([]ParticipantItem, 0, len(participant.Items))
+		for _, item := range participant.Items {
+			items = append(items, ParticipantItem{Item: item.Item, Status: "pending"})
+		}
+		participants = append(participants, Participant{
+			ServiceName: participant.ServiceName,
+			ServiceURL:  s.config.Services[participant.ServiceName],
+			Status:      "pending",
+			Items:       items,
+		})
+	}
+
+	log := &TransactionLog{
+		ID:                   ulid.Make().String(),
+		OrderID:              orderID,
+		Kind:                 KindCancellation,
+		Status:               StatusInitiated,
+		CreatedAt:            time.Now(),
+		UpdatedAt:            time.Now(),
+		TimeoutAt:            time.Now().Add(s.config.TransactionTimeout),
+		MaxRetries:           s.config.MaxRetries,
+		Participants:         participants,
+		TotalPrice:           booking.TotalPrice,
+		Currency:             booking.Currency,
+		BookingTransactionID: booking.ID,
+		CancellationFee:      fee,
+		RefundAmount:         refundAmount,
+	}
+
+	if err := s.createOrderTransactionLog(ctx, log, ErrOrderNotCancellable); err != nil {
+		return nil, err
+	}
+
+	go s.executeTwoPhaseCommit(context.Background(), log.ID, &CancellationPayload{
+		BookingTransactionID: booking.ID,
+		RefundAmount:         refundAmount,
+	})
+
+	return &CancelOrderResponse{
+		OrderID:              orderID,
+		TransactionID:        log.ID,
+		BookingTransactionID: booking.ID,
+		Status:               StatusInitiated,
+		Message:              "Cancellation initiated successfully",
+		CancellationFee:      fee,
+		RefundAmount:         refundAmount,
+		Currency:             booking.Currency,
+	}, nil
+}
+
+// ModifyOrder starts a modification transaction that replaces one item of the committed
+// booking of orderID. The participant owning the item reserves the replacement before
+// releasing the original item, so the booking is left untouched when the transaction
+// aborts. When the price changes, the payment participant takes part too and charges or
+// refunds the difference.
+func (s *Service) ModifyOrder(ctx context.Context, orderID string, req *ModifyOrderRequest) (*ModifyOrderResponse, error) {
+	if req.Flight != nil {
+		return nil, ErrFlightNotModifiable
+	}
+
+	serviceName := req.serviceName()
+	if serviceName == "" {
+		return nil, ErrInvalidModification
+	}
+
+	booking, err := s.committedBooking(ctx, orderID, ErrOrderNotModifiable)
+	if err != nil {
+		return nil, err
+	}
+
+	var bookedItems []ParticipantItem
+	for _, participant := range booking.Participants {
+		if participant.ServiceName == serviceName {
+			bookedItems = participant.Items
+		}
+	}
+	if req.Index >= len(bookedItems) {
+		return nil, ErrItemNotFound
+	}
+
+	order := req.order()
+	quote, err := s.pricing.Price(ctx, order.quoteRequest())
+	if err != nil {
+		return nil, err
+	}
+	order.applyQuote(quote)
+
+	item := order.participantItems()[serviceName][0]
+	priceDifference := item.Price - bookedItems[req.Index].Price
+
+	// The modified leg must stay the first participant, the booking is updated from it
+	participants := []Participant{{
+		ServiceName: serviceName,
+		ServiceURL:  s.config.Services[serviceName],
+		Status:      "pending",
+		Items:       []ParticipantItem{item},
+	}}
+	if priceDifference != 0 {
+		participants = append(participants, Participant{
+			ServiceName: "payment",
+			ServiceURL:  s.config.Services["payment"],
+			Status:      "pending",
+			Items: []ParticipantItem{{
+				Item:   fmt.Sprintf("%d %s", priceDifference, booking.Currency),
+				Price:  priceDifference,
+				Status: "pending",
+			}},
+		})
+	}
+
+	log := &TransactionLog{
+		ID:                   ulid.Make().String(),
+		OrderID:              orderID,
+		Kind:                 KindModification,
+		Status:               StatusInitiated,
+		CreatedAt:            time.Now(),
+		UpdatedAt:            time.Now(),
+		TimeoutAt:            time.Now().Add(s.config.TransactionTimeout),
+		MaxRetries:           s.config.MaxRetries,
+		Participants:         participants,
+		TotalPrice:           booking.TotalPrice + priceDifference,
+		Currency:             booking.Currency,
+		BookingTransactionID: booking.ID,
+		ItemIndex:            req.Index,
+		PriceDifference:      priceDifference,
+	}
+
+	if err := s.createOrderTransactionLog(ctx, log, ErrOrderNotModifiable); err != nil {
+		return nil, err
+	}
+
+	payload := &ModificationPayload{
+		BookingTransactionID: booking.ID,
+		Index:                req.Index,
+		PriceDifference:      priceDifference,
+	}
+	switch serviceName {
+	case "hotel":
+		payload.HotelRoom = &order.HotelRooms[0]
+	case "car":
+		payload.Car = &order.Cars[0]
+	case "train":
+		payload.TrainSeat = &order.TrainSeats[0]
+	}
+
+	go s.executeTwoPhaseCommit(context.Background(), log.ID, payload)
+
+	return &ModifyOrderResponse{
+		OrderID:              orderID,
+		TransactionID:        log.ID,
+		BookingTransactionID: booking.ID,
+		Status:               StatusInitiated,
+		Message:              "Modification initiated successfully",
+		PriceDifference:      priceDifference,
+		TotalPrice:           log.TotalPrice,
+		Currency:             log.Currency,
 	}, nil
 }
 
-// executeTwoPhaseCommit executes the two-phase commit protocol
-func (s *Service) executeTwoPhaseCommit(ctx context.Context, transactionID string, req *CreateOrderRequest) {
+// committedBooking returns the committed booking transaction of orderID. errNotAllowed
+// is returned when the booking is not committed, when a cancellation or modification
+// of the order is in progress, or when the order has been cancelled.
+func (s *Service) committedBooking(ctx context.Context, orderID string, errNotAllowed error) (*TransactionLog, error) {
+	logs, err := s.repo.GetTransactionLogsByOrderID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	return findCommittedBooking(logs, errNotAllowed)
+}
+
+// createOrderTransactionLog creates the cancellation or modification log of a booking.
+// The order is checked again in the same Firestore transaction, so errNotAllowed is
+// returned when another cancellation or modification started after committedBooking.
+func (s *Service) createOrderTransactionLog(ctx context.Context, log *TransactionLog, errNotAllowed error) error {
+	err := s.repo.CreateOrderTransactionLog(ctx, log, func(logs []*TransactionLog) error {
+		booking, err := findCommittedBooking(logs, errNotAllowed)
+		if err != nil {
+			return err
+		}
+		if booking.ID != log.BookingTransactionID {
+			return errNotAllowed
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create transaction log: %w", err)
+	}
+	return nil
+}
+
+// findCommittedBooking returns the committed booking among the transaction logs of one
+// order, see committedBooking
+func findCommittedBooking(logs []*TransactionLog, errNotAllowed error) (*TransactionLog, error) {
+	var booking *TransactionLog
+	for _, log := range logs {
+		if log.isBooking() {
+			booking = log
+			continue
+		}
+		// Only one cancellation or modification may be in progress at a time
+		switch log.Status {
+		case StatusInitiated, StatusPrepared:
+			return nil, errNotAllowed
+		case StatusCommitted:
+			if log.isCancellation() {
+				return nil, errNotAllowed
+			}
+		}
+	}
+	if booking == nil {
+		return nil, ErrOrderNotFound
+	}
+	if booking.Status != StatusCommitted {
+		return nil, errNotAllowed
+	}
+
+	return booking, nil
+}
+
+// priceOrder prices the order items. A given quote is redeemed for orderID so
+// that no other order can use it; otherwise the items are priced at current rates.
+func (s *Service) priceOrder(ctx context.Context, orderID string, req *CreateOrderRequest) (*pricing.Quote, error) {
+	if req.QuoteID != "" {
+		return s.pricing.RedeemQuote(ctx, req.QuoteID, orderID, req.quoteRequest())
+	}
+	return s.pricing.Price(ctx, req.quoteRequest())
+}
+
+// executeTwoPhaseCommit executes the two-phase commit protocol. payload is sent
+// to every participant in the prepare phase.
+func (s *Service) executeTwoPhaseCommit(ctx context.Context, transactionID string, payload any) {
 	// Phase 1: Prepare
-	if !s.preparePhase(ctx, transactionID, req) {
+	if !s.preparePhase(ctx, transactionID, payload) {
 		s.abortTransaction(ctx, transactionID, "Prepare phase failed")
 		return
 	}
@@ -88,7 +398,7 @@ func (s *Service) executeTwoPhaseCommit(ctx context.Context, transactionID strin
 }
 
 // preparePhase executes the prepare phase
-func (s *Service) preparePhase(ctx context.Context, transactionID string, req *CreateOrderRequest) bool {
+func (s *Service) preparePhase(ctx context.Context, transactionID string, payload any) bool {
 	log, err := s.repo.GetTransactionLog(ctx, transactionID)
 	if err != nil {
 		return false
@@ -105,12 +415,12 @@ func (s *Service) preparePhase(ctx context.Context, transactionID string, req *C
 	prepareReq := &PrepareRequest{
 		TransactionID: transactionID,
 		OrderID:       log.OrderID,
-		Payload:       req,
+		Payload:       payload,
 	}
 
 	allPrepared := true
 	for _, participant := range log.Participants {
-		if !s.sendPrepareRequest(ctx, transactionID, participant.ServiceName, prepareReq) {
+		if !s.sendPrepareRequest(ctx, transactionID, participant.ServiceName, log.phasePath(), prepareReq) {
 			allPrepared = false
 			break
 		}
@@ -134,7 +444,7 @@ func (s *Service) commitPhase(ctx context.Context, transactionID string) bool {
 
 	allCommitted := true
 	for _, participant := range log.Participants {
-		if !s.sendCommitRequest(ctx, transactionID, participant.ServiceName, commitReq) {
+		if !s.sendCommitRequest(ctx, transactionID, participant.ServiceName, log.phasePath(), commitReq) {
 			allCommitted = false
 			break
 		}
@@ -144,17 +454,17 @@ func (s *Service) commitPhase(ctx context.Context, transactionID string) bool {
 }
 
 // sendPrepareRequest sends prepare request to a participant with retry logic
-func (s *Service) sendPrepareRequest(ctx context.Context, transactionID, serviceName string, req *PrepareRequest) bool {
+func (s *Service) sendPrepareRequest(ctx context.Context, transactionID, serviceName, phasePath string, req *PrepareRequest) bool {
 	serviceURL := s.config.Services[serviceName]
-	url := fmt.Sprintf("%s/twophase/prepare", serviceURL)
+	url := fmt.Sprintf("%s%s/prepare", serviceURL, phasePath)
 
 	return s.sendRequestWithRetry(ctx, transactionID, serviceName, url, req, "prepare")
 }
 
 // sendCommitRequest sends commit request to a participant with retry logic
-func (s *Service) sendCommitRequest(ctx context.Context, transactionID, serviceName string, req *CommitRequest) bool {
+func (s *Service) sendCommitRequest(ctx context.Context, transactionID, serviceName, phasePath string, req *CommitRequest) bool {
 	serviceURL := s.config.Services[serviceName]
-	url := fmt.Sprintf("%s/twophase/commit", serviceURL)
+	url := fmt.Sprintf("%s%s/commit", serviceURL, phasePath)
 
 	return s.sendRequestWithRetry(ctx, transactionID, serviceName, url, req, "commit")
 }
@@ -212,6 +522,14 @@ func (s *Service) sendSingleRequest(ctx context.Context, transactionID, serviceN
 		return true
 	}
 
+	// Record which item made the participant refuse to prepare
+	if operation == "prepare" {
+		var prepareResp PrepareResponse
+		if err := json.NewDecoder(resp.Body).Decode(&prepareResp); err == nil && prepareResp.FailedItem != nil {
+			s.repo.UpdateParticipantItemError(ctx, transactionID, serviceName, *prepareResp.FailedItem, prepareResp.Message)
+		}
+	}
+
 	return false
 }
 
@@ -230,7 +548,7 @@ func (s *Service) abortTransaction(ctx context.Context, transactionID, reason st
 	}
 
 	for _, participant := range log.Participants {
-		s.sendAbortRequest(ctx, transactionID, participant.ServiceName, abortReq)
+		s.sendAbortRequest(ctx, transactionID, participant.ServiceName, log.phasePath(), abortReq)
 	}
 
 	s.finalizeTransaction(ctx, transactionID, StatusAborted, reason)
@@ -242,9 +560,9 @@ func (s *Service) rollbackTransaction(ctx context.Context, transactionID, reason
 }
 
 // sendAbortRequest sends abort request to a participant
-func (s *Service) sendAbortRequest(ctx context.Context, transactionID, serviceName string, req *AbortRequest) {
+func (s *Service) sendAbortRequest(ctx context.Context, transactionID, serviceName, phasePath string, req *AbortRequest) {
 	serviceURL := s.config.Services[serviceName]
-	url := fmt.Sprintf("%s/twophase/abort", serviceURL)
+	url := fmt.Sprintf("%s%s/abort", serviceURL, phasePath)
 
 	jsonData, err := json.Marshal(req)
 	if err != nil {
@@ -285,7 +603,37 @@ func (s *Service) finalizeTransaction(ctx context.Context, transactionID string,
 		log.CommitTimestamp = &now
 	}
 
-	s.repo.UpdateTransactionLog(ctx, log)
+	if err := s.repo.UpdateTransactionLog(ctx, log); err != nil {
+		return
+	}
+
+	// A committed cancellation releases the booking it cancels
+	if status == StatusCommitted && log.isCancellation() {
+		booking, err := s.repo.GetTransactionLog(ctx, log.BookingTransactionID)
+		if err != nil {
+			return
+		}
+		booking.Status = StatusCancelled
+		booking.UpdatedAt = time.Now()
+		s.repo.UpdateTransactionLog(ctx, booking)
+	}
+
+	// A committed modification replaces the item and the total price of the booking
+	if status == StatusCommitted && log.isModification() {
+		booking, err := s.repo.GetTransactionLog(ctx, log.BookingTransactionID)
+		if err != nil {
+			return
+		}
+		modified := log.Participants[0]
+		for i, participant := range booking.Participants {
+			if participant.ServiceName == modified.ServiceName && log.ItemIndex < len(participant.Items) {
+				booking.Participants[i].Items[log.ItemIndex] = modified.Items[0]
+			}
+		}
+		booking.TotalPrice = log.TotalPrice
+		booking.UpdatedAt = time.Now()
+		s.repo.UpdateTransactionLog(ctx, booking)
+	}
 }
 
 // GetTransactionStatus retrieves the status of a transaction
@@ -298,6 +646,7 @@ func (s *Service) GetTransactionStatus(ctx context.Context, transactionID string
 	return &TransactionStatusResponse{
 		TransactionID: log.ID,
 		OrderID:       log.OrderID,
+		Kind:          log.Kind,
 		Status:        log.Status,
 		Participants:  log.Participants,
 		CreatedAt:     log.CreatedAt,
@@ -305,6 +654,15 @@ func (s *Service) GetTransactionStatus(ctx context.Context, transactionID string
 		TimeoutAt:     log.TimeoutAt,
 		RetryCount:    log.RetryCount,
 		FailureReason: log.FailureReason,
+		QuoteID:       log.QuoteID,
+		TotalPrice:    log.TotalPrice,
+		Currency:      log.Currency,
+
+		BookingTransactionID: log.BookingTransactionID,
+		CancellationFee:      log.CancellationFee,
+		RefundAmount:         log.RefundAmount,
+		ItemIndex:            log.ItemIndex,
+		PriceDifference:      log.PriceDifference,
 	}, nil
 }
 
@@ -332,9 +690,247 @@ func (s *Service) CleanupTimedOutTransactions(ctx context.Context) error {
 		}
 
 		for _, participant := range log.Participants {
-			s.sendAbortRequest(ctx, log.ID, participant.ServiceName, abortReq)
+			s.sendAbortRequest(ctx, log.ID, participant.ServiceName, log.phasePath(), abortReq)
 		}
 	}
 
 	return nil
 }
+
+// CommitTransaction lets an operator finish a transaction that is stuck in the prepared
+// state, for example after the coordinator restarted between the two phases. The commit
+// request is only sent to the participants that have not committed yet. Like
+// executeTwoPhaseCommit, a failed commit phase rolls the transaction back.
+func (s *Service) CommitTransaction(ctx context.Context, transactionID string) error {
+	log, err := s.repo.GetTransactionLog(ctx, transactionID)
+	if err != nil {
+		return err
+	}
+	if log.Status != StatusPrepared {
+		return ErrTransactionNotCommittable
+	}
+	for _, participant := range log.Participants {
+		if participant.Status != "prepared" && participant.Status != "committed" {
+			return ErrTransactionNotCommittable
+		}
+	}
+
+	commitReq := &CommitRequest{
+		TransactionID: transactionID,
+		OrderID:       log.OrderID,
+	}
+	for _, participant := range log.Participants {
+		if participant.Status == "committed" {
+			continue
+		}
+		if !s.sendCommitRequest(ctx, transactionID, participant.ServiceName, log.phasePath(), commitReq) {
+			s.rollbackTransaction(ctx, transactionID, "Commit by operator failed")
+			return ErrTransactionCommitFailed
+		}
+	}
+
+	s.finalizeTransaction(ctx, transactionID, StatusCommitted, "")
+	return nil
+}
+
+// AbortTransaction lets an operator abort a transaction that has not committed. Timed
+// out transactions can be aborted again when a participant missed the abort request
+// sent by CleanupTimedOutTransactions.
+func (s *Service) AbortTransaction(ctx context.Context, transactionID string) error {
+	log, err := s.repo.GetTransactionLog(ctx, transactionID)
+	if err != nil {
+		return err
+	}
+	switch log.Status {
+	case StatusInitiated, StatusPrepared, StatusTimedOut:
+	default:
+		return ErrTransactionNotAbortable
+	}
+
+	s.abortTransaction(ctx, transactionID, "Aborted by operator")
+	return nil
+}
+
+// JoinWaitlist registers a user for a room, car or train seat that is not available.
+// The dates are validated up front, as the order is only placed once the item is released.
+func (s *Service) JoinWaitlist(ctx context.Context, req *WaitlistRequest) (*WaitlistEntry, error) {
+	entry := &WaitlistEntry{
+		ID:        ulid.Make().String(),
+		UserID:    req.UserID,
+		HotelRoom: req.HotelRoom,
+		Car:       req.Car,
+		TrainSeat: req.TrainSeat,
+		Status:    WaitlistStatusWaiting,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	var dates []string
+	switch req.serviceName() {
+	case "hotel":
+		entry.ItemKey = waitlistItemKey("hotel", req.HotelRoom.HotelRoomID, "")
+		if req.HotelRoom.RoomTypeID != "" {
+			entry.ItemKey = waitlistItemKey("hotel_room_type", req.HotelRoom.RoomTypeID, "")
+		}
+		dates = []string{req.HotelRoom.StartDate, req.HotelRoom.EndDate}
+	case "car":
+		entry.ItemKey = waitlistItemKey("car", req.Car.CarID, "")
+		dates = []string{req.Car.StartDate, req.Car.EndDate}
+	case "train":
+		entry.ItemKey = waitlistItemKey("train", req.TrainSeat.JourneyID, req.TrainSeat.SeatID)
+		dates = []string{req.TrainSeat.DepartureDate}
+	default:
+		return nil, ErrInvalidWaitlistEntry
+	}
+
+	for _, date := range dates {
+		if _, err := time.Parse(config.DateFormat, date); err != nil {
+			return nil, fmt.Errorf("%w: invalid date %q", ErrInvalidWaitlistEntry, date)
+		}
+	}
+	if len(dates) == 2 && dates[1] < dates[0] {
+		return nil, fmt.Errorf("%w: end date is before start date", ErrInvalidWaitlistEntry)
+	}
+
+	if err := s.repo.CreateWaitlistEntry(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// GetWaitlistEntry retrieves a waitlist entry, refreshing its status from the booking
+// transaction when an order was started for it
+func (s *Service) GetWaitlistEntry(ctx context.Context, entryID string) (*WaitlistEntry, error) {
+	entry, err := s.repo.GetWaitlistEntry(ctx, entryID)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.Status == WaitlistStatusBooking {
+		if err := s.reconcileWaitlistEntry(ctx, entry); err != nil {
+			return nil, err
+		}
+	}
+
+	return entry, nil
+}
+
+// LeaveWaitlist cancels a waiting entry
+func (s *Service) LeaveWaitlist(ctx context.Context, entryID string) (*WaitlistEntry, error) {
+	entry, err := s.repo.GetWaitlistEntry(ctx, entryID)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.Status != WaitlistStatusWaiting {
+		return nil, ErrWaitlistEntryNotCancellable
+	}
+
+	entry.Status = WaitlistStatusCancelled
+	if err := s.repo.UpdateWaitlistEntry(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// ProcessInventoryReleases starts an order for the first waiting entry of every item
+// released by the participants since the last run. Releases that fail are retried on
+// the next run.
+func (s *Service) ProcessInventoryReleases(ctx context.Context) error {
+	releases, err := s.repo.GetUnprocessedReleases(ctx, 100)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, release := range releases {
+		if err := s.bookFirstWaiting(ctx, release); err != nil {
+			errs = append(errs, fmt.Errorf("release %s: %w", release.ID, err))
+			continue
+		}
+
+		if err := s.repo.MarkReleaseProcessed(ctx, release.ID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// bookFirstWaiting offers the released item to the entries waiting for it. A released
+// room of a room type also frees a unit of that room type.
+func (s *Service) bookFirstWaiting(ctx context.Context, release *api.InventoryRelease) error {
+	var errs []error
+	if release.ItemID != "" {
+		errs = append(errs, s.bookFirstWaitingFor(ctx, waitlistItemKey(release.Service, release.ItemID, release.SeatID), release))
+	}
+	if release.RoomTypeID != "" {
+		errs = append(errs, s.bookFirstWaitingFor(ctx, waitlistItemKey("hotel_room_type", release.RoomTypeID, ""), release))
+	}
+
+	return errors.Join(errs...)
+}
+
+// bookFirstWaitingFor starts an order for the oldest entry waiting for itemKey that wants
+// the released dates. As long as an earlier matching entry is still being booked, the item
+// is left to it.
+func (s *Service) bookFirstWaitingFor(ctx context.Context, itemKey string, release *api.InventoryRelease) error {
+	entries, err := s.repo.GetPendingWaitlistEntries(ctx, itemKey)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if !entry.matches(release.StartDate, release.EndDate) {
+			continue
+		}
+
+		if entry.Status == WaitlistStatusBooking {
+			if err := s.reconcileWaitlistEntry(ctx, entry); err != nil {
+				return err
+			}
+			if entry.Status == WaitlistStatusBooking {
+				return nil
+			}
+		}
+		if entry.Status != WaitlistStatusWaiting {
+			continue
+		}
+
+		response, err := s.CreateOrder(ctx, entry.order())
+		if err != nil {
+			return fmt.Errorf("failed to create order for waitlist entry %s: %w", entry.ID, err)
+		}
+
+		entry.Status = WaitlistStatusBooking
+		entry.OrderID = response.OrderID
+		entry.TransactionID = response.TransactionID
+		return s.repo.UpdateWaitlistEntry(ctx, entry)
+	}
+
+	return nil
+}
+
+// reconcileWaitlistEntry updates a booking entry from its booking transaction. Failed
+// bookings, e.g. because another user reserved the item first, put the entry back in line.
+func (s *Service) reconcileWaitlistEntry(ctx context.Context, entry *WaitlistEntry) error {
+	log, err := s.repo.GetTransactionLog(ctx, entry.TransactionID)
+	if err != nil {
+		return err
+	}
+
+	switch log.Status {
+	case StatusCommitted, StatusCancelled:
+		entry.Status = WaitlistStatusFulfilled
+	case StatusAborted, StatusRolledBack, StatusTimedOut:
+		entry.Status = WaitlistStatusWaiting
+		entry.OrderID = ""
+		entry.TransactionID = ""
+	default:
+		return nil
+	}
+
+	return s.repo.UpdateWaitlistEntry(ctx, entry)
+}
@@ -0,0 +1,81 @@
+package coordinator
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCancellationFee(t *testing.T) {
+	config := &Config{FreeCancellationWindow: 24 * time.Hour, CancellationFeePercent: 10}
+	committedAt := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		committedAt *time.Time
+		now         time.Time
+		want        int64
+	}{
+		{name: "inside free window", committedAt: &committedAt, now: committedAt.Add(time.Hour), want: 0},
+		{name: "window boundary", committedAt: &committedAt, now: committedAt.Add(24 * time.Hour), want: 0},
+		{name: "after free window", committedAt: &committedAt, now: committedAt.Add(24*time.Hour + time.Second), want: 1500},
+		{name: "missing commit timestamp", committedAt: nil, now: committedAt, want: 1500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			booking := &TransactionLog{TotalPrice: 15000, CommitTimestamp: tt.committedAt}
+			if got := cancellationFee(booking, config, tt.now); got != tt.want {
+				t.Errorf("cancellationFee() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindCommittedBooking(t *testing.T) {
+	booking := &TransactionLog{ID: "booking", Kind: KindBooking, Status: StatusCommitted}
+
+	tests := []struct {
+		name    string
+		logs    []*TransactionLog
+		wantErr error
+	}{
+		{name: "committed booking", logs: []*TransactionLog{booking}},
+		{name: "legacy booking without kind", logs: []*TransactionLog{{ID: "booking", Status: StatusCommitted}}},
+		{name: "missing booking", logs: nil, wantErr: ErrOrderNotFound},
+		{name: "booking not committed", logs: []*TransactionLog{{ID: "booking", Kind: KindBooking, Status: StatusPrepared}}, wantErr: ErrOrderNotCancellable},
+		{
+			name:    "cancellation in progress",
+			logs:    []*TransactionLog{booking, {ID: "cancel", Kind: KindCancellation, Status: StatusInitiated}},
+			wantErr: ErrOrderNotCancellable,
+		},
+		{
+			name:    "modification in progress",
+			logs:    []*TransactionLog{booking, {ID: "modify", Kind: KindModification, Status: StatusPrepared}},
+			wantErr: ErrOrderNotCancellable,
+		},
+		{
+			name:    "already cancelled",
+			logs:    []*TransactionLog{booking, {ID: "cancel", Kind: KindCancellation, Status: StatusCommitted}},
+			wantErr: ErrOrderNotCancellable,
+		},
+		{
+			name: "finished modification and aborted cancellation",
+			logs: []*TransactionLog{
+				booking,
+				{ID: "modify", Kind: KindModification, Status: StatusCommitted},
+				{ID: "cancel", Kind: KindCancellation, Status: StatusAborted},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := findCommittedBooking(tt.logs, ErrOrderNotCancellable)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("findCommittedBooking() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && got.ID != "booking" {
+				t.Errorf("findCommittedBooking() = %s, want booking", got.ID)
+			}
+		})
+	}
+}
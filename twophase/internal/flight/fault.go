@@ -0,0 +1,35 @@
+package flight
+
+import (
+	"context"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/fault"
+)
+
+// WithFaults makes the repository fail the prepare, commit and abort of bookings as
+// configured in injector
+func (r *Repository) WithFaults(injector *fault.Injector) *Repository {
+	r.faults = injector
+	return r
+}
+
+// PrepareFlightReservation runs prepareFlightReservation, failing before or after it when a fault is injected
+func (r *Repository) PrepareFlightReservation(ctx context.Context, transactionID string, items []FlightItem) error {
+	return r.faults.Do("PrepareFlightReservation", transactionID, func() error {
+		return r.prepareFlightReservation(ctx, transactionID, items)
+	})
+}
+
+// CommitFlightReservation runs commitFlightReservation, failing before or after it when a fault is injected
+func (r *Repository) CommitFlightReservation(ctx context.Context, transactionID string) error {
+	return r.faults.Do("CommitFlightReservation", transactionID, func() error {
+		return r.commitFlightReservation(ctx, transactionID)
+	})
+}
+
+// AbortFlightReservation runs abortFlightReservation, failing before or after it when a fault is injected
+func (r *Repository) AbortFlightReservation(ctx context.Context, transactionID string) error {
+	return r.faults.Do("AbortFlightReservation", transactionID, func() error {
+		return r.abortFlightReservation(ctx, transactionID)
+	})
+}
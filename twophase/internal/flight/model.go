@@ -0,0 +1,84 @@
+package flight
+
+import (
+	"fmt"
+	"time"
+)
+
+type FlightReservationStatus string
+
+const (
+	FlightReservationStatusCancelled  FlightReservationStatus = "CANCELLED"
+	FlightReservationStatusReserved   FlightReservationStatus = "RESERVED"
+	FlightReservationStatusCancelling FlightReservationStatus = "CANCELLING"
+)
+
+type TwoPhaseTransactionStatus string
+
+const (
+	TwoPhaseTransactionStatusPrepared  TwoPhaseTransactionStatus = "PREPARED"
+	TwoPhaseTransactionStatusCommitted TwoPhaseTransactionStatus = "COMMITTED"
+	TwoPhaseTransactionStatusAborted   TwoPhaseTransactionStatus = "ABORTED"
+)
+
+// Flight represents a flight on a specific departure date. Its cabin has Rows rows of
+// SeatsPerRow seats, lettered from A.
+type Flight struct {
+	ID            string `firestore:"id" json:"id"`
+	FlightNumber  string `firestore:"flight_number" json:"flight_number"`
+	Airline       string `firestore:"airline" json:"airline"`
+	Origin        string `firestore:"origin" json:"origin"`
+	Destination   string `firestore:"destination" json:"destination"`
+	DepartureDate string `firestore:"departure_date" json:"departure_date"`
+	DepartureTime string `firestore:"departure_time" json:"departure_time"`
+	Rows          int    `firestore:"rows" json:"rows"`
+	SeatsPerRow   int    `firestore:"seats_per_row" json:"seats_per_row"`
+}
+
+// FlightSeat represents the availability of one seat of a dated flight
+type FlightSeat struct {
+	FlightID      string `firestore:"flight_id" json:"flight_id"`
+	DepartureDate string `firestore:"departure_date" json:"departure_date"`
+	SeatID        string `firestore:"seat_id" json:"seat_id"`
+	FlightNumber  string `firestore:"flight_number" json:"flight_number"`
+	Available     bool   `firestore:"available" json:"available"`
+}
+
+type FlightReservation struct {
+	ID            string                  `firestore:"id" json:"id"`
+	FlightID      string                  `firestore:"flight_id" json:"flight_id"`
+	DepartureDate string                  `firestore:"departure_date" json:"departure_date"`
+	SeatID        string                  `firestore:"seat_id" json:"seat_id"`
+	FlightNumber  string                  `firestore:"flight_number" json:"flight_number"`
+	Price         int64                   `firestore:"price" json:"price"`
+	TransactionID string                  `firestore:"transaction_id" json:"transaction_id"`
+	Status        FlightReservationStatus `firestore:"status" json:"status"`
+}
+
+// TwoPhaseTransaction represents a two-phase commit transaction for flight seat reservation
+type TwoPhaseTransaction struct {
+	Id             string                    `firestore:"id"`
+	Status         TwoPhaseTransactionStatus `firestore:"status"`
+	ReservationIDs []string                  `firestore:"reservation_ids,omitempty"`
+	// BookingTransactionID is set on cancellation transactions and refers to the booking being cancelled
+	BookingTransactionID string    `firestore:"booking_transaction_id,omitempty"`
+	CreatedAt            time.Time `firestore:"created_at"`
+	UpdatedAt            time.Time `firestore:"updated_at"`
+}
+
+// FlightItem is a single seat booked on a dated flight
+type FlightItem struct {
+	FlightID      string `json:"flight_id" binding:"required"`
+	DepartureDate string `json:"departure_date" binding:"required"`
+	SeatID        string `json:"seat_id" binding:"required"`
+	Price         int64  `json:"price"`
+}
+
+type FlightReservationPayload struct {
+	Flights []FlightItem `json:"flights" binding:"required,min=1,dive"`
+}
+
+// SeatID returns the ID of a seat in the "{row}{letter}" format, e.g. "12A"
+func SeatID(row, number int) string {
+	return fmt.Sprintf("%d%c", row, 'A'+number-1)
+}
@@ -0,0 +1,447 @@
+package flight
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/oklog/ulid/v2"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/api"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/fault"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/utils"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	FlightCollection            = "twophase_flights"
+	FlightSeatCollection        = "twophase_flight_seats"
+	FlightReservationCollection = "twophase_flight_reservations"
+	FlightTransactionCollection = "twophase_flight_transactions"
+)
+
+var (
+	ErrSeatNotAvailable      = errors.New("seat not available")
+	ErrFlightDateMismatch    = errors.New("flight does not depart on the requested date")
+	ErrFlightNotFound        = errors.New("flight not found")
+	ErrBookingNotCancellable = errors.New("booking is not committed or is already being cancelled")
+)
+
+// Repository handles Firestore operations for flight service
+type Repository struct {
+	client *firestore.Client
+	faults *fault.Injector
+}
+
+// NewRepository creates a new repository instance
+func NewRepository(client *firestore.Client) *Repository {
+	return &Repository{
+		client: client,
+	}
+}
+
+// GetTwoPhaseTransaction retrieves a two-phase transaction
+func (r *Repository) GetTwoPhaseTransaction(ctx context.Context, transactionID string) (*TwoPhaseTransaction, error) {
+	ref := r.client.Collection(FlightTransactionCollection).Doc(transactionID)
+
+	doc, err := ref.Get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
+	}
+
+	var transaction TwoPhaseTransaction
+	if err := doc.DataTo(&transaction); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal transaction: %w", err)
+	}
+
+	return &transaction, nil
+}
+
+// GetPreparedTransactions retrieves the transactions that are prepared and still wait
+// for the coordinator to commit or abort them
+func (r *Repository) GetPreparedTransactions(ctx context.Context) ([]*TwoPhaseTransaction, error) {
+	query := r.client.Collection(FlightTransactionCollection).
+		Where("status", "==", TwoPhaseTransactionStatusPrepared)
+
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+
+	var transactions []*TwoPhaseTransaction
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate transactions: %w", err)
+		}
+
+		var transaction TwoPhaseTransaction
+		if err := doc.DataTo(&transaction); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal transaction: %w", err)
+		}
+
+		transactions = append(transactions, &transaction)
+	}
+
+	return transactions, nil
+}
+
+func (r *Repository) getFlightSeatId(flightID, seatID string) string {
+	return fmt.Sprintf("%s-%s", flightID, seatID)
+}
+
+// prepareFlightReservation prepares reservations for all flight seats in a single
+// transaction. If any seat fails, nothing is reserved and an *api.ItemError identifies
+// the offending item.
+func (r *Repository) prepareFlightReservation(ctx context.Context, transactionID string, items []FlightItem) error {
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		flights := make(map[string]*Flight)
+		for i, item := range items {
+			if _, ok := flights[item.FlightID]; ok {
+				continue
+			}
+
+			flightDoc, err := tx.Get(r.client.Collection(FlightCollection).Doc(item.FlightID))
+			if status.Code(err) == codes.NotFound {
+				return &api.ItemError{Index: i, Err: ErrFlightNotFound}
+			}
+			if err != nil {
+				return fmt.Errorf("failed to get flight: %w", err)
+			}
+
+			var flight Flight
+			if err := flightDoc.DataTo(&flight); err != nil {
+				return fmt.Errorf("failed to unmarshal flight: %w", err)
+			}
+			flights[item.FlightID] = &flight
+		}
+
+		seatRefs := make([]*firestore.DocumentRef, 0, len(items))
+		seen := make(map[string]bool)
+		for i, item := range items {
+			if flights[item.FlightID].DepartureDate != item.DepartureDate {
+				return &api.ItemError{Index: i, Err: ErrFlightDateMismatch}
+			}
+
+			ref := r.client.Collection(FlightSeatCollection).Doc(r.getFlightSeatId(item.FlightID, item.SeatID))
+			// The same seat cannot be booked twice within one order
+			if seen[ref.ID] {
+				return &api.ItemError{Index: i, Err: ErrSeatNotAvailable}
+			}
+			seen[ref.ID] = true
+			seatRefs = append(seatRefs, ref)
+		}
+
+		seatDocs, err := tx.GetAll(seatRefs)
+		if err != nil {
+			return fmt.Errorf("failed to get seats: %w", err)
+		}
+
+		for i, seatDoc := range seatDocs {
+			if !seatDoc.Exists() {
+				return &api.ItemError{Index: i, Err: ErrSeatNotAvailable}
+			}
+
+			var seat FlightSeat
+			if err := seatDoc.DataTo(&seat); err != nil {
+				return fmt.Errorf("failed to unmarshal seat: %w", err)
+			}
+
+			if !seat.Available {
+				return &api.ItemError{Index: i, Err: ErrSeatNotAvailable}
+			}
+		}
+
+		reservationIDs := make([]string, 0, len(items))
+		for i, item := range items {
+			if err := tx.Update(seatRefs[i], []firestore.Update{
+				{Path: "available", Value: false},
+			}); err != nil {
+				return fmt.Errorf("failed to update seat: %w", err)
+			}
+
+			flightReservation := &FlightReservation{
+				ID:            ulid.Make().String(),
+				FlightID:      item.FlightID,
+				DepartureDate: item.DepartureDate,
+				SeatID:        item.SeatID,
+				FlightNumber:  flights[item.FlightID].FlightNumber,
+				Price:         item.Price,
+				TransactionID: transactionID,
+				Status:        FlightReservationStatusReserved,
+			}
+
+			flightReservationRef := r.client.Collection(FlightReservationCollection).Doc(flightReservation.ID)
+			if err := tx.Create(flightReservationRef, flightReservation); err != nil {
+				return fmt.Errorf("failed to create flight reservation: %w", err)
+			}
+
+			reservationIDs = append(reservationIDs, flightReservation.ID)
+		}
+
+		twoPhaseTransaction := &TwoPhaseTransaction{
+			Id:             transactionID,
+			Status:         TwoPhaseTransactionStatusPrepared,
+			ReservationIDs: reservationIDs,
+			CreatedAt:      time.Now(),
+			UpdatedAt:      time.Now(),
+		}
+
+		twoPhaseTransactionRef := r.client.Collection(FlightTransactionCollection).Doc(twoPhaseTransaction.Id)
+		if err := tx.Create(twoPhaseTransactionRef, twoPhaseTransaction); err != nil {
+			return fmt.Errorf("failed to create two-phase transaction: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (r *Repository) commitFlightReservation(ctx context.Context, transactionID string) error {
+	transactionRef := r.client.Collection(FlightTransactionCollection).Doc(transactionID)
+
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		transactionDoc, err := tx.Get(transactionRef)
+		if err != nil {
+			return fmt.Errorf("failed to get transaction: %w", err)
+		}
+
+		var transaction TwoPhaseTransaction
+		if err := transactionDoc.DataTo(&transaction); err != nil {
+			return fmt.Errorf("failed to unmarshal transaction: %w", err)
+		}
+
+		if transaction.Status != TwoPhaseTransactionStatusPrepared {
+			// Already committed or aborted
+			return nil
+		}
+
+		if err := tx.Update(transactionRef, []firestore.Update{
+			{Path: "status", Value: TwoPhaseTransactionStatusCommitted},
+			{Path: "updated_at", Value: time.Now()},
+		}); err != nil {
+			return fmt.Errorf("failed to update transaction: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (r *Repository) abortFlightReservation(ctx context.Context, transactionID string) error {
+	return r.releaseFlightReservations(ctx, transactionID, TwoPhaseTransactionStatusAborted)
+}
+
+// releaseFlightReservations releases the seats held by a prepared transaction, cancels
+// its reservations and moves the transaction to finalStatus
+func (r *Repository) releaseFlightReservations(ctx context.Context, transactionID string, finalStatus TwoPhaseTransactionStatus) error {
+	transactionRef := r.client.Collection(FlightTransactionCollection).Doc(transactionID)
+
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		transactionDoc, err := tx.Get(transactionRef)
+		if err != nil {
+			return fmt.Errorf("failed to get transaction: %w", err)
+		}
+
+		var transaction TwoPhaseTransaction
+		if err := transactionDoc.DataTo(&transaction); err != nil {
+			return fmt.Errorf("failed to unmarshal transaction: %w", err)
+		}
+
+		if transaction.Status != TwoPhaseTransactionStatusPrepared {
+			// Already committed or aborted
+			return nil
+		}
+
+		var reservationRefs []*firestore.DocumentRef
+		for _, reservationID := range transaction.ReservationIDs {
+			reservationRefs = append(reservationRefs, r.client.Collection(FlightReservationCollection).Doc(reservationID))
+		}
+
+		reservationDocs, err := tx.GetAll(reservationRefs)
+		if err != nil {
+			return fmt.Errorf("failed to get reservations: %w", err)
+		}
+
+		// All reads must happen before the first write in a transaction
+		var seatRefs []*firestore.DocumentRef
+		for _, reservationDoc := range reservationDocs {
+			var reservation FlightReservation
+			if err := reservationDoc.DataTo(&reservation); err != nil {
+				return fmt.Errorf("failed to unmarshal reservation: %w", err)
+			}
+
+			seatRefs = append(seatRefs, r.client.Collection(FlightSeatCollection).Doc(r.getFlightSeatId(reservation.FlightID, reservation.SeatID)))
+		}
+
+		if _, err := tx.GetAll(seatRefs); err != nil {
+			return fmt.Errorf("failed to get seats: %w", err)
+		}
+
+		for _, seatRef := range seatRefs {
+			if err := tx.Update(seatRef, []firestore.Update{
+				{Path: "available", Value: true},
+			}); err != nil {
+				return fmt.Errorf("failed to update seat: %w", err)
+			}
+		}
+
+		if err := tx.Update(transactionRef, []firestore.Update{
+			{Path: "status", Value: finalStatus},
+			{Path: "updated_at", Value: time.Now()},
+		}); err != nil {
+			return fmt.Errorf("failed to update transaction: %w", err)
+		}
+
+		for _, reservationRef := range reservationRefs {
+			if err := tx.Update(reservationRef, []firestore.Update{
+				{Path: "status", Value: FlightReservationStatusCancelled},
+				{Path: "updated_at", Value: time.Now()},
+			}); err != nil {
+				return fmt.Errorf("failed to update reservation: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// PrepareFlightCancellation prepares releasing the seats reserved by a committed booking
+// transaction. The reservations are marked CANCELLING so that no other cancellation can
+// prepare them, while the seats are only released on commit.
+func (r *Repository) PrepareFlightCancellation(ctx context.Context, transactionID, bookingTransactionID string) error {
+	bookingRef := r.client.Collection(FlightTransactionCollection).Doc(bookingTransactionID)
+
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		bookingDoc, err := tx.Get(bookingRef)
+		if err != nil {
+			return fmt.Errorf("failed to get booking transaction: %w", err)
+		}
+
+		var booking TwoPhaseTransaction
+		if err := bookingDoc.DataTo(&booking); err != nil {
+			return fmt.Errorf("failed to unmarshal booking transaction: %w", err)
+		}
+
+		if booking.Status != TwoPhaseTransactionStatusCommitted {
+			return ErrBookingNotCancellable
+		}
+
+		var reservationRefs []*firestore.DocumentRef
+		for _, reservationID := range booking.ReservationIDs {
+			reservationRefs = append(reservationRefs, r.client.Collection(FlightReservationCollection).Doc(reservationID))
+		}
+
+		reservationDocs, err := tx.GetAll(reservationRefs)
+		if err != nil {
+			return fmt.Errorf("failed to get reservations: %w", err)
+		}
+
+		for _, reservationDoc := range reservationDocs {
+			var reservation FlightReservation
+			if err := reservationDoc.DataTo(&reservation); err != nil {
+				return fmt.Errorf("failed to unmarshal reservation: %w", err)
+			}
+
+			if reservation.Status != FlightReservationStatusReserved {
+				return ErrBookingNotCancellable
+			}
+		}
+
+		for _, reservationRef := range reservationRefs {
+			if err := tx.Update(reservationRef, []firestore.Update{
+				{Path: "status", Value: FlightReservationStatusCancelling},
+				{Path: "updated_at", Value: time.Now()},
+			}); err != nil {
+				return fmt.Errorf("failed to update reservation: %w", err)
+			}
+		}
+
+		twoPhaseTransaction := &TwoPhaseTransaction{
+			Id:                   transactionID,
+			Status:               TwoPhaseTransactionStatusPrepared,
+			ReservationIDs:       booking.ReservationIDs,
+			BookingTransactionID: bookingTransactionID,
+			CreatedAt:            time.Now(),
+			UpdatedAt:            time.Now(),
+		}
+
+		twoPhaseTransactionRef := r.client.Collection(FlightTransactionCollection).Doc(twoPhaseTransaction.Id)
+		if err := tx.Create(twoPhaseTransactionRef, twoPhaseTransaction); err != nil {
+			return fmt.Errorf("failed to create two-phase transaction: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// CommitFlightCancellation releases the seats of the cancelled booking
+func (r *Repository) CommitFlightCancellation(ctx context.Context, transactionID string) error {
+	return r.releaseFlightReservations(ctx, transactionID, TwoPhaseTransactionStatusCommitted)
+}
+
+// AbortFlightCancellation puts the reservations of the booking back to RESERVED
+func (r *Repository) AbortFlightCancellation(ctx context.Context, transactionID string) error {
+	transactionRef := r.client.Collection(FlightTransactionCollection).Doc(transactionID)
+
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		transactionDoc, err := tx.Get(transactionRef)
+		if err != nil {
+			return fmt.Errorf("failed to get transaction: %w", err)
+		}
+
+		var transaction TwoPhaseTransaction
+		if err := transactionDoc.DataTo(&transaction); err != nil {
+			return fmt.Errorf("failed to unmarshal transaction: %w", err)
+		}
+
+		if transaction.Status != TwoPhaseTransactionStatusPrepared {
+			// Already committed or aborted
+			return nil
+		}
+
+		if err := tx.Update(transactionRef, []firestore.Update{
+			{Path: "status", Value: TwoPhaseTransactionStatusAborted},
+			{Path: "updated_at", Value: time.Now()},
+		}); err != nil {
+			return fmt.Errorf("failed to update transaction: %w", err)
+		}
+
+		for _, reservationID := range transaction.ReservationIDs {
+			if err := tx.Update(r.client.Collection(FlightReservationCollection).Doc(reservationID), []firestore.Update{
+				{Path: "status", Value: FlightReservationStatusReserved},
+				{Path: "updated_at", Value: time.Now()},
+			}); err != nil {
+				return fmt.Errorf("failed to update reservation: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r *Repository) BulkWriteFlight(ctx context.Context, flights []Flight, mode utils.WriteMode) (utils.BulkWriteResult, error) {
+	collection := r.client.Collection(FlightCollection)
+
+	docs := make([]utils.Doc, 0, len(flights))
+	for _, flight := range flights {
+		docs = append(docs, utils.Doc{Ref: collection.Doc(flight.ID), Data: flight})
+	}
+
+	return utils.BulkWrite(ctx, r.client, docs, mode)
+}
+
+// BulkWriteFlightSeat seeds flight seats. An upsert keeps the available flag of existing
+// documents.
+func (r *Repository) BulkWriteFlightSeat(ctx context.Context, flightSeats []FlightSeat, mode utils.WriteMode) (utils.BulkWriteResult, error) {
+	collection := r.client.Collection(FlightSeatCollection)
+
+	docs := make([]utils.Doc, 0, len(flightSeats))
+	for _, flightSeat := range flightSeats {
+		docs = append(docs, utils.Doc{Ref: collection.Doc(r.getFlightSeatId(flightSeat.FlightID, flightSeat.SeatID)), Data: flightSeat})
+	}
+
+	return utils.BulkWrite(ctx, r.client, docs, mode, "flight_id", "departure_date", "seat_id", "flight_number")
+}
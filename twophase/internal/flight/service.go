@@ -0,0 +1,164 @@
+package flight
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/api"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/config"
+)
+
+// Service handles flight business logic with two-phase commit
+type Service struct {
+	repo *Repository
+}
+
+// NewService creates a new flight service
+func NewService(repo *Repository) *Service {
+	return &Service{
+		repo: repo,
+	}
+}
+
+// Prepare handles the prepare phase of two-phase commit
+func (s *Service) Prepare(ctx context.Context, req *api.PrepareRequest[FlightReservationPayload]) (*api.PrepareResponse, error) {
+	// Check if transaction already exists
+	existingTransaction, err := s.repo.GetTwoPhaseTransaction(ctx, req.TransactionID)
+	if err == nil && existingTransaction != nil {
+		// Transaction already exists, return current status
+		return &api.PrepareResponse{
+			Success: existingTransaction.Status == TwoPhaseTransactionStatusPrepared,
+			Message: fmt.Sprintf("Transaction already %s", existingTransaction.Status),
+		}, nil
+	}
+
+	flights, itemErr := parseFlights(req.Payload.Flights)
+	if itemErr != nil {
+		return &api.PrepareResponse{
+			Success:    false,
+			Message:    fmt.Sprintf("Failed to parse item: %v", itemErr.Err),
+			FailedItem: &itemErr.Index,
+		}, nil
+	}
+
+	if err := s.repo.PrepareFlightReservation(ctx, req.TransactionID, flights); err != nil {
+		response := &api.PrepareResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to create transaction log: %v", err),
+		}
+
+		var itemErr *api.ItemError
+		if errors.As(err, &itemErr) {
+			response.FailedItem = &itemErr.Index
+		}
+
+		return response, nil
+	}
+
+	return &api.PrepareResponse{
+		Success: true,
+		Message: "Flight service prepared successfully",
+	}, nil
+}
+
+// Commit handles the commit phase of two-phase commit
+func (s *Service) Commit(ctx context.Context, req *api.CommitRequest) (*api.CommitResponse, error) {
+	if err := s.repo.CommitFlightReservation(ctx, req.TransactionID); err != nil {
+		return &api.CommitResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to commit transaction: %v", err),
+		}, nil
+	}
+
+	return &api.CommitResponse{
+		Success: true,
+		Message: "Flight service committed successfully",
+	}, nil
+}
+
+// Abort handles the abort phase of two-phase commit
+func (s *Service) Abort(ctx context.Context, req *api.AbortRequest) (*api.AbortResponse, error) {
+	if err := s.repo.AbortFlightReservation(ctx, req.TransactionID); err != nil {
+		return &api.AbortResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to abort transaction: %v", err),
+		}, nil
+	}
+
+	return &api.AbortResponse{
+		Success: true,
+		Message: "Flight service aborted successfully",
+	}, nil
+}
+
+// PrepareCancellation handles the prepare phase of a cancellation transaction
+func (s *Service) PrepareCancellation(ctx context.Context, req *api.PrepareRequest[api.CancellationPayload]) (*api.PrepareResponse, error) {
+	// Check if transaction already exists
+	existingTransaction, err := s.repo.GetTwoPhaseTransaction(ctx, req.TransactionID)
+	if err == nil && existingTransaction != nil {
+		return &api.PrepareResponse{
+			Success: existingTransaction.Status == TwoPhaseTransactionStatusPrepared,
+			Message: fmt.Sprintf("Transaction already %s", existingTransaction.Status),
+		}, nil
+	}
+
+	if err := s.repo.PrepareFlightCancellation(ctx, req.TransactionID, req.Payload.BookingTransactionID); err != nil {
+		return &api.PrepareResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to prepare cancellation: %v", err),
+		}, nil
+	}
+
+	return &api.PrepareResponse{
+		Success: true,
+		Message: "Flight service prepared cancellation successfully",
+	}, nil
+}
+
+// CommitCancellation handles the commit phase of a cancellation transaction
+func (s *Service) CommitCancellation(ctx context.Context, req *api.CommitRequest) (*api.CommitResponse, error) {
+	if err := s.repo.CommitFlightCancellation(ctx, req.TransactionID); err != nil {
+		return &api.CommitResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to commit cancellation: %v", err),
+		}, nil
+	}
+
+	return &api.CommitResponse{
+		Success: true,
+		Message: "Flight service committed cancellation successfully",
+	}, nil
+}
+
+// AbortCancellation handles the abort phase of a cancellation transaction
+func (s *Service) AbortCancellation(ctx context.Context, req *api.AbortRequest) (*api.AbortResponse, error) {
+	if err := s.repo.AbortFlightCancellation(ctx, req.TransactionID); err != nil {
+		return &api.AbortResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to abort cancellation: %v", err),
+		}, nil
+	}
+
+	return &api.AbortResponse{
+		Success: true,
+		Message: "Flight service aborted cancellation successfully",
+	}, nil
+}
+
+// parseFlights normalizes the departure dates of all flight seats to config.DateFormat
+func parseFlights(items []FlightItem) ([]FlightItem, *api.ItemError) {
+	flights := make([]FlightItem, 0, len(items))
+	for i, flight := range items {
+		departureDate, err := time.Parse(config.DateFormat, flight.DepartureDate)
+		if err != nil {
+			return nil, &api.ItemError{Index: i, Err: fmt.Errorf("invalid departure date: %w", err)}
+		}
+
+		flight.DepartureDate = departureDate.Format(config.DateFormat)
+		flights = append(flights, flight)
+	}
+
+	return flights, nil
+}
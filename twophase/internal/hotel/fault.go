@@ -0,0 +1,35 @@
+package hotel
+
+import (
+	"context"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/fault"
+)
+
+// WithFaults makes the repository fail the prepare, commit and abort of bookings as
+// configured in injector
+func (r *Repository) WithFaults(injector *fault.Injector) *Repository {
+	r.faults = injector
+	return r
+}
+
+// PrepareRoomReservation runs prepareRoomReservation, failing before or after it when a fault is injected
+func (r *Repository) PrepareRoomReservation(ctx context.Context, transactionID string, rooms []HotelRoomItem) error {
+	return r.faults.Do("PrepareRoomReservation", transactionID, func() error {
+		return r.prepareRoomReservation(ctx, transactionID, rooms)
+	})
+}
+
+// CommitRoomReservation runs commitRoomReservation, failing before or after it when a fault is injected
+func (r *Repository) CommitRoomReservation(ctx context.Context, transactionID string) error {
+	return r.faults.Do("CommitRoomReservation", transactionID, func() error {
+		return r.commitRoomReservation(ctx, transactionID)
+	})
+}
+
+// AbortRoomReservation runs abortRoomReservation, failing before or after it when a fault is injected
+func (r *Repository) AbortRoomReservation(ctx context.Context, transactionID string) error {
+	return r.faults.Do("AbortRoomReservation", transactionID, func() error {
+		return r.abortRoomReservation(ctx, transactionID)
+	})
+}
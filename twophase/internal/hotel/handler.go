@@ -1,6 +1,7 @@
 package hotel
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -29,6 +30,35 @@ func (h *Handler) RegisterRoutes(r *gin.Engine) {
 		twophase.POST("/abort", h.Abort)
 	}
 
+	// Cancellation of a committed booking, run as its own two-phase commit transaction
+	cancel := r.Group("/twophase/cancel")
+	{
+		cancel.POST("/prepare", h.PrepareCancellation)
+		cancel.POST("/commit", h.CommitCancellation)
+		cancel.POST("/abort", h.AbortCancellation)
+	}
+
+	// Modification of one room of a committed booking, run as its own two-phase commit transaction
+	modify := r.Group("/twophase/modify")
+	{
+		modify.POST("/prepare", h.PrepareModification)
+		modify.POST("/commit", h.CommitModification)
+		modify.POST("/abort", h.AbortModification)
+	}
+
+	// Holds reserve rooms until they are confirmed, released or expire
+	holds := r.Group("/holds")
+	{
+		holds.POST("", h.Hold)
+		holds.POST("/confirm", h.ConfirmHold)
+		holds.POST("/release", h.ReleaseHold)
+	}
+
+	// Search of the rooms available for a date range
+	r.GET("/hotel-rooms", h.SearchRooms)
+	r.GET("/hotel-rooms/:id/calendar", h.GetRoomCalendar)
+	r.POST("/hotel-reservations/:id/assign-room", h.AssignRoom)
+
 	// Health check
 	// r.GET("/health", h.HealthCheck)
 }
@@ -113,3 +143,334 @@ func (h *Handler) Abort(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, response)
 	}
 }
+
+// PrepareCancellation handles prepare phase requests of a cancellation
+func (h *Handler) PrepareCancellation(c *gin.Context) {
+	var req api.PrepareRequest[api.CancellationPayload]
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	response, err := h.service.PrepareCancellation(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to prepare cancellation",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if response.Success {
+		c.JSON(http.StatusOK, response)
+	} else {
+		c.JSON(http.StatusBadRequest, response)
+	}
+}
+
+// CommitCancellation handles commit phase requests of a cancellation
+func (h *Handler) CommitCancellation(c *gin.Context) {
+	var req api.CommitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	response, err := h.service.CommitCancellation(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to commit cancellation",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if response.Success {
+		c.JSON(http.StatusOK, response)
+	} else {
+		c.JSON(http.StatusBadRequest, response)
+	}
+}
+
+// AbortCancellation handles abort phase requests of a cancellation
+func (h *Handler) AbortCancellation(c *gin.Context) {
+	var req api.AbortRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	response, err := h.service.AbortCancellation(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to abort cancellation",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if response.Success {
+		c.JSON(http.StatusOK, response)
+	} else {
+		c.JSON(http.StatusBadRequest, response)
+	}
+}
+
+// PrepareModification handles prepare phase requests of a modification
+func (h *Handler) PrepareModification(c *gin.Context) {
+	var req api.PrepareRequest[HotelRoomModificationPayload]
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	response, err := h.service.PrepareModification(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to prepare modification",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if response.Success {
+		c.JSON(http.StatusOK, response)
+	} else {
+		c.JSON(http.StatusBadRequest, response)
+	}
+}
+
+// CommitModification handles commit phase requests of a modification
+func (h *Handler) CommitModification(c *gin.Context) {
+	var req api.CommitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	response, err := h.service.CommitModification(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to commit modification",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if response.Success {
+		c.JSON(http.StatusOK, response)
+	} else {
+		c.JSON(http.StatusBadRequest, response)
+	}
+}
+
+// AbortModification handles abort phase requests of a modification
+func (h *Handler) AbortModification(c *gin.Context) {
+	var req api.AbortRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	response, err := h.service.AbortModification(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to abort modification",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if response.Success {
+		c.JSON(http.StatusOK, response)
+	} else {
+		c.JSON(http.StatusBadRequest, response)
+	}
+}
+
+// Hold handles hold requests
+func (h *Handler) Hold(c *gin.Context) {
+	var req api.HoldRequest[HotelRoomReservationPayload]
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	response, err := h.service.Hold(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to hold rooms",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if response.Success {
+		c.JSON(http.StatusOK, response)
+	} else {
+		c.JSON(http.StatusBadRequest, response)
+	}
+}
+
+// ConfirmHold handles hold confirmation requests
+func (h *Handler) ConfirmHold(c *gin.Context) {
+	var req api.ConfirmHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	response, err := h.service.ConfirmHold(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to confirm hold",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if response.Success {
+		c.JSON(http.StatusOK, response)
+	} else {
+		c.JSON(http.StatusBadRequest, response)
+	}
+}
+
+// ReleaseHold handles hold release requests
+func (h *Handler) ReleaseHold(c *gin.Context) {
+	var req api.ReleaseHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	response, err := h.service.ReleaseHold(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to release hold",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if response.Success {
+		c.JSON(http.StatusOK, response)
+	} else {
+		c.JSON(http.StatusBadRequest, response)
+	}
+}
+
+// SearchRooms handles room search requests
+func (h *Handler) SearchRooms(c *gin.Context) {
+	var query RoomSearchQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid query parameters",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	page, err := h.service.SearchRooms(c.Request.Context(), &query)
+	if errors.Is(err, ErrInvalidDateRange) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid date range",
+			"message": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to search rooms",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// GetRoomCalendar handles room calendar requests
+func (h *Handler) GetRoomCalendar(c *gin.Context) {
+	var query CalendarQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid query parameters",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	calendar, err := h.service.GetRoomCalendar(c.Request.Context(), c.Param("id"), &query)
+	if errors.Is(err, ErrInvalidDateRange) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid date range",
+			"message": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to get room calendar",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, calendar)
+}
+
+// AssignRoom handles assigning a room to a room type reservation
+func (h *Handler) AssignRoom(c *gin.Context) {
+	reservation, err := h.service.AssignRoom(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, ErrReservationNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Reservation not found",
+			"message": err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrRoomNotAssignable) || errors.Is(err, ErrNoRoomToAssign) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Failed to assign room",
+			"message": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to assign room",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, reservation)
+}
@@ -2,13 +2,18 @@ package hotel
 
 import (
 	"time"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/api"
 )
 
 type HotelRoomReservationStatus string
 
 const (
-	HotelRoomReservationStatusCancelled HotelRoomReservationStatus = "CANCELLED"
-	HotelRoomReservationStatusReserved  HotelRoomReservationStatus = "RESERVED"
+	HotelRoomReservationStatusCancelled  HotelRoomReservationStatus = "CANCELLED"
+	HotelRoomReservationStatusReserved   HotelRoomReservationStatus = "RESERVED"
+	HotelRoomReservationStatusCancelling HotelRoomReservationStatus = "CANCELLING"
+	HotelRoomReservationStatusModifying  HotelRoomReservationStatus = "MODIFYING"
+	HotelRoomReservationStatusHeld       HotelRoomReservationStatus = "HELD"
 )
 
 type TwoPhaseTransactionStatus string
@@ -17,38 +22,132 @@ const (
 	TwoPhaseTransactionStatusPrepared  TwoPhaseTransactionStatus = "PREPARED"
 	TwoPhaseTransactionStatusCommitted TwoPhaseTransactionStatus = "COMMITTED"
 	TwoPhaseTransactionStatusAborted   TwoPhaseTransactionStatus = "ABORTED"
+	// HELD transactions are holds, which become COMMITTED when confirmed or EXPIRED when
+	// the participant releases them after ExpiresAt
+	TwoPhaseTransactionStatusHeld    TwoPhaseTransactionStatus = "HELD"
+	TwoPhaseTransactionStatusExpired TwoPhaseTransactionStatus = "EXPIRED"
 )
 
 type HotelRoomAvailability struct {
 	RoomID    string `firestore:"room_id" json:"room_id"`
 	HotelName string `firestore:"hotel_name" json:"hotel_name"`
 	RoomName  string `firestore:"room_name" json:"room_name"`
+	City      string `firestore:"city" json:"city"`
 	Date      string `firestore:"date" json:"date"`
 	Available bool   `firestore:"available" json:"available"`
+	// RoomTypeID is the room type the room is sold as, if any
+	RoomTypeID string `firestore:"room_type_id,omitempty" json:"room_type_id,omitempty"`
+}
+
+// RoomTypeAvailability is the number of units of a room type that can still be sold on
+// a date. Every booking of the room type, or of one of its rooms, takes one unit per night.
+type RoomTypeAvailability struct {
+	RoomTypeID     string `firestore:"room_type_id" json:"room_type_id"`
+	HotelName      string `firestore:"hotel_name" json:"hotel_name"`
+	RoomTypeName   string `firestore:"room_type_name" json:"room_type_name"`
+	City           string `firestore:"city" json:"city"`
+	Date           string `firestore:"date" json:"date"`
+	TotalUnits     int    `firestore:"total_units" json:"total_units"`
+	AvailableUnits int    `firestore:"available_units" json:"available_units"`
 }
 
+// HotelReservation reserves a room or a unit of a room type. Room type reservations have
+// no HotelRoomID until a room is assigned to them.
 type HotelReservation struct {
 	ID                 string                     `firestore:"id" json:"id"`
 	HotelRoomID        string                     `firestore:"hotel_room_id" json:"hotel_room_id"`
 	HotelRoomName      string                     `firestore:"hotel_room_name" json:"hotel_room_name"`
+	RoomTypeID         string                     `firestore:"room_type_id,omitempty" json:"room_type_id,omitempty"`
+	RoomTypeName       string                     `firestore:"room_type_name,omitempty" json:"room_type_name,omitempty"`
 	HotelName          string                     `firestore:"hotel_name" json:"hotel_name"`
 	HotelRoomStartDate string                     `firestore:"hotel_room_start_date" json:"hotel_room_start_date"`
 	HotelRoomEndDate   string                     `firestore:"hotel_room_end_date" json:"hotel_room_end_date"`
+	Price              int64                      `firestore:"price" json:"price"`
 	TransactionID      string                     `firestore:"transaction_id" json:"transaction_id"`
 	Status             HotelRoomReservationStatus `firestore:"status" json:"status"`
 }
 
 // TwoPhaseTransaction represents a two-phase commit transaction for hotel
 type TwoPhaseTransaction struct {
-	Id            string                    `firestore:"id"`
-	Status        TwoPhaseTransactionStatus `firestore:"status"` // "prepared", "committed", "aborted"
-	ReservationID string                    `firestore:"reservation_id,omitempty"`
-	CreatedAt     time.Time                 `firestore:"created_at"`
-	UpdatedAt     time.Time                 `firestore:"updated_at"`
+	Id             string                    `firestore:"id"`
+	Status         TwoPhaseTransactionStatus `firestore:"status"` // "prepared", "committed", "aborted"
+	ReservationIDs []string                  `firestore:"reservation_ids,omitempty"`
+	// BookingTransactionID is set on cancellation transactions and refers to the booking being cancelled
+	BookingTransactionID string `firestore:"booking_transaction_id,omitempty"`
+	// ReplacedReservationID is set on modification transactions and refers to the
+	// reservation of the booking that ReservationIDs replaces on commit
+	ReplacedReservationID string `firestore:"replaced_reservation_id,omitempty"`
+	// ExpiresAt is set on holds
+	ExpiresAt *time.Time `firestore:"expires_at,omitempty"`
+	CreatedAt time.Time  `firestore:"created_at"`
+	UpdatedAt time.Time  `firestore:"updated_at"`
+}
+
+// HotelRoomItem is a single room, or a unit of a room type, booked for a date range at the
+// price locked by the coordinator. Exactly one of HotelRoomID and RoomTypeID is set.
+type HotelRoomItem struct {
+	HotelRoomID string `json:"hotel_room_id" binding:"required_without=RoomTypeID,excluded_with=RoomTypeID"`
+	RoomTypeID  string `json:"room_type_id" binding:"required_without=HotelRoomID"`
+	StartDate   string `json:"start_date" binding:"required"`
+	EndDate     string `json:"end_date" binding:"required"`
+	Price       int64  `json:"price"`
 }
 
 type HotelRoomReservationPayload struct {
-	HotelRoomID        string `json:"hotel_room_id" binding:"required"`
-	HotelRoomStartDate string `json:"hotel_room_start_date" binding:"required"`
-	HotelRoomEndDate   string `json:"hotel_room_end_date" binding:"required"`
+	HotelRooms []HotelRoomItem `json:"hotel_rooms" binding:"required,min=1,dive"`
+}
+
+// HotelRoomModificationPayload replaces the room at Index of a committed booking with HotelRoom
+type HotelRoomModificationPayload struct {
+	BookingTransactionID string        `json:"booking_transaction_id" binding:"required"`
+	Index                int           `json:"index" binding:"min=0"`
+	HotelRoom            HotelRoomItem `json:"hotel_room"`
+}
+
+// RoomSearchQuery searches the rooms that are available on every night from StartDate
+// to EndDate (inclusive), optionally narrowed down to a city or a hotel
+type RoomSearchQuery struct {
+	City      string `form:"city"`
+	HotelName string `form:"hotel_name"`
+	StartDate string `form:"start_date" binding:"required"`
+	EndDate   string `form:"end_date" binding:"required"`
+	api.PageRequest
+}
+
+// AvailableRoom is a room found by a search
+type AvailableRoom struct {
+	RoomID    string `json:"room_id"`
+	HotelName string `json:"hotel_name"`
+	RoomName  string `json:"room_name"`
+	City      string `json:"city"`
+}
+
+// BlockingStatus is the status of the reservation that makes a room unavailable on a date
+type BlockingStatus string
+
+const (
+	// PREPARED rooms are reserved by a transaction that is not committed yet
+	BlockingStatusPrepared BlockingStatus = "PREPARED"
+	BlockingStatusHeld     BlockingStatus = "HELD"
+	BlockingStatusReserved BlockingStatus = "RESERVED"
+)
+
+// CalendarQuery is the date range of a calendar, both dates inclusive
+type CalendarQuery struct {
+	From string `form:"from" binding:"required"`
+	To   string `form:"to" binding:"required"`
+}
+
+// CalendarDay is the availability of a room on one date. Status is set when a
+// reservation blocks the date.
+type CalendarDay struct {
+	Date      string         `json:"date"`
+	Available bool           `json:"available"`
+	Status    BlockingStatus `json:"status,omitempty"`
+}
+
+// RoomCalendar is the availability of a room over a date range
+type RoomCalendar struct {
+	HotelRoomID string        `json:"hotel_room_id"`
+	Days        []CalendarDay `json:"days"`
 }
@@ -8,24 +8,41 @@ import (
 
 	"cloud.google.com/go/firestore"
 	"github.com/oklog/ulid/v2"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/api"
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/config"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/fault"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/utils"
+	"google.golang.org/api/iterator"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
 const (
-	HotelRoomAvailabilityCollection = "twophase_hotel_room_availabilities"
-	HotelRoomReservationCollection  = "twophase_hotel_room_reservations"
-	HotelRoomTransactionCollection  = "twophase_hotel_transactions"
+	HotelRoomAvailabilityCollection     = "twophase_hotel_room_availabilities"
+	HotelRoomTypeAvailabilityCollection = "twophase_hotel_room_type_availabilities"
+	HotelRoomReservationCollection      = "twophase_hotel_room_reservations"
+	HotelRoomTransactionCollection      = "twophase_hotel_transactions"
 )
 
 var (
-	ErrRoomNotAvailable = errors.New("room not available")
+	ErrRoomNotAvailable      = errors.New("room not available")
+	ErrBookingNotCancellable = errors.New("booking is not committed or is already being cancelled")
+	ErrBookingNotModifiable  = errors.New("booking is not committed or is already being cancelled or modified")
+	ErrItemNotFound          = errors.New("booking has no room at the given index")
+	ErrHoldNotActive         = errors.New("hold is not active")
+	ErrHoldExpired           = errors.New("hold has expired")
+	ErrInvalidDateRange      = errors.New("invalid date range")
+	ErrReservationNotFound   = errors.New("reservation not found")
+	// ErrRoomNotAssignable is returned when assigning a room to a reservation that is not a
+	// committed room type reservation
+	ErrRoomNotAssignable = errors.New("reservation is not a committed room type reservation")
+	ErrNoRoomToAssign    = errors.New("no room of this type is free for the whole stay")
 )
 
 // Repository handles Firestore operations for hotel service
 type Repository struct {
 	client *firestore.Client
+	faults *fault.Injector
 }
 
 // NewRepository creates a new repository instance
@@ -52,11 +69,73 @@ func (r *Repository) GetTwoPhaseTransaction(ctx context.Context, transactionID s
 	return &transaction, nil
 }
 
+// GetPreparedTransactions retrieves the transactions that are prepared and still wait
+// for the coordinator to commit or abort them
+func (r *Repository) GetPreparedTransactions(ctx context.Context) ([]*TwoPhaseTransaction, error) {
+	query := r.client.Collection(HotelRoomTransactionCollection).
+		Where("status", "==", TwoPhaseTransactionStatusPrepared)
+
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+
+	var transactions []*TwoPhaseTransaction
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate transactions: %w", err)
+		}
+
+		var transaction TwoPhaseTransaction
+		if err := doc.DataTo(&transaction); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal transaction: %w", err)
+		}
+
+		transactions = append(transactions, &transaction)
+	}
+
+	return transactions, nil
+}
+
 func (r *Repository) getRoomAvailabilityId(roomID, date string) string {
-	return fmt.Sprintf("%s-%s", roomID, date)
+	return availabilityID(roomID, date)
+}
+
+// availabilityID is the ID of the availability document of a room or room type on date
+func availabilityID(id, date string) string {
+	return fmt.Sprintf("%s-%s", id, date)
 }
 
 func (r *Repository) getRoomAvailabilityRefs(roomID string, checkInDate, checkOutDate string) ([]*firestore.DocumentRef, error) {
+	return r.getAvailabilityRefs(HotelRoomAvailabilityCollection, roomID, checkInDate, checkOutDate)
+}
+
+// getAvailabilityRefs returns the availability documents in collection of the room or room
+// type id for every night of the stay. Nothing is returned when id is empty, i.e. for room
+// type reservations without an assigned room.
+func (r *Repository) getAvailabilityRefs(collection, id string, checkInDate, checkOutDate string) ([]*firestore.DocumentRef, error) {
+	ids, err := availabilityIDs(id, checkInDate, checkOutDate)
+	if err != nil {
+		return nil, err
+	}
+
+	var availabilityRefs []*firestore.DocumentRef
+	for _, availabilityID := range ids {
+		availabilityRefs = append(availabilityRefs, r.client.Collection(collection).Doc(availabilityID))
+	}
+
+	return availabilityRefs, nil
+}
+
+// availabilityIDs returns the availability document IDs of the room or room type id for
+// every night of the stay, nothing when id is empty
+func availabilityIDs(id string, checkInDate, checkOutDate string) ([]string, error) {
+	if id == "" {
+		return nil, nil
+	}
+
 	startDate, err := time.Parse(config.DateFormat, checkInDate)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse check-in date: %w", err)
@@ -68,24 +147,170 @@ func (r *Repository) getRoomAvailabilityRefs(roomID string, checkInDate, checkOu
 	}
 
 	// Iterate through the dates (inclusive)
-	var dates []string
+	var ids []string
 	for date := startDate; date.Before(endDate.AddDate(0, 0, 1)); date = date.AddDate(0, 0, 1) {
-		dates = append(dates, date.Format(config.DateFormat))
+		ids = append(ids, availabilityID(id, date.Format(config.DateFormat)))
 	}
 
-	var roomAvailabilityRefs []*firestore.DocumentRef
-	for _, date := range dates {
-		roomAvailabilityRefs = append(
-			roomAvailabilityRefs,
-			r.client.Collection(HotelRoomAvailabilityCollection).
-				Doc(r.getRoomAvailabilityId(roomID, date)),
-		)
+	return ids, nil
+}
+
+// unitChanges selects which changes of room type units adjustRoomTypeUnits writes
+type unitChanges int
+
+const (
+	allUnitChanges unitChanges = iota
+	// takenUnitsOnly keeps the units of released reservations, they only offset the
+	// units taken on the same nights
+	takenUnitsOnly
+	// releasedUnitsOnly only returns units, the taken reservations already hold theirs
+	releasedUnitsOnly
+)
+
+// roomTypeUnits is the change of room type units per availability document made by a
+// set of reservations
+type roomTypeUnits struct {
+	// ids are the room type availability documents of the reservations, in order of
+	// appearance. Documents whose changes cancel out are included.
+	ids    []string
+	deltas map[string]int
+	// takenBy is the index of the first taken reservation that takes a unit of the document
+	takenBy map[string]int
+}
+
+// netRoomTypeUnits sums the units taken by the taken reservations and returned by the
+// released reservations per room type availability document
+func netRoomTypeUnits(taken, released []*HotelReservation) (*roomTypeUnits, error) {
+	units := &roomTypeUnits{
+		deltas:  make(map[string]int),
+		takenBy: make(map[string]int),
+	}
+	collect := func(reservation *HotelReservation, delta, index int) error {
+		ids, err := availabilityIDs(reservation.RoomTypeID, reservation.HotelRoomStartDate, reservation.HotelRoomEndDate)
+		if err != nil {
+			return fmt.Errorf("failed to get room type availability refs: %w", err)
+		}
+		for _, id := range ids {
+			if _, ok := units.deltas[id]; !ok {
+				units.ids = append(units.ids, id)
+			}
+			units.deltas[id] += delta
+			if _, ok := units.takenBy[id]; !ok && delta < 0 {
+				units.takenBy[id] = index
+			}
+		}
+		return nil
+	}
+	for i, reservation := range released {
+		if err := collect(reservation, 1, i); err != nil {
+			return nil, err
+		}
+	}
+	for i, reservation := range taken {
+		if err := collect(reservation, -1, i); err != nil {
+			return nil, err
+		}
+	}
+	return units, nil
+}
+
+// apply applies the change of the document id selected by changes to availability and
+// reports whether availability changed. Returned units never exceed the number of rooms
+// of the room type.
+func (u *roomTypeUnits) apply(availability *RoomTypeAvailability, id string, changes unitChanges) (bool, error) {
+	delta := u.deltas[id]
+	if delta == 0 || (changes == takenUnitsOnly && delta > 0) || (changes == releasedUnitsOnly && delta < 0) {
+		return false, nil
+	}
+
+	availability.AvailableUnits = min(availability.AvailableUnits+delta, availability.TotalUnits)
+	if availability.AvailableUnits < 0 {
+		return false, &api.ItemError{Index: u.takenBy[id], Err: ErrRoomNotAvailable}
+	}
+	return true, nil
+}
+
+// adjustRoomTypeUnits takes one unit of the room type per night for every taken
+// reservation and returns the units of every released reservation, netted per night.
+// Reservations without a room type are skipped. The hotel and room type names of taken
+// reservations are filled in from the room type availability. As all reads of a Firestore
+// transaction must happen before its writes, it is called after the other reads and before
+// the other writes of tx.
+func (r *Repository) adjustRoomTypeUnits(tx *firestore.Transaction, taken, released []*HotelReservation, changes unitChanges) error {
+	units, err := netRoomTypeUnits(taken, released)
+	if err != nil {
+		return err
+	}
+	if len(units.ids) == 0 {
+		return nil
+	}
+
+	refs := make([]*firestore.DocumentRef, 0, len(units.ids))
+	for _, id := range units.ids {
+		refs = append(refs, r.client.Collection(HotelRoomTypeAvailabilityCollection).Doc(id))
+	}
+
+	docs, err := tx.GetAll(refs)
+	if err != nil {
+		return fmt.Errorf("failed to get room type availability: %w", err)
+	}
+
+	availabilities := make(map[string]*RoomTypeAvailability, len(docs))
+	for _, doc := range docs {
+		if !doc.Exists() {
+			if index, ok := units.takenBy[doc.Ref.ID]; ok {
+				return &api.ItemError{Index: index, Err: ErrRoomNotAvailable}
+			}
+			continue
+		}
+
+		var availability RoomTypeAvailability
+		if err := doc.DataTo(&availability); err != nil {
+			return fmt.Errorf("failed to unmarshal room type availability: %w", err)
+		}
+		availabilities[doc.Ref.ID] = &availability
+	}
+
+	for _, reservation := range taken {
+		if reservation.RoomTypeID == "" {
+			continue
+		}
+		for _, availability := range availabilities {
+			if availability.RoomTypeID == reservation.RoomTypeID {
+				reservation.HotelName = availability.HotelName
+				reservation.RoomTypeName = availability.RoomTypeName
+				break
+			}
+		}
+	}
+
+	var updates []*firestore.DocumentRef
+	for _, ref := range refs {
+		availability, ok := availabilities[ref.ID]
+		if !ok {
+			continue
+		}
+		changed, err := units.apply(availability, ref.ID, changes)
+		if err != nil {
+			return err
+		}
+		if changed {
+			updates = append(updates, ref)
+		}
+	}
+
+	for _, ref := range updates {
+		if err := tx.Update(ref, []firestore.Update{
+			{Path: "available_units", Value: availabilities[ref.ID].AvailableUnits},
+		}); err != nil {
+			return fmt.Errorf("failed to update room type availability: %w", err)
+		}
 	}
 
-	return roomAvailabilityRefs, nil
+	return nil
 }
 
-func (r *Repository) CommitRoomReservation(ctx context.Context, transactionID string) error {
+func (r *Repository) commitRoomReservation(ctx context.Context, transactionID string) error {
 	transactionRef := r.client.Collection(HotelRoomTransactionCollection).Doc(transactionID)
 
 	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
@@ -115,7 +340,13 @@ func (r *Repository) CommitRoomReservation(ctx context.Context, transactionID st
 	})
 }
 
-func (r *Repository) AbortRoomReservation(ctx context.Context, transactionID string) error {
+func (r *Repository) abortRoomReservation(ctx context.Context, transactionID string) error {
+	return r.releaseRoomReservations(ctx, transactionID, TwoPhaseTransactionStatusPrepared, TwoPhaseTransactionStatusAborted)
+}
+
+// releaseRoomReservations releases the availability held by a transaction in fromStatus,
+// cancels its reservations and moves the transaction to finalStatus
+func (r *Repository) releaseRoomReservations(ctx context.Context, transactionID string, fromStatus, finalStatus TwoPhaseTransactionStatus) error {
 	transactionRef := r.client.Collection(HotelRoomTransactionCollection).Doc(transactionID)
 
 	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
@@ -129,38 +360,49 @@ func (r *Repository) AbortRoomReservation(ctx context.Context, transactionID str
 			return fmt.Errorf("failed to unmarshal transaction: %w", err)
 		}
 
-		if transaction.Status != TwoPhaseTransactionStatusPrepared {
+		if transaction.Status != fromStatus {
 			// Already committed or aborted
 			return nil
 		}
 
-		reservationRef := r.client.Collection(HotelRoomReservationCollection).Doc(transaction.ReservationID)
-		reservationDoc, err := tx.Get(reservationRef)
-		if err != nil {
-			return fmt.Errorf("failed to get reservation: %w", err)
-		}
-
-		var reservation HotelReservation
-		if err := reservationDoc.DataTo(&reservation); err != nil {
-			return fmt.Errorf("failed to unmarshal reservation: %w", err)
+		var reservationRefs []*firestore.DocumentRef
+		for _, reservationID := range transaction.ReservationIDs {
+			reservationRefs = append(reservationRefs, r.client.Collection(HotelRoomReservationCollection).Doc(reservationID))
 		}
 
-		roomAvailabilityRefs, err := r.getRoomAvailabilityRefs(reservation.HotelRoomID, reservation.HotelRoomStartDate, reservation.HotelRoomEndDate)
+		reservationDocs, err := tx.GetAll(reservationRefs)
 		if err != nil {
-			return fmt.Errorf("failed to get room availability refs: %w", err)
+			return fmt.Errorf("failed to get reservations: %w", err)
 		}
 
-		for _, ref := range roomAvailabilityRefs {
-			doc, err := tx.Get(ref)
+		// All reads must happen before the first write in a transaction
+		var roomAvailabilityRefs []*firestore.DocumentRef
+		reservations := make([]*HotelReservation, 0, len(reservationDocs))
+		for _, reservationDoc := range reservationDocs {
+			var reservation HotelReservation
+			if err := reservationDoc.DataTo(&reservation); err != nil {
+				return fmt.Errorf("failed to unmarshal reservation: %w", err)
+			}
+			reservations = append(reservations, &reservation)
+
+			refs, err := r.getRoomAvailabilityRefs(reservation.HotelRoomID, reservation.HotelRoomStartDate, reservation.HotelRoomEndDate)
 			if err != nil {
-				return fmt.Errorf("failed to get room availability: %w", err)
+				return fmt.Errorf("failed to get room availability refs: %w", err)
 			}
+			roomAvailabilityRefs = append(roomAvailabilityRefs, refs...)
+		}
 
-			var roomAvailability HotelRoomAvailability
-			if err := doc.DataTo(&roomAvailability); err != nil {
-				return fmt.Errorf("failed to unmarshal room availability: %w", err)
+		if len(roomAvailabilityRefs) > 0 {
+			if _, err := tx.GetAll(roomAvailabilityRefs); err != nil {
+				return fmt.Errorf("failed to get room availability: %w", err)
 			}
+		}
 
+		if err := r.adjustRoomTypeUnits(tx, nil, reservations, allUnitChanges); err != nil {
+			return err
+		}
+
+		for _, ref := range roomAvailabilityRefs {
 			if err := tx.Update(ref, []firestore.Update{
 				{Path: "available", Value: true},
 			}); err != nil {
@@ -169,72 +411,259 @@ func (r *Repository) AbortRoomReservation(ctx context.Context, transactionID str
 		}
 
 		if err := tx.Update(transactionRef, []firestore.Update{
-			{Path: "status", Value: TwoPhaseTransactionStatusAborted},
+			{Path: "status", Value: finalStatus},
 			{Path: "updated_at", Value: time.Now()},
 		}); err != nil {
 			return fmt.Errorf("failed to update transaction: %w", err)
 		}
 
-		if err := tx.Update(reservationRef, []firestore.Update{
-			{Path: "status", Value: HotelRoomReservationStatusCancelled},
+		for _, reservationRef := range reservationRefs {
+			if err := tx.Update(reservationRef, []firestore.Update{
+				{Path: "status", Value: HotelRoomReservationStatusCancelled},
+				{Path: "updated_at", Value: time.Now()},
+			}); err != nil {
+				return fmt.Errorf("failed to update reservation: %w", err)
+			}
+		}
+
+		for _, reservation := range reservations {
+			if err := r.recordRelease(tx, reservation); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// PrepareRoomCancellation prepares releasing the rooms reserved by a committed booking
+// transaction. The reservations are marked CANCELLING so that no other cancellation can
+// prepare them, while availability is only released on commit.
+func (r *Repository) PrepareRoomCancellation(ctx context.Context, transactionID, bookingTransactionID string) error {
+	bookingRef := r.client.Collection(HotelRoomTransactionCollection).Doc(bookingTransactionID)
+
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		bookingDoc, err := tx.Get(bookingRef)
+		if err != nil {
+			return fmt.Errorf("failed to get booking transaction: %w", err)
+		}
+
+		var booking TwoPhaseTransaction
+		if err := bookingDoc.DataTo(&booking); err != nil {
+			return fmt.Errorf("failed to unmarshal booking transaction: %w", err)
+		}
+
+		if booking.Status != TwoPhaseTransactionStatusCommitted {
+			return ErrBookingNotCancellable
+		}
+
+		var reservationRefs []*firestore.DocumentRef
+		for _, reservationID := range booking.ReservationIDs {
+			reservationRefs = append(reservationRefs, r.client.Collection(HotelRoomReservationCollection).Doc(reservationID))
+		}
+
+		reservationDocs, err := tx.GetAll(reservationRefs)
+		if err != nil {
+			return fmt.Errorf("failed to get reservations: %w", err)
+		}
+
+		for _, reservationDoc := range reservationDocs {
+			var reservation HotelReservation
+			if err := reservationDoc.DataTo(&reservation); err != nil {
+				return fmt.Errorf("failed to unmarshal reservation: %w", err)
+			}
+
+			if reservation.Status != HotelRoomReservationStatusReserved {
+				return ErrBookingNotCancellable
+			}
+		}
+
+		for _, reservationRef := range reservationRefs {
+			if err := tx.Update(reservationRef, []firestore.Update{
+				{Path: "status", Value: HotelRoomReservationStatusCancelling},
+				{Path: "updated_at", Value: time.Now()},
+			}); err != nil {
+				return fmt.Errorf("failed to update reservation: %w", err)
+			}
+		}
+
+		twoPhaseTransaction := &TwoPhaseTransaction{
+			Id:                   transactionID,
+			Status:               TwoPhaseTransactionStatusPrepared,
+			ReservationIDs:       booking.ReservationIDs,
+			BookingTransactionID: bookingTransactionID,
+			CreatedAt:            time.Now(),
+			UpdatedAt:            time.Now(),
+		}
+
+		twoPhaseTransactionRef := r.client.Collection(HotelRoomTransactionCollection).Doc(twoPhaseTransaction.Id)
+		if err := tx.Create(twoPhaseTransactionRef, twoPhaseTransaction); err != nil {
+			return fmt.Errorf("failed to create two-phase transaction: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// CommitRoomCancellation releases the availability of the cancelled booking
+func (r *Repository) CommitRoomCancellation(ctx context.Context, transactionID string) error {
+	return r.releaseRoomReservations(ctx, transactionID, TwoPhaseTransactionStatusPrepared, TwoPhaseTransactionStatusCommitted)
+}
+
+// AbortRoomCancellation puts the reservations of the booking back to RESERVED
+func (r *Repository) AbortRoomCancellation(ctx context.Context, transactionID string) error {
+	transactionRef := r.client.Collection(HotelRoomTransactionCollection).Doc(transactionID)
+
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		transactionDoc, err := tx.Get(transactionRef)
+		if err != nil {
+			return fmt.Errorf("failed to get transaction: %w", err)
+		}
+
+		var transaction TwoPhaseTransaction
+		if err := transactionDoc.DataTo(&transaction); err != nil {
+			return fmt.Errorf("failed to unmarshal transaction: %w", err)
+		}
+
+		if transaction.Status != TwoPhaseTransactionStatusPrepared {
+			// Already committed or aborted
+			return nil
+		}
+
+		if err := tx.Update(transactionRef, []firestore.Update{
+			{Path: "status", Value: TwoPhaseTransactionStatusAborted},
 			{Path: "updated_at", Value: time.Now()},
 		}); err != nil {
-			return fmt.Errorf("failed to update reservation: %w", err)
+			return fmt.Errorf("failed to update transaction: %w", err)
+		}
+
+		for _, reservationID := range transaction.ReservationIDs {
+			if err := tx.Update(r.client.Collection(HotelRoomReservationCollection).Doc(reservationID), []firestore.Update{
+				{Path: "status", Value: HotelRoomReservationStatusReserved},
+				{Path: "updated_at", Value: time.Now()},
+			}); err != nil {
+				return fmt.Errorf("failed to update reservation: %w", err)
+			}
 		}
 
 		return nil
 	})
 }
 
-// PrepareRoomReservation prepares a room reservation
-func (r *Repository) PrepareRoomReservation(ctx context.Context, transactionID, roomID string, checkInDate, checkOutDate string) error {
-	roomAvailabilityRefs, err := r.getRoomAvailabilityRefs(roomID, checkInDate, checkOutDate)
+// PrepareRoomModification prepares replacing the room at index of a committed booking
+// transaction. The new room is reserved right away while the replaced reservation is
+// marked MODIFYING, so the booking keeps its original room until the commit. Nights
+// and room type units already held by the replaced reservation can be reused by the new room.
+func (r *Repository) PrepareRoomModification(ctx context.Context, transactionID, bookingTransactionID string, index int, room HotelRoomItem) error {
+	bookingRef := r.client.Collection(HotelRoomTransactionCollection).Doc(bookingTransactionID)
+
+	newRefs, err := r.getRoomAvailabilityRefs(room.HotelRoomID, room.StartDate, room.EndDate)
 	if err != nil {
 		return fmt.Errorf("failed to get room availability refs: %w", err)
 	}
-
-	if len(roomAvailabilityRefs) == 0 {
+	if room.HotelRoomID != "" && len(newRefs) == 0 {
 		return ErrRoomNotAvailable
 	}
 
 	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		bookingDoc, err := tx.Get(bookingRef)
+		if err != nil {
+			return fmt.Errorf("failed to get booking transaction: %w", err)
+		}
+
+		var booking TwoPhaseTransaction
+		if err := bookingDoc.DataTo(&booking); err != nil {
+			return fmt.Errorf("failed to unmarshal booking transaction: %w", err)
+		}
+
+		if booking.Status != TwoPhaseTransactionStatusCommitted {
+			return ErrBookingNotModifiable
+		}
+		if index < 0 || index >= len(booking.ReservationIDs) {
+			return ErrItemNotFound
+		}
+
+		oldRef := r.client.Collection(HotelRoomReservationCollection).Doc(booking.ReservationIDs[index])
+		oldDoc, err := tx.Get(oldRef)
+		if err != nil {
+			return fmt.Errorf("failed to get reservation: %w", err)
+		}
+
+		var oldReservation HotelReservation
+		if err := oldDoc.DataTo(&oldReservation); err != nil {
+			return fmt.Errorf("failed to unmarshal reservation: %w", err)
+		}
+
+		if oldReservation.Status != HotelRoomReservationStatusReserved {
+			return ErrBookingNotModifiable
+		}
+
+		oldRefs, err := r.getRoomAvailabilityRefs(oldReservation.HotelRoomID, oldReservation.HotelRoomStartDate, oldReservation.HotelRoomEndDate)
+		if err != nil {
+			return fmt.Errorf("failed to get room availability refs: %w", err)
+		}
+
 		var roomAvailability HotelRoomAvailability
-		for _, ref := range roomAvailabilityRefs {
-			doc, err := tx.Get(ref)
-			if status.Code(err) == codes.NotFound {
-				return ErrRoomNotAvailable
-			}
+		if len(newRefs) > 0 {
+			docs, err := tx.GetAll(newRefs)
 			if err != nil {
 				return fmt.Errorf("failed to get room availability: %w", err)
 			}
 
-			if err := doc.DataTo(&roomAvailability); err != nil {
-				return fmt.Errorf("failed to unmarshal room availability: %w", err)
-			}
-
-			if !roomAvailability.Available {
-				return ErrRoomNotAvailable
-			}
+			for _, doc := range docs {
+				if !doc.Exists() {
+					return ErrRoomNotAvailable
+				}
 
-			roomAvailability.Available = false
+				if err := doc.DataTo(&roomAvailability); err != nil {
+					return fmt.Errorf("failed to unmarshal room availability: %w", err)
+				}
 
-			if err := tx.Update(ref, []firestore.Update{
-				{Path: "available", Value: false},
-			}); err != nil {
-				return fmt.Errorf("failed to update room availability: %w", err)
+				if !roomAvailability.Available && !containsRef(oldRefs, doc.Ref) {
+					return ErrRoomNotAvailable
+				}
 			}
 		}
 
 		hotelRoomReservation := &HotelReservation{
 			ID:                 ulid.Make().String(),
 			TransactionID:      transactionID,
-			HotelRoomID:        roomID,
+			HotelRoomID:        room.HotelRoomID,
 			HotelRoomName:      roomAvailability.RoomName,
+			RoomTypeID:         room.RoomTypeID,
 			HotelName:          roomAvailability.HotelName,
-			HotelRoomStartDate: checkInDate,
-			HotelRoomEndDate:   checkOutDate,
+			HotelRoomStartDate: room.StartDate,
+			HotelRoomEndDate:   room.EndDate,
+			Price:              room.Price,
 			Status:             HotelRoomReservationStatusReserved,
 		}
+		if room.HotelRoomID != "" {
+			hotelRoomReservation.RoomTypeID = roomAvailability.RoomTypeID
+		}
+
+		// The replaced reservation keeps its units until the commit
+		if err := r.adjustRoomTypeUnits(tx, []*HotelReservation{hotelRoomReservation}, []*HotelReservation{&oldReservation}, takenUnitsOnly); err != nil {
+			var itemErr *api.ItemError
+			if errors.As(err, &itemErr) {
+				return itemErr.Err
+			}
+			return err
+		}
+
+		for _, ref := range excludeRefs(newRefs, oldRefs) {
+			if err := tx.Update(ref, []firestore.Update{
+				{Path: "available", Value: false},
+			}); err != nil {
+				return fmt.Errorf("failed to update room availability: %w", err)
+			}
+		}
+
+		if err := tx.Update(oldRef, []firestore.Update{
+			{Path: "status", Value: HotelRoomReservationStatusModifying},
+			{Path: "updated_at", Value: time.Now()},
+		}); err != nil {
+			return fmt.Errorf("failed to update reservation: %w", err)
+		}
 
 		hotelRoomReservationRef := r.client.Collection(HotelRoomReservationCollection).Doc(hotelRoomReservation.ID)
 		if err := tx.Create(hotelRoomReservationRef, hotelRoomReservation); err != nil {
@@ -242,11 +671,13 @@ func (r *Repository) PrepareRoomReservation(ctx context.Context, transactionID,
 		}
 
 		twoPhaseTransaction := &TwoPhaseTransaction{
-			Id:            transactionID,
-			Status:        TwoPhaseTransactionStatusPrepared,
-			ReservationID: hotelRoomReservation.ID,
-			CreatedAt:     time.Now(),
-			UpdatedAt:     time.Now(),
+			Id:                    transactionID,
+			Status:                TwoPhaseTransactionStatusPrepared,
+			ReservationIDs:        []string{hotelRoomReservation.ID},
+			BookingTransactionID:  bookingTransactionID,
+			ReplacedReservationID: oldReservation.ID,
+			CreatedAt:             time.Now(),
+			UpdatedAt:             time.Now(),
 		}
 
 		twoPhaseTransactionRef := r.client.Collection(HotelRoomTransactionCollection).Doc(twoPhaseTransaction.Id)
@@ -258,17 +689,714 @@ func (r *Repository) PrepareRoomReservation(ctx context.Context, transactionID,
 	})
 }
 
-func (r *Repository) BulkWriteHotelRoomAvailability(ctx context.Context, hotelRoomAvailabilities []HotelRoomAvailability) error {
-	collection := r.client.Collection(HotelRoomAvailabilityCollection)
-	bw := r.client.BulkWriter(ctx)
+// CommitRoomModification releases the nights of the replaced reservation that the new
+// room does not use and swaps the reservation in the booking transaction
+func (r *Repository) CommitRoomModification(ctx context.Context, transactionID string) error {
+	return r.finishRoomModification(ctx, transactionID, TwoPhaseTransactionStatusCommitted)
+}
 
-	for _, hotelRoomAvailability := range hotelRoomAvailabilities {
-		docRef := collection.Doc(r.getRoomAvailabilityId(hotelRoomAvailability.RoomID, hotelRoomAvailability.Date))
-		bw.Set(docRef, hotelRoomAvailability)
-	}
+// AbortRoomModification releases the new room and puts the replaced reservation back to RESERVED
+func (r *Repository) AbortRoomModification(ctx context.Context, transactionID string) error {
+	return r.finishRoomModification(ctx, transactionID, TwoPhaseTransactionStatusAborted)
+}
 
-	// Flush all writes
-	bw.Flush()
+// finishRoomModification moves a prepared modification transaction to finalStatus. On
+// commit the replaced reservation is cancelled, on abort the new one is.
+func (r *Repository) finishRoomModification(ctx context.Context, transactionID string, finalStatus TwoPhaseTransactionStatus) error {
+	transactionRef := r.client.Collection(HotelRoomTransactionCollection).Doc(transactionID)
 
-	return nil
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		transactionDoc, err := tx.Get(transactionRef)
+		if err != nil {
+			return fmt.Errorf("failed to get transaction: %w", err)
+		}
+
+		var transaction TwoPhaseTransaction
+		if err := transactionDoc.DataTo(&transaction); err != nil {
+			return fmt.Errorf("failed to unmarshal transaction: %w", err)
+		}
+
+		if transaction.Status != TwoPhaseTransactionStatusPrepared {
+			// Already committed or aborted
+			return nil
+		}
+
+		bookingRef := r.client.Collection(HotelRoomTransactionCollection).Doc(transaction.BookingTransactionID)
+		bookingDoc, err := tx.Get(bookingRef)
+		if err != nil {
+			return fmt.Errorf("failed to get booking transaction: %w", err)
+		}
+
+		var booking TwoPhaseTransaction
+		if err := bookingDoc.DataTo(&booking); err != nil {
+			return fmt.Errorf("failed to unmarshal booking transaction: %w", err)
+		}
+
+		oldRef := r.client.Collection(HotelRoomReservationCollection).Doc(transaction.ReplacedReservationID)
+		newRef := r.client.Collection(HotelRoomReservationCollection).Doc(transaction.ReservationIDs[0])
+		reservationDocs, err := tx.GetAll([]*firestore.DocumentRef{oldRef, newRef})
+		if err != nil {
+			return fmt.Errorf("failed to get reservations: %w", err)
+		}
+
+		reservationRefs := make([][]*firestore.DocumentRef, len(reservationDocs))
+		reservations := make([]*HotelReservation, len(reservationDocs))
+		for i, reservationDoc := range reservationDocs {
+			var reservation HotelReservation
+			if err := reservationDoc.DataTo(&reservation); err != nil {
+				return fmt.Errorf("failed to unmarshal reservation: %w", err)
+			}
+			reservations[i] = &reservation
+
+			reservationRefs[i], err = r.getRoomAvailabilityRefs(reservation.HotelRoomID, reservation.HotelRoomStartDate, reservation.HotelRoomEndDate)
+			if err != nil {
+				return fmt.Errorf("failed to get room availability refs: %w", err)
+			}
+		}
+
+		// Only the nights held by the released reservation alone become available again
+		releasedRef, keptRef := oldRef, newRef
+		releasedRefs := excludeRefs(reservationRefs[0], reservationRefs[1])
+		released := reservations[0]
+		if finalStatus == TwoPhaseTransactionStatusAborted {
+			releasedRef, keptRef = newRef, oldRef
+			releasedRefs = excludeRefs(reservationRefs[1], reservationRefs[0])
+			released = reservations[1]
+		}
+
+		kept := reservations[1]
+		if finalStatus == TwoPhaseTransactionStatusAborted {
+			kept = reservations[0]
+		}
+
+		if len(releasedRefs) > 0 {
+			if _, err := tx.GetAll(releasedRefs); err != nil {
+				return fmt.Errorf("failed to get room availability: %w", err)
+			}
+		}
+
+		// The kept reservation took its units at prepare
+		if err := r.adjustRoomTypeUnits(tx, []*HotelReservation{kept}, []*HotelReservation{released}, releasedUnitsOnly); err != nil {
+			return err
+		}
+
+		for _, ref := range releasedRefs {
+			if err := tx.Update(ref, []firestore.Update{
+				{Path: "available", Value: true},
+			}); err != nil {
+				return fmt.Errorf("failed to update room availability: %w", err)
+			}
+		}
+
+		if err := tx.Update(releasedRef, []firestore.Update{
+			{Path: "status", Value: HotelRoomReservationStatusCancelled},
+			{Path: "updated_at", Value: time.Now()},
+		}); err != nil {
+			return fmt.Errorf("failed to update reservation: %w", err)
+		}
+
+		if err := tx.Update(keptRef, []firestore.Update{
+			{Path: "status", Value: HotelRoomReservationStatusReserved},
+			{Path: "updated_at", Value: time.Now()},
+		}); err != nil {
+			return fmt.Errorf("failed to update reservation: %w", err)
+		}
+
+		if finalStatus == TwoPhaseTransactionStatusCommitted {
+			reservationIDs := make([]string, 0, len(booking.ReservationIDs))
+			for _, reservationID := range booking.ReservationIDs {
+				if reservationID == transaction.ReplacedReservationID {
+					reservationID = newRef.ID
+				}
+				reservationIDs = append(reservationIDs, reservationID)
+			}
+
+			if err := tx.Update(bookingRef, []firestore.Update{
+				{Path: "reservation_ids", Value: reservationIDs},
+				{Path: "updated_at", Value: time.Now()},
+			}); err != nil {
+				return fmt.Errorf("failed to update booking transaction: %w", err)
+			}
+		}
+
+		if err := tx.Update(transactionRef, []firestore.Update{
+			{Path: "status", Value: finalStatus},
+			{Path: "updated_at", Value: time.Now()},
+		}); err != nil {
+			return fmt.Errorf("failed to update transaction: %w", err)
+		}
+
+		if len(releasedRefs) > 0 || (released.RoomTypeID != "" && !coversStay(kept, released)) {
+			if err := r.recordRelease(tx, released); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// recordRelease adds the released reservation to the inventory release outbox
+func (r *Repository) recordRelease(tx *firestore.Transaction, reservation *HotelReservation) error {
+	release := &api.InventoryRelease{
+		ID:         ulid.Make().String(),
+		Service:    "hotel",
+		ItemID:     reservation.HotelRoomID,
+		RoomTypeID: reservation.RoomTypeID,
+		StartDate:  reservation.HotelRoomStartDate,
+		EndDate:    reservation.HotelRoomEndDate,
+		CreatedAt:  time.Now(),
+	}
+	if err := tx.Create(r.client.Collection(api.InventoryReleaseCollection).Doc(release.ID), release); err != nil {
+		return fmt.Errorf("failed to record release: %w", err)
+	}
+	return nil
+}
+
+// coversStay reports whether kept holds a unit of the room type of released on every
+// night of released, so that replacing released returns no room type units
+func coversStay(kept, released *HotelReservation) bool {
+	return kept.RoomTypeID == released.RoomTypeID &&
+		kept.HotelRoomStartDate <= released.HotelRoomStartDate &&
+		kept.HotelRoomEndDate >= released.HotelRoomEndDate
+}
+
+// containsRef reports whether refs contains a reference to the same document as ref
+func containsRef(refs []*firestore.DocumentRef, ref *firestore.DocumentRef) bool {
+	for _, r := range refs {
+		if r.Path == ref.Path {
+			return true
+		}
+	}
+	return false
+}
+
+// excludeRefs returns the references of refs that are not in excluded
+func excludeRefs(refs, excluded []*firestore.DocumentRef) []*firestore.DocumentRef {
+	var result []*firestore.DocumentRef
+	for _, ref := range refs {
+		if !containsRef(excluded, ref) {
+			result = append(result, ref)
+		}
+	}
+	return result
+}
+
+// prepareRoomReservation prepares reservations for all rooms in a single transaction.
+// If any room is unavailable on any night, nothing is reserved and an *api.ItemError
+// wrapping ErrRoomNotAvailable identifies the offending item.
+func (r *Repository) prepareRoomReservation(ctx context.Context, transactionID string, rooms []HotelRoomItem) error {
+	return r.reserveRooms(ctx, transactionID, rooms, nil)
+}
+
+// HoldRooms reserves all rooms like PrepareRoomReservation, but as a hold that is
+// released by ExpireRoomHold unless it is confirmed before expiresAt
+func (r *Repository) HoldRooms(ctx context.Context, holdID string, rooms []HotelRoomItem, expiresAt time.Time) error {
+	return r.reserveRooms(ctx, holdID, rooms, &expiresAt)
+}
+
+// reserveRooms marks all nights of the rooms unavailable, takes a unit of their room type
+// per night and creates their reservations under transactionID, as a hold when expiresAt
+// is set or as a prepared transaction otherwise
+func (r *Repository) reserveRooms(ctx context.Context, transactionID string, rooms []HotelRoomItem, expiresAt *time.Time) error {
+	transactionStatus, reservationStatus := TwoPhaseTransactionStatusPrepared, HotelRoomReservationStatusReserved
+	if expiresAt != nil {
+		transactionStatus, reservationStatus = TwoPhaseTransactionStatusHeld, HotelRoomReservationStatusHeld
+	}
+
+	roomAvailabilityRefs := make([][]*firestore.DocumentRef, len(rooms))
+	seen := make(map[string]bool)
+	for i, room := range rooms {
+		refs, err := r.getRoomAvailabilityRefs(room.HotelRoomID, room.StartDate, room.EndDate)
+		if err != nil {
+			return fmt.Errorf("failed to get room availability refs: %w", err)
+		}
+
+		// Room type units are checked inside the transaction
+		if room.HotelRoomID == "" {
+			continue
+		}
+		if len(refs) == 0 {
+			return &api.ItemError{Index: i, Err: ErrRoomNotAvailable}
+		}
+
+		// The same room night cannot be booked twice within one order
+		for _, ref := range refs {
+			if seen[ref.ID] {
+				return &api.ItemError{Index: i, Err: ErrRoomNotAvailable}
+			}
+			seen[ref.ID] = true
+		}
+
+		roomAvailabilityRefs[i] = refs
+	}
+
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		roomAvailabilities := make([]HotelRoomAvailability, len(rooms))
+		for i, refs := range roomAvailabilityRefs {
+			if len(refs) == 0 {
+				continue
+			}
+
+			docs, err := tx.GetAll(refs)
+			if err != nil {
+				return fmt.Errorf("failed to get room availability: %w", err)
+			}
+
+			for _, doc := range docs {
+				if !doc.Exists() {
+					return &api.ItemError{Index: i, Err: ErrRoomNotAvailable}
+				}
+
+				if err := doc.DataTo(&roomAvailabilities[i]); err != nil {
+					return fmt.Errorf("failed to unmarshal room availability: %w", err)
+				}
+
+				if !roomAvailabilities[i].Available {
+					return &api.ItemError{Index: i, Err: ErrRoomNotAvailable}
+				}
+			}
+		}
+
+		reservations := make([]*HotelReservation, 0, len(rooms))
+		for i, room := range rooms {
+			roomTypeID := room.RoomTypeID
+			if room.HotelRoomID != "" {
+				roomTypeID = roomAvailabilities[i].RoomTypeID
+			}
+
+			reservations = append(reservations, &HotelReservation{
+				ID:                 ulid.Make().String(),
+				TransactionID:      transactionID,
+				HotelRoomID:        room.HotelRoomID,
+				HotelRoomName:      roomAvailabilities[i].RoomName,
+				RoomTypeID:         roomTypeID,
+				HotelName:          roomAvailabilities[i].HotelName,
+				HotelRoomStartDate: room.StartDate,
+				HotelRoomEndDate:   room.EndDate,
+				Price:              room.Price,
+				Status:             reservationStatus,
+			})
+		}
+
+		if err := r.adjustRoomTypeUnits(tx, reservations, nil, allUnitChanges); err != nil {
+			return err
+		}
+
+		reservationIDs := make([]string, 0, len(rooms))
+		for i, hotelRoomReservation := range reservations {
+			for _, ref := range roomAvailabilityRefs[i] {
+				if err := tx.Update(ref, []firestore.Update{
+					{Path: "available", Value: false},
+				}); err != nil {
+					return fmt.Errorf("failed to update room availability: %w", err)
+				}
+			}
+
+			hotelRoomReservationRef := r.client.Collection(HotelRoomReservationCollection).Doc(hotelRoomReservation.ID)
+			if err := tx.Create(hotelRoomReservationRef, hotelRoomReservation); err != nil {
+				return fmt.Errorf("failed to create hotel room reservation: %w", err)
+			}
+
+			reservationIDs = append(reservationIDs, hotelRoomReservation.ID)
+		}
+
+		twoPhaseTransaction := &TwoPhaseTransaction{
+			Id:             transactionID,
+			Status:         transactionStatus,
+			ReservationIDs: reservationIDs,
+			ExpiresAt:      expiresAt,
+			CreatedAt:      time.Now(),
+			UpdatedAt:      time.Now(),
+		}
+
+		twoPhaseTransactionRef := r.client.Collection(HotelRoomTransactionCollection).Doc(twoPhaseTransaction.Id)
+		if err := tx.Create(twoPhaseTransactionRef, twoPhaseTransaction); err != nil {
+			return fmt.Errorf("failed to create two-phase transaction: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// ConfirmRoomHold turns an unexpired hold into a committed booking transaction, so it can
+// later be cancelled or modified like any other booking
+func (r *Repository) ConfirmRoomHold(ctx context.Context, holdID string, now time.Time) error {
+	holdRef := r.client.Collection(HotelRoomTransactionCollection).Doc(holdID)
+
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		holdDoc, err := tx.Get(holdRef)
+		if err != nil {
+			return fmt.Errorf("failed to get hold: %w", err)
+		}
+
+		var hold TwoPhaseTransaction
+		if err := holdDoc.DataTo(&hold); err != nil {
+			return fmt.Errorf("failed to unmarshal hold: %w", err)
+		}
+
+		if hold.Status == TwoPhaseTransactionStatusCommitted && hold.ExpiresAt != nil {
+			// Already confirmed
+			return nil
+		}
+		if hold.Status != TwoPhaseTransactionStatusHeld {
+			return ErrHoldNotActive
+		}
+		if hold.ExpiresAt == nil || !now.Before(*hold.ExpiresAt) {
+			return ErrHoldExpired
+		}
+
+		for _, reservationID := range hold.ReservationIDs {
+			if err := tx.Update(r.client.Collection(HotelRoomReservationCollection).Doc(reservationID), []firestore.Update{
+				{Path: "status", Value: HotelRoomReservationStatusReserved},
+				{Path: "updated_at", Value: time.Now()},
+			}); err != nil {
+				return fmt.Errorf("failed to update reservation: %w", err)
+			}
+		}
+
+		if err := tx.Update(holdRef, []firestore.Update{
+			{Path: "status", Value: TwoPhaseTransactionStatusCommitted},
+			{Path: "updated_at", Value: time.Now()},
+		}); err != nil {
+			return fmt.Errorf("failed to update hold: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// ReleaseRoomHold releases the rooms of a hold before it expires
+func (r *Repository) ReleaseRoomHold(ctx context.Context, holdID string) error {
+	return r.releaseRoomReservations(ctx, holdID, TwoPhaseTransactionStatusHeld, TwoPhaseTransactionStatusAborted)
+}
+
+// ExpireRoomHold releases the rooms of an expired hold
+func (r *Repository) ExpireRoomHold(ctx context.Context, holdID string) error {
+	return r.releaseRoomReservations(ctx, holdID, TwoPhaseTransactionStatusHeld, TwoPhaseTransactionStatusExpired)
+}
+
+// GetExpiredHoldIDs returns the IDs of the holds that expired before now
+func (r *Repository) GetExpiredHoldIDs(ctx context.Context, now time.Time) ([]string, error) {
+	docs, err := r.client.Collection(HotelRoomTransactionCollection).
+		Where("status", "==", TwoPhaseTransactionStatusHeld).
+		Where("expires_at", "<=", now).
+		Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expired holds: %w", err)
+	}
+
+	holdIDs := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		holdIDs = append(holdIDs, doc.Ref.ID)
+	}
+
+	return holdIDs, nil
+}
+
+// AssignRoom assigns a room of its room type to a reservation of a committed room type
+// booking. The first room that is free on every night of the stay is marked unavailable
+// for those nights; the room type unit was already taken when the booking was prepared.
+// Reservations that already have a room are returned as they are.
+func (r *Repository) AssignRoom(ctx context.Context, reservationID string) (*HotelReservation, error) {
+	reservationRef := r.client.Collection(HotelRoomReservationCollection).Doc(reservationID)
+
+	var assigned *HotelReservation
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		assigned = nil
+		reservationDoc, err := tx.Get(reservationRef)
+		if status.Code(err) == codes.NotFound {
+			return ErrReservationNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get reservation: %w", err)
+		}
+
+		var reservation HotelReservation
+		if err := reservationDoc.DataTo(&reservation); err != nil {
+			return fmt.Errorf("failed to unmarshal reservation: %w", err)
+		}
+
+		if reservation.HotelRoomID != "" {
+			assigned = &reservation
+			return nil
+		}
+		if reservation.RoomTypeID == "" || reservation.Status != HotelRoomReservationStatusReserved {
+			return ErrRoomNotAssignable
+		}
+
+		transactionDoc, err := tx.Get(r.client.Collection(HotelRoomTransactionCollection).Doc(reservation.TransactionID))
+		if err != nil {
+			return fmt.Errorf("failed to get transaction: %w", err)
+		}
+
+		var transaction TwoPhaseTransaction
+		if err := transactionDoc.DataTo(&transaction); err != nil {
+			return fmt.Errorf("failed to unmarshal transaction: %w", err)
+		}
+
+		if transaction.Status != TwoPhaseTransactionStatusCommitted {
+			return ErrRoomNotAssignable
+		}
+
+		// Candidates are the rooms of the type that are free on the first night
+		candidateDocs, err := tx.Documents(r.client.Collection(HotelRoomAvailabilityCollection).
+			Where("room_type_id", "==", reservation.RoomTypeID).
+			Where("date", "==", reservation.HotelRoomStartDate).
+			Where("available", "==", true)).GetAll()
+		if err != nil {
+			return fmt.Errorf("failed to search room availability: %w", err)
+		}
+
+		for _, candidateDoc := range candidateDocs {
+			var candidate HotelRoomAvailability
+			if err := candidateDoc.DataTo(&candidate); err != nil {
+				return fmt.Errorf("failed to unmarshal room availability: %w", err)
+			}
+
+			refs, err := r.getRoomAvailabilityRefs(candidate.RoomID, reservation.HotelRoomStartDate, reservation.HotelRoomEndDate)
+			if err != nil {
+				return fmt.Errorf("failed to get room availability refs: %w", err)
+			}
+
+			docs, err := tx.GetAll(refs)
+			if err != nil {
+				return fmt.Errorf("failed to get room availability: %w", err)
+			}
+
+			free := true
+			for _, doc := range docs {
+				var availability HotelRoomAvailability
+				if !doc.Exists() {
+					free = false
+					break
+				}
+				if err := doc.DataTo(&availability); err != nil {
+					return fmt.Errorf("failed to unmarshal room availability: %w", err)
+				}
+				if !availability.Available {
+					free = false
+					break
+				}
+			}
+			if !free {
+				continue
+			}
+
+			for _, ref := range refs {
+				if err := tx.Update(ref, []firestore.Update{
+					{Path: "available", Value: false},
+				}); err != nil {
+					return fmt.Errorf("failed to update room availability: %w", err)
+				}
+			}
+
+			if err := tx.Update(reservationRef, []firestore.Update{
+				{Path: "hotel_room_id", Value: candidate.RoomID},
+				{Path: "hotel_room_name", Value: candidate.RoomName},
+				{Path: "updated_at", Value: time.Now()},
+			}); err != nil {
+				return fmt.Errorf("failed to update reservation: %w", err)
+			}
+
+			reservation.HotelRoomID = candidate.RoomID
+			reservation.HotelRoomName = candidate.RoomName
+			assigned = &reservation
+			return nil
+		}
+
+		return ErrNoRoomToAssign
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return assigned, nil
+}
+
+// SearchAvailableRooms returns up to limit rooms, ordered by ID and starting after the room
+// pageToken, that are available on all dates. The returned token is empty once there are
+// no more rooms to search.
+func (r *Repository) SearchAvailableRooms(ctx context.Context, city, hotelName string, dates []string, pageToken string, limit int) ([]AvailableRoom, string, error) {
+	// Every room has one availability document per date, so a room is available when all
+	// of its documents for the dates are
+	query := r.client.Collection(HotelRoomAvailabilityCollection).
+		Where("date", "in", dates).
+		Where("available", "==", true)
+	if city != "" {
+		query = query.Where("city", "==", city)
+	}
+	if hotelName != "" {
+		query = query.Where("hotel_name", "==", hotelName)
+	}
+	query = query.OrderBy("room_id", firestore.Asc)
+	if pageToken != "" {
+		query = query.StartAfter(pageToken)
+	}
+
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+
+	rooms := make([]AvailableRoom, 0, limit)
+	var current *HotelRoomAvailability
+	availableNights := 0
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to search room availability: %w", err)
+		}
+
+		var availability HotelRoomAvailability
+		if err := doc.DataTo(&availability); err != nil {
+			return nil, "", fmt.Errorf("failed to unmarshal room availability: %w", err)
+		}
+
+		if current != nil && availability.RoomID == current.RoomID {
+			availableNights++
+			continue
+		}
+
+		if current != nil && availableNights == len(dates) {
+			rooms = append(rooms, availableRoom(current))
+			if len(rooms) == limit {
+				return rooms, current.RoomID, nil
+			}
+		}
+		current = &availability
+		availableNights = 1
+	}
+
+	if current != nil && availableNights == len(dates) {
+		rooms = append(rooms, availableRoom(current))
+	}
+
+	return rooms, "", nil
+}
+
+func availableRoom(availability *HotelRoomAvailability) AvailableRoom {
+	return AvailableRoom{
+		RoomID:    availability.RoomID,
+		HotelName: availability.HotelName,
+		RoomName:  availability.RoomName,
+		City:      availability.City,
+	}
+}
+
+// GetRoomCalendar returns the availability of the room on each of the dates, which must be
+// in ascending order. Dates without an availability document are unavailable without a status.
+func (r *Repository) GetRoomCalendar(ctx context.Context, roomID string, dates []string) ([]CalendarDay, error) {
+	refs := make([]*firestore.DocumentRef, 0, len(dates))
+	for _, date := range dates {
+		refs = append(refs, r.client.Collection(HotelRoomAvailabilityCollection).Doc(r.getRoomAvailabilityId(roomID, date)))
+	}
+
+	docs, err := r.client.GetAll(ctx, refs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get room availability: %w", err)
+	}
+
+	days := make([]CalendarDay, len(dates))
+	blocked := false
+	for i, doc := range docs {
+		days[i].Date = dates[i]
+		if !doc.Exists() {
+			continue
+		}
+
+		var availability HotelRoomAvailability
+		if err := doc.DataTo(&availability); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal room availability: %w", err)
+		}
+
+		days[i].Available = availability.Available
+		blocked = blocked || !availability.Available
+	}
+	if !blocked {
+		return days, nil
+	}
+
+	// Label the unavailable dates with the status of the reservation blocking them
+	reservationDocs, err := r.client.Collection(HotelRoomReservationCollection).
+		Where("hotel_room_id", "==", roomID).
+		Where("hotel_room_start_date", "<=", dates[len(dates)-1]).
+		Where("hotel_room_end_date", ">=", dates[0]).
+		Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get room reservations: %w", err)
+	}
+
+	var reservations []HotelReservation
+	var transactionRefs []*firestore.DocumentRef
+	for _, doc := range reservationDocs {
+		var reservation HotelReservation
+		if err := doc.DataTo(&reservation); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal reservation: %w", err)
+		}
+		if reservation.Status == HotelRoomReservationStatusCancelled {
+			continue
+		}
+
+		reservations = append(reservations, reservation)
+		transactionRefs = append(transactionRefs, r.client.Collection(HotelRoomTransactionCollection).Doc(reservation.TransactionID))
+	}
+	if len(reservations) == 0 {
+		return days, nil
+	}
+
+	transactionDocs, err := r.client.GetAll(ctx, transactionRefs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transactions: %w", err)
+	}
+
+	for i, reservation := range reservations {
+		status := BlockingStatusReserved
+		if reservation.Status == HotelRoomReservationStatusHeld {
+			status = BlockingStatusHeld
+		} else if transactionDocs[i].Exists() {
+			var transaction TwoPhaseTransaction
+			if err := transactionDocs[i].DataTo(&transaction); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal transaction: %w", err)
+			}
+			if transaction.Status == TwoPhaseTransactionStatusPrepared {
+				status = BlockingStatusPrepared
+			}
+		}
+
+		for j := range days {
+			if !days[j].Available && days[j].Date >= reservation.HotelRoomStartDate && days[j].Date <= reservation.HotelRoomEndDate {
+				days[j].Status = status
+			}
+		}
+	}
+
+	return days, nil
+}
+
+// BulkWriteHotelRoomAvailability seeds room availabilities. An upsert keeps the available
+// flag of existing documents.
+func (r *Repository) BulkWriteHotelRoomAvailability(ctx context.Context, hotelRoomAvailabilities []HotelRoomAvailability, mode utils.WriteMode) (utils.BulkWriteResult, error) {
+	collection := r.client.Collection(HotelRoomAvailabilityCollection)
+
+	docs := make([]utils.Doc, 0, len(hotelRoomAvailabilities))
+	for _, hotelRoomAvailability := range hotelRoomAvailabilities {
+		docs = append(docs, utils.Doc{Ref: collection.Doc(r.getRoomAvailabilityId(hotelRoomAvailability.RoomID, hotelRoomAvailability.Date)), Data: hotelRoomAvailability})
+	}
+
+	return utils.BulkWrite(ctx, r.client, docs, mode, "room_id", "hotel_name", "room_name", "city", "date", "room_type_id")
+}
+
+// BulkWriteRoomTypeAvailability seeds room type availabilities. An upsert keeps the
+// available units of existing documents.
+func (r *Repository) BulkWriteRoomTypeAvailability(ctx context.Context, roomTypeAvailabilities []RoomTypeAvailability, mode utils.WriteMode) (utils.BulkWriteResult, error) {
+	collection := r.client.Collection(HotelRoomTypeAvailabilityCollection)
+
+	docs := make([]utils.Doc, 0, len(roomTypeAvailabilities))
+	for _, roomTypeAvailability := range roomTypeAvailabilities {
+		docs = append(docs, utils.Doc{Ref: collection.Doc(r.getRoomAvailabilityId(roomTypeAvailability.RoomTypeID, roomTypeAvailability.Date)), Data: roomTypeAvailability})
+	}
+
+	return utils.BulkWrite(ctx, r.client, docs, mode, "room_type_id", "hotel_name", "room_type_name", "city", "date", "total_units")
 }
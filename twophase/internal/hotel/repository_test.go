@@ -0,0 +1,144 @@
+package hotel
+
+import (
+	"errors"
+	"maps"
+	"testing"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/api"
+)
+
+func TestAdjustRoomTypeUnits(t *testing.T) {
+	stay := func(roomTypeID, startDate, endDate string) *HotelReservation {
+		return &HotelReservation{RoomTypeID: roomTypeID, HotelRoomStartDate: startDate, HotelRoomEndDate: endDate}
+	}
+
+	tests := []struct {
+		name      string
+		taken     []*HotelReservation
+		released  []*HotelReservation
+		changes   unitChanges
+		available int
+		// want holds the available units of every changed document, with 2 units per room type
+		want           map[string]int
+		wantFailedItem int
+		wantErr        error
+		wantDateErr    bool
+	}{
+		{
+			name:      "take every night of the stay",
+			taken:     []*HotelReservation{stay("deluxe", "2025-12-01", "2025-12-02")},
+			available: 2,
+			want:      map[string]int{"deluxe-2025-12-01": 1, "deluxe-2025-12-02": 1},
+		},
+		{
+			name:      "two reservations of the same night take two units",
+			taken:     []*HotelReservation{stay("deluxe", "2025-12-01", "2025-12-01"), stay("deluxe", "2025-12-01", "2025-12-01")},
+			available: 2,
+			want:      map[string]int{"deluxe-2025-12-01": 0},
+		},
+		{
+			name:      "release is capped at the total units",
+			released:  []*HotelReservation{stay("deluxe", "2025-12-01", "2025-12-01")},
+			available: 2,
+			want:      map[string]int{"deluxe-2025-12-01": 2},
+		},
+		{
+			name:      "modification on the same nights changes nothing",
+			taken:     []*HotelReservation{stay("deluxe", "2025-12-01", "2025-12-02")},
+			released:  []*HotelReservation{stay("deluxe", "2025-12-01", "2025-12-02")},
+			available: 0,
+			want:      map[string]int{},
+		},
+		{
+			name:      "modification shifted by one night only changes the outer nights",
+			taken:     []*HotelReservation{stay("deluxe", "2025-12-02", "2025-12-03")},
+			released:  []*HotelReservation{stay("deluxe", "2025-12-01", "2025-12-02")},
+			available: 1,
+			want:      map[string]int{"deluxe-2025-12-01": 2, "deluxe-2025-12-03": 0},
+		},
+		{
+			name:      "modification to another room type",
+			taken:     []*HotelReservation{stay("suite", "2025-12-01", "2025-12-01")},
+			released:  []*HotelReservation{stay("deluxe", "2025-12-01", "2025-12-01")},
+			available: 1,
+			want:      map[string]int{"deluxe-2025-12-01": 2, "suite-2025-12-01": 0},
+		},
+		{
+			name:      "prepared modification only takes units",
+			taken:     []*HotelReservation{stay("deluxe", "2025-12-02", "2025-12-03")},
+			released:  []*HotelReservation{stay("deluxe", "2025-12-01", "2025-12-02")},
+			changes:   takenUnitsOnly,
+			available: 1,
+			want:      map[string]int{"deluxe-2025-12-03": 0},
+		},
+		{
+			name:      "committed modification only returns units",
+			taken:     []*HotelReservation{stay("deluxe", "2025-12-02", "2025-12-03")},
+			released:  []*HotelReservation{stay("deluxe", "2025-12-01", "2025-12-02")},
+			changes:   releasedUnitsOnly,
+			available: 1,
+			want:      map[string]int{"deluxe-2025-12-01": 2},
+		},
+		{
+			name:      "physical rooms are skipped",
+			taken:     []*HotelReservation{stay("", "2025-12-01", "2025-12-02")},
+			available: 0,
+			want:      map[string]int{},
+		},
+		{
+			name:           "sold out night fails the first reservation taking it",
+			taken:          []*HotelReservation{stay("", "2025-12-01", "2025-12-01"), stay("deluxe", "2025-12-01", "2025-12-01")},
+			available:      0,
+			wantFailedItem: 1,
+			wantErr:        ErrRoomNotAvailable,
+		},
+		{
+			name:        "invalid date",
+			taken:       []*HotelReservation{stay("deluxe", "2025-12-01", "01-12-2025")},
+			available:   2,
+			wantDateErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			units, err := netRoomTypeUnits(tt.taken, tt.released)
+			got := make(map[string]int)
+			if err == nil {
+				for _, id := range units.ids {
+					availability := &RoomTypeAvailability{TotalUnits: 2, AvailableUnits: tt.available}
+					var changed bool
+					if changed, err = units.apply(availability, id, tt.changes); err != nil {
+						break
+					}
+					if changed {
+						got[id] = availability.AvailableUnits
+					}
+				}
+			}
+
+			var itemErr *api.ItemError
+			switch {
+			case tt.wantDateErr:
+				if err == nil || errors.As(err, &itemErr) {
+					t.Errorf("error = %v, want a date parse error", err)
+				}
+			case tt.wantErr == nil:
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if !maps.Equal(got, tt.want) {
+					t.Errorf("available units = %v, want %v", got, tt.want)
+				}
+			default:
+				if !errors.As(err, &itemErr) || !errors.Is(itemErr.Err, tt.wantErr) {
+					t.Fatalf("error = %v, want item error %v", err, tt.wantErr)
+				}
+				if itemErr.Index != tt.wantFailedItem {
+					t.Errorf("failed item = %d, want %d", itemErr.Index, tt.wantFailedItem)
+				}
+			}
+		})
+	}
+}
@@ -2,7 +2,9 @@ package hotel
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"time"
 
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/api"
@@ -11,13 +13,15 @@ import (
 
 // Service handles hotel business logic with two-phase commit
 type Service struct {
-	repo *Repository
+	repo    *Repository
+	holdTTL time.Duration
 }
 
-// NewService creates a new hotel service
-func NewService(repo *Repository) *Service {
+// NewService creates a new hotel service. Holds last holdTTL unless the request sets its own TTL.
+func NewService(repo *Repository, holdTTL time.Duration) *Service {
 	return &Service{
-		repo: repo,
+		repo:    repo,
+		holdTTL: holdTTL,
 	}
 }
 
@@ -33,33 +37,27 @@ func (s *Service) Prepare(ctx context.Context, req *api.PrepareRequest[HotelRoom
 		}, nil
 	}
 
-	startDate, err := time.Parse(config.DateFormat, req.Payload.HotelRoomStartDate)
-	if err != nil {
-		return &api.PrepareResponse{
-			Success: false,
-			Message: fmt.Sprintf("Failed to parse start date: %v", err),
-		}, nil
-	}
-
-	endDate, err := time.Parse(config.DateFormat, req.Payload.HotelRoomEndDate)
-	if err != nil {
+	rooms, itemErr := parseRooms(req.Payload.HotelRooms)
+	if itemErr != nil {
 		return &api.PrepareResponse{
-			Success: false,
-			Message: fmt.Sprintf("Failed to parse end date: %v", err),
+			Success:    false,
+			Message:    fmt.Sprintf("Failed to parse item: %v", itemErr.Err),
+			FailedItem: &itemErr.Index,
 		}, nil
 	}
 
-	if err := s.repo.PrepareRoomReservation(
-		ctx,
-		req.TransactionID,
-		req.Payload.HotelRoomID,
-		startDate.Format(config.DateFormat),
-		endDate.Format(config.DateFormat),
-	); err != nil {
-		return &api.PrepareResponse{
+	if err := s.repo.PrepareRoomReservation(ctx, req.TransactionID, rooms); err != nil {
+		response := &api.PrepareResponse{
 			Success: false,
 			Message: fmt.Sprintf("Failed to create transaction log: %v", err),
-		}, nil
+		}
+
+		var itemErr *api.ItemError
+		if errors.As(err, &itemErr) {
+			response.FailedItem = &itemErr.Index
+		}
+
+		return response, nil
 	}
 
 	return &api.PrepareResponse{
@@ -97,3 +95,328 @@ func (s *Service) Abort(ctx context.Context, req *api.AbortRequest) (*api.AbortR
 		Message: "Hotel service aborted successfully",
 	}, nil
 }
+
+// PrepareCancellation handles the prepare phase of a cancellation transaction
+func (s *Service) PrepareCancellation(ctx context.Context, req *api.PrepareRequest[api.CancellationPayload]) (*api.PrepareResponse, error) {
+	// Check if transaction already exists
+	existingTransaction, err := s.repo.GetTwoPhaseTransaction(ctx, req.TransactionID)
+	if err == nil && existingTransaction != nil {
+		return &api.PrepareResponse{
+			Success: existingTransaction.Status == TwoPhaseTransactionStatusPrepared,
+			Message: fmt.Sprintf("Transaction already %s", existingTransaction.Status),
+		}, nil
+	}
+
+	if err := s.repo.PrepareRoomCancellation(ctx, req.TransactionID, req.Payload.BookingTransactionID); err != nil {
+		return &api.PrepareResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to prepare cancellation: %v", err),
+		}, nil
+	}
+
+	return &api.PrepareResponse{
+		Success: true,
+		Message: "Hotel service prepared cancellation successfully",
+	}, nil
+}
+
+// CommitCancellation handles the commit phase of a cancellation transaction
+func (s *Service) CommitCancellation(ctx context.Context, req *api.CommitRequest) (*api.CommitResponse, error) {
+	if err := s.repo.CommitRoomCancellation(ctx, req.TransactionID); err != nil {
+		return &api.CommitResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to commit cancellation: %v", err),
+		}, nil
+	}
+
+	return &api.CommitResponse{
+		Success: true,
+		Message: "Hotel service committed cancellation successfully",
+	}, nil
+}
+
+// AbortCancellation handles the abort phase of a cancellation transaction
+func (s *Service) AbortCancellation(ctx context.Context, req *api.AbortRequest) (*api.AbortResponse, error) {
+	if err := s.repo.AbortRoomCancellation(ctx, req.TransactionID); err != nil {
+		return &api.AbortResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to abort cancellation: %v", err),
+		}, nil
+	}
+
+	return &api.AbortResponse{
+		Success: true,
+		Message: "Hotel service aborted cancellation successfully",
+	}, nil
+}
+
+// PrepareModification handles the prepare phase of a modification transaction
+func (s *Service) PrepareModification(ctx context.Context, req *api.PrepareRequest[HotelRoomModificationPayload]) (*api.PrepareResponse, error) {
+	// Check if transaction already exists
+	existingTransaction, err := s.repo.GetTwoPhaseTransaction(ctx, req.TransactionID)
+	if err == nil && existingTransaction != nil {
+		return &api.PrepareResponse{
+			Success: existingTransaction.Status == TwoPhaseTransactionStatusPrepared,
+			Message: fmt.Sprintf("Transaction already %s", existingTransaction.Status),
+		}, nil
+	}
+
+	room := req.Payload.HotelRoom
+	startDate, err := time.Parse(config.DateFormat, room.StartDate)
+	if err != nil {
+		return &api.PrepareResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to parse start date: %v", err),
+		}, nil
+	}
+
+	endDate, err := time.Parse(config.DateFormat, room.EndDate)
+	if err != nil {
+		return &api.PrepareResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to parse end date: %v", err),
+		}, nil
+	}
+
+	room.StartDate = startDate.Format(config.DateFormat)
+	room.EndDate = endDate.Format(config.DateFormat)
+	if err := s.repo.PrepareRoomModification(ctx, req.TransactionID, req.Payload.BookingTransactionID, req.Payload.Index, room); err != nil {
+		return &api.PrepareResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to prepare modification: %v", err),
+		}, nil
+	}
+
+	return &api.PrepareResponse{
+		Success: true,
+		Message: "Hotel service prepared modification successfully",
+	}, nil
+}
+
+// CommitModification handles the commit phase of a modification transaction
+func (s *Service) CommitModification(ctx context.Context, req *api.CommitRequest) (*api.CommitResponse, error) {
+	if err := s.repo.CommitRoomModification(ctx, req.TransactionID); err != nil {
+		return &api.CommitResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to commit modification: %v", err),
+		}, nil
+	}
+
+	return &api.CommitResponse{
+		Success: true,
+		Message: "Hotel service committed modification successfully",
+	}, nil
+}
+
+// AbortModification handles the abort phase of a modification transaction
+func (s *Service) AbortModification(ctx context.Context, req *api.AbortRequest) (*api.AbortResponse, error) {
+	if err := s.repo.AbortRoomModification(ctx, req.TransactionID); err != nil {
+		return &api.AbortResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to abort modification: %v", err),
+		}, nil
+	}
+
+	return &api.AbortResponse{
+		Success: true,
+		Message: "Hotel service aborted modification successfully",
+	}, nil
+}
+
+// parseRooms normalizes the dates of all rooms to config.DateFormat
+func parseRooms(items []HotelRoomItem) ([]HotelRoomItem, *api.ItemError) {
+	rooms := make([]HotelRoomItem, 0, len(items))
+	for i, room := range items {
+		startDate, err := time.Parse(config.DateFormat, room.StartDate)
+		if err != nil {
+			return nil, &api.ItemError{Index: i, Err: fmt.Errorf("invalid start date: %w", err)}
+		}
+
+		endDate, err := time.Parse(config.DateFormat, room.EndDate)
+		if err != nil {
+			return nil, &api.ItemError{Index: i, Err: fmt.Errorf("invalid end date: %w", err)}
+		}
+
+		rooms = append(rooms, HotelRoomItem{
+			HotelRoomID: room.HotelRoomID,
+			RoomTypeID:  room.RoomTypeID,
+			StartDate:   startDate.Format(config.DateFormat),
+			EndDate:     endDate.Format(config.DateFormat),
+			Price:       room.Price,
+		})
+	}
+
+	return rooms, nil
+}
+
+// Hold places a hold on the rooms until it is confirmed, released or expires
+func (s *Service) Hold(ctx context.Context, req *api.HoldRequest[HotelRoomReservationPayload]) (*api.HoldResponse, error) {
+	// Check if hold already exists
+	existingHold, err := s.repo.GetTwoPhaseTransaction(ctx, req.HoldID)
+	if err == nil && existingHold != nil {
+		return &api.HoldResponse{
+			Success:   existingHold.Status == TwoPhaseTransactionStatusHeld,
+			Message:   fmt.Sprintf("Hold already %s", existingHold.Status),
+			ExpiresAt: existingHold.ExpiresAt,
+		}, nil
+	}
+
+	rooms, itemErr := parseRooms(req.Payload.HotelRooms)
+	if itemErr != nil {
+		return &api.HoldResponse{
+			Success:    false,
+			Message:    fmt.Sprintf("Failed to parse item: %v", itemErr.Err),
+			FailedItem: &itemErr.Index,
+		}, nil
+	}
+
+	ttl := s.holdTTL
+	if req.TTLSeconds > 0 {
+		ttl = time.Duration(req.TTLSeconds) * time.Second
+	}
+	expiresAt := time.Now().Add(ttl)
+
+	if err := s.repo.HoldRooms(ctx, req.HoldID, rooms, expiresAt); err != nil {
+		response := &api.HoldResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to hold rooms: %v", err),
+		}
+
+		var itemErr *api.ItemError
+		if errors.As(err, &itemErr) {
+			response.FailedItem = &itemErr.Index
+		}
+
+		return response, nil
+	}
+
+	return &api.HoldResponse{
+		Success:   true,
+		Message:   "Hotel service held rooms successfully",
+		ExpiresAt: &expiresAt,
+	}, nil
+}
+
+// ConfirmHold turns a hold into a committed booking transaction
+func (s *Service) ConfirmHold(ctx context.Context, req *api.ConfirmHoldRequest) (*api.CommitResponse, error) {
+	if err := s.repo.ConfirmRoomHold(ctx, req.HoldID, time.Now()); err != nil {
+		return &api.CommitResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to confirm hold: %v", err),
+		}, nil
+	}
+
+	return &api.CommitResponse{
+		Success: true,
+		Message: "Hotel service confirmed hold successfully",
+	}, nil
+}
+
+// ReleaseHold releases a hold before it expires
+func (s *Service) ReleaseHold(ctx context.Context, req *api.ReleaseHoldRequest) (*api.AbortResponse, error) {
+	if err := s.repo.ReleaseRoomHold(ctx, req.HoldID); err != nil {
+		return &api.AbortResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to release hold: %v", err),
+		}, nil
+	}
+
+	return &api.AbortResponse{
+		Success: true,
+		Message: "Hotel service released hold successfully",
+	}, nil
+}
+
+// ReleaseExpiredHolds releases every hold that expired without being confirmed. It is
+// called periodically by the sweeper of the hotel service.
+func (s *Service) ReleaseExpiredHolds(ctx context.Context) error {
+	holdIDs, err := s.repo.GetExpiredHoldIDs(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, holdID := range holdIDs {
+		if err := s.repo.ExpireRoomHold(ctx, holdID); err != nil {
+			errs = append(errs, fmt.Errorf("failed to expire hold %s: %w", holdID, err))
+		}
+	}
+	if len(holdIDs) > 0 {
+		log.Printf("Released %d expired holds", len(holdIDs))
+	}
+
+	return errors.Join(errs...)
+}
+
+// AssignRoom assigns a room to a reservation of a committed room type booking
+func (s *Service) AssignRoom(ctx context.Context, reservationID string) (*HotelReservation, error) {
+	return s.repo.AssignRoom(ctx, reservationID)
+}
+
+const (
+	// maxSearchNights bounds the date range of a search, as Firestore accepts at most
+	// 30 values in an "in" filter
+	maxSearchNights = 30
+	// maxCalendarNights bounds the date range of a calendar to about two months
+	maxCalendarNights = 62
+)
+
+// dateRange returns every date from startDate to endDate (inclusive), which must span
+// 1 to maxNights nights
+func dateRange(startDate, endDate string, maxNights int) ([]string, error) {
+	start, err := time.Parse(config.DateFormat, startDate)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid start date: %v", ErrInvalidDateRange, err)
+	}
+
+	end, err := time.Parse(config.DateFormat, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid end date: %v", ErrInvalidDateRange, err)
+	}
+
+	var dates []string
+	for date := start; !date.After(end) && len(dates) <= maxNights; date = date.AddDate(0, 0, 1) {
+		dates = append(dates, date.Format(config.DateFormat))
+	}
+	if len(dates) == 0 || len(dates) > maxNights {
+		return nil, fmt.Errorf("%w: must span 1 to %d nights", ErrInvalidDateRange, maxNights)
+	}
+
+	return dates, nil
+}
+
+// SearchRooms returns a page of the rooms available on every night of the query
+func (s *Service) SearchRooms(ctx context.Context, query *RoomSearchQuery) (*api.Page[AvailableRoom], error) {
+	dates, err := dateRange(query.StartDate, query.EndDate, maxSearchNights)
+	if err != nil {
+		return nil, err
+	}
+
+	rooms, nextPageToken, err := s.repo.SearchAvailableRooms(ctx, query.City, query.HotelName, dates, query.PageToken, query.Limit())
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.Page[AvailableRoom]{
+		Items:         rooms,
+		NextPageToken: nextPageToken,
+	}, nil
+}
+
+// GetRoomCalendar returns the availability of the room on every night of the query
+func (s *Service) GetRoomCalendar(ctx context.Context, roomID string, query *CalendarQuery) (*RoomCalendar, error) {
+	dates, err := dateRange(query.From, query.To, maxCalendarNights)
+	if err != nil {
+		return nil, err
+	}
+
+	days, err := s.repo.GetRoomCalendar(ctx, roomID, dates)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RoomCalendar{
+		HotelRoomID: roomID,
+		Days:        days,
+	}, nil
+}
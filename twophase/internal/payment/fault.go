@@ -0,0 +1,35 @@
+package payment
+
+import (
+	"context"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/fault"
+)
+
+// WithFaults makes the repository fail the prepare, commit and abort of payments as
+// configured in injector
+func (r *Repository) WithFaults(injector *fault.Injector) *Repository {
+	r.faults = injector
+	return r
+}
+
+// PreparePayment runs preparePayment, failing before or after it when a fault is injected
+func (r *Repository) PreparePayment(ctx context.Context, payment *Payment) error {
+	return r.faults.Do("PreparePayment", payment.TransactionID, func() error {
+		return r.preparePayment(ctx, payment)
+	})
+}
+
+// CommitPayment runs commitPayment, failing before or after it when a fault is injected
+func (r *Repository) CommitPayment(ctx context.Context, transactionID string) error {
+	return r.faults.Do("CommitPayment", transactionID, func() error {
+		return r.commitPayment(ctx, transactionID)
+	})
+}
+
+// AbortPayment runs abortPayment, failing before or after it when a fault is injected
+func (r *Repository) AbortPayment(ctx context.Context, transactionID string) error {
+	return r.faults.Do("AbortPayment", transactionID, func() error {
+		return r.abortPayment(ctx, transactionID)
+	})
+}
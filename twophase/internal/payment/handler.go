@@ -0,0 +1,293 @@
+package payment
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/api"
+)
+
+// Handler handles HTTP requests for payment service
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new handler instance
+func NewHandler(service *Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// RegisterRoutes registers all routes for payment service
+func (h *Handler) RegisterRoutes(r *gin.Engine) {
+	// Two-phase commit endpoints
+	twophase := r.Group("/twophase")
+	{
+		twophase.POST("/prepare", h.Prepare)
+		twophase.POST("/commit", h.Commit)
+		twophase.POST("/abort", h.Abort)
+	}
+
+	// Cancellation of a committed booking, run as its own two-phase commit transaction
+	cancel := r.Group("/twophase/cancel")
+	{
+		cancel.POST("/prepare", h.PrepareCancellation)
+		cancel.POST("/commit", h.CommitCancellation)
+		cancel.POST("/abort", h.AbortCancellation)
+	}
+
+	// Modification of a committed booking, charging or refunding the price difference
+	modify := r.Group("/twophase/modify")
+	{
+		modify.POST("/prepare", h.PrepareModification)
+		modify.POST("/commit", h.CommitModification)
+		modify.POST("/abort", h.AbortModification)
+	}
+
+	// Health check
+	// r.GET("/health", h.HealthCheck)
+}
+
+// Prepare handles prepare phase requests
+func (h *Handler) Prepare(c *gin.Context) {
+	var req api.PrepareRequest[PaymentPayload]
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	response, err := h.service.Prepare(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to prepare transaction",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if response.Success {
+		c.JSON(http.StatusOK, response)
+	} else {
+		c.JSON(http.StatusBadRequest, response)
+	}
+}
+
+// Commit handles commit phase requests
+func (h *Handler) Commit(c *gin.Context) {
+	var req api.CommitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	response, err := h.service.Commit(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to commit transaction",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if response.Success {
+		c.JSON(http.StatusOK, response)
+	} else {
+		c.JSON(http.StatusBadRequest, response)
+	}
+}
+
+// Abort handles abort phase requests
+func (h *Handler) Abort(c *gin.Context) {
+	var req api.AbortRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	response, err := h.service.Abort(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to abort transaction",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if response.Success {
+		c.JSON(http.StatusOK, response)
+	} else {
+		c.JSON(http.StatusBadRequest, response)
+	}
+}
+
+// PrepareCancellation handles prepare phase requests of a cancellation
+func (h *Handler) PrepareCancellation(c *gin.Context) {
+	var req api.PrepareRequest[api.CancellationPayload]
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	response, err := h.service.PrepareCancellation(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to prepare cancellation",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if response.Success {
+		c.JSON(http.StatusOK, response)
+	} else {
+		c.JSON(http.StatusBadRequest, response)
+	}
+}
+
+// CommitCancellation handles commit phase requests of a cancellation
+func (h *Handler) CommitCancellation(c *gin.Context) {
+	var req api.CommitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	response, err := h.service.CommitCancellation(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to commit cancellation",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if response.Success {
+		c.JSON(http.StatusOK, response)
+	} else {
+		c.JSON(http.StatusBadRequest, response)
+	}
+}
+
+// AbortCancellation handles abort phase requests of a cancellation
+func (h *Handler) AbortCancellation(c *gin.Context) {
+	var req api.AbortRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	response, err := h.service.AbortCancellation(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to abort cancellation",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if response.Success {
+		c.JSON(http.StatusOK, response)
+	} else {
+		c.JSON(http.StatusBadRequest, response)
+	}
+}
+
+// PrepareModification handles prepare phase requests of a modification
+func (h *Handler) PrepareModification(c *gin.Context) {
+	var req api.PrepareRequest[AdjustmentPayload]
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	response, err := h.service.PrepareModification(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to prepare modification",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if response.Success {
+		c.JSON(http.StatusOK, response)
+	} else {
+		c.JSON(http.StatusBadRequest, response)
+	}
+}
+
+// CommitModification handles commit phase requests of a modification
+func (h *Handler) CommitModification(c *gin.Context) {
+	var req api.CommitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	response, err := h.service.CommitModification(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to commit modification",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if response.Success {
+		c.JSON(http.StatusOK, response)
+	} else {
+		c.JSON(http.StatusBadRequest, response)
+	}
+}
+
+// AbortModification handles abort phase requests of a modification
+func (h *Handler) AbortModification(c *gin.Context) {
+	var req api.AbortRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	response, err := h.service.AbortModification(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to abort modification",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if response.Success {
+		c.JSON(http.StatusOK, response)
+	} else {
+		c.JSON(http.StatusBadRequest, response)
+	}
+}
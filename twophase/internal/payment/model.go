@@ -0,0 +1,74 @@
+package payment
+
+import (
+	"time"
+)
+
+type PaymentStatus string
+
+const (
+	PaymentStatusAuthorized PaymentStatus = "AUTHORIZED"
+	PaymentStatusCaptured   PaymentStatus = "CAPTURED"
+	PaymentStatusVoided     PaymentStatus = "VOIDED"
+	// PaymentStatusRefunding marks a captured payment held by a prepared cancellation
+	PaymentStatusRefunding PaymentStatus = "REFUNDING"
+	PaymentStatusRefunded  PaymentStatus = "REFUNDED"
+	// PaymentStatusAdjusting marks a captured payment held by a prepared modification
+	PaymentStatusAdjusting PaymentStatus = "ADJUSTING"
+)
+
+type TwoPhaseTransactionStatus string
+
+const (
+	TwoPhaseTransactionStatusPrepared  TwoPhaseTransactionStatus = "PREPARED"
+	TwoPhaseTransactionStatusCommitted TwoPhaseTransactionStatus = "COMMITTED"
+	TwoPhaseTransactionStatusAborted   TwoPhaseTransactionStatus = "ABORTED"
+)
+
+// Payment is an amount held on the user's payment method for one order
+type Payment struct {
+	ID              string        `firestore:"id" json:"id"`
+	TransactionID   string        `firestore:"transaction_id" json:"transaction_id"`
+	OrderID         string        `firestore:"order_id" json:"order_id"`
+	UserID          string        `firestore:"user_id" json:"user_id"`
+	Amount          int64         `firestore:"amount" json:"amount"`
+	Currency        string        `firestore:"currency" json:"currency"`
+	AuthorizationID string        `firestore:"authorization_id" json:"authorization_id"`
+	Status          PaymentStatus `firestore:"status" json:"status"`
+	RefundedAmount  int64         `firestore:"refunded_amount,omitempty" json:"refunded_amount,omitempty"`
+	CreatedAt       time.Time     `firestore:"created_at" json:"created_at"`
+	UpdatedAt       time.Time     `firestore:"updated_at" json:"updated_at"`
+}
+
+// TwoPhaseTransaction represents a two-phase commit transaction for payment.
+// An aborted transaction without a payment is recorded when the abort arrives
+// before the prepare, so that a late prepare is refused. Cancellation transactions
+// refer to the booking they cancel and hold the amount to refund. Modification
+// transactions refer to the booking they modify and hold the price difference, with
+// the authorization of the extra amount when the difference is positive.
+type TwoPhaseTransaction struct {
+	Id                   string                    `firestore:"id"`
+	Status               TwoPhaseTransactionStatus `firestore:"status"`
+	PaymentID            string                    `firestore:"payment_id,omitempty"`
+	BookingTransactionID string                    `firestore:"booking_transaction_id,omitempty"`
+	RefundAmount         int64                     `firestore:"refund_amount,omitempty"`
+	AdjustmentAmount     int64                     `firestore:"adjustment_amount,omitempty"`
+	AuthorizationID      string                    `firestore:"authorization_id,omitempty"`
+	CreatedAt            time.Time                 `firestore:"created_at"`
+	UpdatedAt            time.Time                 `firestore:"updated_at"`
+}
+
+// PaymentPayload is the part of the order the payment service needs. TotalPrice
+// is the price locked by the coordinator.
+type PaymentPayload struct {
+	UserID     string `json:"user_id" binding:"required"`
+	TotalPrice int64  `json:"total_price"`
+	Currency   string `json:"currency" binding:"required"`
+}
+
+// AdjustmentPayload is the part of a modification the payment service needs.
+// PriceDifference is charged when positive and refunded when negative.
+type AdjustmentPayload struct {
+	BookingTransactionID string `json:"booking_transaction_id" binding:"required"`
+	PriceDifference      int64  `json:"price_difference"`
+}
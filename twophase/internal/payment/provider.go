@@ -0,0 +1,102 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+var (
+	ErrPaymentDeclined      = errors.New("payment declined by provider")
+	ErrInvalidAmount        = errors.New("amount must be positive")
+	ErrUnknownAuthorization = errors.New("unknown authorization")
+)
+
+const fakeAuthorizationPrefix = "fake_auth_"
+
+// Provider is the payment gateway used by the payment service. Funds are held on
+// Authorize, taken on Capture, released on Void and returned on Refund.
+type Provider interface {
+	Authorize(ctx context.Context, userID string, amount int64, currency string) (authorizationID string, err error)
+	Capture(ctx context.Context, authorizationID string, amount int64) error
+	Void(ctx context.Context, authorizationID string) error
+	Refund(ctx context.Context, authorizationID string, amount int64) error
+}
+
+// FakeProvider is a local provider for testing. It keeps no state; the status of
+// every payment is tracked by the payment service.
+type FakeProvider struct {
+	declineAbove int64
+	latency      time.Duration
+}
+
+// NewFakeProvider creates a provider that declines authorizations above declineAbove
+// (0 never declines) and waits latency on every call
+func NewFakeProvider(declineAbove int64, latency time.Duration) *FakeProvider {
+	return &FakeProvider{
+		declineAbove: declineAbove,
+		latency:      latency,
+	}
+}
+
+func (p *FakeProvider) wait(ctx context.Context) error {
+	if p.latency <= 0 {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(p.latency):
+		return nil
+	}
+}
+
+// Authorize holds amount for userID
+func (p *FakeProvider) Authorize(ctx context.Context, userID string, amount int64, currency string) (string, error) {
+	if err := p.wait(ctx); err != nil {
+		return "", err
+	}
+	if amount <= 0 {
+		return "", ErrInvalidAmount
+	}
+	if p.declineAbove > 0 && amount > p.declineAbove {
+		return "", ErrPaymentDeclined
+	}
+
+	return fakeAuthorizationPrefix + ulid.Make().String(), nil
+}
+
+// Capture takes the held amount
+func (p *FakeProvider) Capture(ctx context.Context, authorizationID string, amount int64) error {
+	if err := p.wait(ctx); err != nil {
+		return err
+	}
+	return p.checkAuthorization(authorizationID)
+}
+
+// Void releases the held amount
+func (p *FakeProvider) Void(ctx context.Context, authorizationID string) error {
+	if err := p.wait(ctx); err != nil {
+		return err
+	}
+	return p.checkAuthorization(authorizationID)
+}
+
+// Refund returns amount of the captured payment
+func (p *FakeProvider) Refund(ctx context.Context, authorizationID string, amount int64) error {
+	if err := p.wait(ctx); err != nil {
+		return err
+	}
+	return p.checkAuthorization(authorizationID)
+}
+
+func (p *FakeProvider) checkAuthorization(authorizationID string) error {
+	if !strings.HasPrefix(authorizationID, fakeAuthorizationPrefix) {
+		return ErrUnknownAuthorization
+	}
+	return nil
+}
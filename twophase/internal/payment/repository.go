@@ -0,0 +1,352 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/fault"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	PaymentCollection            = "twophase_payment_payments"
+	PaymentTransactionCollection = "twophase_payment_transactions"
+)
+
+var (
+	ErrTransactionNotFound   = errors.New("transaction not found")
+	ErrBookingNotCancellable = errors.New("booking is not captured or is already being cancelled")
+	ErrBookingNotModifiable  = errors.New("booking is not captured or is already being cancelled or modified")
+)
+
+// Repository handles Firestore operations for payment service
+type Repository struct {
+	client *firestore.Client
+	faults *fault.Injector
+}
+
+// NewRepository creates a new repository instance
+func NewRepository(client *firestore.Client) *Repository {
+	return &Repository{
+		client: client,
+	}
+}
+
+// GetTwoPhaseTransaction retrieves a two-phase transaction
+func (r *Repository) GetTwoPhaseTransaction(ctx context.Context, transactionID string) (*TwoPhaseTransaction, error) {
+	doc, err := r.client.Collection(PaymentTransactionCollection).Doc(transactionID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, ErrTransactionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
+	}
+
+	var transaction TwoPhaseTransaction
+	if err := doc.DataTo(&transaction); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal transaction: %w", err)
+	}
+
+	return &transaction, nil
+}
+
+// GetPreparedTransactions retrieves the transactions that are prepared and still wait
+// for the coordinator to commit or abort them
+func (r *Repository) GetPreparedTransactions(ctx context.Context) ([]*TwoPhaseTransaction, error) {
+	query := r.client.Collection(PaymentTransactionCollection).
+		Where("status", "==", TwoPhaseTransactionStatusPrepared)
+
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+
+	var transactions []*TwoPhaseTransaction
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate transactions: %w", err)
+		}
+
+		var transaction TwoPhaseTransaction
+		if err := doc.DataTo(&transaction); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal transaction: %w", err)
+		}
+
+		transactions = append(transactions, &transaction)
+	}
+
+	return transactions, nil
+}
+
+// GetPayment retrieves a payment
+func (r *Repository) GetPayment(ctx context.Context, paymentID string) (*Payment, error) {
+	doc, err := r.client.Collection(PaymentCollection).Doc(paymentID).Get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment: %w", err)
+	}
+
+	var payment Payment
+	if err := doc.DataTo(&payment); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal payment: %w", err)
+	}
+
+	return &payment, nil
+}
+
+// preparePayment records an authorized payment and its prepared transaction. It fails
+// if the transaction already exists, e.g. because it was aborted before the prepare.
+func (r *Repository) preparePayment(ctx context.Context, payment *Payment) error {
+	transactionRef := r.client.Collection(PaymentTransactionCollection).Doc(payment.TransactionID)
+	paymentRef := r.client.Collection(PaymentCollection).Doc(payment.ID)
+
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		if err := tx.Create(transactionRef, &TwoPhaseTransaction{
+			Id:        payment.TransactionID,
+			Status:    TwoPhaseTransactionStatusPrepared,
+			PaymentID: payment.ID,
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}); err != nil {
+			return fmt.Errorf("failed to create two-phase transaction: %w", err)
+		}
+
+		if err := tx.Create(paymentRef, payment); err != nil {
+			return fmt.Errorf("failed to create payment: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// commitPayment marks a prepared transaction committed and its payment captured
+func (r *Repository) commitPayment(ctx context.Context, transactionID string) error {
+	return r.finishTransaction(ctx, transactionID, TwoPhaseTransactionStatusCommitted, []firestore.Update{
+		{Path: "status", Value: PaymentStatusCaptured},
+	})
+}
+
+// abortPayment marks a prepared transaction aborted and its payment voided. A
+// transaction that was never prepared is recorded as aborted.
+func (r *Repository) abortPayment(ctx context.Context, transactionID string) error {
+	return r.finishTransaction(ctx, transactionID, TwoPhaseTransactionStatusAborted, []firestore.Update{
+		{Path: "status", Value: PaymentStatusVoided},
+	})
+}
+
+// GetBookingPayment retrieves the payment of a booking transaction
+func (r *Repository) GetBookingPayment(ctx context.Context, bookingTransactionID string) (*Payment, error) {
+	booking, err := r.GetTwoPhaseTransaction(ctx, bookingTransactionID)
+	if err != nil {
+		return nil, err
+	}
+	if booking.PaymentID == "" {
+		return nil, ErrBookingNotModifiable
+	}
+
+	return r.GetPayment(ctx, booking.PaymentID)
+}
+
+// PrepareRefund prepares refunding the payment of a committed booking. The payment is
+// marked REFUNDING so that no other cancellation can prepare it. refundAmount is
+// capped at the captured amount.
+func (r *Repository) PrepareRefund(ctx context.Context, transactionID, bookingTransactionID string, refundAmount int64) error {
+	bookingRef := r.client.Collection(PaymentTransactionCollection).Doc(bookingTransactionID)
+	transactionRef := r.client.Collection(PaymentTransactionCollection).Doc(transactionID)
+
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		bookingDoc, err := tx.Get(bookingRef)
+		if err != nil {
+			return fmt.Errorf("failed to get booking transaction: %w", err)
+		}
+
+		var booking TwoPhaseTransaction
+		if err := bookingDoc.DataTo(&booking); err != nil {
+			return fmt.Errorf("failed to unmarshal booking transaction: %w", err)
+		}
+
+		if booking.Status != TwoPhaseTransactionStatusCommitted {
+			return ErrBookingNotCancellable
+		}
+
+		paymentRef := r.client.Collection(PaymentCollection).Doc(booking.PaymentID)
+		paymentDoc, err := tx.Get(paymentRef)
+		if err != nil {
+			return fmt.Errorf("failed to get payment: %w", err)
+		}
+
+		var payment Payment
+		if err := paymentDoc.DataTo(&payment); err != nil {
+			return fmt.Errorf("failed to unmarshal payment: %w", err)
+		}
+
+		if payment.Status != PaymentStatusCaptured {
+			return ErrBookingNotCancellable
+		}
+
+		if err := tx.Update(paymentRef, []firestore.Update{
+			{Path: "status", Value: PaymentStatusRefunding},
+			{Path: "updated_at", Value: time.Now()},
+		}); err != nil {
+			return fmt.Errorf("failed to update payment: %w", err)
+		}
+
+		if err := tx.Create(transactionRef, &TwoPhaseTransaction{
+			Id:                   transactionID,
+			Status:               TwoPhaseTransactionStatusPrepared,
+			PaymentID:            payment.ID,
+			BookingTransactionID: bookingTransactionID,
+			RefundAmount:         min(max(refundAmount, 0), payment.Amount),
+			CreatedAt:            time.Now(),
+			UpdatedAt:            time.Now(),
+		}); err != nil {
+			return fmt.Errorf("failed to create two-phase transaction: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// CommitRefund marks a prepared cancellation committed and its payment refunded
+func (r *Repository) CommitRefund(ctx context.Context, transactionID string, refundAmount int64) error {
+	return r.finishTransaction(ctx, transactionID, TwoPhaseTransactionStatusCommitted, []firestore.Update{
+		{Path: "status", Value: PaymentStatusRefunded},
+		{Path: "refunded_amount", Value: refundAmount},
+	})
+}
+
+// AbortRefund marks a prepared cancellation aborted and puts its payment back to captured
+func (r *Repository) AbortRefund(ctx context.Context, transactionID string) error {
+	return r.finishTransaction(ctx, transactionID, TwoPhaseTransactionStatusAborted, []firestore.Update{
+		{Path: "status", Value: PaymentStatusCaptured},
+	})
+}
+
+// PrepareAdjustment prepares adjusting the payment of a committed booking by amount. The
+// payment is marked ADJUSTING so that no cancellation or other modification can prepare
+// it. authorizationID holds the extra amount when amount is positive.
+func (r *Repository) PrepareAdjustment(ctx context.Context, transactionID, bookingTransactionID string, amount int64, authorizationID string) error {
+	bookingRef := r.client.Collection(PaymentTransactionCollection).Doc(bookingTransactionID)
+	transactionRef := r.client.Collection(PaymentTransactionCollection).Doc(transactionID)
+
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		bookingDoc, err := tx.Get(bookingRef)
+		if err != nil {
+			return fmt.Errorf("failed to get booking transaction: %w", err)
+		}
+
+		var booking TwoPhaseTransaction
+		if err := bookingDoc.DataTo(&booking); err != nil {
+			return fmt.Errorf("failed to unmarshal booking transaction: %w", err)
+		}
+
+		if booking.Status != TwoPhaseTransactionStatusCommitted {
+			return ErrBookingNotModifiable
+		}
+
+		paymentRef := r.client.Collection(PaymentCollection).Doc(booking.PaymentID)
+		paymentDoc, err := tx.Get(paymentRef)
+		if err != nil {
+			return fmt.Errorf("failed to get payment: %w", err)
+		}
+
+		var payment Payment
+		if err := paymentDoc.DataTo(&payment); err != nil {
+			return fmt.Errorf("failed to unmarshal payment: %w", err)
+		}
+
+		if payment.Status != PaymentStatusCaptured || payment.Amount+amount < 0 {
+			return ErrBookingNotModifiable
+		}
+
+		if err := tx.Update(paymentRef, []firestore.Update{
+			{Path: "status", Value: PaymentStatusAdjusting},
+			{Path: "updated_at", Value: time.Now()},
+		}); err != nil {
+			return fmt.Errorf("failed to update payment: %w", err)
+		}
+
+		if err := tx.Create(transactionRef, &TwoPhaseTransaction{
+			Id:                   transactionID,
+			Status:               TwoPhaseTransactionStatusPrepared,
+			PaymentID:            payment.ID,
+			BookingTransactionID: bookingTransactionID,
+			AdjustmentAmount:     amount,
+			AuthorizationID:      authorizationID,
+			CreatedAt:            time.Now(),
+			UpdatedAt:            time.Now(),
+		}); err != nil {
+			return fmt.Errorf("failed to create two-phase transaction: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// CommitAdjustment marks a prepared modification committed and puts its payment back to
+// captured with the adjusted amount
+func (r *Repository) CommitAdjustment(ctx context.Context, transactionID string, amount int64) error {
+	return r.finishTransaction(ctx, transactionID, TwoPhaseTransactionStatusCommitted, []firestore.Update{
+		{Path: "status", Value: PaymentStatusCaptured},
+		{Path: "amount", Value: firestore.Increment(amount)},
+	})
+}
+
+// AbortAdjustment marks a prepared modification aborted and puts its payment back to captured
+func (r *Repository) AbortAdjustment(ctx context.Context, transactionID string) error {
+	return r.finishTransaction(ctx, transactionID, TwoPhaseTransactionStatusAborted, []firestore.Update{
+		{Path: "status", Value: PaymentStatusCaptured},
+	})
+}
+
+func (r *Repository) finishTransaction(ctx context.Context, transactionID string, transactionStatus TwoPhaseTransactionStatus, paymentUpdates []firestore.Update) error {
+	transactionRef := r.client.Collection(PaymentTransactionCollection).Doc(transactionID)
+
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		transactionDoc, err := tx.Get(transactionRef)
+		if status.Code(err) == codes.NotFound && transactionStatus == TwoPhaseTransactionStatusAborted {
+			return tx.Create(transactionRef, &TwoPhaseTransaction{
+				Id:        transactionID,
+				Status:    TwoPhaseTransactionStatusAborted,
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+			})
+		}
+		if status.Code(err) == codes.NotFound {
+			return ErrTransactionNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get transaction: %w", err)
+		}
+
+		var transaction TwoPhaseTransaction
+		if err := transactionDoc.DataTo(&transaction); err != nil {
+			return fmt.Errorf("failed to unmarshal transaction: %w", err)
+		}
+
+		if transaction.Status != TwoPhaseTransactionStatusPrepared {
+			// Already committed or aborted
+			return nil
+		}
+
+		if err := tx.Update(transactionRef, []firestore.Update{
+			{Path: "status", Value: transactionStatus},
+			{Path: "updated_at", Value: time.Now()},
+		}); err != nil {
+			return fmt.Errorf("failed to update transaction: %w", err)
+		}
+
+		paymentUpdates = append(paymentUpdates, firestore.Update{Path: "updated_at", Value: time.Now()})
+		if err := tx.Update(r.client.Collection(PaymentCollection).Doc(transaction.PaymentID), paymentUpdates); err != nil {
+			return fmt.Errorf("failed to update payment: %w", err)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,370 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/api"
+)
+
+// Service handles payment business logic with two-phase commit. Prepare
+// authorizes the order total, commit captures it and abort voids it.
+type Service struct {
+	repo     *Repository
+	provider Provider
+}
+
+// NewService creates a new payment service
+func NewService(repo *Repository, provider Provider) *Service {
+	return &Service{
+		repo:     repo,
+		provider: provider,
+	}
+}
+
+// Prepare handles the prepare phase of two-phase commit
+func (s *Service) Prepare(ctx context.Context, req *api.PrepareRequest[PaymentPayload]) (*api.PrepareResponse, error) {
+	// Check if transaction already exists
+	existingTransaction, err := s.repo.GetTwoPhaseTransaction(ctx, req.TransactionID)
+	if err != nil && !errors.Is(err, ErrTransactionNotFound) {
+		return nil, err
+	}
+	if existingTransaction != nil {
+		return &api.PrepareResponse{
+			Success: existingTransaction.Status == TwoPhaseTransactionStatusPrepared,
+			Message: fmt.Sprintf("Transaction already %s", existingTransaction.Status),
+		}, nil
+	}
+
+	authorizationID, err := s.provider.Authorize(ctx, req.Payload.UserID, req.Payload.TotalPrice, req.Payload.Currency)
+	if err != nil {
+		return &api.PrepareResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to authorize payment: %v", err),
+		}, nil
+	}
+
+	payment := &Payment{
+		ID:              ulid.Make().String(),
+		TransactionID:   req.TransactionID,
+		OrderID:         req.OrderID,
+		UserID:          req.Payload.UserID,
+		Amount:          req.Payload.TotalPrice,
+		Currency:        req.Payload.Currency,
+		AuthorizationID: authorizationID,
+		Status:          PaymentStatusAuthorized,
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+	}
+
+	if err := s.repo.PreparePayment(ctx, payment); err != nil {
+		// The hold must not outlive a transaction that could not be prepared
+		if voidErr := s.provider.Void(ctx, authorizationID); voidErr != nil {
+			log.Printf("Failed to void authorization %s: %v", authorizationID, voidErr)
+		}
+
+		return &api.PrepareResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to create transaction log: %v", err),
+		}, nil
+	}
+
+	return &api.PrepareResponse{
+		Success: true,
+		Message: "Payment service prepared successfully",
+	}, nil
+}
+
+// Commit handles the commit phase of two-phase commit
+func (s *Service) Commit(ctx context.Context, req *api.CommitRequest) (*api.CommitResponse, error) {
+	if err := s.capture(ctx, req.TransactionID); err != nil {
+		return &api.CommitResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to commit transaction: %v", err),
+		}, nil
+	}
+
+	return &api.CommitResponse{
+		Success: true,
+		Message: "Payment service committed successfully",
+	}, nil
+}
+
+func (s *Service) capture(ctx context.Context, transactionID string) error {
+	transaction, err := s.repo.GetTwoPhaseTransaction(ctx, transactionID)
+	if err != nil {
+		return err
+	}
+
+	switch transaction.Status {
+	case TwoPhaseTransactionStatusCommitted:
+		return nil
+	case TwoPhaseTransactionStatusAborted:
+		return errors.New("transaction already aborted")
+	}
+
+	payment, err := s.repo.GetPayment(ctx, transaction.PaymentID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.provider.Capture(ctx, payment.AuthorizationID, payment.Amount); err != nil {
+		return fmt.Errorf("failed to capture payment: %w", err)
+	}
+
+	return s.repo.CommitPayment(ctx, transactionID)
+}
+
+// Abort handles the abort phase of two-phase commit
+func (s *Service) Abort(ctx context.Context, req *api.AbortRequest) (*api.AbortResponse, error) {
+	if err := s.void(ctx, req.TransactionID); err != nil {
+		return &api.AbortResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to abort transaction: %v", err),
+		}, nil
+	}
+
+	return &api.AbortResponse{
+		Success: true,
+		Message: "Payment service aborted successfully",
+	}, nil
+}
+
+func (s *Service) void(ctx context.Context, transactionID string) error {
+	transaction, err := s.repo.GetTwoPhaseTransaction(ctx, transactionID)
+	if err != nil && !errors.Is(err, ErrTransactionNotFound) {
+		return err
+	}
+
+	if transaction != nil && transaction.Status == TwoPhaseTransactionStatusPrepared {
+		payment, err := s.repo.GetPayment(ctx, transaction.PaymentID)
+		if err != nil {
+			return err
+		}
+
+		if err := s.provider.Void(ctx, payment.AuthorizationID); err != nil {
+			return fmt.Errorf("failed to void payment: %w", err)
+		}
+	}
+
+	return s.repo.AbortPayment(ctx, transactionID)
+}
+
+// PrepareCancellation handles the prepare phase of a cancellation transaction
+func (s *Service) PrepareCancellation(ctx context.Context, req *api.PrepareRequest[api.CancellationPayload]) (*api.PrepareResponse, error) {
+	// Check if transaction already exists
+	existingTransaction, err := s.repo.GetTwoPhaseTransaction(ctx, req.TransactionID)
+	if err != nil && !errors.Is(err, ErrTransactionNotFound) {
+		return nil, err
+	}
+	if existingTransaction != nil {
+		return &api.PrepareResponse{
+			Success: existingTransaction.Status == TwoPhaseTransactionStatusPrepared,
+			Message: fmt.Sprintf("Transaction already %s", existingTransaction.Status),
+		}, nil
+	}
+
+	if err := s.repo.PrepareRefund(ctx, req.TransactionID, req.Payload.BookingTransactionID, req.Payload.RefundAmount); err != nil {
+		return &api.PrepareResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to prepare cancellation: %v", err),
+		}, nil
+	}
+
+	return &api.PrepareResponse{
+		Success: true,
+		Message: "Payment service prepared cancellation successfully",
+	}, nil
+}
+
+// CommitCancellation handles the commit phase of a cancellation transaction
+func (s *Service) CommitCancellation(ctx context.Context, req *api.CommitRequest) (*api.CommitResponse, error) {
+	if err := s.refund(ctx, req.TransactionID); err != nil {
+		return &api.CommitResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to commit cancellation: %v", err),
+		}, nil
+	}
+
+	return &api.CommitResponse{
+		Success: true,
+		Message: "Payment service committed cancellation successfully",
+	}, nil
+}
+
+func (s *Service) refund(ctx context.Context, transactionID string) error {
+	transaction, err := s.repo.GetTwoPhaseTransaction(ctx, transactionID)
+	if err != nil {
+		return err
+	}
+
+	switch transaction.Status {
+	case TwoPhaseTransactionStatusCommitted:
+		return nil
+	case TwoPhaseTransactionStatusAborted:
+		return errors.New("transaction already aborted")
+	}
+
+	payment, err := s.repo.GetPayment(ctx, transaction.PaymentID)
+	if err != nil {
+		return err
+	}
+
+	if transaction.RefundAmount > 0 {
+		if err := s.provider.Refund(ctx, payment.AuthorizationID, transaction.RefundAmount); err != nil {
+			return fmt.Errorf("failed to refund payment: %w", err)
+		}
+	}
+
+	return s.repo.CommitRefund(ctx, transactionID, transaction.RefundAmount)
+}
+
+// AbortCancellation handles the abort phase of a cancellation transaction
+func (s *Service) AbortCancellation(ctx context.Context, req *api.AbortRequest) (*api.AbortResponse, error) {
+	if err := s.repo.AbortRefund(ctx, req.TransactionID); err != nil {
+		return &api.AbortResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to abort cancellation: %v", err),
+		}, nil
+	}
+
+	return &api.AbortResponse{
+		Success: true,
+		Message: "Payment service aborted cancellation successfully",
+	}, nil
+}
+
+// PrepareModification handles the prepare phase of a modification transaction. A
+// positive price difference is authorized now and captured on commit, a negative one
+// is refunded on commit.
+func (s *Service) PrepareModification(ctx context.Context, req *api.PrepareRequest[AdjustmentPayload]) (*api.PrepareResponse, error) {
+	// Check if transaction already exists
+	existingTransaction, err := s.repo.GetTwoPhaseTransaction(ctx, req.TransactionID)
+	if err != nil && !errors.Is(err, ErrTransactionNotFound) {
+		return nil, err
+	}
+	if existingTransaction != nil {
+		return &api.PrepareResponse{
+			Success: existingTransaction.Status == TwoPhaseTransactionStatusPrepared,
+			Message: fmt.Sprintf("Transaction already %s", existingTransaction.Status),
+		}, nil
+	}
+
+	var authorizationID string
+	if req.Payload.PriceDifference > 0 {
+		payment, err := s.repo.GetBookingPayment(ctx, req.Payload.BookingTransactionID)
+		if err != nil {
+			return &api.PrepareResponse{
+				Success: false,
+				Message: fmt.Sprintf("Failed to prepare modification: %v", err),
+			}, nil
+		}
+
+		authorizationID, err = s.provider.Authorize(ctx, payment.UserID, req.Payload.PriceDifference, payment.Currency)
+		if err != nil {
+			return &api.PrepareResponse{
+				Success: false,
+				Message: fmt.Sprintf("Failed to authorize price difference: %v", err),
+			}, nil
+		}
+	}
+
+	if err := s.repo.PrepareAdjustment(ctx, req.TransactionID, req.Payload.BookingTransactionID, req.Payload.PriceDifference, authorizationID); err != nil {
+		// The hold must not outlive a transaction that could not be prepared
+		if authorizationID != "" {
+			if voidErr := s.provider.Void(ctx, authorizationID); voidErr != nil {
+				log.Printf("Failed to void authorization %s: %v", authorizationID, voidErr)
+			}
+		}
+
+		return &api.PrepareResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to prepare modification: %v", err),
+		}, nil
+	}
+
+	return &api.PrepareResponse{
+		Success: true,
+		Message: "Payment service prepared modification successfully",
+	}, nil
+}
+
+// CommitModification handles the commit phase of a modification transaction
+func (s *Service) CommitModification(ctx context.Context, req *api.CommitRequest) (*api.CommitResponse, error) {
+	if err := s.adjust(ctx, req.TransactionID); err != nil {
+		return &api.CommitResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to commit modification: %v", err),
+		}, nil
+	}
+
+	return &api.CommitResponse{
+		Success: true,
+		Message: "Payment service committed modification successfully",
+	}, nil
+}
+
+func (s *Service) adjust(ctx context.Context, transactionID string) error {
+	transaction, err := s.repo.GetTwoPhaseTransaction(ctx, transactionID)
+	if err != nil {
+		return err
+	}
+
+	switch transaction.Status {
+	case TwoPhaseTransactionStatusCommitted:
+		return nil
+	case TwoPhaseTransactionStatusAborted:
+		return errors.New("transaction already aborted")
+	}
+
+	switch {
+	case transaction.AdjustmentAmount > 0:
+		if err := s.provider.Capture(ctx, transaction.AuthorizationID, transaction.AdjustmentAmount); err != nil {
+			return fmt.Errorf("failed to capture price difference: %w", err)
+		}
+	case transaction.AdjustmentAmount < 0:
+		payment, err := s.repo.GetPayment(ctx, transaction.PaymentID)
+		if err != nil {
+			return err
+		}
+		if err := s.provider.Refund(ctx, payment.AuthorizationID, -transaction.AdjustmentAmount); err != nil {
+			return fmt.Errorf("failed to refund price difference: %w", err)
+		}
+	}
+
+	return s.repo.CommitAdjustment(ctx, transactionID, transaction.AdjustmentAmount)
+}
+
+// AbortModification handles the abort phase of a modification transaction
+func (s *Service) AbortModification(ctx context.Context, req *api.AbortRequest) (*api.AbortResponse, error) {
+	if err := s.cancelAdjustment(ctx, req.TransactionID); err != nil {
+		return &api.AbortResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to abort modification: %v", err),
+		}, nil
+	}
+
+	return &api.AbortResponse{
+		Success: true,
+		Message: "Payment service aborted modification successfully",
+	}, nil
+}
+
+func (s *Service) cancelAdjustment(ctx context.Context, transactionID string) error {
+	transaction, err := s.repo.GetTwoPhaseTransaction(ctx, transactionID)
+	if err != nil && !errors.Is(err, ErrTransactionNotFound) {
+		return err
+	}
+
+	if transaction != nil && transaction.Status == TwoPhaseTransactionStatusPrepared && transaction.AuthorizationID != "" {
+		if err := s.provider.Void(ctx, transaction.AuthorizationID); err != nil {
+			return fmt.Errorf("failed to void price difference: %w", err)
+		}
+	}
+
+	return s.repo.AbortAdjustment(ctx, transactionID)
+}
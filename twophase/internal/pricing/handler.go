@@ -0,0 +1,51 @@
+package pricing
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler handles HTTP requests for quotes
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new handler instance
+func NewHandler(service *Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// RegisterRoutes registers all routes for quotes
+func (h *Handler) RegisterRoutes(r *gin.Engine) {
+	r.POST("/quotes", h.CreateQuote)
+}
+
+// CreateQuote prices a prospective booking and returns a quote valid for the configured TTL
+func (h *Handler) CreateQuote(c *gin.Context) {
+	var req QuoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	quote, err := h.service.CreateQuote(c.Request.Context(), req)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if IsRequestError(err) {
+			statusCode = http.StatusBadRequest
+		}
+		c.JSON(statusCode, gin.H{
+			"error":   "Failed to create quote",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, quote)
+}
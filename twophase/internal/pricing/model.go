@@ -0,0 +1,291 @@
+package pricing
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/config"
+)
+
+// Currency is the currency of every price. Amounts are stored in whole rupiah.
+const Currency = "IDR"
+
+type SeatClass string
+
+const (
+	SeatClassExecutive SeatClass = "EXECUTIVE"
+	SeatClassBusiness  SeatClass = "BUSINESS"
+	SeatClassEconomy   SeatClass = "ECONOMY"
+)
+
+type FareClass string
+
+const (
+	FareClassFirst    FareClass = "FIRST"
+	FareClassBusiness FareClass = "BUSINESS"
+	FareClassEconomy  FareClass = "ECONOMY"
+)
+
+// Season is a period, e.g. the year-end holidays, during which rates carry a
+// percentage surcharge. StartDate and EndDate are inclusive.
+type Season struct {
+	Name             string `firestore:"name" json:"name"`
+	StartDate        string `firestore:"start_date" json:"start_date"`
+	EndDate          string `firestore:"end_date" json:"end_date"`
+	SurchargePercent int64  `firestore:"surcharge_percent" json:"surcharge_percent"`
+}
+
+// RoomRate is the nightly rate of a room or of a room type, exactly one of HotelRoomID
+// and RoomTypeID is set. Friday and Saturday nights use WeekendRate.
+type RoomRate struct {
+	HotelRoomID string   `firestore:"hotel_room_id,omitempty" json:"hotel_room_id,omitempty"`
+	RoomTypeID  string   `firestore:"room_type_id,omitempty" json:"room_type_id,omitempty"`
+	NightlyRate int64    `firestore:"nightly_rate" json:"nightly_rate"`
+	WeekendRate int64    `firestore:"weekend_rate" json:"weekend_rate"`
+	Seasons     []Season `firestore:"seasons" json:"seasons"`
+}
+
+// id returns the document ID of the rate, which is the room or room type it prices
+func (r RoomRate) id() string {
+	if r.RoomTypeID != "" {
+		return r.RoomTypeID
+	}
+	return r.HotelRoomID
+}
+
+// CarRate is the daily rental rate of a car. Saturdays and Sundays use WeekendRate.
+type CarRate struct {
+	CarID       string   `firestore:"car_id" json:"car_id"`
+	DailyRate   int64    `firestore:"daily_rate" json:"daily_rate"`
+	WeekendRate int64    `firestore:"weekend_rate" json:"weekend_rate"`
+	Seasons     []Season `firestore:"seasons" json:"seasons"`
+}
+
+// ClassFare is the per-segment fare of the class occupying coaches FirstCoach to LastCoach
+type ClassFare struct {
+	Class       SeatClass `firestore:"class" json:"class"`
+	FirstCoach  int       `firestore:"first_coach" json:"first_coach"`
+	LastCoach   int       `firestore:"last_coach" json:"last_coach"`
+	SegmentFare int64     `firestore:"segment_fare" json:"segment_fare"`
+}
+
+// JourneyFare holds the seat fares by class of a dated train journey. A seat costs
+// its class SegmentFare times the number of segments between origin and destination.
+type JourneyFare struct {
+	JourneyID string      `firestore:"journey_id" json:"journey_id"`
+	Stations  []string    `firestore:"stations" json:"stations"`
+	Classes   []ClassFare `firestore:"classes" json:"classes"`
+}
+
+// FlightClassFare is the seat fare of the class occupying rows FirstRow to LastRow
+type FlightClassFare struct {
+	Class    FareClass `firestore:"class" json:"class"`
+	FirstRow int       `firestore:"first_row" json:"first_row"`
+	LastRow  int       `firestore:"last_row" json:"last_row"`
+	Fare     int64     `firestore:"fare" json:"fare"`
+}
+
+// FlightFare holds the seat fares by class of a dated flight
+type FlightFare struct {
+	FlightID string            `firestore:"flight_id" json:"flight_id"`
+	Classes  []FlightClassFare `firestore:"classes" json:"classes"`
+}
+
+// RoomLine is the price of a single room in a quote
+type RoomLine struct {
+	HotelRoomID string `firestore:"hotel_room_id,omitempty" json:"hotel_room_id,omitempty"`
+	RoomTypeID  string `firestore:"room_type_id,omitempty" json:"room_type_id,omitempty"`
+	StartDate   string `firestore:"start_date" json:"start_date"`
+	EndDate     string `firestore:"end_date" json:"end_date"`
+	Nights      int    `firestore:"nights" json:"nights"`
+	Price       int64  `firestore:"price" json:"price"`
+}
+
+// CarLine is the price of a single car in a quote
+type CarLine struct {
+	CarID     string `firestore:"car_id" json:"car_id"`
+	StartDate string `firestore:"start_date" json:"start_date"`
+	EndDate   string `firestore:"end_date" json:"end_date"`
+	Days      int    `firestore:"days" json:"days"`
+	Price     int64  `firestore:"price" json:"price"`
+}
+
+// SeatLine is the price of a single train seat in a quote
+type SeatLine struct {
+	JourneyID          string    `firestore:"journey_id" json:"journey_id"`
+	DepartureDate      string    `firestore:"departure_date" json:"departure_date"`
+	SeatID             string    `firestore:"seat_id" json:"seat_id"`
+	OriginStation      string    `firestore:"origin_station" json:"origin_station"`
+	DestinationStation string    `firestore:"destination_station" json:"destination_station"`
+	Class              SeatClass `firestore:"class" json:"class"`
+	Segments           int       `firestore:"segments" json:"segments"`
+	Price              int64     `firestore:"price" json:"price"`
+}
+
+// FlightLine is the price of a single flight seat in a quote
+type FlightLine struct {
+	FlightID      string    `firestore:"flight_id" json:"flight_id"`
+	DepartureDate string    `firestore:"departure_date" json:"departure_date"`
+	SeatID        string    `firestore:"seat_id" json:"seat_id"`
+	Class         FareClass `firestore:"class" json:"class"`
+	Price         int64     `firestore:"price" json:"price"`
+}
+
+// Quote is the price of a prospective booking, valid until ExpiresAt. A quote can
+// back a single order only; OrderID is set once an order has redeemed it.
+type Quote struct {
+	ID         string       `firestore:"id" json:"quote_id"`
+	HotelRooms []RoomLine   `firestore:"hotel_rooms" json:"hotel_rooms"`
+	Cars       []CarLine    `firestore:"cars" json:"cars"`
+	TrainSeats []SeatLine   `firestore:"train_seats" json:"train_seats"`
+	Flights    []FlightLine `firestore:"flights" json:"flights"`
+	TotalPrice int64        `firestore:"total_price" json:"total_price"`
+	Currency   string       `firestore:"currency" json:"currency"`
+	OrderID    string       `firestore:"order_id,omitempty" json:"order_id,omitempty"`
+	CreatedAt  time.Time    `firestore:"created_at" json:"created_at"`
+	ExpiresAt  time.Time    `firestore:"expires_at" json:"expires_at"`
+}
+
+// HolidaySeasons returns the peak seasons of the given year, including the
+// previous year-end holidays that run into early January
+func HolidaySeasons(year int) []Season {
+	return []Season{
+		{Name: "Libur Natal dan Tahun Baru", StartDate: fmt.Sprintf("%d-12-20", year-1), EndDate: fmt.Sprintf("%d-01-05", year), SurchargePercent: 35},
+		{Name: "Libur Sekolah", StartDate: fmt.Sprintf("%d-06-20", year), EndDate: fmt.Sprintf("%d-07-15", year), SurchargePercent: 20},
+		{Name: "Libur Natal dan Tahun Baru", StartDate: fmt.Sprintf("%d-12-20", year), EndDate: fmt.Sprintf("%d-01-05", year+1), SurchargePercent: 35},
+	}
+}
+
+// dailyPrice returns the price of a single date. Seasonal surcharges apply on
+// top of both the weekday and the weekend rate.
+func dailyPrice(rate, weekendRate int64, weekend bool, seasons []Season, date string) int64 {
+	price := rate
+	if weekend {
+		price = weekendRate
+	}
+
+	for _, season := range seasons {
+		if date >= season.StartDate && date <= season.EndDate {
+			return price + price*season.SurchargePercent/100
+		}
+	}
+
+	return price
+}
+
+// Price returns the number of nights and the price of a stay from start to end
+// inclusive, matching the dates locked by a reservation
+func (r *RoomRate) Price(start, end time.Time) (nights int, price int64) {
+	for date := start; !date.After(end); date = date.AddDate(0, 0, 1) {
+		weekend := date.Weekday() == time.Friday || date.Weekday() == time.Saturday
+		price += dailyPrice(r.NightlyRate, r.WeekendRate, weekend, r.Seasons, date.Format(config.DateFormat))
+		nights++
+	}
+	return nights, price
+}
+
+// Price returns the number of days and the price of a rental from start to end inclusive
+func (r *CarRate) Price(start, end time.Time) (days int, price int64) {
+	for date := start; !date.After(end); date = date.AddDate(0, 0, 1) {
+		weekend := date.Weekday() == time.Saturday || date.Weekday() == time.Sunday
+		price += dailyPrice(r.DailyRate, r.WeekendRate, weekend, r.Seasons, date.Format(config.DateFormat))
+		days++
+	}
+	return days, price
+}
+
+// ClassOf returns the class fare of a seat ID in the "{coach}-{number}" format
+func (f *JourneyFare) ClassOf(seatID string) (ClassFare, bool) {
+	var coach, number int
+	if _, err := fmt.Sscanf(seatID, "%d-%d", &coach, &number); err != nil {
+		return ClassFare{}, false
+	}
+
+	for _, class := range f.Classes {
+		if coach >= class.FirstCoach && coach <= class.LastCoach {
+			return class, true
+		}
+	}
+
+	return ClassFare{}, false
+}
+
+// ClassOf returns the class fare of a seat ID in the "{row}{letter}" format
+func (f *FlightFare) ClassOf(seatID string) (FlightClassFare, bool) {
+	var row int
+	var letter rune
+	if _, err := fmt.Sscanf(seatID, "%d%c", &row, &letter); err != nil {
+		return FlightClassFare{}, false
+	}
+
+	for _, class := range f.Classes {
+		if row >= class.FirstRow && row <= class.LastRow {
+			return class, true
+		}
+	}
+
+	return FlightClassFare{}, false
+}
+
+// Segments returns the number of segments between origin and destination.
+// ok is false when either station is not on the route or they are in the wrong order.
+func (f *JourneyFare) Segments(origin, destination string) (int, bool) {
+	from, to := -1, -1
+	for i, station := range f.Stations {
+		if station == origin && from == -1 {
+			from = i
+		}
+		if station == destination {
+			to = i
+		}
+	}
+
+	if from == -1 || to == -1 || from >= to {
+		return 0, false
+	}
+
+	return to - from, true
+}
+
+// Expired reports whether the quote is no longer valid at now
+func (q *Quote) Expired(now time.Time) bool {
+	return !now.Before(q.ExpiresAt)
+}
+
+// Matches reports whether req contains exactly the items priced by the quote, in the same order
+func (q *Quote) Matches(req QuoteRequest) bool {
+	if len(q.HotelRooms) != len(req.HotelRooms) || len(q.Cars) != len(req.Cars) || len(q.TrainSeats) != len(req.TrainSeats) || len(q.Flights) != len(req.Flights) {
+		return false
+	}
+
+	for i, room := range req.HotelRooms {
+		line := q.HotelRooms[i]
+		if line.HotelRoomID != room.HotelRoomID || line.RoomTypeID != room.RoomTypeID || line.StartDate != room.StartDate || line.EndDate != room.EndDate {
+			return false
+		}
+	}
+
+	for i, car := range req.Cars {
+		line := q.Cars[i]
+		if line.CarID != car.CarID || line.StartDate != car.StartDate || line.EndDate != car.EndDate {
+			return false
+		}
+	}
+
+	for i, seat := range req.TrainSeats {
+		line := q.TrainSeats[i]
+		if line.JourneyID != seat.JourneyID || line.DepartureDate != seat.DepartureDate || line.SeatID != seat.SeatID ||
+			line.OriginStation != seat.OriginStation || line.DestinationStation != seat.DestinationStation {
+			return false
+		}
+	}
+
+	for i, flight := range req.Flights {
+		line := q.Flights[i]
+		if line.FlightID != flight.FlightID || line.DepartureDate != flight.DepartureDate || line.SeatID != flight.SeatID {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,198 @@
+package pricing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/utils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	RoomRateCollection    = "twophase_pricing_room_rates"
+	CarRateCollection     = "twophase_pricing_car_rates"
+	JourneyFareCollection = "twophase_pricing_journey_fares"
+	FlightFareCollection  = "twophase_pricing_flight_fares"
+	QuoteCollection       = "twophase_pricing_quotes"
+)
+
+var (
+	ErrQuoteNotFound    = errors.New("quote not found")
+	ErrQuoteExpired     = errors.New("quote has expired")
+	ErrQuoteAlreadyUsed = errors.New("quote has already been used by another order")
+)
+
+// Repository handles Firestore operations for rates and quotes
+type Repository struct {
+	client *firestore.Client
+}
+
+// NewRepository creates a new repository instance
+func NewRepository(client *firestore.Client) *Repository {
+	return &Repository{
+		client: client,
+	}
+}
+
+// GetRoomRates retrieves the rates of the given rooms, keyed by room ID. Rooms without a rate are absent.
+func (r *Repository) GetRoomRates(ctx context.Context, hotelRoomIDs []string) (map[string]*RoomRate, error) {
+	return getAll[RoomRate](ctx, r.client, RoomRateCollection, hotelRoomIDs)
+}
+
+// GetCarRates retrieves the rates of the given cars, keyed by car ID. Cars without a rate are absent.
+func (r *Repository) GetCarRates(ctx context.Context, carIDs []string) (map[string]*CarRate, error) {
+	return getAll[CarRate](ctx, r.client, CarRateCollection, carIDs)
+}
+
+// GetJourneyFares retrieves the fares of the given journeys, keyed by journey ID. Journeys without fares are absent.
+func (r *Repository) GetJourneyFares(ctx context.Context, journeyIDs []string) (map[string]*JourneyFare, error) {
+	return getAll[JourneyFare](ctx, r.client, JourneyFareCollection, journeyIDs)
+}
+
+// GetFlightFares retrieves the fares of the given flights, keyed by flight ID. Flights without fares are absent.
+func (r *Repository) GetFlightFares(ctx context.Context, flightIDs []string) (map[string]*FlightFare, error) {
+	return getAll[FlightFare](ctx, r.client, FlightFareCollection, flightIDs)
+}
+
+// getAll reads the documents with the given IDs in a single round trip
+func getAll[T any](ctx context.Context, client *firestore.Client, collection string, ids []string) (map[string]*T, error) {
+	refs := make([]*firestore.DocumentRef, 0, len(ids))
+	for _, id := range ids {
+		refs = append(refs, client.Collection(collection).Doc(id))
+	}
+
+	docs, err := client.GetAll(ctx, refs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s: %w", collection, err)
+	}
+
+	result := make(map[string]*T, len(docs))
+	for _, doc := range docs {
+		if !doc.Exists() {
+			continue
+		}
+
+		var data T
+		if err := doc.DataTo(&data); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %s: %w", collection, err)
+		}
+		result[doc.Ref.ID] = &data
+	}
+
+	return result, nil
+}
+
+// CreateQuote stores a new quote
+func (r *Repository) CreateQuote(ctx context.Context, quote *Quote) error {
+	if _, err := r.client.Collection(QuoteCollection).Doc(quote.ID).Create(ctx, quote); err != nil {
+		return fmt.Errorf("failed to create quote: %w", err)
+	}
+
+	return nil
+}
+
+// GetQuote retrieves a quote by ID
+func (r *Repository) GetQuote(ctx context.Context, quoteID string) (*Quote, error) {
+	doc, err := r.client.Collection(QuoteCollection).Doc(quoteID).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, ErrQuoteNotFound
+		}
+		return nil, fmt.Errorf("failed to get quote: %w", err)
+	}
+
+	var quote Quote
+	if err := doc.DataTo(&quote); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal quote: %w", err)
+	}
+
+	return &quote, nil
+}
+
+// RedeemQuote marks the quote as used by orderID within a transaction, so that
+// two orders cannot redeem the same quote
+func (r *Repository) RedeemQuote(ctx context.Context, quoteID, orderID string, now time.Time) error {
+	ref := r.client.Collection(QuoteCollection).Doc(quoteID)
+
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil {
+			if status.Code(err) == codes.NotFound {
+				return ErrQuoteNotFound
+			}
+			return fmt.Errorf("failed to get quote: %w", err)
+		}
+
+		var quote Quote
+		if err := doc.DataTo(&quote); err != nil {
+			return fmt.Errorf("failed to unmarshal quote: %w", err)
+		}
+
+		if quote.OrderID != "" && quote.OrderID != orderID {
+			return ErrQuoteAlreadyUsed
+		}
+		if quote.Expired(now) {
+			return ErrQuoteExpired
+		}
+
+		if err := tx.Update(ref, []firestore.Update{
+			{Path: "order_id", Value: orderID},
+		}); err != nil {
+			return fmt.Errorf("failed to update quote: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// BulkWriteRoomRates writes room rates with the given write mode
+func (r *Repository) BulkWriteRoomRates(ctx context.Context, rates []RoomRate, mode utils.WriteMode) (utils.BulkWriteResult, error) {
+	collection := r.client.Collection(RoomRateCollection)
+
+	docs := make([]utils.Doc, 0, len(rates))
+	for _, rate := range rates {
+		docs = append(docs, utils.Doc{Ref: collection.Doc(rate.id()), Data: rate})
+	}
+
+	return utils.BulkWrite(ctx, r.client, docs, mode)
+}
+
+// BulkWriteCarRates writes car rates with the given write mode
+func (r *Repository) BulkWriteCarRates(ctx context.Context, rates []CarRate, mode utils.WriteMode) (utils.BulkWriteResult, error) {
+	collection := r.client.Collection(CarRateCollection)
+
+	docs := make([]utils.Doc, 0, len(rates))
+	for _, rate := range rates {
+		docs = append(docs, utils.Doc{Ref: collection.Doc(rate.CarID), Data: rate})
+	}
+
+	return utils.BulkWrite(ctx, r.client, docs, mode)
+}
+
+// BulkWriteJourneyFares writes train journey fares with the given write mode
+func (r *Repository) BulkWriteJourneyFares(ctx context.Context, fares []JourneyFare, mode utils.WriteMode) (utils.BulkWriteResult, error) {
+	collection := r.client.Collection(JourneyFareCollection)
+
+	docs := make([]utils.Doc, 0, len(fares))
+	for _, fare := range fares {
+		docs = append(docs, utils.Doc{Ref: collection.Doc(fare.JourneyID), Data: fare})
+	}
+
+	return utils.BulkWrite(ctx, r.client, docs, mode)
+}
+
+// BulkWriteFlightFares writes flight fares with the given write mode
+func (r *Repository) BulkWriteFlightFares(ctx context.Context, fares []FlightFare, mode utils.WriteMode) (utils.BulkWriteResult, error) {
+	collection := r.client.Collection(FlightFareCollection)
+
+	docs := make([]utils.Doc, 0, len(fares))
+	for _, fare := range fares {
+		docs = append(docs, utils.Doc{Ref: collection.Doc(fare.FlightID), Data: fare})
+	}
+
+	return utils.BulkWrite(ctx, r.client, docs, mode)
+}
@@ -0,0 +1,35 @@
+package train
+
+import (
+	"context"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/fault"
+)
+
+// WithFaults makes the repository fail the prepare, commit and abort of bookings as
+// configured in injector
+func (r *Repository) WithFaults(injector *fault.Injector) *Repository {
+	r.faults = injector
+	return r
+}
+
+// PrepareSeatReservation runs prepareSeatReservation, failing before or after it when a fault is injected
+func (r *Repository) PrepareSeatReservation(ctx context.Context, transactionID string, seats []TrainSeatItem) error {
+	return r.faults.Do("PrepareSeatReservation", transactionID, func() error {
+		return r.prepareSeatReservation(ctx, transactionID, seats)
+	})
+}
+
+// CommitSeatReservation runs commitSeatReservation, failing before or after it when a fault is injected
+func (r *Repository) CommitSeatReservation(ctx context.Context, transactionID string) error {
+	return r.faults.Do("CommitSeatReservation", transactionID, func() error {
+		return r.commitSeatReservation(ctx, transactionID)
+	})
+}
+
+// AbortSeatReservation runs abortSeatReservation, failing before or after it when a fault is injected
+func (r *Repository) AbortSeatReservation(ctx context.Context, transactionID string) error {
+	return r.faults.Do("AbortSeatReservation", transactionID, func() error {
+		return r.abortSeatReservation(ctx, transactionID)
+	})
+}
@@ -2,13 +2,18 @@ package train
 
 import (
 	"time"
+
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/api"
 )
 
 type TrainSeatReservationStatus string
 
 const (
-	TrainSeatReservationStatusCancelled TrainSeatReservationStatus = "CANCELLED"
-	TrainSeatReservationStatusReserved  TrainSeatReservationStatus = "RESERVED"
+	TrainSeatReservationStatusCancelled  TrainSeatReservationStatus = "CANCELLED"
+	TrainSeatReservationStatusReserved   TrainSeatReservationStatus = "RESERVED"
+	TrainSeatReservationStatusCancelling TrainSeatReservationStatus = "CANCELLING"
+	TrainSeatReservationStatusModifying  TrainSeatReservationStatus = "MODIFYING"
+	TrainSeatReservationStatusHeld       TrainSeatReservationStatus = "HELD"
 )
 
 type TwoPhaseTransactionStatus string
@@ -17,31 +22,120 @@ const (
 	TwoPhaseTransactionStatusPrepared  TwoPhaseTransactionStatus = "PREPARED"
 	TwoPhaseTransactionStatusCommitted TwoPhaseTransactionStatus = "COMMITTED"
 	TwoPhaseTransactionStatusAborted   TwoPhaseTransactionStatus = "ABORTED"
+	// HELD transactions are holds, which become COMMITTED when confirmed or EXPIRED when
+	// the participant releases them after ExpiresAt
+	TwoPhaseTransactionStatusHeld    TwoPhaseTransactionStatus = "HELD"
+	TwoPhaseTransactionStatusExpired TwoPhaseTransactionStatus = "EXPIRED"
 )
 
+// TrainJourney represents a train running its route on a specific departure date.
+// Segment i is the stretch between Stations[i] and Stations[i+1].
+type TrainJourney struct {
+	ID            string   `firestore:"id" json:"id"`
+	TrainName     string   `firestore:"train_name" json:"train_name"`
+	DepartureDate string   `firestore:"departure_date" json:"departure_date"`
+	Stations      []string `firestore:"stations" json:"stations"`
+	Coaches       int      `firestore:"coaches" json:"coaches"`
+	SeatsPerCoach int      `firestore:"seats_per_coach" json:"seats_per_coach"`
+}
+
+// Segments returns the segment range [from, to) travelled between origin and
+// destination. ok is false when either station is not on the route or the
+// stations are in the wrong order.
+func (j *TrainJourney) Segments(origin, destination string) (from, to int, ok bool) {
+	from, to = -1, -1
+	for i, station := range j.Stations {
+		if station == origin && from == -1 {
+			from = i
+		}
+		if station == destination {
+			to = i
+		}
+	}
+
+	if from == -1 || to == -1 || from >= to {
+		return 0, 0, false
+	}
+
+	return from, to, true
+}
+
+// TrainSeatTicket represents the availability of one seat on one segment of a dated train journey
 type TrainSeatTicket struct {
-	SeatID    string `firestore:"seat_id" json:"seat_id"`
-	TrainName string `firestore:"train_name" json:"train_name"`
-	Available bool   `firestore:"available" json:"available"`
+	JourneyID     string `firestore:"journey_id" json:"journey_id"`
+	DepartureDate string `firestore:"departure_date" json:"departure_date"`
+	SeatID        string `firestore:"seat_id" json:"seat_id"`
+	TrainName     string `firestore:"train_name" json:"train_name"`
+	Segment       int    `firestore:"segment" json:"segment"`
+	FromStation   string `firestore:"from_station" json:"from_station"`
+	ToStation     string `firestore:"to_station" json:"to_station"`
+	Available     bool   `firestore:"available" json:"available"`
 }
 
 type TrainSeatReservation struct {
-	ID            string                     `firestore:"id" json:"id"`
-	SeatID        string                     `firestore:"seat_id" json:"seat_id"`
-	TrainName     string                     `firestore:"train_name" json:"train_name"`
-	TransactionID string                     `firestore:"transaction_id" json:"transaction_id"`
-	Status        TrainSeatReservationStatus `firestore:"status" json:"status"`
+	ID                 string                     `firestore:"id" json:"id"`
+	JourneyID          string                     `firestore:"journey_id" json:"journey_id"`
+	DepartureDate      string                     `firestore:"departure_date" json:"departure_date"`
+	SeatID             string                     `firestore:"seat_id" json:"seat_id"`
+	TrainName          string                     `firestore:"train_name" json:"train_name"`
+	OriginStation      string                     `firestore:"origin_station" json:"origin_station"`
+	DestinationStation string                     `firestore:"destination_station" json:"destination_station"`
+	FromSegment        int                        `firestore:"from_segment" json:"from_segment"`
+	ToSegment          int                        `firestore:"to_segment" json:"to_segment"`
+	Price              int64                      `firestore:"price" json:"price"`
+	TransactionID      string                     `firestore:"transaction_id" json:"transaction_id"`
+	Status             TrainSeatReservationStatus `firestore:"status" json:"status"`
 }
 
 // TwoPhaseTransaction represents a two-phase commit transaction for train seat reservation
 type TwoPhaseTransaction struct {
-	Id            string                    `firestore:"id"`
-	Status        TwoPhaseTransactionStatus `firestore:"status"` // "prepared", "committed", "aborted"
-	ReservationID string                    `firestore:"reservation_id,omitempty"`
-	CreatedAt     time.Time                 `firestore:"created_at"`
-	UpdatedAt     time.Time                 `firestore:"updated_at"`
+	Id             string                    `firestore:"id"`
+	Status         TwoPhaseTransactionStatus `firestore:"status"` // "prepared", "committed", "aborted"
+	ReservationIDs []string                  `firestore:"reservation_ids,omitempty"`
+	// BookingTransactionID is set on cancellation transactions and refers to the booking being cancelled
+	BookingTransactionID string `firestore:"booking_transaction_id,omitempty"`
+	// ReplacedReservationID is set on modification transactions and refers to the
+	// reservation of the booking that ReservationIDs replaces on commit
+	ReplacedReservationID string `firestore:"replaced_reservation_id,omitempty"`
+	// ExpiresAt is set on holds
+	ExpiresAt *time.Time `firestore:"expires_at,omitempty"`
+	CreatedAt time.Time  `firestore:"created_at"`
+	UpdatedAt time.Time  `firestore:"updated_at"`
+}
+
+// TrainSeatItem is a single seat booked between two stations of a dated journey
+type TrainSeatItem struct {
+	JourneyID          string `json:"journey_id" binding:"required"`
+	DepartureDate      string `json:"departure_date" binding:"required"`
+	SeatID             string `json:"seat_id" binding:"required"`
+	OriginStation      string `json:"origin_station" binding:"required"`
+	DestinationStation string `json:"destination_station" binding:"required"`
+	Price              int64  `json:"price"`
 }
 
 type TrainSeatReservationPayload struct {
-	TrainSeatID string `json:"train_seat_id" binding:"required"`
+	TrainSeats []TrainSeatItem `json:"train_seats" binding:"required,min=1,dive"`
+}
+
+// TrainSeatModificationPayload replaces the seat at Index of a committed booking with TrainSeat
+type TrainSeatModificationPayload struct {
+	BookingTransactionID string        `json:"booking_transaction_id" binding:"required"`
+	Index                int           `json:"index" binding:"min=0"`
+	TrainSeat            TrainSeatItem `json:"train_seat"`
+}
+
+// SeatSearchQuery searches the seats of a journey that are available between two of its
+// stations. The whole route is searched when the stations are not set.
+type SeatSearchQuery struct {
+	OriginStation      string `form:"origin_station"`
+	DestinationStation string `form:"destination_station"`
+	api.PageRequest
+}
+
+// AvailableSeat is a seat found by a search
+type AvailableSeat struct {
+	SeatID        string `json:"seat_id"`
+	JourneyID     string `json:"journey_id"`
+	TrainName     string `json:"train_name"`
+	DepartureDate string `json:"departure_date"`
 }
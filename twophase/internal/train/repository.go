@@ -8,21 +8,37 @@ import (
 
 	"cloud.google.com/go/firestore"
 	"github.com/oklog/ulid/v2"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/api"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/fault"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/utils"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 const (
+	TrainJourneyCollection         = "twophase_train_journeys"
 	TrainSeatTicketCollection      = "twophase_train_seat_tickets"
 	TrainSeatReservationCollection = "twophase_train_seat_reservations"
 	TrainTransactionCollection     = "twophase_train_transactions"
 )
 
 var (
-	ErrSeatNotAvailable = errors.New("seat not available")
+	ErrSeatNotAvailable      = errors.New("seat not available")
+	ErrJourneyDateMismatch   = errors.New("journey does not depart on the requested date")
+	ErrJourneyNotFound       = errors.New("journey not found")
+	ErrBookingNotCancellable = errors.New("booking is not committed or is already being cancelled")
+	ErrBookingNotModifiable  = errors.New("booking is not committed or is already being cancelled or modified")
+	ErrHoldNotActive         = errors.New("hold is not active")
+	ErrHoldExpired           = errors.New("hold has expired")
+	ErrItemNotFound          = errors.New("booking has no seat at the given index")
+	ErrStationsNotOnRoute    = errors.New("journey does not serve the requested stations")
 )
 
 // Repository handles Firestore operations for hotel service
 type Repository struct {
 	client *firestore.Client
+	faults *fault.Injector
 }
 
 // NewRepository creates a new repository instance
@@ -49,7 +65,50 @@ func (r *Repository) GetTwoPhaseTransaction(ctx context.Context, transactionID s
 	return &transaction, nil
 }
 
-func (r *Repository) CommitSeatReservation(ctx context.Context, transactionID string) error {
+// GetPreparedTransactions retrieves the transactions that are prepared and still wait
+// for the coordinator to commit or abort them
+func (r *Repository) GetPreparedTransactions(ctx context.Context) ([]*TwoPhaseTransaction, error) {
+	query := r.client.Collection(TrainTransactionCollection).
+		Where("status", "==", TwoPhaseTransactionStatusPrepared)
+
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+
+	var transactions []*TwoPhaseTransaction
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate transactions: %w", err)
+		}
+
+		var transaction TwoPhaseTransaction
+		if err := doc.DataTo(&transaction); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal transaction: %w", err)
+		}
+
+		transactions = append(transactions, &transaction)
+	}
+
+	return transactions, nil
+}
+
+func (r *Repository) getSeatTicketId(journeyID, seatID string, segment int) string {
+	return fmt.Sprintf("%s-%s-%d", journeyID, seatID, segment)
+}
+
+// seatTicketRefs returns the ticket references covering segments [fromSegment, toSegment)
+func (r *Repository) seatTicketRefs(journeyID, seatID string, fromSegment, toSegment int) []*firestore.DocumentRef {
+	refs := make([]*firestore.DocumentRef, 0, toSegment-fromSegment)
+	for segment := fromSegment; segment < toSegment; segment++ {
+		refs = append(refs, r.client.Collection(TrainSeatTicketCollection).Doc(r.getSeatTicketId(journeyID, seatID, segment)))
+	}
+	return refs
+}
+
+func (r *Repository) commitSeatReservation(ctx context.Context, transactionID string) error {
 	transactionRef := r.client.Collection(TrainTransactionCollection).Doc(transactionID)
 
 	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
@@ -79,7 +138,13 @@ func (r *Repository) CommitSeatReservation(ctx context.Context, transactionID st
 	})
 }
 
-func (r *Repository) AbortSeatReservation(ctx context.Context, transactionID string) error {
+func (r *Repository) abortSeatReservation(ctx context.Context, transactionID string) error {
+	return r.releaseSeatReservations(ctx, transactionID, TwoPhaseTransactionStatusPrepared, TwoPhaseTransactionStatusAborted)
+}
+
+// releaseSeatReservations releases the availability held by a transaction in fromStatus,
+// cancels its reservations and moves the transaction to finalStatus
+func (r *Repository) releaseSeatReservations(ctx context.Context, transactionID string, fromStatus, finalStatus TwoPhaseTransactionStatus) error {
 	transactionRef := r.client.Collection(TrainTransactionCollection).Doc(transactionID)
 
 	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
@@ -93,41 +158,164 @@ func (r *Repository) AbortSeatReservation(ctx context.Context, transactionID str
 			return fmt.Errorf("failed to unmarshal transaction: %w", err)
 		}
 
-		if transaction.Status != TwoPhaseTransactionStatusPrepared {
+		if transaction.Status != fromStatus {
 			// Already committed or aborted
 			return nil
 		}
 
-		reservationRef := r.client.Collection(TrainSeatReservationCollection).Doc(transaction.ReservationID)
-		reservationDoc, err := tx.Get(reservationRef)
+		var reservationRefs []*firestore.DocumentRef
+		for _, reservationID := range transaction.ReservationIDs {
+			reservationRefs = append(reservationRefs, r.client.Collection(TrainSeatReservationCollection).Doc(reservationID))
+		}
+
+		reservationDocs, err := tx.GetAll(reservationRefs)
 		if err != nil {
-			return fmt.Errorf("failed to get reservation: %w", err)
+			return fmt.Errorf("failed to get reservations: %w", err)
 		}
 
-		var reservation TrainSeatReservation
-		if err := reservationDoc.DataTo(&reservation); err != nil {
-			return fmt.Errorf("failed to unmarshal reservation: %w", err)
+		// All reads must happen before the first write in a transaction
+		var ticketRefs []*firestore.DocumentRef
+		reservations := make([]*TrainSeatReservation, 0, len(reservationDocs))
+		for _, reservationDoc := range reservationDocs {
+			var reservation TrainSeatReservation
+			if err := reservationDoc.DataTo(&reservation); err != nil {
+				return fmt.Errorf("failed to unmarshal reservation: %w", err)
+			}
+			reservations = append(reservations, &reservation)
+
+			ticketRefs = append(ticketRefs, r.seatTicketRefs(reservation.JourneyID, reservation.SeatID, reservation.FromSegment, reservation.ToSegment)...)
+		}
+
+		if _, err := tx.GetAll(ticketRefs); err != nil {
+			return fmt.Errorf("failed to get tickets: %w", err)
+		}
+
+		for _, ticketRef := range ticketRefs {
+			if err := tx.Update(ticketRef, []firestore.Update{
+				{Path: "available", Value: true},
+			}); err != nil {
+				return fmt.Errorf("failed to update ticket: %w", err)
+			}
+		}
+
+		if err := tx.Update(transactionRef, []firestore.Update{
+			{Path: "status", Value: finalStatus},
+			{Path: "updated_at", Value: time.Now()},
+		}); err != nil {
+			return fmt.Errorf("failed to update transaction: %w", err)
+		}
+
+		for _, reservationRef := range reservationRefs {
+			if err := tx.Update(reservationRef, []firestore.Update{
+				{Path: "status", Value: TrainSeatReservationStatusCancelled},
+				{Path: "updated_at", Value: time.Now()},
+			}); err != nil {
+				return fmt.Errorf("failed to update reservation: %w", err)
+			}
+		}
+
+		for _, reservation := range reservations {
+			if err := r.recordRelease(tx, reservation); err != nil {
+				return err
+			}
 		}
 
-		ticketRef := r.client.Collection(TrainSeatTicketCollection).Doc(reservation.SeatID)
-		ticketDoc, err := tx.Get(ticketRef)
+		return nil
+	})
+}
+
+// PrepareSeatCancellation prepares releasing the seats reserved by a committed booking
+// transaction. The reservations are marked CANCELLING so that no other cancellation can
+// prepare them, while availability is only released on commit.
+func (r *Repository) PrepareSeatCancellation(ctx context.Context, transactionID, bookingTransactionID string) error {
+	bookingRef := r.client.Collection(TrainTransactionCollection).Doc(bookingTransactionID)
+
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		bookingDoc, err := tx.Get(bookingRef)
 		if err != nil {
-			return fmt.Errorf("failed to get ticket: %w", err)
+			return fmt.Errorf("failed to get booking transaction: %w", err)
 		}
 
-		var ticket TrainSeatTicket
-		if err := ticketDoc.DataTo(&ticket); err != nil {
-			return fmt.Errorf("failed to unmarshal ticket: %w", err)
+		var booking TwoPhaseTransaction
+		if err := bookingDoc.DataTo(&booking); err != nil {
+			return fmt.Errorf("failed to unmarshal booking transaction: %w", err)
 		}
 
-		if !ticket.Available {
-			return ErrSeatNotAvailable
+		if booking.Status != TwoPhaseTransactionStatusCommitted {
+			return ErrBookingNotCancellable
 		}
 
-		if err := tx.Update(ticketRef, []firestore.Update{
-			{Path: "available", Value: true},
-		}); err != nil {
-			return fmt.Errorf("failed to update ticket: %w", err)
+		var reservationRefs []*firestore.DocumentRef
+		for _, reservationID := range booking.ReservationIDs {
+			reservationRefs = append(reservationRefs, r.client.Collection(TrainSeatReservationCollection).Doc(reservationID))
+		}
+
+		reservationDocs, err := tx.GetAll(reservationRefs)
+		if err != nil {
+			return fmt.Errorf("failed to get reservations: %w", err)
+		}
+
+		for _, reservationDoc := range reservationDocs {
+			var reservation TrainSeatReservation
+			if err := reservationDoc.DataTo(&reservation); err != nil {
+				return fmt.Errorf("failed to unmarshal reservation: %w", err)
+			}
+
+			if reservation.Status != TrainSeatReservationStatusReserved {
+				return ErrBookingNotCancellable
+			}
+		}
+
+		for _, reservationRef := range reservationRefs {
+			if err := tx.Update(reservationRef, []firestore.Update{
+				{Path: "status", Value: TrainSeatReservationStatusCancelling},
+				{Path: "updated_at", Value: time.Now()},
+			}); err != nil {
+				return fmt.Errorf("failed to update reservation: %w", err)
+			}
+		}
+
+		twoPhaseTransaction := &TwoPhaseTransaction{
+			Id:                   transactionID,
+			Status:               TwoPhaseTransactionStatusPrepared,
+			ReservationIDs:       booking.ReservationIDs,
+			BookingTransactionID: bookingTransactionID,
+			CreatedAt:            time.Now(),
+			UpdatedAt:            time.Now(),
+		}
+
+		twoPhaseTransactionRef := r.client.Collection(TrainTransactionCollection).Doc(twoPhaseTransaction.Id)
+		if err := tx.Create(twoPhaseTransactionRef, twoPhaseTransaction); err != nil {
+			return fmt.Errorf("failed to create two-phase transaction: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// CommitSeatCancellation releases the availability of the cancelled booking
+func (r *Repository) CommitSeatCancellation(ctx context.Context, transactionID string) error {
+	return r.releaseSeatReservations(ctx, transactionID, TwoPhaseTransactionStatusPrepared, TwoPhaseTransactionStatusCommitted)
+}
+
+// AbortSeatCancellation puts the reservations of the booking back to RESERVED
+func (r *Repository) AbortSeatCancellation(ctx context.Context, transactionID string) error {
+	transactionRef := r.client.Collection(TrainTransactionCollection).Doc(transactionID)
+
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		transactionDoc, err := tx.Get(transactionRef)
+		if err != nil {
+			return fmt.Errorf("failed to get transaction: %w", err)
+		}
+
+		var transaction TwoPhaseTransaction
+		if err := transactionDoc.DataTo(&transaction); err != nil {
+			return fmt.Errorf("failed to unmarshal transaction: %w", err)
+		}
+
+		if transaction.Status != TwoPhaseTransactionStatusPrepared {
+			// Already committed or aborted
+			return nil
 		}
 
 		if err := tx.Update(transactionRef, []firestore.Update{
@@ -137,48 +325,132 @@ func (r *Repository) AbortSeatReservation(ctx context.Context, transactionID str
 			return fmt.Errorf("failed to update transaction: %w", err)
 		}
 
-		if err := tx.Update(reservationRef, []firestore.Update{
-			{Path: "status", Value: TrainSeatReservationStatusCancelled},
-			{Path: "updated_at", Value: time.Now()},
-		}); err != nil {
-			return fmt.Errorf("failed to update reservation: %w", err)
+		for _, reservationID := range transaction.ReservationIDs {
+			if err := tx.Update(r.client.Collection(TrainSeatReservationCollection).Doc(reservationID), []firestore.Update{
+				{Path: "status", Value: TrainSeatReservationStatusReserved},
+				{Path: "updated_at", Value: time.Now()},
+			}); err != nil {
+				return fmt.Errorf("failed to update reservation: %w", err)
+			}
 		}
 
 		return nil
 	})
 }
 
-// PrepareSeatReservation prepares a seat reservation
-func (r *Repository) PrepareSeatReservation(ctx context.Context, transactionID, seatID string) error {
-	ticketRef := r.client.Collection(TrainSeatTicketCollection).Doc(seatID)
+// PrepareSeatModification prepares replacing the seat at index of a committed booking
+// transaction. The new seat is reserved right away while the replaced reservation is
+// marked MODIFYING, so the booking keeps its original seat until the commit. Segments
+// already held by the replaced reservation can be reused by the new seat.
+func (r *Repository) PrepareSeatModification(ctx context.Context, transactionID, bookingTransactionID string, index int, seat TrainSeatItem) error {
+	bookingRef := r.client.Collection(TrainTransactionCollection).Doc(bookingTransactionID)
 
 	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
-		var ticket TrainSeatTicket
-		ticketDoc, err := tx.Get(ticketRef)
+		bookingDoc, err := tx.Get(bookingRef)
+		if err != nil {
+			return fmt.Errorf("failed to get booking transaction: %w", err)
+		}
+
+		var booking TwoPhaseTransaction
+		if err := bookingDoc.DataTo(&booking); err != nil {
+			return fmt.Errorf("failed to unmarshal booking transaction: %w", err)
+		}
+
+		if booking.Status != TwoPhaseTransactionStatusCommitted {
+			return ErrBookingNotModifiable
+		}
+		if index < 0 || index >= len(booking.ReservationIDs) {
+			return ErrItemNotFound
+		}
+
+		oldRef := r.client.Collection(TrainSeatReservationCollection).Doc(booking.ReservationIDs[index])
+		oldDoc, err := tx.Get(oldRef)
 		if err != nil {
-			return fmt.Errorf("failed to get ticket: %w", err)
+			return fmt.Errorf("failed to get reservation: %w", err)
 		}
 
-		if err := ticketDoc.DataTo(&ticket); err != nil {
-			return fmt.Errorf("failed to unmarshal ticket: %w", err)
+		var oldReservation TrainSeatReservation
+		if err := oldDoc.DataTo(&oldReservation); err != nil {
+			return fmt.Errorf("failed to unmarshal reservation: %w", err)
 		}
 
-		if !ticket.Available {
-			return ErrSeatNotAvailable
+		if oldReservation.Status != TrainSeatReservationStatusReserved {
+			return ErrBookingNotModifiable
 		}
 
-		if err := tx.Update(ticketRef, []firestore.Update{
-			{Path: "available", Value: false},
+		journeyDoc, err := tx.Get(r.client.Collection(TrainJourneyCollection).Doc(seat.JourneyID))
+		if status.Code(err) == codes.NotFound {
+			return ErrJourneyNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get journey: %w", err)
+		}
+
+		var journey TrainJourney
+		if err := journeyDoc.DataTo(&journey); err != nil {
+			return fmt.Errorf("failed to unmarshal journey: %w", err)
+		}
+
+		if journey.DepartureDate != seat.DepartureDate {
+			return ErrJourneyDateMismatch
+		}
+
+		fromSegment, toSegment, ok := journey.Segments(seat.OriginStation, seat.DestinationStation)
+		if !ok {
+			return ErrStationsNotOnRoute
+		}
+
+		oldRefs := r.seatTicketRefs(oldReservation.JourneyID, oldReservation.SeatID, oldReservation.FromSegment, oldReservation.ToSegment)
+		newRefs := r.seatTicketRefs(seat.JourneyID, seat.SeatID, fromSegment, toSegment)
+
+		ticketDocs, err := tx.GetAll(newRefs)
+		if err != nil {
+			return fmt.Errorf("failed to get tickets: %w", err)
+		}
+
+		for _, ticketDoc := range ticketDocs {
+			if !ticketDoc.Exists() {
+				return ErrSeatNotAvailable
+			}
+
+			var ticket TrainSeatTicket
+			if err := ticketDoc.DataTo(&ticket); err != nil {
+				return fmt.Errorf("failed to unmarshal ticket: %w", err)
+			}
+
+			if !ticket.Available && !containsRef(oldRefs, ticketDoc.Ref) {
+				return ErrSeatNotAvailable
+			}
+		}
+
+		for _, ticketRef := range excludeRefs(newRefs, oldRefs) {
+			if err := tx.Update(ticketRef, []firestore.Update{
+				{Path: "available", Value: false},
+			}); err != nil {
+				return fmt.Errorf("failed to update ticket: %w", err)
+			}
+		}
+
+		if err := tx.Update(oldRef, []firestore.Update{
+			{Path: "status", Value: TrainSeatReservationStatusModifying},
+			{Path: "updated_at", Value: time.Now()},
 		}); err != nil {
-			return fmt.Errorf("failed to update ticket: %w", err)
+			return fmt.Errorf("failed to update reservation: %w", err)
 		}
 
 		trainSeatReservation := &TrainSeatReservation{
-			ID:            ulid.Make().String(),
-			SeatID:        seatID,
-			TrainName:     ticket.TrainName,
-			TransactionID: transactionID,
-			Status:        TrainSeatReservationStatusReserved,
+			ID:                 ulid.Make().String(),
+			JourneyID:          seat.JourneyID,
+			DepartureDate:      seat.DepartureDate,
+			SeatID:             seat.SeatID,
+			TrainName:          journey.TrainName,
+			OriginStation:      seat.OriginStation,
+			DestinationStation: seat.DestinationStation,
+			FromSegment:        fromSegment,
+			ToSegment:          toSegment,
+			Price:              seat.Price,
+			TransactionID:      transactionID,
+			Status:             TrainSeatReservationStatusReserved,
 		}
 
 		trainSeatReservationRef := r.client.Collection(TrainSeatReservationCollection).Doc(trainSeatReservation.ID)
@@ -187,11 +459,13 @@ func (r *Repository) PrepareSeatReservation(ctx context.Context, transactionID,
 		}
 
 		twoPhaseTransaction := &TwoPhaseTransaction{
-			Id:            transactionID,
-			Status:        TwoPhaseTransactionStatusPrepared,
-			ReservationID: trainSeatReservation.ID,
-			CreatedAt:     time.Now(),
-			UpdatedAt:     time.Now(),
+			Id:                    transactionID,
+			Status:                TwoPhaseTransactionStatusPrepared,
+			ReservationIDs:        []string{trainSeatReservation.ID},
+			BookingTransactionID:  bookingTransactionID,
+			ReplacedReservationID: oldReservation.ID,
+			CreatedAt:             time.Now(),
+			UpdatedAt:             time.Now(),
 		}
 
 		twoPhaseTransactionRef := r.client.Collection(TrainTransactionCollection).Doc(twoPhaseTransaction.Id)
@@ -203,17 +477,504 @@ func (r *Repository) PrepareSeatReservation(ctx context.Context, transactionID,
 	})
 }
 
-func (r *Repository) BulkWriteTrainSeatTicket(ctx context.Context, trainSeatTickets []TrainSeatTicket) error {
+// CommitSeatModification releases the segments of the replaced reservation that the new
+// seat does not use and swaps the reservation in the booking transaction
+func (r *Repository) CommitSeatModification(ctx context.Context, transactionID string) error {
+	return r.finishSeatModification(ctx, transactionID, TwoPhaseTransactionStatusCommitted)
+}
+
+// AbortSeatModification releases the new seat and puts the replaced reservation back to RESERVED
+func (r *Repository) AbortSeatModification(ctx context.Context, transactionID string) error {
+	return r.finishSeatModification(ctx, transactionID, TwoPhaseTransactionStatusAborted)
+}
+
+// finishSeatModification moves a prepared modification transaction to finalStatus. On
+// commit the replaced reservation is cancelled, on abort the new one is.
+func (r *Repository) finishSeatModification(ctx context.Context, transactionID string, finalStatus TwoPhaseTransactionStatus) error {
+	transactionRef := r.client.Collection(TrainTransactionCollection).Doc(transactionID)
+
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		transactionDoc, err := tx.Get(transactionRef)
+		if err != nil {
+			return fmt.Errorf("failed to get transaction: %w", err)
+		}
+
+		var transaction TwoPhaseTransaction
+		if err := transactionDoc.DataTo(&transaction); err != nil {
+			return fmt.Errorf("failed to unmarshal transaction: %w", err)
+		}
+
+		if transaction.Status != TwoPhaseTransactionStatusPrepared {
+			// Already committed or aborted
+			return nil
+		}
+
+		bookingRef := r.client.Collection(TrainTransactionCollection).Doc(transaction.BookingTransactionID)
+		bookingDoc, err := tx.Get(bookingRef)
+		if err != nil {
+			return fmt.Errorf("failed to get booking transaction: %w", err)
+		}
+
+		var booking TwoPhaseTransaction
+		if err := bookingDoc.DataTo(&booking); err != nil {
+			return fmt.Errorf("failed to unmarshal booking transaction: %w", err)
+		}
+
+		oldRef := r.client.Collection(TrainSeatReservationCollection).Doc(transaction.ReplacedReservationID)
+		newRef := r.client.Collection(TrainSeatReservationCollection).Doc(transaction.ReservationIDs[0])
+		reservationDocs, err := tx.GetAll([]*firestore.DocumentRef{oldRef, newRef})
+		if err != nil {
+			return fmt.Errorf("failed to get reservations: %w", err)
+		}
+
+		ticketRefs := make([][]*firestore.DocumentRef, len(reservationDocs))
+		reservations := make([]*TrainSeatReservation, len(reservationDocs))
+		for i, reservationDoc := range reservationDocs {
+			var reservation TrainSeatReservation
+			if err := reservationDoc.DataTo(&reservation); err != nil {
+				return fmt.Errorf("failed to unmarshal reservation: %w", err)
+			}
+			reservations[i] = &reservation
+
+			ticketRefs[i] = r.seatTicketRefs(reservation.JourneyID, reservation.SeatID, reservation.FromSegment, reservation.ToSegment)
+		}
+
+		// Only the segments held by the released reservation alone become available again
+		releasedRef, keptRef := oldRef, newRef
+		releasedRefs := excludeRefs(ticketRefs[0], ticketRefs[1])
+		released := reservations[0]
+		if finalStatus == TwoPhaseTransactionStatusAborted {
+			releasedRef, keptRef = newRef, oldRef
+			releasedRefs = excludeRefs(ticketRefs[1], ticketRefs[0])
+			released = reservations[1]
+		}
+
+		if _, err := tx.GetAll(releasedRefs); err != nil {
+			return fmt.Errorf("failed to get tickets: %w", err)
+		}
+
+		for _, ticketRef := range releasedRefs {
+			if err := tx.Update(ticketRef, []firestore.Update{
+				{Path: "available", Value: true},
+			}); err != nil {
+				return fmt.Errorf("failed to update ticket: %w", err)
+			}
+		}
+
+		if err := tx.Update(releasedRef, []firestore.Update{
+			{Path: "status", Value: TrainSeatReservationStatusCancelled},
+			{Path: "updated_at", Value: time.Now()},
+		}); err != nil {
+			return fmt.Errorf("failed to update reservation: %w", err)
+		}
+
+		if err := tx.Update(keptRef, []firestore.Update{
+			{Path: "status", Value: TrainSeatReservationStatusReserved},
+			{Path: "updated_at", Value: time.Now()},
+		}); err != nil {
+			return fmt.Errorf("failed to update reservation: %w", err)
+		}
+
+		if finalStatus == TwoPhaseTransactionStatusCommitted {
+			reservationIDs := make([]string, 0, len(booking.ReservationIDs))
+			for _, reservationID := range booking.ReservationIDs {
+				if reservationID == transaction.ReplacedReservationID {
+					reservationID = newRef.ID
+				}
+				reservationIDs = append(reservationIDs, reservationID)
+			}
+
+			if err := tx.Update(bookingRef, []firestore.Update{
+				{Path: "reservation_ids", Value: reservationIDs},
+				{Path: "updated_at", Value: time.Now()},
+			}); err != nil {
+				return fmt.Errorf("failed to update booking transaction: %w", err)
+			}
+		}
+
+		if err := tx.Update(transactionRef, []firestore.Update{
+			{Path: "status", Value: finalStatus},
+			{Path: "updated_at", Value: time.Now()},
+		}); err != nil {
+			return fmt.Errorf("failed to update transaction: %w", err)
+		}
+
+		if len(releasedRefs) > 0 {
+			if err := r.recordRelease(tx, released); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// recordRelease adds the released reservation to the inventory release outbox
+func (r *Repository) recordRelease(tx *firestore.Transaction, reservation *TrainSeatReservation) error {
+	release := &api.InventoryRelease{
+		ID:        ulid.Make().String(),
+		Service:   "train",
+		ItemID:    reservation.JourneyID,
+		SeatID:    reservation.SeatID,
+		StartDate: reservation.DepartureDate,
+		EndDate:   reservation.DepartureDate,
+		CreatedAt: time.Now(),
+	}
+	if err := tx.Create(r.client.Collection(api.InventoryReleaseCollection).Doc(release.ID), release); err != nil {
+		return fmt.Errorf("failed to record release: %w", err)
+	}
+	return nil
+}
+
+// containsRef reports whether refs contains a reference to the same document as ref
+func containsRef(refs []*firestore.DocumentRef, ref *firestore.DocumentRef) bool {
+	for _, r := range refs {
+		if r.Path == ref.Path {
+			return true
+		}
+	}
+	return false
+}
+
+// excludeRefs returns the references of refs that are not in excluded
+func excludeRefs(refs, excluded []*firestore.DocumentRef) []*firestore.DocumentRef {
+	var result []*firestore.DocumentRef
+	for _, ref := range refs {
+		if !containsRef(excluded, ref) {
+			result = append(result, ref)
+		}
+	}
+	return result
+}
+
+// prepareSeatReservation prepares reservations for all seats in a single transaction.
+// Only the segments between each seat's origin and destination stations are locked,
+// so the same seat can be sold for other non-overlapping parts of the route. If any
+// seat fails, nothing is reserved and an *api.ItemError identifies the offending item.
+func (r *Repository) prepareSeatReservation(ctx context.Context, transactionID string, seats []TrainSeatItem) error {
+	return r.reserveSeats(ctx, transactionID, seats, nil)
+}
+
+// HoldSeats reserves all seats like PrepareSeatReservation, but as a hold that is
+// released by ExpireSeatHold unless it is confirmed before expiresAt
+func (r *Repository) HoldSeats(ctx context.Context, holdID string, seats []TrainSeatItem, expiresAt time.Time) error {
+	return r.reserveSeats(ctx, holdID, seats, &expiresAt)
+}
+
+// reserveSeats locks the availability of the seats and creates their reservations under
+// transactionID, as a hold when expiresAt is set or as a prepared transaction otherwise
+func (r *Repository) reserveSeats(ctx context.Context, transactionID string, seats []TrainSeatItem, expiresAt *time.Time) error {
+	transactionStatus, reservationStatus := TwoPhaseTransactionStatusPrepared, TrainSeatReservationStatusReserved
+	if expiresAt != nil {
+		transactionStatus, reservationStatus = TwoPhaseTransactionStatusHeld, TrainSeatReservationStatusHeld
+	}
+
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		journeys := make(map[string]*TrainJourney)
+		for i, seat := range seats {
+			if _, ok := journeys[seat.JourneyID]; ok {
+				continue
+			}
+
+			journeyDoc, err := tx.Get(r.client.Collection(TrainJourneyCollection).Doc(seat.JourneyID))
+			if status.Code(err) == codes.NotFound {
+				return &api.ItemError{Index: i, Err: ErrJourneyNotFound}
+			}
+			if err != nil {
+				return fmt.Errorf("failed to get journey: %w", err)
+			}
+
+			var journey TrainJourney
+			if err := journeyDoc.DataTo(&journey); err != nil {
+				return fmt.Errorf("failed to unmarshal journey: %w", err)
+			}
+			journeys[seat.JourneyID] = &journey
+		}
+
+		fromSegments := make([]int, len(seats))
+		toSegments := make([]int, len(seats))
+		ticketRefs := make([][]*firestore.DocumentRef, len(seats))
+		seen := make(map[string]bool)
+		for i, seat := range seats {
+			journey := journeys[seat.JourneyID]
+			if journey.DepartureDate != seat.DepartureDate {
+				return &api.ItemError{Index: i, Err: ErrJourneyDateMismatch}
+			}
+
+			fromSegment, toSegment, ok := journey.Segments(seat.OriginStation, seat.DestinationStation)
+			if !ok {
+				return &api.ItemError{Index: i, Err: ErrStationsNotOnRoute}
+			}
+
+			refs := r.seatTicketRefs(seat.JourneyID, seat.SeatID, fromSegment, toSegment)
+			// The same seat segment cannot be booked twice within one order
+			for _, ref := range refs {
+				if seen[ref.ID] {
+					return &api.ItemError{Index: i, Err: ErrSeatNotAvailable}
+				}
+				seen[ref.ID] = true
+			}
+
+			fromSegments[i], toSegments[i], ticketRefs[i] = fromSegment, toSegment, refs
+		}
+
+		for i, refs := range ticketRefs {
+			ticketDocs, err := tx.GetAll(refs)
+			if err != nil {
+				return fmt.Errorf("failed to get tickets: %w", err)
+			}
+
+			for _, ticketDoc := range ticketDocs {
+				if !ticketDoc.Exists() {
+					return &api.ItemError{Index: i, Err: ErrSeatNotAvailable}
+				}
+
+				var ticket TrainSeatTicket
+				if err := ticketDoc.DataTo(&ticket); err != nil {
+					return fmt.Errorf("failed to unmarshal ticket: %w", err)
+				}
+
+				if !ticket.Available {
+					return &api.ItemError{Index: i, Err: ErrSeatNotAvailable}
+				}
+			}
+		}
+
+		reservationIDs := make([]string, 0, len(seats))
+		for i, seat := range seats {
+			for _, ticketRef := range ticketRefs[i] {
+				if err := tx.Update(ticketRef, []firestore.Update{
+					{Path: "available", Value: false},
+				}); err != nil {
+					return fmt.Errorf("failed to update ticket: %w", err)
+				}
+			}
+
+			trainSeatReservation := &TrainSeatReservation{
+				ID:                 ulid.Make().String(),
+				JourneyID:          seat.JourneyID,
+				DepartureDate:      seat.DepartureDate,
+				SeatID:             seat.SeatID,
+				TrainName:          journeys[seat.JourneyID].TrainName,
+				OriginStation:      seat.OriginStation,
+				DestinationStation: seat.DestinationStation,
+				FromSegment:        fromSegments[i],
+				ToSegment:          toSegments[i],
+				Price:              seat.Price,
+				TransactionID:      transactionID,
+				Status:             reservationStatus,
+			}
+
+			trainSeatReservationRef := r.client.Collection(TrainSeatReservationCollection).Doc(trainSeatReservation.ID)
+			if err := tx.Create(trainSeatReservationRef, trainSeatReservation); err != nil {
+				return fmt.Errorf("failed to create train seat reservation: %w", err)
+			}
+
+			reservationIDs = append(reservationIDs, trainSeatReservation.ID)
+		}
+
+		twoPhaseTransaction := &TwoPhaseTransaction{
+			Id:             transactionID,
+			Status:         transactionStatus,
+			ReservationIDs: reservationIDs,
+			ExpiresAt:      expiresAt,
+			CreatedAt:      time.Now(),
+			UpdatedAt:      time.Now(),
+		}
+
+		twoPhaseTransactionRef := r.client.Collection(TrainTransactionCollection).Doc(twoPhaseTransaction.Id)
+		if err := tx.Create(twoPhaseTransactionRef, twoPhaseTransaction); err != nil {
+			return fmt.Errorf("failed to create two-phase transaction: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// ConfirmSeatHold turns an unexpired hold into a committed booking transaction, so it can
+// later be cancelled or modified like any other booking
+func (r *Repository) ConfirmSeatHold(ctx context.Context, holdID string, now time.Time) error {
+	holdRef := r.client.Collection(TrainTransactionCollection).Doc(holdID)
+
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		holdDoc, err := tx.Get(holdRef)
+		if err != nil {
+			return fmt.Errorf("failed to get hold: %w", err)
+		}
+
+		var hold TwoPhaseTransaction
+		if err := holdDoc.DataTo(&hold); err != nil {
+			return fmt.Errorf("failed to unmarshal hold: %w", err)
+		}
+
+		if hold.Status == TwoPhaseTransactionStatusCommitted && hold.ExpiresAt != nil {
+			// Already confirmed
+			return nil
+		}
+		if hold.Status != TwoPhaseTransactionStatusHeld {
+			return ErrHoldNotActive
+		}
+		if hold.ExpiresAt == nil || !now.Before(*hold.ExpiresAt) {
+			return ErrHoldExpired
+		}
+
+		for _, reservationID := range hold.ReservationIDs {
+			if err := tx.Update(r.client.Collection(TrainSeatReservationCollection).Doc(reservationID), []firestore.Update{
+				{Path: "status", Value: TrainSeatReservationStatusReserved},
+				{Path: "updated_at", Value: time.Now()},
+			}); err != nil {
+				return fmt.Errorf("failed to update reservation: %w", err)
+			}
+		}
+
+		if err := tx.Update(holdRef, []firestore.Update{
+			{Path: "status", Value: TwoPhaseTransactionStatusCommitted},
+			{Path: "updated_at", Value: time.Now()},
+		}); err != nil {
+			return fmt.Errorf("failed to update hold: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// ReleaseSeatHold releases the seats of a hold before it expires
+func (r *Repository) ReleaseSeatHold(ctx context.Context, holdID string) error {
+	return r.releaseSeatReservations(ctx, holdID, TwoPhaseTransactionStatusHeld, TwoPhaseTransactionStatusAborted)
+}
+
+// ExpireSeatHold releases the seats of an expired hold
+func (r *Repository) ExpireSeatHold(ctx context.Context, holdID string) error {
+	return r.releaseSeatReservations(ctx, holdID, TwoPhaseTransactionStatusHeld, TwoPhaseTransactionStatusExpired)
+}
+
+// GetExpiredHoldIDs returns the IDs of the holds that expired before now
+func (r *Repository) GetExpiredHoldIDs(ctx context.Context, now time.Time) ([]string, error) {
+	docs, err := r.client.Collection(TrainTransactionCollection).
+		Where("status", "==", TwoPhaseTransactionStatusHeld).
+		Where("expires_at", "<=", now).
+		Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expired holds: %w", err)
+	}
+
+	holdIDs := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		holdIDs = append(holdIDs, doc.Ref.ID)
+	}
+
+	return holdIDs, nil
+}
+
+// GetTrainJourney retrieves a dated train journey
+func (r *Repository) GetTrainJourney(ctx context.Context, journeyID string) (*TrainJourney, error) {
+	doc, err := r.client.Collection(TrainJourneyCollection).Doc(journeyID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, ErrJourneyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get journey: %w", err)
+	}
+
+	var journey TrainJourney
+	if err := doc.DataTo(&journey); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal journey: %w", err)
+	}
+
+	return &journey, nil
+}
+
+// SearchAvailableSeats returns up to limit seats of the journey, ordered by ID and starting
+// after the seat pageToken, that are available on all segments [fromSegment, toSegment).
+// The returned token is empty once there are no more seats to search.
+func (r *Repository) SearchAvailableSeats(ctx context.Context, journey *TrainJourney, fromSegment, toSegment int, pageToken string, limit int) ([]AvailableSeat, string, error) {
+	segments := make([]int, 0, toSegment-fromSegment)
+	for segment := fromSegment; segment < toSegment; segment++ {
+		segments = append(segments, segment)
+	}
+
+	// Every seat has one ticket per segment, so a seat is available when all of its
+	// tickets for the segments are
+	query := r.client.Collection(TrainSeatTicketCollection).
+		Where("journey_id", "==", journey.ID).
+		Where("segment", "in", segments).
+		Where("available", "==", true).
+		OrderBy("seat_id", firestore.Asc)
+	if pageToken != "" {
+		query = query.StartAfter(pageToken)
+	}
+
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+
+	seats := make([]AvailableSeat, 0, limit)
+	currentSeatID := ""
+	availableSegments := 0
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to search seat tickets: %w", err)
+		}
+
+		var ticket TrainSeatTicket
+		if err := doc.DataTo(&ticket); err != nil {
+			return nil, "", fmt.Errorf("failed to unmarshal seat ticket: %w", err)
+		}
+
+		if currentSeatID != "" && ticket.SeatID == currentSeatID {
+			availableSegments++
+			continue
+		}
+
+		if currentSeatID != "" && availableSegments == len(segments) {
+			seats = append(seats, availableSeat(journey, currentSeatID))
+			if len(seats) == limit {
+				return seats, currentSeatID, nil
+			}
+		}
+		currentSeatID = ticket.SeatID
+		availableSegments = 1
+	}
+
+	if currentSeatID != "" && availableSegments == len(segments) {
+		seats = append(seats, availableSeat(journey, currentSeatID))
+	}
+
+	return seats, "", nil
+}
+
+func availableSeat(journey *TrainJourney, seatID string) AvailableSeat {
+	return AvailableSeat{
+		SeatID:        seatID,
+		JourneyID:     journey.ID,
+		TrainName:     journey.TrainName,
+		DepartureDate: journey.DepartureDate,
+	}
+}
+
+func (r *Repository) BulkWriteTrainJourney(ctx context.Context, trainJourneys []TrainJourney, mode utils.WriteMode) (utils.BulkWriteResult, error) {
+	collection := r.client.Collection(TrainJourneyCollection)
+
+	docs := make([]utils.Doc, 0, len(trainJourneys))
+	for _, trainJourney := range trainJourneys {
+		docs = append(docs, utils.Doc{Ref: collection.Doc(trainJourney.ID), Data: trainJourney})
+	}
+
+	return utils.BulkWrite(ctx, r.client, docs, mode)
+}
+
+// BulkWriteTrainSeatTicket seeds seat tickets. An upsert keeps the available flag of
+// existing documents.
+func (r *Repository) BulkWriteTrainSeatTicket(ctx context.Context, trainSeatTickets []TrainSeatTicket, mode utils.WriteMode) (utils.BulkWriteResult, error) {
 	collection := r.client.Collection(TrainSeatTicketCollection)
-	bw := r.client.BulkWriter(ctx)
 
+	docs := make([]utils.Doc, 0, len(trainSeatTickets))
 	for _, trainSeatTicket := range trainSeatTickets {
-		docRef := collection.Doc(trainSeatTicket.SeatID)
-		bw.Set(docRef, trainSeatTicket)
+		docs = append(docs, utils.Doc{Ref: collection.Doc(r.getSeatTicketId(trainSeatTicket.JourneyID, trainSeatTicket.SeatID, trainSeatTicket.Segment)), Data: trainSeatTicket})
 	}
 
-	// Flush all writes
-	bw.Flush()
-
-	return nil
+	return utils.BulkWrite(ctx, r.client, docs, mode, "journey_id", "departure_date", "seat_id", "train_name", "segment", "from_station", "to_station")
 }
@@ -2,20 +2,26 @@ package train
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"time"
 
 	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/api"
+	"github.com/zydhanlinnar11/hotel-train-car-booking-services/twophase/pkg/config"
 )
 
 // Service handles hotel business logic with two-phase commit
 type Service struct {
-	repo *Repository
+	repo    *Repository
+	holdTTL time.Duration
 }
 
-// NewService creates a new hotel service
-func NewService(repo *Repository) *Service {
+// NewService creates a new hotel service. Holds last holdTTL unless the request sets its own TTL.
+func NewService(repo *Repository, holdTTL time.Duration) *Service {
 	return &Service{
-		repo: repo,
+		repo:    repo,
+		holdTTL: holdTTL,
 	}
 }
 
@@ -31,11 +37,27 @@ func (s *Service) Prepare(ctx context.Context, req *api.PrepareRequest[TrainSeat
 		}, nil
 	}
 
-	if err := s.repo.PrepareSeatReservation(ctx, req.TransactionID, req.Payload.TrainSeatID); err != nil {
+	seats, itemErr := parseSeats(req.Payload.TrainSeats)
+	if itemErr != nil {
 		return &api.PrepareResponse{
+			Success:    false,
+			Message:    fmt.Sprintf("Failed to parse item: %v", itemErr.Err),
+			FailedItem: &itemErr.Index,
+		}, nil
+	}
+
+	if err := s.repo.PrepareSeatReservation(ctx, req.TransactionID, seats); err != nil {
+		response := &api.PrepareResponse{
 			Success: false,
 			Message: fmt.Sprintf("Failed to create transaction log: %v", err),
-		}, nil
+		}
+
+		var itemErr *api.ItemError
+		if errors.As(err, &itemErr) {
+			response.FailedItem = &itemErr.Index
+		}
+
+		return response, nil
 	}
 
 	return &api.PrepareResponse{
@@ -73,3 +95,267 @@ func (s *Service) Abort(ctx context.Context, req *api.AbortRequest) (*api.AbortR
 		Message: "Train service aborted successfully",
 	}, nil
 }
+
+// PrepareCancellation handles the prepare phase of a cancellation transaction
+func (s *Service) PrepareCancellation(ctx context.Context, req *api.PrepareRequest[api.CancellationPayload]) (*api.PrepareResponse, error) {
+	// Check if transaction already exists
+	existingTransaction, err := s.repo.GetTwoPhaseTransaction(ctx, req.TransactionID)
+	if err == nil && existingTransaction != nil {
+		return &api.PrepareResponse{
+			Success: existingTransaction.Status == TwoPhaseTransactionStatusPrepared,
+			Message: fmt.Sprintf("Transaction already %s", existingTransaction.Status),
+		}, nil
+	}
+
+	if err := s.repo.PrepareSeatCancellation(ctx, req.TransactionID, req.Payload.BookingTransactionID); err != nil {
+		return &api.PrepareResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to prepare cancellation: %v", err),
+		}, nil
+	}
+
+	return &api.PrepareResponse{
+		Success: true,
+		Message: "Train service prepared cancellation successfully",
+	}, nil
+}
+
+// CommitCancellation handles the commit phase of a cancellation transaction
+func (s *Service) CommitCancellation(ctx context.Context, req *api.CommitRequest) (*api.CommitResponse, error) {
+	if err := s.repo.CommitSeatCancellation(ctx, req.TransactionID); err != nil {
+		return &api.CommitResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to commit cancellation: %v", err),
+		}, nil
+	}
+
+	return &api.CommitResponse{
+		Success: true,
+		Message: "Train service committed cancellation successfully",
+	}, nil
+}
+
+// AbortCancellation handles the abort phase of a cancellation transaction
+func (s *Service) AbortCancellation(ctx context.Context, req *api.AbortRequest) (*api.AbortResponse, error) {
+	if err := s.repo.AbortSeatCancellation(ctx, req.TransactionID); err != nil {
+		return &api.AbortResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to abort cancellation: %v", err),
+		}, nil
+	}
+
+	return &api.AbortResponse{
+		Success: true,
+		Message: "Train service aborted cancellation successfully",
+	}, nil
+}
+
+// PrepareModification handles the prepare phase of a modification transaction
+func (s *Service) PrepareModification(ctx context.Context, req *api.PrepareRequest[TrainSeatModificationPayload]) (*api.PrepareResponse, error) {
+	// Check if transaction already exists
+	existingTransaction, err := s.repo.GetTwoPhaseTransaction(ctx, req.TransactionID)
+	if err == nil && existingTransaction != nil {
+		return &api.PrepareResponse{
+			Success: existingTransaction.Status == TwoPhaseTransactionStatusPrepared,
+			Message: fmt.Sprintf("Transaction already %s", existingTransaction.Status),
+		}, nil
+	}
+
+	seat := req.Payload.TrainSeat
+	departureDate, err := time.Parse(config.DateFormat, seat.DepartureDate)
+	if err != nil {
+		return &api.PrepareResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to parse departure date: %v", err),
+		}, nil
+	}
+
+	seat.DepartureDate = departureDate.Format(config.DateFormat)
+	if err := s.repo.PrepareSeatModification(ctx, req.TransactionID, req.Payload.BookingTransactionID, req.Payload.Index, seat); err != nil {
+		return &api.PrepareResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to prepare modification: %v", err),
+		}, nil
+	}
+
+	return &api.PrepareResponse{
+		Success: true,
+		Message: "Train service prepared modification successfully",
+	}, nil
+}
+
+// CommitModification handles the commit phase of a modification transaction
+func (s *Service) CommitModification(ctx context.Context, req *api.CommitRequest) (*api.CommitResponse, error) {
+	if err := s.repo.CommitSeatModification(ctx, req.TransactionID); err != nil {
+		return &api.CommitResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to commit modification: %v", err),
+		}, nil
+	}
+
+	return &api.CommitResponse{
+		Success: true,
+		Message: "Train service committed modification successfully",
+	}, nil
+}
+
+// AbortModification handles the abort phase of a modification transaction
+func (s *Service) AbortModification(ctx context.Context, req *api.AbortRequest) (*api.AbortResponse, error) {
+	if err := s.repo.AbortSeatModification(ctx, req.TransactionID); err != nil {
+		return &api.AbortResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to abort modification: %v", err),
+		}, nil
+	}
+
+	return &api.AbortResponse{
+		Success: true,
+		Message: "Train service aborted modification successfully",
+	}, nil
+}
+
+// parseSeats normalizes the departure dates of all seats to config.DateFormat
+func parseSeats(items []TrainSeatItem) ([]TrainSeatItem, *api.ItemError) {
+	seats := make([]TrainSeatItem, 0, len(items))
+	for i, seat := range items {
+		departureDate, err := time.Parse(config.DateFormat, seat.DepartureDate)
+		if err != nil {
+			return nil, &api.ItemError{Index: i, Err: fmt.Errorf("invalid departure date: %w", err)}
+		}
+
+		seat.DepartureDate = departureDate.Format(config.DateFormat)
+		seats = append(seats, seat)
+	}
+
+	return seats, nil
+}
+
+// Hold places a hold on the seats until it is confirmed, released or expires
+func (s *Service) Hold(ctx context.Context, req *api.HoldRequest[TrainSeatReservationPayload]) (*api.HoldResponse, error) {
+	// Check if hold already exists
+	existingHold, err := s.repo.GetTwoPhaseTransaction(ctx, req.HoldID)
+	if err == nil && existingHold != nil {
+		return &api.HoldResponse{
+			Success:   existingHold.Status == TwoPhaseTransactionStatusHeld,
+			Message:   fmt.Sprintf("Hold already %s", existingHold.Status),
+			ExpiresAt: existingHold.ExpiresAt,
+		}, nil
+	}
+
+	seats, itemErr := parseSeats(req.Payload.TrainSeats)
+	if itemErr != nil {
+		return &api.HoldResponse{
+			Success:    false,
+			Message:    fmt.Sprintf("Failed to parse item: %v", itemErr.Err),
+			FailedItem: &itemErr.Index,
+		}, nil
+	}
+
+	ttl := s.holdTTL
+	if req.TTLSeconds > 0 {
+		ttl = time.Duration(req.TTLSeconds) * time.Second
+	}
+	expiresAt := time.Now().Add(ttl)
+
+	if err := s.repo.HoldSeats(ctx, req.HoldID, seats, expiresAt); err != nil {
+		response := &api.HoldResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to hold seats: %v", err),
+		}
+
+		var itemErr *api.ItemError
+		if errors.As(err, &itemErr) {
+			response.FailedItem = &itemErr.Index
+		}
+
+		return response, nil
+	}
+
+	return &api.HoldResponse{
+		Success:   true,
+		Message:   "Train service held seats successfully",
+		ExpiresAt: &expiresAt,
+	}, nil
+}
+
+// ConfirmHold turns a hold into a committed booking transaction
+func (s *Service) ConfirmHold(ctx context.Context, req *api.ConfirmHoldRequest) (*api.CommitResponse, error) {
+	if err := s.repo.ConfirmSeatHold(ctx, req.HoldID, time.Now()); err != nil {
+		return &api.CommitResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to confirm hold: %v", err),
+		}, nil
+	}
+
+	return &api.CommitResponse{
+		Success: true,
+		Message: "Train service confirmed hold successfully",
+	}, nil
+}
+
+// ReleaseHold releases a hold before it expires
+func (s *Service) ReleaseHold(ctx context.Context, req *api.ReleaseHoldRequest) (*api.AbortResponse, error) {
+	if err := s.repo.ReleaseSeatHold(ctx, req.HoldID); err != nil {
+		return &api.AbortResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to release hold: %v", err),
+		}, nil
+	}
+
+	return &api.AbortResponse{
+		Success: true,
+		Message: "Train service released hold successfully",
+	}, nil
+}
+
+// ReleaseExpiredHolds releases every hold that expired without being confirmed. It is
+// called periodically by the sweeper of the train service.
+func (s *Service) ReleaseExpiredHolds(ctx context.Context) error {
+	holdIDs, err := s.repo.GetExpiredHoldIDs(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, holdID := range holdIDs {
+		if err := s.repo.ExpireSeatHold(ctx, holdID); err != nil {
+			errs = append(errs, fmt.Errorf("failed to expire hold %s: %w", holdID, err))
+		}
+	}
+	if len(holdIDs) > 0 {
+		log.Printf("Released %d expired holds", len(holdIDs))
+	}
+
+	return errors.Join(errs...)
+}
+
+// SearchSeats returns a page of the seats of the journey available for the whole trip of the query
+func (s *Service) SearchSeats(ctx context.Context, journeyID string, query *SeatSearchQuery) (*api.Page[AvailableSeat], error) {
+	journey, err := s.repo.GetTrainJourney(ctx, journeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	origin, destination := query.OriginStation, query.DestinationStation
+	if origin == "" && len(journey.Stations) > 0 {
+		origin = journey.Stations[0]
+	}
+	if destination == "" && len(journey.Stations) > 0 {
+		destination = journey.Stations[len(journey.Stations)-1]
+	}
+
+	fromSegment, toSegment, ok := journey.Segments(origin, destination)
+	if !ok {
+		return nil, ErrStationsNotOnRoute
+	}
+
+	seats, nextPageToken, err := s.repo.SearchAvailableSeats(ctx, journey, fromSegment, toSegment, query.PageToken, query.Limit())
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.Page[AvailableSeat]{
+		Items:         seats,
+		NextPageToken: nextPageToken,
+	}, nil
+}
@@ -0,0 +1,17 @@
+package api
+
+import "fmt"
+
+// ItemError reports which item of a multi-item request caused a failure
+type ItemError struct {
+	Index int
+	Err   error
+}
+
+func (e *ItemError) Error() string {
+	return fmt.Sprintf("item %d: %v", e.Index, e.Err)
+}
+
+func (e *ItemError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,26 @@
+package api
+
+import "time"
+
+// InventoryReleaseCollection is the outbox participants write to whenever reserved
+// availability becomes available again, in the same transaction that releases it
+const InventoryReleaseCollection = "twophase_inventory_releases"
+
+// InventoryRelease is one reservation whose availability was released by an abort, a
+// cancellation, a modification or a released or expired hold
+type InventoryRelease struct {
+	ID string `firestore:"id" json:"id"`
+	// Service is the participant that released the item: "hotel", "car" or "train"
+	Service string `firestore:"service" json:"service"`
+	// ItemID is the room, car or train journey ID. SeatID is only set for train seats.
+	// Hotel rooms also set RoomTypeID when the room belongs to a room type, and room
+	// type bookings without an assigned room only set RoomTypeID.
+	ItemID     string `firestore:"item_id" json:"item_id"`
+	SeatID     string `firestore:"seat_id,omitempty" json:"seat_id,omitempty"`
+	RoomTypeID string `firestore:"room_type_id,omitempty" json:"room_type_id,omitempty"`
+	StartDate  string `firestore:"start_date" json:"start_date"`
+	EndDate    string `firestore:"end_date" json:"end_date"`
+	// Processed is set once the coordinator waitlist has handled the release
+	Processed bool      `firestore:"processed" json:"processed"`
+	CreatedAt time.Time `firestore:"created_at" json:"created_at"`
+}
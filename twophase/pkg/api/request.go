@@ -16,3 +16,49 @@ type CommitRequest struct {
 type AbortRequest struct {
 	TransactionID string `json:"transaction_id" binding:"required"`
 }
+
+// CancellationPayload is the prepare payload of a cancellation transaction, which
+// releases everything a participant reserved for BookingTransactionID
+type CancellationPayload struct {
+	BookingTransactionID string `json:"booking_transaction_id" binding:"required"`
+	// RefundAmount is the amount returned to the user after the cancellation fee
+	RefundAmount int64 `json:"refund_amount"`
+}
+
+// HoldRequest places a hold on the items of Payload. The hold reserves availability
+// like a prepared transaction, but is released by the participant once it expires
+// unless it is confirmed first.
+type HoldRequest[T any] struct {
+	HoldID string `json:"hold_id" binding:"required"`
+	// TTLSeconds overrides the default hold time-to-live of the participant
+	TTLSeconds int `json:"ttl_seconds" binding:"min=0"`
+	Payload    T   `json:"payload"`
+}
+
+// ConfirmHoldRequest turns a hold into a committed booking transaction with the same ID
+type ConfirmHoldRequest struct {
+	HoldID string `json:"hold_id" binding:"required"`
+}
+
+// ReleaseHoldRequest releases a hold before it expires
+type ReleaseHoldRequest struct {
+	HoldID string `json:"hold_id" binding:"required"`
+}
+
+// DefaultPageSize is the page size of searches that do not set one
+const DefaultPageSize = 20
+
+// PageRequest holds the pagination query parameters of a search
+type PageRequest struct {
+	PageSize int `form:"page_size" binding:"omitempty,min=1,max=100"`
+	// PageToken is the NextPageToken of the previous page
+	PageToken string `form:"page_token"`
+}
+
+// Limit returns the page size, or DefaultPageSize when it is not set
+func (p PageRequest) Limit() int {
+	if p.PageSize == 0 {
+		return DefaultPageSize
+	}
+	return p.PageSize
+}
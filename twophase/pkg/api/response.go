@@ -1,9 +1,13 @@
 package api
 
+import "time"
+
 // PrepareResponse represents prepare phase response
 type PrepareResponse struct {
 	Success bool   `json:"success"`
 	Message string `json:"message"`
+	// FailedItem is the index of the payload item that could not be prepared, if any
+	FailedItem *int `json:"failed_item,omitempty"`
 }
 
 // CommitResponse represents commit phase response
@@ -17,3 +21,18 @@ type AbortResponse struct {
 	Success bool   `json:"success"`
 	Message string `json:"message"`
 }
+
+// HoldResponse represents hold response
+type HoldResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	// FailedItem is the index of the payload item that could not be held, if any
+	FailedItem *int       `json:"failed_item,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+}
+
+// Page is one page of search results. NextPageToken is empty on the last page.
+type Page[T any] struct {
+	Items         []T    `json:"items"`
+	NextPageToken string `json:"next_page_token,omitempty"`
+}
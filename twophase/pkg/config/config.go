@@ -1,6 +1,10 @@
 package config
 
-import "github.com/caarlos0/env/v11"
+import (
+	"time"
+
+	"github.com/caarlos0/env/v11"
+)
 
 const (
 	DateFormat = "2006-01-02"
@@ -8,6 +12,19 @@ const (
 
 type Config struct {
 	GoogleProjectID string `env:"GOOGLE_PROJECT_ID,required"`
+
+	// HoldTTL is how long a hold lasts when the request does not set its own TTL.
+	// Participants release expired holds every HoldSweepInterval.
+	HoldTTL           time.Duration `env:"HOLD_TTL" envDefault:"10m"`
+	HoldSweepInterval time.Duration `env:"HOLD_SWEEP_INTERVAL" envDefault:"1m"`
+
+	// Fake payment provider settings. Authorizations above FakePaymentDeclineAbove
+	// are declined, 0 never declines.
+	FakePaymentDeclineAbove int64         `env:"FAKE_PAYMENT_DECLINE_ABOVE" envDefault:"0"`
+	FakePaymentLatency      time.Duration `env:"FAKE_PAYMENT_LATENCY" envDefault:"0s"`
+
+	// ResetAllowedProjectIDs lists the test Google Cloud projects cmd/reset may wipe
+	ResetAllowedProjectIDs []string `env:"RESET_ALLOWED_PROJECT_IDS" envSeparator:","`
 }
 
 func LoadConfig() (Config, error) {
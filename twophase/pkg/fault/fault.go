@@ -0,0 +1,248 @@
+// Package fault injects failures into the coordinator's HTTP client and into the
+// participant repositories for chaos testing. Every injected fault is counted and
+// recorded in Firestore so that it shows up in the per-transaction metrics.
+package fault
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caarlos0/env/v11"
+)
+
+// ErrInjected is returned by operations failed by an injector
+var ErrInjected = errors.New("injected fault")
+
+// Kind is a kind of injected fault
+type Kind string
+
+const (
+	// Coordinator HTTP client faults. A timeout or a 500 never reaches the participant,
+	// while a lost response is dropped after the participant acted on the request.
+	KindTimeout      Kind = "http_timeout"
+	KindServerError  Kind = "http_500"
+	KindLostResponse Kind = "http_lost_response"
+
+	// Participant repository faults. A failure after commit returns an error although
+	// the write was stored.
+	KindFailBeforeCommit Kind = "fail_before_commit"
+	KindFailAfterCommit  Kind = "fail_after_commit"
+)
+
+// Config holds the probability (0 to 1) of every kind of fault. It is read from the
+// environment and can be changed at runtime through the admin endpoint.
+type Config struct {
+	TimeoutRate      float64 `env:"FAULT_TIMEOUT_RATE" json:"timeout_rate"`
+	ServerErrorRate  float64 `env:"FAULT_SERVER_ERROR_RATE" json:"server_error_rate"`
+	LostResponseRate float64 `env:"FAULT_LOST_RESPONSE_RATE" json:"lost_response_rate"`
+	// Timeout is how long a timed out request hangs before failing, unless the request
+	// context ends first
+	Timeout time.Duration `env:"FAULT_TIMEOUT" envDefault:"10s" json:"-"`
+
+	FailBeforeCommitRate float64 `env:"FAULT_FAIL_BEFORE_COMMIT_RATE" json:"fail_before_commit_rate"`
+	FailAfterCommitRate  float64 `env:"FAULT_FAIL_AFTER_COMMIT_RATE" json:"fail_after_commit_rate"`
+
+	// Targets limits faults to request URLs or repository operations that contain one
+	// of the values. Empty targets everything.
+	Targets []string `env:"FAULT_TARGETS" envSeparator:"," json:"targets"`
+}
+
+// LoadConfig reads the Config from the environment
+func LoadConfig() (Config, error) {
+	cfg := Config{}
+	if err := env.Parse(&cfg); err != nil {
+		return Config{}, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// Validate checks that every rate is between 0 and 1
+func (c Config) Validate() error {
+	for _, kind := range []Kind{KindTimeout, KindServerError, KindLostResponse, KindFailBeforeCommit, KindFailAfterCommit} {
+		if rate := c.rate(kind); rate < 0 || rate > 1 {
+			return fmt.Errorf("%s rate must be between 0 and 1, got %v", kind, rate)
+		}
+	}
+	if c.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative, got %s", c.Timeout)
+	}
+	return nil
+}
+
+func (c Config) rate(kind Kind) float64 {
+	switch kind {
+	case KindTimeout:
+		return c.TimeoutRate
+	case KindServerError:
+		return c.ServerErrorRate
+	case KindLostResponse:
+		return c.LostResponseRate
+	case KindFailBeforeCommit:
+		return c.FailBeforeCommitRate
+	case KindFailAfterCommit:
+		return c.FailAfterCommitRate
+	}
+	return 0
+}
+
+func (c Config) targets(target string) bool {
+	if len(c.Targets) == 0 {
+		return true
+	}
+	for _, t := range c.Targets {
+		if t != "" && strings.Contains(target, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// configJSON writes Timeout as a duration such as "10s" instead of nanoseconds
+type configJSON struct {
+	*configAlias
+	Timeout string `json:"timeout"`
+}
+
+type configAlias Config
+
+func (c Config) MarshalJSON() ([]byte, error) {
+	return json.Marshal(configJSON{configAlias: (*configAlias)(&c), Timeout: c.Timeout.String()})
+}
+
+// UnmarshalJSON only changes the fields present in the JSON, so the admin endpoint can
+// update part of the config
+func (c *Config) UnmarshalJSON(data []byte) error {
+	aux := configJSON{configAlias: (*configAlias)(c)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	if aux.Timeout != "" {
+		timeout, err := time.ParseDuration(aux.Timeout)
+		if err != nil {
+			return fmt.Errorf("invalid timeout: %w", err)
+		}
+		c.Timeout = timeout
+	}
+	return nil
+}
+
+// Injector decides when faults are injected and counts every injected fault. A nil
+// injector never injects anything.
+type Injector struct {
+	service  string
+	recorder *Recorder
+
+	mu       sync.Mutex
+	config   Config
+	injected map[Kind]int64
+}
+
+// NewInjector creates an injector for service. recorder may be nil when faults only
+// need to be counted.
+func NewInjector(service string, config Config, recorder *Recorder) *Injector {
+	return &Injector{
+		service:  service,
+		recorder: recorder,
+		config:   config,
+		injected: make(map[Kind]int64),
+	}
+}
+
+// Config returns the config in effect
+func (i *Injector) Config() Config {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.config
+}
+
+// SetConfig replaces the config in effect
+func (i *Injector) SetConfig(config Config) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.config = config
+	return nil
+}
+
+// Injected returns the number of injected faults per kind
+func (i *Injector) Injected() map[Kind]int64 {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	injected := make(map[Kind]int64, len(i.injected))
+	for kind, count := range i.injected {
+		injected[kind] = count
+	}
+	return injected
+}
+
+// inject rolls a fault of kind on target. An injected fault is counted and recorded
+// with transactionID so that it can be matched to its transaction.
+func (i *Injector) inject(kind Kind, target, transactionID string) bool {
+	if i == nil {
+		return false
+	}
+
+	i.mu.Lock()
+	rate := i.config.rate(kind)
+	if rate <= 0 || !i.config.targets(target) || rand.Float64() >= rate {
+		i.mu.Unlock()
+		return false
+	}
+	i.injected[kind]++
+	i.mu.Unlock()
+
+	log.Printf("Injected %s fault on %s for %s", kind, target, transactionID)
+	if i.recorder != nil {
+		e := &Event{
+			Service:       i.service,
+			Kind:          kind,
+			Target:        target,
+			TransactionID: transactionID,
+			CreatedAt:     time.Now(),
+		}
+		go func() {
+			if err := i.recorder.Record(context.Background(), e); err != nil {
+				log.Printf("Failed to record %s fault: %v", kind, err)
+			}
+		}()
+	}
+	return true
+}
+
+// timeout returns how long a timed out request hangs
+func (i *Injector) timeout() time.Duration {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.config.Timeout
+}
+
+// Do runs the write op of transactionID and may fail it before or after the write is
+// stored
+func (i *Injector) Do(op, transactionID string, write func() error) error {
+	if i.inject(KindFailBeforeCommit, op, transactionID) {
+		return fmt.Errorf("%s before commit: %w", op, ErrInjected)
+	}
+
+	if err := write(); err != nil {
+		return err
+	}
+
+	if i.inject(KindFailAfterCommit, op, transactionID) {
+		return fmt.Errorf("%s after commit: %w", op, ErrInjected)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package fault
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler serves the admin endpoint that reads and changes an injector's config
+type Handler struct {
+	injector *Injector
+}
+
+// NewHandler creates a new fault admin handler
+func NewHandler(injector *Injector) *Handler {
+	return &Handler{
+		injector: injector,
+	}
+}
+
+// RegisterRoutes registers the fault admin routes
+func (h *Handler) RegisterRoutes(r *gin.Engine) {
+	admin := r.Group("/admin/faults")
+	{
+		admin.GET("", h.GetFaults)
+		admin.PUT("", h.UpdateFaults)
+		admin.DELETE("", h.ResetFaults)
+	}
+}
+
+// GetFaults returns the config in effect and the number of injected faults per kind
+func (h *Handler) GetFaults(c *gin.Context) {
+	h.respond(c)
+}
+
+// UpdateFaults changes the config fields present in the body and keeps the others
+func (h *Handler) UpdateFaults(c *gin.Context) {
+	config := h.injector.Config()
+	if err := c.ShouldBindJSON(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := h.injector.SetConfig(config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid fault config",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.respond(c)
+}
+
+// ResetFaults turns every fault off. The injected counts are kept.
+func (h *Handler) ResetFaults(c *gin.Context) {
+	config := Config{Timeout: h.injector.Config().Timeout}
+	if err := h.injector.SetConfig(config); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to reset fault config",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.respond(c)
+}
+
+func (h *Handler) respond(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"service":  h.injector.service,
+		"config":   h.injector.Config(),
+		"injected": h.injector.Injected(),
+	})
+}
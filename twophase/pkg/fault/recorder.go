@@ -0,0 +1,43 @@
+package fault
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/oklog/ulid/v2"
+)
+
+// EventCollection stores every injected fault. The metrics calculator counts them per
+// transaction.
+const EventCollection = "fault_events"
+
+// Event is a single injected fault
+type Event struct {
+	ID      string `firestore:"id"`
+	Service string `firestore:"service"`
+	Kind    Kind   `firestore:"kind"`
+	// Target is the request URL or repository operation that was failed
+	Target        string    `firestore:"target"`
+	TransactionID string    `firestore:"transaction_id"`
+	CreatedAt     time.Time `firestore:"created_at"`
+}
+
+// Recorder stores injected faults in Firestore
+type Recorder struct {
+	client *firestore.Client
+}
+
+// NewRecorder creates a new fault recorder
+func NewRecorder(client *firestore.Client) *Recorder {
+	return &Recorder{client: client}
+}
+
+// Record stores an injected fault
+func (r *Recorder) Record(ctx context.Context, e *Event) error {
+	if e.ID == "" {
+		e.ID = ulid.Make().String()
+	}
+	_, err := r.client.Collection(EventCollection).Doc(e.ID).Set(ctx, e)
+	return err
+}
@@ -0,0 +1,101 @@
+package fault
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Transport wraps the coordinator's HTTP transport so that participant requests can time
+// out, fail with a 500, or lose their response after the participant acted on them
+type Transport struct {
+	next     http.RoundTripper
+	injector *Injector
+}
+
+// NewTransport creates a new fault injecting transport. A nil next uses
+// http.DefaultTransport.
+func NewTransport(next http.RoundTripper, injector *Injector) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Transport{
+		next:     next,
+		injector: injector,
+	}
+}
+
+// RoundTrip sends req unless a fault is injected
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	target := req.URL.String()
+	transactionID := requestTransactionID(req)
+
+	if t.injector.inject(KindTimeout, target, transactionID) {
+		closeBody(req)
+		timer := time.NewTimer(t.injector.timeout())
+		defer timer.Stop()
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-timer.C:
+			return nil, fmt.Errorf("request timed out: %w", ErrInjected)
+		}
+	}
+
+	if t.injector.inject(KindServerError, target, transactionID) {
+		closeBody(req)
+		return &http.Response{
+			Status:     "500 Internal Server Error",
+			StatusCode: http.StatusInternalServerError,
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"success":false,"message":"injected fault"}`)),
+			Request:    req,
+		}, nil
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.injector.inject(KindLostResponse, target, transactionID) {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return nil, fmt.Errorf("response lost: %w", ErrInjected)
+	}
+	return resp, nil
+}
+
+// requestTransactionID reads the transaction ID from a copy of the request body
+func requestTransactionID(req *http.Request) string {
+	if req.GetBody == nil {
+		return ""
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return ""
+	}
+	defer body.Close()
+
+	var payload struct {
+		TransactionID string `json:"transaction_id"`
+	}
+	if err := json.NewDecoder(body).Decode(&payload); err != nil {
+		return ""
+	}
+	return payload.TransactionID
+}
+
+// closeBody closes the body of a request that is never sent, as a RoundTripper must
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
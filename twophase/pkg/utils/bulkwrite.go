@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// WriteMode controls what BulkWrite does with documents that already exist
+type WriteMode string
+
+const (
+	// WriteModeCreate only creates missing documents and skips existing ones
+	WriteModeCreate WriteMode = "create"
+	// WriteModeUpsert creates missing documents and updates existing ones
+	WriteModeUpsert WriteMode = "upsert"
+)
+
+// Doc is a document written by BulkWrite
+type Doc struct {
+	Ref  *firestore.DocumentRef
+	Data any
+}
+
+// BulkWriteResult counts the documents BulkWrite wrote and skipped
+type BulkWriteResult struct {
+	Written int
+	Skipped int
+}
+
+// BulkWrite writes docs with a BulkWriter according to mode, so it can be run again and
+// again. With WriteModeUpsert, fields limits what is updated on existing documents so that
+// other fields, such as availability flags, keep their value. Without fields, existing
+// documents are overwritten.
+func BulkWrite(ctx context.Context, client *firestore.Client, docs []Doc, mode WriteMode, fields ...string) (BulkWriteResult, error) {
+	if mode == WriteModeUpsert && len(fields) == 0 {
+		written, _, err := bulkWrite(ctx, client, docs, func(bw *firestore.BulkWriter, doc Doc) (*firestore.BulkWriterJob, error) {
+			return bw.Set(doc.Ref, doc.Data)
+		})
+		return BulkWriteResult{Written: written}, err
+	}
+
+	created, existing, err := bulkWrite(ctx, client, docs, func(bw *firestore.BulkWriter, doc Doc) (*firestore.BulkWriterJob, error) {
+		return bw.Create(doc.Ref, doc.Data)
+	})
+	if err != nil || mode == WriteModeCreate || len(existing) == 0 {
+		return BulkWriteResult{Written: created, Skipped: len(existing)}, err
+	}
+
+	paths := make([]firestore.FieldPath, 0, len(fields))
+	for _, field := range fields {
+		paths = append(paths, firestore.FieldPath{field})
+	}
+	updated, _, err := bulkWrite(ctx, client, existing, func(bw *firestore.BulkWriter, doc Doc) (*firestore.BulkWriterJob, error) {
+		return bw.Set(doc.Ref, doc.Data, firestore.Merge(paths...))
+	})
+	return BulkWriteResult{Written: created + updated}, err
+}
+
+// bulkWrite runs write for every document and returns the ones that already exist
+func bulkWrite(ctx context.Context, client *firestore.Client, docs []Doc, write func(*firestore.BulkWriter, Doc) (*firestore.BulkWriterJob, error)) (int, []Doc, error) {
+	bw := client.BulkWriter(ctx)
+	jobs := make([]*firestore.BulkWriterJob, 0, len(docs))
+	for _, doc := range docs {
+		job, err := write(bw, doc)
+		if err != nil {
+			bw.End()
+			return 0, nil, fmt.Errorf("failed to write %s: %w", doc.Ref.Path, err)
+		}
+		jobs = append(jobs, job)
+	}
+	bw.End()
+
+	written, failed := 0, 0
+	var existing []Doc
+	var firstErr error
+	for i, job := range jobs {
+		_, err := job.Results()
+		switch {
+		case err == nil:
+			written++
+		case status.Code(err) == codes.AlreadyExists:
+			existing = append(existing, docs[i])
+		default:
+			failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	if failed > 0 {
+		return written, existing, fmt.Errorf("failed to write %d of %d documents: %w", failed, len(docs), firstErr)
+	}
+	return written, existing, nil
+}